@@ -0,0 +1,351 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/mux"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/openapi"
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+// MessageResponse documents simple acknowledgement bodies
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// pageQuery are the pagination parameters shared by list endpoints
+var pageQuery = []openapi.Param{
+	{Name: "page", Type: "integer", Description: "Page number (default 1)"},
+	{Name: "limit", Type: "integer", Description: "Items per page (default 10, max 100)"},
+}
+
+// usageDaysParam is the period parameter of the API key usage endpoints
+var usageDaysParam = openapi.Param{Name: "days", Type: "integer", Description: "Number of days up to today to report, 1 to 90 (default 30)"}
+
+// periodTZParam sets where the days of a dashboard period start
+var periodTZParam = openapi.Param{Name: "tz", Description: "IANA time zone the current day, week or month and custom dates are read in, e.g. Europe/Paris (default the caller's time_zone, or UTC)"}
+
+// includeArchivedParam lets the task and project listings include archived projects
+var includeArchivedParam = openapi.Param{Name: "include_archived", Type: "boolean", Description: "Include archived projects and their tasks, and tasks archived as stale (default false)"}
+
+// ndjsonFormatParam lets the large listings stream every match instead of a page
+var ndjsonFormatParam = openapi.Param{Name: "format", Description: "ndjson streams every match, ignoring page and limit, as one line of JSON per record (application/x-ndjson; also chosen by Accept: application/x-ndjson)"}
+
+// fieldsParam lets the task and user listings return only some fields of each record
+var fieldsParam = openapi.Param{Name: "fields", Description: "Comma-separated fields to return for each record besides id, e.g. title,status,due_date (default all)"}
+
+// countModeParam lets the large listings skip counting every match for total_count
+var countModeParam = openapi.Param{Name: "count_mode", Description: "exact (default) or estimated: total_count comes from collection statistics, or stops at 10000, and count_estimated reports when it is approximate"}
+
+// listQuery documents the parameters of a list endpoint built on the query package:
+// its filters, <range>_from/<range>_to bounds, ?sort= and pagination
+func listQuery(filters []openapi.Param, ranges []string, sorts ...string) []openapi.Param {
+	params := append([]openapi.Param{}, filters...)
+	for _, r := range ranges {
+		params = append(params,
+			openapi.Param{Name: r + "_from", Description: "Lower bound (RFC 3339 time or YYYY-MM-DD)"},
+			openapi.Param{Name: r + "_to", Description: "Upper bound (RFC 3339 time or YYYY-MM-DD)"},
+		)
+	}
+	params = append(params, openapi.Param{Name: "sort", Description: "Comma-separated fields, prefix with - for descending. Allowed: " + strings.Join(sorts, ", ")})
+	return append(params, pageQuery...)
+}
+
+// routeDocs documents routes by method and version-relative path.
+// Routes missing from this map still appear in the spec with a generic summary.
+var routeDocs = map[string]openapi.Operation{
+	"POST /auth/register":             {Summary: "Register a new user", Tag: "Auth", Public: true, Request: models.UserRegisterRequest{}, Response: models.UserResponse{}, ResponseStatus: http.StatusCreated},
+	"POST /auth/login":                {Summary: "Log in and obtain a JWT, or session cookies when cookie authentication is enabled", Tag: "Auth", Public: true, Request: models.UserLoginRequest{}, Response: models.LoginResponse{}},
+	"POST /auth/forgot_password":      {Summary: "Request a password reset email", Tag: "Auth", Public: true, Request: models.ForgotPasswordRequest{}, Response: MessageResponse{}},
+	"POST /auth/reset_password":       {Summary: "Reset a password with a reset token", Tag: "Auth", Public: true, Request: models.ResetPasswordRequest{}, Response: MessageResponse{}},
+	"POST /auth/refresh":              {Summary: "Rotate the refresh cookie and renew the session cookie", Tag: "Auth", Public: true, Response: models.RefreshSessionResponse{}},
+	"POST /auth/logout":               {Summary: "End the cookie session and clear its cookies", Tag: "Auth", Public: true, Response: MessageResponse{}},
+	"GET /auth/csrf":                  {Summary: "Get the CSRF token cookie-authenticated requests must send in X-CSRF-Token", Tag: "Auth", Public: true, Response: models.CSRFTokenResponse{}},
+	"POST /auth/verify_email":         {Summary: "Verify the current user's email", Tag: "Auth", Response: MessageResponse{}, Query: []openapi.Param{{Name: "token", Required: true}}},
+	"POST /auth/change_temp_password": {Summary: "Replace a temporary password", Tag: "Auth", Request: models.ChangeTemporaryPasswordRequest{}, Response: MessageResponse{}},
+
+	"POST /users/admin":       {Summary: "Create an admin user", Tag: "Users", Permission: "user:create_admin", Request: models.UserRegisterRequest{}, ResponseStatus: http.StatusCreated},
+	"GET /users/{id}":         {Summary: "Get a user profile", Tag: "Users", Permission: "user:read_own", Response: models.UserResponse{}},
+	"DELETE /users/{id}":      {Summary: "Delete a user and their tasks, or reassign the tasks", Tag: "Users", Permission: "user:delete", ResponseStatus: http.StatusNoContent, Query: []openapi.Param{{Name: "reassign_to", Description: "User ID that should receive the deleted user's tasks and owned projects; required when the user owns projects"}}},
+	"PUT /users/{id}/role":    {Summary: "Change a user's role", Tag: "Users", Permission: "user:update_role", Request: models.UpdateUserRoleRequest{}, Response: models.UserResponse{}},
+	"POST /users/{id}/merge":  {Summary: "Merge a duplicate account into a user; repeating a merge resumes one that was interrupted", Tag: "Users", Permission: "user:merge", Request: models.MergeUsersRequest{}, Response: models.MergeUsersResponse{}},
+	"PUT /users/{id}/profile": {Summary: "Update a user profile", Tag: "Users", Permission: "user:update_profile", Request: models.UpdateUserProfileRequest{}, Response: models.UserResponse{}},
+	"GET /users": {Summary: "List users", Tag: "Users", Permission: "user:read_all", Response: models.UserListResponse{},
+		Query: listQuery([]openapi.Param{{Name: "email_like"}, {Name: "role_name"}, {Name: "service_account", Type: "boolean"}, countModeParam, ndjsonFormatParam, fieldsParam}, []string{"created"}, "created_at", "updated_at", "email", "first_name", "last_name")},
+
+	"POST /service-accounts": {Summary: "Create a service account, a non-human user that authenticates with API keys", Tag: "Service accounts", Permission: "service_account:manage", Request: models.CreateServiceAccountRequest{}, Response: models.UserResponse{}, ResponseStatus: http.StatusCreated},
+	"GET /service-accounts": {Summary: "List service accounts", Tag: "Service accounts", Permission: "service_account:manage", Response: models.UserListResponse{},
+		Query: listQuery([]openapi.Param{{Name: "email_like"}, countModeParam}, []string{"created"}, "created_at", "updated_at", "email", "first_name", "last_name")},
+	"DELETE /service-accounts/{id}": {Summary: "Delete a service account, its keys and its tasks, or reassign the tasks", Tag: "Service accounts", Permission: "service_account:manage", ResponseStatus: http.StatusNoContent,
+		Query: []openapi.Param{{Name: "reassign_to", Description: "User ID that should receive the service account's tasks"}}},
+	"POST /service-accounts/{id}/keys":            {Summary: "Issue an API key; the key is only returned once", Tag: "Service accounts", Permission: "service_account:manage", Request: models.CreateAPIKeyRequest{}, Response: models.CreateAPIKeyResponse{}, ResponseStatus: http.StatusCreated},
+	"GET /service-accounts/{id}/keys":             {Summary: "List a service account's API keys", Tag: "Service accounts", Permission: "service_account:manage", Response: models.APIKeyListResponse{}},
+	"PUT /service-accounts/{id}/keys/{key_id}":    {Summary: "Rename an API key or change its rate limit", Tag: "Service accounts", Permission: "service_account:manage", Request: models.UpdateAPIKeyRequest{}, Response: models.APIKey{}},
+	"DELETE /service-accounts/{id}/keys/{key_id}": {Summary: "Revoke an API key", Tag: "Service accounts", Permission: "service_account:manage", ResponseStatus: http.StatusNoContent},
+	"GET /service-accounts/{id}/keys/{key_id}/usage": {Summary: "Count the requests made with an API key per day", Tag: "Service accounts", Permission: "service_account:manage", Response: models.APIKeyUsageResponse{},
+		Query: []openapi.Param{usageDaysParam}},
+	"GET /api-keys/current/usage": {Summary: "Count the requests made per day with the API key authenticating this request", Tag: "Service accounts", Response: models.APIKeyUsageResponse{},
+		Query: []openapi.Param{usageDaysParam}},
+
+	"POST /tasks": {Summary: "Create a task", Tag: "Tasks", Permission: "task:create", Request: models.CreateTaskRequest{}, Response: models.Task{}, ResponseStatus: http.StatusCreated},
+	"GET /tasks": {Summary: "List tasks; has_unread_updates flags those updated, or commented on by someone else, since the caller last viewed them", Tag: "Tasks", Permission: "task:read_own", Response: models.TaskListResponse{},
+		Query: listQuery([]openapi.Param{{Name: "status"}, {Name: "search"}, {Name: "user_id"}, {Name: "project_id"}, {Name: "milestone_id"}, {Name: "sprint_id"}, {Name: "priority"}, {Name: "tag", Description: "Tasks with this tag"}, {Name: "color", Description: "Tasks of this color: a palette name or hex color, with # encoded as %23"}, {Name: "is_pinned", Description: "true for the tasks the caller pinned only, false for the others"}, includeArchivedParam, countModeParam, ndjsonFormatParam, fieldsParam}, []string{"created", "updated", "due", "stale", "sla_breached"}, "created_at", "updated_at", "due_date", "title", "status", "pinned")},
+	"POST /tasks/quick": {Summary: "Create a task from shorthand such as \"Pay rent tomorrow 5pm #finance !high\": #tags, a !low/!medium/!high/!urgent priority, and a due date (today, tomorrow, friday, next week, in 3 days, YYYY-MM-DD) and time (5pm, 17:00, noon); the other words are the title",
+		Tag: "Tasks", Permission: "task:create", Request: models.QuickAddTaskRequest{}, Response: models.Task{}, ResponseStatus: http.StatusCreated,
+		Query: []openapi.Param{{Name: "tz", Description: "IANA time zone dates and times are read in, e.g. Europe/Paris (default the caller's time_zone, or UTC)"}}},
+	"GET /tasks/suggest": {Summary: "Suggest the tasks the caller can list whose title starts with q, ignoring case, for search-as-you-type", Tag: "Tasks", Permission: "task:read_own",
+		Response: models.TaskSuggestResponse{},
+		Query:    []openapi.Param{{Name: "q", Description: "Typed prefix, 1 to 100 characters"}, {Name: "limit", Type: "integer", Description: "Default 10, at most 20"}}},
+	"GET /tasks/export": {Summary: "Stream a backup of all the caller's tasks with their comments and attachments as one JSON document; a backup cut short isn't valid JSON",
+		Tag: "Tasks", Permission: "task:read_own", Response: models.TaskBackup{},
+		Query: []openapi.Param{{Name: "format", Description: "json (default) or ndjson, one line per task (application/x-ndjson); an ndjson backup cut short ends the connection abruptly"}}},
+	"GET /tasks/{id}":                   {Summary: "Get a task", Tag: "Tasks", Permission: "task:read_own", Response: models.Task{}},
+	"PUT /tasks/{id}":                   {Summary: "Update a task, bringing it back if it was flagged or archived as stale", Tag: "Tasks", Permission: "task:update_own", Request: models.UpdateTaskRequest{}, Response: models.Task{}},
+	"DELETE /tasks/{id}":                {Summary: "Delete a task", Tag: "Tasks", Permission: "task:delete_own", ResponseStatus: http.StatusNoContent},
+	"POST /tasks/{id}/pin":              {Summary: "Pin a task for the caller, so it can be listed first with sort=-pinned; anyone who can see a task may pin it", Tag: "Tasks", Permission: "task:read_own", Response: models.Task{}},
+	"POST /tasks/{id}/unpin":            {Summary: "Unpin a task for the caller", Tag: "Tasks", Permission: "task:read_own", Response: models.Task{}},
+	"POST /tasks/{id}/seen":             {Summary: "Mark a task as seen by the caller, clearing its has_unread_updates flag in their listings until it is updated or someone else comments on it; getting or updating the task also marks it as seen", Tag: "Tasks", Permission: "task:read_own", ResponseStatus: http.StatusNoContent},
+	"POST /tasks/{id}/merge":            {Summary: "Merge a duplicate task into a task: its comments and attachments move over, and it is closed as done with merged_into pointing to the task", Tag: "Tasks", Permission: "task:update_own", Request: models.MergeTasksRequest{}, Response: models.MergeTasksResponse{}},
+	"POST /tasks/{id}/attachments/link": {Summary: "Attach one of the caller's existing uploads to a task", Tag: "Tasks", Permission: "task:update_own", Request: models.LinkAttachmentRequest{}, Response: models.Upload{}},
+	"GET /tasks/{id}/export": {Summary: "Download a printable PDF (application/pdf) of a task with its description, comments and the changes made to it",
+		Tag: "Tasks", Permission: "task:read_own",
+		Query: []openapi.Param{
+			{Name: "format", Description: "pdf (the default and only format)"},
+			{Name: "tz", Description: "IANA time zone times are printed in, e.g. Europe/Paris (default the caller's time_zone, or UTC)"},
+		}},
+
+	"POST /projects": {Summary: "Create a project owned by the caller", Tag: "Projects", Permission: "project:create", Request: models.CreateProjectRequest{}, Response: models.Project{}, ResponseStatus: http.StatusCreated},
+	"GET /projects": {Summary: "List projects", Tag: "Projects", Permission: "project:read_own", Response: models.ProjectListResponse{},
+		Query: listQuery([]openapi.Param{{Name: "archived", Type: "boolean"}, {Name: "owner_id"}, includeArchivedParam}, []string{"created", "updated"}, "created_at", "updated_at", "name")},
+	"GET /projects/{id}":    {Summary: "Get a project and its members", Tag: "Projects", Permission: "project:read_own", Response: models.Project{}},
+	"PUT /projects/{id}":    {Summary: "Rename a project or change its description", Tag: "Projects", Permission: "project:update_own", Request: models.UpdateProjectRequest{}, Response: models.Project{}},
+	"DELETE /projects/{id}": {Summary: "Delete a project with its milestones and sprints; its tasks are kept outside any project", Tag: "Projects", Permission: "project:delete_own", ResponseStatus: http.StatusNoContent},
+	"GET /projects/{id}/metrics": {Summary: "Get the status distribution, member workload and throughput of a project's tasks", Tag: "Projects", Permission: "project:read_own",
+		Response: models.ProjectMetricsResponse{},
+		Query: []openapi.Param{
+			{Name: "period", Description: "daily, weekly, monthly or custom"}, {Name: "start_date"}, {Name: "end_date"},
+			{Name: "tz", Description: "IANA time zone days are counted in, e.g. Europe/Paris (default the caller's time_zone, or UTC)"},
+		}},
+	"GET /projects/{id}/burndown": {Summary: "Count a project's open tasks at the end of each day of a sprint or date range", Tag: "Projects", Permission: "project:read_own",
+		Response: models.BurndownResponse{},
+		Query: []openapi.Param{
+			{Name: "sprint_id", Description: "Only count this sprint's tasks, over its dates by default"},
+			{Name: "from", Description: "First day (YYYY-MM-DD), required without sprint_id"},
+			{Name: "to", Description: "Last day (YYYY-MM-DD), at most 366 days after from; required without sprint_id"},
+			{Name: "tz", Description: "IANA time zone days are counted in, e.g. Europe/Paris (default the caller's time_zone, or UTC)"},
+		}},
+	"POST /projects/{id}/archive":             {Summary: "Archive a project: it accepts no new tasks and its tasks are hidden from listings", Tag: "Projects", Permission: "project:update_own", Response: models.Project{}},
+	"POST /projects/{id}/restore":             {Summary: "Restore an archived project and show its tasks again", Tag: "Projects", Permission: "project:update_own", Response: models.Project{}},
+	"POST /projects/{id}/members":             {Summary: "Add a user, by ID or email, to a project as a viewer, editor or manager (managers only)", Tag: "Projects", Permission: "project:update_own", Request: models.AddProjectMemberRequest{}, Response: models.Project{}, ResponseStatus: http.StatusCreated},
+	"PUT /projects/{id}/members/{user_id}":    {Summary: "Change a member's role (managers only)", Tag: "Projects", Permission: "project:update_own", Request: models.UpdateProjectMemberRequest{}, Response: models.Project{}},
+	"DELETE /projects/{id}/members/{user_id}": {Summary: "Remove a member from a project (managers, or members leaving)", Tag: "Projects", Permission: "project:read_own", ResponseStatus: http.StatusNoContent},
+
+	"POST /projects/{id}/milestones": {Summary: "Create a milestone in a project (managers only)", Tag: "Milestones", Permission: "project:update_own", Request: models.CreateMilestoneRequest{}, Response: models.Milestone{}, ResponseStatus: http.StatusCreated},
+	"GET /projects/{id}/milestones": {Summary: "List a project's milestones with their progress, soonest due first", Tag: "Milestones", Permission: "project:read_own", Response: models.MilestoneListResponse{},
+		Query: listQuery(nil, []string{"due", "created"}, "due_date", "created_at", "title")},
+	"GET /projects/{id}/milestones/{milestone_id}":    {Summary: "Get a milestone and the share of its tasks that are done", Tag: "Milestones", Permission: "project:read_own", Response: models.Milestone{}},
+	"PUT /projects/{id}/milestones/{milestone_id}":    {Summary: "Change the title, description or due date of a milestone (managers only)", Tag: "Milestones", Permission: "project:update_own", Request: models.UpdateMilestoneRequest{}, Response: models.Milestone{}},
+	"DELETE /projects/{id}/milestones/{milestone_id}": {Summary: "Delete a milestone; its tasks stay in the project (managers only)", Tag: "Milestones", Permission: "project:update_own", ResponseStatus: http.StatusNoContent},
+
+	"POST /projects/{id}/sprints": {Summary: "Create a sprint in a project (managers only)", Tag: "Sprints", Permission: "project:update_own", Request: models.CreateSprintRequest{}, Response: models.Sprint{}, ResponseStatus: http.StatusCreated},
+	"GET /projects/{id}/sprints": {Summary: "List a project's sprints by start date", Tag: "Sprints", Permission: "project:read_own", Response: models.SprintListResponse{},
+		Query: listQuery([]openapi.Param{{Name: "closed", Type: "boolean"}}, []string{"start", "end"}, "start_date", "end_date", "created_at", "name")},
+	"GET /projects/{id}/sprints/{sprint_id}":         {Summary: "Get a sprint", Tag: "Sprints", Permission: "project:read_own", Response: models.Sprint{}},
+	"PUT /projects/{id}/sprints/{sprint_id}":         {Summary: "Change the name, goal or dates of an open sprint (managers only)", Tag: "Sprints", Permission: "project:update_own", Request: models.UpdateSprintRequest{}, Response: models.Sprint{}},
+	"DELETE /projects/{id}/sprints/{sprint_id}":      {Summary: "Delete a sprint; its tasks go back to the backlog (managers only)", Tag: "Sprints", Permission: "project:update_own", ResponseStatus: http.StatusNoContent},
+	"GET /projects/{id}/sprints/{sprint_id}/summary": {Summary: "Count a sprint's tasks by status and the days elapsed and remaining", Tag: "Sprints", Permission: "project:read_own", Response: models.SprintSummaryResponse{}},
+	"POST /projects/{id}/sprints/{sprint_id}/close": {Summary: "Close a sprint, rolling its unfinished tasks over to next_sprint_id or the next open sprint (managers only)", Tag: "Sprints", Permission: "project:update_own",
+		Request: models.CloseSprintRequest{}, Response: models.Sprint{}},
+	"POST /projects/{id}/sprints/{sprint_id}/tasks":             {Summary: "Plan a task of the project into an open sprint (editors only)", Tag: "Sprints", Permission: "project:read_own", Request: models.SprintTaskRequest{}, Response: models.Task{}},
+	"DELETE /projects/{id}/sprints/{sprint_id}/tasks/{task_id}": {Summary: "Take a task out of an open sprint (editors only)", Tag: "Sprints", Permission: "project:read_own", ResponseStatus: http.StatusNoContent},
+
+	"GET /tasks/{id}/comments": {Summary: "List the comments on a task, oldest first", Tag: "Comments", Permission: "task:read_own", Response: models.CommentListResponse{},
+		Query: listQuery([]openapi.Param{{Name: "author_id"}}, []string{"created"}, "created_at")},
+	"POST /tasks/{id}/comments":                     {Summary: "Comment on a task", Tag: "Comments", Permission: "task:read_own", Request: models.CommentRequest{}, Response: models.Comment{}, ResponseStatus: http.StatusCreated},
+	"PUT /tasks/{id}/comments/{comment_id}":         {Summary: "Edit one of the caller's comments, within the edit window when one is configured; the previous version is kept", Tag: "Comments", Permission: "task:read_own", Request: models.CommentRequest{}, Response: models.Comment{}},
+	"DELETE /tasks/{id}/comments/{comment_id}":      {Summary: "Delete a comment (its author, or with task:update_all)", Tag: "Comments", Permission: "task:read_own", ResponseStatus: http.StatusNoContent},
+	"GET /tasks/{id}/comments/{comment_id}/history": {Summary: "Get a comment and its earlier versions, oldest first", Tag: "Comments", Permission: "task:read_own", Response: models.CommentHistoryResponse{}},
+
+	"GET /search": {Summary: "Search tasks, their comments and, with user:read_all, users; results are grouped and paginated by type", Tag: "Search", Permission: "task:read_own", Response: models.SearchResponse{},
+		Query: []openapi.Param{
+			{Name: "q", Required: true, Description: "Text to find in task titles and descriptions, comment bodies and user names and emails, 2 to 100 characters"},
+			{Name: "types", Description: "Comma-separated types to search: tasks, comments, users (default all the caller may search)"},
+			{Name: "tasks_page", Type: "integer"}, {Name: "tasks_limit", Type: "integer"},
+			{Name: "comments_page", Type: "integer"}, {Name: "comments_limit", Type: "integer"},
+			{Name: "users_page", Type: "integer"}, {Name: "users_limit", Type: "integer"},
+		}},
+
+	"POST /imports/{source}": {Summary: "Create tasks from a Trello board export or Todoist backup sent as the body", Tag: "Imports", Permission: "task:create", Response: models.ImportPreview{}, ResponseStatus: http.StatusCreated,
+		Query: []openapi.Param{{Name: "dry_run", Type: "boolean", Description: "Only return what would be imported (status 200)"}}},
+
+	"GET /notifications": {Summary: "List the caller's in-app notifications", Tag: "Notifications", Permission: "user:update_profile", Response: models.NotificationListResponse{},
+		Query: listQuery([]openapi.Param{{Name: "read", Type: "boolean"}, {Name: "event"}}, []string{"created"}, "created_at")},
+	"POST /notifications/read":      {Summary: "Mark all of the caller's notifications as read", Tag: "Notifications", Permission: "user:update_profile", Response: models.MarkAllReadResponse{}},
+	"POST /notifications/{id}/read": {Summary: "Mark a notification as read", Tag: "Notifications", Permission: "user:update_profile", Response: models.Notification{}},
+	"GET /notifications/preferences": {Summary: "Get the channels the caller receives each event on (email, in_app, push, webhook)", Tag: "Notifications", Permission: "user:update_profile",
+		Response: models.NotificationPreferencesResponse{}},
+	"PUT /notifications/preferences": {Summary: "Choose the channels of events, the webhook URL (requests are signed with the returned secret in X-TaskFlow-Signature) and push device tokens",
+		Tag: "Notifications", Permission: "user:update_profile", Request: models.UpdateNotificationPreferencesRequest{}, Response: models.NotificationPreferencesResponse{}},
+
+	"GET /dashboard/metrics": {Summary: "Get dashboard metrics", Tag: "Dashboard", Permission: "dashboard:read_metrics", Response: models.DashboardMetricsResponse{},
+		Query: []openapi.Param{{Name: "period", Description: "daily, weekly, monthly or custom"}, {Name: "start_date"}, {Name: "end_date"}, periodTZParam}},
+	"GET /dashboard/leaderboard": {Summary: "Rank users by tasks completed in a period", Tag: "Dashboard", Permission: "dashboard:read_leaderboard", Response: models.LeaderboardResponse{},
+		Query: []openapi.Param{{Name: "period", Description: "daily, weekly, monthly or custom"}, {Name: "start_date"}, {Name: "end_date"}, periodTZParam, {Name: "page", Type: "integer"}, {Name: "limit", Type: "integer"}}},
+	"GET /dashboard/me": {Summary: "Get statistics about the caller's own tasks", Tag: "Dashboard", Permission: "dashboard:read_own", Response: models.MyDashboardResponse{},
+		Query: []openapi.Param{{Name: "tz", Description: "IANA time zone days are counted in, e.g. Europe/Paris (default the caller's time_zone, or UTC)"}}},
+	"GET /dashboard/activity": {Summary: "Count tasks created and completed per day over the past year", Tag: "Dashboard", Permission: "dashboard:read_own", Response: models.ActivityHeatmapResponse{},
+		Query: []openapi.Param{
+			{Name: "scope", Description: "user (default) or workspace; workspace requires dashboard:read_metrics"},
+			{Name: "user_id", Description: "User whose tasks to count (default the caller); another user requires dashboard:read_metrics"},
+			{Name: "tz", Description: "IANA time zone days are counted in, e.g. Europe/Paris (default the caller's time_zone, or UTC)"},
+		}},
+
+	"GET /audit": {Summary: "List audit log entries for mutating requests", Tag: "Audit", Permission: "audit:read", Response: models.AuditLogListResponse{},
+		Query: listQuery([]openapi.Param{{Name: "actor_id"}, {Name: "target_id"}, {Name: "method"}, {Name: "route", Description: "Route template, e.g. /api/v1/tasks/{id}"}, {Name: "status", Type: "integer"}}, []string{"created"}, "created_at", "status", "duration_ms")},
+
+	"GET /ip-blocks": {Summary: "List addresses with recent failed authentication attempts and their bans", Tag: "Security", Permission: "ip_block:manage", Response: models.IPBlockListResponse{},
+		Query: listQuery([]openapi.Param{{Name: "ip"}}, []string{"banned_until", "last_failure"}, "last_failure_at", "banned_until", "failures", "bans")},
+	"DELETE /ip-blocks/{ip}": {Summary: "Lift the ban of an address and forget its failed attempts", Tag: "Security", Permission: "ip_block:manage", ResponseStatus: http.StatusNoContent},
+
+	"POST /config/reload": {Summary: "Reload the settings that apply without a restart, as sending the server SIGHUP does", Tag: "Admin", Permission: "config:reload", Response: models.ConfigReloadResponse{}},
+	"GET /log-level":      {Summary: "Get the log level of the server handling the request", Tag: "Admin", Permission: "log_level:manage", Response: models.LogLevel{}},
+	"PUT /log-level":      {Summary: "Change the log level of the server handling the request until it restarts; debug also logs every request", Tag: "Admin", Permission: "log_level:manage", Request: models.LogLevel{}, Response: models.LogLevel{}},
+
+	"GET /email-templates":                 {Summary: "List email templates and whether each is customised", Tag: "Email", Permission: "email_template:manage", Response: models.EmailTemplateListResponse{}},
+	"GET /email-templates/{name}":          {Summary: "Get the template currently used for an email", Tag: "Email", Permission: "email_template:manage", Response: models.EmailTemplate{}},
+	"PUT /email-templates/{name}":          {Summary: "Customise an email template", Tag: "Email", Permission: "email_template:manage", Request: models.UpdateEmailTemplateRequest{}, Response: models.EmailTemplate{}},
+	"DELETE /email-templates/{name}":       {Summary: "Restore the built-in email template", Tag: "Email", Permission: "email_template:manage", ResponseStatus: http.StatusNoContent},
+	"POST /email-templates/{name}/preview": {Summary: "Render an email template with sample data", Tag: "Email", Permission: "email_template:manage", Request: models.PreviewEmailTemplateRequest{}, Response: models.EmailTemplatePreview{}},
+	"POST /email-templates/{name}/test":    {Summary: "Send an email template with sample data to an address, to check the email provider", Tag: "Email", Permission: "email_template:manage", Request: models.SendTestEmailRequest{}, Response: models.TestEmailResult{}},
+	"GET /email-deliveries": {Summary: "Search the log of email send attempts", Tag: "Email", Permission: "email_delivery:read", Response: models.EmailDeliveryListResponse{},
+		Query: listQuery([]openapi.Param{{Name: "recipient", Description: "Case-insensitive substring of the recipient address"}, {Name: "template"}, {Name: "status", Description: "sent or failed"}, {Name: "job_id"}}, []string{"created"}, "created_at", "recipient", "status")},
+
+	"GET /report-schedules":         {Summary: "List scheduled dashboard report emails", Tag: "Reports", Permission: "report:manage", Response: models.ReportScheduleListResponse{}},
+	"POST /report-schedules":        {Summary: "Schedule a daily, weekly or monthly dashboard report email", Tag: "Reports", Permission: "report:manage", Request: models.ReportScheduleRequest{}, Response: models.ReportSchedule{}, ResponseStatus: http.StatusCreated},
+	"GET /report-schedules/{id}":    {Summary: "Get a report schedule", Tag: "Reports", Permission: "report:manage", Response: models.ReportSchedule{}},
+	"PUT /report-schedules/{id}":    {Summary: "Replace the settings of a report schedule", Tag: "Reports", Permission: "report:manage", Request: models.ReportScheduleRequest{}, Response: models.ReportSchedule{}},
+	"DELETE /report-schedules/{id}": {Summary: "Delete a report schedule", Tag: "Reports", Permission: "report:manage", ResponseStatus: http.StatusNoContent},
+	"GET /sla-rules":                {Summary: "List the task SLA rules", Tag: "SLA", Permission: "sla:manage", Response: models.SLARuleListResponse{}},
+	"POST /sla-rules":               {Summary: "Create an SLA rule limiting how long tasks may stay in a status", Tag: "SLA", Permission: "sla:manage", Request: models.SLARuleRequest{}, Response: models.SLARule{}, ResponseStatus: http.StatusCreated},
+	"GET /sla-rules/{id}":           {Summary: "Get an SLA rule", Tag: "SLA", Permission: "sla:manage", Response: models.SLARule{}},
+	"PUT /sla-rules/{id}":           {Summary: "Replace the settings of an SLA rule", Tag: "SLA", Permission: "sla:manage", Request: models.SLARuleRequest{}, Response: models.SLARule{}},
+	"DELETE /sla-rules/{id}":        {Summary: "Delete an SLA rule; flagged tasks stay flagged until they change status", Tag: "SLA", Permission: "sla:manage", ResponseStatus: http.StatusNoContent},
+
+	"GET /announcements/active": {Summary: "List the announcements to show now, most severe first; no token needed", Tag: "Announcements", Public: true, Response: models.ActiveAnnouncementsResponse{}},
+	"GET /announcements": {Summary: "List announcements, past and future included", Tag: "Announcements", Permission: "announcement:manage", Response: models.AnnouncementListResponse{},
+		Query: listQuery([]openapi.Param{{Name: "severity"}}, []string{"starts", "ends", "created"}, "starts_at", "ends_at", "created_at")},
+	"POST /announcements":        {Summary: "Publish a banner announcement, shown from starts_at (default now) until ends_at or until deleted", Tag: "Announcements", Permission: "announcement:manage", Request: models.CreateAnnouncementRequest{}, Response: models.Announcement{}, ResponseStatus: http.StatusCreated},
+	"GET /announcements/{id}":    {Summary: "Get an announcement", Tag: "Announcements", Permission: "announcement:manage", Response: models.Announcement{}},
+	"PUT /announcements/{id}":    {Summary: "Change the message, severity or times of an announcement", Tag: "Announcements", Permission: "announcement:manage", Request: models.UpdateAnnouncementRequest{}, Response: models.Announcement{}},
+	"DELETE /announcements/{id}": {Summary: "Delete an announcement", Tag: "Announcements", Permission: "announcement:manage", ResponseStatus: http.StatusNoContent},
+
+	"GET /export": {Summary: "Stream every role, user, task and comment as newline-delimited JSON records (application/x-ndjson), for backups and migrations. " +
+		"The first record has type \"export\" and the last \"end\" with the record counts; password hashes are not included and personal data is decrypted.",
+		Tag: "Admin", Permission: "data:export", Response: models.ExportRecord{}},
+
+	"GET /integrations/google-calendar":          {Summary: "Get the caller's Google Calendar connection and sync status", Tag: "Integrations", Permission: "user:update_profile", Response: models.CalendarConnection{}},
+	"DELETE /integrations/google-calendar":       {Summary: "Stop syncing the caller's Google Calendar; existing events are kept", Tag: "Integrations", Permission: "user:update_profile", ResponseStatus: http.StatusNoContent},
+	"POST /integrations/google-calendar/connect": {Summary: "Start connecting Google Calendar: returns the consent page to send the user to", Tag: "Integrations", Permission: "user:update_profile", Response: models.CalendarConnectResponse{}},
+	"GET /integrations/google-calendar/callback": {Summary: "OAuth redirect target of the Google consent page; redirects to the frontend when configured", Tag: "Integrations", Public: true, Response: MessageResponse{},
+		Query: []openapi.Param{{Name: "state", Required: true}, {Name: "code", Required: true}, {Name: "error", Description: "Set by Google when consent was refused"}}},
+
+	"POST /webhooks/inbound-email": {Summary: "Create a task from an inbound email (SendGrid/Mailgun inbound parse) whose sender passed SPF or DKIM", Tag: "Webhooks", Public: true, Response: models.Task{}, ResponseStatus: http.StatusCreated,
+		Query: []openapi.Param{{Name: "token", Required: true, Description: "Shared webhook secret"}}},
+
+	"POST /upload":                  {Summary: "Upload a file (multipart field \"file\", optionally linked with resource_type and resource_id fields)", Tag: "Uploads", Permission: "user:update_profile", Response: models.UploadResponse{}},
+	"POST /upload/signature":        {Summary: "Sign a direct upload to the storage backend (Cloudinary only)", Tag: "Uploads", Permission: "user:update_profile", Response: models.DirectUploadSignature{}},
+	"DELETE /upload/{public_id:.+}": {Summary: "Delete an upload and its thumbnails, clearing profile pictures that use it", Tag: "Uploads", Permission: "user:update_profile", ResponseStatus: http.StatusNoContent},
+	"GET /uploads/mine": {Summary: "List the current user's uploads", Tag: "Uploads", Permission: "user:update_profile", Response: models.UploadListResponse{},
+		Query: listQuery([]openapi.Param{{Name: "resource_type", Description: "user or task"}, {Name: "resource_id"}}, []string{"created"}, "created_at", "size")},
+	"GET /uploads/usage":    {Summary: "Get the current user's storage usage and remaining quota", Tag: "Uploads", Permission: "user:update_profile", Response: models.UploadUsage{}},
+	"POST /upload/callback": {Summary: "Confirm a direct upload with the storage backend's response", Tag: "Uploads", Permission: "user:update_profile", Request: models.ConfirmDirectUploadRequest{}, Response: models.UploadResponse{}},
+}
+
+// swaggerUIPage renders Swagger UI from the public CDN, pointed at the generated spec
+const swaggerUIPage = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="UTF-8">
+  <title>TaskFlow API Docs</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.onload = function () {
+      SwaggerUIBundle({ url: "/docs/openapi.json", dom_id: "#swagger-ui" });
+    };
+  </script>
+</body>
+</html>`
+
+// setupDocsRoutes serves the OpenAPI document and Swagger UI. The document is generated
+// lazily on first request by walking the router, so every registered route is included.
+func setupDocsRoutes(router *mux.Router) {
+	var (
+		once sync.Once
+		spec map[string]interface{}
+	)
+
+	router.HandleFunc("/docs/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() { spec = buildOpenAPISpec(router) })
+		utils.RespondWithJSON(w, http.StatusOK, spec)
+	}).Methods("GET")
+
+	router.HandleFunc("/docs", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(swaggerUIPage))
+	}).Methods("GET")
+}
+
+// buildOpenAPISpec collects the versioned API routes and documents them
+func buildOpenAPISpec(router *mux.Router) map[string]interface{} {
+	generator := openapi.NewGenerator("TaskFlow API", "1.0.0", utils.ErrorResponse{})
+	return generator.Build(versionedRoutes(router), lookupRouteDoc)
+}
+
+// NewRequestValidator reflects the documented request schemas of the routes registered on
+// router, for the request validation middleware
+func NewRequestValidator(router *mux.Router) *openapi.RequestValidator {
+	return openapi.NewRequestValidator(versionedRoutes(router), lookupRouteDoc)
+}
+
+// versionedRoutes collects the versioned API routes registered on router
+func versionedRoutes(router *mux.Router) []openapi.Route {
+	var routes []openapi.Route
+	router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil || !strings.HasPrefix(path, "/api/") {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, method := range methods {
+			routes = append(routes, openapi.Route{Method: method, Path: path})
+		}
+		return nil
+	})
+	return routes
+}
+
+// lookupRouteDoc returns the documentation of a versioned route, shared by all API versions
+func lookupRouteDoc(route openapi.Route) (openapi.Operation, bool) {
+	op, ok := routeDocs[route.Method+" "+versionRelativePath(route.Path)]
+	return op, ok
+}
+
+// versionRelativePath strips the /api/<version> prefix from a route path
+func versionRelativePath(path string) string {
+	parts := strings.SplitN(strings.TrimPrefix(path, "/api/"), "/", 2)
+	if len(parts) < 2 {
+		return path
+	}
+	return "/" + parts[1]
+}
@@ -1,56 +1,114 @@
 package api
 
 import (
+	"net/http"
+
 	"github.com/gorilla/mux"
 
 	"github.com/OsGift/taskflow-api/internal/handlers"
 	"github.com/OsGift/taskflow-api/internal/middleware"
+	"github.com/OsGift/taskflow-api/internal/utils"
 )
 
-// SetupRoutes configures all API routes
-func SetupRoutes(
-	router *mux.Router,
-	authMiddleware *middleware.AuthMiddleware,
-	authHandler *handlers.AuthHandler,
-	userHandler *handlers.UserHandler,
-	taskHandler *handlers.TaskHandler,
-	dashboardHandler *handlers.DashboardHandler, // New
-	uploadHandler *handlers.UploadHandler, // New
-) {
-	v1 := router.PathPrefix("/api/v1").Subrouter()
-
-	// Authentication routes (public)
-	v1.HandleFunc("/auth/register", authHandler.RegisterUser).Methods("POST")
-	v1.HandleFunc("/auth/login", authHandler.LoginUser).Methods("POST")
-	v1.HandleFunc("/auth/forgot_password", authHandler.ForgotPassword).Methods("POST")
-	v1.HandleFunc("/auth/reset_password", authHandler.ResetPassword).Methods("POST")
-	// This endpoint is for logged-in users to verify their email, using a token from email
-	v1.HandleFunc("/auth/verify_email", authMiddleware.JWTAuth(authHandler.VerifyEmail, "")).Methods("POST")
-	// For admins who log in with a temporary password to set a permanent one
-	v1.HandleFunc("/auth/change_temp_password", authMiddleware.JWTAuth(authHandler.ChangeTemporaryPassword, "")).Methods("POST")
-
-	// User routes (protected)
-	// Admin can create another admin user
-	v1.HandleFunc("/users/admin", authMiddleware.JWTAuth(userHandler.CreateAdminUser, "user:create_admin")).Methods("POST")
-	// Get user by ID (own profile or any if admin)
-	v1.HandleFunc("/users/{id}", authMiddleware.JWTAuth(userHandler.GetUserByID, "user:read_own")).Methods("GET")
-	// Update user role (admin only)
-	v1.HandleFunc("/users/{id}/role", authMiddleware.JWTAuth(userHandler.UpdateUserRole, "user:update_role")).Methods("PUT")
-	// Update user profile (own profile or any if admin with permission)
-	v1.HandleFunc("/users/{id}/profile", authMiddleware.JWTAuth(userHandler.UpdateUserProfile, "user:update_profile")).Methods("PUT")
-	// List all users (admin only, with pagination/filters)
-	v1.HandleFunc("/users", authMiddleware.JWTAuth(userHandler.ListUsers, "user:read_all")).Methods("GET")
-
-	// Task routes (protected)
-	v1.HandleFunc("/tasks", authMiddleware.JWTAuth(taskHandler.CreateTask, "task:create")).Methods("POST")
-	v1.HandleFunc("/tasks", authMiddleware.JWTAuth(taskHandler.GetTasks, "task:read_own")).Methods("GET")
-	v1.HandleFunc("/tasks/{id}", authMiddleware.JWTAuth(taskHandler.GetTaskByID, "task:read_own")).Methods("GET")
-	v1.HandleFunc("/tasks/{id}", authMiddleware.JWTAuth(taskHandler.UpdateTask, "task:update_own")).Methods("PUT")
-	v1.HandleFunc("/tasks/{id}", authMiddleware.JWTAuth(taskHandler.DeleteTask, "task:delete_own")).Methods("DELETE")
-
-	// Dashboard routes (protected, typically admin/manager access)
-	v1.HandleFunc("/dashboard/metrics", authMiddleware.JWTAuth(dashboardHandler.GetDashboardMetrics, "dashboard:read_metrics")).Methods("GET")
-
-	// File Uploads (protected)
-	v1.HandleFunc("/upload", authMiddleware.JWTAuth(uploadHandler.UploadFile, "user:update_profile")).Methods("POST") // Example: only users who can update profiles can upload
+// Handlers bundles every HTTP handler that versioned route sets can wire up
+type Handlers struct {
+	Auth           *handlers.AuthHandler
+	User           *handlers.UserHandler
+	ServiceAccount *handlers.ServiceAccountHandler
+	Task           *handlers.TaskHandler
+	Project        *handlers.ProjectHandler
+	Milestone      *handlers.MilestoneHandler
+	Sprint         *handlers.SprintHandler
+	Dashboard      *handlers.DashboardHandler
+	Upload         *handlers.UploadHandler
+	InboundEmail   *handlers.InboundEmailHandler
+	Audit          *handlers.AuditHandler
+	IPBlock        *handlers.IPBlockHandler
+	Config         *handlers.ConfigHandler
+	EmailTemplate  *handlers.EmailTemplateHandler
+	EmailDelivery  *handlers.EmailDeliveryHandler
+	ReportSchedule *handlers.ReportScheduleHandler
+	SLARule        *handlers.SLARuleHandler
+	Announcement   *handlers.AnnouncementHandler
+	Comment        *handlers.CommentHandler
+	Search         *handlers.SearchHandler
+	Notification   *handlers.NotificationHandler
+	Export         *handlers.ExportHandler
+	Import         *handlers.ImportHandler
+	Calendar       *handlers.CalendarHandler
+	Files          *handlers.FileHandler // Only set when uploads are stored on local disk
+	Health         *handlers.HealthHandler
+}
+
+// Middlewares bundles the per-route middleware shared by all API versions
+type Middlewares struct {
+	Auth        *middleware.AuthMiddleware
+	Idempotency *middleware.IdempotencyMiddleware
+	CSRF        *middleware.CSRFMiddleware
+}
+
+// apiVersion describes one mounted API version
+type apiVersion struct {
+	name     string                                   // Path segment, e.g. "v1"
+	register func(*mux.Router, Middlewares, Handlers) // Wires the version's routes
+	policy   middleware.DeprecationPolicy             // Lifecycle headers sent with every response
+}
+
+// versionInfo is the public description of an API version returned by GET /api/versions
+type versionInfo struct {
+	Version    string `json:"version"`
+	Prefix     string `json:"prefix"`
+	Deprecated bool   `json:"deprecated"`
+	Sunset     string `json:"sunset,omitempty"`
+	Successor  string `json:"successor,omitempty"`
+}
+
+// SetupRoutes configures all API routes.
+// Each API version lives under /api/<version> with its own route wiring, so a new
+// version can coexist with older ones while they are being sunset. policies maps a
+// version name to its deprecation policy; versions without an entry are current.
+func SetupRoutes(router *mux.Router, mw Middlewares, h Handlers, policies map[string]middleware.DeprecationPolicy) {
+	versions := []apiVersion{
+		{name: "v1", register: registerV1Routes},
+		{name: "v2", register: registerV2Routes},
+	}
+
+	infos := make([]versionInfo, 0, len(versions))
+	for _, version := range versions {
+		version.policy = policies[version.name]
+		prefix := "/api/" + version.name
+
+		sub := router.PathPrefix(prefix).Subrouter()
+		if version.policy.Deprecated {
+			sub.Use(middleware.DeprecationHeaders(version.policy))
+		}
+		version.register(sub, mw, h)
+
+		info := versionInfo{
+			Version:    version.name,
+			Prefix:     prefix,
+			Deprecated: version.policy.Deprecated,
+			Successor:  version.policy.Successor,
+		}
+		if !version.policy.SunsetAt.IsZero() {
+			info.Sunset = version.policy.SunsetAt.UTC().Format("2006-01-02")
+		}
+		infos = append(infos, info)
+	}
+
+	// API documentation (public)
+	setupDocsRoutes(router)
+
+	// Locally stored uploads (public, so they can be embedded like any other image URL)
+	if h.Files != nil {
+		router.HandleFunc("/files/{path:.+}", h.Files.ServeFile).Methods("GET", "HEAD")
+	}
+
+	// Version discovery (public)
+	router.HandleFunc("/api/versions", func(w http.ResponseWriter, r *http.Request) {
+		utils.RespondWithJSON(w, http.StatusOK, map[string]interface{}{"versions": infos})
+	}).Methods("GET")
+
+	// Readiness probe (public): 503 while a dependency check fails
+	router.HandleFunc("/readyz", h.Health.Ready).Methods("GET")
 }
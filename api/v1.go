@@ -0,0 +1,204 @@
+package api
+
+import (
+	"github.com/gorilla/mux"
+)
+
+// registerV1Routes wires the /api/v1 routes
+func registerV1Routes(v1 *mux.Router, mw Middlewares, h Handlers) {
+	authMiddleware := mw.Auth
+
+	// Authentication routes (public)
+	// Registration honours Idempotency-Key so client retries can't create duplicate accounts
+	v1.HandleFunc("/auth/register", mw.Idempotency.Wrap(h.Auth.RegisterUser)).Methods("POST")
+	v1.HandleFunc("/auth/login", h.Auth.LoginUser).Methods("POST")
+	v1.HandleFunc("/auth/forgot_password", h.Auth.ForgotPassword).Methods("POST")
+	v1.HandleFunc("/auth/reset_password", h.Auth.ResetPassword).Methods("POST")
+	// Cookie sessions: CSRF token, refresh and logout
+	v1.HandleFunc("/auth/csrf", mw.CSRF.IssueToken).Methods("GET")
+	v1.HandleFunc("/auth/refresh", h.Auth.RefreshSession).Methods("POST")
+	v1.HandleFunc("/auth/logout", h.Auth.Logout).Methods("POST")
+	// This endpoint is for logged-in users to verify their email, using a token from email
+	v1.HandleFunc("/auth/verify_email", authMiddleware.JWTAuth(h.Auth.VerifyEmail, "")).Methods("POST")
+	// For admins who log in with a temporary password to set a permanent one
+	v1.HandleFunc("/auth/change_temp_password", authMiddleware.JWTAuth(h.Auth.ChangeTemporaryPassword, "")).Methods("POST")
+
+	// User routes (protected)
+	// Admin can create another admin user
+	v1.HandleFunc("/users/admin", authMiddleware.JWTAuth(h.User.CreateAdminUser, "user:create_admin")).Methods("POST")
+	// Get user by ID (own profile or any if admin)
+	v1.HandleFunc("/users/{id}", authMiddleware.JWTAuth(h.User.GetUserByID, "user:read_own")).Methods("GET")
+	// Update user role (admin only)
+	v1.HandleFunc("/users/{id}/role", authMiddleware.JWTAuth(h.User.UpdateUserRole, "user:update_role")).Methods("PUT")
+	// Update user profile (own profile or any if admin with permission)
+	v1.HandleFunc("/users/{id}/profile", authMiddleware.JWTAuth(h.User.UpdateUserProfile, "user:update_profile")).Methods("PUT")
+	// Delete a user, deleting or reassigning their tasks (admin only)
+	v1.HandleFunc("/users/{id}", authMiddleware.JWTAuth(h.User.DeleteUser, "user:delete")).Methods("DELETE")
+	// Merge a duplicate account into a user, disabling the duplicate (admin only)
+	v1.HandleFunc("/users/{id}/merge", authMiddleware.JWTAuth(h.User.MergeUsers, "user:merge")).Methods("POST")
+	// List all users (admin only, with pagination/filters)
+	v1.HandleFunc("/users", authMiddleware.JWTAuth(h.User.ListUsers, "user:read_all")).Methods("GET")
+
+	// Service accounts for machine integrations, which authenticate with API keys (admin only)
+	v1.HandleFunc("/service-accounts", authMiddleware.JWTAuth(h.ServiceAccount.ListServiceAccounts, "service_account:manage")).Methods("GET")
+	v1.HandleFunc("/service-accounts", authMiddleware.JWTAuth(h.ServiceAccount.CreateServiceAccount, "service_account:manage")).Methods("POST")
+	v1.HandleFunc("/service-accounts/{id}", authMiddleware.JWTAuth(h.ServiceAccount.DeleteServiceAccount, "service_account:manage")).Methods("DELETE")
+	v1.HandleFunc("/service-accounts/{id}/keys", authMiddleware.JWTAuth(h.ServiceAccount.ListKeys, "service_account:manage")).Methods("GET")
+	v1.HandleFunc("/service-accounts/{id}/keys", authMiddleware.JWTAuth(h.ServiceAccount.CreateKey, "service_account:manage")).Methods("POST")
+	v1.HandleFunc("/service-accounts/{id}/keys/{key_id}", authMiddleware.JWTAuth(h.ServiceAccount.UpdateKey, "service_account:manage")).Methods("PUT")
+	v1.HandleFunc("/service-accounts/{id}/keys/{key_id}", authMiddleware.JWTAuth(h.ServiceAccount.RevokeKey, "service_account:manage")).Methods("DELETE")
+	v1.HandleFunc("/service-accounts/{id}/keys/{key_id}/usage", authMiddleware.JWTAuth(h.ServiceAccount.GetKeyUsage, "service_account:manage")).Methods("GET")
+	// Usage of the API key the request is made with (service accounts only)
+	v1.HandleFunc("/api-keys/current/usage", authMiddleware.JWTAuth(h.ServiceAccount.GetCurrentKeyUsage, "")).Methods("GET")
+
+	// Task routes (protected)
+	v1.HandleFunc("/tasks", authMiddleware.JWTAuth(mw.Idempotency.Wrap(h.Task.CreateTask), "task:create")).Methods("POST")
+	v1.HandleFunc("/tasks", authMiddleware.JWTAuth(h.Task.GetTasks, "task:read_own")).Methods("GET")
+	// Registered before /tasks/{id} so "quick", "suggest" and "export" aren't taken for task IDs
+	v1.HandleFunc("/tasks/quick", authMiddleware.JWTAuth(mw.Idempotency.Wrap(h.Task.QuickAddTask), "task:create")).Methods("POST")
+	v1.HandleFunc("/tasks/suggest", authMiddleware.JWTAuth(h.Search.Suggest, "task:read_own")).Methods("GET")
+	v1.HandleFunc("/tasks/export", authMiddleware.JWTAuth(h.Export.ExportMyTasks, "task:read_own")).Methods("GET")
+	v1.HandleFunc("/tasks/{id}", authMiddleware.JWTAuth(h.Task.GetTaskByID, "task:read_own")).Methods("GET")
+	v1.HandleFunc("/tasks/{id}", authMiddleware.JWTAuth(h.Task.UpdateTask, "task:update_own")).Methods("PUT")
+	v1.HandleFunc("/tasks/{id}", authMiddleware.JWTAuth(h.Task.DeleteTask, "task:delete_own")).Methods("DELETE")
+	// Pins are per user; the caller's pinned tasks can be listed first with sort=-pinned
+	v1.HandleFunc("/tasks/{id}/pin", authMiddleware.JWTAuth(h.Task.PinTask, "task:read_own")).Methods("POST")
+	v1.HandleFunc("/tasks/{id}/unpin", authMiddleware.JWTAuth(h.Task.UnpinTask, "task:read_own")).Methods("POST")
+	// Clears the task's has_unread_updates flag for the caller
+	v1.HandleFunc("/tasks/{id}/seen", authMiddleware.JWTAuth(h.Task.MarkTaskSeen, "task:read_own")).Methods("POST")
+	// Merge a duplicate task into a task, closing the duplicate
+	v1.HandleFunc("/tasks/{id}/merge", authMiddleware.JWTAuth(h.Task.MergeTasks, "task:update_own")).Methods("POST")
+	// Attach one of the caller's uploads to a task
+	v1.HandleFunc("/tasks/{id}/attachments/link", authMiddleware.JWTAuth(h.Task.LinkAttachment, "task:update_own")).Methods("POST")
+	// Printable PDF of a task with its comments and activity
+	v1.HandleFunc("/tasks/{id}/export", authMiddleware.JWTAuth(h.Export.ExportTaskPDF, "task:read_own")).Methods("GET")
+
+	// Project routes (protected)
+	v1.HandleFunc("/projects", authMiddleware.JWTAuth(h.Project.CreateProject, "project:create")).Methods("POST")
+	v1.HandleFunc("/projects", authMiddleware.JWTAuth(h.Project.ListProjects, "project:read_own")).Methods("GET")
+	v1.HandleFunc("/projects/{id}", authMiddleware.JWTAuth(h.Project.GetProject, "project:read_own")).Methods("GET")
+	v1.HandleFunc("/projects/{id}", authMiddleware.JWTAuth(h.Project.UpdateProject, "project:update_own")).Methods("PUT")
+	v1.HandleFunc("/projects/{id}", authMiddleware.JWTAuth(h.Project.DeleteProject, "project:delete_own")).Methods("DELETE")
+	v1.HandleFunc("/projects/{id}/metrics", authMiddleware.JWTAuth(h.Project.GetProjectMetrics, "project:read_own")).Methods("GET")
+	v1.HandleFunc("/projects/{id}/burndown", authMiddleware.JWTAuth(h.Project.GetBurndown, "project:read_own")).Methods("GET")
+	// Archived projects are kept but accept no new tasks, and their tasks are hidden from listings
+	v1.HandleFunc("/projects/{id}/archive", authMiddleware.JWTAuth(h.Project.ArchiveProject, "project:update_own")).Methods("POST")
+	v1.HandleFunc("/projects/{id}/restore", authMiddleware.JWTAuth(h.Project.RestoreProject, "project:update_own")).Methods("POST")
+	// Project members, managed by the project's managers; members can remove themselves
+	v1.HandleFunc("/projects/{id}/members", authMiddleware.JWTAuth(h.Project.AddMember, "project:update_own")).Methods("POST")
+	v1.HandleFunc("/projects/{id}/members/{user_id}", authMiddleware.JWTAuth(h.Project.UpdateMember, "project:update_own")).Methods("PUT")
+	v1.HandleFunc("/projects/{id}/members/{user_id}", authMiddleware.JWTAuth(h.Project.RemoveMember, "project:read_own")).Methods("DELETE")
+	// Milestones, managed by the project's managers; their progress comes from the attached tasks
+	v1.HandleFunc("/projects/{id}/milestones", authMiddleware.JWTAuth(h.Milestone.CreateMilestone, "project:update_own")).Methods("POST")
+	v1.HandleFunc("/projects/{id}/milestones", authMiddleware.JWTAuth(h.Milestone.ListMilestones, "project:read_own")).Methods("GET")
+	v1.HandleFunc("/projects/{id}/milestones/{milestone_id}", authMiddleware.JWTAuth(h.Milestone.GetMilestone, "project:read_own")).Methods("GET")
+	v1.HandleFunc("/projects/{id}/milestones/{milestone_id}", authMiddleware.JWTAuth(h.Milestone.UpdateMilestone, "project:update_own")).Methods("PUT")
+	v1.HandleFunc("/projects/{id}/milestones/{milestone_id}", authMiddleware.JWTAuth(h.Milestone.DeleteMilestone, "project:update_own")).Methods("DELETE")
+	// Sprints, managed by the project's managers; editors plan tasks into them. Closing a sprint
+	// rolls its unfinished tasks over to the next one.
+	v1.HandleFunc("/projects/{id}/sprints", authMiddleware.JWTAuth(h.Sprint.CreateSprint, "project:update_own")).Methods("POST")
+	v1.HandleFunc("/projects/{id}/sprints", authMiddleware.JWTAuth(h.Sprint.ListSprints, "project:read_own")).Methods("GET")
+	v1.HandleFunc("/projects/{id}/sprints/{sprint_id}", authMiddleware.JWTAuth(h.Sprint.GetSprint, "project:read_own")).Methods("GET")
+	v1.HandleFunc("/projects/{id}/sprints/{sprint_id}", authMiddleware.JWTAuth(h.Sprint.UpdateSprint, "project:update_own")).Methods("PUT")
+	v1.HandleFunc("/projects/{id}/sprints/{sprint_id}", authMiddleware.JWTAuth(h.Sprint.DeleteSprint, "project:update_own")).Methods("DELETE")
+	v1.HandleFunc("/projects/{id}/sprints/{sprint_id}/summary", authMiddleware.JWTAuth(h.Sprint.GetSprintSummary, "project:read_own")).Methods("GET")
+	v1.HandleFunc("/projects/{id}/sprints/{sprint_id}/close", authMiddleware.JWTAuth(h.Sprint.CloseSprint, "project:update_own")).Methods("POST")
+	v1.HandleFunc("/projects/{id}/sprints/{sprint_id}/tasks", authMiddleware.JWTAuth(h.Sprint.AddTask, "project:read_own")).Methods("POST")
+	v1.HandleFunc("/projects/{id}/sprints/{sprint_id}/tasks/{task_id}", authMiddleware.JWTAuth(h.Sprint.RemoveTask, "project:read_own")).Methods("DELETE")
+
+	// Comments on tasks, for anyone who can view the task; authors can edit theirs
+	v1.HandleFunc("/tasks/{id}/comments", authMiddleware.JWTAuth(h.Comment.ListComments, "task:read_own")).Methods("GET")
+	v1.HandleFunc("/tasks/{id}/comments", authMiddleware.JWTAuth(h.Comment.CreateComment, "task:read_own")).Methods("POST")
+	v1.HandleFunc("/tasks/{id}/comments/{comment_id}", authMiddleware.JWTAuth(h.Comment.UpdateComment, "task:read_own")).Methods("PUT")
+	v1.HandleFunc("/tasks/{id}/comments/{comment_id}", authMiddleware.JWTAuth(h.Comment.DeleteComment, "task:read_own")).Methods("DELETE")
+	v1.HandleFunc("/tasks/{id}/comments/{comment_id}/history", authMiddleware.JWTAuth(h.Comment.GetCommentHistory, "task:read_own")).Methods("GET")
+
+	// Search tasks and, for admins, users in one call
+	v1.HandleFunc("/search", authMiddleware.JWTAuth(h.Search.Search, "task:read_own")).Methods("GET")
+
+	// Import tasks from another task manager's export (source is trello or todoist)
+	v1.HandleFunc("/imports/{source}", authMiddleware.JWTAuth(h.Import.ImportTasks, "task:create")).Methods("POST")
+
+	// The caller's in-app notifications, and the channels they receive each event on
+	v1.HandleFunc("/notifications", authMiddleware.JWTAuth(h.Notification.ListNotifications, "user:update_profile")).Methods("GET")
+	v1.HandleFunc("/notifications/read", authMiddleware.JWTAuth(h.Notification.MarkAllRead, "user:update_profile")).Methods("POST")
+	v1.HandleFunc("/notifications/preferences", authMiddleware.JWTAuth(h.Notification.GetPreferences, "user:update_profile")).Methods("GET")
+	v1.HandleFunc("/notifications/preferences", authMiddleware.JWTAuth(h.Notification.UpdatePreferences, "user:update_profile")).Methods("PUT")
+	v1.HandleFunc("/notifications/{id}/read", authMiddleware.JWTAuth(h.Notification.MarkRead, "user:update_profile")).Methods("POST")
+
+	// Dashboard routes (protected, typically admin/manager access)
+	v1.HandleFunc("/dashboard/metrics", authMiddleware.JWTAuth(h.Dashboard.GetDashboardMetrics, "dashboard:read_metrics")).Methods("GET")
+	v1.HandleFunc("/dashboard/leaderboard", authMiddleware.JWTAuth(h.Dashboard.GetLeaderboard, "dashboard:read_leaderboard")).Methods("GET")
+	v1.HandleFunc("/dashboard/me", authMiddleware.JWTAuth(h.Dashboard.GetMyDashboard, "dashboard:read_own")).Methods("GET")
+	v1.HandleFunc("/dashboard/activity", authMiddleware.JWTAuth(h.Dashboard.GetActivityHeatmap, "dashboard:read_own")).Methods("GET")
+
+	// Audit log of mutating requests (admin only)
+	v1.HandleFunc("/audit", authMiddleware.JWTAuth(h.Audit.ListAuditLogs, "audit:read")).Methods("GET")
+
+	// Addresses banned for failing to authenticate too often (admin only)
+	v1.HandleFunc("/ip-blocks", authMiddleware.JWTAuth(h.IPBlock.ListIPBlocks, "ip_block:manage")).Methods("GET")
+	v1.HandleFunc("/ip-blocks/{ip}", authMiddleware.JWTAuth(h.IPBlock.ClearIPBlock, "ip_block:manage")).Methods("DELETE")
+
+	// Reload the settings that apply without a restart, as SIGHUP does (admin only)
+	v1.HandleFunc("/config/reload", authMiddleware.JWTAuth(h.Config.ReloadConfig, "config:reload")).Methods("POST")
+	// Log level of the server handling the request, to debug production without redeploying (admin only)
+	v1.HandleFunc("/log-level", authMiddleware.JWTAuth(h.Config.GetLogLevel, "log_level:manage")).Methods("GET")
+	v1.HandleFunc("/log-level", authMiddleware.JWTAuth(h.Config.SetLogLevel, "log_level:manage")).Methods("PUT")
+
+	// Customisable email templates (admin only); deleting a template restores the built-in one
+	v1.HandleFunc("/email-templates", authMiddleware.JWTAuth(h.EmailTemplate.ListTemplates, "email_template:manage")).Methods("GET")
+	v1.HandleFunc("/email-templates/{name}", authMiddleware.JWTAuth(h.EmailTemplate.GetTemplate, "email_template:manage")).Methods("GET")
+	v1.HandleFunc("/email-templates/{name}", authMiddleware.JWTAuth(h.EmailTemplate.UpdateTemplate, "email_template:manage")).Methods("PUT")
+	v1.HandleFunc("/email-templates/{name}", authMiddleware.JWTAuth(h.EmailTemplate.ResetTemplate, "email_template:manage")).Methods("DELETE")
+	v1.HandleFunc("/email-templates/{name}/preview", authMiddleware.JWTAuth(h.EmailTemplate.PreviewTemplate, "email_template:manage")).Methods("POST")
+	v1.HandleFunc("/email-templates/{name}/test", authMiddleware.JWTAuth(h.EmailTemplate.SendTestEmail, "email_template:manage")).Methods("POST")
+	// Log of email send attempts, for support (admin only)
+	v1.HandleFunc("/email-deliveries", authMiddleware.JWTAuth(h.EmailDelivery.ListDeliveries, "email_delivery:read")).Methods("GET")
+
+	// Scheduled dashboard report emails (admin only)
+	v1.HandleFunc("/report-schedules", authMiddleware.JWTAuth(h.ReportSchedule.ListSchedules, "report:manage")).Methods("GET")
+	v1.HandleFunc("/report-schedules", authMiddleware.JWTAuth(h.ReportSchedule.CreateSchedule, "report:manage")).Methods("POST")
+	v1.HandleFunc("/report-schedules/{id}", authMiddleware.JWTAuth(h.ReportSchedule.GetSchedule, "report:manage")).Methods("GET")
+	v1.HandleFunc("/report-schedules/{id}", authMiddleware.JWTAuth(h.ReportSchedule.UpdateSchedule, "report:manage")).Methods("PUT")
+	v1.HandleFunc("/report-schedules/{id}", authMiddleware.JWTAuth(h.ReportSchedule.DeleteSchedule, "report:manage")).Methods("DELETE")
+
+	// Task SLA rules (admin only)
+	v1.HandleFunc("/sla-rules", authMiddleware.JWTAuth(h.SLARule.ListRules, "sla:manage")).Methods("GET")
+	v1.HandleFunc("/sla-rules", authMiddleware.JWTAuth(h.SLARule.CreateRule, "sla:manage")).Methods("POST")
+	v1.HandleFunc("/sla-rules/{id}", authMiddleware.JWTAuth(h.SLARule.GetRule, "sla:manage")).Methods("GET")
+	v1.HandleFunc("/sla-rules/{id}", authMiddleware.JWTAuth(h.SLARule.UpdateRule, "sla:manage")).Methods("PUT")
+	v1.HandleFunc("/sla-rules/{id}", authMiddleware.JWTAuth(h.SLARule.DeleteRule, "sla:manage")).Methods("DELETE")
+
+	// Banner announcements, published by admins. The active ones are public so frontends can
+	// show them before login; /announcements/active is registered before /announcements/{id}.
+	v1.HandleFunc("/announcements/active", h.Announcement.GetActiveAnnouncements).Methods("GET")
+	v1.HandleFunc("/announcements", authMiddleware.JWTAuth(h.Announcement.ListAnnouncements, "announcement:manage")).Methods("GET")
+	v1.HandleFunc("/announcements", authMiddleware.JWTAuth(h.Announcement.CreateAnnouncement, "announcement:manage")).Methods("POST")
+	v1.HandleFunc("/announcements/{id}", authMiddleware.JWTAuth(h.Announcement.GetAnnouncement, "announcement:manage")).Methods("GET")
+	v1.HandleFunc("/announcements/{id}", authMiddleware.JWTAuth(h.Announcement.UpdateAnnouncement, "announcement:manage")).Methods("PUT")
+	v1.HandleFunc("/announcements/{id}", authMiddleware.JWTAuth(h.Announcement.DeleteAnnouncement, "announcement:manage")).Methods("DELETE")
+
+	// Full data export as newline-delimited JSON, for backups and migrations (admin only)
+	v1.HandleFunc("/export", authMiddleware.JWTAuth(h.Export.ExportData, "data:export")).Methods("GET")
+
+	// Google Calendar sync of the caller's task due dates. Google redirects the browser to the
+	// callback, which is public and identifies the user by the signed state parameter.
+	v1.HandleFunc("/integrations/google-calendar", authMiddleware.JWTAuth(h.Calendar.GetConnection, "user:update_profile")).Methods("GET")
+	v1.HandleFunc("/integrations/google-calendar", authMiddleware.JWTAuth(h.Calendar.Disconnect, "user:update_profile")).Methods("DELETE")
+	v1.HandleFunc("/integrations/google-calendar/connect", authMiddleware.JWTAuth(h.Calendar.Connect, "user:update_profile")).Methods("POST")
+	v1.HandleFunc("/integrations/google-calendar/callback", h.Calendar.Callback).Methods("GET")
+
+	// Inbound email webhook (public, authenticated by a shared secret in the URL and, for Mailgun, its signature)
+	v1.HandleFunc("/webhooks/inbound-email", h.InboundEmail.ReceiveEmail).Methods("POST")
+
+	// File Uploads (protected)
+	v1.HandleFunc("/upload", authMiddleware.JWTAuth(h.Upload.UploadFile, "user:update_profile")).Methods("POST") // Example: only users who can update profiles can upload
+	// Direct browser-to-storage uploads: sign first, then confirm the backend's response
+	v1.HandleFunc("/upload/signature", authMiddleware.JWTAuth(h.Upload.SignDirectUpload, "user:update_profile")).Methods("POST")
+	v1.HandleFunc("/upload/callback", authMiddleware.JWTAuth(h.Upload.ConfirmDirectUpload, "user:update_profile")).Methods("POST")
+	// Delete an upload (own uploads, or any with 'upload:delete_all'); public IDs contain slashes
+	v1.HandleFunc("/upload/{public_id:.+}", authMiddleware.JWTAuth(h.Upload.DeleteUpload, "user:update_profile")).Methods("DELETE")
+	// The current user's uploads
+	v1.HandleFunc("/uploads/mine", authMiddleware.JWTAuth(h.Upload.ListMyUploads, "user:update_profile")).Methods("GET")
+	v1.HandleFunc("/uploads/usage", authMiddleware.JWTAuth(h.Upload.GetMyUsage, "user:update_profile")).Methods("GET")
+}
@@ -0,0 +1,12 @@
+package api
+
+import (
+	"github.com/gorilla/mux"
+)
+
+// registerV2Routes wires the /api/v2 routes.
+// v2 currently serves the same contract as v1 so clients can start migrating early;
+// breaking changes should be made here (by registering v2-specific handlers) rather than in v1.
+func registerV2Routes(v2 *mux.Router, mw Middlewares, h Handlers) {
+	registerV1Routes(v2, mw, h)
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/OsGift/taskflow-api/internal/config"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+// demoRole is an extra role given to some demo users, so role breakdowns show more than the defaults
+var demoRole = models.Role{
+	Name: "Contractor",
+	Permissions: []models.Permission{
+		{Action: "task:create"}, {Action: "task:read_own"}, {Action: "task:update_own"},
+		{Action: "user:update_profile"}, {Action: "dashboard:read_own"},
+	},
+}
+
+// demoRoleWeights is the share of demo users given each role, out of 100
+var demoRoleWeights = []struct {
+	name   string
+	weight int
+}{
+	{"Admin", 3}, {"Manager", 12}, {"Contractor", 10}, {"User", 75},
+}
+
+var (
+	demoFirstNames = []string{
+		"Amara", "Ben", "Chloe", "Daniel", "Elena", "Farid", "Grace", "Hiro", "Ines", "Jonas",
+		"Kemi", "Liam", "Maya", "Noah", "Olivia", "Priya", "Quentin", "Rosa", "Samuel", "Tara",
+		"Uche", "Vera", "Wei", "Ximena", "Yusuf", "Zoe",
+	}
+	demoLastNames = []string{
+		"Adeyemi", "Bauer", "Costa", "Dubois", "Eriksen", "Fischer", "Garcia", "Haddad", "Ito",
+		"Johnson", "Kowalski", "Lopez", "Müller", "Nakamura", "Okafor", "Patel", "Rossi", "Schmidt",
+		"Tanaka", "Usman", "Varga", "Walker", "Yilmaz", "Zhang",
+	}
+	demoLocales   = []string{"", "", "", "fr", "es", "de"}
+	demoTaskVerbs = []string{
+		"Draft", "Review", "Update", "Fix", "Prepare", "Plan", "Test", "Document", "Migrate",
+		"Refactor", "Schedule", "Publish", "Audit", "Design",
+	}
+	demoTaskObjects = []string{
+		"quarterly report", "onboarding checklist", "login page bug", "release notes",
+		"customer feedback survey", "marketing email", "API rate limits", "invoice template",
+		"sprint retrospective", "database backups", "pricing page", "support macros",
+		"mobile navigation", "security review", "team offsite agenda", "hiring plan",
+	}
+	demoTaskDetails = []string{
+		"Coordinate with the team before the next planning meeting.",
+		"Blocked until the requirements are confirmed.",
+		"See the notes from last week's call.",
+		"Keep it short; a first version is fine.",
+		"",
+	}
+)
+
+// seedDemo fills the database with fake users and tasks spread over past dates and all statuses,
+// so the dashboards and list endpoints have something to show and can be load-tested
+func seedDemo(ctx context.Context, cfg *config.Config, store *repository.Store, args []string) error {
+	fs := flag.NewFlagSet("seed-demo", flag.ContinueOnError)
+	users := fs.Int("users", 50, "number of users to create")
+	tasksPerUser := fs.Int("tasks-per-user", 20, "average number of tasks per user")
+	days := fs.Int("days", 90, "how many days back tasks are spread over")
+	seed := fs.Int64("seed", 1, "random seed; the same seed creates the same data")
+	domain := fs.String("domain", "demo.taskflow.test", "email domain of the demo users")
+	password := fs.String("password", "demo1234", "password of every demo user")
+	allowProduction := fs.Bool("allow-production", false, "seed even though APP_ENV is production")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *users < 1 || *tasksPerUser < 0 || *days < 1 {
+		return errors.New("--users and --days must be positive and --tasks-per-user not negative")
+	}
+	if len(*password) < minPasswordLength {
+		return fmt.Errorf("--password must be at least %d characters", minPasswordLength)
+	}
+	if cfg.IsProduction() && !*allowProduction {
+		return errors.New("refusing to add demo data to a production database; pass --allow-production to do it anyway")
+	}
+
+	if err := repository.SeedDefaultRoles(ctx, store.Roles); err != nil {
+		return fmt.Errorf("failed to seed roles: %w", err)
+	}
+	if _, err := store.Roles.Sync(ctx, demoRole); err != nil {
+		return fmt.Errorf("failed to create role %q: %w", demoRole.Name, err)
+	}
+	roles := map[string]primitive.ObjectID{}
+	for _, weighted := range demoRoleWeights {
+		role, err := store.Roles.FindByName(ctx, weighted.name)
+		if err != nil {
+			return fmt.Errorf("failed to find role %q: %w", weighted.name, err)
+		}
+		roles[weighted.name] = role.ID
+	}
+
+	// Hashing is deliberately slow, and every demo user shares the password anyway
+	hasher, err := cfg.PasswordHasher()
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := hasher.Hash(*password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	now := time.Now().UTC()
+	span := time.Duration(*days) * 24 * time.Hour
+	var createdUsers, skippedUsers, createdTasks int
+	for i := 0; i < *users; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// Each user has their own source, so a rerun skips exactly the users it already created
+		rng := rand.New(rand.NewSource(*seed*1_000_003 + int64(i)))
+
+		firstName := pick(rng, demoFirstNames)
+		lastName := pick(rng, demoLastNames)
+		// Users join before the tasks start, with a few joining during the period
+		createdAt := now.Add(-span - time.Duration(rng.Int63n(int64(30*24*time.Hour))))
+		if rng.Intn(5) == 0 {
+			createdAt = now.Add(-time.Duration(rng.Int63n(int64(span))))
+		}
+		user := &models.User{
+			ID:              primitive.NewObjectID(),
+			FirstName:       firstName,
+			LastName:        lastName,
+			Email:           demoEmail(firstName, lastName, i+1, *domain),
+			Password:        hashedPassword,
+			RoleID:          roles[pickRole(rng)],
+			IsEmailVerified: rng.Intn(10) > 0,
+			Locale:          pick(rng, demoLocales),
+			CreatedAt:       createdAt,
+			UpdatedAt:       createdAt,
+		}
+		// The unique email index may not exist yet on a database the API hasn't started against
+		if _, err := store.Users.FindByEmail(ctx, user.Email); err == nil {
+			// Seeded by an earlier run with the same settings
+			skippedUsers++
+			continue
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("failed to look up user %s: %w", user.Email, err)
+		}
+		if err := store.Users.Create(ctx, user); err != nil {
+			return fmt.Errorf("failed to create user %s: %w", user.Email, err)
+		}
+		createdUsers++
+
+		taskCount := 0
+		if *tasksPerUser > 0 {
+			taskCount = rng.Intn(2**tasksPerUser + 1)
+		}
+		for j := 0; j < taskCount; j++ {
+			task := demoTask(rng, user, now, span)
+			if err := store.Tasks.Create(ctx, task); err != nil {
+				return fmt.Errorf("failed to create task for %s: %w", user.Email, err)
+			}
+			createdTasks++
+		}
+	}
+
+	fmt.Printf("Created %d users and %d tasks", createdUsers, createdTasks)
+	if skippedUsers > 0 {
+		fmt.Printf(" (%d users already existed and were skipped)", skippedUsers)
+	}
+	fmt.Printf("\nDemo users log in with their email and the password %q\n", *password)
+	return nil
+}
+
+// demoTask builds a task for user created within span before now, with a status, due date and
+// timestamps that are consistent with each other
+func demoTask(rng *rand.Rand, user *models.User, now time.Time, span time.Duration) *models.Task {
+	start := now.Add(-span)
+	if user.CreatedAt.After(start) {
+		start = user.CreatedAt
+	}
+	createdAt := start.Add(time.Duration(rng.Int63n(int64(now.Sub(start)) + 1)))
+	// after returns a random time between createdAt and now, at most max later than createdAt
+	after := func(max time.Duration) time.Time {
+		if remaining := now.Sub(createdAt); remaining < max {
+			max = remaining
+		}
+		return createdAt.Add(time.Duration(rng.Int63n(int64(max) + 1)))
+	}
+
+	task := &models.Task{
+		ID:          primitive.NewObjectID(),
+		Title:       pick(rng, demoTaskVerbs) + " " + pick(rng, demoTaskObjects),
+		Description: pick(rng, demoTaskDetails),
+		UserID:      user.ID,
+		CreatedAt:   createdAt,
+	}
+	changedAt := createdAt
+	switch n := rng.Intn(100); {
+	case n < 45:
+		task.Status = models.StatusDone
+		changedAt = after(14 * 24 * time.Hour)
+		task.CompletedAt = &changedAt
+	case n < 70:
+		task.Status = models.StatusInProgress
+		changedAt = after(5 * 24 * time.Hour)
+	default:
+		task.Status = models.StatusTodo
+	}
+	task.StatusChangedAt = &changedAt
+	task.UpdatedAt = changedAt
+
+	if rng.Intn(10) < 7 {
+		due := createdAt.Add(time.Duration(1+rng.Intn(21)) * 24 * time.Hour).Truncate(time.Hour)
+		task.DueDate = &due
+	}
+	return task
+}
+
+// demoEmail builds a unique, stable address for the nth demo user
+func demoEmail(firstName, lastName string, n int, domain string) string {
+	local := strings.ToLower(firstName + "." + lastName)
+	local = strings.NewReplacer("ü", "u", " ", "").Replace(local)
+	return fmt.Sprintf("%s.%d@%s", local, n, domain)
+}
+
+// pickRole chooses a role name according to demoRoleWeights
+func pickRole(rng *rand.Rand) string {
+	n := rng.Intn(100)
+	for _, weighted := range demoRoleWeights {
+		if n < weighted.weight {
+			return weighted.name
+		}
+		n -= weighted.weight
+	}
+	return "User"
+}
+
+// pick returns a random element of values
+func pick(rng *rand.Rand, values []string) string {
+	return values[rng.Intn(len(values))]
+}
@@ -0,0 +1,283 @@
+// Command taskflow-admin performs operator tasks directly against the database, for when
+// nobody can use the API yet: creating the first administrator, resetting a password,
+// re-running the default role seeding, re-encrypting personal data after a data key rotation
+// and filling a database with demo data.
+//
+// Usage:
+//
+//	taskflow-admin [configuration flags] <command> [command flags]
+//
+// Configuration is read as by the API server (defaults, --config, the environment and flags
+// such as --mongo-uri and --db-name), so the database it targets can be chosen on the command
+// line. Commands:
+//
+//	create-admin   --email <address> [--first-name <name>] [--last-name <name>] [--password <password>]
+//	reset-password --email <address> [--password <password>]
+//	seed-roles
+//	rotate-data-key
+//	seed-demo      [--users <n>] [--tasks-per-user <n>] [--days <n>] [--seed <n>] [--domain <domain>] [--password <password>]
+//
+// When --password is omitted from create-admin or reset-password, a temporary password is
+// generated and printed; the user has to change it when they next log in. seed-demo refuses
+// to run when APP_ENV is production unless --allow-production is given.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/OsGift/taskflow-api/internal/config"
+	"github.com/OsGift/taskflow-api/internal/database"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/repository"
+	"github.com/OsGift/taskflow-api/internal/repository/mongostore"
+	"github.com/OsGift/taskflow-api/internal/repository/pgstore"
+	"github.com/OsGift/taskflow-api/internal/services"
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+// minPasswordLength matches the minimum enforced by the API
+const minPasswordLength = 6
+
+// command runs one subcommand with its own arguments
+type command func(ctx context.Context, cfg *config.Config, store *repository.Store, args []string) error
+
+var commands = map[string]command{
+	"create-admin":    createAdmin,
+	"reset-password":  resetPassword,
+	"seed-roles":      seedRoles,
+	"rotate-data-key": rotateDataKey,
+	"seed-demo":       seedDemo,
+}
+
+func main() {
+	cfg, args, err := config.LoadCommand(os.Args[1:])
+	if err != nil {
+		log.Fatalf("Error loading config: %v", err)
+	}
+	if len(args) == 0 {
+		usage()
+	}
+	run, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n", args[0])
+		usage()
+	}
+
+	store, closeStore, err := openStore(cfg)
+	if err != nil {
+		log.Fatalf("Error connecting to the database: %v", err)
+	}
+	defer closeStore()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, cfg, store, args[1:]); err != nil {
+		closeStore()
+		log.Fatalf("%s: %v", args[0], err)
+	}
+}
+
+// usage prints the available commands and exits
+func usage() {
+	fmt.Fprintln(os.Stderr, `Usage: taskflow-admin [configuration flags] <command> [command flags]
+
+Commands:
+  create-admin     Create an administrator account
+  reset-password   Set a new password for a user
+  seed-roles       Create the default roles, or restore their permissions
+  rotate-data-key  Re-encrypt personal data with the current data key
+  seed-demo        Fill the database with fake users and tasks for demos and load tests
+
+Run "taskflow-admin <command> -h" for the flags of a command.`)
+	os.Exit(2)
+}
+
+// openStore connects to the configured storage backend. The returned function closes the
+// connection.
+func openStore(cfg *config.Config) (*repository.Store, func(), error) {
+	switch cfg.StorageDriver {
+	case "postgres":
+		pg, err := pgstore.Open(cfg.PostgresURL)
+		if err != nil {
+			return nil, nil, err
+		}
+		return pgstore.New(pg), func() { pg.Close() }, nil
+	default:
+		mongoOptions, _ := cfg.MongoClientOptions() // Validated by LoadCommand
+		client, err := database.ConnectMongoDB(cfg.MongoURI, cfg.DBName, mongoOptions)
+		if err != nil {
+			return nil, nil, err
+		}
+		// One-off commands retry transient failures, but have no breaker to fail fast with
+		retry := database.NewRetrier(cfg.MongoRetryAttempts, time.Duration(cfg.MongoRetryBackoffMS)*time.Millisecond, nil)
+		return mongostore.New(client.Database(cfg.DBName), retry), func() {
+			if err := client.Disconnect(context.Background()); err != nil {
+				log.Printf("Error disconnecting from MongoDB: %v", err)
+			}
+		}, nil
+	}
+}
+
+// createAdmin creates an administrator, seeding the roles first so it works on an empty database
+func createAdmin(ctx context.Context, cfg *config.Config, store *repository.Store, args []string) error {
+	fs := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	email := fs.String("email", "", "email address of the administrator (required)")
+	firstName := fs.String("first-name", "Admin", "first name")
+	lastName := fs.String("last-name", "User", "last name")
+	password := fs.String("password", "", "password; a temporary one is generated when omitted")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" {
+		return errors.New("--email is required")
+	}
+
+	if err := repository.SeedDefaultRoles(ctx, store.Roles); err != nil {
+		return fmt.Errorf("failed to seed roles: %w", err)
+	}
+	keys, err := cfg.DataKeyring()
+	if err != nil {
+		return err
+	}
+	userService := services.NewUserService(store, 0, nil, keys)
+	role, err := userService.GetRoleByName(ctx, "Admin")
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, temporary, err := choosePassword(cfg, *password)
+	if err != nil {
+		return err
+	}
+	user, err := userService.CreateUser(ctx, &models.User{
+		FirstName:           *firstName,
+		LastName:            *lastName,
+		Email:               *email,
+		Password:            hashedPassword,
+		RoleID:              role.ID,
+		NeedsPasswordChange: temporary != "",
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Created administrator %s (%s)\n", user.Email, user.ID)
+	printTemporaryPassword(temporary)
+	return nil
+}
+
+// resetPassword sets a new password for an existing user
+func resetPassword(ctx context.Context, cfg *config.Config, store *repository.Store, args []string) error {
+	fs := flag.NewFlagSet("reset-password", flag.ContinueOnError)
+	email := fs.String("email", "", "email address of the user (required)")
+	password := fs.String("password", "", "new password; a temporary one is generated when omitted")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" {
+		return errors.New("--email is required")
+	}
+
+	keys, err := cfg.DataKeyring()
+	if err != nil {
+		return err
+	}
+	userService := services.NewUserService(store, 0, nil, keys)
+	user, err := userService.GetUserByEmail(ctx, *email)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, temporary, err := choosePassword(cfg, *password)
+	if err != nil {
+		return err
+	}
+	if err := userService.UpdateUserPasswordAndNeedsChange(ctx, user.ID, hashedPassword, temporary != ""); err != nil {
+		return err
+	}
+	if err := revokeSessions(ctx, cfg, store, user.ID); err != nil {
+		return fmt.Errorf("password reset, but failed to end the user's sessions: %w", err)
+	}
+
+	fmt.Printf("Reset the password of %s and ended their sessions\n", user.Email)
+	printTemporaryPassword(temporary)
+	return nil
+}
+
+// revokeSessions ends every session of a user
+func revokeSessions(ctx context.Context, cfg *config.Config, store *repository.Store, userID primitive.ObjectID) error {
+	sessions := services.NewSessionService(store.Documents, nil, []byte(cfg.JWTSecret),
+		time.Duration(cfg.SessionTTLMinutes)*time.Minute, time.Duration(cfg.RefreshTokenTTLDays)*24*time.Hour)
+	return sessions.RevokeAllForUser(ctx, userID)
+}
+
+// seedRoles creates the default roles and restores their default permissions
+func seedRoles(ctx context.Context, cfg *config.Config, store *repository.Store, args []string) error {
+	fs := flag.NewFlagSet("seed-roles", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	return repository.SeedDefaultRoles(ctx, store.Roles)
+}
+
+// rotateDataKey re-encrypts the personal data stored under retired data keys, or in plaintext,
+// with the current key
+func rotateDataKey(ctx context.Context, cfg *config.Config, store *repository.Store, args []string) error {
+	fs := flag.NewFlagSet("rotate-data-key", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	keys, err := cfg.DataKeyring()
+	if err != nil {
+		return err
+	}
+	if keys == nil {
+		return errors.New("DATA_ENCRYPTION_KEYS is not set")
+	}
+	rotated, err := services.NewUserService(store, 0, nil, keys).RotateDataKey(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Re-encrypted the personal data of %d users with key %q\n", rotated, cfg.DataEncryptionKeyID)
+	return nil
+}
+
+// choosePassword hashes password, or a generated temporary password when it is empty. The
+// temporary password is returned so it can be shown to the operator.
+func choosePassword(cfg *config.Config, password string) (hashed, temporary string, err error) {
+	if password == "" {
+		temporary = utils.GenerateRandomString(16)
+		password = temporary
+	} else if len(password) < minPasswordLength {
+		return "", "", fmt.Errorf("--password must be at least %d characters", minPasswordLength)
+	}
+	hasher, err := cfg.PasswordHasher()
+	if err != nil {
+		return "", "", err
+	}
+	hashed, err = hasher.Hash(password)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return hashed, temporary, nil
+}
+
+// printTemporaryPassword shows a generated password, which is not stored anywhere else
+func printTemporaryPassword(temporary string) {
+	if temporary != "" {
+		fmt.Printf("Temporary password: %s\nIt must be changed at the next login.\n", temporary)
+	}
+}
@@ -0,0 +1,92 @@
+// Command taskflow-worker runs the background job worker as a standalone process,
+// for deployments that want to scale job processing separately from the API server.
+// Set JOB_WORKER_ENABLED=false on the API servers when running dedicated workers.
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	_ "time/tzdata" // Users' time zones must load on hosts without a zoneinfo database
+
+	"github.com/OsGift/taskflow-api/internal/app"
+	"github.com/OsGift/taskflow-api/internal/config"
+	"github.com/OsGift/taskflow-api/internal/database"
+	"github.com/OsGift/taskflow-api/internal/jobs"
+	"github.com/OsGift/taskflow-api/internal/logging"
+	"github.com/OsGift/taskflow-api/internal/mailer"
+	"github.com/OsGift/taskflow-api/internal/repository"
+	"github.com/OsGift/taskflow-api/internal/repository/mongostore"
+	"github.com/OsGift/taskflow-api/internal/repository/pgstore"
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+func main() {
+	// 1. Load configuration
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		logging.Fatalf("Error loading config: %v", err)
+	}
+	log.Printf("Configuration:\n%s", cfg.Summary())
+	logLevel, _ := logging.ParseLevel(cfg.LogLevel) // Validated by LoadConfig
+	logging.SetLevel(logLevel)
+
+	// 2. Initialize Mailer
+	emailSender, err := mailer.New(context.Background(), cfg.Mailer())
+	if err != nil {
+		logging.Fatalf("Error initializing mailer: %v", err)
+	}
+	if err := utils.InitMailer(emailSender, cfg.SenderAddress(), cfg.EmailTemplateDir); err != nil {
+		logging.Fatalf("Error initializing mailer: %v", err)
+	}
+
+	// 3. Connect to the database: PostgreSQL when STORAGE_DRIVER selects it, otherwise MongoDB,
+	// retrying transient read failures and failing fast while it is down
+	var store *repository.Store
+	switch cfg.StorageDriver {
+	case "postgres":
+		pg, err := pgstore.Open(cfg.PostgresURL)
+		if err != nil {
+			logging.Fatalf("Error connecting to PostgreSQL: %v", err)
+		}
+		defer pg.Close()
+		store = pgstore.New(pg)
+	default:
+		dbBreaker := database.NewBreaker(cfg.MongoBreakerThreshold, time.Duration(cfg.MongoBreakerCooldownSeconds)*time.Second)
+		dbRetrier := database.NewRetrier(cfg.MongoRetryAttempts, time.Duration(cfg.MongoRetryBackoffMS)*time.Millisecond, dbBreaker)
+		mongoOptions, _ := cfg.MongoClientOptions() // Validated by LoadConfig
+		mongoOptions.Breaker = dbBreaker
+		client, err := database.ConnectMongoDB(cfg.MongoURI, cfg.DBName, mongoOptions)
+		if err != nil {
+			logging.Fatalf("Error connecting to MongoDB: %v", err)
+		}
+		defer func() {
+			if err = client.Disconnect(context.Background()); err != nil {
+				logging.Warnf("Error disconnecting from MongoDB: %v", err)
+			}
+		}()
+		store = mongostore.New(client.Database(cfg.DBName), dbRetrier)
+	}
+
+	// 4. Initialize the services as the API server does, so jobs see the same cache and observers
+	sharedCache, closeCache, err := app.NewCache(cfg)
+	if err != nil {
+		logging.Fatalf("Error initializing cache: %v", err)
+	}
+	defer closeCache()
+	svc, err := app.NewServices(cfg, store, sharedCache)
+	if err != nil {
+		logging.Fatalf("Error initializing services: %v", err)
+	}
+
+	// 5. Run the worker until SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	worker := jobs.NewWorker(svc.Queue, cfg.JobWorkerConcurrency, time.Duration(cfg.JobPollIntervalSeconds)*time.Second)
+	svc.RegisterJobs(ctx, worker, cfg)
+	worker.Run(ctx)
+}
@@ -0,0 +1,259 @@
+// Package app wires the services together. The API server and the standalone job worker both
+// build them here, so tasks changed by a job go through the same observers, and jobs run with
+// the same handlers, as they would in the API server.
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/OsGift/taskflow-api/internal/antivirus"
+	"github.com/OsGift/taskflow-api/internal/cache"
+	"github.com/OsGift/taskflow-api/internal/config"
+	"github.com/OsGift/taskflow-api/internal/jobs"
+	"github.com/OsGift/taskflow-api/internal/logging"
+	"github.com/OsGift/taskflow-api/internal/repository"
+	"github.com/OsGift/taskflow-api/internal/services"
+	"github.com/OsGift/taskflow-api/internal/storage"
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+// Services holds every service, wired to each other
+type Services struct {
+	Cache   cache.Cache // Shared between processes with the redis driver; nil when disabled
+	Queue   *jobs.Queue
+	Storage services.StorageProvider
+
+	Users          *services.UserService
+	Tasks          *services.TaskService
+	Notifications  *services.NotificationService
+	AuthTokens     *services.AuthTokenService
+	Sessions       *services.SessionService
+	Auth           *services.AuthService
+	ServiceAccount *services.ServiceAccountService
+	Dashboard      *services.DashboardService
+	Audit          *services.AuditService
+	IPBlock        *services.IPBlockService
+	EmailTemplates *services.EmailTemplateService
+	EmailDelivery  *services.EmailDeliveryService
+	Reports        *services.ReportService
+	SLA            *services.SLAService
+	Announcements  *services.AnnouncementService
+	Comments       *services.CommentService
+	Projects       *services.ProjectService
+	Milestones     *services.MilestoneService
+	Sprints        *services.SprintService
+	Search         *services.SearchService
+	Import         *services.ImportService
+	Calendar       *services.CalendarService
+	TaskViews      *services.TaskViewService
+	TaskPins       *services.TaskPinService
+	Uploads        *services.UploadService
+	UserMerge      *services.UserMergeService
+	TaskMerge      *services.TaskMergeService
+	Export         *services.ExportService
+	Idempotency    *services.IdempotencyService
+	Digests        *services.DigestService
+	DailySummaries *services.DailySummaryService
+	Retention      *services.RetentionService
+	StaleTasks     *services.StaleTaskService
+}
+
+// NewServices creates the services over store and connects them: task observers, the project
+// role source, user data cleaners and the email template source
+func NewServices(cfg *config.Config, store *repository.Store, sharedCache cache.Cache) (*Services, error) {
+	db := store.Documents
+	storageProvider, err := NewStorageProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+	s := &Services{Cache: sharedCache, Queue: jobs.NewQueue(db), Storage: storageProvider}
+	if err := s.Queue.EnsureIndexes(); err != nil {
+		logging.Warnf("Failed to create job queue indexes: %v", err)
+	}
+
+	dataKeyring, _ := cfg.DataKeyring() // Validated by LoadConfig
+	s.Users = services.NewUserService(store, time.Duration(cfg.AuthCacheTTLSeconds)*time.Second, sharedCache, dataKeyring)
+	s.Tasks = services.NewTaskService(store, sharedCache)
+	s.Notifications = services.NewNotificationService(db, s.Queue, cfg.PushGatewayURL != "")
+	passwordHasher, _ := cfg.PasswordHasher() // Validated by LoadConfig
+	s.AuthTokens = services.NewAuthTokenService(db)
+	s.Sessions = services.NewSessionService(db, s.Users, []byte(cfg.JWTSecret),
+		time.Duration(cfg.SessionTTLMinutes)*time.Minute, time.Duration(cfg.RefreshTokenTTLDays)*24*time.Hour)
+	s.Auth = services.NewAuthService(s.Users, []byte(cfg.JWTSecret), []byte(cfg.PasswordResetSecret), s.Notifications, passwordHasher, s.AuthTokens, s.Sessions)
+	s.ServiceAccount = services.NewServiceAccountService(db, s.Users, cfg.APIKeyRateLimitPerMinute)
+	s.Dashboard = services.NewDashboardService(store, sharedCache)
+	s.Audit = services.NewAuditService(db)
+	s.IPBlock = services.NewIPBlockService(db, cfg.IPBanMaxFailures,
+		time.Duration(cfg.IPBanWindowMinutes)*time.Minute, time.Duration(cfg.IPBanMinutes)*time.Minute)
+	s.EmailTemplates = services.NewEmailTemplateService(db)
+	utils.SetTemplateSource(s.EmailTemplates) // Emails use the templates customised by administrators
+	s.EmailDelivery = services.NewEmailDeliveryService(db)
+	s.Reports = services.NewReportService(db, s.Dashboard, s.Queue)
+	s.SLA = services.NewSLAService(db, store, s.Tasks, s.Queue, s.Notifications,
+		cfg.SLAChecksEnabled, time.Duration(cfg.SLACheckIntervalMinutes)*time.Minute)
+	s.Announcements = services.NewAnnouncementService(db, sharedCache)
+	s.Comments = services.NewCommentService(db, time.Duration(cfg.CommentEditWindowMinutes)*time.Minute)
+	s.Tasks.AddObserver(s.Comments)
+	s.Projects = services.NewProjectService(db, s.Tasks, s.Users, s.Notifications)
+	s.Users.SetProjectRoleSource(s.Projects)
+	// Projects first: owning one refuses the deletion before any task is gone
+	s.Users.AddUserDataCleaner(s.Projects)
+	s.Users.AddUserDataCleaner(s.Tasks)
+	s.Milestones = services.NewMilestoneService(db, store, s.Tasks)
+	s.Sprints = services.NewSprintService(db, store, s.Tasks)
+	s.Search = services.NewSearchService(s.Tasks, s.Comments, s.Users)
+	s.Import = services.NewImportService(s.Tasks)
+	s.Calendar = services.NewCalendarService(db, store, s.Tasks, s.Queue,
+		cfg.GoogleOAuth(), []byte(cfg.JWTSecret), time.Duration(cfg.CalendarSyncIntervalMinutes)*time.Minute)
+	s.Tasks.AddObserver(s.Calendar)
+	s.TaskViews = services.NewTaskViewService(db)
+	s.Tasks.AddObserver(s.TaskViews)
+	s.TaskPins = services.NewTaskPinService(db)
+	s.Tasks.AddObserver(s.TaskPins)
+	s.Users.AddUserDataCleaner(s.TaskPins)
+	uploadPolicy := services.UploadPolicy{
+		AllowedTypes: cfg.UploadTypes(),
+		MaxSize:      int64(cfg.UploadMaxSizeBytes),
+		MaxWidth:     cfg.UploadMaxImageWidth,
+		MaxHeight:    cfg.UploadMaxImageHeight,
+		Quota:        int64(cfg.UploadQuotaBytes),
+	}
+	virusScanning := services.VirusScanning{QuarantineDir: cfg.UploadQuarantineDir}
+	if cfg.ClamAVAddress != "" {
+		virusScanning.Scanner = antivirus.NewClamAV(cfg.ClamAVAddress, time.Duration(cfg.ClamAVTimeoutSeconds)*time.Second)
+		log.Printf("Scanning uploads with ClamAV at %s", cfg.ClamAVAddress)
+	}
+	s.Uploads = services.NewUploadService(store, db, s.Notifications, s.Queue, storageProvider, uploadPolicy, virusScanning)
+	s.UserMerge = services.NewUserMergeService(db, store, s.Users, s.Projects, s.Sessions, sharedCache)
+	s.TaskMerge = services.NewTaskMergeService(db, store, s.Tasks)
+	s.Export = services.NewExportService(store, s.Users, s.Comments, s.Uploads, s.Audit)
+	s.Idempotency = services.NewIdempotencyService(db, time.Duration(cfg.IdempotencyKeyTTLHours)*time.Hour)
+	if err := s.Idempotency.EnsureIndexes(); err != nil {
+		logging.Warnf("Failed to create idempotency key indexes: %v", err)
+	}
+
+	digestWeekday, _ := cfg.DigestWeekday() // Validated by LoadConfig
+	s.Digests = services.NewDigestService(store, s.Queue, s.Notifications, cfg.WeeklyDigestEnabled, digestWeekday, cfg.WeeklyDigestHour)
+	s.DailySummaries = services.NewDailySummaryService(store, s.Queue, s.Notifications, cfg.DailySummaryEnabled, cfg.DailySummaryHour)
+	s.Retention = services.NewRetentionService(db, s.Queue, services.RetentionPolicy{
+		AuditLogDays:         cfg.AuditLogRetentionDays,
+		EmailDeliveryDays:    cfg.EmailDeliveryRetentionDays,
+		ReadNotificationDays: cfg.ReadNotificationRetentionDays,
+		CompletedJobDays:     cfg.CompletedJobRetentionDays,
+	}, cfg.RetentionEnabled, cfg.RetentionDryRun, cfg.RetentionHour)
+	s.StaleTasks = services.NewStaleTaskService(store, s.Tasks, s.Queue, s.Notifications, services.StaleTaskPolicy{
+		Days:        cfg.StaleTaskDays,
+		WarningDays: cfg.StaleTaskWarningDays,
+		Archive:     cfg.StaleTaskAction == "archive",
+	}, cfg.StaleTasksEnabled, cfg.StaleTaskHour)
+	return s, nil
+}
+
+// NewCache creates the shared cache selected by CACHE_DRIVER. The returned function closes
+// it; the cache is nil when caching is disabled.
+func NewCache(cfg *config.Config) (cache.Cache, func(), error) {
+	switch cfg.CacheDriver {
+	case "redis":
+		redisCache, err := cache.NewRedisCache(cfg.RedisURL, cfg.CacheKeyPrefix)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error connecting to Redis: %w", err)
+		}
+		return redisCache, func() { redisCache.Close() }, nil
+	case "memory":
+		return cache.NewMemoryCache(), func() {}, nil
+	case "none":
+		return nil, func() {}, nil // Caching disabled
+	}
+	return nil, nil, fmt.Errorf("unknown CACHE_DRIVER %q (expected memory, redis or none)", cfg.CacheDriver)
+}
+
+// NewStorageProvider creates the upload storage backend selected by UPLOAD_DRIVER
+func NewStorageProvider(cfg *config.Config) (services.StorageProvider, error) {
+	switch cfg.UploadDriver {
+	case "local":
+		provider, err := storage.NewLocal(cfg.LocalStorageDir, cfg.LocalStoragePublicURL)
+		if err != nil {
+			return nil, fmt.Errorf("error initializing local storage: %w", err)
+		}
+		return provider, nil
+
+	case "s3":
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		provider, err := storage.NewS3(ctx, storage.S3Config{
+			Endpoint:        cfg.S3Endpoint,
+			Region:          cfg.S3Region,
+			Bucket:          cfg.S3Bucket,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+			UseSSL:          cfg.S3UseSSL,
+			PublicURL:       cfg.S3PublicURL,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error initializing S3 storage: %w", err)
+		}
+		return provider, nil
+	}
+
+	provider, err := storage.NewCloudinary(cfg.CloudinaryCloudName, cfg.CloudinaryAPIKey, cfg.CloudinaryAPISecret)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing Cloudinary storage: %w", err)
+	}
+	return provider, nil
+}
+
+// RegisterJobs registers every job handler with worker and schedules the recurring jobs
+func (s *Services) RegisterJobs(ctx context.Context, worker *jobs.Worker, cfg *config.Config) {
+	jobs.RegisterDefaultHandlers(worker, s.EmailDelivery)
+	jobs.RegisterNotificationHandlers(worker, jobs.NewPushSender(cfg.PushGatewayURL, cfg.PushGatewayToken))
+	worker.OnDeadLetter(jobs.NewAlerter(s.Queue, cfg.JobAlertWebhookURL, cfg.JobAlertEmail).JobDead)
+
+	worker.Register(jobs.TypeWeeklyDigest, jobs.Recurring(s.Queue, jobs.TypeWeeklyDigest, s.Digests.NextRun, s.Digests.SendWeeklyDigests))
+	if err := s.Digests.Schedule(ctx); err != nil {
+		logging.Warnf("Failed to schedule the weekly digest: %v", err)
+	}
+	worker.Register(jobs.TypeDailySummary, jobs.Recurring(s.Queue, jobs.TypeDailySummary, s.DailySummaries.NextRun, s.DailySummaries.SendDailySummaries))
+	if err := s.DailySummaries.Schedule(ctx); err != nil {
+		logging.Warnf("Failed to schedule the daily summary: %v", err)
+	}
+	worker.Register(jobs.TypeScheduledReport, jobs.Recurring(s.Queue, jobs.TypeScheduledReport, s.Reports.NextRun, s.Reports.SendScheduledReport))
+	worker.Register(jobs.TypeRetentionCleanup, jobs.Recurring(s.Queue, jobs.TypeRetentionCleanup, s.Retention.NextRun, s.Retention.RunCleanup))
+	if err := s.Retention.Schedule(ctx); err != nil {
+		logging.Warnf("Failed to schedule the retention cleanup: %v", err)
+	}
+	worker.Register(jobs.TypeStaleTasks, jobs.Recurring(s.Queue, jobs.TypeStaleTasks, s.StaleTasks.NextRun, s.StaleTasks.RunStaleTasks))
+	if err := s.StaleTasks.Schedule(ctx); err != nil {
+		logging.Warnf("Failed to schedule the stale task job: %v", err)
+	}
+	worker.Register(jobs.TypeSLACheck, jobs.Recurring(s.Queue, jobs.TypeSLACheck, s.SLA.NextRun, s.SLA.RunSLACheck))
+	if err := s.SLA.Schedule(ctx); err != nil {
+		logging.Warnf("Failed to schedule the SLA check: %v", err)
+	}
+	worker.Register(jobs.TypeCalendarPushTask, s.Calendar.PushTask)
+	worker.Register(jobs.TypeCalendarPull, s.Calendar.PullChanges)
+	worker.Register(jobs.TypeExpireDirectUpload, s.Uploads.ExpireDirectUpload)
+}
+
+// ApplyJobSettings schedules or unschedules the recurring jobs after the configuration was
+// reloaded
+func (s *Services) ApplyJobSettings(ctx context.Context, c *config.Config) {
+	if err := s.Digests.SetEnabled(ctx, c.WeeklyDigestEnabled); err != nil {
+		logging.Warnf("Failed to schedule the weekly digest: %v", err)
+	}
+	if err := s.DailySummaries.SetEnabled(ctx, c.DailySummaryEnabled); err != nil {
+		logging.Warnf("Failed to schedule the daily summary: %v", err)
+	}
+	if err := s.Retention.SetEnabled(ctx, c.RetentionEnabled, c.RetentionDryRun); err != nil {
+		logging.Warnf("Failed to schedule the retention cleanup: %v", err)
+	}
+	if err := s.StaleTasks.SetEnabled(ctx, c.StaleTasksEnabled); err != nil {
+		logging.Warnf("Failed to schedule the stale task job: %v", err)
+	}
+	if err := s.SLA.SetEnabled(ctx, c.SLAChecksEnabled); err != nil {
+		logging.Warnf("Failed to schedule the SLA check: %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+// Package apperror defines the structured error type shared by services and handlers.
+// Each error carries a machine-readable Code that clients can branch on, a human-readable
+// Message, and optional Details (e.g. per-field validation failures).
+package apperror
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Code is a stable, machine-readable error identifier returned to API clients
+type Code string
+
+const (
+	CodeInvalidArgument      Code = "invalid_argument"
+	CodeValidationFailed     Code = "validation_failed"
+	CodeUnauthenticated      Code = "unauthenticated"
+	CodePermissionDenied     Code = "permission_denied"
+	CodeNotFound             Code = "not_found"
+	CodeAlreadyExists        Code = "already_exists"
+	CodeConflict             Code = "conflict"
+	CodeFailedPrecondition   Code = "failed_precondition"
+	CodePayloadTooLarge      Code = "payload_too_large"
+	CodeUnsupportedMediaType Code = "unsupported_media_type"
+	CodeUnprocessableEntity  Code = "unprocessable_entity"
+	CodeRateLimited          Code = "rate_limited"
+	CodeUnavailable          Code = "unavailable"
+	CodeInternal             Code = "internal"
+)
+
+// Error is an application error with a code, message and optional details
+type Error struct {
+	Code    Code
+	Message string
+	Details map[string]interface{}
+	cause   error
+}
+
+// New creates an Error
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Wrap creates an Error that keeps cause for logging and errors.Is/As
+func Wrap(code Code, message string, cause error) *Error {
+	return &Error{Code: code, Message: message, cause: cause}
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the underlying cause, if any
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is matches errors with the same code and message, so copies made by WithDetails
+// still match the sentinel they were derived from
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code && t.Message == e.Message
+}
+
+// WithDetails returns a copy of e carrying the given details
+func (e *Error) WithDetails(details map[string]interface{}) *Error {
+	clone := *e
+	clone.Details = details
+	return &clone
+}
+
+// From returns err as an *Error. Errors that aren't application errors become
+// CodeInternal with a generic message, so internal details never leak to clients.
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Wrap(CodeInternal, "internal server error", err)
+}
+
+// CodeOf returns the code of err, or CodeInternal if it isn't an application error
+func CodeOf(err error) Code {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Code
+	}
+	return CodeInternal
+}
+
+// HTTPStatus maps a code to the HTTP status it is served with
+func HTTPStatus(code Code) int {
+	switch code {
+	case CodeInvalidArgument, CodeValidationFailed, CodeFailedPrecondition:
+		return http.StatusBadRequest
+	case CodeUnauthenticated:
+		return http.StatusUnauthorized
+	case CodePermissionDenied:
+		return http.StatusForbidden
+	case CodeNotFound:
+		return http.StatusNotFound
+	case CodeAlreadyExists, CodeConflict:
+		return http.StatusConflict
+	case CodePayloadTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case CodeUnsupportedMediaType:
+		return http.StatusUnsupportedMediaType
+	case CodeUnprocessableEntity:
+		return http.StatusUnprocessableEntity
+	case CodeRateLimited:
+		return http.StatusTooManyRequests
+	case CodeUnavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// CodeForStatus picks the code for an error response that only has an HTTP status
+func CodeForStatus(status int) Code {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeInvalidArgument
+	case http.StatusUnauthorized:
+		return CodeUnauthenticated
+	case http.StatusForbidden:
+		return CodePermissionDenied
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusUnsupportedMediaType:
+		return CodeUnsupportedMediaType
+	case http.StatusUnprocessableEntity:
+		return CodeUnprocessableEntity
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	}
+	if status >= 400 && status < 500 {
+		return CodeInvalidArgument
+	}
+	return CodeInternal
+}
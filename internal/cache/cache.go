@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/OsGift/taskflow-api/internal/logging"
+)
+
+// Cache is a shared key/value cache with per-entry TTLs.
+// Implementations must be safe for concurrent use.
+type Cache interface {
+	// Get returns the value stored under key, reporting whether it was found
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key for ttl
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes the given keys
+	Delete(ctx context.Context, keys ...string) error
+	// DeletePrefix removes every key starting with prefix (used for group invalidation)
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+// GetJSON decodes a cached JSON value into dst, reporting whether it was found.
+// Cache errors are logged and treated as misses so the cache never breaks a request.
+func GetJSON(ctx context.Context, c Cache, key string, dst interface{}) bool {
+	if c == nil {
+		return false
+	}
+	data, found, err := c.Get(ctx, key)
+	if err != nil {
+		logging.Warnf("Cache get %s failed: %v", key, err)
+		return false
+	}
+	if !found {
+		return false
+	}
+	if err := json.Unmarshal(data, dst); err != nil {
+		logging.Warnf("Cache entry %s is corrupt: %v", key, err)
+		return false
+	}
+	return true
+}
+
+// SetJSON stores value as JSON under key for ttl, logging (not returning) failures
+func SetJSON(ctx context.Context, c Cache, key string, value interface{}, ttl time.Duration) {
+	if c == nil {
+		return
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		logging.Warnf("Cache encode %s failed: %v", key, err)
+		return
+	}
+	if err := c.Set(ctx, key, data, ttl); err != nil {
+		logging.Warnf("Cache set %s failed: %v", key, err)
+	}
+}
+
+// InvalidatePrefixes removes every key under the given prefixes, logging failures
+func InvalidatePrefixes(ctx context.Context, c Cache, prefixes ...string) {
+	if c == nil {
+		return
+	}
+	for _, prefix := range prefixes {
+		if err := c.DeletePrefix(ctx, prefix); err != nil {
+			logging.Warnf("Cache invalidation of %s* failed: %v", prefix, err)
+		}
+	}
+}
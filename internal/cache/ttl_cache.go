@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepThreshold is the number of entries above which Set purges expired items. Sweeps scan
+// the whole map, so they run at most once per TTL: no entry can have expired since the last
+// one for longer than that.
+const sweepThreshold = 1024
+
+// ttlEntry is a cached value along with its expiry time
+type ttlEntry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// TTLCache is a small concurrency-safe in-memory cache whose entries expire after a fixed TTL
+type TTLCache[K comparable, V any] struct {
+	mu         sync.RWMutex
+	ttl        time.Duration
+	entries    map[K]ttlEntry[V]
+	nextSweep  time.Time // Earliest time Set sweeps expired entries again
+	generation uint64    // Counts Delete and Clear calls, see SetIfGeneration
+}
+
+// NewTTLCache creates a new TTLCache with the given entry lifetime
+func NewTTLCache[K comparable, V any](ttl time.Duration) *TTLCache[K, V] {
+	return &TTLCache[K, V]{
+		ttl:     ttl,
+		entries: make(map[K]ttlEntry[V]),
+	}
+}
+
+// Get returns the cached value for key if present and not expired
+func (c *TTLCache[K, V]) Get(key K) (V, bool) {
+	c.mu.RLock()
+	entry, ok := c.entries[key]
+	c.mu.RUnlock()
+
+	if !ok || time.Now().After(entry.expiresAt) {
+		var zero V
+		return zero, false
+	}
+	return entry.value, true
+}
+
+// Set stores value under key for the cache's TTL
+func (c *TTLCache[K, V]) Set(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(key, value)
+}
+
+// set stores value under key, sweeping expired entries now and then. The caller holds mu.
+func (c *TTLCache[K, V]) set(key K, value V) {
+	now := time.Now()
+	if len(c.entries) >= sweepThreshold && !now.Before(c.nextSweep) {
+		c.nextSweep = now.Add(c.ttl)
+		for k, e := range c.entries {
+			if now.After(e.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	c.entries[key] = ttlEntry[V]{value: value, expiresAt: now.Add(c.ttl)}
+}
+
+// Generation returns a token that changes whenever an entry is deleted or the cache cleared.
+// Callers loading a value take it before reading the source, then store the value with
+// SetIfGeneration, so an invalidation during the load isn't undone by a stale value.
+func (c *TTLCache[K, V]) Generation() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.generation
+}
+
+// SetIfGeneration stores value under key like Set, unless an entry was deleted or the cache
+// cleared since Generation returned generation. It reports whether value was stored.
+func (c *TTLCache[K, V]) SetIfGeneration(key K, value V, generation uint64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generation != generation {
+		return false
+	}
+	c.set(key, value)
+	return true
+}
+
+// Delete removes key from the cache
+func (c *TTLCache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	delete(c.entries, key)
+	c.generation++
+	c.mu.Unlock()
+}
+
+// Clear removes every entry from the cache
+func (c *TTLCache[K, V]) Clear() {
+	c.mu.Lock()
+	c.entries = make(map[K]ttlEntry[V])
+	c.generation++
+	c.mu.Unlock()
+}
@@ -0,0 +1,38 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetIfGenerationSkipsValuesLoadedBeforeAnInvalidation(t *testing.T) {
+	c := NewTTLCache[string, int](time.Minute)
+
+	generation := c.Generation()
+	if !c.SetIfGeneration("a", 1, generation) {
+		t.Fatal("SetIfGeneration refused a value loaded without any invalidation")
+	}
+
+	// An invalidation lands while the next value is loaded
+	generation = c.Generation()
+	c.Delete("a")
+	if c.SetIfGeneration("a", 2, generation) {
+		t.Error("SetIfGeneration stored a value loaded before a Delete")
+	}
+	if _, ok := c.Get("a"); ok {
+		t.Error("the stale value is cached")
+	}
+
+	generation = c.Generation()
+	c.Clear()
+	if c.SetIfGeneration("b", 3, generation) {
+		t.Error("SetIfGeneration stored a value loaded before a Clear")
+	}
+
+	if !c.SetIfGeneration("b", 4, c.Generation()) {
+		t.Fatal("SetIfGeneration refused a value loaded after the invalidation")
+	}
+	if v, ok := c.Get("b"); !ok || v != 4 {
+		t.Errorf("Get = %d, %v; want 4, true", v, ok)
+	}
+}
@@ -1,60 +1,918 @@
 package config
 
 import (
-	"log"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/joho/godotenv"
+	"golang.org/x/oauth2"
+
+	"github.com/OsGift/taskflow-api/internal/database"
+	"github.com/OsGift/taskflow-api/internal/errorreport"
+	"github.com/OsGift/taskflow-api/internal/fieldcrypt"
+	"github.com/OsGift/taskflow-api/internal/gcal"
+	"github.com/OsGift/taskflow-api/internal/logging"
+	"github.com/OsGift/taskflow-api/internal/mailer"
+	"github.com/OsGift/taskflow-api/internal/passhash"
+)
+
+// Insecure defaults shipped for local development; production refuses to start with them
+const (
+	defaultJWTSecret           = "your_very_secret_jwt_key_here_change_this_in_production"
+	defaultPasswordResetSecret = "another_super_secret_key_for_password_resets"
 )
 
-// Config holds the application configuration
+// minProductionSecretLength is the shortest signing secret accepted in production
+const minProductionSecretLength = 32
+
+// Config holds the application configuration.
+// Every field can be set from a YAML file (yaml tag), an environment variable (env tag)
+// or a command-line flag (the yaml key with dashes, e.g. --mongo-uri). Fields tagged
+// redact are masked in the startup summary, and fields tagged reload are applied to the
+// running server when the configuration is reloaded (SIGHUP or POST /config/reload); the
+// others only change on restart.
 type Config struct {
-	MongoURI            string
-	DBName              string
-	JWTSecret           string
-	Port                string
-	PasswordResetSecret string
+	// Deployment environment: "development" (default), "staging" or "production"
+	Environment string `yaml:"app_env" env:"APP_ENV"`
+
+	// Least severe messages logged: "debug" (every request too), "info" (default) or "warn".
+	// Administrators can also change it on a running server with PUT /log-level.
+	LogLevel string `yaml:"log_level" env:"LOG_LEVEL" reload:"true"`
+
+	MongoURI            string `yaml:"mongo_uri" env:"MONGO_URI" redact:"url"`
+	DBName              string `yaml:"db_name" env:"DB_NAME"`
+	JWTSecret           string `yaml:"jwt_secret" env:"JWT_SECRET" redact:"secret"`
+	Port                string `yaml:"port" env:"PORT"`
+	PasswordResetSecret string `yaml:"password_reset_secret" env:"PASSWORD_RESET_SECRET" redact:"secret"`
+
+	// MongoDB client tuning, mostly for replica sets; unset values keep those of MONGO_URI or
+	// the driver's defaults. MongoReadPreference is primary, primaryPreferred, secondary,
+	// secondaryPreferred or nearest (secondaries may lag behind recent writes; transactions
+	// always read from the primary). MongoWriteConcern is "majority" or how many members
+	// must acknowledge writes.
+	MongoMaxPoolSize              int    `yaml:"mongo_max_pool_size" env:"MONGO_MAX_POOL_SIZE"`
+	MongoMinPoolSize              int    `yaml:"mongo_min_pool_size" env:"MONGO_MIN_POOL_SIZE"`
+	MongoReadPreference           string `yaml:"mongo_read_preference" env:"MONGO_READ_PREFERENCE"`
+	MongoWriteConcern             string `yaml:"mongo_write_concern" env:"MONGO_WRITE_CONCERN"`
+	MongoServerSelectionTimeoutMS int    `yaml:"mongo_server_selection_timeout_ms" env:"MONGO_SERVER_SELECTION_TIMEOUT_MS"`
+
+	// Where the data is stored: "mongo" (default) or "postgres". With postgres everything,
+	// users, roles and tasks in their own tables and the other collections as documents, lives
+	// in the POSTGRES_URL database and the MONGO_* settings are unused.
+	StorageDriver string `yaml:"storage_driver" env:"STORAGE_DRIVER"`
+	PostgresURL   string `yaml:"postgres_url" env:"POSTGRES_URL" redact:"url"`
+
+	// MongoDB reads failing with a transient error (network errors, timeouts) are tried up to
+	// MongoRetryAttempts times, backing off from MongoRetryBackoffMS. After
+	// MongoBreakerThreshold consecutive failures, or when no writable server is left, requests
+	// fail fast with 503 for MongoBreakerCooldownSeconds; 0 MongoBreakerThreshold disables it.
+	MongoRetryAttempts          int `yaml:"mongo_retry_attempts" env:"MONGO_RETRY_ATTEMPTS"`
+	MongoRetryBackoffMS         int `yaml:"mongo_retry_backoff_ms" env:"MONGO_RETRY_BACKOFF_MS"`
+	MongoBreakerThreshold       int `yaml:"mongo_breaker_threshold" env:"MONGO_BREAKER_THRESHOLD"`
+	MongoBreakerCooldownSeconds int `yaml:"mongo_breaker_cooldown_seconds" env:"MONGO_BREAKER_COOLDOWN_SECONDS"`
 
 	// Email SMTP Configuration
-	SMTPHost     string
-	SMTPPort     string
-	SMTPUsername string
-	SMTPPassword string
+	SMTPHost     string `yaml:"smtp_host" env:"SMTP_HOST"`
+	SMTPPort     string `yaml:"smtp_port" env:"SMTP_PORT"`
+	SMTPUsername string `yaml:"smtp_username" env:"SMTP_USERNAME"`
+	SMTPPassword string `yaml:"smtp_password" env:"SMTP_PASSWORD" redact:"secret"` // Use app password for Gmail
+
+	// Email delivery: "smtp" (default), "sendgrid", "mailgun" or "ses". The HTTP APIs are
+	// useful on hosts that block outbound SMTP. EmailFrom defaults to SMTPUsername.
+	EmailProvider string `yaml:"email_provider" env:"EMAIL_PROVIDER"`
+	EmailFrom     string `yaml:"email_from" env:"EMAIL_FROM"`
+	// Directory of *.html files (and locale directories of translations) overriding the
+	// email templates built into the binary
+	EmailTemplateDir   string `yaml:"email_template_dir" env:"EMAIL_TEMPLATE_DIR"`
+	SendGridAPIKey     string `yaml:"sendgrid_api_key" env:"SENDGRID_API_KEY" redact:"secret"`
+	MailgunAPIKey      string `yaml:"mailgun_api_key" env:"MAILGUN_API_KEY" redact:"secret"`
+	MailgunDomain      string `yaml:"mailgun_domain" env:"MAILGUN_DOMAIN"`
+	MailgunAPIBase     string `yaml:"mailgun_api_base" env:"MAILGUN_API_BASE"` // https://api.eu.mailgun.net for EU domains
+	SESRegion          string `yaml:"ses_region" env:"SES_REGION"`
+	SESAccessKeyID     string `yaml:"ses_access_key_id" env:"SES_ACCESS_KEY_ID"` // Empty uses the default AWS credential chain
+	SESSecretAccessKey string `yaml:"ses_secret_access_key" env:"SES_SECRET_ACCESS_KEY" redact:"secret"`
+
+	// Upload storage backend: "cloudinary" (default), "s3" or "local"
+	UploadDriver string `yaml:"upload_driver" env:"UPLOAD_DRIVER"`
+
+	// Upload validation: allowed MIME types (comma-separated, wildcards like image/* allowed),
+	// maximum size in bytes and maximum image dimensions in pixels (0 disables a limit)
+	UploadAllowedTypes   string `yaml:"upload_allowed_types" env:"UPLOAD_ALLOWED_TYPES"`
+	UploadMaxSizeBytes   int    `yaml:"upload_max_size_bytes" env:"UPLOAD_MAX_SIZE_BYTES"`
+	UploadMaxImageWidth  int    `yaml:"upload_max_image_width" env:"UPLOAD_MAX_IMAGE_WIDTH"`
+	UploadMaxImageHeight int    `yaml:"upload_max_image_height" env:"UPLOAD_MAX_IMAGE_HEIGHT"`
+
+	// Total bytes each user may have stored (0 means unlimited)
+	UploadQuotaBytes int `yaml:"upload_quota_bytes" env:"UPLOAD_QUOTA_BYTES"`
 
 	// Cloudinary Configuration
-	CloudinaryCloudName   string
-	CloudinaryAPIKey      string
-	CloudinaryAPISecret   string
+	CloudinaryCloudName string `yaml:"cloudinary_cloud_name" env:"CLOUDINARY_CLOUD_NAME"`
+	CloudinaryAPIKey    string `yaml:"cloudinary_api_key" env:"CLOUDINARY_API_KEY" redact:"secret"`
+	CloudinaryAPISecret string `yaml:"cloudinary_api_secret" env:"CLOUDINARY_API_SECRET" redact:"secret"`
+
+	// S3-compatible storage (AWS S3, MinIO, ...). S3PublicURL is the base URL objects are
+	// served from; it defaults to the endpoint followed by the bucket name.
+	S3Endpoint        string `yaml:"s3_endpoint" env:"S3_ENDPOINT"`
+	S3Region          string `yaml:"s3_region" env:"S3_REGION"`
+	S3Bucket          string `yaml:"s3_bucket" env:"S3_BUCKET"`
+	S3AccessKeyID     string `yaml:"s3_access_key_id" env:"S3_ACCESS_KEY_ID" redact:"secret"`
+	S3SecretAccessKey string `yaml:"s3_secret_access_key" env:"S3_SECRET_ACCESS_KEY" redact:"secret"`
+	S3UseSSL          bool   `yaml:"s3_use_ssl" env:"S3_USE_SSL"`
+	S3PublicURL       string `yaml:"s3_public_url" env:"S3_PUBLIC_URL"`
+
+	// Local disk storage: files are kept under LocalStorageDir and served at /files/.
+	// LocalStoragePublicURL is the base URL returned to clients (e.g. https://api.example.com/files).
+	LocalStorageDir       string `yaml:"local_storage_dir" env:"LOCAL_STORAGE_DIR"`
+	LocalStoragePublicURL string `yaml:"local_storage_public_url" env:"LOCAL_STORAGE_PUBLIC_URL"`
+
+	// Virus scanning of uploads with a clamd daemon (host:port or unix:/path/to/clamd.sock);
+	// empty disables scanning. Flagged files are copied to UploadQuarantineDir for review.
+	ClamAVAddress        string `yaml:"clamav_address" env:"CLAMAV_ADDRESS"`
+	ClamAVTimeoutSeconds int    `yaml:"clamav_timeout_seconds" env:"CLAMAV_TIMEOUT_SECONDS"`
+	UploadQuarantineDir  string `yaml:"upload_quarantine_dir" env:"UPLOAD_QUARANTINE_DIR"`
+
+	// Response compression: bodies smaller than this (in bytes) are sent uncompressed
+	CompressionMinSize int `yaml:"compression_min_size" env:"COMPRESSION_MIN_SIZE"`
+
+	// How long Idempotency-Key responses are kept for replay
+	IdempotencyKeyTTLHours int `yaml:"idempotency_key_ttl_hours" env:"IDEMPOTENCY_KEY_TTL_HOURS"`
+
+	// Cookie authentication for browser frontends: login sets Secure, httpOnly cookies holding
+	// an access token valid for SessionTTLMinutes and a refresh token, rotated on every use,
+	// valid for RefreshTokenTTLDays without use. The cookies are sent with CookieSameSite
+	// ("lax", "strict" or "none"; "none" lets frontends on another site use them).
+	// State-changing requests authenticated by the cookies must echo the token from
+	// GET /auth/csrf in an X-CSRF-Token header.
+	CookieAuthEnabled   bool   `yaml:"cookie_auth_enabled" env:"COOKIE_AUTH_ENABLED"`
+	SessionTTLMinutes   int    `yaml:"session_ttl_minutes" env:"SESSION_TTL_MINUTES"`
+	RefreshTokenTTLDays int    `yaml:"refresh_token_ttl_days" env:"REFRESH_TOKEN_TTL_DAYS"`
+	CookieSameSite      string `yaml:"cookie_same_site" env:"COOKIE_SAME_SITE"`
+
+	// Origins allowed to make credentialed cross-origin requests (comma-separated), which
+	// frontends on another origin need to send the auth cookies; empty allows every origin
+	// without credentials
+	CORSAllowedOrigins string `yaml:"cors_allowed_origins" env:"CORS_ALLOWED_ORIGINS" reload:"true"`
+
+	// Field-level encryption of personal data (phone numbers and addresses) with AES-256-GCM.
+	// DataEncryptionKeys lists data keys as comma-separated <id>:<base64 32-byte key> pairs
+	// and DataEncryptionKeyID names the one new values are encrypted with. Keep retired keys
+	// listed until "taskflow-admin rotate-data-key" has re-encrypted their values. Empty
+	// stores the fields in plaintext.
+	DataEncryptionKeys  string `yaml:"data_encryption_keys" env:"DATA_ENCRYPTION_KEYS" redact:"secret"`
+	DataEncryptionKeyID string `yaml:"data_encryption_key_id" env:"DATA_ENCRYPTION_KEY_ID"`
+
+	// Brute-force blocking: an IP address failing to authenticate IPBanMaxFailures times within
+	// IPBanWindowMinutes (wrong passwords, invalid API keys, forged tokens) is refused for
+	// IPBanMinutes, doubled by each later ban up to a day; 0 IPBanMaxFailures disables it.
+	// TrustedProxyHops is the number of reverse proxies in front of the server that append the
+	// client address to X-Forwarded-For; 0 counts failures against the connection's address.
+	// Idempotency keys of anonymous callers are scoped to the same address.
+	IPBanMaxFailures   int `yaml:"ip_ban_max_failures" env:"IP_BAN_MAX_FAILURES" reload:"true"`
+	IPBanWindowMinutes int `yaml:"ip_ban_window_minutes" env:"IP_BAN_WINDOW_MINUTES" reload:"true"`
+	IPBanMinutes       int `yaml:"ip_ban_minutes" env:"IP_BAN_MINUTES" reload:"true"`
+	TrustedProxyHops   int `yaml:"trusted_proxy_hops" env:"TRUSTED_PROXY_HOPS"`
+
+	// Password hashing: "bcrypt" with BcryptCost, or "argon2id" with the Argon2 parameters
+	// (memory in KiB). Passwords hashed with another algorithm or parameters keep working and
+	// are rehashed with the current ones when their users next log in.
+	PasswordHashAlgorithm string `yaml:"password_hash_algorithm" env:"PASSWORD_HASH_ALGORITHM"`
+	BcryptCost            int    `yaml:"bcrypt_cost" env:"BCRYPT_COST"`
+	Argon2MemoryKiB       int    `yaml:"argon2_memory_kib" env:"ARGON2_MEMORY_KIB"`
+	Argon2Iterations      int    `yaml:"argon2_iterations" env:"ARGON2_ITERATIONS"`
+	Argon2Parallelism     int    `yaml:"argon2_parallelism" env:"ARGON2_PARALLELISM"`
+
+	// How long resolved auth contexts (user + role) are cached; 0 disables caching. The cache is
+	// kept in each process, so with several servers a role change, disabled account or removed
+	// project membership can take this long to reach the servers that didn't make the change.
+	AuthCacheTTLSeconds int `yaml:"auth_cache_ttl_seconds" env:"AUTH_CACHE_TTL_SECONDS"`
+
+	// Requests per minute allowed for service account API keys without their own limit; 0 means unlimited
+	APIKeyRateLimitPerMinute int `yaml:"api_key_rate_limit_per_minute" env:"API_KEY_RATE_LIMIT_PER_MINUTE" reload:"true"`
+
+	// How long after posting authors may edit their comments; 0 means there is no limit
+	CommentEditWindowMinutes int `yaml:"comment_edit_window_minutes" env:"COMMENT_EDIT_WINDOW_MINUTES"`
+
+	// API versioning: mark v1 as deprecated and optionally announce its sunset date (YYYY-MM-DD)
+	APIV1Deprecated bool   `yaml:"api_v1_deprecated" env:"API_V1_DEPRECATED"`
+	APIV1SunsetDate string `yaml:"api_v1_sunset_date" env:"API_V1_SUNSET_DATE"`
+
+	// gRPC server for internal consumers; only started when GRPCAuthToken is set
+	GRPCPort      string `yaml:"grpc_port" env:"GRPC_PORT"`
+	GRPCAuthToken string `yaml:"grpc_auth_token" env:"GRPC_AUTH_TOKEN" redact:"secret"`
+
+	// Shared secret for the inbound email webhook (?token=...); empty disables the endpoint.
+	// With the Mailgun webhook signing key set, webhooks must also carry a valid Mailgun
+	// signature; SendGrid inbound parse doesn't sign its requests.
+	InboundEmailSecret            string `yaml:"inbound_email_secret" env:"INBOUND_EMAIL_SECRET" redact:"secret"`
+	InboundEmailMailgunSigningKey string `yaml:"inbound_email_mailgun_signing_key" env:"INBOUND_EMAIL_MAILGUN_SIGNING_KEY" redact:"secret"`
+
+	// Background job worker (emails and other deferred work)
+	JobWorkerEnabled       bool `yaml:"job_worker_enabled" env:"JOB_WORKER_ENABLED"`
+	JobWorkerConcurrency   int  `yaml:"job_worker_concurrency" env:"JOB_WORKER_CONCURRENCY"`
+	JobPollIntervalSeconds int  `yaml:"job_poll_interval_seconds" env:"JOB_POLL_INTERVAL_SECONDS"`
+
+	// Weekly digest emails for users who opted in, sent on WeeklyDigestWeekday
+	// (e.g. "monday") at WeeklyDigestHour:00 UTC by the job worker
+	WeeklyDigestEnabled bool   `yaml:"weekly_digest_enabled" env:"WEEKLY_DIGEST_ENABLED" reload:"true"`
+	WeeklyDigestWeekday string `yaml:"weekly_digest_weekday" env:"WEEKLY_DIGEST_WEEKDAY"`
+	WeeklyDigestHour    int    `yaml:"weekly_digest_hour" env:"WEEKLY_DIGEST_HOUR"`
+
+	// Daily summaries for users who opted in, sent by the job worker at the hour of their day
+	// they chose, or DailySummaryHour:00 in their time zone
+	DailySummaryEnabled bool `yaml:"daily_summary_enabled" env:"DAILY_SUMMARY_ENABLED" reload:"true"`
+	DailySummaryHour    int  `yaml:"daily_summary_hour" env:"DAILY_SUMMARY_HOUR"`
+
+	// Data retention: when enabled, the job worker deletes daily at RetentionHour:00 UTC the
+	// records older than their number of days (0 keeps them forever). RetentionDryRun only logs
+	// how many records each rule would delete, to check a policy before turning it on.
+	RetentionEnabled              bool `yaml:"retention_enabled" env:"RETENTION_ENABLED" reload:"true"`
+	RetentionDryRun               bool `yaml:"retention_dry_run" env:"RETENTION_DRY_RUN" reload:"true"`
+	RetentionHour                 int  `yaml:"retention_hour" env:"RETENTION_HOUR"`
+	AuditLogRetentionDays         int  `yaml:"audit_log_retention_days" env:"AUDIT_LOG_RETENTION_DAYS"`
+	EmailDeliveryRetentionDays    int  `yaml:"email_delivery_retention_days" env:"EMAIL_DELIVERY_RETENTION_DAYS"`
+	ReadNotificationRetentionDays int  `yaml:"read_notification_retention_days" env:"READ_NOTIFICATION_RETENTION_DAYS"`
+	CompletedJobRetentionDays     int  `yaml:"completed_job_retention_days" env:"COMPLETED_JOB_RETENTION_DAYS"`
+
+	// Stale tasks: when enabled, the job worker checks daily at StaleTaskHour:00 UTC for open
+	// tasks not updated in StaleTaskDays. Their owners are warned StaleTaskWarningDays ahead
+	// (0 sends no warning); then the tasks are flagged with stale_at, or also archived when
+	// StaleTaskAction is "archive". Updating a task brings it back.
+	StaleTasksEnabled    bool   `yaml:"stale_tasks_enabled" env:"STALE_TASKS_ENABLED" reload:"true"`
+	StaleTaskDays        int    `yaml:"stale_task_days" env:"STALE_TASK_DAYS"`
+	StaleTaskWarningDays int    `yaml:"stale_task_warning_days" env:"STALE_TASK_WARNING_DAYS"`
+	StaleTaskAction      string `yaml:"stale_task_action" env:"STALE_TASK_ACTION"` // "flag" or "archive"
+	StaleTaskHour        int    `yaml:"stale_task_hour" env:"STALE_TASK_HOUR"`
+
+	// SLA checks: when enabled, the job worker checks tasks against the SLA rules set up by
+	// administrators every SLACheckIntervalMinutes, flagging the breaches and telling managers
+	SLAChecksEnabled        bool `yaml:"sla_checks_enabled" env:"SLA_CHECKS_ENABLED" reload:"true"`
+	SLACheckIntervalMinutes int  `yaml:"sla_check_interval_minutes" env:"SLA_CHECK_INTERVAL_MINUTES"`
+
+	// Alerts for jobs that fail all their retries (e.g. undeliverable password-reset emails):
+	// a webhook receiving a Slack-compatible {"text": ...} payload and/or an email address
+	JobAlertWebhookURL string `yaml:"job_alert_webhook_url" env:"JOB_ALERT_WEBHOOK_URL" redact:"secret"`
+	JobAlertEmail      string `yaml:"job_alert_email" env:"JOB_ALERT_EMAIL"`
+
+	// Error reporting: server errors and panics of request handlers are sent to Sentry, or a
+	// compatible tracker, when SentryDSN is set. Events are tagged with SentryEnvironment
+	// (APP_ENV by default) and SentryRelease (the VCS revision of the build by default).
+	SentryDSN         string `yaml:"sentry_dsn" env:"SENTRY_DSN" redact:"secret"`
+	SentryEnvironment string `yaml:"sentry_environment" env:"SENTRY_ENVIRONMENT"`
+	SentryRelease     string `yaml:"sentry_release" env:"SENTRY_RELEASE"`
+
+	// Dependency checks (MongoDB and its indexes, SMTP, Cloudinary) run at startup and for
+	// GET /readyz, which reuses their results for ReadinessCacheSeconds so probes don't run
+	// into the providers' rate limits. Failures are logged; with StartupChecksRequired the
+	// server doesn't start while one fails.
+	StartupChecksRequired bool `yaml:"startup_checks_required" env:"STARTUP_CHECKS_REQUIRED"`
+	ReadinessCacheSeconds int  `yaml:"readiness_cache_seconds" env:"READINESS_CACHE_SECONDS"`
+
+	// Push notifications are posted as JSON to a gateway that relays them to devices (e.g. to
+	// FCM and APNs), with PushGatewayToken as bearer token; empty PushGatewayURL disables push
+	PushGatewayURL   string `yaml:"push_gateway_url" env:"PUSH_GATEWAY_URL"`
+	PushGatewayToken string `yaml:"push_gateway_token" env:"PUSH_GATEWAY_TOKEN" redact:"secret"`
+
+	// Google Calendar sync: users connect their calendar through Google's consent screen, which
+	// redirects to GoogleRedirectURL (the API's /integrations/google-calendar/callback URL, as
+	// registered for the OAuth client) and then to GoogleCalendarReturnURL in the frontend.
+	// Calendars are pulled for changes every CalendarSyncIntervalMinutes. Empty GoogleClientID
+	// disables the integration.
+	GoogleClientID              string `yaml:"google_client_id" env:"GOOGLE_CLIENT_ID"`
+	GoogleClientSecret          string `yaml:"google_client_secret" env:"GOOGLE_CLIENT_SECRET" redact:"secret"`
+	GoogleRedirectURL           string `yaml:"google_redirect_url" env:"GOOGLE_REDIRECT_URL"`
+	GoogleCalendarReturnURL     string `yaml:"google_calendar_return_url" env:"GOOGLE_CALENDAR_RETURN_URL"`
+	CalendarSyncIntervalMinutes int    `yaml:"calendar_sync_interval_minutes" env:"CALENDAR_SYNC_INTERVAL_MINUTES"`
+
+	// Shared cache: "memory" (default), "redis" or "none"
+	CacheDriver    string `yaml:"cache_driver" env:"CACHE_DRIVER"`
+	RedisURL       string `yaml:"redis_url" env:"REDIS_URL" redact:"url"`
+	CacheKeyPrefix string `yaml:"cache_key_prefix" env:"CACHE_KEY_PREFIX"`
+
+	// Native TLS: either a certificate/key pair, or automatic Let's Encrypt certificates
+	// for AutocertDomains (comma-separated). Autocert answers HTTP-01 challenges on
+	// ACMEHTTPPort, which also redirects plain HTTP to HTTPS.
+	TLSCertFile      string `yaml:"tls_cert_file" env:"TLS_CERT_FILE"`
+	TLSKeyFile       string `yaml:"tls_key_file" env:"TLS_KEY_FILE"`
+	AutocertDomains  string `yaml:"autocert_domains" env:"AUTOCERT_DOMAINS"`
+	AutocertEmail    string `yaml:"autocert_email" env:"AUTOCERT_EMAIL"`
+	AutocertCacheDir string `yaml:"autocert_cache_dir" env:"AUTOCERT_CACHE_DIR"`
+	ACMEHTTPPort     string `yaml:"acme_http_port" env:"ACME_HTTP_PORT"`
 }
 
-// LoadConfig loads configuration from .env file or environment variables
-func LoadConfig(path string) (*Config, error) {
-	if err := godotenv.Load(path); err != nil {
-		log.Printf("No .env file found at %s, attempting to read from environment variables. Error: %v", path, err)
+// defaults returns the configuration used when nothing overrides a setting
+func defaults() *Config {
+	return &Config{
+		Environment: "development",
+		LogLevel:    "info",
+
+		MongoURI:            "mongodb://localhost:27017",
+		DBName:              "taskflow_db",
+		JWTSecret:           defaultJWTSecret,
+		Port:                "8080",
+		PasswordResetSecret: defaultPasswordResetSecret,
+
+		StorageDriver: "mongo",
+		PostgresURL:   "postgres://localhost:5432/taskflow?sslmode=disable",
+
+		MongoRetryAttempts:          3,
+		MongoRetryBackoffMS:         100,
+		MongoBreakerThreshold:       5,
+		MongoBreakerCooldownSeconds: 10,
+
+		SMTPHost:     "smtp.gmail.com",
+		SMTPPort:     "587",
+		SMTPUsername: "your_email@gmail.com",
+		SMTPPassword: "your_app_password",
+
+		EmailProvider:  "smtp",
+		MailgunAPIBase: "https://api.mailgun.net",
+		SESRegion:      "us-east-1",
+
+		UploadDriver:         "cloudinary",
+		UploadAllowedTypes:   "image/jpeg,image/png,image/gif,image/webp",
+		UploadMaxSizeBytes:   10 << 20,
+		UploadMaxImageWidth:  4096,
+		UploadMaxImageHeight: 4096,
+		UploadQuotaBytes:     100 << 20,
+		S3Endpoint:           "s3.amazonaws.com",
+		S3Region:             "us-east-1",
+		S3UseSSL:             true,
+
+		LocalStorageDir:       "uploads",
+		LocalStoragePublicURL: "/files",
+
+		ClamAVTimeoutSeconds: 30,
+		UploadQuarantineDir:  "quarantine",
+
+		CompressionMinSize: 1024,
+
+		IdempotencyKeyTTLHours: 24,
+		AuthCacheTTLSeconds:    30,
+
+		SessionTTLMinutes:   15,
+		RefreshTokenTTLDays: 30,
+		CookieSameSite:      "lax",
+
+		APIKeyRateLimitPerMinute: 600,
+
+		PasswordHashAlgorithm: "bcrypt",
+		BcryptCost:            10,
+		Argon2MemoryKiB:       64 * 1024,
+		Argon2Iterations:      3,
+		Argon2Parallelism:     2,
+
+		IPBanMaxFailures:   20,
+		IPBanWindowMinutes: 15,
+		IPBanMinutes:       15,
+
+		GRPCPort: "9090",
+
+		JobWorkerEnabled:       true,
+		JobWorkerConcurrency:   2,
+		JobPollIntervalSeconds: 2,
+
+		WeeklyDigestEnabled: true,
+		WeeklyDigestWeekday: "monday",
+		WeeklyDigestHour:    8,
+
+		DailySummaryEnabled: true,
+		DailySummaryHour:    8,
+
+		RetentionHour:                 3,
+		AuditLogRetentionDays:         365,
+		EmailDeliveryRetentionDays:    90,
+		ReadNotificationRetentionDays: 90,
+		CompletedJobRetentionDays:     30,
+
+		StaleTaskDays:        30,
+		StaleTaskWarningDays: 7,
+		StaleTaskAction:      "archive",
+		StaleTaskHour:        4,
+
+		SLAChecksEnabled:        true,
+		SLACheckIntervalMinutes: 15,
+
+		ReadinessCacheSeconds: 60,
+
+		CalendarSyncIntervalMinutes: 5,
+
+		CacheDriver:    "memory",
+		RedisURL:       "redis://localhost:6379/0",
+		CacheKeyPrefix: "taskflow:",
+
+		AutocertCacheDir: "autocert-cache",
+		ACMEHTTPPort:     "80",
 	}
+}
 
-	return &Config{
-		MongoURI:            getEnv("MONGO_URI", "mongodb://localhost:27017"),
-		DBName:              getEnv("DB_NAME", "taskflow_db"),
-		JWTSecret:           getEnv("JWT_SECRET", "your_very_secret_jwt_key_here_change_this_in_production"),
-		Port:                getEnv("PORT", "8080"),
-		PasswordResetSecret: getEnv("PASSWORD_RESET_SECRET", "another_super_secret_key_for_password_resets"),
-
-		SMTPHost:     getEnv("SMTP_HOST", "smtp.gmail.com"),
-		SMTPPort:     getEnv("SMTP_PORT", "587"),
-		SMTPUsername: getEnv("SMTP_USERNAME", "your_email@gmail.com"),
-		SMTPPassword: getEnv("SMTP_PASSWORD", "your_app_password"), // Use app password for Gmail
-
-		CloudinaryCloudName:   getEnv("CLOUDINARY_CLOUD_NAME", ""),
-		CloudinaryAPIKey:      getEnv("CLOUDINARY_API_KEY", ""),
-		CloudinaryAPISecret:   getEnv("CLOUDINARY_API_SECRET", ""),
+// TLSMode reports how the HTTP server terminates TLS: "autocert", "files" or "" (plain HTTP)
+func (c *Config) TLSMode() string {
+	switch {
+	case c.AutocertDomains != "":
+		return "autocert"
+	case c.TLSCertFile != "":
+		return "files"
+	}
+	return ""
+}
+
+// AutocertHosts returns the domains listed in AutocertDomains
+func (c *Config) AutocertHosts() []string {
+	var hosts []string
+	for _, host := range strings.Split(c.AutocertDomains, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// DataKeyring returns the keyring personal data is encrypted with, or nil when no data keys
+// are configured
+func (c *Config) DataKeyring() (*fieldcrypt.Keyring, error) {
+	return fieldcrypt.ParseKeyring(c.DataEncryptionKeys, c.DataEncryptionKeyID)
+}
+
+// MongoClientOptions returns the MongoDB client settings. The caller adds its circuit breaker,
+// if any.
+func (c *Config) MongoClientOptions() (database.ClientOptions, error) {
+	if c.MongoMaxPoolSize < 0 || c.MongoMinPoolSize < 0 || c.MongoServerSelectionTimeoutMS < 0 {
+		return database.ClientOptions{}, errors.New("MONGO_MAX_POOL_SIZE, MONGO_MIN_POOL_SIZE and MONGO_SERVER_SELECTION_TIMEOUT_MS must not be negative")
+	}
+	if c.MongoMaxPoolSize > 0 && c.MongoMinPoolSize > c.MongoMaxPoolSize {
+		return database.ClientOptions{}, errors.New("MONGO_MIN_POOL_SIZE must not exceed MONGO_MAX_POOL_SIZE")
+	}
+	readPreference, err := database.ParseReadPreference(c.MongoReadPreference)
+	if err != nil {
+		return database.ClientOptions{}, fmt.Errorf("MONGO_READ_PREFERENCE: %w", err)
+	}
+	writeConcern, err := database.ParseWriteConcern(c.MongoWriteConcern)
+	if err != nil {
+		return database.ClientOptions{}, fmt.Errorf("MONGO_WRITE_CONCERN: %w", err)
+	}
+	return database.ClientOptions{
+		MaxPoolSize:            uint64(c.MongoMaxPoolSize),
+		MinPoolSize:            uint64(c.MongoMinPoolSize),
+		ReadPreference:         readPreference,
+		WriteConcern:           writeConcern,
+		ServerSelectionTimeout: time.Duration(c.MongoServerSelectionTimeoutMS) * time.Millisecond,
 	}, nil
 }
 
-// getEnv retrieves an environment variable or returns a default value
-func getEnv(key string, defaultValue string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
+// PasswordHasher returns the hasher new passwords are hashed with
+func (c *Config) PasswordHasher() (passhash.Hasher, error) {
+	if c.Argon2MemoryKiB < 0 || c.Argon2Iterations < 0 || c.Argon2Parallelism < 0 || c.Argon2Parallelism > 255 {
+		return nil, errors.New("argon2id parameters must not be negative, and parallelism at most 255")
+	}
+	return passhash.New(c.PasswordHashAlgorithm, c.BcryptCost, passhash.Argon2id{
+		Memory:      uint32(c.Argon2MemoryKiB),
+		Iterations:  uint32(c.Argon2Iterations),
+		Parallelism: uint8(c.Argon2Parallelism),
+	})
+}
+
+// CORSOrigins returns the origins listed in CORSAllowedOrigins
+func (c *Config) CORSOrigins() []string {
+	var origins []string
+	for _, origin := range strings.Split(c.CORSAllowedOrigins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+// CookieSameSiteMode returns CookieSameSite as an http.SameSite, and false if it isn't a mode
+func (c *Config) CookieSameSiteMode() (http.SameSite, bool) {
+	switch strings.ToLower(c.CookieSameSite) {
+	case "lax":
+		return http.SameSiteLaxMode, true
+	case "strict":
+		return http.SameSiteStrictMode, true
+	case "none":
+		return http.SameSiteNoneMode, true
+	}
+	return http.SameSiteDefaultMode, false
+}
+
+// UploadTypes returns the MIME types listed in UploadAllowedTypes; empty means any type
+func (c *Config) UploadTypes() []string {
+	var types []string
+	for _, mimeType := range strings.Split(c.UploadAllowedTypes, ",") {
+		if mimeType = strings.ToLower(strings.TrimSpace(mimeType)); mimeType != "" {
+			types = append(types, mimeType)
+		}
+	}
+	return types
+}
+
+// SenderAddress returns the From address for outgoing email
+func (c *Config) SenderAddress() string {
+	if c.EmailFrom != "" {
+		return c.EmailFrom
+	}
+	return c.SMTPUsername
+}
+
+// Mailer returns the settings for the configured email provider
+func (c *Config) Mailer() mailer.Config {
+	return mailer.Config{
+		Provider:           c.EmailProvider,
+		SMTPHost:           c.SMTPHost,
+		SMTPPort:           c.SMTPPort,
+		SMTPUsername:       c.SMTPUsername,
+		SMTPPassword:       c.SMTPPassword,
+		SendGridAPIKey:     c.SendGridAPIKey,
+		MailgunAPIKey:      c.MailgunAPIKey,
+		MailgunDomain:      c.MailgunDomain,
+		MailgunAPIBase:     c.MailgunAPIBase,
+		SESRegion:          c.SESRegion,
+		SESAccessKeyID:     c.SESAccessKeyID,
+		SESSecretAccessKey: c.SESSecretAccessKey,
+	}
+}
+
+// ErrorTracker returns the client reporting errors to the tracker at SentryDSN, or nil when
+// error reporting is off
+func (c *Config) ErrorTracker() (*errorreport.Sentry, error) {
+	if c.SentryDSN == "" {
+		return nil, nil
+	}
+	environment := c.SentryEnvironment
+	if environment == "" {
+		environment = c.Environment
+	}
+	return errorreport.NewSentry(c.SentryDSN, environment, c.SentryRelease)
+}
+
+// GoogleOAuth returns the OAuth settings of the Google Calendar integration, or nil when it is
+// not configured
+func (c *Config) GoogleOAuth() *oauth2.Config {
+	if c.GoogleClientID == "" {
+		return nil
+	}
+	return gcal.OAuthConfig(c.GoogleClientID, c.GoogleClientSecret, c.GoogleRedirectURL)
+}
+
+// DigestWeekday returns WeeklyDigestWeekday as a time.Weekday, and false if it isn't a day name
+func (c *Config) DigestWeekday() (time.Weekday, bool) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(c.WeeklyDigestWeekday, day.String()) {
+			return day, true
+		}
+	}
+	return time.Sunday, false
+}
+
+// IsProduction reports whether the server runs in production mode
+func (c *Config) IsProduction() bool {
+	return c.Environment == "production"
+}
+
+// Validate checks the configuration for values the server can't run with.
+// All problems are reported together rather than one at a time.
+func (c *Config) Validate() error {
+	var problems []error
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Errorf(format, args...))
+	}
+
+	switch c.Environment {
+	case "development", "staging", "production":
+	default:
+		add("APP_ENV must be development, staging or production (got %q)", c.Environment)
+	}
+	if _, err := logging.ParseLevel(c.LogLevel); err != nil {
+		add("LOG_LEVEL must be debug, info or warn (got %q)", c.LogLevel)
+	}
+
+	if c.IsProduction() {
+		if c.JWTSecret == defaultJWTSecret {
+			add("JWT_SECRET must be changed from its default in production")
+		} else if len(c.JWTSecret) < minProductionSecretLength {
+			add("JWT_SECRET must be at least %d characters in production", minProductionSecretLength)
+		}
+		if c.PasswordResetSecret == defaultPasswordResetSecret {
+			add("PASSWORD_RESET_SECRET must be changed from its default in production")
+		} else if len(c.PasswordResetSecret) < minProductionSecretLength {
+			add("PASSWORD_RESET_SECRET must be at least %d characters in production", minProductionSecretLength)
+		}
+		if c.JWTSecret == c.PasswordResetSecret {
+			add("JWT_SECRET and PASSWORD_RESET_SECRET must differ")
+		}
+	} else if c.JWTSecret == defaultJWTSecret || c.PasswordResetSecret == defaultPasswordResetSecret {
+		logging.Warnf("Using insecure default signing secrets; set JWT_SECRET and PASSWORD_RESET_SECRET before deploying")
+	}
+
+	if c.DBName == "" {
+		add("DB_NAME must not be empty")
+	}
+	switch c.StorageDriver {
+	case "mongo":
+		if err := validateURL(c.MongoURI, "mongodb", "mongodb+srv"); err != nil {
+			add("MONGO_URI: %v", err)
+		}
+		if _, err := c.MongoClientOptions(); err != nil {
+			add("%v", err)
+		}
+	case "postgres":
+		if err := validateURL(c.PostgresURL, "postgres", "postgresql"); err != nil {
+			add("POSTGRES_URL: %v", err)
+		}
+	default:
+		add("STORAGE_DRIVER must be mongo or postgres (got %q)", c.StorageDriver)
+	}
+	if c.MongoRetryAttempts < 1 || c.MongoRetryAttempts > 10 {
+		add("MONGO_RETRY_ATTEMPTS must be between 1 and 10")
+	}
+	if c.MongoRetryBackoffMS < 1 {
+		add("MONGO_RETRY_BACKOFF_MS must be at least 1")
+	}
+	if c.MongoBreakerThreshold < 0 {
+		add("MONGO_BREAKER_THRESHOLD must not be negative")
+	}
+	if c.MongoBreakerThreshold > 0 && c.MongoBreakerCooldownSeconds < 1 {
+		add("MONGO_BREAKER_COOLDOWN_SECONDS must be at least 1")
+	}
+
+	for _, port := range []struct{ key, value string }{{"PORT", c.Port}, {"GRPC_PORT", c.GRPCPort}, {"SMTP_PORT", c.SMTPPort}, {"ACME_HTTP_PORT", c.ACMEHTTPPort}} {
+		if n, err := strconv.Atoi(port.value); err != nil || n < 1 || n > 65535 {
+			add("%s must be a port number between 1 and 65535 (got %q)", port.key, port.value)
+		}
+	}
+
+	if c.CompressionMinSize < 0 {
+		add("COMPRESSION_MIN_SIZE must not be negative")
+	}
+	if c.IdempotencyKeyTTLHours < 1 {
+		add("IDEMPOTENCY_KEY_TTL_HOURS must be at least 1")
+	}
+	if c.APIKeyRateLimitPerMinute < 0 {
+		add("API_KEY_RATE_LIMIT_PER_MINUTE must not be negative")
+	}
+	if c.IPBanMaxFailures < 0 {
+		add("IP_BAN_MAX_FAILURES must not be negative")
+	}
+	if c.IPBanMaxFailures > 0 {
+		if c.IPBanWindowMinutes < 1 {
+			add("IP_BAN_WINDOW_MINUTES must be at least 1")
+		}
+		if c.IPBanMinutes < 1 {
+			add("IP_BAN_MINUTES must be at least 1")
+		}
+	}
+	if c.TrustedProxyHops < 0 {
+		add("TRUSTED_PROXY_HOPS must not be negative")
+	}
+	if c.CommentEditWindowMinutes < 0 {
+		add("COMMENT_EDIT_WINDOW_MINUTES must not be negative")
+	}
+	if c.AuthCacheTTLSeconds < 0 {
+		add("AUTH_CACHE_TTL_SECONDS must not be negative")
+	}
+	if c.CookieAuthEnabled {
+		if c.SessionTTLMinutes < 1 {
+			add("SESSION_TTL_MINUTES must be at least 1")
+		}
+		if c.RefreshTokenTTLDays < 1 {
+			add("REFRESH_TOKEN_TTL_DAYS must be at least 1")
+		}
+		if _, ok := c.CookieSameSiteMode(); !ok {
+			add("COOKIE_SAME_SITE must be lax, strict or none (got %q)", c.CookieSameSite)
+		}
+	}
+	if _, err := c.PasswordHasher(); err != nil {
+		add("PASSWORD_HASH_ALGORITHM: %v", err)
+	}
+	if _, err := c.DataKeyring(); err != nil {
+		add("DATA_ENCRYPTION_KEYS: %v", err)
+	}
+	for _, origin := range c.CORSOrigins() {
+		if err := validateURL(origin, "http", "https"); err != nil {
+			add("CORS_ALLOWED_ORIGINS entry %q: %v", origin, err)
+		}
+	}
+	if c.JobWorkerConcurrency < 1 {
+		add("JOB_WORKER_CONCURRENCY must be at least 1")
+	}
+	if c.JobPollIntervalSeconds < 1 {
+		add("JOB_POLL_INTERVAL_SECONDS must be at least 1")
+	}
+	if _, ok := c.DigestWeekday(); !ok {
+		add("WEEKLY_DIGEST_WEEKDAY must be a day of the week (got %q)", c.WeeklyDigestWeekday)
+	}
+	if c.WeeklyDigestHour < 0 || c.WeeklyDigestHour > 23 {
+		add("WEEKLY_DIGEST_HOUR must be between 0 and 23")
+	}
+	if c.DailySummaryHour < 0 || c.DailySummaryHour > 23 {
+		add("DAILY_SUMMARY_HOUR must be between 0 and 23")
+	}
+	if c.RetentionHour < 0 || c.RetentionHour > 23 {
+		add("RETENTION_HOUR must be between 0 and 23")
+	}
+	for _, retention := range []struct {
+		key  string
+		days int
+	}{
+		{"AUDIT_LOG_RETENTION_DAYS", c.AuditLogRetentionDays},
+		{"EMAIL_DELIVERY_RETENTION_DAYS", c.EmailDeliveryRetentionDays},
+		{"READ_NOTIFICATION_RETENTION_DAYS", c.ReadNotificationRetentionDays},
+		{"COMPLETED_JOB_RETENTION_DAYS", c.CompletedJobRetentionDays},
+	} {
+		if retention.days < 0 {
+			add("%s must not be negative", retention.key)
+		}
+	}
+	if c.StaleTaskDays < 1 {
+		add("STALE_TASK_DAYS must be at least 1")
+	}
+	if c.StaleTaskWarningDays < 0 || c.StaleTaskWarningDays >= c.StaleTaskDays {
+		add("STALE_TASK_WARNING_DAYS must be between 0 and STALE_TASK_DAYS - 1")
+	}
+	if c.StaleTaskAction != "flag" && c.StaleTaskAction != "archive" {
+		add("STALE_TASK_ACTION must be flag or archive (got %q)", c.StaleTaskAction)
+	}
+	if c.StaleTaskHour < 0 || c.StaleTaskHour > 23 {
+		add("STALE_TASK_HOUR must be between 0 and 23")
+	}
+	if c.SLACheckIntervalMinutes < 1 || c.SLACheckIntervalMinutes > 1440 {
+		add("SLA_CHECK_INTERVAL_MINUTES must be between 1 and 1440")
+	}
+	if c.JobAlertWebhookURL != "" {
+		if err := validateURL(c.JobAlertWebhookURL, "http", "https"); err != nil {
+			add("JOB_ALERT_WEBHOOK_URL: %v", err)
+		}
+	}
+	if c.SentryDSN != "" {
+		if _, err := errorreport.NewSentry(c.SentryDSN, "", ""); err != nil {
+			add("SENTRY_DSN: %v", err)
+		}
+	}
+	if c.ReadinessCacheSeconds < 0 {
+		add("READINESS_CACHE_SECONDS must not be negative")
+	}
+	if c.PushGatewayURL != "" {
+		if err := validateURL(c.PushGatewayURL, "http", "https"); err != nil {
+			add("PUSH_GATEWAY_URL: %v", err)
+		}
+	}
+
+	if c.GoogleClientID != "" {
+		if c.GoogleClientSecret == "" {
+			add("GOOGLE_CLIENT_SECRET must be set when GOOGLE_CLIENT_ID is")
+		}
+		if err := validateURL(c.GoogleRedirectURL, "http", "https"); err != nil {
+			add("GOOGLE_REDIRECT_URL: %v", err)
+		}
+		if c.GoogleCalendarReturnURL != "" {
+			if err := validateURL(c.GoogleCalendarReturnURL, "http", "https"); err != nil {
+				add("GOOGLE_CALENDAR_RETURN_URL: %v", err)
+			}
+		}
+	}
+	if c.CalendarSyncIntervalMinutes < 1 {
+		add("CALENDAR_SYNC_INTERVAL_MINUTES must be at least 1")
+	}
+
+	if c.APIV1SunsetDate != "" {
+		if _, err := time.Parse("2006-01-02", c.APIV1SunsetDate); err != nil {
+			add("API_V1_SUNSET_DATE must be a date in YYYY-MM-DD format (got %q)", c.APIV1SunsetDate)
+		}
+	}
+
+	switch c.CacheDriver {
+	case "memory", "none":
+	case "redis":
+		if err := validateURL(c.RedisURL, "redis", "rediss"); err != nil {
+			add("REDIS_URL: %v", err)
+		}
+	default:
+		add("CACHE_DRIVER must be memory, redis or none (got %q)", c.CacheDriver)
+	}
+
+	switch c.EmailProvider {
+	case "smtp":
+	case "sendgrid":
+		if c.SendGridAPIKey == "" {
+			add("SENDGRID_API_KEY must be set when EMAIL_PROVIDER is sendgrid")
+		}
+	case "mailgun":
+		if c.MailgunAPIKey == "" || c.MailgunDomain == "" {
+			add("MAILGUN_API_KEY and MAILGUN_DOMAIN must be set when EMAIL_PROVIDER is mailgun")
+		}
+		if err := validateURL(c.MailgunAPIBase, "https"); err != nil {
+			add("MAILGUN_API_BASE: %v", err)
+		}
+	case "ses":
+		if c.SESRegion == "" {
+			add("SES_REGION must be set when EMAIL_PROVIDER is ses")
+		}
+		if (c.SESAccessKeyID == "") != (c.SESSecretAccessKey == "") {
+			add("SES_ACCESS_KEY_ID and SES_SECRET_ACCESS_KEY must be set together")
+		}
+	default:
+		add("EMAIL_PROVIDER must be smtp, sendgrid, mailgun or ses (got %q)", c.EmailProvider)
+	}
+	if c.EmailTemplateDir != "" {
+		if info, err := os.Stat(c.EmailTemplateDir); err != nil {
+			add("EMAIL_TEMPLATE_DIR: %v", err)
+		} else if !info.IsDir() {
+			add("EMAIL_TEMPLATE_DIR must be a directory (got %q)", c.EmailTemplateDir)
+		}
+	}
+	if c.EmailProvider != "smtp" && c.SenderAddress() == "" {
+		add("EMAIL_FROM must be set when EMAIL_PROVIDER is %s", c.EmailProvider)
+	}
+
+	if c.UploadMaxSizeBytes < 0 || c.UploadMaxImageWidth < 0 || c.UploadMaxImageHeight < 0 || c.UploadQuotaBytes < 0 {
+		add("UPLOAD_MAX_SIZE_BYTES, UPLOAD_MAX_IMAGE_WIDTH, UPLOAD_MAX_IMAGE_HEIGHT and UPLOAD_QUOTA_BYTES must not be negative")
+	}
+	for _, mimeType := range c.UploadTypes() {
+		if major, minor, ok := strings.Cut(mimeType, "/"); !ok || major == "" || minor == "" {
+			add("UPLOAD_ALLOWED_TYPES entry %q is not a MIME type", mimeType)
+		}
+	}
+
+	switch c.UploadDriver {
+	case "cloudinary":
+	case "s3":
+		if c.S3Endpoint == "" || strings.Contains(c.S3Endpoint, "://") {
+			add("S3_ENDPOINT must be a host[:port] without a scheme (got %q)", c.S3Endpoint)
+		}
+		if c.S3Bucket == "" {
+			add("S3_BUCKET must be set when UPLOAD_DRIVER is s3")
+		}
+		if c.S3AccessKeyID == "" || c.S3SecretAccessKey == "" {
+			add("S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY must be set when UPLOAD_DRIVER is s3")
+		}
+		if c.S3PublicURL != "" {
+			if err := validateURL(c.S3PublicURL, "http", "https"); err != nil {
+				add("S3_PUBLIC_URL: %v", err)
+			}
+		}
+	case "local":
+		if c.LocalStorageDir == "" {
+			add("LOCAL_STORAGE_DIR must be set when UPLOAD_DRIVER is local")
+		}
+		if c.LocalStoragePublicURL == "" {
+			add("LOCAL_STORAGE_PUBLIC_URL must be set when UPLOAD_DRIVER is local")
+		}
+	default:
+		add("UPLOAD_DRIVER must be cloudinary, s3 or local (got %q)", c.UploadDriver)
+	}
+	if c.ClamAVAddress != "" {
+		if c.ClamAVTimeoutSeconds < 1 {
+			add("CLAMAV_TIMEOUT_SECONDS must be at least 1")
+		}
+		if c.UploadQuarantineDir == "" {
+			add("UPLOAD_QUARANTINE_DIR must be set when CLAMAV_ADDRESS is set")
+		}
+	}
+
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		add("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if c.AutocertDomains != "" {
+		if c.TLSCertFile != "" {
+			add("AUTOCERT_DOMAINS cannot be combined with TLS_CERT_FILE/TLS_KEY_FILE")
+		}
+		if len(c.AutocertHosts()) == 0 {
+			add("AUTOCERT_DOMAINS must list at least one domain")
+		}
+		if c.AutocertCacheDir == "" {
+			add("AUTOCERT_CACHE_DIR must be set when AUTOCERT_DOMAINS is used")
+		}
+	}
+	if c.TLSCertFile != "" {
+		if _, err := os.Stat(c.TLSCertFile); err != nil {
+			add("TLS_CERT_FILE: %v", err)
+		}
+		if _, err := os.Stat(c.TLSKeyFile); err != nil {
+			add("TLS_KEY_FILE: %v", err)
+		}
+	}
+
+	return errors.Join(problems...)
+}
+
+// validateURL checks that raw parses as an absolute URL with one of the given schemes
+func validateURL(raw string, schemes ...string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return errors.New("not a valid URL")
+	}
+	for _, scheme := range schemes {
+		if u.Scheme == scheme {
+			if u.Host == "" {
+				return errors.New("missing host")
+			}
+			return nil
+		}
 	}
-	return defaultValue
+	return fmt.Errorf("scheme must be one of %s", strings.Join(schemes, ", "))
 }
@@ -0,0 +1,222 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// LoadConfig loads configuration from .env file or environment variables
+func LoadConfig(path string) (*Config, error) {
+	return load("", path, nil)
+}
+
+// Load builds the configuration from, in increasing order of precedence: built-in
+// defaults, an optional YAML file, environment variables (including a .env file)
+// and command-line flags. Besides one flag per setting, args may contain
+// --config <file.yaml> (or CONFIG_FILE) and --env-file <path> (default ".env").
+func Load(args []string) (*Config, error) {
+	cfg, _, err := LoadCommand(args)
+	return cfg, err
+}
+
+// LoadCommand is Load for tools taking a subcommand after the configuration flags: it also
+// returns the arguments from the first non-flag one on, e.g. ["seed-roles"] for
+// "--mongo-uri mongodb://db:27017 seed-roles".
+func LoadCommand(args []string) (*Config, []string, error) {
+	fs := flag.NewFlagSet("taskflow", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML configuration file")
+	envFile := fs.String("env-file", ".env", "path to a .env file")
+
+	overrides := map[string]string{}
+	for _, field := range configFields() {
+		field := field
+		usage := "overrides " + field.env
+		if field.kind == reflect.Bool {
+			fs.BoolFunc(field.flag, usage, func(value string) error {
+				overrides[field.name] = value
+				return nil
+			})
+			continue
+		}
+		fs.Func(field.flag, usage, func(value string) error {
+			overrides[field.name] = value
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	cfg, err := load(*configFile, *envFile, overrides)
+	return cfg, fs.Args(), err
+}
+
+// load applies each configuration layer in turn and validates the result
+func load(configFile, envFile string, overrides map[string]string) (*Config, error) {
+	// The .env file is read rather than loaded into the process environment, so reloads see
+	// its current contents; variables set in the environment still take precedence
+	dotenv, err := godotenv.Read(envFile)
+	if err != nil {
+		log.Printf("No .env file found at %s, attempting to read from environment variables. Error: %v", envFile, err)
+	}
+
+	cfg := defaults()
+	var problems []error
+
+	if configFile != "" {
+		if err := cfg.applyYAML(configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	value := reflect.ValueOf(cfg).Elem()
+	for _, field := range configFields() {
+		raw, exists := os.LookupEnv(field.env)
+		if !exists {
+			raw, exists = dotenv[field.env]
+		}
+		if !exists {
+			continue
+		}
+		if err := setField(value.FieldByName(field.name), raw); err != nil {
+			problems = append(problems, fmt.Errorf("%s %v (got %q)", field.env, err, raw))
+		}
+	}
+	for _, field := range configFields() {
+		raw, exists := overrides[field.name]
+		if !exists {
+			continue
+		}
+		if err := setField(value.FieldByName(field.name), raw); err != nil {
+			problems = append(problems, fmt.Errorf("--%s %v (got %q)", field.flag, err, raw))
+		}
+	}
+	cfg.Environment = strings.ToLower(cfg.Environment)
+	cfg.LogLevel = strings.ToLower(cfg.LogLevel)
+
+	if err := errors.Join(append(problems, cfg.Validate())...); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return cfg, nil
+}
+
+// applyYAML overlays the settings present in a YAML file; unknown keys are rejected
+// so typos don't silently fall back to defaults
+func (c *Config) applyYAML(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && err != io.EOF {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	log.Printf("Loaded configuration file %s", path)
+	return nil
+}
+
+// configField describes how one Config field is named in each configuration source
+type configField struct {
+	name   string // Go field name
+	env    string
+	yaml   string
+	flag   string
+	redact string
+	reload bool // Applied to the running server by Reloader
+	kind   reflect.Kind
+}
+
+// configFields lists the settable Config fields in declaration order
+func configFields() []configField {
+	t := reflect.TypeOf(Config{})
+	fields := make([]configField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		env := f.Tag.Get("env")
+		if env == "" {
+			continue
+		}
+		yamlKey := f.Tag.Get("yaml")
+		fields = append(fields, configField{
+			name:   f.Name,
+			env:    env,
+			yaml:   yamlKey,
+			flag:   strings.ReplaceAll(yamlKey, "_", "-"),
+			redact: f.Tag.Get("redact"),
+			reload: f.Tag.Get("reload") == "true",
+			kind:   f.Type.Kind(),
+		})
+	}
+	return fields
+}
+
+// setField parses raw into a string, int or bool field
+func setField(field reflect.Value, raw string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Int:
+		parsed, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		field.SetInt(int64(parsed))
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return errors.New("must be a boolean")
+		}
+		field.SetBool(parsed)
+	default:
+		return fmt.Errorf("has unsupported type %s", field.Kind())
+	}
+	return nil
+}
+
+// Summary renders the configuration for startup logs with secrets redacted
+func (c *Config) Summary() string {
+	value := reflect.ValueOf(c).Elem()
+	var b strings.Builder
+	for _, field := range configFields() {
+		rendered := fmt.Sprint(value.FieldByName(field.name).Interface())
+		switch field.redact {
+		case "secret":
+			rendered = redactSecret(rendered)
+		case "url":
+			rendered = redactURL(rendered)
+		}
+		fmt.Fprintf(&b, "  %-26s %s\n", field.env, rendered)
+	}
+	return b.String()
+}
+
+// redactSecret hides a secret while still showing whether it is set
+func redactSecret(value string) string {
+	if value == "" {
+		return "(not set)"
+	}
+	return "********"
+}
+
+// redactURL strips the password from a connection string
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "(invalid URL)"
+	}
+	return u.Redacted()
+}
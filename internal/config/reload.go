@@ -0,0 +1,75 @@
+package config
+
+import (
+	"log"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Reloader reloads the configuration from the sources it was first loaded from and applies
+// the settings tagged reload to the running server
+type Reloader struct {
+	args    []string
+	mu      sync.Mutex
+	current *Config
+	apply   []func(*Config)
+}
+
+// NewReloader creates a Reloader for cfg, loaded by Load from args
+func NewReloader(cfg *Config, args []string) *Reloader {
+	current := *cfg
+	return &Reloader{args: args, current: &current}
+}
+
+// OnReload registers fn to apply reloaded settings. fn receives a copy of the configuration
+// after each reload changing a reloadable setting, and must be safe to call while requests
+// are being served.
+func (r *Reloader) OnReload(fn func(*Config)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.apply = append(r.apply, fn)
+}
+
+// Reload reads the configuration again, YAML file, .env file and environment included. It
+// returns the environment variable names of the reloadable settings that changed, which are
+// applied, and of the other settings that changed, which only apply after a restart. Nothing
+// is applied when the new configuration is invalid.
+func (r *Reloader) Reload() (changed, restartRequired []string, err error) {
+	next, err := Load(r.args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changed, restartRequired = []string{}, []string{}
+	current, updated := reflect.ValueOf(r.current).Elem(), reflect.ValueOf(next).Elem()
+	for _, field := range configFields() {
+		from, to := current.FieldByName(field.name), updated.FieldByName(field.name)
+		if from.Interface() == to.Interface() {
+			continue
+		}
+		if !field.reload {
+			restartRequired = append(restartRequired, field.env)
+			continue
+		}
+		from.Set(to)
+		changed = append(changed, field.env)
+	}
+
+	if len(restartRequired) > 0 {
+		log.Printf("Configuration reload: %s changed but only apply after a restart", strings.Join(restartRequired, ", "))
+	}
+	if len(changed) == 0 {
+		log.Println("Configuration reload: no reloadable setting changed")
+		return changed, restartRequired, nil
+	}
+	for _, fn := range r.apply {
+		snapshot := *r.current
+		fn(&snapshot)
+	}
+	log.Printf("Configuration reload: applied %s", strings.Join(changed, ", "))
+	return changed, restartRequired, nil
+}
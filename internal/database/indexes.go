@@ -0,0 +1,235 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// collectionIndexes lists the indexes each collection needs, keyed by collection name.
+// Every index is named explicitly so startup can tell which ones already exist.
+var collectionIndexes = map[string][]mongo.IndexModel{
+	"users": {
+		{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetName("email_unique").SetUnique(true)},
+		{Keys: bson.D{{Key: "role_id", Value: 1}}, Options: options.Index().SetName("role_id")},
+		{Keys: bson.D{{Key: "created_at", Value: -1}}, Options: options.Index().SetName("created_at_desc")},
+	},
+	"roles": {
+		{Keys: bson.D{{Key: "name", Value: 1}}, Options: options.Index().SetName("name_unique").SetUnique(true)},
+	},
+	"tasks": {
+		// Serves the default listing: a user's tasks, newest first
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetName("user_id_created_at")},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "status", Value: 1}}, Options: options.Index().SetName("user_id_status")},
+		{Keys: bson.D{{Key: "status", Value: 1}}, Options: options.Index().SetName("status")},
+		// Serves overdue/upcoming lookups for a user's tasks
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "due_date", Value: 1}}, Options: options.Index().SetName("user_id_due_date")},
+		// Serves completion streaks and digests
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "completed_at", Value: -1}}, Options: options.Index().SetName("user_id_completed_at")},
+		{Keys: bson.D{{Key: "created_at", Value: -1}}, Options: options.Index().SetName("created_at_desc")},
+		{Keys: bson.D{{Key: "title", Value: "text"}, {Key: "description", Value: "text"}}, Options: options.Index().SetName("title_description_text")},
+		// Serve title suggestions; the case-insensitive collation must match the one queries use
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "title", Value: 1}}, Options: options.Index().SetName("user_id_title_ci").SetCollation(&options.Collation{Locale: "en", Strength: 2})},
+		{Keys: bson.D{{Key: "title", Value: 1}}, Options: options.Index().SetName("title_ci").SetCollation(&options.Collation{Locale: "en", Strength: 2})},
+		// Serves a project's tasks, newest first
+		{Keys: bson.D{{Key: "project_id", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetName("project_id_created_at")},
+		// Counts the tasks of a milestone for its progress
+		{Keys: bson.D{{Key: "milestone_id", Value: 1}, {Key: "status", Value: 1}}, Options: options.Index().SetName("milestone_id_status")},
+		// Counts the tasks of a sprint for its summary and rolls them over
+		{Keys: bson.D{{Key: "sprint_id", Value: 1}, {Key: "status", Value: 1}}, Options: options.Index().SetName("sprint_id_status")},
+		// Serves the ?tag= filter (multikey)
+		{Keys: bson.D{{Key: "tags", Value: 1}}, Options: options.Index().SetName("tags")},
+		// Finds the open tasks left untouched for the stale task job
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "updated_at", Value: 1}}, Options: options.Index().SetName("status_updated_at")},
+		// Finds the tasks in a status since before an SLA rule's cutoff
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "status_changed_at", Value: 1}}, Options: options.Index().SetName("status_status_changed_at")},
+	},
+	"projects": {
+		// Serves a user's projects, newest first
+		{Keys: bson.D{{Key: "owner_id", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetName("owner_id_created_at")},
+		// Finds the projects a user is a member of
+		{Keys: bson.D{{Key: "members.user_id", Value: 1}}, Options: options.Index().SetName("members_user_id")},
+	},
+	"announcements": {
+		// Serves GET /announcements/active, which reads the announcements that haven't ended
+		{Keys: bson.D{{Key: "ends_at", Value: 1}}, Options: options.Index().SetName("ends_at")},
+	},
+	"milestones": {
+		// Serves a project's milestones, soonest due first
+		{Keys: bson.D{{Key: "project_id", Value: 1}, {Key: "due_date", Value: 1}}, Options: options.Index().SetName("project_id_due_date")},
+	},
+	"sprints": {
+		// Serves a project's sprints in order and finds the next open one
+		{Keys: bson.D{{Key: "project_id", Value: 1}, {Key: "start_date", Value: 1}}, Options: options.Index().SetName("project_id_start_date")},
+	},
+	"audit_logs": {
+		{Keys: bson.D{{Key: "created_at", Value: -1}}, Options: options.Index().SetName("created_at_desc")},
+		{Keys: bson.D{{Key: "actor_id", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetName("actor_id_created_at")},
+		{Keys: bson.D{{Key: "target_id", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetName("target_id_created_at")},
+	},
+	"uploads": {
+		{Keys: bson.D{{Key: "public_id", Value: 1}}, Options: options.Index().SetName("public_id_unique").SetUnique(true)},
+		// Serves GET /uploads/mine: a user's uploads, newest first
+		{Keys: bson.D{{Key: "uploader_id", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetName("uploader_id_created_at")},
+		// Finds the attachments of a task
+		{Keys: bson.D{{Key: "resource_type", Value: 1}, {Key: "resource_id", Value: 1}}, Options: options.Index().SetName("resource_type_resource_id")},
+	},
+	"pending_uploads": {
+		{Keys: bson.D{{Key: "public_id", Value: 1}}, Options: options.Index().SetName("public_id_unique").SetUnique(true)},
+		// Totals the quota a user's unconfirmed direct uploads reserve
+		{Keys: bson.D{{Key: "uploader_id", Value: 1}, {Key: "expires_at", Value: 1}}, Options: options.Index().SetName("uploader_id_expires_at")},
+	},
+	"email_templates": {
+		{Keys: bson.D{{Key: "name", Value: 1}}, Options: options.Index().SetName("name_unique").SetUnique(true)},
+	},
+	"calendar_connections": {
+		{Keys: bson.D{{Key: "user_id", Value: 1}}, Options: options.Index().SetName("user_id_unique").SetUnique(true)},
+	},
+	"calendar_events": {
+		{Keys: bson.D{{Key: "user_id", Value: 1}}, Options: options.Index().SetName("user_id")},
+	},
+	"api_keys": {
+		{Keys: bson.D{{Key: "hash", Value: 1}}, Options: options.Index().SetName("hash_unique").SetUnique(true)},
+		{Keys: bson.D{{Key: "service_account_id", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetName("service_account_id_created_at")},
+	},
+	"api_key_usage": {
+		{Keys: bson.D{{Key: "key_id", Value: 1}, {Key: "hour", Value: 1}}, Options: options.Index().SetName("key_id_hour_unique").SetUnique(true)},
+		// Usage is reported for up to 90 days
+		{Keys: bson.D{{Key: "hour", Value: 1}}, Options: options.Index().SetName("hour_ttl").SetExpireAfterSeconds(91 * 24 * 60 * 60)},
+	},
+	"notifications": {
+		// Serves GET /notifications: a user's notifications, newest first
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetName("user_id_created_at")},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "read", Value: 1}}, Options: options.Index().SetName("user_id_read")},
+		// Notifications that may be sent twice (e.g. by a retried digest run) are deduplicated by key
+		{Keys: bson.D{{Key: "key", Value: 1}}, Options: options.Index().SetName("key_unique").SetUnique(true).
+			SetPartialFilterExpression(bson.M{"key": bson.M{"$exists": true}})},
+	},
+	"comments": {
+		// Serves a task's comments in order
+		{Keys: bson.D{{Key: "task_id", Value: 1}, {Key: "created_at", Value: 1}}, Options: options.Index().SetName("task_id_created_at")},
+	},
+	"task_pins": {
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "task_id", Value: 1}}, Options: options.Index().SetName("user_id_task_id_unique").SetUnique(true)},
+		// Forgets the pins of deleted tasks
+		{Keys: bson.D{{Key: "task_id", Value: 1}}, Options: options.Index().SetName("task_id")},
+	},
+	"task_views": {
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "task_id", Value: 1}}, Options: options.Index().SetName("user_id_task_id_unique").SetUnique(true)},
+		// Forgets the views of deleted tasks
+		{Keys: bson.D{{Key: "task_id", Value: 1}}, Options: options.Index().SetName("task_id")},
+	},
+	"comment_versions": {
+		{Keys: bson.D{{Key: "comment_id", Value: 1}, {Key: "version", Value: 1}}, Options: options.Index().SetName("comment_id_version_unique").SetUnique(true)},
+	},
+	"auth_tokens": {
+		{Keys: bson.D{{Key: "hash", Value: 1}}, Options: options.Index().SetName("hash_unique").SetUnique(true)},
+		// Revokes a user's other tokens once one is used
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "purpose", Value: 1}}, Options: options.Index().SetName("user_id_purpose")},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0)},
+	},
+	"ip_blocks": {
+		// Serves the admin listing of currently banned addresses
+		{Keys: bson.D{{Key: "banned_until", Value: -1}}, Options: options.Index().SetName("banned_until_desc")},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0)},
+	},
+	"refresh_tokens": {
+		{Keys: bson.D{{Key: "hash", Value: 1}}, Options: options.Index().SetName("hash_unique").SetUnique(true)},
+		// Revokes every token descending from a login at once
+		{Keys: bson.D{{Key: "family_id", Value: 1}}, Options: options.Index().SetName("family_id")},
+		// Revokes every session of a user once their password changes or they are disabled
+		{Keys: bson.D{{Key: "user_id", Value: 1}}, Options: options.Index().SetName("user_id")},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0)},
+	},
+	"email_deliveries": {
+		{Keys: bson.D{{Key: "created_at", Value: -1}}, Options: options.Index().SetName("created_at_desc")},
+		{Keys: bson.D{{Key: "recipient", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetName("recipient_created_at")},
+		{Keys: bson.D{{Key: "job_id", Value: 1}}, Options: options.Index().SetName("job_id")},
+	},
+}
+
+// Indexes returns the indexes each collection needs, keyed by collection name, for the
+// backends that create them in their own way
+func Indexes() map[string][]mongo.IndexModel {
+	return collectionIndexes
+}
+
+// EnsureIndexes creates any missing indexes on the application's collections and logs what it created
+func EnsureIndexes(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	for collectionName, indexes := range collectionIndexes {
+		collection := db.Collection(collectionName)
+
+		existing, err := existingIndexNames(ctx, collection)
+		if err != nil {
+			return fmt.Errorf("failed to list indexes on %s: %w", collectionName, err)
+		}
+
+		var missing []mongo.IndexModel
+		for _, index := range indexes {
+			if !existing[*index.Options.Name] {
+				missing = append(missing, index)
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+
+		created, err := collection.Indexes().CreateMany(ctx, missing)
+		if err != nil {
+			return fmt.Errorf("failed to create indexes on %s: %w", collectionName, err)
+		}
+		for _, name := range created {
+			log.Printf("Created index %s.%s", collectionName, name)
+		}
+	}
+	return nil
+}
+
+// CheckIndexes returns an error naming the indexes EnsureIndexes would create, if any
+func CheckIndexes(ctx context.Context, db *mongo.Database) error {
+	var missing []string
+	for collectionName, indexes := range collectionIndexes {
+		existing, err := existingIndexNames(ctx, db.Collection(collectionName))
+		if err != nil {
+			return fmt.Errorf("failed to list indexes on %s: %w", collectionName, err)
+		}
+		for _, index := range indexes {
+			if !existing[*index.Options.Name] {
+				missing = append(missing, collectionName+"."+*index.Options.Name)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		slices.Sort(missing)
+		return fmt.Errorf("missing indexes %s; restarting the API server creates them", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// existingIndexNames returns the set of index names already present on a collection
+func existingIndexNames(ctx context.Context, collection *mongo.Collection) (map[string]bool, error) {
+	specs, err := collection.Indexes().ListSpecifications(ctx)
+	if err != nil {
+		// A collection that doesn't exist yet has no indexes
+		if cmdErr, ok := err.(mongo.CommandError); ok && cmdErr.Name == "NamespaceNotFound" {
+			return map[string]bool{}, nil
+		}
+		return nil, err
+	}
+
+	names := make(map[string]bool, len(specs))
+	for _, spec := range specs {
+		names[spec.Name] = true
+	}
+	return names, nil
+}
@@ -2,23 +2,83 @@ package database
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"strconv"
 	"time"
 
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
-
-	"github.com/OsGift/taskflow-api/internal/models"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 )
 
+// ClientOptions tunes the MongoDB client, mostly for replica sets. Zero values keep what the
+// connection URI says, or the driver's defaults.
+type ClientOptions struct {
+	MaxPoolSize            uint64                     // Connections per server
+	MinPoolSize            uint64                     // Connections kept open per server, even when idle
+	ReadPreference         *readpref.ReadPref         // Members reads go to, outside transactions
+	WriteConcern           *writeconcern.WriteConcern // Acknowledgement writes wait for
+	ServerSelectionTimeout time.Duration              // How long an operation waits for a suitable server
+	Breaker                *Breaker                   // Told when the deployment has no writable server left
+}
+
+// ParseReadPreference parses a read preference mode: primary, primaryPreferred, secondary,
+// secondaryPreferred or nearest. An empty mode gives nil.
+func ParseReadPreference(mode string) (*readpref.ReadPref, error) {
+	if mode == "" {
+		return nil, nil
+	}
+	m, err := readpref.ModeFromString(mode)
+	if err != nil {
+		return nil, err
+	}
+	return readpref.New(m)
+}
+
+// ParseWriteConcern parses "majority" or the number of members that must acknowledge writes,
+// at least 1: the API reads back what it writes, so unacknowledged writes aren't allowed. An
+// empty value gives nil.
+func ParseWriteConcern(w string) (*writeconcern.WriteConcern, error) {
+	if w == "" {
+		return nil, nil
+	}
+	if w == "majority" {
+		return writeconcern.Majority(), nil
+	}
+	n, err := strconv.Atoi(w)
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("write concern must be majority or a number of members of at least 1 (got %q)", w)
+	}
+	return &writeconcern.WriteConcern{W: n}, nil
+}
+
 // ConnectMongoDB establishes a connection to MongoDB
-func ConnectMongoDB(uri, dbName string) (*mongo.Client, error) {
+func ConnectMongoDB(uri, dbName string, opts ClientOptions) (*mongo.Client, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	clientOpts := options.Client().ApplyURI(uri)
+	if opts.MaxPoolSize > 0 {
+		clientOpts.SetMaxPoolSize(opts.MaxPoolSize)
+	}
+	if opts.MinPoolSize > 0 {
+		clientOpts.SetMinPoolSize(opts.MinPoolSize)
+	}
+	if opts.ReadPreference != nil {
+		clientOpts.SetReadPreference(opts.ReadPreference)
+	}
+	if opts.WriteConcern != nil {
+		clientOpts.SetWriteConcern(opts.WriteConcern)
+	}
+	if opts.ServerSelectionTimeout > 0 {
+		clientOpts.SetServerSelectionTimeout(opts.ServerSelectionTimeout)
+	}
+	if opts.Breaker != nil {
+		clientOpts.SetServerMonitor(opts.Breaker.ServerMonitor())
+	}
+	client, err := mongo.Connect(ctx, clientOpts)
 	if err != nil {
 		return nil, err
 	}
@@ -31,41 +91,3 @@ func ConnectMongoDB(uri, dbName string) (*mongo.Client, error) {
 	log.Printf("Successfully connected to MongoDB: %s", uri)
 	return client, nil
 }
-
-// SeedDefaultRoles ensures that default roles exist in the database
-func SeedDefaultRoles(db *mongo.Database) error {
-	rolesCollection := db.Collection("roles")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	for _, defaultRole := range models.DefaultRoles {
-		filter := bson.M{"name": defaultRole.Name}
-		var existingRole models.Role
-		err := rolesCollection.FindOne(ctx, filter).Decode(&existingRole)
-
-		if err == mongo.ErrNoDocuments {
-			// Role does not exist, insert it
-			_, err := rolesCollection.InsertOne(ctx, defaultRole)
-			if err != nil {
-				return err
-			}
-			log.Printf("Seeded default role: %s", defaultRole.Name)
-		} else if err != nil {
-			// Other error than not found
-			return err
-		} else {
-			// Role exists, update its permissions to ensure they are current
-			update := bson.M{
-				"$set": bson.M{
-					"permissions": defaultRole.Permissions,
-				},
-			}
-			_, err = rolesCollection.UpdateOne(ctx, filter, update)
-			if err != nil {
-				return err
-			}
-			log.Printf("Updated existing default role: %s", defaultRole.Name)
-		}
-	}
-	return nil
-}
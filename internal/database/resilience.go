@@ -0,0 +1,180 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/description"
+
+	"github.com/OsGift/taskflow-api/internal/apperror"
+)
+
+// ErrUnavailable is returned instead of querying MongoDB while the circuit breaker is open
+var ErrUnavailable = apperror.New(apperror.CodeUnavailable, "the database is unavailable, try again later")
+
+// IsTransient reports whether err is a MongoDB failure that may go away on its own: network
+// errors, timeouts (including failing to select a server) and errors the server labels as
+// retryable. A cancelled request isn't a failure of the database.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if mongo.IsNetworkError(err) || mongo.IsTimeout(err) {
+		return true
+	}
+	var labeled mongo.LabeledError
+	return errors.As(err, &labeled) &&
+		(labeled.HasErrorLabel("RetryableWriteError") || labeled.HasErrorLabel("TransientTransactionError"))
+}
+
+// Breaker is a circuit breaker in front of MongoDB. It opens after threshold consecutive
+// transient failures, or as soon as the driver loses every server it can write to, and then
+// refuses requests for cooldown, so they fail fast instead of each waiting out its timeout.
+// Once the cooldown is over, requests go through again, and the next failure opens it at once;
+// a success closes it. While the driver sees no writable server it stays open, until the
+// driver's monitoring finds one again.
+type Breaker struct {
+	threshold int // 0 disables the breaker
+	cooldown  time.Duration
+
+	mu          sync.Mutex
+	failures    int       // Consecutive transient failures
+	openUntil   time.Time // Zero while closed
+	primaryLost bool      // The driver sees no writable server
+}
+
+// NewBreaker creates a Breaker opening after threshold consecutive failures for cooldown; 0
+// threshold never opens it
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{threshold: threshold, cooldown: cooldown}
+}
+
+// Allow reports whether MongoDB may be queried, and otherwise how long until it is worth trying
+// again
+func (b *Breaker) Allow() (bool, time.Duration) {
+	if b == nil || b.threshold == 0 {
+		return true, 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.primaryLost {
+		return false, b.cooldown
+	}
+	if wait := time.Until(b.openUntil); wait > 0 {
+		return false, wait
+	}
+	return true, 0
+}
+
+// Record counts the outcome of a MongoDB operation: a transient error counts as a failure, and
+// anything else, errors included, shows the database answered
+func (b *Breaker) Record(err error) {
+	if b == nil || b.threshold == 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !IsTransient(err) {
+		if !b.openUntil.IsZero() {
+			log.Printf("MongoDB circuit breaker closed: the database answered again")
+		}
+		b.failures = 0
+		b.openUntil = time.Time{}
+		return
+	}
+
+	b.failures++
+	if b.failures >= b.threshold {
+		if b.openUntil.IsZero() {
+			log.Printf("MongoDB circuit breaker opened after %d consecutive failures, last: %v", b.failures, err)
+		}
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
+
+// ServerMonitor returns the driver monitor that tells the breaker when the driver loses, then
+// finds again, a server it can write to. Load balanced deployments don't report servers, so
+// only failures open the breaker there.
+func (b *Breaker) ServerMonitor() *event.ServerMonitor {
+	return &event.ServerMonitor{
+		TopologyDescriptionChanged: func(e *event.TopologyDescriptionChangedEvent) {
+			if b.threshold == 0 || e.NewDescription.Kind == description.LoadBalanced {
+				return
+			}
+			wasWritable, writable := e.PreviousDescription.HasWritableServer(), e.NewDescription.HasWritableServer()
+			if wasWritable == writable {
+				return
+			}
+
+			// Called with the driver's topology locked: only the breaker's own state is touched
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if writable {
+				// Also reached once when the client first connects
+				if b.primaryLost {
+					log.Printf("MongoDB circuit breaker closed: a writable server is available again")
+				}
+				b.primaryLost = false
+				b.failures = 0
+				b.openUntil = time.Time{}
+				return
+			}
+			log.Printf("MongoDB circuit breaker opened: no writable server is available")
+			b.primaryLost = true
+			b.failures = b.threshold
+			b.openUntil = time.Now().Add(b.cooldown)
+		},
+	}
+}
+
+// Retrier runs MongoDB operations through a Breaker, retrying those failing with a transient
+// error with exponential backoff. Only operations that are safe to run twice should be retried:
+// the driver itself already retries a failed write once.
+type Retrier struct {
+	attempts int           // Tries in all, the first included
+	backoff  time.Duration // Wait before the first retry, doubled before each later one
+	breaker  *Breaker
+}
+
+// NewRetrier creates a Retrier making up to attempts tries, backing off from backoff
+func NewRetrier(attempts int, backoff time.Duration, breaker *Breaker) *Retrier {
+	return &Retrier{attempts: max(attempts, 1), backoff: max(backoff, time.Millisecond), breaker: breaker}
+}
+
+// Do runs fn until it succeeds, fails with an error that isn't transient, runs out of attempts
+// or ctx is done. It returns ErrUnavailable without running fn while the breaker is open. A
+// nil Retrier runs fn once.
+func (r *Retrier) Do(ctx context.Context, fn func() error) error {
+	if r == nil {
+		return fn()
+	}
+
+	backoff := r.backoff
+	for attempt := 1; ; attempt++ {
+		if ok, _ := r.breaker.Allow(); !ok {
+			return ErrUnavailable
+		}
+		err := fn()
+		r.breaker.Record(err)
+		if !IsTransient(err) || attempt >= r.attempts || ctx.Err() != nil {
+			return err
+		}
+
+		// Full jitter, so clients that failed together don't retry together
+		wait := rand.N(backoff) + 1
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// transactionSupport caches, per client, whether the deployment supports transactions
+var transactionSupport sync.Map // *mongo.Client -> bool
+
+// SupportsTransactions reports whether the connected deployment is a replica set or sharded
+// cluster. Standalone servers reject multi-document transactions.
+func SupportsTransactions(ctx context.Context, client *mongo.Client) bool {
+	if supported, ok := transactionSupport.Load(client); ok {
+		return supported.(bool)
+	}
+
+	var hello bson.M
+	if err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return false // Don't cache: the server may just be temporarily unreachable
+	}
+	_, isReplicaSet := hello["setName"]
+	isMongos := hello["msg"] == "isdbgrid"
+	supported := isReplicaSet || isMongos
+
+	transactionSupport.Store(client, supported)
+	return supported
+}
+
+// WithTransaction runs fn inside a multi-document transaction, retrying on transient errors.
+// fn must use the context it is given for every operation so they join the transaction.
+// When ctx is already inside a transaction, fn joins it instead of starting another. On
+// deployments without transaction support (standalone development servers), fn runs
+// directly with the original context.
+func WithTransaction(ctx context.Context, db *mongo.Database, fn func(txCtx context.Context) error) error {
+	client := db.Client()
+	if !SupportsTransactions(ctx, client) || mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+
+	session, err := client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	// Transactions must read from the primary, whatever the client's read preference
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	}, options.Transaction().SetReadPreference(readpref.Primary()))
+	return err
+}
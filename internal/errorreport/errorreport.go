@@ -0,0 +1,134 @@
+// Package errorreport sends server errors and panics to an error tracking service
+package errorreport
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// modulePath prefixes the functions of this application, which trackers show as in-app frames
+const modulePath = "github.com/OsGift/taskflow-api/"
+
+// Event is an error or panic, in the event format of Sentry's store API
+type Event struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Level       string            `json:"level"` // "error", or "fatal" for panics
+	Platform    string            `json:"platform"`
+	Message     string            `json:"message,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	User        *User             `json:"user,omitempty"`
+	Request     *Request          `json:"request,omitempty"`
+	Exception   []Exception       `json:"exception,omitempty"`
+}
+
+// Exception is the error behind an event
+type Exception struct {
+	Type       string      `json:"type"`
+	Value      string      `json:"value"`
+	Stacktrace *Stacktrace `json:"stacktrace,omitempty"`
+}
+
+// Stacktrace lists the calls that led to an error, outermost first
+type Stacktrace struct {
+	Frames []Frame `json:"frames"`
+}
+
+// Frame is one call of a stack trace
+type Frame struct {
+	Function string `json:"function"`
+	Module   string `json:"module,omitempty"`
+	AbsPath  string `json:"abs_path"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+// Request is the HTTP request being served when the error happened
+type Request struct {
+	URL     string            `json:"url"`
+	Method  string            `json:"method"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// User is the caller of the request
+type User struct {
+	ID        string `json:"id,omitempty"`
+	IPAddress string `json:"ip_address,omitempty"`
+}
+
+// NewEvent returns an error event with a new ID
+func NewEvent(level, message string) *Event {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return &Event{
+		EventID:   hex.EncodeToString(id),
+		Timestamp: time.Now().UTC(),
+		Level:     level,
+		Platform:  "go",
+		Message:   message,
+	}
+}
+
+// NewStacktrace returns the stack of its caller, leaving out skip more frames
+func NewStacktrace(skip int) *Stacktrace {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(skip+2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var stack []Frame
+	for {
+		frame, more := frames.Next()
+		module, function := splitFunction(frame.Function)
+		stack = append(stack, Frame{
+			Function: function,
+			Module:   module,
+			AbsPath:  frame.File,
+			Lineno:   frame.Line,
+			InApp:    strings.HasPrefix(frame.Function, modulePath),
+		})
+		if !more {
+			break
+		}
+	}
+	// runtime.Callers lists the innermost call first
+	for i, j := 0, len(stack)-1; i < j; i, j = i+1, j-1 {
+		stack[i], stack[j] = stack[j], stack[i]
+	}
+	return &Stacktrace{Frames: stack}
+}
+
+// splitFunction splits a qualified function name such as
+// "github.com/OsGift/taskflow-api/internal/handlers.(*TaskHandler).ListTasks" into its
+// package path and the function within the package
+func splitFunction(name string) (module, function string) {
+	lastSlash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[lastSlash+1:], ".")
+	if dot < 0 {
+		return "", name
+	}
+	return name[:lastSlash+1+dot], name[lastSlash+1+dot+1:]
+}
+
+// buildRelease returns the VCS revision the binary was built from, or its module version
+func buildRelease() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			return setting.Value
+		}
+	}
+	if info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return ""
+}
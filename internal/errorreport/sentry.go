@@ -0,0 +1,128 @@
+package errorreport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/OsGift/taskflow-api/internal/logging"
+)
+
+// maxPendingEvents is how many events may be sending at once; more are dropped, so a flood of
+// errors can't pile up goroutines while the tracker is slow
+const maxPendingEvents = 20
+
+// Sentry reports events to Sentry, or to a compatible tracker such as GlitchTip, through the
+// store API of the project its DSN names
+type Sentry struct {
+	host        string
+	storeURL    string
+	auth        string // X-Sentry-Auth header
+	environment string
+	release     string
+	serverName  string
+	client      *http.Client
+	pending     chan struct{}
+}
+
+// NewSentry creates a Sentry client for dsn (https://<key>@<host>/<project id>). Events are
+// tagged with environment and release; an empty release is taken from the VCS revision the
+// binary was built from.
+func NewSentry(dsn, environment, release string) (*Sentry, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Sentry DSN: %w", err)
+	}
+	path, project, _ := cutLast(strings.TrimSuffix(u.Path, "/"), "/")
+	if u.Scheme == "" || u.Host == "" || u.User == nil || project == "" {
+		return nil, fmt.Errorf("invalid Sentry DSN: expected https://<key>@<host>/<project id>")
+	}
+
+	auth := "Sentry sentry_version=7, sentry_client=taskflow-api/1.0, sentry_key=" + u.User.Username()
+	if secret, ok := u.User.Password(); ok {
+		auth += ", sentry_secret=" + secret
+	}
+	if release == "" {
+		release = buildRelease()
+	}
+	serverName, _ := os.Hostname()
+
+	return &Sentry{
+		host:        u.Host,
+		storeURL:    fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, path, project),
+		auth:        auth,
+		environment: environment,
+		release:     release,
+		serverName:  serverName,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		pending:     make(chan struct{}, maxPendingEvents),
+	}, nil
+}
+
+// Host returns the host name of the tracker
+func (s *Sentry) Host() string {
+	return s.host
+}
+
+// Report sends event in the background, filling in the environment, release and server name
+func (s *Sentry) Report(event *Event) {
+	event.Environment = s.environment
+	event.Release = s.release
+	event.ServerName = s.serverName
+
+	select {
+	case s.pending <- struct{}{}:
+	default:
+		logging.Warnf("Error report %s dropped: too many reports are being sent", event.EventID)
+		return
+	}
+	go func() {
+		defer func() { <-s.pending }()
+		if err := s.send(event); err != nil {
+			logging.Warnf("Failed to send error report %s: %v", event.EventID, err)
+		}
+	}()
+}
+
+// send posts event to the store API
+func (s *Sentry) send(event *Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.storeURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", s.auth)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("tracker answered %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// cutLast slices s around the last instance of sep
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return "", s, false
+}
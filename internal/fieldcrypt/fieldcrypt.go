@@ -0,0 +1,198 @@
+// Package fieldcrypt encrypts individual fields of stored records, such as personal data,
+// with AES-256-GCM application data keys.
+//
+// Encrypted values are strings of the form "enc:<key id>:<base64 nonce and ciphertext>", so
+// they fit in the columns of the plaintext values and name the key they were encrypted with.
+// A Keyring holds the current key, used to encrypt, and older keys still needed to decrypt
+// values written before a key rotation. Values without the prefix are taken as plaintext
+// written before encryption was enabled; Encrypt refuses plaintext starting with the prefix,
+// so a stored value is never mistaken for the other kind.
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// prefix starts every encrypted value
+const prefix = "enc:"
+
+// KeySize is the length of a data key in bytes
+const KeySize = 32
+
+// ErrUnknownKey is returned when decrypting a value encrypted with a key the keyring lacks
+var ErrUnknownKey = errors.New("fieldcrypt: value encrypted with an unknown key")
+
+// ErrMalformed is returned when decrypting a value that isn't a valid encrypted value
+var ErrMalformed = errors.New("fieldcrypt: malformed encrypted value")
+
+// ErrReservedPrefix is returned when encrypting a value that starts like an encrypted value
+var ErrReservedPrefix = errors.New(`fieldcrypt: values can't start with "` + prefix + `"`)
+
+// Keyring encrypts values with its current key and decrypts them with any of its keys.
+// A nil Keyring stores values in plaintext.
+type Keyring struct {
+	currentID string
+	keys      map[string]cipher.AEAD
+}
+
+// ParseKeyring builds a keyring from comma-separated <id>:<base64 key> pairs, encrypting with
+// the key named currentID. Empty keys return a nil keyring.
+func ParseKeyring(keys, currentID string) (*Keyring, error) {
+	if strings.TrimSpace(keys) == "" {
+		return nil, nil
+	}
+
+	k := &Keyring{currentID: currentID, keys: map[string]cipher.AEAD{}}
+	for _, pair := range strings.Split(keys, ",") {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("key %q must be <id>:<base64 key>", pair)
+		}
+		if _, exists := k.keys[id]; exists {
+			return nil, fmt.Errorf("key id %q is listed twice", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != KeySize {
+			return nil, fmt.Errorf("key %q must be %d bytes encoded in base64", id, KeySize)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		if k.keys[id], err = cipher.NewGCM(block); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := k.keys[currentID]; !ok {
+		return nil, fmt.Errorf("current key id %q is not among the keys", currentID)
+	}
+	return k, nil
+}
+
+// Encrypt encrypts value with the current key. field names what the value is stored as, and
+// the value only decrypts as that field, so encrypted values can't be swapped between fields.
+// Empty values are kept empty. Values starting with "enc:" are refused, even without a
+// keyring, as they would read back as encrypted values.
+func (k *Keyring) Encrypt(field, value string) (string, error) {
+	if strings.HasPrefix(value, prefix) {
+		return "", ErrReservedPrefix
+	}
+	if k == nil || value == "" {
+		return value, nil
+	}
+
+	aead := k.keys[k.currentID]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(value)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(value), []byte(field))
+	return prefix + k.currentID + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt returns the plaintext of a value Encrypt returned for field. Plaintext values are
+// returned unchanged.
+func (k *Keyring) Decrypt(field, value string) (string, error) {
+	if !strings.HasPrefix(value, prefix) {
+		return value, nil
+	}
+	id, encoded, ok := strings.Cut(value[len(prefix):], ":")
+	if !ok {
+		return "", ErrMalformed
+	}
+	if k == nil {
+		return "", ErrUnknownKey
+	}
+	aead, ok := k.keys[id]
+	if !ok {
+		return "", ErrUnknownKey
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", ErrMalformed
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(field))
+	if err != nil {
+		return "", ErrMalformed
+	}
+	return string(plaintext), nil
+}
+
+// IsCurrent reports whether value is stored as the keyring stores new values: encrypted with
+// the current key, or in plaintext when there is no keyring. Empty values always are.
+func (k *Keyring) IsCurrent(value string) bool {
+	if value == "" {
+		return true
+	}
+	if k == nil {
+		return !strings.HasPrefix(value, prefix)
+	}
+	return strings.HasPrefix(value, prefix+k.currentID+":")
+}
+
+// EncryptFields encrypts in place the string fields tagged `encrypted:"true"` of the struct v
+// points to. Fields are named after their bson tag.
+func (k *Keyring) EncryptFields(v interface{}) error {
+	return eachField(v, func(name string, field reflect.Value) error {
+		encrypted, err := k.Encrypt(name, field.String())
+		if err != nil {
+			return err
+		}
+		field.SetString(encrypted)
+		return nil
+	})
+}
+
+// DecryptFields decrypts in place the fields EncryptFields encrypts. Fields that fail to
+// decrypt are emptied, and their errors returned together once every field was tried.
+func (k *Keyring) DecryptFields(v interface{}) error {
+	var errs []error
+	eachField(v, func(name string, field reflect.Value) error {
+		plaintext, err := k.Decrypt(name, field.String())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+		field.SetString(plaintext)
+		return nil
+	})
+	return errors.Join(errs...)
+}
+
+// Fields returns the names of the fields tagged `encrypted:"true"` in the struct v points to,
+// with their current values
+func Fields(v interface{}) map[string]string {
+	values := map[string]string{}
+	eachField(v, func(name string, field reflect.Value) error {
+		values[name] = field.String()
+		return nil
+	})
+	return values
+}
+
+// eachField calls fn with the bson name and value of every encrypted string field of the
+// struct v points to
+func eachField(v interface{}, fn func(name string, field reflect.Value) error) error {
+	value := reflect.ValueOf(v).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		structField := value.Type().Field(i)
+		if structField.Tag.Get("encrypted") != "true" || structField.Type.Kind() != reflect.String {
+			continue
+		}
+		name, _, _ := strings.Cut(structField.Tag.Get("bson"), ",")
+		if name == "" {
+			name = structField.Name
+		}
+		if err := fn(name, value.Field(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
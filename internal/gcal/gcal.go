@@ -0,0 +1,226 @@
+// Package gcal is a minimal Google Calendar API v3 client: the OAuth settings needed to act on
+// a user's behalf, and the event calls used to mirror tasks into their calendar
+package gcal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// apiBase is the Calendar API v3 root
+const apiBase = "https://www.googleapis.com/calendar/v3"
+
+// Scope grants access to events only, not to calendar settings or sharing
+const Scope = "https://www.googleapis.com/auth/calendar.events"
+
+// TaskIDProperty is the private extended property holding the ID of the task an event mirrors
+const TaskIDProperty = "taskflowTaskId"
+
+var (
+	// ErrNotFound is returned when an event does not exist
+	ErrNotFound = errors.New("calendar event not found")
+	// ErrConflict is returned when inserting an event whose ID is already used
+	ErrConflict = errors.New("calendar event already exists")
+	// ErrSyncTokenExpired is returned when a sync token is no longer valid; a full sync is needed
+	ErrSyncTokenExpired = errors.New("calendar sync token expired")
+)
+
+// OAuthConfig returns the OAuth 2.0 settings for Google with offline access to events.
+// redirectURL must match one registered for the client in the Google Cloud console.
+func OAuthConfig(clientID, clientSecret, redirectURL string) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       []string{Scope},
+		Endpoint: oauth2.Endpoint{
+			AuthURL:   "https://accounts.google.com/o/oauth2/auth",
+			TokenURL:  "https://oauth2.googleapis.com/token",
+			AuthStyle: oauth2.AuthStyleInParams,
+		},
+	}
+}
+
+// AuthCodeURL returns the consent page URL. Consent is always prompted so Google returns a
+// refresh token even when the user connected before.
+func AuthCodeURL(cfg *oauth2.Config, state string) string {
+	return cfg.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.SetAuthURLParam("prompt", "consent"))
+}
+
+// EventTime is the start or end of an event: a time for timed events, a date for all-day ones
+type EventTime struct {
+	DateTime string `json:"dateTime,omitempty"` // RFC 3339
+	Date     string `json:"date,omitempty"`     // YYYY-MM-DD
+	TimeZone string `json:"timeZone,omitempty"` // IANA time zone the event is shown in
+}
+
+// Time returns the instant of t; all-day events start at midnight UTC
+func (t EventTime) Time() (time.Time, error) {
+	if t.DateTime != "" {
+		return time.Parse(time.RFC3339, t.DateTime)
+	}
+	return time.Parse("2006-01-02", t.Date)
+}
+
+// ExtendedProperties are key/value pairs attached to an event
+type ExtendedProperties struct {
+	Private map[string]string `json:"private,omitempty"`
+}
+
+// Event is the subset of a calendar event used by the sync
+type Event struct {
+	ID                 string              `json:"id,omitempty"`
+	Status             string              `json:"status,omitempty"` // "confirmed", "tentative" or "cancelled" (deleted)
+	Summary            string              `json:"summary,omitempty"`
+	Description        string              `json:"description,omitempty"`
+	Start              *EventTime          `json:"start,omitempty"`
+	End                *EventTime          `json:"end,omitempty"`
+	ExtendedProperties *ExtendedProperties `json:"extendedProperties,omitempty"`
+}
+
+// TaskID returns the ID of the task the event mirrors, or "" for other events
+func (e *Event) TaskID() string {
+	if e.ExtendedProperties == nil {
+		return ""
+	}
+	return e.ExtendedProperties.Private[TaskIDProperty]
+}
+
+// EventList is one page of a list of events
+type EventList struct {
+	Items         []Event `json:"items"`
+	NextPageToken string  `json:"nextPageToken"`
+	NextSyncToken string  `json:"nextSyncToken"` // Only set on the last page
+}
+
+// Client calls the Calendar API with an authorised HTTP client, e.g. from oauth2.Config.Client
+type Client struct {
+	http *http.Client
+}
+
+// NewClient creates a Client sending requests through httpClient
+func NewClient(httpClient *http.Client) *Client {
+	return &Client{http: httpClient}
+}
+
+// InsertEvent creates event in calendarID. Events may carry their own ID (lowercase a-v and
+// digits, 5 to 1024 characters); ErrConflict is returned if it is taken.
+func (c *Client) InsertEvent(ctx context.Context, calendarID string, event *Event) (*Event, error) {
+	var created Event
+	err := c.do(ctx, http.MethodPost, eventsPath(calendarID), nil, event, &created)
+	return &created, err
+}
+
+// PatchEvent updates the fields set in event. Patching a deleted event with Status
+// "confirmed" restores it.
+func (c *Client) PatchEvent(ctx context.Context, calendarID, eventID string, event *Event) (*Event, error) {
+	var updated Event
+	err := c.do(ctx, http.MethodPatch, eventsPath(calendarID)+"/"+url.PathEscape(eventID), nil, event, &updated)
+	return &updated, err
+}
+
+// DeleteEvent deletes an event; deleting one that is already gone is not an error
+func (c *Client) DeleteEvent(ctx context.Context, calendarID, eventID string) error {
+	err := c.do(ctx, http.MethodDelete, eventsPath(calendarID)+"/"+url.PathEscape(eventID), nil, nil, nil)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// ListEvents returns one page of the events changed since syncToken, including deleted ones.
+// An empty syncToken lists every event, and the last page of a listing carries the sync token
+// for the next one.
+func (c *Client) ListEvents(ctx context.Context, calendarID, syncToken, pageToken string) (*EventList, error) {
+	params := url.Values{"showDeleted": {"true"}, "maxResults": {"250"}}
+	if syncToken != "" {
+		params.Set("syncToken", syncToken)
+	}
+	if pageToken != "" {
+		params.Set("pageToken", pageToken)
+	}
+	var list EventList
+	err := c.do(ctx, http.MethodGet, eventsPath(calendarID), params, nil, &list)
+	return &list, err
+}
+
+// eventsPath is the events collection of a calendar
+func eventsPath(calendarID string) string {
+	return "/calendars/" + url.PathEscape(calendarID) + "/events"
+}
+
+// do sends a JSON request and decodes the response into out, when it is not nil
+func (c *Client) do(ctx context.Context, method, path string, params url.Values, in, out interface{}) error {
+	endpoint := apiBase + path
+	if len(params) > 0 {
+		endpoint += "?" + params.Encode()
+	}
+	var body io.Reader
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Google Calendar: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case resp.StatusCode == http.StatusConflict:
+		return ErrConflict
+	case resp.StatusCode == http.StatusGone && params.Get("syncToken") != "":
+		return ErrSyncTokenExpired
+	case resp.StatusCode == http.StatusGone:
+		return ErrNotFound // Deleting an event that was already deleted
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		return fmt.Errorf("Google Calendar rejected the request: %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Revoke invalidates a refresh or access token, e.g. when a user disconnects their calendar
+func Revoke(ctx context.Context, token string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://oauth2.googleapis.com/revoke",
+		strings.NewReader(url.Values{"token": {token}}.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Google: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Google did not revoke the token: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/OsGift/taskflow-api/internal/apperror"
+	"github.com/OsGift/taskflow-api/internal/services"
+)
+
+// NewServer creates a gRPC server exposing the task and user services.
+// Every call must carry "authorization: Bearer <authToken>" metadata; the gRPC API is meant
+// for trusted internal consumers and is not tied to end-user JWTs.
+func NewServer(ts *services.TaskService, us *services.UserService, authToken string) *grpc.Server {
+	server := grpc.NewServer(grpc.UnaryInterceptor(tokenAuthInterceptor(authToken)))
+	registerTaskServer(server, ts)
+	registerUserServer(server, us)
+	return server
+}
+
+// tokenAuthInterceptor rejects calls that don't present the shared service token
+func tokenAuthInterceptor(authToken string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, ok := metadata.FromIncomingContext(ctx)
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "missing metadata")
+		}
+		values := md.Get("authorization")
+		if len(values) == 0 {
+			return nil, status.Error(codes.Unauthenticated, "missing authorization metadata")
+		}
+		token := strings.TrimSpace(strings.TrimPrefix(values[0], "Bearer "))
+		if subtle.ConstantTimeCompare([]byte(token), []byte(authToken)) != 1 {
+			return nil, status.Error(codes.Unauthenticated, "invalid service token")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// toStatus maps service-layer errors onto gRPC status codes by their application error code
+func toStatus(err error) error {
+	appErr := apperror.From(err)
+	switch appErr.Code {
+	case apperror.CodeInvalidArgument, apperror.CodeValidationFailed:
+		return status.Error(codes.InvalidArgument, appErr.Message)
+	case apperror.CodeNotFound:
+		return status.Error(codes.NotFound, appErr.Message)
+	case apperror.CodeAlreadyExists:
+		return status.Error(codes.AlreadyExists, appErr.Message)
+	case apperror.CodeConflict:
+		return status.Error(codes.Aborted, appErr.Message)
+	case apperror.CodeFailedPrecondition:
+		return status.Error(codes.FailedPrecondition, appErr.Message)
+	case apperror.CodeUnauthenticated:
+		return status.Error(codes.Unauthenticated, appErr.Message)
+	case apperror.CodePermissionDenied:
+		return status.Error(codes.PermissionDenied, appErr.Message)
+	case apperror.CodeRateLimited:
+		return status.Error(codes.ResourceExhausted, appErr.Message)
+	case apperror.CodeUnavailable:
+		return status.Error(codes.Unavailable, appErr.Message)
+	}
+	return status.Error(codes.Internal, err.Error())
+}
@@ -0,0 +1,157 @@
+package grpcapi
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/OsGift/taskflow-api/internal/grpcapi/taskflowpb"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/services"
+)
+
+// taskServer implements taskflowpb.TaskServiceServer on top of services.TaskService
+type taskServer struct {
+	taskflowpb.UnimplementedTaskServiceServer
+	taskService *services.TaskService
+}
+
+// registerTaskServer attaches the task service to a gRPC server
+func registerTaskServer(server *grpc.Server, ts *services.TaskService) {
+	taskflowpb.RegisterTaskServiceServer(server, &taskServer{taskService: ts})
+}
+
+// validTaskStatus reports whether status is one of the known task statuses
+func validTaskStatus(status string) bool {
+	switch models.TaskStatus(status) {
+	case models.StatusTodo, models.StatusInProgress, models.StatusDone:
+		return true
+	}
+	return false
+}
+
+// CreateTask creates a task owned by the given user
+func (s *taskServer) CreateTask(ctx context.Context, req *taskflowpb.CreateTaskRequest) (*taskflowpb.Task, error) {
+	userID, err := primitive.ObjectIDFromHex(req.GetUserId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid user_id")
+	}
+	if len(req.GetTitle()) < 5 {
+		return nil, status.Error(codes.InvalidArgument, "title must be at least 5 characters")
+	}
+	taskStatus := req.GetStatus()
+	if taskStatus == "" {
+		taskStatus = string(models.StatusTodo)
+	}
+	if !validTaskStatus(taskStatus) {
+		return nil, status.Error(codes.InvalidArgument, "status must be todo, in_progress or done")
+	}
+
+	task, err := s.taskService.CreateTask(ctx, &models.Task{
+		Title:       req.GetTitle(),
+		Description: req.GetDescription(),
+		Status:      models.TaskStatus(taskStatus),
+		UserID:      userID,
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return taskToProto(task), nil
+}
+
+// GetTask retrieves a single task
+func (s *taskServer) GetTask(ctx context.Context, req *taskflowpb.GetTaskRequest) (*taskflowpb.Task, error) {
+	task, err := s.taskService.GetTaskByID(ctx, req.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return taskToProto(task), nil
+}
+
+// ListTasks lists tasks with optional owner/status filters, search and pagination. Archived
+// tasks are left out unless include_archived is set, as in the REST API.
+func (s *taskServer) ListTasks(ctx context.Context, req *taskflowpb.ListTasksRequest) (*taskflowpb.ListTasksResponse, error) {
+	page, limit := req.GetPage(), req.GetLimit()
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	filter := primitive.M{}
+	if req.GetUserId() != "" {
+		userID, err := primitive.ObjectIDFromHex(req.GetUserId())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid user_id")
+		}
+		filter["user_id"] = userID
+	}
+	if req.GetStatus() != "" {
+		if !validTaskStatus(req.GetStatus()) {
+			return nil, status.Error(codes.InvalidArgument, "status must be todo, in_progress or done")
+		}
+		filter["status"] = models.TaskStatus(req.GetStatus())
+	}
+	if !req.GetIncludeArchived() {
+		filter["archived"] = primitive.M{"$ne": true}
+	}
+
+	list, err := s.taskService.ListTasks(ctx, query.New(filter, page, limit), req.GetSearch())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &taskflowpb.ListTasksResponse{TotalCount: list.TotalCount, Page: list.Page, Limit: list.Limit}
+	for i := range list.Tasks {
+		resp.Tasks = append(resp.Tasks, taskToProto(&list.Tasks[i]))
+	}
+	return resp, nil
+}
+
+// UpdateTask applies a partial update to a task
+func (s *taskServer) UpdateTask(ctx context.Context, req *taskflowpb.UpdateTaskRequest) (*taskflowpb.Task, error) {
+	if req.Title != nil && len(req.GetTitle()) < 5 {
+		return nil, status.Error(codes.InvalidArgument, "title must be at least 5 characters")
+	}
+	if req.Status != nil && !validTaskStatus(req.GetStatus()) {
+		return nil, status.Error(codes.InvalidArgument, "status must be todo, in_progress or done")
+	}
+
+	task, err := s.taskService.UpdateTask(ctx, req.GetId(), &models.UpdateTaskRequest{
+		Title:       req.Title,
+		Description: req.Description,
+		Status:      req.Status,
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return taskToProto(task), nil
+}
+
+// DeleteTask deletes a task
+func (s *taskServer) DeleteTask(ctx context.Context, req *taskflowpb.DeleteTaskRequest) (*emptypb.Empty, error) {
+	if err := s.taskService.DeleteTask(ctx, req.GetId()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// taskToProto converts a models.Task into its protobuf representation
+func taskToProto(task *models.Task) *taskflowpb.Task {
+	return &taskflowpb.Task{
+		Id:          task.ID.Hex(),
+		Title:       task.Title,
+		Description: task.Description,
+		Status:      string(task.Status),
+		UserId:      task.UserID.Hex(),
+		CreatedAt:   timestamppb.New(task.CreatedAt),
+		UpdatedAt:   timestamppb.New(task.UpdatedAt),
+	}
+}
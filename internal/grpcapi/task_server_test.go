@@ -0,0 +1,54 @@
+package grpcapi
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/OsGift/taskflow-api/internal/grpcapi/taskflowpb"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/repository/memstore"
+	"github.com/OsGift/taskflow-api/internal/services"
+)
+
+func TestListTasksHidesArchivedTasksByDefault(t *testing.T) {
+	tasks := services.NewTaskService(memstore.New(), nil)
+	server := &taskServer{taskService: tasks}
+	ctx := context.Background()
+	owner := primitive.NewObjectID()
+	for _, task := range []models.Task{
+		{Title: "Write the report", Status: models.StatusTodo, UserID: owner},
+		{Title: "Plan the offsite", Status: models.StatusDone, UserID: owner, Archived: true},
+	} {
+		if _, err := tasks.CreateTask(ctx, &task); err != nil {
+			t.Fatalf("CreateTask: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		req  *taskflowpb.ListTasksRequest
+		want []string
+	}{
+		{"default", &taskflowpb.ListTasksRequest{UserId: owner.Hex()}, []string{"Write the report"}},
+		{"include_archived", &taskflowpb.ListTasksRequest{UserId: owner.Hex(), IncludeArchived: true}, []string{"Plan the offsite", "Write the report"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := server.ListTasks(ctx, tt.req)
+			if err != nil {
+				t.Fatalf("ListTasks: %v", err)
+			}
+			var titles []string
+			for _, task := range resp.GetTasks() {
+				titles = append(titles, task.GetTitle())
+			}
+			slices.Sort(titles)
+			if !slices.Equal(titles, tt.want) || resp.GetTotalCount() != int64(len(tt.want)) {
+				t.Errorf("ListTasks returned %v (total %d), want %v", titles, resp.GetTotalCount(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package grpcapi
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/OsGift/taskflow-api/internal/grpcapi/taskflowpb"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/services"
+)
+
+// userServer implements taskflowpb.UserServiceServer on top of services.UserService
+type userServer struct {
+	taskflowpb.UnimplementedUserServiceServer
+	userService *services.UserService
+}
+
+// registerUserServer attaches the user service to a gRPC server
+func registerUserServer(server *grpc.Server, us *services.UserService) {
+	taskflowpb.RegisterUserServiceServer(server, &userServer{userService: us})
+}
+
+// GetUser retrieves a user by ID
+func (s *userServer) GetUser(ctx context.Context, req *taskflowpb.GetUserRequest) (*taskflowpb.User, error) {
+	user, err := s.userService.GetUserResponseByID(ctx, req.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return userToProto(user), nil
+}
+
+// GetUserByEmail retrieves a user by email address
+func (s *userServer) GetUserByEmail(ctx context.Context, req *taskflowpb.GetUserByEmailRequest) (*taskflowpb.User, error) {
+	user, err := s.userService.GetUserByEmail(ctx, req.GetEmail())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp, err := s.userService.GetUserResponseByID(ctx, user.ID.Hex())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return userToProto(resp), nil
+}
+
+// ListUsers lists users with an optional role filter and pagination
+func (s *userServer) ListUsers(ctx context.Context, req *taskflowpb.ListUsersRequest) (*taskflowpb.ListUsersResponse, error) {
+	page, limit := req.GetPage(), req.GetLimit()
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	filter := primitive.M{}
+	if req.GetRoleName() != "" {
+		role, err := s.userService.GetRoleByName(ctx, req.GetRoleName())
+		if err != nil {
+			return &taskflowpb.ListUsersResponse{Page: page, Limit: limit}, nil
+		}
+		filter["role_id"] = role.ID
+	}
+
+	list, err := s.userService.ListUsers(ctx, query.New(filter, page, limit))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &taskflowpb.ListUsersResponse{TotalCount: list.TotalCount, Page: list.Page, Limit: list.Limit}
+	for i := range list.Users {
+		resp.Users = append(resp.Users, userToProto(&list.Users[i]))
+	}
+	return resp, nil
+}
+
+// userToProto converts a models.UserResponse into its protobuf representation
+func userToProto(user *models.UserResponse) *taskflowpb.User {
+	return &taskflowpb.User{
+		Id:                  user.ID,
+		FirstName:           user.FirstName,
+		LastName:            user.LastName,
+		Email:               user.Email,
+		RoleName:            user.RoleName,
+		ProfilePictureUrl:   user.ProfilePictureURL,
+		IsEmailVerified:     user.IsEmailVerified,
+		NeedsPasswordChange: user.NeedsPasswordChange,
+		CreatedAt:           timestamppb.New(user.CreatedAt),
+		UpdatedAt:           timestamppb.New(user.UpdatedAt),
+	}
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+
+	"github.com/OsGift/taskflow-api/internal/middleware"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/services"
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+// announcementListSpec whitelists the filters and sorts accepted by GET /announcements
+var announcementListSpec = query.Spec{
+	Filters: []query.Filter{
+		{Param: "severity", Kind: query.Enum, Values: []string{string(models.AnnouncementInfo), string(models.AnnouncementWarning), string(models.AnnouncementCritical)}},
+		{Param: "starts", Field: "starts_at", Kind: query.TimeRange},
+		{Param: "ends", Field: "ends_at", Kind: query.TimeRange},
+		{Param: "created", Field: "created_at", Kind: query.TimeRange},
+	},
+	Sorts:       []string{"starts_at", "ends_at", "created_at"},
+	DefaultSort: "-starts_at",
+}
+
+// AnnouncementHandler lets administrators publish banner announcements and serves the ones
+// showing now to frontends
+type AnnouncementHandler struct {
+	announcementService *services.AnnouncementService
+	validator           *validator.Validate
+}
+
+// NewAnnouncementHandler creates a new AnnouncementHandler
+func NewAnnouncementHandler(as *services.AnnouncementService) *AnnouncementHandler {
+	return &AnnouncementHandler{
+		announcementService: as,
+		validator:           validator.New(),
+	}
+}
+
+// GetActiveAnnouncements lists the announcements to show now. It needs no token, so
+// frontends can show them on the login page too.
+func (h *AnnouncementHandler) GetActiveAnnouncements(w http.ResponseWriter, r *http.Request) {
+	announcements, err := h.announcementService.ActiveAnnouncements(r.Context())
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve announcements")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, announcements)
+}
+
+// ListAnnouncements lists every announcement, past and future included.
+// Supports filtering by severity and starts_from/starts_to, ends_from/ends_to and
+// created_from/created_to ranges.
+func (h *AnnouncementHandler) ListAnnouncements(w http.ResponseWriter, r *http.Request) {
+	q, err := announcementListSpec.Parse(r.URL.Query())
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Invalid query parameters")
+		return
+	}
+
+	announcements, err := h.announcementService.ListAnnouncements(r.Context(), q)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve announcements")
+		return
+	}
+
+	respondWithPage(w, r, announcements)
+}
+
+// GetAnnouncement returns an announcement
+func (h *AnnouncementHandler) GetAnnouncement(w http.ResponseWriter, r *http.Request) {
+	announcement, err := h.announcementService.GetAnnouncement(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve announcement")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, announcement)
+}
+
+// CreateAnnouncement publishes an announcement
+func (h *AnnouncementHandler) CreateAnnouncement(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateAnnouncementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondWithValidationError(w, err)
+		return
+	}
+
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	announcement, err := h.announcementService.CreateAnnouncement(r.Context(), &req, authContext.UserID)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to create announcement")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, announcement)
+}
+
+// UpdateAnnouncement changes the message, severity or times of an announcement
+func (h *AnnouncementHandler) UpdateAnnouncement(w http.ResponseWriter, r *http.Request) {
+	var req models.UpdateAnnouncementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondWithValidationError(w, err)
+		return
+	}
+
+	announcement, err := h.announcementService.UpdateAnnouncement(r.Context(), mux.Vars(r)["id"], &req)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to update announcement")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, announcement)
+}
+
+// DeleteAnnouncement deletes an announcement
+func (h *AnnouncementHandler) DeleteAnnouncement(w http.ResponseWriter, r *http.Request) {
+	if err := h.announcementService.DeleteAnnouncement(r.Context(), mux.Vars(r)["id"]); err != nil {
+		utils.RespondWithAppError(w, err, "Failed to delete announcement")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/services"
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+// auditListSpec whitelists the filters and sorts accepted by GET /audit
+var auditListSpec = query.Spec{
+	Filters: []query.Filter{
+		{Param: "actor_id", Kind: query.ObjectID},
+		{Param: "target_id", Kind: query.Exact},
+		{Param: "method", Kind: query.Enum, Values: []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}},
+		{Param: "route", Kind: query.Exact},
+		{Param: "status", Kind: query.Int},
+		{Param: "created", Field: "created_at", Kind: query.TimeRange},
+	},
+	Sorts:       []string{"created_at", "status", "duration_ms"},
+	DefaultSort: "-created_at",
+}
+
+// AuditHandler exposes the audit trail to administrators
+type AuditHandler struct {
+	auditService *services.AuditService
+}
+
+// NewAuditHandler creates a new AuditHandler
+func NewAuditHandler(as *services.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auditService: as,
+	}
+}
+
+// ListAuditLogs lists audit entries, newest first (requires 'audit:read' permission).
+// Supports filtering by actor_id, target_id, method, route, status and a created_from/created_to range.
+func (h *AuditHandler) ListAuditLogs(w http.ResponseWriter, r *http.Request) {
+	q, err := auditListSpec.Parse(r.URL.Query())
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Invalid query parameters")
+		return
+	}
+
+	logs, err := h.auditService.ListAuditLogs(r.Context(), q)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve audit logs")
+		return
+	}
+
+	respondWithPage(w, r, logs)
+}
@@ -14,17 +14,22 @@ import (
 
 // AuthHandler handles authentication related HTTP requests
 type AuthHandler struct {
-	authService *services.AuthService
-	userService *services.UserService // To get role name for login response
-	validator   *validator.Validate
+	authService    *services.AuthService
+	userService    *services.UserService    // To get role name for login response
+	sessionService *services.SessionService // Nil unless cookie authentication is enabled
+	cookieSameSite http.SameSite
+	validator      *validator.Validate
 }
 
-// NewAuthHandler creates a new AuthHandler
-func NewAuthHandler(as *services.AuthService, us *services.UserService) *AuthHandler {
+// NewAuthHandler creates a new AuthHandler. With a SessionService, logins get session
+// cookies sent with the given SameSite mode instead of a bearer token.
+func NewAuthHandler(as *services.AuthService, us *services.UserService, ss *services.SessionService, cookieSameSite http.SameSite) *AuthHandler {
 	return &AuthHandler{
-		authService: as,
-		userService: us,
-		validator:   validator.New(),
+		authService:    as,
+		userService:    us,
+		sessionService: ss,
+		cookieSameSite: cookieSameSite,
+		validator:      validator.New(),
 	}
 }
 
@@ -37,18 +42,14 @@ func (h *AuthHandler) RegisterUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.validator.Struct(req); err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		utils.RespondWithValidationError(w, err)
 		return
 	}
 
 	// This endpoint is for regular user registration. Admin creation is a separate process.
-	userResponse, err := h.authService.RegisterUser(req, false, "") // not admin creation, no temp password
+	userResponse, err := h.authService.RegisterUser(r.Context(), req, false, "") // not admin creation, no temp password
 	if err != nil {
-		if err.Error() == "email already registered" {
-			utils.RespondWithError(w, http.StatusConflict, err.Error())
-			return
-		}
-		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to register user")
+		utils.RespondWithAppError(w, err, "Failed to register user")
 		return
 	}
 
@@ -64,19 +65,102 @@ func (h *AuthHandler) LoginUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.validator.Struct(req); err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		utils.RespondWithValidationError(w, err)
 		return
 	}
 
-	loginResponse, err := h.authService.LoginUser(req)
+	loginResponse, err := h.authService.LoginUser(r.Context(), req)
+	if err == services.ErrInvalidCredentials {
+		middleware.RecordAuthFailure(r)
+	}
 	if err != nil {
-		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		utils.RespondWithAppError(w, err, "Failed to log in")
 		return
 	}
 
+	if h.sessionService != nil {
+		session, err := h.sessionService.StartSession(r.Context(), loginResponse.UserID)
+		if err != nil {
+			utils.RespondWithAppError(w, err, "Failed to start session")
+			return
+		}
+		csrfToken, err := middleware.SetCSRFCookie(w, r, h.cookieSameSite)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create CSRF token")
+			return
+		}
+		h.setSessionCookies(w, session)
+		loginResponse.Token = "" // Kept out of reach of scripts
+		loginResponse.SessionExpiresAt = &session.AccessExpiresAt
+		loginResponse.CSRFToken = csrfToken
+	}
+
 	utils.RespondWithJSON(w, http.StatusOK, loginResponse)
 }
 
+// RefreshSession exchanges the refresh cookie for new session cookies. Frontends call it
+// when the session cookie is about to expire or a request fails with 401.
+func (h *AuthHandler) RefreshSession(w http.ResponseWriter, r *http.Request) {
+	if h.sessionService == nil {
+		utils.RespondWithError(w, http.StatusNotFound, "Cookie authentication is not enabled")
+		return
+	}
+
+	cookie, err := r.Cookie(middleware.RefreshCookieName)
+	if err != nil {
+		utils.RespondWithAppError(w, services.ErrInvalidRefreshToken, "Failed to refresh session")
+		return
+	}
+
+	session, err := h.sessionService.RefreshSession(r.Context(), cookie.Value)
+	if err != nil {
+		// The cookies are left alone: a concurrent refresh may just have replaced them
+		utils.RespondWithAppError(w, err, "Failed to refresh session")
+		return
+	}
+
+	h.setSessionCookies(w, session)
+	utils.RespondWithJSON(w, http.StatusOK, models.RefreshSessionResponse{
+		Message:          "Session refreshed",
+		SessionExpiresAt: session.AccessExpiresAt,
+	})
+}
+
+// Logout ends the cookie session and clears its cookies
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if h.sessionService == nil {
+		utils.RespondWithError(w, http.StatusNotFound, "Cookie authentication is not enabled")
+		return
+	}
+
+	if cookie, err := r.Cookie(middleware.RefreshCookieName); err == nil {
+		if err := h.sessionService.RevokeSession(r.Context(), cookie.Value); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to log out")
+			return
+		}
+	}
+
+	h.setSessionCookies(w, nil)
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Logged out"})
+}
+
+// setSessionCookies sets the httpOnly cookies of a session, or deletes them when session is nil.
+// The refresh cookie is only sent to the API, not to the locally stored files.
+func (h *AuthHandler) setSessionCookies(w http.ResponseWriter, session *models.Session) {
+	access := &http.Cookie{Name: middleware.SessionCookieName, Path: "/", MaxAge: -1}
+	refresh := &http.Cookie{Name: middleware.RefreshCookieName, Path: "/api/", MaxAge: -1}
+	if session != nil {
+		access.Value, access.Expires, access.MaxAge = session.AccessToken, session.AccessExpiresAt, 0
+		refresh.Value, refresh.Expires, refresh.MaxAge = session.RefreshToken, session.RefreshExpiresAt, 0
+	}
+	for _, cookie := range []*http.Cookie{access, refresh} {
+		cookie.HttpOnly = true
+		cookie.Secure = true
+		cookie.SameSite = h.cookieSameSite
+		http.SetCookie(w, cookie)
+	}
+}
+
 // ForgotPassword handles initiating the password reset process
 func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
 	var req models.ForgotPasswordRequest
@@ -86,13 +170,13 @@ func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.validator.Struct(req); err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		utils.RespondWithValidationError(w, err)
 		return
 	}
 
 	// It's important NOT to reveal if the email exists or not for security reasons.
 	// Always return a success message if the email format is valid.
-	err := h.authService.ForgotPassword(req.Email)
+	err := h.authService.ForgotPassword(r.Context(), req.Email)
 	if err != nil {
 		// Log internal error but return generic success to client
 		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to initiate password reset")
@@ -111,13 +195,13 @@ func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.validator.Struct(req); err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		utils.RespondWithValidationError(w, err)
 		return
 	}
 
-	err := h.authService.ResetPassword(req.Token, req.NewPassword)
+	err := h.authService.ResetPassword(r.Context(), req.Token, req.NewPassword)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, err.Error()) // Specific errors are OK here
+		utils.RespondWithAppError(w, err, "Failed to reset password")
 		return
 	}
 
@@ -133,7 +217,7 @@ func (h *AuthHandler) ChangeTemporaryPassword(w http.ResponseWriter, r *http.Req
 	}
 
 	if err := h.validator.Struct(req); err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		utils.RespondWithValidationError(w, err)
 		return
 	}
 
@@ -149,17 +233,27 @@ func (h *AuthHandler) ChangeTemporaryPassword(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	err = h.authService.ChangeTemporaryPassword(authContext.UserID, req.OldPassword, req.NewPassword)
+	err = h.authService.ChangeTemporaryPassword(r.Context(), authContext.UserID, req.OldPassword, req.NewPassword)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		utils.RespondWithAppError(w, err, "Failed to change password")
 		return
 	}
 
+	// Changing the password ended every session, so a browser signed in with cookies gets a new one
+	if _, err := r.Cookie(middleware.SessionCookieName); h.sessionService != nil && err == nil {
+		session, err := h.sessionService.StartSession(r.Context(), authContext.UserID.Hex())
+		if err != nil {
+			utils.RespondWithAppError(w, err, "Failed to start session")
+			return
+		}
+		h.setSessionCookies(w, session)
+	}
+
 	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Password updated successfully. You can now access the dashboard."})
 }
 
 // VerifyEmail handles setting a user's email as verified.
-// This endpoint expects a verification token in the query params.
+// This endpoint expects the verification token emailed at registration in the query params.
 func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
 	tokenString := r.URL.Query().Get("token")
 	if tokenString == "" {
@@ -167,11 +261,6 @@ func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// In a real app, this verification token would contain the user ID and be validated.
-	// For this simplified example, we'll assume the token is just a placeholder
-	// and we get the user ID from the JWT of the *current* authenticated user.
-	// A more robust system would validate the token itself to get the user ID.
-
 	authContext, err := middleware.GetAuthContext(r)
 	if err != nil {
 		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
@@ -184,9 +273,9 @@ func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.userService.VerifyUserEmail(authContext.UserID)
+	err = h.authService.VerifyEmail(r.Context(), authContext.UserID, tokenString)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		utils.RespondWithAppError(w, err, "Failed to verify email")
 		return
 	}
 
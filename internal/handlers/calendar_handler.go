@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/OsGift/taskflow-api/internal/apperror"
+	"github.com/OsGift/taskflow-api/internal/logging"
+	"github.com/OsGift/taskflow-api/internal/middleware"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/services"
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+// CalendarHandler lets users connect their Google Calendar so task due dates are synced with it
+type CalendarHandler struct {
+	calendarService *services.CalendarService
+	returnURL       string // Frontend page the consent flow ends on; empty responds with JSON
+}
+
+// NewCalendarHandler creates a new CalendarHandler
+func NewCalendarHandler(cs *services.CalendarService, returnURL string) *CalendarHandler {
+	return &CalendarHandler{
+		calendarService: cs,
+		returnURL:       returnURL,
+	}
+}
+
+// Connect returns the Google consent page the caller should be sent to
+func (h *CalendarHandler) Connect(w http.ResponseWriter, r *http.Request) {
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	authURL, err := h.calendarService.ConnectURL(authContext.UserID)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to start connecting Google Calendar")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, models.CalendarConnectResponse{AuthURL: authURL})
+}
+
+// Callback is where Google sends the user back after the consent screen. The signed state
+// parameter identifies the user, as the browser doesn't send the API token here.
+func (h *CalendarHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	if reason := params.Get("error"); reason != "" {
+		// e.g. access_denied when the user declines
+		h.finishConnect(w, r, apperror.New(apperror.CodeFailedPrecondition, "Google Calendar was not connected: "+reason))
+		return
+	}
+	h.finishConnect(w, r, h.calendarService.CompleteConnection(r.Context(), params.Get("state"), params.Get("code")))
+}
+
+// finishConnect reports the outcome of the consent flow, on the frontend's page when configured
+func (h *CalendarHandler) finishConnect(w http.ResponseWriter, r *http.Request, err error) {
+	if h.returnURL == "" {
+		if err != nil {
+			utils.RespondWithAppError(w, err, "Failed to connect Google Calendar")
+			return
+		}
+		utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Google Calendar connected"})
+		return
+	}
+
+	target, _ := url.Parse(h.returnURL) // Validated by config.Validate
+	query := target.Query()
+	if err != nil {
+		appErr := apperror.From(err)
+		if appErr.Code == apperror.CodeInternal {
+			logging.Warnf("Failed to connect Google Calendar: %v", err)
+		}
+		query.Set("calendar", "error")
+		query.Set("message", appErr.Message)
+	} else {
+		query.Set("calendar", "connected")
+	}
+	target.RawQuery = query.Encode()
+	http.Redirect(w, r, target.String(), http.StatusSeeOther)
+}
+
+// GetConnection returns the caller's calendar connection and its sync status
+func (h *CalendarHandler) GetConnection(w http.ResponseWriter, r *http.Request) {
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	conn, err := h.calendarService.GetConnection(r.Context(), authContext.UserID)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve Google Calendar connection")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, conn)
+}
+
+// Disconnect stops syncing the caller's calendar
+func (h *CalendarHandler) Disconnect(w http.ResponseWriter, r *http.Request) {
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if err := h.calendarService.Disconnect(r.Context(), authContext.UserID); err != nil {
+		utils.RespondWithAppError(w, err, "Failed to disconnect Google Calendar")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+
+	"github.com/OsGift/taskflow-api/internal/middleware"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/services"
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+// commentListSpec whitelists the filters and sorts accepted by GET /tasks/{id}/comments
+var commentListSpec = query.Spec{
+	Filters: []query.Filter{
+		{Param: "author_id", Kind: query.ObjectID},
+		{Param: "created", Field: "created_at", Kind: query.TimeRange},
+	},
+	Sorts:       []string{"created_at"},
+	DefaultSort: "created_at",
+}
+
+// CommentHandler handles the comments on tasks. Anyone who can view a task can comment on it.
+type CommentHandler struct {
+	taskService    *services.TaskService
+	commentService *services.CommentService
+	validator      *validator.Validate
+}
+
+// NewCommentHandler creates a new CommentHandler
+func NewCommentHandler(ts *services.TaskService, cs *services.CommentService) *CommentHandler {
+	return &CommentHandler{
+		taskService:    ts,
+		commentService: cs,
+		validator:      validator.New(),
+	}
+}
+
+// visibleTask loads the task in the URL and checks the caller can view it, responding with
+// an error when it can't be found or viewed
+func (h *CommentHandler) visibleTask(w http.ResponseWriter, r *http.Request) (*models.AuthContext, *models.Task, bool) {
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return nil, nil, false
+	}
+
+	task, err := h.taskService.GetTaskByID(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve task")
+		return nil, nil, false
+	}
+
+	// Authorization check: 'task:read_all', owner or project member
+	if !canAccessTask(authContext, task, "task:read_all", models.ProjectRoleViewer) {
+		utils.RespondWithError(w, http.StatusForbidden, "You do not have permission to view this task")
+		return nil, nil, false
+	}
+	return authContext, task, true
+}
+
+// decodeComment reads and validates a comment body, responding with an error when it is invalid
+func (h *CommentHandler) decodeComment(w http.ResponseWriter, r *http.Request) (*models.CommentRequest, bool) {
+	var req models.CommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return nil, false
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondWithValidationError(w, err)
+		return nil, false
+	}
+	return &req, true
+}
+
+// CreateComment adds a comment to a task
+func (h *CommentHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
+	authContext, task, ok := h.visibleTask(w, r)
+	if !ok {
+		return
+	}
+	req, ok := h.decodeComment(w, r)
+	if !ok {
+		return
+	}
+
+	comment, err := h.commentService.CreateComment(r.Context(), task.ID, authContext.UserID, req.Body)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to create comment")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, comment)
+}
+
+// ListComments lists a task's comments, oldest first.
+// Supports filtering by author_id and a created_from/created_to range.
+func (h *CommentHandler) ListComments(w http.ResponseWriter, r *http.Request) {
+	_, task, ok := h.visibleTask(w, r)
+	if !ok {
+		return
+	}
+
+	q, err := commentListSpec.Parse(r.URL.Query())
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Invalid query parameters")
+		return
+	}
+
+	comments, err := h.commentService.ListComments(r.Context(), task.ID, q)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve comments")
+		return
+	}
+
+	respondWithPage(w, r, comments)
+}
+
+// UpdateComment edits a comment. Only its author can, within the configured edit window.
+func (h *CommentHandler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	authContext, task, ok := h.visibleTask(w, r)
+	if !ok {
+		return
+	}
+	req, ok := h.decodeComment(w, r)
+	if !ok {
+		return
+	}
+
+	comment, err := h.commentService.UpdateComment(r.Context(), task.ID, mux.Vars(r)["comment_id"], authContext.UserID, req.Body)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to update comment")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, comment)
+}
+
+// GetCommentHistory returns a comment with its earlier versions
+func (h *CommentHandler) GetCommentHistory(w http.ResponseWriter, r *http.Request) {
+	_, task, ok := h.visibleTask(w, r)
+	if !ok {
+		return
+	}
+
+	history, err := h.commentService.History(r.Context(), task.ID, mux.Vars(r)["comment_id"])
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve comment history")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, history)
+}
+
+// DeleteComment deletes a comment (its author, or anyone with 'task:update_all')
+func (h *CommentHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	authContext, task, ok := h.visibleTask(w, r)
+	if !ok {
+		return
+	}
+
+	comment, err := h.commentService.GetComment(r.Context(), task.ID, mux.Vars(r)["comment_id"])
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve comment")
+		return
+	}
+
+	// Authorization check: 'task:update_all' or author
+	if !authContext.HasPermission("task:update_all") && comment.AuthorID != authContext.UserID {
+		utils.RespondWithError(w, http.StatusForbidden, "You do not have permission to delete this comment")
+		return
+	}
+
+	if err := h.commentService.DeleteComment(r.Context(), comment.ID); err != nil {
+		utils.RespondWithAppError(w, err, "Failed to delete comment")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/OsGift/taskflow-api/internal/logging"
+	"github.com/OsGift/taskflow-api/internal/middleware"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+// ConfigReloader reloads the configuration, applying the settings that don't need a restart
+type ConfigReloader interface {
+	Reload() (changed, restartRequired []string, err error)
+}
+
+// ConfigHandler lets administrators reload the configuration without restarting the server,
+// as sending it SIGHUP does, and change its log level
+type ConfigHandler struct {
+	reloader  ConfigReloader
+	validator *validator.Validate
+}
+
+// NewConfigHandler creates a new ConfigHandler
+func NewConfigHandler(reloader ConfigReloader) *ConfigHandler {
+	return &ConfigHandler{
+		reloader:  reloader,
+		validator: validator.New(),
+	}
+}
+
+// ReloadConfig reads the configuration again and applies the reloadable settings, such as
+// CORS origins and rate limits (requires 'config:reload' permission). An invalid configuration
+// is reported and leaves the running one untouched.
+func (h *ConfigHandler) ReloadConfig(w http.ResponseWriter, r *http.Request) {
+	changed, restartRequired, err := h.reloader.Reload()
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Configuration not reloaded: "+err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, models.ConfigReloadResponse{Changed: changed, RestartRequired: restartRequired})
+}
+
+// GetLogLevel returns the log level of the server handling the request (requires
+// 'log_level:manage' permission)
+func (h *ConfigHandler) GetLogLevel(w http.ResponseWriter, r *http.Request) {
+	utils.RespondWithJSON(w, http.StatusOK, models.LogLevel{Level: logging.CurrentLevel().String()})
+}
+
+// SetLogLevel changes the log level of the server handling the request until it restarts or
+// LOG_LEVEL is reloaded with another value (requires 'log_level:manage' permission). Behind a
+// load balancer, each server's level is set separately.
+func (h *ConfigHandler) SetLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req models.LogLevel
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondWithValidationError(w, err)
+		return
+	}
+
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	level, _ := logging.ParseLevel(req.Level) // Validated above
+	log.Printf("Log level set to %s by user %s", level, authContext.UserID.Hex())
+	logging.SetLevel(level)
+
+	utils.RespondWithJSON(w, http.StatusOK, models.LogLevel{Level: level.String()})
+}
@@ -1,12 +1,16 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 
+	"github.com/OsGift/taskflow-api/internal/middleware"
 	"github.com/OsGift/taskflow-api/internal/models"
 	"github.com/OsGift/taskflow-api/internal/services"
 	"github.com/OsGift/taskflow-api/internal/utils"
@@ -26,10 +30,25 @@ func NewDashboardHandler(ds *services.DashboardService) *DashboardHandler {
 	}
 }
 
-// GetDashboardMetrics handles fetching various dashboard metrics
-func (h *DashboardHandler) GetDashboardMetrics(w http.ResponseWriter, r *http.Request) {
-	// Permission 'dashboard:read_metrics' is checked by middleware
+// requestLocation reads the tz query parameter, an IANA time zone such as "Europe/Paris",
+// defaulting to the caller's preferred time zone, and else UTC. It responds with an error when
+// tz isn't a time zone.
+func requestLocation(w http.ResponseWriter, r *http.Request, authContext *models.AuthContext) (*time.Location, bool) {
+	tz := r.URL.Query().Get("tz")
+	if tz == "" {
+		return authContext.Location(), true
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid tz. Use an IANA time zone name such as Europe/Paris.")
+		return nil, false
+	}
+	return loc, true
+}
 
+// parsePeriod reads the period, start_date and end_date query parameters shared by the
+// dashboard endpoints; custom dates are days in loc. The error message is meant for the client.
+func parsePeriod(r *http.Request, loc *time.Location) (models.DashboardPeriod, *time.Time, *time.Time, error) {
 	periodStr := r.URL.Query().Get("period")
 	if periodStr == "" {
 		periodStr = string(models.PeriodMonthly) // Default to monthly if not specified
@@ -43,36 +62,53 @@ func (h *DashboardHandler) GetDashboardMetrics(w http.ResponseWriter, r *http.Re
 		endStr := r.URL.Query().Get("end_date")
 
 		if startStr == "" || endStr == "" {
-			utils.RespondWithError(w, http.StatusBadRequest, "start_date and end_date are required for custom period")
-			return
+			return "", nil, nil, errors.New("start_date and end_date are required for custom period")
 		}
 
-		parsedStartDate, err := time.Parse("2006-01-02", startStr) // YYYY-MM-DD
+		parsedStartDate, err := time.ParseInLocation("2006-01-02", startStr, loc) // YYYY-MM-DD
 		if err != nil {
-			utils.RespondWithError(w, http.StatusBadRequest, "Invalid start_date format. Use YYYY-MM-DD.")
-			return
+			return "", nil, nil, errors.New("Invalid start_date format. Use YYYY-MM-DD.")
 		}
-		parsedEndDate, err := time.Parse("2006-01-02", endStr) // YYYY-MM-DD
+		parsedEndDate, err := time.ParseInLocation("2006-01-02", endStr, loc) // YYYY-MM-DD
 		if err != nil {
-			utils.RespondWithError(w, http.StatusBadRequest, "Invalid end_date format. Use YYYY-MM-DD.")
-			return
+			return "", nil, nil, errors.New("Invalid end_date format. Use YYYY-MM-DD.")
 		}
-		// Set end date to end of the day for proper range
-		parsedEndDate = parsedEndDate.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
+		// Set end date to end of the day for proper range (days aren't 24 hours long on DST changes)
+		parsedEndDate = parsedEndDate.AddDate(0, 0, 1).Add(-time.Second)
 
 		startDate = &parsedStartDate
 		endDate = &parsedEndDate
 
 		if startDate.After(*endDate) {
-			utils.RespondWithError(w, http.StatusBadRequest, "start_date cannot be after end_date")
-			return
+			return "", nil, nil, errors.New("start_date cannot be after end_date")
 		}
 	} else if period != models.PeriodDaily && period != models.PeriodWeekly && period != models.PeriodMonthly {
-		utils.RespondWithError(w, http.StatusBadRequest, "Invalid period. Must be 'daily', 'weekly', 'monthly', or 'custom'.")
+		return "", nil, nil, errors.New("Invalid period. Must be 'daily', 'weekly', 'monthly', or 'custom'.")
+	}
+	return period, startDate, endDate, nil
+}
+
+// GetDashboardMetrics handles fetching various dashboard metrics. The optional tz query
+// parameter sets where the days of the period start; the caller's time zone by default.
+func (h *DashboardHandler) GetDashboardMetrics(w http.ResponseWriter, r *http.Request) {
+	// Permission 'dashboard:read_metrics' is checked by middleware
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	loc, ok := requestLocation(w, r, authContext)
+	if !ok {
+		return
+	}
+
+	period, startDate, endDate, err := parsePeriod(r, loc)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	metrics, err := h.dashboardService.GetDashboardMetrics(period, startDate, endDate)
+	metrics, err := h.dashboardService.GetDashboardMetrics(r.Context(), period, startDate, endDate, loc)
 	if err != nil {
 		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve dashboard metrics")
 		return
@@ -80,3 +116,106 @@ func (h *DashboardHandler) GetDashboardMetrics(w http.ResponseWriter, r *http.Re
 
 	utils.RespondWithJSON(w, http.StatusOK, metrics)
 }
+
+// GetLeaderboard ranks users by the number of tasks they completed in the period, most first.
+// Users with the same count share a rank; page and limit paginate as on list endpoints, and
+// tz sets where the days of the period start as on the dashboard.
+func (h *DashboardHandler) GetLeaderboard(w http.ResponseWriter, r *http.Request) {
+	// Permission 'dashboard:read_leaderboard' is checked by middleware
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	loc, ok := requestLocation(w, r, authContext)
+	if !ok {
+		return
+	}
+
+	period, startDate, endDate, err := parsePeriod(r, loc)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, _ := strconv.ParseInt(r.URL.Query().Get("page"), 10, 64)
+	limit, _ := strconv.ParseInt(r.URL.Query().Get("limit"), 10, 64)
+
+	leaderboard, err := h.dashboardService.GetLeaderboard(r.Context(), period, startDate, endDate, loc, page, limit)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve leaderboard")
+		return
+	}
+
+	respondWithPage(w, r, leaderboard)
+}
+
+// GetActivityHeatmap returns the number of tasks created and completed on each day of the
+// past year. By default it covers the caller's tasks; user_id selects another user's and
+// scope=workspace everyone's, both of which require 'dashboard:read_metrics'. The optional tz
+// query parameter sets where days start; the caller's time zone by default.
+func (h *DashboardHandler) GetActivityHeatmap(w http.ResponseWriter, r *http.Request) {
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	userID := &authContext.UserID
+	switch scope := r.URL.Query().Get("scope"); scope {
+	case "", "user":
+		if id := r.URL.Query().Get("user_id"); id != "" {
+			target, err := primitive.ObjectIDFromHex(id)
+			if err != nil {
+				utils.RespondWithError(w, http.StatusBadRequest, "Invalid user_id")
+				return
+			}
+			userID = &target
+		}
+	case "workspace":
+		userID = nil
+	default:
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid scope. Must be 'user' or 'workspace'.")
+		return
+	}
+	if (userID == nil || *userID != authContext.UserID) && !authContext.HasPermission("dashboard:read_metrics") {
+		utils.RespondWithError(w, http.StatusForbidden, "You do not have permission to view this activity")
+		return
+	}
+
+	loc, ok := requestLocation(w, r, authContext)
+	if !ok {
+		return
+	}
+
+	heatmap, err := h.dashboardService.GetActivityHeatmap(r.Context(), userID, loc)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve activity")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, heatmap)
+}
+
+// GetMyDashboard returns statistics about the current user's own tasks. The optional tz
+// query parameter (an IANA time zone, e.g. "Europe/Paris") sets where days start; the caller's time zone by default.
+func (h *DashboardHandler) GetMyDashboard(w http.ResponseWriter, r *http.Request) {
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	loc, ok := requestLocation(w, r, authContext)
+	if !ok {
+		return
+	}
+
+	metrics, err := h.dashboardService.GetMyDashboard(r.Context(), authContext.UserID, loc)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve dashboard")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, metrics)
+}
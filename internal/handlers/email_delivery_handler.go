@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/services"
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+// emailDeliveryListSpec whitelists the filters and sorts accepted by GET /email-deliveries
+var emailDeliveryListSpec = query.Spec{
+	Filters: []query.Filter{
+		{Param: "recipient", Kind: query.Contains},
+		{Param: "template", Kind: query.Exact},
+		{Param: "status", Kind: query.Enum, Values: []string{string(models.EmailDeliverySent), string(models.EmailDeliveryFailed)}},
+		{Param: "job_id", Kind: query.ObjectID},
+		{Param: "created", Field: "created_at", Kind: query.TimeRange},
+	},
+	Sorts:       []string{"created_at", "recipient", "status"},
+	DefaultSort: "-created_at",
+}
+
+// EmailDeliveryHandler exposes the email delivery log to administrators
+type EmailDeliveryHandler struct {
+	emailDeliveryService *services.EmailDeliveryService
+}
+
+// NewEmailDeliveryHandler creates a new EmailDeliveryHandler
+func NewEmailDeliveryHandler(eds *services.EmailDeliveryService) *EmailDeliveryHandler {
+	return &EmailDeliveryHandler{
+		emailDeliveryService: eds,
+	}
+}
+
+// ListDeliveries lists email send attempts, newest first (requires 'email_delivery:read' permission).
+// Supports filtering by recipient (substring), template, status, job_id and a created_from/created_to range.
+func (h *EmailDeliveryHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	q, err := emailDeliveryListSpec.Parse(r.URL.Query())
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Invalid query parameters")
+		return
+	}
+
+	deliveries, err := h.emailDeliveryService.ListDeliveries(r.Context(), q)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve email deliveries")
+		return
+	}
+
+	respondWithPage(w, r, deliveries)
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+
+	"github.com/OsGift/taskflow-api/internal/middleware"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/services"
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+// EmailTemplateHandler lets administrators customise transactional email templates
+type EmailTemplateHandler struct {
+	emailTemplateService *services.EmailTemplateService
+	validator            *validator.Validate
+}
+
+// NewEmailTemplateHandler creates a new EmailTemplateHandler
+func NewEmailTemplateHandler(ets *services.EmailTemplateService) *EmailTemplateHandler {
+	return &EmailTemplateHandler{
+		emailTemplateService: ets,
+		validator:            validator.New(),
+	}
+}
+
+// ListTemplates lists every email template and whether it has been customised
+func (h *EmailTemplateHandler) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := h.emailTemplateService.ListTemplates(r.Context())
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve email templates")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, templates)
+}
+
+// GetTemplate returns the template currently used for an email
+func (h *EmailTemplateHandler) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	template, err := h.emailTemplateService.GetTemplate(r.Context(), name)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve email template")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, template)
+}
+
+// UpdateTemplate stores a customised template, after checking it only uses the email's variables
+func (h *EmailTemplateHandler) UpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	var req models.UpdateEmailTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondWithValidationError(w, err)
+		return
+	}
+
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	template, err := h.emailTemplateService.UpdateTemplate(r.Context(), name, &req, authContext.UserID)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to update email template")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, template)
+}
+
+// ResetTemplate discards a customised template so the built-in one is used again
+func (h *EmailTemplateHandler) ResetTemplate(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	if err := h.emailTemplateService.ResetTemplate(r.Context(), name); err != nil {
+		utils.RespondWithAppError(w, err, "Failed to reset email template")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SendTestEmail sends a template rendered with sample data to the given address, so the
+// email provider configuration can be checked without going through a real user flow
+func (h *EmailTemplateHandler) SendTestEmail(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	var req models.SendTestEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondWithValidationError(w, err)
+		return
+	}
+
+	result, err := h.emailTemplateService.SendTestEmail(r.Context(), name, &req)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to send test email")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, result)
+}
+
+// PreviewTemplate renders a template with sample data, optionally with unsaved changes
+func (h *EmailTemplateHandler) PreviewTemplate(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	var req models.PreviewEmailTemplateRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondWithValidationError(w, err)
+		return
+	}
+
+	preview, err := h.emailTemplateService.PreviewTemplate(r.Context(), name, &req)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to preview email template")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, preview)
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/OsGift/taskflow-api/internal/logging"
+	"github.com/OsGift/taskflow-api/internal/middleware"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/services"
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+// ndjsonFlushEvery is how many records are written between flushes of a streamed listing
+const ndjsonFlushEvery = 100
+
+// ExportHandler serves full data exports to administrators, and task backups and printable
+// tasks to users
+type ExportHandler struct {
+	exportService *services.ExportService
+	taskService   *services.TaskService
+}
+
+// NewExportHandler creates a new ExportHandler
+func NewExportHandler(es *services.ExportService, ts *services.TaskService) *ExportHandler {
+	return &ExportHandler{
+		exportService: es,
+		taskService:   ts,
+	}
+}
+
+// ExportData streams every role, user, task and comment as newline-delimited JSON (requires
+// 'data:export' permission). Records are written as they are read, so the export never sits in
+// memory; an export that fails part-way ends without its "end" record.
+func (h *ExportHandler) ExportData(w http.ResponseWriter, r *http.Request) {
+	filename := "taskflow-export-" + time.Now().UTC().Format("20060102T150405Z") + ".ndjson"
+	flush := startDownload(w, "application/x-ndjson", filename)
+	if err := h.exportService.Export(r.Context(), w, flush); err != nil {
+		// The status has already been sent; the missing end record tells the client it's incomplete
+		logging.Warnf("Data export stopped: %v", err)
+	}
+}
+
+// ExportMyTasks streams a backup of all the caller's tasks, with their comments and
+// attachments inlined, as a single JSON document (format=json, the default) or as one line of
+// JSON per task (format=ndjson).
+func (h *ExportHandler) ExportMyTasks(w http.ResponseWriter, r *http.Request) {
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+	case "ndjson":
+		filename := "taskflow-tasks-" + time.Now().UTC().Format("20060102T150405Z") + ".ndjson"
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+		streamNDJSON(w, nil, func(fn func(*models.TaskBackupEntry) error) error {
+			return h.exportService.EachTaskBackupEntry(r.Context(), authContext.UserID, fn)
+		})
+		return
+	default:
+		utils.RespondWithError(w, http.StatusBadRequest, "format must be json or ndjson")
+		return
+	}
+
+	filename := "taskflow-tasks-" + time.Now().UTC().Format("20060102T150405Z") + ".json"
+	flush := startDownload(w, "application/json", filename)
+	if err := h.exportService.ExportUserTasks(r.Context(), authContext.UserID, w, flush); err != nil {
+		// The status has already been sent; the unterminated document tells the client it's incomplete
+		logging.Warnf("Task backup of user %s stopped: %v", authContext.UserID.Hex(), err)
+	}
+}
+
+// ExportTaskPDF renders a task, with its comments and the changes made to it, as a printable
+// PDF for anyone who can view the task. format=pdf is the only format, and the default; times
+// are printed in the tz time zone, the caller's by default.
+func (h *ExportHandler) ExportTaskPDF(w http.ResponseWriter, r *http.Request) {
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	if format := r.URL.Query().Get("format"); format != "" && format != "pdf" {
+		utils.RespondWithError(w, http.StatusBadRequest, "format must be pdf")
+		return
+	}
+	loc, ok := requestLocation(w, r, authContext)
+	if !ok {
+		return
+	}
+
+	task, err := h.taskService.GetTaskByID(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve task")
+		return
+	}
+
+	// Authorization check: 'task:read_all', owner or project member
+	if !canAccessTask(authContext, task, "task:read_all", models.ProjectRoleViewer) {
+		utils.RespondWithError(w, http.StatusForbidden, "You do not have permission to view this task")
+		return
+	}
+
+	// A single task is small enough to render before anything is sent, so failures get a proper error
+	var doc bytes.Buffer
+	if err := h.exportService.TaskPDF(r.Context(), task, loc, &doc); err != nil {
+		utils.RespondWithAppError(w, err, "Failed to export task")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `attachment; filename="task-`+task.ID.Hex()+`.pdf"`)
+	w.Header().Set("Content-Length", strconv.Itoa(doc.Len()))
+	w.WriteHeader(http.StatusOK)
+	if _, err := doc.WriteTo(w); err != nil {
+		logging.Warnf("Failed to send the PDF of task %s: %v", task.ID.Hex(), err)
+	}
+}
+
+// startDownload sends the headers of a streamed file download and returns a function flushing
+// what has been written so far
+func startDownload(w http.ResponseWriter, contentType, filename string) func() {
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	return startStream(w, contentType)
+}
+
+// startStream sends the headers of a streamed response and returns a function flushing what
+// has been written so far
+func startStream(w http.ResponseWriter, contentType string) func() {
+	rc := http.NewResponseController(w)
+	// The server's write timeout is meant for ordinary responses; a large stream takes longer
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		logging.Warnf("Failed to lift the write deadline for a streamed response: %v", err)
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	return func() { rc.Flush() }
+}
+
+// wantsNDJSON reports whether a listing was requested as a stream of newline-delimited JSON,
+// with format=ndjson or by accepting application/x-ndjson
+func wantsNDJSON(r *http.Request) bool {
+	return r.URL.Query().Get("format") == "ndjson" || strings.Contains(r.Header.Get("Accept"), "application/x-ndjson")
+}
+
+// streamNDJSON writes every record each produces as one line of JSON, ignoring the listing's
+// paging, so listings of any size are sent as the database is read instead of being built in
+// memory. With fields, records keep only their id and those fields. A stream that fails
+// part-way is cut off, so the client can't mistake it for a complete one.
+func streamNDJSON[T any](w http.ResponseWriter, fields []string, each func(fn func(*T) error) error) {
+	flush := startStream(w, "application/x-ndjson")
+	encoder := json.NewEncoder(w)
+	var written int
+	err := each(func(record *T) error {
+		var line interface{} = record
+		if len(fields) > 0 {
+			selected, err := selectFields(record, fields)
+			if err != nil {
+				return err
+			}
+			line = selected
+		}
+		if err := encoder.Encode(line); err != nil {
+			return err
+		}
+		written++
+		if written%ndjsonFlushEvery == 0 {
+			flush()
+		}
+		return nil
+	})
+	if err != nil {
+		// The status has already been sent; aborting drops the connection without ending the body
+		logging.Warnf("Streamed response stopped after %d records: %v", written, err)
+		panic(http.ErrAbortHandler)
+	}
+	flush()
+}
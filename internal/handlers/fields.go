@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+// selectFields returns the JSON object of record with only its id and the given fields
+func selectFields(record interface{}, fields []string) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+	return keepFields(object, fields), nil
+}
+
+// keepFields removes the members of object other than id and the given fields
+func keepFields(object map[string]json.RawMessage, fields []string) map[string]json.RawMessage {
+	keep := map[string]bool{"id": true}
+	for _, field := range fields {
+		keep[field] = true
+	}
+	for key := range object {
+		if !keep[key] {
+			delete(object, key)
+		}
+	}
+	return object
+}
+
+// respondWithFields writes a list response whose records, in its listKey array, keep only
+// their id and the fields selected with ?fields=. Without selected fields the response is
+// written whole.
+func respondWithFields(w http.ResponseWriter, response interface{}, listKey string, fields []string) {
+	if len(fields) == 0 {
+		utils.RespondWithJSON(w, http.StatusOK, response)
+		return
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to encode response")
+		return
+	}
+	var object map[string]json.RawMessage
+	var records []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to encode response")
+		return
+	}
+	if err := json.Unmarshal(object[listKey], &records); err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to encode response")
+		return
+	}
+	for _, record := range records {
+		keepFields(record, fields)
+	}
+	if object[listKey], err = json.Marshal(records); err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to encode response")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, object)
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/OsGift/taskflow-api/internal/storage"
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+// FileHandler serves files stored by the local storage backend
+type FileHandler struct {
+	root string
+}
+
+// NewFileHandler creates a FileHandler serving files below root
+func NewFileHandler(root string) *FileHandler {
+	return &FileHandler{root: root}
+}
+
+// ServeFile handles GET /files/{path}
+func (h *FileHandler) ServeFile(w http.ResponseWriter, r *http.Request) {
+	path, err := storage.ResolvePath(h.root, mux.Vars(r)["path"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "File not found")
+		return
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "File not found")
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		utils.RespondWithError(w, http.StatusNotFound, "File not found")
+		return
+	}
+
+	// Uploaded content is untrusted: only inert types are rendered inline, anything else
+	// (HTML, scripts, ...) is forced to download, and the browser may never sniff or run it
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(file, head)
+	contentType := http.DetectContentType(head[:n])
+	if !inlineContentType(contentType) {
+		contentType = "application/octet-stream"
+		w.Header().Set("Content-Disposition", "attachment")
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to read file")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; sandbox")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+}
+
+// inlineContentType reports whether a sniffed type is safe to display in the browser
+func inlineContentType(contentType string) bool {
+	return strings.HasPrefix(contentType, "image/") ||
+		strings.HasPrefix(contentType, "video/") ||
+		strings.HasPrefix(contentType, "audio/") ||
+		contentType == "application/pdf"
+}
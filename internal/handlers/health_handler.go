@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/OsGift/taskflow-api/internal/services"
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+// HealthHandler serves the readiness probe of load balancers and orchestrators
+type HealthHandler struct {
+	readinessService *services.ReadinessService
+}
+
+// NewHealthHandler creates a new HealthHandler
+func NewHealthHandler(rs *services.ReadinessService) *HealthHandler {
+	return &HealthHandler{readinessService: rs}
+}
+
+// Ready reports whether the API's dependencies work: 200 when every check passes, and 503
+// naming the failing checks otherwise. Why a check fails is only logged.
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	report := h.readinessService.Report(r.Context())
+	status := http.StatusOK
+	if !report.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	utils.RespondWithJSON(w, status, report)
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/OsGift/taskflow-api/internal/logging"
+	"github.com/OsGift/taskflow-api/internal/middleware"
+	"github.com/OsGift/taskflow-api/internal/services"
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+// maxImportSize is the largest export accepted by an import
+const maxImportSize = 20 << 20
+
+// ImportHandler handles imports of other task managers' exports
+type ImportHandler struct {
+	importService *services.ImportService
+}
+
+// NewImportHandler creates a new ImportHandler
+func NewImportHandler(is *services.ImportService) *ImportHandler {
+	return &ImportHandler{
+		importService: is,
+	}
+}
+
+// ImportTasks creates tasks for the caller from the export in the request body: a Trello board
+// JSON export or a Todoist backup, depending on the source in the path. With ?dry_run=true it only
+// returns what would be imported.
+func (h *ImportHandler) ImportTasks(w http.ResponseWriter, r *http.Request) {
+	dryRun := false
+	if raw := r.URL.Query().Get("dry_run"); raw != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(raw); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "dry_run must be true or false")
+			return
+		}
+	}
+
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	// Creating thousands of tasks can outlast the server's write timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		logging.Warnf("Failed to lift the write deadline for an import: %v", err)
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+
+	preview, err := h.importService.Import(r.Context(), authContext.UserID, mux.Vars(r)["source"], r.Body, dryRun)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to import tasks")
+		return
+	}
+
+	status := http.StatusCreated
+	if dryRun {
+		status = http.StatusOK
+	}
+	utils.RespondWithJSON(w, status, preview)
+}
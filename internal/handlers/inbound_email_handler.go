@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OsGift/taskflow-api/internal/logging"
+	"github.com/OsGift/taskflow-api/internal/middleware"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/services"
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+const (
+	// maxInboundDescriptionLength caps how much of an email body is copied into a task
+	maxInboundDescriptionLength = 10000
+
+	// maxMailgunTimestampSkew is how far from now the timestamp of a signed Mailgun webhook may be
+	maxMailgunTimestampSkew = 15 * time.Minute
+)
+
+var (
+	// sendGridDKIMResult matches one "@domain : result" entry of SendGrid's dkim field
+	sendGridDKIMResult = regexp.MustCompile(`^\s*@([A-Za-z0-9.-]+)\s*:\s*(\w+)\s*$`)
+	// dkimSigningDomain matches the d= tag of a DKIM-Signature header
+	dkimSigningDomain = regexp.MustCompile(`(?:^|;)\s*d=([^;\s]+)`)
+	// dkimSignatureHeader matches the DKIM-Signature fields of a raw header block
+	dkimSignatureHeader = regexp.MustCompile(`(?im)^dkim-signature:`)
+)
+
+// InboundEmailHandler converts emails received via SendGrid/Mailgun inbound parse webhooks into tasks
+type InboundEmailHandler struct {
+	taskService       *services.TaskService
+	userService       *services.UserService
+	secret            string // Shared secret expected in the webhook URL's "token" query parameter
+	mailgunSigningKey string // When set, every webhook must carry a valid Mailgun signature
+}
+
+// NewInboundEmailHandler creates a new InboundEmailHandler
+func NewInboundEmailHandler(ts *services.TaskService, us *services.UserService, secret, mailgunSigningKey string) *InboundEmailHandler {
+	return &InboundEmailHandler{
+		taskService:       ts,
+		userService:       us,
+		secret:            secret,
+		mailgunSigningKey: mailgunSigningKey,
+	}
+}
+
+// ReceiveEmail handles an inbound parse webhook and creates a task for the sender's account.
+// Subject becomes the title and the plain-text body becomes the description.
+// The webhook must carry the shared token and, with a Mailgun signing key configured, a valid
+// Mailgun signature. The From address must be vouched for by the provider's SPF or DKIM checks,
+// and belong to an enabled user account. Other emails are acknowledged with 200 and ignored so
+// the provider doesn't keep retrying.
+func (h *InboundEmailHandler) ReceiveEmail(w http.ResponseWriter, r *http.Request) {
+	if h.secret == "" {
+		utils.RespondWithError(w, http.StatusNotFound, "Inbound email is not enabled")
+		return
+	}
+	token := r.URL.Query().Get("token")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.secret)) != 1 {
+		middleware.RecordAuthFailure(r)
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid webhook token")
+		return
+	}
+
+	// SendGrid posts multipart/form-data; Mailgun may use either multipart or urlencoded forms
+	if err := r.ParseMultipartForm(10 << 20); err != nil && err != http.ErrNotMultipart {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid form payload")
+		return
+	}
+
+	if h.mailgunSigningKey != "" && !validMailgunSignature(r, h.mailgunSigningKey, time.Now()) {
+		middleware.RecordAuthFailure(r)
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid webhook signature")
+		return
+	}
+
+	// The From header is what the user sees and what DKIM vouches for
+	senderAddress, err := mail.ParseAddress(r.FormValue("from"))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Missing or invalid sender address")
+		return
+	}
+	if !senderAuthenticated(r, senderAddress.Address) {
+		logging.Warnf("Inbound email from %s ignored: neither SPF nor DKIM vouch for the sender", senderAddress.Address)
+		utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Sender could not be authenticated; email ignored."})
+		return
+	}
+
+	user, err := h.userService.GetUserByEmail(r.Context(), senderAddress.Address)
+	if err != nil {
+		user, err = h.userService.GetUserByEmail(r.Context(), strings.ToLower(senderAddress.Address))
+	}
+	if err != nil || user.Disabled || user.MergedInto != nil || user.IsServiceAccount {
+		utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Sender does not match any active account; email ignored."})
+		return
+	}
+
+	title := strings.TrimSpace(r.FormValue("subject"))
+	if title == "" {
+		title = "Task from email"
+	} else if len(title) < 5 {
+		title = "Email: " + title // Titles must be at least 5 characters
+	}
+
+	description := strings.TrimSpace(firstFormValue(r, "stripped-text", "body-plain", "text"))
+	if len(description) > maxInboundDescriptionLength {
+		description = description[:maxInboundDescriptionLength]
+	}
+
+	task, err := h.taskService.CreateTask(r.Context(), &models.Task{
+		Title:       title,
+		Description: description,
+		Status:      models.StatusTodo,
+		UserID:      user.ID,
+	})
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create task from email")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, task)
+}
+
+// validMailgunSignature checks the timestamp, token and signature fields Mailgun signs its
+// webhooks with: the signature is the hex HMAC-SHA256 of timestamp and token under the
+// signing key, and the timestamp must be recent so captured requests can't be replayed later
+func validMailgunSignature(r *http.Request, signingKey string, now time.Time) bool {
+	timestamp, token := r.FormValue("timestamp"), r.FormValue("token")
+	signature, err := hex.DecodeString(r.FormValue("signature"))
+	if err != nil || timestamp == "" || token == "" {
+		return false
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if skew := now.Sub(time.Unix(seconds, 0)); skew > maxMailgunTimestampSkew || skew < -maxMailgunTimestampSkew {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(signingKey))
+	mac.Write([]byte(timestamp + token))
+	return hmac.Equal(signature, mac.Sum(nil))
+}
+
+// senderAuthenticated reports whether the provider's checks vouch for the From address from:
+// DKIM passed with a signature of its domain, or SPF passed for an envelope sender that is the
+// same address. Mailgun reports its checks in the message-headers field, SendGrid in its SPF,
+// dkim and envelope fields.
+func senderAuthenticated(r *http.Request, from string) bool {
+	var spfPassed bool
+	var envelopeSender string
+	var dkimDomains []string
+
+	if messageHeaders := r.FormValue("message-headers"); messageHeaders != "" {
+		var headers [][]string
+		if err := json.Unmarshal([]byte(messageHeaders), &headers); err != nil {
+			return false
+		}
+		spfPassed, dkimDomains = mailgunResults(headers)
+		envelopeSender = r.FormValue("sender")
+	} else {
+		spfPassed = strings.EqualFold(r.FormValue("SPF"), "pass")
+		var envelope struct {
+			From string `json:"from"`
+		}
+		if err := json.Unmarshal([]byte(r.FormValue("envelope")), &envelope); err == nil {
+			envelopeSender = envelope.From
+		}
+		// The raw MIME message replaces the headers field when SendGrid is set to post it
+		dkimDomains = sendGridDKIMDomains(r.FormValue("dkim"), headerBlock(firstFormValue(r, "headers", "email")))
+	}
+
+	if spfPassed && strings.EqualFold(envelopeSender, from) {
+		return true
+	}
+	domain := from[strings.LastIndex(from, "@")+1:]
+	for _, dkimDomain := range dkimDomains {
+		if strings.EqualFold(dkimDomain, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// mailgunResults reads Mailgun's SPF result and the domains DKIM passed for from the message
+// headers. Mailgun prepends its X-Mailgun-Spf and X-Mailgun-Dkim-Check-Result headers, so only
+// the first of each is its own: later ones came with the message. The DKIM result doesn't say
+// which signature passed, so it only vouches for a domain when the message has a single
+// signature.
+func mailgunResults(headers [][]string) (spfPassed bool, dkimDomains []string) {
+	var spfResult, dkimResult *string
+	var signingDomains []string
+	for _, header := range headers {
+		if len(header) != 2 {
+			continue
+		}
+		switch strings.ToLower(header[0]) {
+		case "x-mailgun-spf":
+			if spfResult == nil {
+				spfResult = &header[1]
+			}
+		case "x-mailgun-dkim-check-result":
+			if dkimResult == nil {
+				dkimResult = &header[1]
+			}
+		case "dkim-signature":
+			match := dkimSigningDomain.FindStringSubmatch(header[1])
+			if match == nil {
+				match = []string{"", ""} // Still counts as a signature
+			}
+			signingDomains = append(signingDomains, match[1])
+		}
+	}
+
+	spfPassed = spfResult != nil && strings.EqualFold(*spfResult, "pass")
+	if dkimResult != nil && strings.EqualFold(*dkimResult, "pass") && len(signingDomains) == 1 {
+		dkimDomains = signingDomains
+	}
+	return spfPassed, dkimDomains
+}
+
+// sendGridDKIMDomains returns the domains SendGrid's dkim field ("{@example.com : pass}")
+// reports a passed check for. The field lists one entry per signature, so when it doesn't
+// parse into as many entries as the raw headers have signatures, a signing domain made up to
+// look like more entries may have been copied into it, and none is trusted.
+func sendGridDKIMDomains(results, rawHeaders string) []string {
+	results = strings.TrimSpace(results)
+	results = strings.TrimSuffix(strings.TrimPrefix(results, "{"), "}")
+	if results == "" {
+		return nil
+	}
+	entries := strings.Split(results, ",")
+	if len(dkimSignatureHeader.FindAllStringIndex(rawHeaders, -1)) != len(entries) {
+		return nil
+	}
+
+	var domains []string
+	for _, entry := range entries {
+		match := sendGridDKIMResult.FindStringSubmatch(entry)
+		if match == nil {
+			return nil
+		}
+		if strings.EqualFold(match[2], "pass") {
+			domains = append(domains, match[1])
+		}
+	}
+	return domains
+}
+
+// headerBlock returns the header section of a raw message, which ends at the first empty line
+func headerBlock(message string) string {
+	message = strings.ReplaceAll(message, "\r\n", "\n")
+	if end := strings.Index(message, "\n\n"); end >= 0 {
+		return message[:end+1]
+	}
+	return message
+}
+
+// firstFormValue returns the first non-empty form value among keys
+func firstFormValue(r *http.Request, keys ...string) string {
+	for _, key := range keys {
+		if value := r.FormValue(key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// inboundRequest returns a parsed webhook request posting form
+func inboundRequest(t *testing.T, form url.Values) *http.Request {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/inbound-email", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := r.ParseForm(); err != nil {
+		t.Fatalf("ParseForm: %v", err)
+	}
+	return r
+}
+
+// mailgunForm returns a Mailgun webhook form with the given message headers
+func mailgunForm(t *testing.T, sender string, headers ...[]string) url.Values {
+	t.Helper()
+	encoded, err := json.Marshal(headers)
+	if err != nil {
+		t.Fatalf("encoding headers: %v", err)
+	}
+	return url.Values{"sender": {sender}, "message-headers": {string(encoded)}}
+}
+
+func TestSenderAuthenticatedMailgun(t *testing.T) {
+	const from = "ceo@victim.example"
+	victimSignature := []string{"DKIM-Signature", "v=1; a=rsa-sha256; d=victim.example; s=mail; b=abc"}
+	attackerSignature := []string{"DKIM-Signature", "v=1; a=rsa-sha256; d=attacker.example; s=mail; b=abc"}
+
+	tests := []struct {
+		name string
+		form url.Values
+		want bool
+	}{
+		{
+			name: "SPF pass for the same envelope sender",
+			form: mailgunForm(t, from, []string{"X-Mailgun-Spf", "Pass"}, []string{"X-Mailgun-Dkim-Check-Result", "Fail"}),
+			want: true,
+		},
+		{
+			name: "SPF pass for another envelope sender",
+			form: mailgunForm(t, "bounce@attacker.example", []string{"X-Mailgun-Spf", "Pass"}),
+			want: false,
+		},
+		{
+			name: "forged SPF result after Mailgun's",
+			form: mailgunForm(t, from,
+				[]string{"X-Mailgun-Spf", "Fail"},
+				[]string{"X-Mailgun-Dkim-Check-Result", "Fail"},
+				[]string{"X-Mailgun-Spf", "Pass"}),
+			want: false,
+		},
+		{
+			name: "DKIM pass with a single signature of the From domain",
+			form: mailgunForm(t, "bounce@mailer.example",
+				[]string{"X-Mailgun-Spf", "Fail"},
+				[]string{"X-Mailgun-Dkim-Check-Result", "Pass"},
+				victimSignature),
+			want: true,
+		},
+		{
+			name: "forged DKIM result after Mailgun's",
+			form: mailgunForm(t, "bounce@attacker.example",
+				[]string{"X-Mailgun-Spf", "Fail"},
+				[]string{"X-Mailgun-Dkim-Check-Result", "Fail"},
+				[]string{"X-Mailgun-Dkim-Check-Result", "Pass"},
+				victimSignature),
+			want: false,
+		},
+		{
+			name: "DKIM pass for another domain",
+			form: mailgunForm(t, "bounce@attacker.example",
+				[]string{"X-Mailgun-Spf", "Pass"},
+				[]string{"X-Mailgun-Dkim-Check-Result", "Pass"},
+				attackerSignature),
+			want: false,
+		},
+		{
+			name: "unverified signature of the From domain next to a valid one",
+			form: mailgunForm(t, "bounce@attacker.example",
+				[]string{"X-Mailgun-Spf", "Pass"},
+				[]string{"X-Mailgun-Dkim-Check-Result", "Pass"},
+				attackerSignature,
+				victimSignature),
+			want: false,
+		},
+		{
+			name: "no results",
+			form: mailgunForm(t, from, victimSignature),
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := senderAuthenticated(inboundRequest(t, tt.form), from); got != tt.want {
+				t.Errorf("senderAuthenticated = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSenderAuthenticatedSendGrid(t *testing.T) {
+	const from = "ceo@victim.example"
+	oneSignature := "From: " + from + "\nDKIM-Signature: v=1; d=victim.example;\n  b=abc\nSubject: hi\n"
+
+	tests := []struct {
+		name string
+		form url.Values
+		want bool
+	}{
+		{
+			name: "SPF pass for the same envelope sender",
+			form: url.Values{"SPF": {"pass"}, "envelope": {`{"from":"` + from + `"}`}},
+			want: true,
+		},
+		{
+			name: "SPF pass for another envelope sender",
+			form: url.Values{"SPF": {"pass"}, "envelope": {`{"from":"bounce@attacker.example"}`}},
+			want: false,
+		},
+		{
+			name: "DKIM pass for the From domain",
+			form: url.Values{"dkim": {"{@victim.example : pass}"}, "headers": {oneSignature}},
+			want: true,
+		},
+		{
+			name: "DKIM pass for another domain",
+			form: url.Values{"dkim": {"{@attacker.example : pass}"}, "headers": {oneSignature}},
+			want: false,
+		},
+		{
+			name: "signing domain made up to look like a passed result",
+			form: url.Values{"dkim": {"{@victim.example : pass, @attacker.example : fail}"}, "headers": {oneSignature}},
+			want: false,
+		},
+		{
+			name: "signature header forged in the body of the raw message",
+			form: url.Values{
+				"dkim":  {"{@victim.example : pass, @attacker.example : fail}"},
+				"email": {oneSignature + "\nDKIM-Signature: v=1; d=victim.example;\n"},
+			},
+			want: false,
+		},
+		{
+			name: "DKIM result without headers",
+			form: url.Values{"dkim": {"{@victim.example : pass}"}},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := senderAuthenticated(inboundRequest(t, tt.form), from); got != tt.want {
+				t.Errorf("senderAuthenticated = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/services"
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+// ipBlockListSpec whitelists the filters and sorts accepted by GET /ip-blocks
+var ipBlockListSpec = query.Spec{
+	Filters: []query.Filter{
+		{Param: "ip", Field: "_id", Kind: query.Exact},
+		{Param: "banned_until", Kind: query.TimeRange},
+		{Param: "last_failure", Field: "last_failure_at", Kind: query.TimeRange},
+	},
+	Sorts:       []string{"last_failure_at", "banned_until", "failures", "bans"},
+	DefaultSort: "-last_failure_at",
+}
+
+// IPBlockHandler lets administrators inspect and lift the bans of addresses that failed to
+// authenticate too often
+type IPBlockHandler struct {
+	ipBlockService *services.IPBlockService
+}
+
+// NewIPBlockHandler creates a new IPBlockHandler
+func NewIPBlockHandler(ibs *services.IPBlockService) *IPBlockHandler {
+	return &IPBlockHandler{
+		ipBlockService: ibs,
+	}
+}
+
+// ListIPBlocks lists the addresses with recent failed authentication attempts or bans,
+// most recent failure first (requires 'ip_block:manage' permission). Passing
+// banned_until_from=<now> lists the addresses banned at the moment.
+func (h *IPBlockHandler) ListIPBlocks(w http.ResponseWriter, r *http.Request) {
+	q, err := ipBlockListSpec.Parse(r.URL.Query())
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Invalid query parameters")
+		return
+	}
+
+	blocks, err := h.ipBlockService.ListBlocks(r.Context(), q)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve IP blocks")
+		return
+	}
+
+	respondWithPage(w, r, blocks)
+}
+
+// ClearIPBlock lifts the ban of an address and forgets its failed attempts (requires
+// 'ip_block:manage' permission). Other servers may refuse the address for a few more seconds.
+func (h *IPBlockHandler) ClearIPBlock(w http.ResponseWriter, r *http.Request) {
+	if err := h.ipBlockService.ClearBlock(r.Context(), mux.Vars(r)["ip"]); err != nil {
+		utils.RespondWithAppError(w, err, "Failed to clear IP block")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
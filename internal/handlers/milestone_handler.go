@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/services"
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+// milestoneListSpec whitelists the filters and sorts accepted by GET /projects/{id}/milestones
+var milestoneListSpec = query.Spec{
+	Filters: []query.Filter{
+		{Param: "due", Field: "due_date", Kind: query.TimeRange},
+		{Param: "created", Field: "created_at", Kind: query.TimeRange},
+	},
+	Sorts:       []string{"due_date", "created_at", "title"},
+	DefaultSort: "due_date",
+}
+
+// MilestoneHandler handles the milestones of projects. Members can see them; managers
+// create, change and delete them.
+type MilestoneHandler struct {
+	projectService   *services.ProjectService
+	milestoneService *services.MilestoneService
+	validator        *validator.Validate
+}
+
+// NewMilestoneHandler creates a new MilestoneHandler
+func NewMilestoneHandler(ps *services.ProjectService, ms *services.MilestoneService) *MilestoneHandler {
+	return &MilestoneHandler{
+		projectService:   ps,
+		milestoneService: ms,
+		validator:        validator.New(),
+	}
+}
+
+// CreateMilestone handles creating a milestone in a project
+func (h *MilestoneHandler) CreateMilestone(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateMilestoneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondWithValidationError(w, err)
+		return
+	}
+
+	authContext, project, ok := projectFor(w, r, h.projectService, canManageProject, "You do not have permission to manage the milestones of this project")
+	if !ok {
+		return
+	}
+
+	milestone, err := h.milestoneService.CreateMilestone(r.Context(), project.ID, authContext.UserID, &req)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to create milestone")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, milestone)
+}
+
+// ListMilestones lists a project's milestones with their progress, soonest due first.
+// Supports due_from/due_to and created_from/created_to ranges.
+func (h *MilestoneHandler) ListMilestones(w http.ResponseWriter, r *http.Request) {
+	_, project, ok := projectFor(w, r, h.projectService, canViewProject, "You do not have permission to view this project")
+	if !ok {
+		return
+	}
+
+	q, err := milestoneListSpec.Parse(r.URL.Query())
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Invalid query parameters")
+		return
+	}
+
+	milestones, err := h.milestoneService.ListMilestones(r.Context(), project.ID, q)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve milestones")
+		return
+	}
+
+	respondWithPage(w, r, milestones)
+}
+
+// GetMilestone handles retrieving a single milestone with its progress
+func (h *MilestoneHandler) GetMilestone(w http.ResponseWriter, r *http.Request) {
+	milestone, ok := h.milestoneFor(w, r, canViewProject, "You do not have permission to view this project")
+	if !ok {
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, milestone)
+}
+
+// UpdateMilestone handles changing the title, description or due date of a milestone
+func (h *MilestoneHandler) UpdateMilestone(w http.ResponseWriter, r *http.Request) {
+	var req models.UpdateMilestoneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondWithValidationError(w, err)
+		return
+	}
+
+	milestone, ok := h.milestoneFor(w, r, canManageProject, "You do not have permission to manage the milestones of this project")
+	if !ok {
+		return
+	}
+
+	updatedMilestone, err := h.milestoneService.UpdateMilestone(r.Context(), milestone.ID, &req)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to update milestone")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, updatedMilestone)
+}
+
+// DeleteMilestone handles deleting a milestone. Its tasks stay in the project.
+func (h *MilestoneHandler) DeleteMilestone(w http.ResponseWriter, r *http.Request) {
+	milestone, ok := h.milestoneFor(w, r, canManageProject, "You do not have permission to manage the milestones of this project")
+	if !ok {
+		return
+	}
+
+	if err := h.milestoneService.DeleteMilestone(r.Context(), milestone.ID); err != nil {
+		utils.RespondWithAppError(w, err, "Failed to delete milestone")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// milestoneFor loads the project and milestone in the URL and checks allowed lets the caller
+// act on the project, responding with an error when it doesn't or either can't be found
+func (h *MilestoneHandler) milestoneFor(w http.ResponseWriter, r *http.Request, allowed func(*models.AuthContext, *models.Project) bool, forbidden string) (*models.Milestone, bool) {
+	_, project, ok := projectFor(w, r, h.projectService, allowed, forbidden)
+	if !ok {
+		return nil, false
+	}
+
+	milestone, err := h.milestoneService.GetMilestone(r.Context(), mux.Vars(r)["milestone_id"])
+	if err == nil && milestone.ProjectID != project.ID {
+		err = services.ErrMilestoneNotFound
+	}
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve milestone")
+		return nil, false
+	}
+	return milestone, true
+}
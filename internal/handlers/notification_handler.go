@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+
+	"github.com/OsGift/taskflow-api/internal/middleware"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/services"
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+// notificationListSpec whitelists the filters and sorts accepted by GET /notifications
+var notificationListSpec = query.Spec{
+	Filters: []query.Filter{
+		{Param: "read", Kind: query.Bool},
+		{Param: "event", Kind: query.Exact},
+		{Param: "created", Field: "created_at", Kind: query.TimeRange},
+	},
+	Sorts:       []string{"created_at"},
+	DefaultSort: "-created_at",
+}
+
+// NotificationHandler serves the caller's in-app notifications and notification preferences
+type NotificationHandler struct {
+	notificationService *services.NotificationService
+	validator           *validator.Validate
+}
+
+// NewNotificationHandler creates a new NotificationHandler
+func NewNotificationHandler(ns *services.NotificationService) *NotificationHandler {
+	return &NotificationHandler{
+		notificationService: ns,
+		validator:           validator.New(),
+	}
+}
+
+// ListNotifications lists the caller's in-app notifications, newest first.
+// Supports filtering by read, event and a created_from/created_to range.
+func (h *NotificationHandler) ListNotifications(w http.ResponseWriter, r *http.Request) {
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	q, err := notificationListSpec.Parse(r.URL.Query())
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Invalid query parameters")
+		return
+	}
+
+	notifications, err := h.notificationService.ListNotifications(r.Context(), authContext.UserID, q)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve notifications")
+		return
+	}
+
+	respondWithPage(w, r, notifications)
+}
+
+// MarkRead marks one of the caller's notifications as read
+func (h *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	notification, err := h.notificationService.MarkRead(r.Context(), authContext.UserID, mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to mark notification as read")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, notification)
+}
+
+// MarkAllRead marks all of the caller's notifications as read
+func (h *NotificationHandler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	count, err := h.notificationService.MarkAllRead(r.Context(), authContext.UserID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to mark notifications as read")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, models.MarkAllReadResponse{Updated: count})
+}
+
+// GetPreferences returns the channels the caller receives each event on
+func (h *NotificationHandler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	prefs, err := h.notificationService.GetPreferences(r.Context(), authContext.UserID)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve notification preferences")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, prefs)
+}
+
+// UpdatePreferences changes the caller's channels, webhook URL or push device tokens
+func (h *NotificationHandler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var req models.UpdateNotificationPreferencesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondWithValidationError(w, err)
+		return
+	}
+
+	prefs, err := h.notificationService.UpdatePreferences(r.Context(), authContext.UserID, &req)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to update notification preferences")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, prefs)
+}
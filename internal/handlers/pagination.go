@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+// paginated is a list response embedding models.Pagination
+type paginated interface {
+	PageInfo() *models.Pagination
+}
+
+// setPageLinks fills in the links of a page of a list response and sends them in a Link
+// header as well. The links repeat the request's path and query with only page changed.
+func setPageLinks(w http.ResponseWriter, r *http.Request, p *models.Pagination) {
+	last := p.TotalPages
+	if last < 1 {
+		last = 1
+	}
+	pageURL := func(page int64) string {
+		values := r.URL.Query()
+		values.Set("page", strconv.FormatInt(page, 10))
+		return r.URL.Path + "?" + values.Encode()
+	}
+
+	links := &models.PageLinks{First: pageURL(1), Last: pageURL(last)}
+	if p.Page > 1 {
+		links.Prev = pageURL(min(p.Page-1, last))
+	}
+	if p.Page < p.TotalPages {
+		links.Next = pageURL(p.Page + 1)
+	}
+	p.Links = links
+
+	header := []string{`<` + links.First + `>; rel="first"`}
+	if links.Prev != "" {
+		header = append(header, `<`+links.Prev+`>; rel="prev"`)
+	}
+	if links.Next != "" {
+		header = append(header, `<`+links.Next+`>; rel="next"`)
+	}
+	header = append(header, `<`+links.Last+`>; rel="last"`)
+	w.Header().Set("Link", strings.Join(header, ", "))
+}
+
+// respondWithPage writes a page of a list response along with its links
+func respondWithPage(w http.ResponseWriter, r *http.Request, response paginated) {
+	setPageLinks(w, r, response.PageInfo())
+	utils.RespondWithJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,433 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/OsGift/taskflow-api/internal/middleware"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/services"
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+// projectListSpec whitelists the filters and sorts accepted by GET /projects
+var projectListSpec = query.Spec{
+	Filters: []query.Filter{
+		{Param: "archived", Kind: query.Bool},
+		{Param: "owner_id", Kind: query.ObjectID},
+		{Param: "created", Field: "created_at", Kind: query.TimeRange},
+		{Param: "updated", Field: "updated_at", Kind: query.TimeRange},
+	},
+	Sorts:       []string{"created_at", "updated_at", "name"},
+	DefaultSort: "-created_at",
+}
+
+// ProjectHandler handles project related HTTP requests
+type ProjectHandler struct {
+	projectService   *services.ProjectService
+	dashboardService *services.DashboardService
+	validator        *validator.Validate
+}
+
+// NewProjectHandler creates a new ProjectHandler
+func NewProjectHandler(ps *services.ProjectService, ds *services.DashboardService) *ProjectHandler {
+	return &ProjectHandler{
+		projectService:   ps,
+		dashboardService: ds,
+		validator:        validator.New(),
+	}
+}
+
+// canViewProject reports whether the caller can see a project: 'project:read_all' or any member
+func canViewProject(authContext *models.AuthContext, project *models.Project) bool {
+	return authContext.HasPermission("project:read_all") || project.RoleOf(authContext.UserID) != ""
+}
+
+// canFileTasks reports whether the caller can file tasks under a project: 'project:update_all'
+// or an editor
+func canFileTasks(authContext *models.AuthContext, project *models.Project) bool {
+	return authContext.HasPermission("project:update_all") || project.RoleOf(authContext.UserID).Includes(models.ProjectRoleEditor)
+}
+
+// canManageProject reports whether the caller can change a project and its members:
+// 'project:update_all' or a manager
+func canManageProject(authContext *models.AuthContext, project *models.Project) bool {
+	return authContext.HasPermission("project:update_all") || project.RoleOf(authContext.UserID).Includes(models.ProjectRoleManager)
+}
+
+// canDeleteProject reports whether the caller can delete a project: 'project:delete_all' or its owner
+func canDeleteProject(authContext *models.AuthContext, project *models.Project) bool {
+	return authContext.HasPermission("project:delete_all") || project.OwnerID == authContext.UserID
+}
+
+// includeArchived reads the include_archived query parameter, responding with an error when it
+// isn't a boolean
+func includeArchived(w http.ResponseWriter, r *http.Request) (include bool, ok bool) {
+	raw := r.URL.Query().Get("include_archived")
+	if raw == "" {
+		return false, true
+	}
+	include, err := strconv.ParseBool(raw)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "include_archived must be true or false")
+		return false, false
+	}
+	return include, true
+}
+
+// CreateProject handles creating a new project owned by the caller
+func (h *ProjectHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondWithValidationError(w, err)
+		return
+	}
+
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	project, err := h.projectService.CreateProject(r.Context(), authContext.UserID, &req)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to create project")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, project)
+}
+
+// ListProjects handles listing projects with filters and pagination. Archived projects are
+// left out unless include_archived=true or the archived filter is given.
+func (h *ProjectHandler) ListProjects(w http.ResponseWriter, r *http.Request) {
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	q, err := projectListSpec.Parse(r.URL.Query())
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Invalid query parameters")
+		return
+	}
+	include, ok := includeArchived(w, r)
+	if !ok {
+		return
+	}
+	if _, filtered := q.Filter["archived"]; !filtered && !include {
+		q.Filter["archived"] = false
+	}
+
+	// Without 'project:read_all', users only see the projects they own or are members of
+	if !authContext.HasPermission("project:read_all") {
+		q.Filter["$or"] = []primitive.M{{"owner_id": authContext.UserID}, {"members.user_id": authContext.UserID}}
+	}
+
+	projects, err := h.projectService.ListProjects(r.Context(), q)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve projects")
+		return
+	}
+
+	respondWithPage(w, r, projects)
+}
+
+// GetProject handles retrieving a single project
+func (h *ProjectHandler) GetProject(w http.ResponseWriter, r *http.Request) {
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	project, err := h.projectService.GetProject(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve project")
+		return
+	}
+
+	// Authorization check: 'project:read_all' or member
+	if !canViewProject(authContext, project) {
+		utils.RespondWithError(w, http.StatusForbidden, "You do not have permission to view this project")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, project)
+}
+
+// GetProjectMetrics returns statistics about a project's tasks for its members. period,
+// start_date and end_date select the period as on the dashboard; the optional tz query
+// parameter sets where days start, the caller's time zone by default.
+func (h *ProjectHandler) GetProjectMetrics(w http.ResponseWriter, r *http.Request) {
+	authContext, project, ok := projectFor(w, r, h.projectService, canViewProject, "You do not have permission to view this project")
+	if !ok {
+		return
+	}
+
+	loc, ok := requestLocation(w, r, authContext)
+	if !ok {
+		return
+	}
+
+	period, startDate, endDate, err := parsePeriod(r, loc)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	metrics, err := h.dashboardService.GetProjectMetrics(r.Context(), project, period, startDate, endDate, loc)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve project metrics")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, metrics)
+}
+
+// GetBurndown returns how many of a project's tasks were open at the end of each day, over the
+// sprint given as sprint_id or the from and to dates (YYYY-MM-DD); with sprint_id, from and
+// to narrow the sprint's dates. The optional tz query parameter sets where days start, the
+// caller's time zone by default.
+func (h *ProjectHandler) GetBurndown(w http.ResponseWriter, r *http.Request) {
+	authContext, project, ok := projectFor(w, r, h.projectService, canViewProject, "You do not have permission to view this project")
+	if !ok {
+		return
+	}
+
+	loc, ok := requestLocation(w, r, authContext)
+	if !ok {
+		return
+	}
+
+	var days [2]*time.Time
+	for i, param := range []string{"from", "to"} {
+		value := r.URL.Query().Get(param)
+		if value == "" {
+			continue
+		}
+		day, err := time.ParseInLocation("2006-01-02", value, loc)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid "+param+" format. Use YYYY-MM-DD.")
+			return
+		}
+		days[i] = &day
+	}
+
+	burndown, err := h.projectService.Burndown(r.Context(), project, r.URL.Query().Get("sprint_id"), days[0], days[1], loc)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve burn-down")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, burndown)
+}
+
+// UpdateProject handles renaming a project or changing its description
+func (h *ProjectHandler) UpdateProject(w http.ResponseWriter, r *http.Request) {
+	var req models.UpdateProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondWithValidationError(w, err)
+		return
+	}
+
+	_, project, ok := projectFor(w, r, h.projectService, canManageProject, "You do not have permission to update this project")
+	if !ok {
+		return
+	}
+
+	updatedProject, err := h.projectService.UpdateProject(r.Context(), project.ID, &req)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to update project")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, updatedProject)
+}
+
+// ArchiveProject handles archiving a project, after which it accepts no new tasks and its
+// tasks are hidden from listings
+func (h *ProjectHandler) ArchiveProject(w http.ResponseWriter, r *http.Request) {
+	_, project, ok := projectFor(w, r, h.projectService, canManageProject, "You do not have permission to archive this project")
+	if !ok {
+		return
+	}
+
+	archivedProject, err := h.projectService.ArchiveProject(r.Context(), project.ID)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to archive project")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, archivedProject)
+}
+
+// RestoreProject handles restoring an archived project and showing its tasks again
+func (h *ProjectHandler) RestoreProject(w http.ResponseWriter, r *http.Request) {
+	_, project, ok := projectFor(w, r, h.projectService, canManageProject, "You do not have permission to restore this project")
+	if !ok {
+		return
+	}
+
+	restoredProject, err := h.projectService.RestoreProject(r.Context(), project.ID)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to restore project")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, restoredProject)
+}
+
+// DeleteProject handles deleting a project. Its tasks are kept outside any project.
+func (h *ProjectHandler) DeleteProject(w http.ResponseWriter, r *http.Request) {
+	_, project, ok := projectFor(w, r, h.projectService, canDeleteProject, "You do not have permission to delete this project")
+	if !ok {
+		return
+	}
+
+	if err := h.projectService.DeleteProject(r.Context(), project.ID); err != nil {
+		utils.RespondWithAppError(w, err, "Failed to delete project")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AddMember handles adding a user to a project with a role (managers only)
+func (h *ProjectHandler) AddMember(w http.ResponseWriter, r *http.Request) {
+	var req models.AddProjectMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondWithValidationError(w, err)
+		return
+	}
+
+	authContext, project, ok := projectFor(w, r, h.projectService, canManageProject, "You do not have permission to manage the members of this project")
+	if !ok {
+		return
+	}
+
+	updatedProject, err := h.projectService.AddMember(r.Context(), project, &req, authContext.UserID)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to add project member")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, updatedProject)
+}
+
+// UpdateMember handles changing a member's role (managers only)
+func (h *ProjectHandler) UpdateMember(w http.ResponseWriter, r *http.Request) {
+	var req models.UpdateProjectMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondWithValidationError(w, err)
+		return
+	}
+
+	_, project, ok := projectFor(w, r, h.projectService, canManageProject, "You do not have permission to manage the members of this project")
+	if !ok {
+		return
+	}
+	userID, ok := memberID(w, r, project)
+	if !ok {
+		return
+	}
+
+	updatedProject, err := h.projectService.UpdateMemberRole(r.Context(), project.ID, userID, req.Role)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to update project member")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, updatedProject)
+}
+
+// RemoveMember handles removing a member from a project. Managers can remove anyone;
+// other members can only leave.
+func (h *ProjectHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	authContext, project, ok := projectFor(w, r, h.projectService, canViewProject, "You do not have permission to view this project")
+	if !ok {
+		return
+	}
+	userID, ok := memberID(w, r, project)
+	if !ok {
+		return
+	}
+
+	// Authorization check: manager or the member themselves
+	if !canManageProject(authContext, project) && userID != authContext.UserID {
+		utils.RespondWithError(w, http.StatusForbidden, "You do not have permission to manage the members of this project")
+		return
+	}
+
+	if err := h.projectService.RemoveMember(r.Context(), project.ID, userID); err != nil {
+		utils.RespondWithAppError(w, err, "Failed to remove project member")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// projectFor loads the project in the URL and checks allowed lets the caller act on it,
+// responding with forbidden or another error when it doesn't or the project can't be found
+func projectFor(w http.ResponseWriter, r *http.Request, ps *services.ProjectService, allowed func(*models.AuthContext, *models.Project) bool, forbidden string) (*models.AuthContext, *models.Project, bool) {
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return nil, nil, false
+	}
+
+	project, err := ps.GetProject(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve project")
+		return nil, nil, false
+	}
+
+	if !allowed(authContext, project) {
+		utils.RespondWithError(w, http.StatusForbidden, forbidden)
+		return nil, nil, false
+	}
+	return authContext, project, true
+}
+
+// memberID reads the member's user ID from the URL, responding with an error when it is
+// invalid or names the project owner, who isn't a listed member
+func memberID(w http.ResponseWriter, r *http.Request, project *models.Project) (primitive.ObjectID, bool) {
+	userID, err := primitive.ObjectIDFromHex(mux.Vars(r)["user_id"])
+	if err != nil {
+		utils.RespondWithAppError(w, services.ErrInvalidUserID, "Invalid user ID")
+		return primitive.NilObjectID, false
+	}
+	if userID == project.OwnerID {
+		utils.RespondWithAppError(w, services.ErrProjectOwnerMember, "Invalid project member")
+		return primitive.NilObjectID, false
+	}
+	return userID, true
+}
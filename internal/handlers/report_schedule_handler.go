@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+
+	"github.com/OsGift/taskflow-api/internal/middleware"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/services"
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+// ReportScheduleHandler lets administrators schedule dashboard report emails
+type ReportScheduleHandler struct {
+	reportService *services.ReportService
+	validator     *validator.Validate
+}
+
+// NewReportScheduleHandler creates a new ReportScheduleHandler
+func NewReportScheduleHandler(rs *services.ReportService) *ReportScheduleHandler {
+	return &ReportScheduleHandler{
+		reportService: rs,
+		validator:     validator.New(),
+	}
+}
+
+// ListSchedules lists every report schedule
+func (h *ReportScheduleHandler) ListSchedules(w http.ResponseWriter, r *http.Request) {
+	schedules, err := h.reportService.ListSchedules(r.Context())
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve report schedules")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, schedules)
+}
+
+// GetSchedule returns a report schedule
+func (h *ReportScheduleHandler) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	schedule, err := h.reportService.GetSchedule(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve report schedule")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, schedule)
+}
+
+// CreateSchedule schedules a new report and queues its first run
+func (h *ReportScheduleHandler) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	var req models.ReportScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondWithValidationError(w, err)
+		return
+	}
+
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	schedule, err := h.reportService.CreateSchedule(r.Context(), &req, authContext.UserID)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to create report schedule")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, schedule)
+}
+
+// UpdateSchedule replaces the settings of a report schedule
+func (h *ReportScheduleHandler) UpdateSchedule(w http.ResponseWriter, r *http.Request) {
+	var req models.ReportScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondWithValidationError(w, err)
+		return
+	}
+
+	schedule, err := h.reportService.UpdateSchedule(r.Context(), mux.Vars(r)["id"], &req)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to update report schedule")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, schedule)
+}
+
+// DeleteSchedule deletes a report schedule; reports already sent are unaffected
+func (h *ReportScheduleHandler) DeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	if err := h.reportService.DeleteSchedule(r.Context(), mux.Vars(r)["id"]); err != nil {
+		utils.RespondWithAppError(w, err, "Failed to delete report schedule")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
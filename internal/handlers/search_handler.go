@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/OsGift/taskflow-api/internal/middleware"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/services"
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+// SearchHandler handles searches across tasks, comments and users
+type SearchHandler struct {
+	searchService *services.SearchService
+}
+
+// NewSearchHandler creates a new SearchHandler
+func NewSearchHandler(ss *services.SearchService) *SearchHandler {
+	return &SearchHandler{
+		searchService: ss,
+	}
+}
+
+// Search returns the tasks, comments and users matching ?q=, grouped by type. ?types= restricts the
+// search (e.g. "tasks"); each type is paginated with its own <type>_page and <type>_limit.
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	values := r.URL.Query()
+	req := services.SearchRequest{
+		Query: values.Get("q"),
+		Pages: map[string]*query.Query{},
+	}
+	if raw := values.Get("types"); raw != "" {
+		for _, t := range strings.Split(raw, ",") {
+			req.Types = append(req.Types, strings.TrimSpace(t))
+		}
+	}
+	for _, t := range services.SearchTypes {
+		// Like list endpoints, bad paging values fall back to the defaults
+		page, _ := strconv.ParseInt(values.Get(t+"_page"), 10, 64)
+		limit, _ := strconv.ParseInt(values.Get(t+"_limit"), 10, 64)
+		req.Pages[t] = query.New(nil, page, limit)
+	}
+
+	results, err := h.searchService.Search(r.Context(), authContext, req)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to search")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, results)
+}
+
+// Suggest returns the titles of the tasks the caller can list starting with ?q=, for search-as-you-type.
+// ?limit= sets how many (default 10, at most 20).
+func (h *SearchHandler) Suggest(w http.ResponseWriter, r *http.Request) {
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	values := r.URL.Query()
+	limit, _ := strconv.ParseInt(values.Get("limit"), 10, 64) // Falls back to the default
+	suggestions, err := h.searchService.Suggest(r.Context(), authContext, values.Get("q"), limit)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to suggest tasks")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, suggestions)
+}
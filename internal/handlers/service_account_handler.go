@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+
+	"github.com/OsGift/taskflow-api/internal/middleware"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/services"
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+// ServiceAccountHandler lets administrators manage service accounts and their API keys
+type ServiceAccountHandler struct {
+	serviceAccountService *services.ServiceAccountService
+	validator             *validator.Validate
+}
+
+// NewServiceAccountHandler creates a new ServiceAccountHandler
+func NewServiceAccountHandler(sas *services.ServiceAccountService) *ServiceAccountHandler {
+	return &ServiceAccountHandler{
+		serviceAccountService: sas,
+		validator:             validator.New(),
+	}
+}
+
+// CreateServiceAccount creates a service account with a role. Its role can be changed later
+// like any user's, through PUT /users/{id}/role.
+func (h *ServiceAccountHandler) CreateServiceAccount(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateServiceAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondWithValidationError(w, err)
+		return
+	}
+
+	account, err := h.serviceAccountService.CreateServiceAccount(r.Context(), req)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to create service account")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, account)
+}
+
+// ListServiceAccounts lists service accounts, with the filters and sorts of GET /users
+func (h *ServiceAccountHandler) ListServiceAccounts(w http.ResponseWriter, r *http.Request) {
+	q, err := userListSpec.Parse(r.URL.Query())
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Invalid query parameters")
+		return
+	}
+
+	accounts, err := h.serviceAccountService.ListServiceAccounts(r.Context(), q)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve service accounts")
+		return
+	}
+
+	respondWithPage(w, r, accounts)
+}
+
+// DeleteServiceAccount deletes a service account and its keys. Its tasks are deleted too,
+// unless ?reassign_to names a user to hand them over to.
+func (h *ServiceAccountHandler) DeleteServiceAccount(w http.ResponseWriter, r *http.Request) {
+	err := h.serviceAccountService.DeleteServiceAccount(r.Context(), mux.Vars(r)["id"], r.URL.Query().Get("reassign_to"))
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to delete service account")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateKey issues an API key for a service account. The key is only shown in this response.
+func (h *ServiceAccountHandler) CreateKey(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondWithValidationError(w, err)
+		return
+	}
+
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	key, err := h.serviceAccountService.CreateKey(r.Context(), mux.Vars(r)["id"], req, authContext.UserID)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to create API key")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, key)
+}
+
+// ListKeys lists the API keys of a service account, without the keys themselves
+func (h *ServiceAccountHandler) ListKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.serviceAccountService.ListKeys(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve API keys")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, keys)
+}
+
+// UpdateKey renames an API key or changes its rate limit
+func (h *ServiceAccountHandler) UpdateKey(w http.ResponseWriter, r *http.Request) {
+	var req models.UpdateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondWithValidationError(w, err)
+		return
+	}
+
+	vars := mux.Vars(r)
+	key, err := h.serviceAccountService.UpdateKey(r.Context(), vars["id"], vars["key_id"], req)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to update API key")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, key)
+}
+
+// GetKeyUsage reports the requests made with an API key per day, over the past ?days (default 30)
+func (h *ServiceAccountHandler) GetKeyUsage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	usage, err := h.serviceAccountService.KeyUsage(r.Context(), vars["id"], vars["key_id"], usageDays(r))
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve API key usage")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, usage)
+}
+
+// GetCurrentKeyUsage reports the usage of the API key the request is made with, so integrations
+// can watch their own consumption
+func (h *ServiceAccountHandler) GetCurrentKeyUsage(w http.ResponseWriter, r *http.Request) {
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	usage, err := h.serviceAccountService.CurrentKeyUsage(r.Context(), authContext, usageDays(r))
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve API key usage")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, usage)
+}
+
+// usageDays reads the ?days parameter of the usage endpoints; invalid values are rejected by the service
+func usageDays(r *http.Request) int {
+	raw := r.URL.Query().Get("days")
+	if raw == "" {
+		return 30
+	}
+	days, err := strconv.Atoi(raw)
+	if err != nil {
+		return -1
+	}
+	return days
+}
+
+// RevokeKey revokes an API key of a service account
+func (h *ServiceAccountHandler) RevokeKey(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := h.serviceAccountService.RevokeKey(r.Context(), vars["id"], vars["key_id"]); err != nil {
+		utils.RespondWithAppError(w, err, "Failed to revoke API key")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
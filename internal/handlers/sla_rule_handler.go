@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+
+	"github.com/OsGift/taskflow-api/internal/middleware"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/services"
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+// SLARuleHandler lets administrators set how long tasks may stay in a status
+type SLARuleHandler struct {
+	slaService *services.SLAService
+	validator  *validator.Validate
+}
+
+// NewSLARuleHandler creates a new SLARuleHandler
+func NewSLARuleHandler(ss *services.SLAService) *SLARuleHandler {
+	return &SLARuleHandler{
+		slaService: ss,
+		validator:  validator.New(),
+	}
+}
+
+// ListRules lists every SLA rule
+func (h *SLARuleHandler) ListRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.slaService.ListRules(r.Context())
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve SLA rules")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, rules)
+}
+
+// GetRule returns an SLA rule
+func (h *SLARuleHandler) GetRule(w http.ResponseWriter, r *http.Request) {
+	rule, err := h.slaService.GetRule(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve SLA rule")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, rule)
+}
+
+// CreateRule creates an SLA rule, checked from the next SLA check on
+func (h *SLARuleHandler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	var req models.SLARuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondWithValidationError(w, err)
+		return
+	}
+
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	rule, err := h.slaService.CreateRule(r.Context(), &req, authContext.UserID)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to create SLA rule")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, rule)
+}
+
+// UpdateRule replaces the settings of an SLA rule
+func (h *SLARuleHandler) UpdateRule(w http.ResponseWriter, r *http.Request) {
+	var req models.SLARuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondWithValidationError(w, err)
+		return
+	}
+
+	rule, err := h.slaService.UpdateRule(r.Context(), mux.Vars(r)["id"], &req)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to update SLA rule")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, rule)
+}
+
+// DeleteRule deletes an SLA rule; tasks it flagged stay flagged until they change status
+func (h *SLARuleHandler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	if err := h.slaService.DeleteRule(r.Context(), mux.Vars(r)["id"]); err != nil {
+		utils.RespondWithAppError(w, err, "Failed to delete SLA rule")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
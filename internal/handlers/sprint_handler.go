@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/services"
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+// sprintListSpec whitelists the filters and sorts accepted by GET /projects/{id}/sprints
+var sprintListSpec = query.Spec{
+	Filters: []query.Filter{
+		{Param: "closed", Kind: query.Bool},
+		{Param: "start", Field: "start_date", Kind: query.TimeRange},
+		{Param: "end", Field: "end_date", Kind: query.TimeRange},
+	},
+	Sorts:       []string{"start_date", "end_date", "created_at", "name"},
+	DefaultSort: "start_date",
+}
+
+// SprintHandler handles the sprints of projects. Members can see them, editors plan tasks
+// into them and managers create, change, close and delete them.
+type SprintHandler struct {
+	projectService *services.ProjectService
+	sprintService  *services.SprintService
+	taskService    *services.TaskService
+	validator      *validator.Validate
+}
+
+// NewSprintHandler creates a new SprintHandler
+func NewSprintHandler(ps *services.ProjectService, ss *services.SprintService, ts *services.TaskService) *SprintHandler {
+	return &SprintHandler{
+		projectService: ps,
+		sprintService:  ss,
+		taskService:    ts,
+		validator:      validator.New(),
+	}
+}
+
+// CreateSprint handles creating a sprint in a project
+func (h *SprintHandler) CreateSprint(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateSprintRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondWithValidationError(w, err)
+		return
+	}
+
+	authContext, project, ok := projectFor(w, r, h.projectService, canManageProject, "You do not have permission to manage the sprints of this project")
+	if !ok {
+		return
+	}
+
+	sprint, err := h.sprintService.CreateSprint(r.Context(), project.ID, authContext.UserID, &req)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to create sprint")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, sprint)
+}
+
+// ListSprints lists a project's sprints in order of their start date.
+// Supports filtering by closed and start_from/start_to and end_from/end_to ranges.
+func (h *SprintHandler) ListSprints(w http.ResponseWriter, r *http.Request) {
+	_, project, ok := projectFor(w, r, h.projectService, canViewProject, "You do not have permission to view this project")
+	if !ok {
+		return
+	}
+
+	q, err := sprintListSpec.Parse(r.URL.Query())
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Invalid query parameters")
+		return
+	}
+
+	sprints, err := h.sprintService.ListSprints(r.Context(), project.ID, q)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve sprints")
+		return
+	}
+
+	respondWithPage(w, r, sprints)
+}
+
+// GetSprint handles retrieving a single sprint
+func (h *SprintHandler) GetSprint(w http.ResponseWriter, r *http.Request) {
+	sprint, ok := h.sprintFor(w, r, canViewProject, "You do not have permission to view this project")
+	if !ok {
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, sprint)
+}
+
+// GetSprintSummary returns how many of a sprint's tasks are done and how far through its
+// dates it is
+func (h *SprintHandler) GetSprintSummary(w http.ResponseWriter, r *http.Request) {
+	sprint, ok := h.sprintFor(w, r, canViewProject, "You do not have permission to view this project")
+	if !ok {
+		return
+	}
+
+	summary, err := h.sprintService.Summary(r.Context(), sprint)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve sprint summary")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, summary)
+}
+
+// UpdateSprint handles changing the name, goal or dates of an open sprint
+func (h *SprintHandler) UpdateSprint(w http.ResponseWriter, r *http.Request) {
+	var req models.UpdateSprintRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondWithValidationError(w, err)
+		return
+	}
+
+	sprint, ok := h.sprintFor(w, r, canManageProject, "You do not have permission to manage the sprints of this project")
+	if !ok {
+		return
+	}
+
+	updatedSprint, err := h.sprintService.UpdateSprint(r.Context(), sprint, &req)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to update sprint")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, updatedSprint)
+}
+
+// CloseSprint handles closing a sprint. Its unfinished tasks roll over to the sprint given
+// as next_sprint_id, or by default the project's next open sprint; without one they go back
+// to the backlog.
+func (h *SprintHandler) CloseSprint(w http.ResponseWriter, r *http.Request) {
+	var req models.CloseSprintRequest
+	if r.ContentLength != 0 { // The body is optional
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+	}
+
+	sprint, ok := h.sprintFor(w, r, canManageProject, "You do not have permission to manage the sprints of this project")
+	if !ok {
+		return
+	}
+
+	closedSprint, err := h.sprintService.CloseSprint(r.Context(), sprint, req.NextSprintID)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to close sprint")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, closedSprint)
+}
+
+// DeleteSprint handles deleting a sprint. Its tasks go back to the project's backlog.
+func (h *SprintHandler) DeleteSprint(w http.ResponseWriter, r *http.Request) {
+	sprint, ok := h.sprintFor(w, r, canManageProject, "You do not have permission to manage the sprints of this project")
+	if !ok {
+		return
+	}
+
+	if err := h.sprintService.DeleteSprint(r.Context(), sprint.ID); err != nil {
+		utils.RespondWithAppError(w, err, "Failed to delete sprint")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AddTask handles planning one of the project's tasks into an open sprint (editors only).
+// A task in another sprint moves to this one.
+func (h *SprintHandler) AddTask(w http.ResponseWriter, r *http.Request) {
+	var req models.SprintTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondWithValidationError(w, err)
+		return
+	}
+
+	sprint, ok := h.sprintFor(w, r, canFileTasks, "You do not have permission to plan the tasks of this project")
+	if !ok {
+		return
+	}
+	if sprint.Closed {
+		utils.RespondWithAppError(w, services.ErrSprintClosed, "Failed to add task to sprint")
+		return
+	}
+
+	task, err := h.taskService.GetTaskByID(r.Context(), req.TaskID)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve task")
+		return
+	}
+	if task.ProjectID == nil || *task.ProjectID != sprint.ProjectID {
+		utils.RespondWithAppError(w, services.ErrSprintProjectMismatch, "Failed to add task to sprint")
+		return
+	}
+
+	updatedTask, err := h.taskService.SetSprint(r.Context(), task.ID, &sprint.ID)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to add task to sprint")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, updatedTask)
+}
+
+// RemoveTask handles taking a task out of an open sprint and back to the project's backlog
+// (editors only)
+func (h *SprintHandler) RemoveTask(w http.ResponseWriter, r *http.Request) {
+	sprint, ok := h.sprintFor(w, r, canFileTasks, "You do not have permission to plan the tasks of this project")
+	if !ok {
+		return
+	}
+	if sprint.Closed {
+		utils.RespondWithAppError(w, services.ErrSprintClosed, "Failed to remove task from sprint")
+		return
+	}
+
+	task, err := h.taskService.GetTaskByID(r.Context(), mux.Vars(r)["task_id"])
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve task")
+		return
+	}
+	if task.SprintID == nil || *task.SprintID != sprint.ID {
+		utils.RespondWithAppError(w, services.ErrTaskNotInSprint, "Failed to remove task from sprint")
+		return
+	}
+
+	if _, err := h.taskService.SetSprint(r.Context(), task.ID, nil); err != nil {
+		utils.RespondWithAppError(w, err, "Failed to remove task from sprint")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sprintFor loads the project and sprint in the URL and checks allowed lets the caller act
+// on the project, responding with an error when it doesn't or either can't be found
+func (h *SprintHandler) sprintFor(w http.ResponseWriter, r *http.Request, allowed func(*models.AuthContext, *models.Project) bool, forbidden string) (*models.Sprint, bool) {
+	_, project, ok := projectFor(w, r, h.projectService, allowed, forbidden)
+	if !ok {
+		return nil, false
+	}
+
+	sprint, err := h.sprintService.GetSprint(r.Context(), mux.Vars(r)["sprint_id"])
+	if err == nil && sprint.ProjectID != project.ID {
+		err = services.ErrSprintNotFound
+	}
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve sprint")
+		return nil, false
+	}
+	return sprint, true
+}
@@ -1,33 +1,126 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
-	"strconv"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
+	"github.com/OsGift/taskflow-api/internal/apperror"
+	"github.com/OsGift/taskflow-api/internal/logging"
 	"github.com/OsGift/taskflow-api/internal/middleware"
 	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
 	"github.com/OsGift/taskflow-api/internal/services"
 	"github.com/OsGift/taskflow-api/internal/utils"
 )
 
+// taskListSpec whitelists the filters and sorts accepted by GET /tasks
+var taskListSpec = query.Spec{
+	Filters: []query.Filter{
+		{Param: "status", Kind: query.Enum, Values: []string{string(models.StatusTodo), string(models.StatusInProgress), string(models.StatusDone)}},
+		{Param: "user_id", Kind: query.ObjectID}, // Narrows down the tasks the caller can see
+		{Param: "project_id", Kind: query.ObjectID},
+		{Param: "milestone_id", Kind: query.ObjectID},
+		{Param: "sprint_id", Kind: query.ObjectID},
+		{Param: "priority", Kind: query.Enum, Values: []string{string(models.PriorityLow), string(models.PriorityMedium), string(models.PriorityHigh), string(models.PriorityUrgent)}},
+		{Param: "tag", Field: "tags", Kind: query.Exact},
+		{Param: "color", Kind: query.Exact},
+		{Param: "created", Field: "created_at", Kind: query.TimeRange},
+		{Param: "updated", Field: "updated_at", Kind: query.TimeRange},
+		{Param: "due", Field: "due_date", Kind: query.TimeRange},
+		{Param: "stale", Field: "stale_at", Kind: query.TimeRange},
+		{Param: "sla_breached", Field: "sla_breached_at", Kind: query.TimeRange},
+		{Param: "is_pinned", Field: "pinned", Kind: query.Bool}, // The caller's pins, applied by pinnedGroups
+	},
+	// "-pinned" lists the caller's pinned tasks first, e.g. sort=-pinned,due_date
+	Sorts:       []string{"created_at", "updated_at", "due_date", "title", "status", "pinned"},
+	DefaultSort: "-created_at",
+	Fields: []string{"title", "description", "status", "user_id", "assigned_at", "project_id", "milestone_id", "sprint_id", "archived",
+		"due_date", "priority", "tags", "color", "completed_at", "status_changed_at",
+		"stale_warned_at", "stale_at", "sla_breached_at", "sla_rule_id", "merged_into", "pinned", "pinned_at", "created_at", "updated_at",
+		"has_unread_updates"},
+	FieldSources: map[string][]string{"has_unread_updates": {"created_at", "updated_at"}, "pinned": {}, "pinned_at": {}},
+}
+
+// errPinnedSortNotFirst rejects sorts such as sort=due_date,-pinned, as pinned tasks can only
+// be listed before or after the others
+var errPinnedSortNotFirst = apperror.New(apperror.CodeInvalidArgument, "pinned can only be the first sort field")
+
 // TaskHandler handles task related HTTP requests
 type TaskHandler struct {
-	taskService *services.TaskService
-	validator   *validator.Validate
+	taskService      *services.TaskService
+	uploadService    *services.UploadService
+	projectService   *services.ProjectService
+	milestoneService *services.MilestoneService
+	taskMergeService *services.TaskMergeService
+	taskViewService  *services.TaskViewService
+	taskPinService   *services.TaskPinService
+	validator        *validator.Validate
 }
 
 // NewTaskHandler creates a new TaskHandler
-func NewTaskHandler(ts *services.TaskService) *TaskHandler {
+func NewTaskHandler(ts *services.TaskService, us *services.UploadService, ps *services.ProjectService, ms *services.MilestoneService, tms *services.TaskMergeService, tvs *services.TaskViewService, tps *services.TaskPinService) *TaskHandler {
 	return &TaskHandler{
-		taskService: ts,
-		validator:   validator.New(),
+		taskService:      ts,
+		uploadService:    us,
+		projectService:   ps,
+		milestoneService: ms,
+		taskMergeService: tms,
+		taskViewService:  tvs,
+		taskPinService:   tps,
+		validator:        validator.New(),
+	}
+}
+
+// checkProject checks the caller can file tasks under the project with ID projectID,
+// responding with an error when the project can't be found, seen or is archived
+func (h *TaskHandler) checkProject(w http.ResponseWriter, r *http.Request, authContext *models.AuthContext, projectID string) (*models.Project, bool) {
+	project, err := h.projectService.GetProject(r.Context(), projectID)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve project")
+		return nil, false
+	}
+	if !canFileTasks(authContext, project) {
+		utils.RespondWithError(w, http.StatusForbidden, "You do not have permission to add tasks to this project")
+		return nil, false
+	}
+	if project.Archived {
+		utils.RespondWithAppError(w, services.ErrProjectArchived, "Failed to add task to project")
+		return nil, false
+	}
+	return project, true
+}
+
+// checkMilestone checks the milestone with ID milestoneID belongs to the project with ID
+// projectID, responding with an error when it can't be found or doesn't. Tasks outside any
+// project can't have a milestone.
+func (h *TaskHandler) checkMilestone(w http.ResponseWriter, r *http.Request, projectID *primitive.ObjectID, milestoneID string) (*models.Milestone, bool) {
+	milestone, err := h.milestoneService.GetMilestone(r.Context(), milestoneID)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve milestone")
+		return nil, false
+	}
+	if projectID == nil || *projectID != milestone.ProjectID {
+		utils.RespondWithAppError(w, services.ErrMilestoneProjectMismatch, "Failed to attach task to milestone")
+		return nil, false
 	}
+	return milestone, true
+}
+
+// canAccessTask reports whether the caller can act on task: with allPermission, as its owner,
+// or with at least role in the project it is filed under
+func canAccessTask(authContext *models.AuthContext, task *models.Task, allPermission string, role models.ProjectRole) bool {
+	if authContext.HasPermission(allPermission) || task.UserID == authContext.UserID {
+		return true
+	}
+	return task.ProjectID != nil && authContext.ProjectRole(*task.ProjectID).Includes(role)
 }
 
 // CreateTask handles creating a new task
@@ -38,8 +131,46 @@ func (h *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.createTask(w, r, &req)
+}
+
+// QuickAddTask handles creating a task from a line of shorthand such as
+// "Pay rent tomorrow 5pm #finance !high", parsed into its title, due date, tags and priority
+// (see services.ParseQuickAdd). The optional tz query parameter (an IANA time zone, e.g.
+// "Europe/Paris") sets the time zone dates and times are read in; the caller's by default.
+func (h *TaskHandler) QuickAddTask(w http.ResponseWriter, r *http.Request) {
+	var req models.QuickAddTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondWithValidationError(w, err)
+		return
+	}
+
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	loc, ok := requestLocation(w, r, authContext)
+	if !ok {
+		return
+	}
+
+	parsed := services.ParseQuickAdd(req.Text, time.Now().In(loc))
+	parsed.DueTimeZone = loc.String()
+	parsed.ProjectID = req.ProjectID
+	parsed.MilestoneID = req.MilestoneID
+	h.createTask(w, r, parsed)
+}
+
+// createTask validates req and creates the task it describes for the caller
+func (h *TaskHandler) createTask(w http.ResponseWriter, r *http.Request, req *models.CreateTaskRequest) {
 	if err := h.validator.Struct(req); err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		utils.RespondWithValidationError(w, err)
 		return
 	}
 
@@ -53,17 +184,40 @@ func (h *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 	if req.Status == "" {
 		req.Status = string(models.StatusTodo)
 	}
+	// Due dates are read in the caller's time zone unless the request names another
+	if req.DueTimeZone == "" {
+		req.DueTimeZone = authContext.Location().String()
+	}
 
 	task := &models.Task{
 		Title:       req.Title,
 		Description: req.Description,
 		Status:      models.TaskStatus(req.Status),
 		UserID:      authContext.UserID, // Assign task to the authenticated user
+		DueDate:     req.DueDate,
+		DueTimeZone: req.DueTimeZone,
+		Priority:    models.TaskPriority(req.Priority),
+		Tags:        req.Tags,
+		Color:       req.Color,
+	}
+	if req.ProjectID != "" {
+		project, ok := h.checkProject(w, r, authContext, req.ProjectID)
+		if !ok {
+			return
+		}
+		task.ProjectID = &project.ID
+	}
+	if req.MilestoneID != "" {
+		milestone, ok := h.checkMilestone(w, r, task.ProjectID, req.MilestoneID)
+		if !ok {
+			return
+		}
+		task.MilestoneID = &milestone.ID
 	}
 
-	createdTask, err := h.taskService.CreateTask(task)
+	createdTask, err := h.taskService.CreateTask(r.Context(), task)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create task")
+		utils.RespondWithAppError(w, err, "Failed to create task")
 		return
 	}
 
@@ -78,63 +232,128 @@ func (h *TaskHandler) GetTasks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Pagination parameters
-	pageStr := r.URL.Query().Get("page")
-	limitStr := r.URL.Query().Get("limit")
+	// Pagination, filters (status, user_id, created/updated ranges) and sorting
+	q, err := taskListSpec.Parse(r.URL.Query())
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Invalid query parameters")
+		return
+	}
 
-	page, err := strconv.ParseInt(pageStr, 10, 64)
-	if err != nil || page < 1 {
-		page = 1 // Default page
+	// Tasks of archived projects are hidden unless asked for
+	include, ok := includeArchived(w, r)
+	if !ok {
+		return
 	}
-	limit, err := strconv.ParseInt(limitStr, 10, 64)
-	if err != nil || limit < 1 || limit > 100 { // Max 100 items per page
-		limit = 10 // Default limit
+	if !include {
+		q.Filter["archived"] = primitive.M{"$ne": true}
 	}
 
-	// Filtering parameters
-	statusFilter := r.URL.Query().Get("status")
-	targetUserIDParam := r.URL.Query().Get("user_id") // For admins to filter by user
+	// Colors are stored lowercase
+	if color, ok := q.Filter["color"].(string); ok {
+		q.Filter["color"] = strings.ToLower(color)
+	}
 
-	filter := primitive.M{}
+	// Without 'task:read_all', users see their own tasks and those of the projects they are members of
+	for field, condition := range services.VisibleTasksFilter(authContext) {
+		q.Filter[field] = condition // Replaces any user_id asked for when the caller sees only their own
+	}
 
-	// Determine if user has 'task:read_all' permission
-	hasReadAllPermission := authContext.HasPermission("task:read_all")
+	// Pins are per user, so is_pinned and sort=pinned go by the caller's pinned task IDs
+	first, rest, err := h.pinnedGroups(r.Context(), authContext.UserID, q)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve tasks")
+		return
+	}
 
-	// If not admin, restrict to own tasks only
-	if !hasReadAllPermission {
-		filter["user_id"] = authContext.UserID
-	} else {
-		// If admin and a user_id query param is provided, filter by that user
-		if targetUserIDParam != "" {
-			objUserID, err := primitive.ObjectIDFromHex(targetUserIDParam)
-			if err != nil {
-				utils.RespondWithError(w, http.StatusBadRequest, "Invalid user_id filter format")
-				return
+	// Search parameter
+	searchQuery := r.URL.Query().Get("search")
+
+	if wantsNDJSON(r) {
+		streamNDJSON(w, q.Fields, func(fn func(*models.Task) error) error {
+			if first == nil {
+				return h.taskService.EachTask(r.Context(), q, searchQuery, fn)
+			}
+			for _, group := range []primitive.M{first, rest} {
+				groupQuery := *q
+				groupQuery.Filter = primitive.M{"$and": []primitive.M{q.Filter, group}}
+				if err := h.taskService.EachTask(r.Context(), &groupQuery, searchQuery, fn); err != nil {
+					return err
+				}
 			}
-			filter["user_id"] = objUserID
+			return nil
+		})
+		return
+	}
+
+	var tasksResponse *models.TaskListResponse
+	if first == nil {
+		tasksResponse, err = h.taskService.ListTasks(r.Context(), q, searchQuery)
+	} else {
+		tasksResponse, err = h.taskService.ListTasksInGroups(r.Context(), q, searchQuery, first, rest)
+	}
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve tasks")
+		return
+	}
+
+	// Flag the tasks that changed since the caller last viewed them
+	if len(q.Fields) == 0 || slices.Contains(q.Fields, "has_unread_updates") {
+		if err := h.taskViewService.FlagUnread(r.Context(), authContext.UserID, tasksResponse.Tasks); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve tasks")
+			return
 		}
 	}
 
-	if statusFilter != "" {
-		switch strings.ToLower(statusFilter) {
-		case "todo", "in_progress", "done":
-			filter["status"] = models.TaskStatus(strings.ToLower(statusFilter))
-		default:
-			utils.RespondWithError(w, http.StatusBadRequest, "Invalid status filter. Must be 'todo', 'in_progress', or 'done'.")
+	if len(q.Fields) == 0 || slices.Contains(q.Fields, "pinned") || slices.Contains(q.Fields, "pinned_at") {
+		if err := h.taskPinService.FlagPinned(r.Context(), authContext.UserID, tasksResponse.Tasks); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve tasks")
 			return
 		}
 	}
 
-	// Search parameter
-	searchQuery := r.URL.Query().Get("search")
+	setPageLinks(w, r, tasksResponse.PageInfo())
+	respondWithFields(w, tasksResponse, "tasks", q.Fields)
+}
 
-	tasksResponse, err := h.taskService.ListTasks(filter, searchQuery, page, limit)
-	if err != nil {
-		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve tasks")
-		return
+// pinnedGroups turns the is_pinned filter and a pinned sort, which must come first, into
+// conditions on the IDs of the tasks userID pinned. The filter is applied to q; for the sort,
+// the conditions of the tasks to list first and of the rest are returned. Both are nil when
+// neither is asked for.
+func (h *TaskHandler) pinnedGroups(ctx context.Context, userID primitive.ObjectID, q *query.Query) (first, rest primitive.M, err error) {
+	pinnedOnly, filtered := q.Filter["pinned"].(bool)
+	delete(q.Filter, "pinned")
+	var pinnedFirst, sorted bool
+	for i, field := range q.Sort {
+		if field.Key != "pinned" {
+			continue
+		}
+		if i != 0 {
+			return nil, nil, errPinnedSortNotFirst
+		}
+		pinnedFirst, sorted = field.Value == -1, true
+		q.Sort = q.Sort[1:]
+		break
+	}
+	if !filtered && !sorted {
+		return nil, nil, nil
 	}
 
-	utils.RespondWithJSON(w, http.StatusOK, tasksResponse)
+	ids, err := h.taskPinService.PinnedTaskIDs(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	pinned, unpinned := primitive.M{"$in": ids}, primitive.M{"$nin": ids}
+	switch {
+	case filtered && pinnedOnly:
+		q.Filter["_id"] = pinned
+	case filtered:
+		q.Filter["_id"] = unpinned
+	case pinnedFirst:
+		return primitive.M{"_id": pinned}, primitive.M{"_id": unpinned}, nil
+	case sorted:
+		return primitive.M{"_id": unpinned}, primitive.M{"_id": pinned}, nil
+	}
+	return nil, nil, nil // Filtered to one group, so sorting by it changes nothing
 }
 
 // GetTaskByID handles retrieving a single task by ID
@@ -148,23 +367,59 @@ func (h *TaskHandler) GetTaskByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	task, err := h.taskService.GetTaskByID(taskID)
+	task, err := h.taskService.GetTaskByID(r.Context(), taskID)
 	if err != nil {
-		if err.Error() == "task not found" {
-			utils.RespondWithError(w, http.StatusNotFound, err.Error())
-			return
-		}
-		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve task")
+		utils.RespondWithAppError(w, err, "Failed to retrieve task")
 		return
 	}
 
-	// Authorization check: 'task:read_all' or owner
-	if !authContext.HasPermission("task:read_all") && task.UserID != authContext.UserID {
+	// Authorization check: 'task:read_all', owner or project member
+	if !canAccessTask(authContext, task, "task:read_all", models.ProjectRoleViewer) {
 		utils.RespondWithError(w, http.StatusForbidden, "You do not have permission to view this task")
 		return
 	}
 
-	utils.RespondWithJSON(w, http.StatusOK, task)
+	h.markSeen(r, authContext, task)
+	utils.RespondWithJSON(w, http.StatusOK, h.withPin(r, authContext, task))
+}
+
+// MarkTaskSeen marks the task in the path as seen by the caller, clearing its
+// has_unread_updates flag in their listings until it changes again
+func (h *TaskHandler) MarkTaskSeen(w http.ResponseWriter, r *http.Request) {
+	taskID := mux.Vars(r)["id"]
+
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	task, err := h.taskService.GetTaskByID(r.Context(), taskID)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve task")
+		return
+	}
+
+	// Authorization check: 'task:read_all', owner or project member
+	if !canAccessTask(authContext, task, "task:read_all", models.ProjectRoleViewer) {
+		utils.RespondWithError(w, http.StatusForbidden, "You do not have permission to view this task")
+		return
+	}
+
+	if err := h.taskViewService.MarkSeen(r.Context(), authContext.UserID, task.ID); err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to mark task as seen")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// markSeen records that the caller viewed task, or changed it themselves. A failure only
+// leaves the task flagged as unread, so it is logged rather than failing the request.
+func (h *TaskHandler) markSeen(r *http.Request, authContext *models.AuthContext, task *models.Task) {
+	if err := h.taskViewService.MarkSeen(r.Context(), authContext.UserID, task.ID); err != nil {
+		logging.Warnf("Failed to mark task %s as seen by user %s: %v", task.ID.Hex(), authContext.UserID.Hex(), err)
+	}
 }
 
 // UpdateTask handles updating an existing task
@@ -179,7 +434,7 @@ func (h *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.validator.Struct(req); err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		utils.RespondWithValidationError(w, err)
 		return
 	}
 
@@ -189,32 +444,50 @@ func (h *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	task, err := h.taskService.GetTaskByID(taskID)
+	task, err := h.taskService.GetTaskByID(r.Context(), taskID)
 	if err != nil {
-		if err.Error() == "task not found" {
-			utils.RespondWithError(w, http.StatusNotFound, err.Error())
-			return
-		}
-		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve task for update")
+		utils.RespondWithAppError(w, err, "Failed to retrieve task for update")
 		return
 	}
 
-	// Authorization check: 'task:update_all' or owner
-	if !authContext.HasPermission("task:update_all") && task.UserID != authContext.UserID {
+	// Authorization check: 'task:update_all', owner or project editor
+	if !canAccessTask(authContext, task, "task:update_all", models.ProjectRoleEditor) {
 		utils.RespondWithError(w, http.StatusForbidden, "You do not have permission to update this task")
 		return
 	}
 
-	updatedTask, err := h.taskService.UpdateTask(taskID, &req)
-	if err != nil {
-		if err.Error() == "task not found or no changes made" {
-			utils.RespondWithError(w, http.StatusNotFound, err.Error())
+	if req.DueDate != nil && req.DueTimeZone == "" {
+		req.DueTimeZone = authContext.Location().String()
+	}
+
+	// Moving the task into a project needs the same access as creating one there
+	projectID := task.ProjectID
+	if req.ProjectID != nil {
+		projectID = nil
+		if *req.ProjectID != "" {
+			project, ok := h.checkProject(w, r, authContext, *req.ProjectID)
+			if !ok {
+				return
+			}
+			projectID = &project.ID
+		}
+	}
+
+	// The milestone must belong to the project the task ends up in
+	if req.MilestoneID != nil && *req.MilestoneID != "" {
+		if _, ok := h.checkMilestone(w, r, projectID, *req.MilestoneID); !ok {
 			return
 		}
-		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update task")
+	}
+
+	updatedTask, err := h.taskService.UpdateTask(r.Context(), taskID, &req)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to update task")
 		return
 	}
 
+	// The caller's own changes aren't unread updates to them
+	h.markSeen(r, authContext, updatedTask)
 	utils.RespondWithJSON(w, http.StatusOK, updatedTask)
 }
 
@@ -229,13 +502,9 @@ func (h *TaskHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	task, err := h.taskService.GetTaskByID(taskID)
+	task, err := h.taskService.GetTaskByID(r.Context(), taskID)
 	if err != nil {
-		if err.Error() == "task not found" {
-			utils.RespondWithError(w, http.StatusNotFound, err.Error())
-			return
-		}
-		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve task for deletion check")
+		utils.RespondWithAppError(w, err, "Failed to retrieve task for deletion check")
 		return
 	}
 
@@ -245,15 +514,160 @@ func (h *TaskHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.taskService.DeleteTask(taskID)
+	err = h.taskService.DeleteTask(r.Context(), taskID)
 	if err != nil {
-		if err.Error() == "task not found" {
-			utils.RespondWithError(w, http.StatusNotFound, err.Error())
-			return
-		}
-		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to delete task")
+		utils.RespondWithAppError(w, err, "Failed to delete task")
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent) // 204 No Content for successful deletion
 }
+
+// PinTask pins a task for the caller, so it can be listed first
+func (h *TaskHandler) PinTask(w http.ResponseWriter, r *http.Request) {
+	h.setPinned(w, r, true)
+}
+
+// UnpinTask unpins a task for the caller
+func (h *TaskHandler) UnpinTask(w http.ResponseWriter, r *http.Request) {
+	h.setPinned(w, r, false)
+}
+
+// setPinned pins or unpins the task in the path for the caller. Pins are the caller's own, so
+// anyone who can see the task may pin it.
+func (h *TaskHandler) setPinned(w http.ResponseWriter, r *http.Request, pinned bool) {
+	taskID := mux.Vars(r)["id"]
+
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	task, err := h.taskService.GetTaskByID(r.Context(), taskID)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve task")
+		return
+	}
+
+	// Authorization check: 'task:read_all', owner or project member
+	if !canAccessTask(authContext, task, "task:read_all", models.ProjectRoleViewer) {
+		utils.RespondWithError(w, http.StatusForbidden, "You do not have permission to view this task")
+		return
+	}
+
+	if pinned {
+		err = h.taskPinService.Pin(r.Context(), authContext.UserID, task.ID)
+	} else {
+		err = h.taskPinService.Unpin(r.Context(), authContext.UserID, task.ID)
+	}
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to pin task")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, h.withPin(r, authContext, task))
+}
+
+// withPin returns task with Pinned and PinnedAt set for the caller. A failure only leaves the
+// task shown as unpinned, so it is logged rather than failing the request.
+func (h *TaskHandler) withPin(r *http.Request, authContext *models.AuthContext, task *models.Task) *models.Task {
+	tasks := []models.Task{*task}
+	if err := h.taskPinService.FlagPinned(r.Context(), authContext.UserID, tasks); err != nil {
+		logging.Warnf("Failed to look up whether user %s pinned task %s: %v", authContext.UserID.Hex(), task.ID.Hex(), err)
+	}
+	return &tasks[0]
+}
+
+// MergeTasks merges a duplicate task into the task in the path. The caller must be able to
+// update both; the duplicate's comments and attachments move over and it is closed.
+func (h *TaskHandler) MergeTasks(w http.ResponseWriter, r *http.Request) {
+	taskID := mux.Vars(r)["id"]
+
+	var req models.MergeTasksRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondWithValidationError(w, err)
+		return
+	}
+
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	task, err := h.taskService.GetTaskByID(r.Context(), taskID)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve task for merge")
+		return
+	}
+
+	// Authorization check: 'task:update_all', owner or project editor, of both tasks
+	allowed := canAccessTask(authContext, task, "task:update_all", models.ProjectRoleEditor)
+	if allowed {
+		if source, err := h.taskService.GetTaskByID(r.Context(), req.SourceID); err == nil {
+			allowed = canAccessTask(authContext, source, "task:update_all", models.ProjectRoleEditor)
+		}
+	}
+	if !allowed {
+		utils.RespondWithError(w, http.StatusForbidden, "You do not have permission to update both tasks")
+		return
+	}
+
+	merged, err := h.taskMergeService.MergeTasks(r.Context(), taskID, req.SourceID)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to merge tasks")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, merged)
+}
+
+// LinkAttachment attaches one of the caller's existing uploads to a task, so files can be
+// uploaded before the task they belong to exists
+func (h *TaskHandler) LinkAttachment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	taskID := vars["id"]
+
+	var req models.LinkAttachmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondWithValidationError(w, err)
+		return
+	}
+
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	task, err := h.taskService.GetTaskByID(r.Context(), taskID)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to retrieve task")
+		return
+	}
+
+	// Authorization check: 'task:update_all' or owner
+	if !authContext.HasPermission("task:update_all") && task.UserID != authContext.UserID {
+		utils.RespondWithError(w, http.StatusForbidden, "You do not have permission to update this task")
+		return
+	}
+
+	upload, err := h.uploadService.LinkUpload(r.Context(), authContext.UserID, req.PublicID, "task", task.ID)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to link attachment")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, upload)
+}
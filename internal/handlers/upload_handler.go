@@ -1,31 +1,65 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+
+	"github.com/OsGift/taskflow-api/internal/apperror"
+	"github.com/OsGift/taskflow-api/internal/middleware"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
 	"github.com/OsGift/taskflow-api/internal/services"
 	"github.com/OsGift/taskflow-api/internal/utils"
 )
 
+// uploadListSpec whitelists the filters and sorts accepted by GET /uploads/mine
+var uploadListSpec = query.Spec{
+	Filters: []query.Filter{
+		{Param: "resource_type", Kind: query.Enum, Values: []string{"user", "task"}},
+		{Param: "resource_id", Kind: query.ObjectID},
+		{Param: "created", Field: "created_at", Kind: query.TimeRange},
+	},
+	Sorts:       []string{"created_at", "size"},
+	DefaultSort: "-created_at",
+}
+
 // UploadHandler handles file upload related HTTP requests
 type UploadHandler struct {
 	uploadService *services.UploadService
+	validator     *validator.Validate
 }
 
 // NewUploadHandler creates a new UploadHandler
 func NewUploadHandler(us *services.UploadService) *UploadHandler {
 	return &UploadHandler{
 		uploadService: us,
+		validator:     validator.New(),
 	}
 }
 
-// UploadFile handles file uploads to Cloudinary
+// UploadFile handles file uploads to the configured storage backend
 func (h *UploadHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
 	// Permission check is done by middleware (e.g., any logged-in user can upload their profile pic)
 
-	// Max 10MB file size
-	r.ParseMultipartForm(10 << 20) // 10MB
+	// Leave room for the multipart framing around the file itself
+	if maxSize := h.uploadService.MaxSize(); maxSize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, maxSize+1<<20)
+	}
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			utils.RespondWithAppError(w, apperror.New(apperror.CodePayloadTooLarge, fmt.Sprintf("File exceeds the maximum size of %d bytes", h.uploadService.MaxSize())).
+				WithDetails(map[string]interface{}{"max_size": h.uploadService.MaxSize()}), "Failed to upload file")
+			return
+		}
+		utils.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Error parsing multipart form: %v", err))
+		return
+	}
 
 	file, fileHeader, err := r.FormFile("file") // "file" is the name of the form field
 	if err != nil {
@@ -34,22 +68,132 @@ func (h *UploadHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
-	if fileHeader.Size == 0 {
-		utils.RespondWithError(w, http.StatusBadRequest, "Uploaded file is empty.")
+	// Optional form fields linking the upload to the resource it belongs to
+	link := models.UploadLink{ResourceType: r.FormValue("resource_type"), ResourceID: r.FormValue("resource_id")}
+	if err := h.validator.Struct(link); err != nil {
+		utils.RespondWithValidationError(w, err)
+		return
+	}
+
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	// Type, size and dimensions are checked by the service against the configured upload policy
+	upload, err := h.uploadService.UploadFile(r.Context(), authContext.UserID, link, fileHeader)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to upload file")
 		return
 	}
 
-	// You might want to add file type validation here (e.g., only images)
-	// if !strings.HasPrefix(fileHeader.Header.Get("Content-Type"), "image/") {
-	// 	utils.RespondWithError(w, http.StatusBadRequest, "Only image files are allowed.")
-	// 	return
-	// }
+	utils.RespondWithJSON(w, http.StatusOK, models.UploadResponse{Message: "File uploaded successfully", Upload: *upload})
+}
+
+// SignDirectUpload issues a short-lived signature for uploading one file straight to the
+// storage backend, so large files don't pass through the API server
+func (h *UploadHandler) SignDirectUpload(w http.ResponseWriter, r *http.Request) {
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	signature, err := h.uploadService.SignDirectUpload(r.Context(), authContext.UserID)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to sign upload")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, signature)
+}
+
+// ConfirmDirectUpload records a finished direct upload, verifying the backend's signature
+func (h *UploadHandler) ConfirmDirectUpload(w http.ResponseWriter, r *http.Request) {
+	var req models.ConfirmDirectUploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondWithValidationError(w, err)
+		return
+	}
+
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	upload, err := h.uploadService.ConfirmDirectUpload(r.Context(), authContext.UserID, req)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to confirm upload")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, models.UploadResponse{Message: "File uploaded successfully", Upload: *upload})
+}
+
+// DeleteUpload removes an uploaded file; users may delete their own uploads, and callers
+// with 'upload:delete_all' any upload
+func (h *UploadHandler) DeleteUpload(w http.ResponseWriter, r *http.Request) {
+	publicID := mux.Vars(r)["public_id"]
+
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if err := h.uploadService.DeleteUpload(r.Context(), authContext.UserID, authContext.HasPermission("upload:delete_all"), publicID); err != nil {
+		utils.RespondWithAppError(w, err, "Failed to delete upload")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListMyUploads lists the current user's uploads, newest first.
+// Supports filtering by resource_type, resource_id and a created_from/created_to range.
+func (h *UploadHandler) ListMyUploads(w http.ResponseWriter, r *http.Request) {
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	q, err := uploadListSpec.Parse(r.URL.Query())
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Invalid query parameters")
+		return
+	}
+	q.Filter["uploader_id"] = authContext.UserID
+
+	uploads, err := h.uploadService.ListUploads(r.Context(), q)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve uploads")
+		return
+	}
+
+	respondWithPage(w, r, uploads)
+}
+
+// GetMyUsage reports how much of their storage quota the current user has used
+func (h *UploadHandler) GetMyUsage(w http.ResponseWriter, r *http.Request) {
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
 
-	imageURL, err := h.uploadService.UploadFile(fileHeader)
+	usage, err := h.uploadService.Usage(r.Context(), authContext.UserID)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to upload file: %v", err))
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve storage usage")
 		return
 	}
 
-	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "File uploaded successfully", "url": imageURL})
+	utils.RespondWithJSON(w, http.StatusOK, usage)
 }
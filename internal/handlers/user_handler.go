@@ -3,31 +3,48 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
-	"strconv"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/OsGift/taskflow-api/internal/middleware"
 	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
 	"github.com/OsGift/taskflow-api/internal/services"
 	"github.com/OsGift/taskflow-api/internal/utils"
 )
 
+// userListSpec whitelists the filters and sorts accepted by GET /users
+var userListSpec = query.Spec{
+	Filters: []query.Filter{
+		{Param: "email_like", Field: "email", Kind: query.Contains},
+		{Param: "created", Field: "created_at", Kind: query.TimeRange},
+		{Param: "service_account", Field: "is_service_account", Kind: query.Bool},
+	},
+	Sorts:       []string{"created_at", "updated_at", "email", "first_name", "last_name"},
+	DefaultSort: "-created_at",
+	Fields: []string{"first_name", "last_name", "email", "role_name", "profile_picture_url", "phone", "address",
+		"is_email_verified", "needs_password_change", "weekly_digest", "daily_summary", "daily_summary_hour", "locale", "time_zone",
+		"is_service_account", "disabled",
+		"merged_into", "created_at", "updated_at"},
+	FieldSources: map[string][]string{"role_name": {"role_id"}},
+}
+
 // UserHandler handles user related HTTP requests
 type UserHandler struct {
-	userService *services.UserService
-	authService *services.AuthService // Needed for admin creation to hash temp password
-	validator   *validator.Validate
+	userService      *services.UserService
+	authService      *services.AuthService // Needed for admin creation to hash temp password
+	userMergeService *services.UserMergeService
+	validator        *validator.Validate
 }
 
 // NewUserHandler creates a new UserHandler
-func NewUserHandler(us *services.UserService, as *services.AuthService) *UserHandler {
+func NewUserHandler(us *services.UserService, as *services.AuthService, ms *services.UserMergeService) *UserHandler {
 	return &UserHandler{
-		userService: us,
-		authService: as,
-		validator:   validator.New(),
+		userService:      us,
+		authService:      as,
+		userMergeService: ms,
+		validator:        validator.New(),
 	}
 }
 
@@ -40,7 +57,7 @@ func (h *UserHandler) CreateAdminUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.validator.Struct(req); err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		utils.RespondWithValidationError(w, err)
 		return
 	}
 
@@ -48,13 +65,9 @@ func (h *UserHandler) CreateAdminUser(w http.ResponseWriter, r *http.Request) {
 	tempPassword := utils.GenerateRandomString(12) // You'll need to implement this in utils/helpers.go
 
 	// Delegate to authService's register logic, but indicate it's an admin creation
-	userResponse, err := h.authService.RegisterUser(req, true, tempPassword) // is_admin_creation = true
+	userResponse, err := h.authService.RegisterUser(r.Context(), req, true, tempPassword) // is_admin_creation = true
 	if err != nil {
-		if err.Error() == "email already registered" {
-			utils.RespondWithError(w, http.StatusConflict, err.Error())
-			return
-		}
-		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create admin user")
+		utils.RespondWithAppError(w, err, "Failed to create admin user")
 		return
 	}
 
@@ -80,9 +93,9 @@ func (h *UserHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 
 	// Check if the authenticated user is requesting their own profile
 	if authContext.UserID.Hex() == targetUserID {
-		userResponse, err := h.userService.GetUserResponseByID(targetUserID)
+		userResponse, err := h.userService.GetUserResponseByID(r.Context(), targetUserID)
 		if err != nil {
-			utils.RespondWithError(w, http.StatusNotFound, err.Error())
+			utils.RespondWithAppError(w, err, "Failed to retrieve user")
 			return
 		}
 		utils.RespondWithJSON(w, http.StatusOK, userResponse)
@@ -95,9 +108,9 @@ func (h *UserHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userResponse, err := h.userService.GetUserResponseByID(targetUserID)
+	userResponse, err := h.userService.GetUserResponseByID(r.Context(), targetUserID)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusNotFound, err.Error())
+		utils.RespondWithAppError(w, err, "Failed to retrieve user")
 		return
 	}
 
@@ -116,7 +129,7 @@ func (h *UserHandler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.validator.Struct(req); err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		utils.RespondWithValidationError(w, err)
 		return
 	}
 
@@ -129,13 +142,13 @@ func (h *UserHandler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
 	// The permission check for "user:update_role" is done by the middleware before reaching here.
 	// Additional check: Cannot change the role of a user to 'Admin' if not explicitly permitted
 	// And Super Admin (the initial seeded Admin) role cannot be changed by another admin.
-	targetUser, err := h.userService.GetUserByID(targetUserID)
+	targetUser, err := h.userService.GetUserByID(r.Context(), targetUserID)
 	if err != nil {
 		utils.RespondWithError(w, http.StatusNotFound, "Target user not found")
 		return
 	}
 
-	targetRole, err := h.userService.GetRoleByID(targetUser.RoleID.Hex())
+	targetRole, err := h.userService.GetRoleByID(r.Context(), targetUser.RoleID.Hex())
 	if err != nil {
 		utils.RespondWithError(w, http.StatusInternalServerError, "Could not determine target user's current role")
 		return
@@ -158,13 +171,9 @@ func (h *UserHandler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
 	// (currently covered by 'user:update_role' which is for Admin role)
 	// You might introduce a 'user:assign_admin_role' permission for this if needed.
 
-	userResponse, err := h.userService.UpdateUserRole(targetUserID, req.RoleName)
+	userResponse, err := h.userService.UpdateUserRole(r.Context(), targetUserID, req.RoleName)
 	if err != nil {
-		if err.Error() == "user not found or role not changed" || err.Error() == "new role not found" || err.Error() == "invalid user ID format" {
-			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
-			return
-		}
-		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update user role")
+		utils.RespondWithAppError(w, err, "Failed to update user role")
 		return
 	}
 
@@ -183,7 +192,7 @@ func (h *UserHandler) UpdateUserProfile(w http.ResponseWriter, r *http.Request)
 	}
 
 	if err := h.validator.Struct(req); err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		utils.RespondWithValidationError(w, err)
 		return
 	}
 
@@ -201,13 +210,9 @@ func (h *UserHandler) UpdateUserProfile(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	userResponse, err := h.userService.UpdateUserProfile(targetUserID, &req)
+	userResponse, err := h.userService.UpdateUserProfile(r.Context(), targetUserID, &req)
 	if err != nil {
-		if err.Error() == "user not found or no changes made to profile" || err.Error() == "invalid user ID format" {
-			utils.RespondWithError(w, http.StatusNotFound, err.Error())
-			return
-		}
-		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update user profile")
+		utils.RespondWithAppError(w, err, "Failed to update user profile")
 		return
 	}
 
@@ -218,47 +223,132 @@ func (h *UserHandler) UpdateUserProfile(w http.ResponseWriter, r *http.Request)
 func (h *UserHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
 	// Permission 'user:read_all' is checked by middleware
 
-	// Pagination parameters
-	pageStr := r.URL.Query().Get("page")
-	limitStr := r.URL.Query().Get("limit")
-
-	page, err := strconv.ParseInt(pageStr, 10, 64)
-	if err != nil || page < 1 {
-		page = 1 // Default page
-	}
-	limit, err := strconv.ParseInt(limitStr, 10, 64)
-	if err != nil || limit < 1 || limit > 100 { // Max 100 items per page
-		limit = 10 // Default limit
+	// Pagination, filters (email_like, created range) and sorting
+	q, err := userListSpec.Parse(r.URL.Query())
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Invalid query parameters")
+		return
 	}
 
-	// Build filter based on query parameters (e.g., by email, role, etc. if needed)
-	filter := primitive.M{}
-	// Example: filter by email fragment (case-insensitive)
-	emailFilter := r.URL.Query().Get("email_like")
-	if emailFilter != "" {
-		filter["email"] = primitive.Regex{Pattern: emailFilter, Options: "i"}
-	}
-	// Example: filter by role name
+	// Filter by role name, resolved to the role's ID
 	roleNameFilter := r.URL.Query().Get("role_name")
 	if roleNameFilter != "" {
-		role, err := h.userService.GetRoleByName(roleNameFilter)
+		role, err := h.userService.GetRoleByName(r.Context(), roleNameFilter)
 		if err == nil {
-			filter["role_id"] = role.ID
+			q.Filter["role_id"] = role.ID
 		} else {
 			// If role name doesn't exist, return empty list or error
+			if wantsNDJSON(r) {
+				startStream(w, "application/x-ndjson")
+				return
+			}
 			utils.RespondWithJSON(w, http.StatusOK, models.UserListResponse{
 				Users:      []models.UserResponse{},
-				TotalCount: 0, Page: page, Limit: limit,
+				Pagination: models.NewPagination(0, q.Page, q.Limit),
 			})
 			return
 		}
 	}
 
-	usersResponse, err := h.userService.ListUsers(filter, page, limit)
+	if wantsNDJSON(r) {
+		streamNDJSON(w, q.Fields, func(fn func(*models.UserResponse) error) error {
+			return h.userService.EachUser(r.Context(), q, fn)
+		})
+		return
+	}
+
+	usersResponse, err := h.userService.ListUsers(r.Context(), q)
 	if err != nil {
 		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve users")
 		return
 	}
 
-	utils.RespondWithJSON(w, http.StatusOK, usersResponse)
+	setPageLinks(w, r, usersResponse.PageInfo())
+	respondWithFields(w, usersResponse, "users", q.Fields)
+}
+
+// DeleteUser deletes a user (requires 'user:delete' permission).
+// Their tasks are deleted too, unless ?reassign_to=<user id> names a user to hand them, and the
+// projects the user owns, over to. A user who owns projects can only be deleted that way.
+func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	targetUserID := vars["id"]
+	reassignTo := r.URL.Query().Get("reassign_to")
+
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if targetUserID == authContext.UserID.Hex() {
+		utils.RespondWithError(w, http.StatusForbidden, "You cannot delete your own account.")
+		return
+	}
+
+	targetUser, err := h.userService.GetUserByID(r.Context(), targetUserID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "Target user not found")
+		return
+	}
+	targetRole, err := h.userService.GetRoleByID(r.Context(), targetUser.RoleID.Hex())
+	if err == nil && targetRole.Name == "Admin" {
+		// Same rule as role changes: one Admin cannot remove another
+		utils.RespondWithError(w, http.StatusForbidden, "You cannot delete another Admin.")
+		return
+	}
+
+	err = h.userService.DeleteUser(r.Context(), targetUserID, reassignTo)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to delete user")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MergeUsers merges a duplicate account into the user in the path (requires 'user:merge'
+// permission). The duplicate's tasks, comments and uploads move over and it is disabled.
+func (h *UserHandler) MergeUsers(w http.ResponseWriter, r *http.Request) {
+	primaryUserID := mux.Vars(r)["id"]
+
+	var req models.MergeUsersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondWithValidationError(w, err)
+		return
+	}
+
+	authContext, err := middleware.GetAuthContext(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if req.DuplicateID == authContext.UserID.Hex() {
+		utils.RespondWithError(w, http.StatusForbidden, "You cannot merge away your own account.")
+		return
+	}
+
+	duplicateUser, err := h.userService.GetUserByID(r.Context(), req.DuplicateID)
+	if err == nil {
+		duplicateRole, err := h.userService.GetRoleByID(r.Context(), duplicateUser.RoleID.Hex())
+		if err == nil && duplicateRole.Name == "Admin" {
+			// Merging disables the duplicate, so the rule for deleting users applies
+			utils.RespondWithError(w, http.StatusForbidden, "You cannot merge away another Admin.")
+			return
+		}
+	}
+
+	merged, err := h.userMergeService.MergeUsers(r.Context(), primaryUserID, req.DuplicateID)
+	if err != nil {
+		utils.RespondWithAppError(w, err, "Failed to merge users")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, merged)
 }
@@ -0,0 +1,111 @@
+// Package importer converts exports from other task managers into TaskFlow tasks. Each source
+// maps its own containers (Trello lists, Todoist sections) onto task statuses by name.
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+)
+
+const (
+	// MaxTasks is the most tasks one import may create
+	MaxTasks = 5000
+	// minTitleLength matches the validation of task titles
+	minTitleLength = 5
+)
+
+// ErrTooManyTasks is returned for exports with more than MaxTasks importable tasks
+var ErrTooManyTasks = errors.New("the export contains too many tasks")
+
+var (
+	// doneNames and inProgressNames recognise the lists and sections whose tasks are done or
+	// being worked on; everything else is to do
+	doneNames       = regexp.MustCompile(`(?i)\b(done|complete[d]?|finished|shipped|closed|resolved|released)\b`)
+	inProgressNames = regexp.MustCompile(`(?i)\b(doing|in[ -]?progress|wip|working|active|started|review|reviewing|testing|qa)\b`)
+)
+
+// statusForName guesses the status of the tasks in a list or section from its name
+func statusForName(name string) models.TaskStatus {
+	switch {
+	case doneNames.MatchString(name):
+		return models.StatusDone
+	case inProgressNames.MatchString(name):
+		return models.StatusInProgress
+	}
+	return models.StatusTodo
+}
+
+// result collects the tasks and skipped items of a conversion
+type result struct {
+	preview *models.ImportPreview
+}
+
+// newResult starts the preview of an import from source
+func newResult(source string) *result {
+	return &result{preview: &models.ImportPreview{
+		Source:       source,
+		Containers:   []string{},
+		Tasks:        []models.ImportedTask{},
+		Skipped:      []models.ImportSkipped{},
+		StatusCounts: map[models.TaskStatus]int{},
+	}}
+}
+
+// add records an importable task, filling in the timestamps the source didn't have. Tasks
+// whose title is too short for TaskFlow are skipped.
+func (r *result) add(task models.ImportedTask, now time.Time) error {
+	task.Title = strings.TrimSpace(task.Title)
+	if utf8.RuneCountInString(task.Title) < minTitleLength {
+		r.skip(task.Title, fmt.Sprintf("title is shorter than %d characters", minTitleLength))
+		return nil
+	}
+	if len(r.preview.Tasks) == MaxTasks {
+		return ErrTooManyTasks
+	}
+	if task.CreatedAt.IsZero() && task.CompletedAt != nil {
+		task.CreatedAt = *task.CompletedAt
+	}
+	if task.CreatedAt.IsZero() || task.CreatedAt.After(now) {
+		task.CreatedAt = now
+	}
+	if task.Status == models.StatusDone {
+		if task.CompletedAt == nil || task.CompletedAt.Before(task.CreatedAt) {
+			task.CompletedAt = &task.CreatedAt
+		}
+	} else {
+		task.CompletedAt = nil
+	}
+	r.preview.Tasks = append(r.preview.Tasks, task)
+	r.preview.StatusCounts[task.Status]++
+	return nil
+}
+
+// skip records an item that won't be imported
+func (r *result) skip(name, reason string) {
+	r.preview.Skipped = append(r.preview.Skipped, models.ImportSkipped{Name: name, Reason: reason})
+}
+
+// sourceLine is appended to imported descriptions, since tasks have no board or project
+func sourceLine(description, source string, path ...string) string {
+	line := "Imported from " + source + ": " + strings.Join(path, " › ")
+	if description = strings.TrimSpace(description); description == "" {
+		return line
+	}
+	return description + "\n\n" + line
+}
+
+// parseTime accepts RFC 3339 times, floating date-times (taken as UTC) and plain dates
+func parseTime(value string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
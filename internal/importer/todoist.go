@@ -0,0 +1,146 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+)
+
+// todoistID accepts Todoist IDs, which are numbers in old exports and strings in newer ones
+type todoistID string
+
+// UnmarshalJSON implements json.Unmarshaler
+func (id *todoistID) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*id = todoistID(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*id = todoistID(n.String())
+	return nil
+}
+
+// todoistBackup is the part of a Todoist Sync API response (resource_types=["all"]) that is
+// imported
+type todoistBackup struct {
+	Projects []struct {
+		ID         todoistID `json:"id"`
+		Name       string    `json:"name"`
+		IsArchived bool      `json:"is_archived"`
+		IsDeleted  bool      `json:"is_deleted"`
+	} `json:"projects"`
+	Sections []struct {
+		ID   todoistID `json:"id"`
+		Name string    `json:"name"`
+	} `json:"sections"`
+	Items []struct {
+		ID          todoistID  `json:"id"`
+		Content     string     `json:"content"`
+		Description string     `json:"description"`
+		ProjectID   todoistID  `json:"project_id"`
+		SectionID   *todoistID `json:"section_id"`
+		Checked     bool       `json:"checked"`
+		IsDeleted   bool       `json:"is_deleted"`
+		Due         *struct {
+			Date string `json:"date"`
+		} `json:"due"`
+		AddedAt     string  `json:"added_at"`
+		CompletedAt *string `json:"completed_at"`
+	} `json:"items"`
+}
+
+// Todoist converts a Todoist backup in the Sync API format. Items take their status from
+// the name of their section, or are done when checked; deleted items and items in archived or
+// deleted projects are skipped.
+func Todoist(r io.Reader, now time.Time) (*models.ImportPreview, error) {
+	var backup todoistBackup
+	if err := json.NewDecoder(r).Decode(&backup); err != nil {
+		return nil, fmt.Errorf("not a Todoist backup: %w", err)
+	}
+	if backup.Projects == nil || backup.Items == nil {
+		return nil, fmt.Errorf("not a Todoist backup: no projects or items")
+	}
+
+	res := newResult("todoist")
+	type project struct {
+		name    string
+		skipped bool
+	}
+	projects := map[todoistID]project{}
+	for _, p := range backup.Projects {
+		projects[p.ID] = project{name: p.Name, skipped: p.IsArchived || p.IsDeleted}
+		if !p.IsArchived && !p.IsDeleted {
+			res.preview.Containers = append(res.preview.Containers, p.Name)
+		}
+	}
+	sections := map[todoistID]string{}
+	for _, s := range backup.Sections {
+		sections[s.ID] = s.Name
+	}
+
+	for _, item := range backup.Items {
+		p, ok := projects[item.ProjectID]
+		switch {
+		case item.IsDeleted:
+			res.skip(item.Content, "deleted item")
+			continue
+		case !ok:
+			res.skip(item.Content, "item is in an unknown project")
+			continue
+		case p.skipped:
+			res.skip(item.Content, "project "+p.name+" is archived or deleted")
+			continue
+		case item.Content == "":
+			res.skip(string(item.ID), "item has no title")
+			continue
+		}
+
+		path := []string{p.name}
+		status := models.StatusTodo
+		if item.SectionID != nil {
+			if section, ok := sections[*item.SectionID]; ok {
+				path = append(path, section)
+				status = statusForName(section)
+			}
+		}
+		if item.Checked {
+			status = models.StatusDone
+		}
+
+		task := models.ImportedTask{
+			Title:       item.Content,
+			Description: sourceLine(item.Description, "Todoist", path...),
+			Status:      status,
+		}
+		for i, part := range path {
+			if i > 0 {
+				task.Source += " › "
+			}
+			task.Source += part
+		}
+		if item.Due != nil {
+			if due, ok := parseTime(item.Due.Date); ok {
+				task.DueDate = &due
+			}
+		}
+		if added, ok := parseTime(item.AddedAt); ok {
+			task.CreatedAt = added
+		}
+		if item.CompletedAt != nil {
+			if completed, ok := parseTime(*item.CompletedAt); ok {
+				task.CompletedAt = &completed
+			}
+		}
+		if err := res.add(task, now); err != nil {
+			return nil, err
+		}
+	}
+	return res.preview, nil
+}
@@ -0,0 +1,101 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+)
+
+// trelloBoard is the part of a Trello board export (Menu › Print, export and share › Export as
+// JSON) that is imported
+type trelloBoard struct {
+	Name  string `json:"name"`
+	Lists []struct {
+		ID     string `json:"id"`
+		Name   string `json:"name"`
+		Closed bool   `json:"closed"`
+	} `json:"lists"`
+	Cards []struct {
+		ID               string  `json:"id"`
+		Name             string  `json:"name"`
+		Desc             string  `json:"desc"`
+		IDList           string  `json:"idList"`
+		Closed           bool    `json:"closed"`
+		Due              *string `json:"due"`
+		DueComplete      bool    `json:"dueComplete"`
+		DateLastActivity string  `json:"dateLastActivity"`
+	} `json:"cards"`
+}
+
+// Trello converts a Trello board export. Cards take their status from the name of their list,
+// or are done when their due date is marked complete; archived cards and cards in archived
+// lists are skipped.
+func Trello(r io.Reader, now time.Time) (*models.ImportPreview, error) {
+	var board trelloBoard
+	if err := json.NewDecoder(r).Decode(&board); err != nil {
+		return nil, fmt.Errorf("not a Trello board export: %w", err)
+	}
+	if board.Name == "" || board.Lists == nil {
+		return nil, fmt.Errorf("not a Trello board export: no board name or lists")
+	}
+
+	res := newResult("trello")
+	res.preview.Containers = append(res.preview.Containers, board.Name)
+	type list struct {
+		name   string
+		closed bool
+	}
+	lists := map[string]list{}
+	for _, l := range board.Lists {
+		lists[l.ID] = list{name: l.Name, closed: l.Closed}
+	}
+
+	for _, card := range board.Cards {
+		l, ok := lists[card.IDList]
+		switch {
+		case card.Closed:
+			res.skip(card.Name, "archived card")
+			continue
+		case !ok:
+			res.skip(card.Name, "card is in an unknown list")
+			continue
+		case l.closed:
+			res.skip(card.Name, "list "+l.name+" is archived")
+			continue
+		case card.Name == "":
+			res.skip(card.ID, "card has no title")
+			continue
+		}
+
+		task := models.ImportedTask{
+			Title:       card.Name,
+			Description: sourceLine(card.Desc, "Trello", board.Name, l.name),
+			Status:      statusForName(l.name),
+			Source:      board.Name + " › " + l.name,
+		}
+		if card.DueComplete {
+			task.Status = models.StatusDone
+		}
+		if card.Due != nil {
+			if due, ok := parseTime(*card.Due); ok {
+				task.DueDate = &due
+			}
+		}
+		// Trello IDs are MongoDB object IDs, so they carry the card's creation time
+		if id, err := primitive.ObjectIDFromHex(card.ID); err == nil {
+			task.CreatedAt = id.Timestamp().UTC()
+		}
+		if activity, ok := parseTime(card.DateLastActivity); ok && task.Status == models.StatusDone {
+			task.CompletedAt = &activity
+		}
+		if err := res.add(task, now); err != nil {
+			return nil, err
+		}
+	}
+	return res.preview, nil
+}
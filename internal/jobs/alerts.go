@@ -0,0 +1,102 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/OsGift/taskflow-api/internal/logging"
+	"github.com/OsGift/taskflow-api/internal/models"
+)
+
+// jobFailedTemplate is the email template used for dead-letter alerts
+const jobFailedTemplate = "job_failed"
+
+// Alerter tells operators about dead-lettered jobs, by posting to a webhook (Slack-compatible
+// "text" payload) and/or emailing an address. Either channel may be left empty.
+type Alerter struct {
+	queue      *Queue
+	webhookURL string
+	email      string
+	client     *http.Client
+}
+
+// NewAlerter creates an Alerter; alert emails are sent through q like any other email
+func NewAlerter(q *Queue, webhookURL, email string) *Alerter {
+	return &Alerter{
+		queue:      q,
+		webhookURL: webhookURL,
+		email:      email,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// JobDead reports a dead-lettered job. It is meant to be registered with Worker.OnDeadLetter.
+func (a *Alerter) JobDead(ctx context.Context, job *models.Job, jobErr error) {
+	summary := fmt.Sprintf("Job %s (%s) failed permanently after %d attempts: %v", job.ID.Hex(), job.Type, job.Attempts, jobErr)
+
+	// Name the template and recipient of failed emails, but never their data (e.g. reset links)
+	var email EmailPayload
+	isEmail := job.Type == TypeSendEmail && json.Unmarshal(job.Payload, &email) == nil
+	if isEmail {
+		summary += fmt.Sprintf(" [email %q to %s]", email.Template, email.To)
+	}
+
+	if a.webhookURL != "" {
+		if err := a.postWebhook(ctx, job, jobErr, summary); err != nil {
+			logging.Warnf("Job alerts: failed to post webhook alert for job %s: %v", job.ID.Hex(), err)
+		}
+	}
+
+	// An alert that itself can't be delivered must not raise another alert
+	if a.email != "" && !(isEmail && email.Template == jobFailedTemplate) {
+		data := map[string]interface{}{
+			"JobID":     job.ID.Hex(),
+			"JobType":   job.Type,
+			"Attempts":  job.Attempts,
+			"Error":     jobErr.Error(),
+			"Email":     "",
+			"Recipient": "",
+			"Year":      time.Now().Year(),
+		}
+		if isEmail {
+			data["Email"], data["Recipient"] = email.Template, email.To
+		}
+		if err := a.queue.EnqueueEmail(ctx, jobFailedTemplate, "TaskFlow: background job failed", a.email, "", data); err != nil {
+			logging.Warnf("Job alerts: failed to queue alert email for job %s: %v", job.ID.Hex(), err)
+		}
+	}
+}
+
+// postWebhook sends an alert to the webhook
+func (a *Alerter) postWebhook(ctx context.Context, job *models.Job, jobErr error, summary string) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"text":     summary,
+		"job_id":   job.ID.Hex(),
+		"job_type": job.Type,
+		"attempts": job.Attempts,
+		"error":    jobErr.Error(),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
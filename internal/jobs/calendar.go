@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// TypeCalendarPushTask is the job type that mirrors one task into its owner's Google Calendar
+	TypeCalendarPushTask = "calendar:push_task"
+	// TypeCalendarPull is the job type that applies calendar changes back to one user's tasks
+	TypeCalendarPull = "calendar:pull"
+)
+
+// CalendarPushTaskPayload identifies the task to mirror and the user whose calendar holds it
+type CalendarPushTaskPayload struct {
+	TaskID primitive.ObjectID `json:"task_id"`
+	UserID primitive.ObjectID `json:"user_id"`
+}
+
+// CalendarPullPayload identifies one pull of a user's calendar
+type CalendarPullPayload struct {
+	UserID primitive.ObjectID `json:"user_id"`
+	RunAt  time.Time          `json:"run_at"` // Scheduled time of the pull
+}
+
+// ScheduleCalendarPull queues a pull of a user's calendar at runAt. A pull is only ever queued
+// once, so rescheduling the same pull is harmless.
+func ScheduleCalendarPull(ctx context.Context, q *Queue, userID primitive.ObjectID, runAt time.Time) error {
+	key := fmt.Sprintf("%s:%s:%s", TypeCalendarPull, userID.Hex(), runAt.Format(time.RFC3339))
+	_, err := q.EnqueueUnique(ctx, key, TypeCalendarPull, CalendarPullPayload{UserID: userID, RunAt: runAt}, runAt)
+	return err
+}
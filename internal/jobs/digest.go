@@ -0,0 +1,17 @@
+package jobs
+
+import "time"
+
+// TypeWeeklyDigest is the job type that emails the weekly digest to every opted-in user
+const TypeWeeklyDigest = "digest:weekly"
+
+// NextWeekly returns the first time strictly after t that falls on weekday at hour:00 UTC
+func NextWeekly(t time.Time, weekday time.Weekday, hour int) time.Time {
+	t = t.UTC()
+	next := time.Date(t.Year(), t.Month(), t.Day(), hour, 0, 0, 0, time.UTC)
+	next = next.AddDate(0, 0, (int(weekday)-int(next.Weekday())+7)%7)
+	if !next.After(t) {
+		next = next.AddDate(0, 0, 7)
+	}
+	return next
+}
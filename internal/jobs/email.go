@@ -0,0 +1,107 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/OsGift/taskflow-api/internal/logging"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+// TypeSendEmail is the job type for transactional emails
+const TypeSendEmail = "email:send"
+
+// EmailPayload describes an email to render and send
+type EmailPayload struct {
+	Template string      `json:"template"`
+	Subject  string      `json:"subject"`
+	To       string      `json:"to"`
+	Locale   string      `json:"locale,omitempty"` // Recipient's locale; English when empty or untranslated
+	Data     interface{} `json:"data"`             // Template data; decoded as a map when the job runs
+}
+
+// EnqueueEmail queues a templated email for delivery by the worker. subject is the English
+// subject, replaced by its translation when the email is sent in another locale.
+func (q *Queue) EnqueueEmail(ctx context.Context, templateName, subject, toEmail, locale string, data interface{}) error {
+	return q.Enqueue(ctx, TypeSendEmail, EmailPayload{
+		Template: templateName,
+		Subject:  subject,
+		To:       toEmail,
+		Locale:   locale,
+		Data:     data,
+	})
+}
+
+// EnqueueUniqueEmail queues a templated email unless one was already queued under key,
+// e.g. to send each user at most one copy of a scheduled email
+func (q *Queue) EnqueueUniqueEmail(ctx context.Context, key, templateName, subject, toEmail, locale string, data interface{}) (bool, error) {
+	return q.EnqueueUnique(ctx, key, TypeSendEmail, EmailPayload{
+		Template: templateName,
+		Subject:  subject,
+		To:       toEmail,
+		Locale:   locale,
+		Data:     data,
+	}, time.Now())
+}
+
+// DeliveryRecorder stores the outcome of every email send attempt
+type DeliveryRecorder interface {
+	RecordDelivery(ctx context.Context, delivery *models.EmailDelivery) error
+}
+
+// SendEmailHandler delivers an EmailPayload job via utils.SendEmail
+func SendEmailHandler(ctx context.Context, payload []byte) error {
+	var email EmailPayload
+	if err := json.Unmarshal(payload, &email); err != nil {
+		return err
+	}
+	return utils.SendEmail(ctx, email.Template, email.Subject, email.To, email.Locale, email.Data)
+}
+
+// RecordingEmailHandler returns a handler that delivers email jobs like SendEmailHandler and
+// reports each attempt to deliveries. Failing to record an attempt doesn't fail the job.
+func RecordingEmailHandler(deliveries DeliveryRecorder) HandlerFunc {
+	return func(ctx context.Context, payload []byte) error {
+		var email EmailPayload
+		if err := json.Unmarshal(payload, &email); err != nil {
+			return err
+		}
+		sendErr := utils.SendEmail(ctx, email.Template, email.Subject, email.To, email.Locale, email.Data)
+
+		delivery := &models.EmailDelivery{
+			Template:  email.Template,
+			Subject:   email.Subject,
+			Recipient: email.To,
+			Status:    models.EmailDeliverySent,
+		}
+		if job := jobFromContext(ctx); job != nil {
+			delivery.JobID = &job.ID
+			delivery.Attempt = job.Attempts
+			delivery.FinalAttempt = job.Attempts >= job.MaxAttempts
+		}
+		if sendErr != nil {
+			delivery.Status = models.EmailDeliveryFailed
+			delivery.Error = sendErr.Error()
+		}
+
+		// Recorded even if the job's context has run out, so timeouts show up in the log
+		recordCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		if err := deliveries.RecordDelivery(recordCtx, delivery); err != nil {
+			logging.Warnf("Failed to record delivery of email %q to %s: %v", email.Template, email.To, err)
+		}
+		return sendErr
+	}
+}
+
+// RegisterDefaultHandlers registers the handlers for every built-in job type.
+// Email send attempts are recorded to deliveries when it isn't nil.
+func RegisterDefaultHandlers(w *Worker, deliveries DeliveryRecorder) {
+	if deliveries != nil {
+		w.Register(TypeSendEmail, RecordingEmailHandler(deliveries))
+	} else {
+		w.Register(TypeSendEmail, SendEmailHandler)
+	}
+}
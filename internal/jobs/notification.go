@@ -0,0 +1,130 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/OsGift/taskflow-api/internal/logging"
+)
+
+const (
+	// TypeNotificationWebhook is the job type that POSTs a notification to a user's webhook
+	TypeNotificationWebhook = "notification:webhook"
+	// TypeNotificationPush is the job type that sends a notification to a user's devices
+	TypeNotificationPush = "notification:push"
+)
+
+// WebhookPayload is a signed notification to POST to a user's webhook. The signature is
+// computed when the notification is queued, so the signing secret isn't stored in the job.
+type WebhookPayload struct {
+	URL       string          `json:"url"`
+	Body      json.RawMessage `json:"body"`
+	Signature string          `json:"signature"` // X-TaskFlow-Signature header
+}
+
+// PushPayload is a notification for the push gateway to deliver to device tokens
+type PushPayload struct {
+	Tokens []string          `json:"tokens"`
+	Title  string            `json:"title"`
+	Body   string            `json:"body,omitempty"`
+	Data   map[string]string `json:"data,omitempty"`
+}
+
+// errPrivateAddress rejects webhook URLs pointing into the server's own network
+var errPrivateAddress = errors.New("webhook address is not publicly routable")
+
+// webhookClient posts user webhooks. Users choose the URLs, so connections to loopback,
+// private and link-local addresses are refused after DNS resolution (and no proxy is used,
+// which would bypass the check).
+var webhookClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				ip := net.ParseIP(host)
+				if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+					return errPrivateAddress
+				}
+				return nil
+			},
+		}).DialContext,
+	},
+	// Redirects could lead anywhere; a webhook must answer itself
+	CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+}
+
+// DeliverWebhook handles TypeNotificationWebhook jobs
+func DeliverWebhook(ctx context.Context, payload []byte) error {
+	var webhook WebhookPayload
+	if err := json.Unmarshal(payload, &webhook); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(webhook.Body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-TaskFlow-Signature", webhook.Signature)
+	return post(webhookClient, req)
+}
+
+// PushSender delivers push notifications through a gateway (e.g. a relay to FCM and APNs)
+// that accepts a PushPayload as JSON
+type PushSender struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewPushSender creates a PushSender posting to url, authenticated with token when it isn't empty
+func NewPushSender(url, token string) *PushSender {
+	return &PushSender{url: url, token: token, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Send handles TypeNotificationPush jobs. Without a gateway the notification is dropped.
+func (p *PushSender) Send(ctx context.Context, payload []byte) error {
+	if p.url == "" {
+		logging.Warnf("Push notification dropped: no push gateway is configured")
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+	return post(p.client, req)
+}
+
+// RegisterNotificationHandlers registers the webhook and push delivery handlers
+func RegisterNotificationHandlers(w *Worker, push *PushSender) {
+	w.Register(TypeNotificationWebhook, DeliverWebhook)
+	w.Register(TypeNotificationPush, push.Send)
+}
+
+// post sends req and fails on error statuses, so the job is retried
+func post(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s responded with status %d", req.URL.Host, resp.StatusCode)
+	}
+	return nil
+}
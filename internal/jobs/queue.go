@@ -0,0 +1,166 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+// DefaultMaxAttempts is how many times a job is tried before it is dead-lettered
+const DefaultMaxAttempts = 5
+
+// Queue is a persistent, MongoDB-backed job queue
+type Queue struct {
+	db             repository.Documents
+	jobsCollection repository.Collection
+}
+
+// NewQueue creates a new Queue
+func NewQueue(db repository.Documents) *Queue {
+	return &Queue{
+		db:             db,
+		jobsCollection: db.Collection("jobs"),
+	}
+}
+
+// EnsureIndexes creates the index used by workers to find runnable jobs and the one
+// enforcing unique keys
+func (q *Queue) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return q.db.EnsureIndexes(ctx, "jobs", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "run_at", Value: 1}}},
+		{
+			Keys: bson.D{{Key: "unique_key", Value: 1}},
+			Options: options.Index().SetName("unique_key_unique").SetUnique(true).
+				SetPartialFilterExpression(bson.M{"unique_key": bson.M{"$exists": true}}),
+		},
+	})
+}
+
+// Enqueue persists a job of the given type; payload is JSON-encoded for the handler
+func (q *Queue) Enqueue(ctx context.Context, jobType string, payload interface{}) error {
+	return q.EnqueueAt(ctx, jobType, payload, time.Now())
+}
+
+// EnqueueAt persists a job that becomes runnable at runAt
+func (q *Queue) EnqueueAt(ctx context.Context, jobType string, payload interface{}, runAt time.Time) error {
+	return q.insert(ctx, jobType, "", payload, runAt)
+}
+
+// EnqueueUnique persists a job unless one was ever enqueued with the same key, so that
+// several processes (or a retried job) can schedule the same work safely. It reports
+// whether the job was enqueued.
+func (q *Queue) EnqueueUnique(ctx context.Context, key, jobType string, payload interface{}, runAt time.Time) (bool, error) {
+	err := q.insert(ctx, jobType, key, payload, runAt)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// insert persists a pending job
+func (q *Queue) insert(ctx context.Context, jobType, uniqueKey string, payload interface{}, runAt time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s job payload: %w", jobType, err)
+	}
+
+	now := time.Now()
+	_, err = q.jobsCollection.InsertOne(ctx, models.Job{
+		Type:        jobType,
+		Payload:     data,
+		UniqueKey:   uniqueKey,
+		Status:      models.JobPending,
+		MaxAttempts: DefaultMaxAttempts,
+		RunAt:       runAt,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	})
+	return err
+}
+
+// claim atomically leases the next runnable job, or returns nil if there is none.
+// Running jobs whose lease has expired (e.g., the worker crashed) are picked up again.
+func (q *Queue) claim(ctx context.Context, lease time.Duration) (*models.Job, error) {
+	now := time.Now()
+	filter := bson.M{"$or": []bson.M{
+		{"status": models.JobPending, "run_at": bson.M{"$lte": now}},
+		{"status": models.JobRunning, "locked_until": bson.M{"$lt": now}},
+	}}
+	update := bson.M{
+		"$set": bson.M{"status": models.JobRunning, "locked_until": now.Add(lease), "updated_at": now},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "run_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var job models.Job
+	err := q.jobsCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// complete marks a job as successfully finished. The payload is dropped so that
+// sensitive data (e.g., temporary passwords in emails) doesn't linger in the database.
+func (q *Queue) complete(ctx context.Context, job *models.Job) error {
+	now := time.Now()
+	_, err := q.jobsCollection.UpdateByID(ctx, job.ID, bson.M{
+		"$set": bson.M{
+			"status":       models.JobCompleted,
+			"completed_at": now,
+			"updated_at":   now,
+			"last_error":   "",
+		},
+		"$unset": bson.M{"payload": ""},
+	})
+	return err
+}
+
+// fail records a failed attempt, scheduling a retry with exponential backoff or
+// dead-lettering the job once it has used all of its attempts. It reports whether the job is dead.
+func (q *Queue) fail(ctx context.Context, job *models.Job, jobErr error) (bool, error) {
+	now := time.Now()
+	set := bson.M{"last_error": jobErr.Error(), "updated_at": now}
+
+	dead := job.Attempts >= job.MaxAttempts
+	if dead {
+		set["status"] = models.JobDead
+	} else {
+		set["status"] = models.JobPending
+		set["run_at"] = now.Add(backoff(job.Attempts))
+	}
+
+	_, err := q.jobsCollection.UpdateByID(ctx, job.ID, bson.M{"$set": set})
+	return dead, err
+}
+
+// backoff returns the delay before retry number attempt: 30s, 1m, 2m, 4m... capped at 1h
+func backoff(attempt int) time.Duration {
+	delay := 30 * time.Second
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= time.Hour {
+			return time.Hour
+		}
+	}
+	return delay
+}
@@ -0,0 +1,60 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RunPayload identifies one run of a recurring job
+type RunPayload struct {
+	ScheduleID *primitive.ObjectID `json:"schedule_id,omitempty"` // Which schedule, for job types with several
+	RunAt      time.Time           `json:"run_at"`                // Scheduled time of the run
+}
+
+// NextRunFunc returns the scheduled time of the run following run, or false when there is none
+// because the job was turned off, or its schedule changed or was deleted since run was queued
+type NextRunFunc func(ctx context.Context, run RunPayload) (time.Time, bool, error)
+
+// Recurring returns the handler of jobType, a job recurring at the times nextRun gives. Each run
+// queues the following one first, so one failing run doesn't end the schedule, then calls
+// handler. Runs nextRun has no follower for do nothing.
+func Recurring(q *Queue, jobType string, nextRun NextRunFunc, handler func(ctx context.Context, run RunPayload) error) HandlerFunc {
+	return func(ctx context.Context, payload []byte) error {
+		var run RunPayload
+		if err := json.Unmarshal(payload, &run); err != nil {
+			return err
+		}
+
+		next, ok, err := nextRun(ctx, run)
+		if err != nil || !ok {
+			return err
+		}
+		if err := ScheduleRun(ctx, q, jobType, RunPayload{ScheduleID: run.ScheduleID, RunAt: next}); err != nil {
+			return fmt.Errorf("failed to schedule the next %s run: %w", jobType, err)
+		}
+		return handler(ctx, run)
+	}
+}
+
+// ScheduleNext queues the first run of jobType from now on, unless nextRun has none
+func ScheduleNext(ctx context.Context, q *Queue, jobType string, nextRun NextRunFunc) error {
+	next, ok, err := nextRun(ctx, RunPayload{RunAt: time.Now()})
+	if err != nil || !ok {
+		return err
+	}
+	return ScheduleRun(ctx, q, jobType, RunPayload{RunAt: next})
+}
+
+// ScheduleRun queues run of jobType. Every process may call it: a run is only ever queued once.
+func ScheduleRun(ctx context.Context, q *Queue, jobType string, run RunPayload) error {
+	key := jobType
+	if run.ScheduleID != nil {
+		key += ":" + run.ScheduleID.Hex()
+	}
+	_, err := q.EnqueueUnique(ctx, key+":"+run.RunAt.Format(time.RFC3339), jobType, run, run.RunAt)
+	return err
+}
@@ -0,0 +1,27 @@
+package jobs
+
+import "time"
+
+// TypeScheduledReport is the job type that emails one run of a scheduled dashboard report
+const TypeScheduledReport = "report:scheduled"
+
+// NextDaily returns the first time strictly after t at hour:00 UTC
+func NextDaily(t time.Time, hour int) time.Time {
+	t = t.UTC()
+	next := time.Date(t.Year(), t.Month(), t.Day(), hour, 0, 0, 0, time.UTC)
+	if !next.After(t) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// NextMonthly returns the first time strictly after t on day of the month at hour:00 UTC.
+// day must be at most 28 so that every month has it.
+func NextMonthly(t time.Time, day, hour int) time.Time {
+	t = t.UTC()
+	next := time.Date(t.Year(), t.Month(), day, hour, 0, 0, 0, time.UTC)
+	if !next.After(t) {
+		next = next.AddDate(0, 1, 0)
+	}
+	return next
+}
@@ -0,0 +1,4 @@
+package jobs
+
+// TypeRetentionCleanup is the job type that deletes records older than the retention policy keeps
+const TypeRetentionCleanup = "retention:cleanup"
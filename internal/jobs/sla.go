@@ -0,0 +1,12 @@
+package jobs
+
+import "time"
+
+// TypeSLACheck is the job type that looks for tasks breaching an SLA rule
+const TypeSLACheck = "sla:check"
+
+// NextInterval returns the first time strictly after t that is a multiple of interval on the
+// clock: every 15 minutes falls at :00, :15, :30 and :45
+func NextInterval(t time.Time, interval time.Duration) time.Time {
+	return t.UTC().Truncate(interval).Add(interval)
+}
@@ -0,0 +1,5 @@
+package jobs
+
+// TypeStaleTasks is the job type that warns the owners of tasks left untouched, then flags or
+// archives the tasks once they go stale
+const TypeStaleTasks = "tasks:stale"
@@ -0,0 +1,12 @@
+package jobs
+
+import "time"
+
+// TypeDailySummary is the job type that sends the daily summary to the opted-in users whose
+// summary hour it is in their time zone
+const TypeDailySummary = "summary:daily"
+
+// NextHour returns the first full hour strictly after t, in UTC
+func NextHour(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Hour).Add(time.Hour)
+}
@@ -0,0 +1,10 @@
+package jobs
+
+// TypeExpireDirectUpload is the job type that deletes a direct upload that was signed but never
+// confirmed from the storage backend
+const TypeExpireDirectUpload = "upload:expire_direct"
+
+// ExpireDirectUploadPayload identifies the direct upload to expire
+type ExpireDirectUploadPayload struct {
+	PublicID string `json:"public_id"`
+}
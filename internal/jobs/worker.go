@@ -0,0 +1,152 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/OsGift/taskflow-api/internal/logging"
+	"github.com/OsGift/taskflow-api/internal/models"
+)
+
+// HandlerFunc processes the JSON payload of a job. Returning an error schedules a retry.
+type HandlerFunc func(ctx context.Context, payload []byte) error
+
+// DeadLetterFunc is called when a job has failed its last attempt
+type DeadLetterFunc func(ctx context.Context, job *models.Job, err error)
+
+// jobContextKey is the context key under which handlers can find the job they are running
+type jobContextKey struct{}
+
+// jobFromContext returns the job being processed, or nil outside a handler
+func jobFromContext(ctx context.Context) *models.Job {
+	job, _ := ctx.Value(jobContextKey{}).(*models.Job)
+	return job
+}
+
+// Worker polls a Queue and dispatches jobs to registered handlers
+type Worker struct {
+	queue        *Queue
+	handlers     map[string]HandlerFunc
+	concurrency  int
+	pollInterval time.Duration
+	lease        time.Duration // How long a job may run before another worker can reclaim it
+	onDead       []DeadLetterFunc
+}
+
+// NewWorker creates a Worker running up to concurrency jobs at a time
+func NewWorker(q *Queue, concurrency int, pollInterval time.Duration) *Worker {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &Worker{
+		queue:        q,
+		handlers:     map[string]HandlerFunc{},
+		concurrency:  concurrency,
+		pollInterval: pollInterval,
+		lease:        5 * time.Minute,
+	}
+}
+
+// Register sets the handler for a job type
+func (w *Worker) Register(jobType string, handler HandlerFunc) {
+	w.handlers[jobType] = handler
+}
+
+// OnDeadLetter registers fn to be called for every job that is dead-lettered (e.g. to alert operators)
+func (w *Worker) OnDeadLetter(fn DeadLetterFunc) {
+	w.onDead = append(w.onDead, fn)
+}
+
+// Run processes jobs until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	log.Printf("Job worker started with concurrency %d", w.concurrency)
+
+	var wg sync.WaitGroup
+	for i := 0; i < w.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx)
+		}()
+	}
+	wg.Wait()
+	log.Println("Job worker stopped.")
+}
+
+// loop claims and runs jobs, sleeping for the poll interval whenever the queue is empty
+func (w *Worker) loop(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		job, err := w.queue.claim(ctx, w.lease)
+		if err != nil {
+			logging.Warnf("Job worker: failed to claim job: %v", err)
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(w.pollInterval):
+			}
+			continue
+		}
+
+		w.process(ctx, job)
+	}
+}
+
+// process runs a single job and records its outcome
+func (w *Worker) process(ctx context.Context, job *models.Job) {
+	jobCtx, cancel := context.WithTimeout(context.WithValue(ctx, jobContextKey{}, job), w.lease)
+	defer cancel()
+
+	var jobErr error
+	handler, ok := w.handlers[job.Type]
+	if !ok {
+		jobErr = fmt.Errorf("no handler registered for job type %q", job.Type)
+	} else {
+		jobErr = runSafely(jobCtx, handler, job.Payload)
+	}
+
+	// Record the outcome with a fresh context so shutdown doesn't lose it
+	saveCtx, saveCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer saveCancel()
+
+	if jobErr == nil {
+		if err := w.queue.complete(saveCtx, job); err != nil {
+			logging.Warnf("Job worker: failed to mark job %s completed: %v", job.ID.Hex(), err)
+		}
+		return
+	}
+
+	dead, err := w.queue.fail(saveCtx, job, jobErr)
+	if err != nil {
+		logging.Warnf("Job worker: failed to record failure of job %s: %v", job.ID.Hex(), err)
+		return
+	}
+	if dead {
+		logging.Warnf("Job worker: job %s (%s) dead-lettered after %d attempts: %v", job.ID.Hex(), job.Type, job.Attempts, jobErr)
+		alertCtx, alertCancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer alertCancel()
+		for _, fn := range w.onDead {
+			fn(alertCtx, job, jobErr)
+		}
+	} else {
+		logging.Warnf("Job worker: job %s (%s) attempt %d failed, will retry: %v", job.ID.Hex(), job.Type, job.Attempts, jobErr)
+	}
+}
+
+// runSafely invokes a handler, converting panics into errors so one bad job can't kill the worker
+func runSafely(ctx context.Context, handler HandlerFunc, payload []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job handler panicked: %v", r)
+		}
+	}()
+	return handler(ctx, payload)
+}
@@ -0,0 +1,97 @@
+// Package logging filters the server's logs by level. Messages logged with the standard log
+// package are info messages; Debugf and Warnf log the other levels, in the same format with
+// the level in front. The level can be changed while the server runs.
+package logging
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync/atomic"
+)
+
+// Level is the least severe level of the messages that are logged
+type Level int32
+
+// Levels, from the most verbose
+const (
+	LevelDebug Level = iota // Details for debugging, such as every request served
+	LevelInfo               // What the server does; the default
+	LevelWarn               // Only failures and problems needing attention
+)
+
+// levelNames are the names of the levels, as set in LOG_LEVEL
+var levelNames = map[Level]string{LevelDebug: "debug", LevelInfo: "info", LevelWarn: "warn"}
+
+var (
+	level  atomic.Int32
+	output = log.New(os.Stderr, "", log.LstdFlags)
+)
+
+func init() {
+	level.Store(int32(LevelInfo))
+	log.SetOutput(infoWriter{})
+}
+
+// String returns the name of the level
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// ParseLevel returns the level named name: "debug", "info" or "warn"
+func ParseLevel(name string) (Level, error) {
+	for l, levelName := range levelNames {
+		if name == levelName {
+			return l, nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q (expected debug, info or warn)", name)
+}
+
+// SetLevel changes the least severe level logged
+func SetLevel(l Level) {
+	level.Store(int32(l))
+}
+
+// CurrentLevel returns the least severe level logged
+func CurrentLevel() Level {
+	return Level(level.Load())
+}
+
+// Enabled reports whether messages of level l are logged, so callers can skip the work of
+// building messages that would be dropped
+func Enabled(l Level) bool {
+	return l >= CurrentLevel()
+}
+
+// Debugf logs a debug message, formatted as with fmt.Sprintf
+func Debugf(format string, args ...interface{}) {
+	if Enabled(LevelDebug) {
+		output.Output(2, "DEBUG "+fmt.Sprintf(format, args...))
+	}
+}
+
+// Warnf logs a warning, formatted as with fmt.Sprintf
+func Warnf(format string, args ...interface{}) {
+	if Enabled(LevelWarn) {
+		output.Output(2, "WARN "+fmt.Sprintf(format, args...))
+	}
+}
+
+// Fatalf logs a message whatever the level, formatted as with fmt.Sprintf, and exits with
+// status 1. The server uses it instead of log.Fatalf, whose message the warn level would drop.
+func Fatalf(format string, args ...interface{}) {
+	output.Output(2, fmt.Sprintf(format, args...))
+	os.Exit(1)
+}
+
+// infoWriter is the output of the standard logger, whose messages are info messages
+type infoWriter struct{}
+
+// Write writes p to standard error unless info messages are filtered out
+func (infoWriter) Write(p []byte) (int, error) {
+	if !Enabled(LevelInfo) {
+		return len(p), nil
+	}
+	return os.Stderr.Write(p)
+}
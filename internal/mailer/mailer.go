@@ -0,0 +1,73 @@
+// Package mailer delivers rendered emails through SMTP or an HTTP email API
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Message is a rendered HTML email with an optional plain-text alternative
+type Message struct {
+	From    string
+	To      string
+	Subject string
+	HTML    string
+	Text    string // Plain-text version of HTML (see PlainText); sent as multipart/alternative when set
+}
+
+// Mailer delivers messages. Implementations return an error for any message the provider
+// did not accept, so the job worker can retry it.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Config selects and configures a provider: "smtp" (default), "sendgrid", "mailgun" or "ses"
+type Config struct {
+	Provider string
+
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+
+	SendGridAPIKey string
+
+	MailgunAPIKey  string
+	MailgunDomain  string
+	MailgunAPIBase string // e.g. https://api.eu.mailgun.net for EU domains
+
+	SESRegion          string
+	SESAccessKeyID     string // Empty uses the default AWS credential chain
+	SESSecretAccessKey string
+}
+
+// New creates the Mailer selected by cfg.Provider
+func New(ctx context.Context, cfg Config) (Mailer, error) {
+	switch cfg.Provider {
+	case "", "smtp":
+		return NewSMTP(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword), nil
+	case "sendgrid":
+		return NewSendGrid(cfg.SendGridAPIKey), nil
+	case "mailgun":
+		return NewMailgun(cfg.MailgunAPIBase, cfg.MailgunDomain, cfg.MailgunAPIKey), nil
+	case "ses":
+		return NewSES(ctx, cfg.SESRegion, cfg.SESAccessKeyID, cfg.SESSecretAccessKey)
+	}
+	return nil, fmt.Errorf("unknown email provider %q", cfg.Provider)
+}
+
+// httpClient is shared by the HTTP API providers
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// checkResponse turns a non-2xx API response into an error carrying the provider's message
+func checkResponse(provider string, resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+	return fmt.Errorf("%s rejected the message: %s: %s", provider, resp.Status, strings.TrimSpace(string(body)))
+}
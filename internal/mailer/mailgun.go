@@ -0,0 +1,57 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// defaultMailgunAPIBase is Mailgun's US region API
+const defaultMailgunAPIBase = "https://api.mailgun.net"
+
+// Mailgun sends messages through the Mailgun messages API
+type Mailgun struct {
+	endpoint string
+	apiKey   string
+}
+
+// NewMailgun creates a Mailgun mailer for domain. apiBase selects the region and
+// defaults to the US API.
+func NewMailgun(apiBase, domain, apiKey string) *Mailgun {
+	if apiBase == "" {
+		apiBase = defaultMailgunAPIBase
+	}
+	return &Mailgun{
+		endpoint: strings.TrimSuffix(apiBase, "/") + "/v3/" + url.PathEscape(domain) + "/messages",
+		apiKey:   apiKey,
+	}
+}
+
+// Send delivers msg
+func (m *Mailgun) Send(ctx context.Context, msg Message) error {
+	form := url.Values{
+		"from":    {msg.From},
+		"to":      {msg.To},
+		"subject": {msg.Subject},
+		"html":    {msg.HTML},
+	}
+	if msg.Text != "" {
+		form.Set("text", msg.Text)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth("api", m.apiKey)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Mailgun: %w", err)
+	}
+	defer resp.Body.Close()
+	return checkResponse("Mailgun", resp)
+}
@@ -0,0 +1,71 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// sendGridURL is the SendGrid v3 mail send endpoint
+const sendGridURL = "https://api.sendgrid.com/v3/mail/send"
+
+// SendGrid sends messages through the SendGrid v3 API
+type SendGrid struct {
+	apiKey string
+}
+
+// NewSendGrid creates a SendGrid mailer authenticated with apiKey
+func NewSendGrid(apiKey string) *SendGrid {
+	return &SendGrid{apiKey: apiKey}
+}
+
+// sendGridAddress is an email address in a SendGrid request
+type sendGridAddress struct {
+	Email string `json:"email"`
+}
+
+// Send delivers msg
+func (s *SendGrid) Send(ctx context.Context, msg Message) error {
+	type personalization struct {
+		To []sendGridAddress `json:"to"`
+	}
+	type content struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	}
+	// SendGrid requires text/plain to come before text/html
+	contents := []content{{Type: "text/html", Value: msg.HTML}}
+	if msg.Text != "" {
+		contents = append([]content{{Type: "text/plain", Value: msg.Text}}, contents...)
+	}
+	payload, err := json.Marshal(struct {
+		Personalizations []personalization `json:"personalizations"`
+		From             sendGridAddress   `json:"from"`
+		Subject          string            `json:"subject"`
+		Content          []content         `json:"content"`
+	}{
+		Personalizations: []personalization{{To: []sendGridAddress{{Email: msg.To}}}},
+		From:             sendGridAddress{Email: msg.From},
+		Subject:          msg.Subject,
+		Content:          contents,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sendGridURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach SendGrid: %w", err)
+	}
+	defer resp.Body.Close()
+	return checkResponse("SendGrid", resp)
+}
@@ -0,0 +1,56 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
+)
+
+// SES sends messages through the Amazon SES v2 API
+type SES struct {
+	client *sesv2.Client
+}
+
+// NewSES creates an SES mailer for region. Without an access key the default AWS credential
+// chain (environment, shared config, instance or task role) is used.
+func NewSES(ctx context.Context, region, accessKeyID, secretAccessKey string) (*SES, error) {
+	opts := []func(*awsconfig.LoadOptions) error{awsconfig.WithRegion(region)}
+	if accessKeyID != "" {
+		opts = append(opts, awsconfig.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(accessKeyID, secretAccessKey, "")))
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+	return &SES{client: sesv2.NewFromConfig(cfg)}, nil
+}
+
+// Send delivers msg
+func (s *SES) Send(ctx context.Context, msg Message) error {
+	body := &types.Body{
+		Html: &types.Content{Data: aws.String(msg.HTML), Charset: aws.String("UTF-8")},
+	}
+	if msg.Text != "" {
+		body.Text = &types.Content{Data: aws.String(msg.Text), Charset: aws.String("UTF-8")}
+	}
+	_, err := s.client.SendEmail(ctx, &sesv2.SendEmailInput{
+		FromEmailAddress: aws.String(msg.From),
+		Destination:      &types.Destination{ToAddresses: []string{msg.To}},
+		Content: &types.EmailContent{
+			Simple: &types.Message{
+				Subject: &types.Content{Data: aws.String(msg.Subject), Charset: aws.String("UTF-8")},
+				Body:    body,
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("SES rejected the message: %w", err)
+	}
+	return nil
+}
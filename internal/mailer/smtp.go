@@ -0,0 +1,126 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+)
+
+// SMTP sends messages through an SMTP server with PLAIN authentication
+type SMTP struct {
+	addr string
+	auth smtp.Auth
+}
+
+// NewSMTP creates an SMTP mailer for host:port
+func NewSMTP(host, port, username, password string) *SMTP {
+	return &SMTP{
+		addr: fmt.Sprintf("%s:%s", host, port),
+		auth: smtp.PlainAuth("", username, password, host),
+	}
+}
+
+// Send delivers msg. net/smtp has no context support, so ctx is only checked up front.
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	body, err := mimeMessage(msg)
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(s.addr, s.auth, msg.From, []string{msg.To}, body)
+}
+
+// Check connects and authenticates to the server as Send does, without sending anything
+func (s *SMTP) Check(ctx context.Context) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to the SMTP server at %s: %w", s.addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	host, _, _ := net.SplitHostPort(s.addr)
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("the SMTP server at %s did not greet: %w", s.addr, err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("failed to start TLS with the SMTP server at %s: %w", s.addr, err)
+		}
+	}
+	if ok, _ := client.Extension("AUTH"); ok {
+		if err := client.Auth(s.auth); err != nil {
+			return fmt.Errorf("the SMTP server at %s rejected the username and password: %w", s.addr, err)
+		}
+	}
+	return client.Quit()
+}
+
+// mimeMessage encodes msg as a MIME message: multipart/alternative with the plain-text part
+// first (clients show the last part they support), or only HTML when msg has no text.
+// Parts are quoted-printable, which keeps lines within SMTP's length limit.
+func mimeMessage(msg Message) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("To: " + msg.To + "\r\n" +
+		"From: " + msg.From + "\r\n" +
+		"Subject: " + mime.QEncoding.Encode("UTF-8", msg.Subject) + "\r\n" + // Translated subjects aren't ASCII
+		"MIME-Version: 1.0\r\n")
+
+	if msg.Text == "" {
+		buf.WriteString("Content-Type: text/html; charset=\"UTF-8\"\r\n" +
+			"Content-Transfer-Encoding: quoted-printable\r\n" +
+			"\r\n")
+		if err := writeQuotedPrintable(&buf, msg.HTML); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	parts := multipart.NewWriter(&buf)
+	buf.WriteString("Content-Type: multipart/alternative; boundary=" + parts.Boundary() + "\r\n\r\n")
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain", msg.Text},
+		{"text/html", msg.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType + `; charset="UTF-8"`},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(w, part.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeQuotedPrintable writes content to w in quoted-printable encoding
+func writeQuotedPrintable(w io.Writer, content string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(content)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
@@ -0,0 +1,97 @@
+package mailer
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	// spaceRun matches whitespace collapsed to a single space, as browsers do
+	spaceRun = regexp.MustCompile(`[ \t\r\n\f]+`)
+	// blankLines matches the runs of empty lines that are collapsed to a single one
+	blankLines = regexp.MustCompile(`\n{3,}`)
+)
+
+// PlainText converts an HTML email into its plain-text alternative: paragraphs, table cells
+// and list items become lines, links are followed by their URL and head, style and script
+// content is dropped
+func PlainText(htmlBody string) string {
+	var b strings.Builder
+	var skip int       // Depth inside elements whose content isn't shown
+	var links []string // href of each open <a>, "" when not worth printing
+	var linkText []int // Length of b when each open <a> started
+	newline := func() { b.WriteString("\n") }
+
+	z := html.NewTokenizer(strings.NewReader(htmlBody))
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return tidyText(b.String())
+		case html.TextToken:
+			if skip == 0 {
+				b.WriteString(spaceRun.ReplaceAllString(html.UnescapeString(string(z.Raw())), " "))
+			}
+		case html.StartTagToken, html.SelfClosingTagToken, html.EndTagToken:
+			name, hasAttr := z.TagName()
+			tag := atom.Lookup(name)
+			end := tt == html.EndTagToken
+			switch tag {
+			case atom.Head, atom.Style, atom.Script, atom.Title:
+				if tt == html.StartTagToken {
+					skip++
+				} else if end && skip > 0 {
+					skip--
+				}
+			case atom.Br:
+				newline()
+			case atom.P, atom.Div, atom.Table, atom.Ul, atom.Ol,
+				atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+				newline()
+				if end {
+					newline()
+				}
+			case atom.Li:
+				newline()
+				if !end {
+					b.WriteString("- ")
+				}
+			case atom.Td, atom.Th:
+				newline()
+			case atom.A:
+				if !end {
+					var href string
+					for hasAttr {
+						var key, val []byte
+						key, val, hasAttr = z.TagAttr()
+						if string(key) == "href" {
+							href = string(val)
+						}
+					}
+					links = append(links, href)
+					linkText = append(linkText, b.Len())
+				} else if len(links) > 0 {
+					href, start := links[len(links)-1], linkText[len(linkText)-1]
+					links, linkText = links[:len(links)-1], linkText[:len(linkText)-1]
+					text := strings.TrimSpace(b.String()[start:])
+					if href != "" && !strings.HasPrefix(href, "#") && text != href {
+						b.WriteString(" (" + href + ")")
+					}
+				}
+			}
+		}
+	}
+}
+
+// tidyText trims every line and collapses runs of blank lines
+func tidyText(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	text = blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(text) + "\n"
+}
@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/OsGift/taskflow-api/internal/logging"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/services"
+)
+
+// contextKeyAuditActor holds the *auditActor the audit middleware shares with JWTAuth
+const contextKeyAuditActor ContextKey = "auditActor"
+
+// auditActor is filled in by JWTAuth once the caller is authenticated. The audit middleware
+// wraps the whole route, so it can't see the context JWTAuth derives further down the chain.
+type auditActor struct {
+	userID   primitive.ObjectID
+	roleName string
+	set      bool
+}
+
+// AuditMiddleware records every mutating request in the audit log
+type AuditMiddleware struct {
+	auditService *services.AuditService
+}
+
+// NewAuditMiddleware creates a new AuditMiddleware
+func NewAuditMiddleware(as *services.AuditService) *AuditMiddleware {
+	return &AuditMiddleware{
+		auditService: as,
+	}
+}
+
+// Handler records POST, PUT, PATCH and DELETE requests after they complete.
+// Request bodies are never stored, so credentials and personal data stay out of the log.
+func (m *AuditMiddleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		actor := &auditActor{}
+		r = r.WithContext(context.WithValue(r.Context(), contextKeyAuditActor, actor))
+		rec := &recordingResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		entry := &models.AuditLog{
+			Method:     r.Method,
+			Route:      routeTemplate(r),
+			Path:       r.URL.Path,
+			TargetID:   auditTargetID(r, rec),
+			Status:     rec.status,
+			IPAddress:  clientIP(r),
+			UserAgent:  r.UserAgent(),
+			DurationMS: time.Since(start).Milliseconds(),
+			CreatedAt:  start,
+		}
+		if actor.set {
+			entry.ActorID = &actor.userID
+			entry.ActorRole = actor.roleName
+		}
+
+		// Write in the background so auditing never delays the response; the write must
+		// outlive the request, so it keeps the request's values but not its cancellation
+		ctx := context.WithoutCancel(r.Context())
+		go func() {
+			if err := m.auditService.Record(ctx, entry); err != nil {
+				logging.Warnf("Failed to write audit log for %s %s: %v", entry.Method, entry.Path, err)
+			}
+		}()
+	})
+}
+
+// setAuditActor attaches the authenticated user to the request's audit entry, if any
+func setAuditActor(r *http.Request, authContext *models.AuthContext) {
+	if actor, ok := r.Context().Value(contextKeyAuditActor).(*auditActor); ok {
+		actor.userID = authContext.UserID
+		actor.roleName = authContext.RoleName
+		actor.set = true
+	}
+}
+
+// routeTemplate returns the matched mux route template, falling back to the raw path
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return r.URL.Path
+}
+
+// auditTargetID identifies the affected resource: the {id} path variable, or the
+// "id" of a newly created resource in a successful response body
+func auditTargetID(r *http.Request, rec *recordingResponseWriter) string {
+	if id := mux.Vars(r)["id"]; id != "" {
+		return id
+	}
+	if rec.status != http.StatusCreated || !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") {
+		return ""
+	}
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(rec.body.Bytes(), &created); err != nil {
+		return ""
+	}
+	return created.ID
+}
+
+// clientIP returns the caller's address, preferring the first X-Forwarded-For hop
+func clientIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		first, _, _ := strings.Cut(forwarded, ",")
+		return strings.TrimSpace(first)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
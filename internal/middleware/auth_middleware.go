@@ -2,9 +2,13 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/OsGift/taskflow-api/internal/models"
 	"github.com/OsGift/taskflow-api/internal/services"
@@ -25,19 +29,26 @@ type AuthMiddleware struct {
 	jwtSecret   []byte
 	userService *services.UserService
 	authService *services.AuthService // Added Auth service
+	// Resolves the API keys service accounts authenticate with
+	serviceAccountService *services.ServiceAccountService
+	cookieAuth            bool // Whether browsers may authenticate with the session cookie
 }
 
 // NewAuthMiddleware creates a new AuthMiddleware
 // Changed constructor to accept AuthService
-func NewAuthMiddleware(secret []byte, us *services.UserService, as *services.AuthService) *AuthMiddleware {
+func NewAuthMiddleware(secret []byte, us *services.UserService, as *services.AuthService, sas *services.ServiceAccountService, cookieAuth bool) *AuthMiddleware {
 	return &AuthMiddleware{
-		jwtSecret:   secret,
-		userService: us,
-		authService: as, // Assign auth service
+		jwtSecret:             secret,
+		userService:           us,
+		authService:           as, // Assign auth service
+		serviceAccountService: sas,
+		cookieAuth:            cookieAuth,
 	}
 }
 
-// JWTAuth middleware verifies the JWT token and populates AuthContext in request context
+// JWTAuth middleware verifies the JWT token and populates AuthContext in request context.
+// Service accounts send an API key as the bearer token instead. With cookie authentication
+// enabled, requests without an Authorization header may send the token in the session cookie.
 // requiredPermission is the minimum permission needed to pass this middleware.
 // If it's an empty string (""), it means only authentication is required, no specific permission.
 // If the handler needs more nuanced permission checks (e.g., resource ownership vs. global access),
@@ -45,19 +56,37 @@ func NewAuthMiddleware(secret []byte, us *services.UserService, as *services.Aut
 func (m *AuthMiddleware) JWTAuth(next http.HandlerFunc, requiredPermission string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
+		tokenString := ""
+		if authHeader != "" {
+			parts := strings.Split(authHeader, " ")
+			if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+				utils.RespondWithError(w, http.StatusUnauthorized, "Invalid authorization header format")
+				return
+			}
+			tokenString = parts[1]
+		} else if cookie, err := r.Cookie(SessionCookieName); m.cookieAuth && err == nil {
+			tokenString = cookie.Value
+		} else {
 			utils.RespondWithError(w, http.StatusUnauthorized, "Missing authorization header")
 			return
 		}
 
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-			utils.RespondWithError(w, http.StatusUnauthorized, "Invalid authorization header format")
+		if authHeader != "" && strings.HasPrefix(tokenString, models.APIKeyPrefix) {
+			authContext, rateLimit, err := m.serviceAccountService.Authenticate(r.Context(), tokenString)
+			if rateLimit != nil {
+				setRateLimitHeaders(w, rateLimit, err == services.ErrAPIKeyRateLimited)
+			}
+			if err == services.ErrInvalidAPIKey {
+				RecordAuthFailure(r)
+			}
+			if err != nil {
+				utils.RespondWithAppError(w, err, "Failed to authenticate API key")
+				return
+			}
+			m.authorize(w, r, next, authContext, requiredPermission)
 			return
 		}
 
-		tokenString := parts[1]
-
 		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
@@ -66,6 +95,9 @@ func (m *AuthMiddleware) JWTAuth(next http.HandlerFunc, requiredPermission strin
 		})
 
 		if err != nil {
+			if !errors.Is(err, jwt.ErrTokenExpired) {
+				RecordAuthFailure(r) // Only a forged or corrupted token fails otherwise
+			}
 			utils.RespondWithError(w, http.StatusUnauthorized, "Invalid or expired token: "+err.Error())
 			return
 		}
@@ -95,31 +127,54 @@ func (m *AuthMiddleware) JWTAuth(next http.HandlerFunc, requiredPermission strin
 
 		userID, err := primitive.ObjectIDFromHex(userIDHex)
 		if err != nil {
-			utils.RespondWithError(w, http.StatusInternalServerError, "Invalid user ID format in token")
+			utils.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID format in token")
 			return
 		}
 		roleID, err := primitive.ObjectIDFromHex(roleIDHex)
 		if err != nil {
-			utils.RespondWithError(w, http.StatusInternalServerError, "Invalid role ID format in token")
+			utils.RespondWithError(w, http.StatusUnauthorized, "Invalid role ID format in token")
 			return
 		}
 
-		// Corrected: Use m.authService.AuthenticatedUserContext to get the AuthContext
-		authContext, err := m.authService.AuthenticatedUserContext(userID, roleID)
+		// A valid token whose user has since been deleted or disabled no longer authenticates
+		authContext, err := m.authService.AuthenticatedUserContext(r.Context(), userID, roleID)
+		if errors.Is(err, services.ErrUserNotFound) || errors.Is(err, services.ErrAccountDisabled) {
+			err = services.ErrTokenAccountInactive
+		}
 		if err != nil {
-			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve user authentication context: "+err.Error())
+			utils.RespondWithAppError(w, err, "Failed to retrieve user authentication context")
 			return
 		}
 
-		// Check if a specific permission is required for the route
-		if requiredPermission != "" && !authContext.HasPermission(requiredPermission) {
-			utils.RespondWithError(w, http.StatusForbidden, "You do not have sufficient permissions to access this resource")
-			return
-		}
+		m.authorize(w, r, next, authContext, requiredPermission)
+	}
+}
+
+// authorize checks that the authenticated caller has requiredPermission and passes the request
+// on to next with authContext attached
+func (m *AuthMiddleware) authorize(w http.ResponseWriter, r *http.Request, next http.HandlerFunc, authContext *models.AuthContext, requiredPermission string) {
+	// Check if a specific permission is required for the route
+	if requiredPermission != "" && !authContext.HasPermission(requiredPermission) {
+		utils.RespondWithError(w, http.StatusForbidden, "You do not have sufficient permissions to access this resource")
+		return
+	}
+
+	setAuditActor(r, authContext)
+
+	// Add AuthContext to the request context
+	ctx := context.WithValue(r.Context(), ContextKeyAuthContext, authContext)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
 
-		// Add AuthContext to the request context
-		ctx := context.WithValue(r.Context(), ContextKeyAuthContext, authContext)
-		next.ServeHTTP(w, r.WithContext(ctx))
+// setRateLimitHeaders tells API key callers how many requests they have left in the current
+// minute, and when to retry once they have none
+func setRateLimitHeaders(w http.ResponseWriter, rateLimit *services.RateLimit, exceeded bool) {
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(rateLimit.Limit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(rateLimit.Remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(rateLimit.Reset.Unix(), 10))
+	if exceeded {
+		retryAfter := int(math.Ceil(time.Until(rateLimit.Reset).Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
 	}
 }
 
@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CompressionMiddleware transparently compresses JSON responses with gzip or deflate
+// when the client advertises support via Accept-Encoding and the body is large enough
+// to be worth it. Small payloads are passed through untouched.
+type CompressionMiddleware struct {
+	minSize int // Minimum body size in bytes before compression kicks in
+}
+
+// NewCompressionMiddleware creates a new CompressionMiddleware
+func NewCompressionMiddleware(minSize int) *CompressionMiddleware {
+	if minSize < 0 {
+		minSize = 0
+	}
+	return &CompressionMiddleware{minSize: minSize}
+}
+
+// Handler wraps next so that its responses are compressed when negotiated
+func (m *CompressionMiddleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressResponseWriter{
+			ResponseWriter: w,
+			encoding:       encoding,
+			minSize:        m.minSize,
+		}
+		defer cw.finish()
+
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiateEncoding picks gzip or deflate from an Accept-Encoding header, honouring q=0 exclusions.
+// gzip is preferred when both are acceptable.
+func negotiateEncoding(header string) string {
+	accepted := map[string]bool{}
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		if name == "" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		accepted[name] = quality > 0
+	}
+
+	for _, candidate := range []string{"gzip", "deflate"} {
+		if enabled, ok := accepted[candidate]; ok {
+			if enabled {
+				return candidate
+			}
+			continue
+		}
+		if accepted["*"] {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// isCompressible reports whether a response with the given Content-Type should be compressed
+func isCompressible(contentType string) bool {
+	return strings.HasPrefix(strings.ToLower(contentType), "application/json")
+}
+
+// compressResponseWriter buffers the start of a response until it knows whether the body
+// crosses the size threshold, then either streams it through a compressor or writes it as-is.
+type compressResponseWriter struct {
+	http.ResponseWriter
+	encoding   string
+	minSize    int
+	status     int
+	buf        []byte
+	decided    bool
+	compressor io.WriteCloser
+}
+
+// WriteHeader records the status code; it is sent once the compression decision is made
+func (cw *compressResponseWriter) WriteHeader(status int) {
+	if cw.status == 0 {
+		cw.status = status
+	}
+}
+
+// Write buffers data until the threshold is reached, then forwards it
+func (cw *compressResponseWriter) Write(p []byte) (int, error) {
+	if cw.decided {
+		if cw.compressor != nil {
+			return cw.compressor.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) >= cw.minSize {
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush makes the writer usable for streamed responses
+func (cw *compressResponseWriter) Flush() {
+	if !cw.decided {
+		cw.decide(len(cw.buf) >= cw.minSize)
+	}
+	if f, ok := cw.compressor.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (cw *compressResponseWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// decide sets the response headers, sends the status and drains the buffer
+func (cw *compressResponseWriter) decide(large bool) error {
+	cw.decided = true
+
+	h := cw.Header()
+	if large && cw.status != http.StatusNoContent && isCompressible(h.Get("Content-Type")) && h.Get("Content-Encoding") == "" {
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		switch cw.encoding {
+		case "gzip":
+			cw.compressor = gzip.NewWriter(cw.ResponseWriter)
+		case "deflate":
+			// HTTP's deflate is the zlib format (RFC 1950), not a bare DEFLATE stream
+			zw, err := zlib.NewWriterLevel(cw.ResponseWriter, zlib.DefaultCompression)
+			if err != nil {
+				return err
+			}
+			cw.compressor = zw
+		}
+	}
+
+	if cw.status != 0 {
+		cw.ResponseWriter.WriteHeader(cw.status)
+	}
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if cw.compressor != nil {
+		_, err := cw.compressor.Write(buf)
+		return err
+	}
+	_, err := cw.ResponseWriter.Write(buf)
+	return err
+}
+
+// finish flushes anything still buffered and closes the compressor
+func (cw *compressResponseWriter) finish() {
+	if !cw.decided {
+		cw.decide(false)
+	}
+	if cw.compressor != nil {
+		cw.compressor.Close()
+	}
+}
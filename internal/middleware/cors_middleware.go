@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/rs/cors"
+)
+
+// CORSMiddleware answers cross-origin requests. Without allowed origins it allows every
+// origin without credentials; with them, only those origins, with credentials (cookies).
+// The origins can be changed while serving, when the configuration is reloaded.
+type CORSMiddleware struct {
+	cors atomic.Pointer[cors.Cors]
+}
+
+// NewCORSMiddleware creates a new CORSMiddleware allowing origins
+func NewCORSMiddleware(origins []string) *CORSMiddleware {
+	m := &CORSMiddleware{}
+	m.SetOrigins(origins)
+	return m
+}
+
+// SetOrigins changes the origins allowed to make credentialed requests; none allows every
+// origin without credentials
+func (m *CORSMiddleware) SetOrigins(origins []string) {
+	c := cors.AllowAll()
+	if len(origins) > 0 {
+		c = cors.New(cors.Options{
+			AllowedOrigins:   origins,
+			AllowedMethods:   []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete},
+			AllowedHeaders:   []string{"Authorization", "Content-Type", "Accept-Language", IdempotencyKeyHeader, CSRFTokenHeader},
+			ExposedHeaders:   []string{"Content-Disposition", "Deprecation", "Sunset", "Link", "Idempotent-Replayed", "Retry-After"},
+			AllowCredentials: true,
+		})
+	}
+	m.cors.Store(c)
+}
+
+// Handler wraps next with the CORS policy of the currently allowed origins
+func (m *CORSMiddleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.cors.Load().Handler(next).ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+const (
+	// SessionCookieName is the cookie browsers authenticate with when cookie authentication is
+	// enabled; it holds a short-lived access token
+	SessionCookieName = "taskflow_session"
+	// RefreshCookieName is the cookie holding the refresh token of a cookie session
+	RefreshCookieName = "taskflow_refresh"
+	// CSRFCookieName is the cookie holding the CSRF token, readable by frontend scripts
+	CSRFCookieName = "taskflow_csrf"
+	// CSRFTokenHeader is the request header state-changing requests echo the CSRF token in
+	CSRFTokenHeader = "X-CSRF-Token"
+)
+
+// csrfTokenBytes is the number of random bytes in a CSRF token
+const csrfTokenBytes = 32
+
+// CSRFMiddleware protects cookie-authenticated requests against cross-site request forgery
+// with the double-submit cookie pattern: the token is stored in a cookie and returned to the
+// frontend, and state-changing requests must send it back in the X-CSRF-Token header, which
+// other sites can't do since they can't read either. Requests authenticated with an Authorization header can't be forged
+// by another site and are not checked.
+type CSRFMiddleware struct {
+	enabled  bool          // Whether cookie authentication is enabled
+	sameSite http.SameSite // Same as the session cookies, so the token cookie is sent with them
+}
+
+// NewCSRFMiddleware creates a new CSRFMiddleware; it lets every request through unless
+// cookie authentication is enabled
+func NewCSRFMiddleware(enabled bool, sameSite http.SameSite) *CSRFMiddleware {
+	return &CSRFMiddleware{enabled: enabled, sameSite: sameSite}
+}
+
+// Handler rejects state-changing requests that carry the session or refresh cookie but not
+// the matching CSRF token
+func (m *CSRFMiddleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !m.enabled || !needsCSRFCheck(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cookie, err := r.Cookie(CSRFCookieName)
+		header := r.Header.Get(CSRFTokenHeader)
+		if err != nil || cookie.Value == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(header)) != 1 {
+			utils.RespondWithError(w, http.StatusForbidden, "Missing or invalid CSRF token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// IssueToken returns the caller's CSRF token, creating it and setting its cookie if the
+// browser doesn't have one yet. Frontends served from another origin can't read the cookie,
+// so the token is in the response body too.
+func (m *CSRFMiddleware) IssueToken(w http.ResponseWriter, r *http.Request) {
+	if !m.enabled {
+		utils.RespondWithError(w, http.StatusNotFound, "Cookie authentication is not enabled")
+		return
+	}
+
+	token, err := SetCSRFCookie(w, r, m.sameSite)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create CSRF token")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	utils.RespondWithJSON(w, http.StatusOK, models.CSRFTokenResponse{CSRFToken: token})
+}
+
+// SetCSRFCookie sets the CSRF cookie on the response and returns its token, keeping the
+// token the browser already has if any
+func SetCSRFCookie(w http.ResponseWriter, r *http.Request, sameSite http.SameSite) (string, error) {
+	token := ""
+	if cookie, err := r.Cookie(CSRFCookieName); err == nil && validCSRFToken(cookie.Value) {
+		token = cookie.Value
+	} else {
+		secret := make([]byte, csrfTokenBytes)
+		if _, err := rand.Read(secret); err != nil {
+			return "", err
+		}
+		token = base64.RawURLEncoding.EncodeToString(secret)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     CSRFCookieName,
+		Value:    token,
+		Path:     "/",
+		Secure:   true,
+		SameSite: sameSite,
+	})
+	return token, nil
+}
+
+// needsCSRFCheck reports whether r changes state on behalf of a cookie session
+func needsCSRFCheck(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return false
+	}
+	if r.Header.Get("Authorization") != "" {
+		return false // Authenticated by the header, which takes precedence over the cookie
+	}
+	for _, name := range []string{SessionCookieName, RefreshCookieName} {
+		if _, err := r.Cookie(name); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// validCSRFToken reports whether token looks like a token IssueToken created
+func validCSRFToken(token string) bool {
+	secret, err := base64.RawURLEncoding.DecodeString(token)
+	return err == nil && len(secret) == csrfTokenBytes
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/OsGift/taskflow-api/internal/database"
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+// DatabaseBreakerMiddleware fails requests fast while MongoDB is known to be down, rather than
+// letting each wait out its timeout
+type DatabaseBreakerMiddleware struct {
+	breaker *database.Breaker
+}
+
+// NewDatabaseBreakerMiddleware creates a new DatabaseBreakerMiddleware
+func NewDatabaseBreakerMiddleware(breaker *database.Breaker) *DatabaseBreakerMiddleware {
+	return &DatabaseBreakerMiddleware{breaker: breaker}
+}
+
+// Handler answers requests with 503 and a Retry-After header while the breaker is open
+func (m *DatabaseBreakerMiddleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ok, wait := m.breaker.Allow(); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(max(int(math.Ceil(wait.Seconds())), 1)))
+			utils.RespondWithAppError(w, database.ErrUnavailable, "Request refused")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// DeprecationPolicy describes the lifecycle of an API version being sunset
+type DeprecationPolicy struct {
+	Deprecated   bool
+	DeprecatedAt time.Time // When the version was deprecated; zero if unspecified
+	SunsetAt     time.Time // When the version will stop being served; zero if not yet announced
+	Successor    string    // Path prefix of the replacement version, e.g. "/api/v2"
+}
+
+// DeprecationHeaders returns a mux middleware that advertises a version's deprecation
+// using the Deprecation (RFC 9745), Sunset (RFC 8594) and successor-version Link headers.
+func DeprecationHeaders(policy DeprecationPolicy) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if policy.DeprecatedAt.IsZero() {
+				w.Header().Set("Deprecation", "true")
+			} else {
+				w.Header().Set("Deprecation", fmt.Sprintf("@%d", policy.DeprecatedAt.Unix()))
+			}
+			if !policy.SunsetAt.IsZero() {
+				w.Header().Set("Sunset", policy.SunsetAt.UTC().Format(http.TimeFormat))
+			}
+			if policy.Successor != "" {
+				w.Header().Add("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", policy.Successor))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
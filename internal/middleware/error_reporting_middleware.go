@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+
+	"github.com/OsGift/taskflow-api/internal/errorreport"
+	"github.com/OsGift/taskflow-api/internal/logging"
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+// maxReportedBody is how much of a server error response is kept to read its message
+const maxReportedBody = 4 << 10
+
+// reportedHeaders are the request headers sent with error reports; the others may carry
+// credentials
+var reportedHeaders = []string{"Accept", "Accept-Language", "Content-Type", "Content-Length", "User-Agent", "Referer", IdempotencyKeyHeader}
+
+// ErrorReportingMiddleware reports the requests answered with a server error, and the panics
+// of their handlers, to an error tracker along with the request, its route and its caller.
+// Panics are answered with a 500 instead of dropping the connection.
+type ErrorReportingMiddleware struct {
+	tracker *errorreport.Sentry
+}
+
+// NewErrorReportingMiddleware creates a new ErrorReportingMiddleware
+func NewErrorReportingMiddleware(tracker *errorreport.Sentry) *ErrorReportingMiddleware {
+	return &ErrorReportingMiddleware{tracker: tracker}
+}
+
+// Handler reports the server errors and panics of next
+func (m *ErrorReportingMiddleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// JWTAuth fills in the caller; the audit middleware has already set one up for
+		// mutating requests
+		actor, ok := r.Context().Value(contextKeyAuditActor).(*auditActor)
+		if !ok {
+			actor = &auditActor{}
+			r = r.WithContext(context.WithValue(r.Context(), contextKeyAuditActor, actor))
+		}
+		ew := &errorReportingWriter{ResponseWriter: w, status: http.StatusOK}
+
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				panic(p) // Deliberate abort of a streamed response
+			}
+			logging.Warnf("Panic serving %s %s: %v\n%s", r.Method, r.URL.Path, p, debug.Stack())
+			event := errorreport.NewEvent("fatal", fmt.Sprint(p))
+			event.Exception = []errorreport.Exception{{Type: "panic", Value: fmt.Sprint(p), Stacktrace: errorreport.NewStacktrace(2)}}
+			m.report(event, r, actor, http.StatusInternalServerError)
+			if !ew.wroteHeader {
+				utils.RespondWithError(ew.ResponseWriter, http.StatusInternalServerError, "Internal server error")
+			}
+		}()
+		next.ServeHTTP(ew, r)
+
+		if ew.status < http.StatusInternalServerError {
+			return
+		}
+		event := errorreport.NewEvent("error", ew.message())
+		if ew.err != nil {
+			event.Exception = []errorreport.Exception{{Type: fmt.Sprintf("%T", ew.err), Value: ew.err.Error(), Stacktrace: ew.stack}}
+		}
+		m.report(event, r, actor, ew.status)
+	})
+}
+
+// report sends event with the context of request r
+func (m *ErrorReportingMiddleware) report(event *errorreport.Event, r *http.Request, actor *auditActor, status int) {
+	event.Tags = map[string]string{
+		"method": r.Method,
+		"route":  routeTemplate(r),
+		"status": strconv.Itoa(status),
+	}
+	event.Request = &errorreport.Request{
+		URL:     requestScheme(r) + "://" + r.Host + r.URL.Path, // Query strings may carry secrets
+		Method:  r.Method,
+		Headers: map[string]string{},
+	}
+	for _, name := range reportedHeaders {
+		if value := r.Header.Get(name); value != "" {
+			event.Request.Headers[name] = value
+		}
+	}
+	event.User = &errorreport.User{IPAddress: clientIP(r)}
+	if actor.set {
+		event.User.ID = actor.userID.Hex()
+		event.Tags["role"] = actor.roleName
+	}
+	m.tracker.Report(event)
+}
+
+// requestScheme returns the scheme the client used, as far as the server can tell
+func requestScheme(r *http.Request) string {
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		return proto
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// errorReportingWriter records the status of a response, the start of its body when it is a
+// server error, and the error handed over by utils.RespondWithAppError
+type errorReportingWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	err         error
+	stack       *errorreport.Stacktrace
+}
+
+// WriteHeader records the status code before forwarding it
+func (ew *errorReportingWriter) WriteHeader(status int) {
+	if !ew.wroteHeader {
+		ew.status = status
+		ew.wroteHeader = true
+	}
+	ew.ResponseWriter.WriteHeader(status)
+}
+
+// Write keeps the start of server error bodies before forwarding p
+func (ew *errorReportingWriter) Write(p []byte) (int, error) {
+	ew.wroteHeader = true
+	if ew.status >= http.StatusInternalServerError && ew.body.Len() < maxReportedBody {
+		ew.body.Write(p[:min(len(p), maxReportedBody-ew.body.Len())])
+	}
+	return ew.ResponseWriter.Write(p)
+}
+
+// RecordError keeps the error behind a 500 response, with the stack of the handler reporting it
+func (ew *errorReportingWriter) RecordError(err error) {
+	ew.err = err
+	ew.stack = errorreport.NewStacktrace(3) // Leave out RecordError and the utils functions calling it
+}
+
+// Unwrap returns the wrapped writer, for http.ResponseController
+func (ew *errorReportingWriter) Unwrap() http.ResponseWriter {
+	return ew.ResponseWriter
+}
+
+// message returns the message of the error response, or its status text
+func (ew *errorReportingWriter) message() string {
+	var response utils.ErrorResponse
+	if json.Unmarshal(ew.body.Bytes(), &response) == nil && response.Message != "" {
+		return response.Message
+	}
+	return http.StatusText(ew.status)
+}
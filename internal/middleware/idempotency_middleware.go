@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/OsGift/taskflow-api/internal/logging"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/services"
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+// IdempotencyKeyHeader is the request header clients use to make POST requests safe to retry
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength bounds the size of client-supplied keys
+const maxIdempotencyKeyLength = 255
+
+// IdempotencyMiddleware replays stored responses for requests that reuse an Idempotency-Key
+type IdempotencyMiddleware struct {
+	idempotencyService *services.IdempotencyService
+	proxyHops          int // Reverse proxies in front of the server that append to X-Forwarded-For
+}
+
+// NewIdempotencyMiddleware creates a new IdempotencyMiddleware; with proxyHops reverse proxies
+// in front of the server, anonymous callers are told apart by the address the outermost one saw
+func NewIdempotencyMiddleware(is *services.IdempotencyService, proxyHops int) *IdempotencyMiddleware {
+	return &IdempotencyMiddleware{
+		idempotencyService: is,
+		proxyHops:          proxyHops,
+	}
+}
+
+// Wrap makes next idempotent for requests carrying an Idempotency-Key header.
+// Requests without the header are passed straight through. When used behind JWTAuth,
+// keys are scoped to the authenticated user. Otherwise they are scoped to the caller's IP
+// address and the request body, so an anonymous caller reusing someone else's key can only
+// ever be replayed the response to a request identical to their own.
+func (m *IdempotencyMiddleware) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientKey := strings.TrimSpace(r.Header.Get(IdempotencyKeyHeader))
+		if clientKey == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(clientKey) > maxIdempotencyKeyLength {
+			utils.RespondWithError(w, http.StatusBadRequest, "Idempotency-Key header is too long")
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Failed to read request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		bodyHash := sha256.Sum256(body)
+		requestHash := hex.EncodeToString(bodyHash[:])
+
+		actor := "anonymous:" + requestIP(r, m.proxyHops) + ":" + requestHash
+		if authContext, err := GetAuthContext(r); err == nil {
+			actor = authContext.UserID.Hex()
+		}
+		scopedKey := strings.Join([]string{actor, r.Method, r.URL.Path, clientKey}, "|")
+
+		record, existing, err := m.idempotencyService.Begin(r.Context(), scopedKey, requestHash)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to process Idempotency-Key")
+			return
+		}
+
+		if existing {
+			if record.RequestHash != requestHash {
+				utils.RespondWithError(w, http.StatusUnprocessableEntity, "Idempotency-Key has already been used with a different request payload")
+				return
+			}
+			if record.Status != models.IdempotencyCompleted {
+				utils.RespondWithError(w, http.StatusConflict, "A request with this Idempotency-Key is still being processed")
+				return
+			}
+			if record.ContentType != "" {
+				w.Header().Set("Content-Type", record.ContentType)
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(record.ResponseStatus)
+			w.Write(record.ResponseBody)
+			return
+		}
+
+		rec := &recordingResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		// The outcome is stored even if the client has gone away in the meantime
+		ctx := context.WithoutCancel(r.Context())
+
+		// Server errors are not cached so the client can retry with the same key
+		if rec.status >= http.StatusInternalServerError {
+			if err := m.idempotencyService.Release(ctx, scopedKey); err != nil {
+				logging.Warnf("Failed to release idempotency key: %v", err)
+			}
+			return
+		}
+		if err := m.idempotencyService.Complete(ctx, scopedKey, rec.status, w.Header().Get("Content-Type"), rec.body.Bytes()); err != nil {
+			logging.Warnf("Failed to store idempotent response: %v", err)
+		}
+	}
+}
+
+// recordingResponseWriter passes a response through while keeping a copy of its status and body
+type recordingResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+// WriteHeader records the status code before forwarding it
+func (rw *recordingResponseWriter) WriteHeader(status int) {
+	if !rw.wroteHeader {
+		rw.status = status
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the body before forwarding it
+func (rw *recordingResponseWriter) Write(p []byte) (int, error) {
+	rw.wroteHeader = true
+	rw.body.Write(p)
+	return rw.ResponseWriter.Write(p)
+}
+
+// Unwrap returns the wrapped writer, for http.ResponseController
+func (rw *recordingResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OsGift/taskflow-api/internal/logging"
+	"github.com/OsGift/taskflow-api/internal/services"
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+// contextKeyAuthFailure holds the *bool RecordAuthFailure sets for the IP block middleware
+const contextKeyAuthFailure ContextKey = "authFailure"
+
+// IPBlockMiddleware refuses requests from IP addresses banned for failing to authenticate too
+// often, and counts the failed attempts of the others
+type IPBlockMiddleware struct {
+	ipBlockService *services.IPBlockService
+	proxyHops      int // Reverse proxies in front of the server that append to X-Forwarded-For
+}
+
+// NewIPBlockMiddleware creates a new IPBlockMiddleware; with proxyHops reverse proxies in
+// front of the server, client addresses are taken from X-Forwarded-For
+func NewIPBlockMiddleware(ibs *services.IPBlockService, proxyHops int) *IPBlockMiddleware {
+	return &IPBlockMiddleware{
+		ipBlockService: ibs,
+		proxyHops:      proxyHops,
+	}
+}
+
+// Handler answers requests from banned addresses with 429 and a Retry-After header, and
+// records a failure for requests flagged by RecordAuthFailure once they complete. Requests
+// are let through when the ban can't be checked, so an outage of the database doesn't lock
+// everyone out.
+func (m *IPBlockMiddleware) Handler(next http.Handler) http.Handler {
+	if !m.ipBlockService.Enabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := requestIP(r, m.proxyHops)
+		until, err := m.ipBlockService.BannedUntil(r.Context(), ip)
+		if err != nil {
+			logging.Warnf("Failed to check whether %s is banned: %v", ip, err)
+		} else if !until.IsZero() {
+			retryAfter := int(math.Ceil(time.Until(until).Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+			utils.RespondWithAppError(w, services.ErrIPBanned, "Request refused")
+			return
+		}
+
+		failed := new(bool)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKeyAuthFailure, failed)))
+		if !*failed {
+			return
+		}
+
+		// Record in the background, like audit entries, so the response isn't delayed
+		ctx := context.WithoutCancel(r.Context())
+		go func() {
+			if err := m.ipBlockService.RecordFailure(ctx, ip); err != nil {
+				logging.Warnf("Failed to record failed authentication from %s: %v", ip, err)
+			}
+		}()
+	})
+}
+
+// RecordAuthFailure counts r as a failed authentication attempt against the caller's IP
+// address. Only what guessing produces counts: wrong passwords, invalid API keys and forged
+// tokens, but not missing credentials or expired JWTs, which honest clients send too.
+func RecordAuthFailure(r *http.Request) {
+	if failed, ok := r.Context().Value(contextKeyAuthFailure).(*bool); ok {
+		*failed = true
+	}
+}
+
+// requestIP returns the caller's address as far as it can be trusted: the connection's peer, or
+// behind proxyHops proxies the address the outermost proxy received the request from. Entries
+// further left in X-Forwarded-For are sent by the client and could be forged, e.g. to dodge a
+// ban or to get another address banned.
+func requestIP(r *http.Request, proxyHops int) string {
+	if proxyHops > 0 {
+		hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+		if len(hops) >= proxyHops {
+			if ip := strings.TrimSpace(hops[len(hops)-proxyHops]); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
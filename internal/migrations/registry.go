@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// registered is the ordered list of migrations applied at startup.
+// Append new migrations with the next version number; never edit one that has shipped.
+var registered = []Migration{
+	{Version: 1, Name: "backfill needs_password_change on users", Up: backfillNeedsPasswordChange},
+	{Version: 2, Name: "backfill updated_at on tasks", Up: backfillTaskUpdatedAt},
+}
+
+// backfillNeedsPasswordChange sets needs_password_change=false on users created before the field existed
+func backfillNeedsPasswordChange(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("users").UpdateMany(ctx,
+		bson.M{"needs_password_change": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"needs_password_change": false}},
+	)
+	return err
+}
+
+// backfillTaskUpdatedAt copies created_at into updated_at for tasks missing it
+func backfillTaskUpdatedAt(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("tasks").UpdateMany(ctx,
+		bson.M{"updated_at": bson.M{"$exists": false}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{"updated_at": "$created_at"}}}},
+	)
+	return err
+}
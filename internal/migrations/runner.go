@@ -0,0 +1,141 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/OsGift/taskflow-api/internal/logging"
+)
+
+// collectionName is where applied migrations are recorded, one document per version
+const collectionName = "migrations"
+
+// Migration is a single, ordered schema or data change
+type Migration struct {
+	Version int    // Unique, strictly increasing; never renumber a released migration
+	Name    string // Short description, stored alongside the version
+	Up      func(ctx context.Context, db *mongo.Database) error
+}
+
+// migrationStatus tracks whether a migration is being applied or has finished
+type migrationStatus string
+
+const (
+	statusRunning migrationStatus = "running"
+	statusApplied migrationStatus = "applied"
+)
+
+// migrationRecord is the document stored in the migrations collection
+type migrationRecord struct {
+	Version   int             `bson:"_id"`
+	Name      string          `bson:"name"`
+	Status    migrationStatus `bson:"status"`
+	StartedAt time.Time       `bson:"started_at"`
+	AppliedAt *time.Time      `bson:"applied_at,omitempty"`
+}
+
+// Run applies every registered migration that hasn't been applied yet, in version order.
+// Each migration is claimed by inserting its record first, so two instances starting at
+// the same time can't apply the same migration twice.
+func Run(db *mongo.Database) error {
+	return RunMigrations(db, registered)
+}
+
+// RunMigrations applies the given migrations; exposed so tools can run an explicit list
+func RunMigrations(db *mongo.Database, migrations []Migration) error {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].Version == sorted[i-1].Version {
+			return fmt.Errorf("duplicate migration version %d", sorted[i].Version)
+		}
+	}
+
+	collection := db.Collection(collectionName)
+	applied, err := appliedVersions(collection)
+	if err != nil {
+		return fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	for _, migration := range sorted {
+		if status, ok := applied[migration.Version]; ok {
+			if status == statusRunning {
+				return fmt.Errorf("migration %d (%s) is marked as running; it is either in progress elsewhere or was interrupted and needs manual attention", migration.Version, migration.Name)
+			}
+			continue
+		}
+		if err := apply(db, collection, migration); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply claims, runs and records a single migration
+func apply(db *mongo.Database, collection *mongo.Collection, migration Migration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	record := migrationRecord{
+		Version:   migration.Version,
+		Name:      migration.Name,
+		Status:    statusRunning,
+		StartedAt: time.Now(),
+	}
+	if _, err := collection.InsertOne(ctx, record); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("migration %d (%s) was claimed by another instance", migration.Version, migration.Name)
+		}
+		return fmt.Errorf("failed to claim migration %d: %w", migration.Version, err)
+	}
+
+	log.Printf("Applying migration %d: %s", migration.Version, migration.Name)
+	if err := migration.Up(ctx, db); err != nil {
+		// Release the claim so the migration is retried on the next start
+		if _, delErr := collection.DeleteOne(context.Background(), bson.M{"_id": migration.Version}); delErr != nil {
+			logging.Warnf("Failed to release claim on migration %d: %v", migration.Version, delErr)
+		}
+		return fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+	}
+
+	now := time.Now()
+	_, err := collection.UpdateByID(ctx, migration.Version, bson.M{"$set": bson.M{
+		"status":     statusApplied,
+		"applied_at": now,
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+	}
+	log.Printf("Applied migration %d in %s", migration.Version, now.Sub(record.StartedAt).Round(time.Millisecond))
+	return nil
+}
+
+// appliedVersions returns the status of every recorded migration keyed by version
+func appliedVersions(collection *mongo.Collection) (map[int]migrationStatus, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"status": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []migrationRecord
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	versions := make(map[int]migrationStatus, len(records))
+	for _, record := range records {
+		versions[record.Version] = record.Status
+	}
+	return versions, nil
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AnnouncementSeverity is how prominently frontends show an announcement
+type AnnouncementSeverity string
+
+const (
+	AnnouncementInfo     AnnouncementSeverity = "info"
+	AnnouncementWarning  AnnouncementSeverity = "warning"
+	AnnouncementCritical AnnouncementSeverity = "critical"
+)
+
+// Announcement is a banner message administrators publish to every user, such as planned
+// maintenance. It is shown from StartsAt until EndsAt, or until deleted when EndsAt is unset.
+type Announcement struct {
+	ID        primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	Message   string               `bson:"message" json:"message"`
+	Severity  AnnouncementSeverity `bson:"severity" json:"severity"`
+	StartsAt  time.Time            `bson:"starts_at" json:"starts_at"`
+	EndsAt    *time.Time           `bson:"ends_at,omitempty" json:"ends_at,omitempty"`
+	CreatedBy primitive.ObjectID   `bson:"created_by" json:"created_by"`
+	CreatedAt time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time            `bson:"updated_at" json:"updated_at"`
+}
+
+// CreateAnnouncementRequest is for publishing an announcement. StartsAt defaults to now and
+// Severity to "info".
+type CreateAnnouncementRequest struct {
+	Message  string     `json:"message" validate:"required,max=1000"`
+	Severity string     `json:"severity,omitempty" validate:"omitempty,oneof=info warning critical"`
+	StartsAt *time.Time `json:"starts_at,omitempty"`
+	EndsAt   *time.Time `json:"ends_at,omitempty"`
+}
+
+// UpdateAnnouncementRequest is for changing an announcement
+type UpdateAnnouncementRequest struct {
+	Message  *string    `json:"message,omitempty" validate:"omitempty,min=1,max=1000"`
+	Severity *string    `json:"severity,omitempty" validate:"omitempty,oneof=info warning critical"`
+	StartsAt *time.Time `json:"starts_at,omitempty"`
+	EndsAt   *time.Time `json:"ends_at,omitempty"`
+	NoEnd    bool       `json:"no_end,omitempty"` // Removes the end time, keeping the announcement until it is deleted
+}
+
+// AnnouncementListResponse holds announcements and pagination metadata
+type AnnouncementListResponse struct {
+	Announcements []Announcement `json:"announcements"`
+	Pagination
+}
+
+// ActiveAnnouncement is an announcement as shown to everyone, without who published it
+type ActiveAnnouncement struct {
+	ID       primitive.ObjectID   `bson:"_id" json:"id"`
+	Message  string               `bson:"message" json:"message"`
+	Severity AnnouncementSeverity `bson:"severity" json:"severity"`
+	StartsAt time.Time            `bson:"starts_at" json:"starts_at"`
+	EndsAt   *time.Time           `bson:"ends_at,omitempty" json:"ends_at,omitempty"`
+}
+
+// ActiveAnnouncementsResponse holds the announcements to show now, most severe first
+type ActiveAnnouncementsResponse struct {
+	Announcements []ActiveAnnouncement `json:"announcements"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditLog records a single mutating API request: who did what to which resource, and the outcome
+type AuditLog struct {
+	ID         primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Method     string              `bson:"method" json:"method"`
+	Route      string              `bson:"route" json:"route"` // Route template, e.g. /api/v1/tasks/{id}
+	Path       string              `bson:"path" json:"path"`   // Concrete request path
+	ActorID    *primitive.ObjectID `bson:"actor_id,omitempty" json:"actor_id,omitempty"`
+	ActorRole  string              `bson:"actor_role,omitempty" json:"actor_role,omitempty"`
+	TargetID   string              `bson:"target_id,omitempty" json:"target_id,omitempty"` // ID of the affected resource, when known
+	Status     int                 `bson:"status" json:"status"`
+	IPAddress  string              `bson:"ip_address" json:"ip_address"`
+	UserAgent  string              `bson:"user_agent,omitempty" json:"user_agent,omitempty"`
+	DurationMS int64               `bson:"duration_ms" json:"duration_ms"`
+	CreatedAt  time.Time           `bson:"created_at" json:"created_at"`
+}
+
+// AuditLogListResponse holds audit entries and pagination metadata
+type AuditLogListResponse struct {
+	Logs []AuditLog `json:"logs"`
+	Pagination
+}
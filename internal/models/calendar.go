@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CalendarConnection links a user to the Google Calendar their tasks with due dates are
+// mirrored into. The OAuth tokens never leave the server.
+type CalendarConnection struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	UserID       primitive.ObjectID `bson:"user_id" json:"user_id"`
+	CalendarID   string             `bson:"calendar_id" json:"calendar_id"` // "primary" unless chosen otherwise
+	AccessToken  string             `bson:"access_token" json:"-"`
+	RefreshToken string             `bson:"refresh_token" json:"-"`
+	TokenExpiry  time.Time          `bson:"token_expiry" json:"-"`
+	// SyncToken lets the next pull fetch only the events changed since the last one
+	SyncToken    string     `bson:"sync_token,omitempty" json:"-"`
+	NextPullAt   time.Time  `bson:"next_pull_at" json:"-"` // Run time of the pull job currently queued
+	LastSyncedAt *time.Time `bson:"last_synced_at,omitempty" json:"last_synced_at,omitempty"`
+	// LastError is set when syncing stopped, e.g. because access was revoked in the Google account
+	LastError string    `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// CalendarEvent records the calendar event mirroring a task, as of the last sync in either
+// direction, so unchanged tasks aren't written again
+type CalendarEvent struct {
+	TaskID   primitive.ObjectID `bson:"_id"`
+	UserID   primitive.ObjectID `bson:"user_id"`
+	EventID  string             `bson:"event_id"`
+	DueDate  time.Time          `bson:"due_date"`
+	Title    string             `bson:"title"`
+	Done     bool               `bson:"done"`
+	SyncedAt time.Time          `bson:"synced_at"`
+}
+
+// CalendarConnectResponse is returned when a user starts connecting their Google Calendar
+type CalendarConnectResponse struct {
+	AuthURL string `json:"auth_url"` // Google consent page to send the user to
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Comment is a note left on a task by someone who can see it
+type Comment struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TaskID    primitive.ObjectID `bson:"task_id" json:"task_id"`
+	AuthorID  primitive.ObjectID `bson:"author_id" json:"author_id"`
+	Body      string             `bson:"body" json:"body"`
+	Version   int                `bson:"version" json:"version"`                         // 1 until the comment is edited
+	EditedAt  *time.Time         `bson:"edited_at,omitempty" json:"edited_at,omitempty"` // Last edit; unset when never edited
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// CommentVersion is an earlier body of an edited comment
+type CommentVersion struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	CommentID  primitive.ObjectID `bson:"comment_id" json:"comment_id"`
+	Version    int                `bson:"version" json:"version"`
+	Body       string             `bson:"body" json:"body"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`   // When this version was written
+	ReplacedAt time.Time          `bson:"replaced_at" json:"replaced_at"` // When it was edited away
+}
+
+// CommentRequest is for writing or editing a comment
+type CommentRequest struct {
+	Body string `json:"body" validate:"required,max=10000"`
+}
+
+// CommentListResponse holds comments and pagination metadata
+type CommentListResponse struct {
+	Comments []Comment `json:"comments"`
+	Pagination
+}
+
+// CommentHistoryResponse holds a comment and its earlier versions, oldest first
+type CommentHistoryResponse struct {
+	Comment  Comment          `json:"comment"`
+	Versions []CommentVersion `json:"versions"`
+}
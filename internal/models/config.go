@@ -0,0 +1,13 @@
+package models
+
+// ConfigReloadResponse reports what reloading the configuration changed. Settings are named
+// by their environment variable, e.g. CORS_ALLOWED_ORIGINS.
+type ConfigReloadResponse struct {
+	Changed         []string `json:"changed"`          // Reloadable settings that changed and now apply
+	RestartRequired []string `json:"restart_required"` // Settings that changed but only apply after a restart
+}
+
+// LogLevel is the least severe level of the messages a server logs
+type LogLevel struct {
+	Level string `json:"level" validate:"required,oneof=debug info warn"`
+}
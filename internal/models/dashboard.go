@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 // DashboardPeriod defines possible date filters
 type DashboardPeriod string
@@ -18,6 +22,12 @@ type TaskStatusCount struct {
 	Count  int64      `json:"count"`
 }
 
+// UserTaskCount represents the count of one user's tasks
+type UserTaskCount struct {
+	UserID primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Count  int64              `bson:"count" json:"count"`
+}
+
 // DashboardMetricsResponse holds various metrics for the dashboard
 type DashboardMetricsResponse struct {
 	TotalUsers     int64             `json:"total_users"`
@@ -25,10 +35,123 @@ type DashboardMetricsResponse struct {
 	NewUsers       int64             `json:"new_users_count"`      // Users created in the period
 	NewTasks       int64             `json:"new_tasks_count"`      // Tasks created in the period
 	TasksByStatus  []TaskStatusCount `json:"tasks_by_status"`
-	AdminsCount    int64             `json:"admins_count"`
-	ManagersCount  int64             `json:"managers_count"`
-	RegularUsersCount int64          `json:"regular_users_count"`
+	OverdueCount   int64             `json:"overdue_count"`        // Open tasks past their due date, regardless of period
+	OpenTasksByAge []TaskAgeCount    `json:"open_tasks_by_age"`    // Open tasks by time in their current status, regardless of period
+	UsersByRole    map[string]int64  `json:"users_by_role"`        // Users per role name, including roles nobody has
 	StartDate      *time.Time        `json:"start_date,omitempty"` // Applied filter start date
 	EndDate        *time.Time        `json:"end_date,omitempty"`   // Applied filter end date
 	Period         DashboardPeriod   `json:"period"`               // Period requested
-}
\ No newline at end of file
+}
+
+// Boundaries of the TaskAgeCount buckets
+const (
+	TaskAgeRecent = 3 * 24 * time.Hour
+	TaskAgeStale  = 7 * 24 * time.Hour
+)
+
+// TaskAgeCount counts the open tasks in one status by how long they have been in it
+type TaskAgeCount struct {
+	Status TaskStatus `json:"status"`
+	Recent int64      `json:"0_3d"`    // Less than TaskAgeRecent
+	Aging  int64      `json:"3_7d"`    // From TaskAgeRecent to TaskAgeStale
+	Stale  int64      `json:"over_7d"` // TaskAgeStale or more
+}
+
+// DashboardCounts holds the raw counts behind DashboardMetricsResponse, as computed by the store
+type DashboardCounts struct {
+	TotalUsers     int64
+	UsersByRole    map[string]int64 // Keyed by role name; every role is present
+	NewUsers       int64            // Users created in the requested range
+	TotalTasks     int64
+	NewTasks       int64             // Tasks created in the requested range
+	TasksByStatus  []TaskStatusCount // Tasks created in the requested range, or all tasks without one
+	OverdueTasks   int64             // Open tasks past their due date
+	OpenTasksByAge []TaskAgeCount    // Open tasks by time in their current status
+}
+
+// TaskActivity is a recent change to one of the user's tasks
+type TaskActivity struct {
+	TaskID string     `json:"task_id"`
+	Title  string     `json:"title"`
+	Status TaskStatus `json:"status"`
+	Action string     `json:"action"` // "created", "completed" or "updated"
+	At     time.Time  `json:"at"`
+}
+
+// MyDashboardResponse holds the statistics of the current user's own tasks
+type MyDashboardResponse struct {
+	TotalTasks       int64             `json:"total_tasks"`
+	TasksByStatus    []TaskStatusCount `json:"tasks_by_status"`
+	OverdueCount     int64             `json:"overdue_count"`     // Open tasks past their due date
+	DueTodayCount    int64             `json:"due_today_count"`   // Open tasks due today, overdue or not
+	DueSoonCount     int64             `json:"due_soon_count"`    // Open tasks due from now to the end of the 7th day after today
+	CompletionStreak int               `json:"completion_streak"` // Consecutive days, up to today, on which a task was completed
+	RecentActivity   []TaskActivity    `json:"recent_activity"`   // Most recently changed tasks first
+	TimeZone         string            `json:"time_zone"`         // Time zone days are counted in
+}
+
+// LeaderboardEntry is one user's position on the productivity leaderboard
+type LeaderboardEntry struct {
+	Rank           int64              `bson:"rank" json:"rank"` // Users with the same count share a rank (1, 2, 2, 4)
+	UserID         primitive.ObjectID `bson:"user_id" json:"user_id"`
+	FirstName      string             `bson:"first_name" json:"first_name"`
+	LastName       string             `bson:"last_name" json:"last_name"`
+	CompletedCount int64              `bson:"completed_count" json:"completed_count"` // Tasks completed in the period
+}
+
+// LeaderboardResponse ranks users by the number of tasks they completed in a period
+type LeaderboardResponse struct {
+	Entries    []LeaderboardEntry `json:"entries"`
+	Pagination // TotalCount counts the users who completed at least one task
+	StartDate  time.Time          `json:"start_date"`
+	EndDate    time.Time          `json:"end_date"`
+	Period     DashboardPeriod    `json:"period"`
+}
+
+// ActivityDay counts the task activity of one calendar day
+type ActivityDay struct {
+	Date      string `json:"date"` // YYYY-MM-DD
+	Created   int64  `json:"created"`
+	Completed int64  `json:"completed"`
+	Count     int64  `json:"count"` // Created plus completed
+}
+
+// ActivityHeatmapResponse holds a year of daily task activity, for a heatmap
+type ActivityHeatmapResponse struct {
+	UserID    *primitive.ObjectID `json:"user_id,omitempty"` // Absent for the whole workspace
+	StartDate string              `json:"start_date"`
+	EndDate   string              `json:"end_date"`
+	TimeZone  string              `json:"time_zone"` // Time zone days are counted in
+	Total     int64               `json:"total"`
+	Days      []ActivityDay       `json:"days"` // Every day from StartDate to EndDate, oldest first
+}
+
+// MemberWorkload is one project member's share of the project's tasks
+type MemberWorkload struct {
+	UserID         primitive.ObjectID `json:"user_id"`
+	Role           ProjectRole        `json:"role"`
+	OpenTasks      int64              `json:"open_tasks"`      // To do or in progress
+	OverdueCount   int64              `json:"overdue_count"`   // Open tasks past their due date
+	CompletedCount int64              `json:"completed_count"` // Tasks completed in the period
+}
+
+// ProjectThroughput counts the tasks of a project created and completed in a period
+type ProjectThroughput struct {
+	Created   int64         `json:"created_count"`
+	Completed int64         `json:"completed_count"`
+	Days      []ActivityDay `json:"days"` // Days of the period with activity, oldest first
+}
+
+// ProjectMetricsResponse holds the statistics of one project's tasks
+type ProjectMetricsResponse struct {
+	ProjectID      primitive.ObjectID `json:"project_id"`
+	TotalTasks     int64              `json:"total_tasks"`
+	TasksByStatus  []TaskStatusCount  `json:"tasks_by_status"` // Every task of the project, regardless of period
+	OverdueCount   int64              `json:"overdue_count"`   // Open tasks past their due date, regardless of period
+	MemberWorkload []MemberWorkload   `json:"member_workload"` // The owner and every member, most open tasks first
+	Throughput     ProjectThroughput  `json:"throughput"`      // Activity in the period
+	StartDate      time.Time          `json:"start_date"`
+	EndDate        time.Time          `json:"end_date"`
+	Period         DashboardPeriod    `json:"period"`
+	TimeZone       string             `json:"time_zone"` // Time zone days are counted in
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EmailDeliveryStatus is the outcome of an email send attempt
+type EmailDeliveryStatus string
+
+const (
+	EmailDeliverySent   EmailDeliveryStatus = "sent"   // Accepted by the email provider
+	EmailDeliveryFailed EmailDeliveryStatus = "failed" // Rendering or delivery failed
+)
+
+// EmailDelivery records one attempt to send an email. Failed attempts are retried by the
+// job worker unless FinalAttempt is set.
+type EmailDelivery struct {
+	ID           primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	JobID        *primitive.ObjectID `bson:"job_id,omitempty" json:"job_id,omitempty"`
+	Template     string              `bson:"template" json:"template"`
+	Subject      string              `bson:"subject" json:"subject"`
+	Recipient    string              `bson:"recipient" json:"recipient"`
+	Status       EmailDeliveryStatus `bson:"status" json:"status"`
+	Error        string              `bson:"error,omitempty" json:"error,omitempty"`
+	Attempt      int                 `bson:"attempt" json:"attempt"`
+	FinalAttempt bool                `bson:"final_attempt" json:"final_attempt"`
+	CreatedAt    time.Time           `bson:"created_at" json:"created_at"`
+}
+
+// EmailDeliveryListResponse holds delivery attempts and pagination metadata
+type EmailDeliveryListResponse struct {
+	Deliveries []EmailDelivery `json:"deliveries"`
+	Pagination
+}
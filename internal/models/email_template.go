@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EmailTemplate is a transactional email template. Customised templates are stored in the
+// database and replace the built-in template of the same name; Subject, when set, replaces
+// the subject chosen by the code sending the email. Both are English: users whose locale is
+// listed in Translations receive the translated template instead.
+type EmailTemplate struct {
+	ID           primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty"`
+	Name         string              `bson:"name" json:"name"` // e.g. "welcome", "forgot_password"
+	Subject      string              `bson:"subject,omitempty" json:"subject,omitempty"`
+	Body         string              `bson:"body" json:"body"`      // html/template source
+	Variables    []string            `bson:"-" json:"variables"`    // Fields the template may use, e.g. "FirstName" for {{.FirstName}}
+	Customized   bool                `bson:"-" json:"customized"`   // False when the built-in template is in use
+	Translations []string            `bson:"-" json:"translations"` // Locales the template is translated into, e.g. "fr"
+	UpdatedBy    *primitive.ObjectID `bson:"updated_by,omitempty" json:"updated_by,omitempty"`
+	CreatedAt    time.Time           `bson:"created_at" json:"created_at,omitempty"`
+	UpdatedAt    time.Time           `bson:"updated_at" json:"updated_at,omitempty"`
+}
+
+// UpdateEmailTemplateRequest customises an email template
+type UpdateEmailTemplateRequest struct {
+	Subject string `json:"subject,omitempty" validate:"max=200"`
+	Body    string `json:"body" validate:"required,max=100000"`
+}
+
+// PreviewEmailTemplateRequest renders a template without sending it. Subject and Body preview
+// unsaved changes; when Body is empty the template currently in use for Locale is rendered.
+// Data overrides the sample values of individual variables.
+type PreviewEmailTemplateRequest struct {
+	Subject string                 `json:"subject,omitempty" validate:"max=200"`
+	Body    string                 `json:"body,omitempty" validate:"max=100000"`
+	Locale  string                 `json:"locale,omitempty" validate:"omitempty,bcp47_language_tag"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+}
+
+// EmailTemplatePreview is a rendered email template
+type EmailTemplatePreview struct {
+	Subject string `json:"subject"`
+	HTML    string `json:"html"`
+	Text    string `json:"text"` // Plain-text alternative sent alongside the HTML
+}
+
+// SendTestEmailRequest sends an email template, rendered with sample data, to an address
+// of the caller's choosing. Locale and Data work as in PreviewEmailTemplateRequest.
+type SendTestEmailRequest struct {
+	To     string                 `json:"to" validate:"required,email"`
+	Locale string                 `json:"locale,omitempty" validate:"omitempty,bcp47_language_tag"`
+	Data   map[string]interface{} `json:"data,omitempty"`
+}
+
+// TestEmailResult describes a test email accepted by the email provider
+type TestEmailResult struct {
+	Template string `json:"template"`
+	To       string `json:"to"`
+	Subject  string `json:"subject"`
+}
+
+// EmailTemplateListResponse lists every email template
+type EmailTemplateListResponse struct {
+	Templates []EmailTemplate `json:"templates"`
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Export record types, in the order they appear in an export
+const (
+	ExportRecordHeader  = "export"
+	ExportRecordRole    = "role"
+	ExportRecordUser    = "user"
+	ExportRecordTask    = "task"
+	ExportRecordComment = "comment"
+	ExportRecordEnd     = "end"
+)
+
+// ExportFormatVersion is bumped whenever the layout of an export changes incompatibly
+const ExportFormatVersion = 1
+
+// ExportRecord is one line of a newline-delimited JSON export: a header, then every role, user,
+// task and comment, then an end record. An export without the end record was cut short.
+type ExportRecord struct {
+	Type string      `json:"type"` // One of the ExportRecord* constants
+	Data interface{} `json:"data"`
+}
+
+// ExportHeader is the data of the first record of an export
+type ExportHeader struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+}
+
+// ExportSummary is the data of the last record of an export, counting the records written
+type ExportSummary struct {
+	Roles    int64 `json:"roles"`
+	Users    int64 `json:"users"`
+	Tasks    int64 `json:"tasks"`
+	Comments int64 `json:"comments"`
+}
+
+// TaskBackupFormatVersion is bumped whenever the layout of a task backup changes incompatibly
+const TaskBackupFormatVersion = 1
+
+// TaskBackup is what GET /tasks/export writes: one user's tasks with their comments and
+// attachments. It is streamed, so a backup cut short isn't valid JSON.
+type TaskBackup struct {
+	Version    int                `json:"version"`
+	ExportedAt time.Time          `json:"exported_at"`
+	UserID     primitive.ObjectID `json:"user_id"`
+	Tasks      []TaskBackupEntry  `json:"tasks"` // Oldest first
+	TaskCount  int64              `json:"task_count"`
+}
+
+// TaskBackupEntry is a task with its comments, oldest first, and its attachments
+type TaskBackupEntry struct {
+	Task        Task      `json:"task"`
+	Comments    []Comment `json:"comments"`
+	Attachments []Upload  `json:"attachments"`
+}
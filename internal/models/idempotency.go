@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// IdempotencyStatus tracks whether the original request is still running
+type IdempotencyStatus string
+
+const (
+	IdempotencyInProgress IdempotencyStatus = "in_progress"
+	IdempotencyCompleted  IdempotencyStatus = "completed"
+)
+
+// IdempotencyRecord stores the outcome of a request made with an Idempotency-Key header
+// so that retries with the same key replay the original response instead of re-executing it.
+type IdempotencyRecord struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	Key            string             `bson:"key"`          // Scoped key: actor + method + path + client key
+	RequestHash    string             `bson:"request_hash"` // SHA-256 of the request body
+	Status         IdempotencyStatus  `bson:"status"`
+	ResponseStatus int                `bson:"response_status,omitempty"`
+	ContentType    string             `bson:"content_type,omitempty"`
+	ResponseBody   []byte             `bson:"response_body,omitempty"`
+	CreatedAt      time.Time          `bson:"created_at"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// ImportedTask is a task converted from another task manager's export
+type ImportedTask struct {
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Status      TaskStatus `json:"status"`
+	DueDate     *time.Time `json:"due_date,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Source      string     `json:"source"` // Where the task was in the export, e.g. "Roadmap › Doing"
+}
+
+// ImportSkipped is an item of an export that isn't imported
+type ImportSkipped struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// ImportPreview describes what an import creates; on a dry run nothing is created
+type ImportPreview struct {
+	Source       string             `json:"source"`     // "trello" or "todoist"
+	Containers   []string           `json:"containers"` // Boards or projects the tasks come from
+	DryRun       bool               `json:"dry_run"`
+	Imported     int                `json:"imported"` // Tasks created; 0 on a dry run
+	StatusCounts map[TaskStatus]int `json:"status_counts"`
+	Tasks        []ImportedTask     `json:"tasks"`
+	Skipped      []ImportSkipped    `json:"skipped"`
+}
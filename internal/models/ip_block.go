@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// IPBlock tracks the failed authentication attempts made from one IP address: wrong
+// passwords, invalid API keys and forged tokens. Too many failures within the window ban the
+// address for a while, and every ban an address earns makes its next one longer.
+type IPBlock struct {
+	IP            string     `bson:"_id" json:"ip"`
+	Failures      int        `bson:"failures" json:"failures"`         // Failures in the current window
+	WindowStart   time.Time  `bson:"window_start" json:"window_start"` // When the first of those failures happened
+	LastFailureAt time.Time  `bson:"last_failure_at" json:"last_failure_at"`
+	BannedUntil   *time.Time `bson:"banned_until,omitempty" json:"banned_until,omitempty"` // Set once the address was banned
+	Banned        bool       `bson:"-" json:"banned"`                                      // Whether the ban is still in force
+	Bans          int        `bson:"bans" json:"bans"`                                     // Bans earned so far
+	ExpiresAt     time.Time  `bson:"expires_at" json:"-"`                                  // When the record is forgotten
+}
+
+// IPBlockListResponse holds tracked IP addresses and pagination metadata
+type IPBlockListResponse struct {
+	Blocks []IPBlock `json:"blocks"`
+	Pagination
+}
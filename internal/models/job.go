@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// JobStatus represents where a background job is in its lifecycle
+type JobStatus string
+
+const (
+	JobPending   JobStatus = "pending"
+	JobRunning   JobStatus = "running"
+	JobCompleted JobStatus = "completed"
+	JobDead      JobStatus = "dead" // Exhausted its retries; kept for inspection (dead-letter)
+)
+
+// Job is a unit of background work persisted in the jobs collection
+type Job struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Type        string             `bson:"type" json:"type"`                                 // e.g., "email:send"
+	Payload     []byte             `bson:"payload" json:"payload"`                           // JSON-encoded, interpreted by the job's handler
+	UniqueKey   string             `bson:"unique_key,omitempty" json:"unique_key,omitempty"` // At most one job is ever enqueued per key
+	Status      JobStatus          `bson:"status" json:"status"`
+	Attempts    int                `bson:"attempts" json:"attempts"`
+	MaxAttempts int                `bson:"max_attempts" json:"max_attempts"`
+	RunAt       time.Time          `bson:"run_at" json:"run_at"`             // Not picked up before this time
+	LockedUntil time.Time          `bson:"locked_until" json:"locked_until"` // Lease held by the worker running the job
+	LastError   string             `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+	CompletedAt *time.Time         `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+}
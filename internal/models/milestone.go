@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Milestone is a dated goal in a project that the project's tasks can be attached to
+type Milestone struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ProjectID   primitive.ObjectID `bson:"project_id" json:"project_id"`
+	Title       string             `bson:"title" json:"title"`
+	Description string             `bson:"description" json:"description"`
+	DueDate     time.Time          `bson:"due_date" json:"due_date"`
+	CreatedBy   primitive.ObjectID `bson:"created_by" json:"created_by"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+	// Progress is computed from the attached tasks when the milestone is read; it isn't stored
+	Progress *MilestoneProgress `bson:"-" json:"progress,omitempty"`
+}
+
+// MilestoneProgress counts the tasks attached to a milestone and how many of them are done
+type MilestoneProgress struct {
+	TotalTasks     int64   `json:"total_tasks"`
+	CompletedTasks int64   `json:"completed_tasks"`
+	Percent        float64 `json:"percent"` // Share of the tasks done, 0 to 100; 0 without tasks
+}
+
+// CreateMilestoneRequest is for creating a new milestone in a project
+type CreateMilestoneRequest struct {
+	Title       string    `json:"title" validate:"required,max=200"`
+	Description string    `json:"description" validate:"max=2000"`
+	DueDate     time.Time `json:"due_date" validate:"required"`
+}
+
+// UpdateMilestoneRequest is for updating an existing milestone
+type UpdateMilestoneRequest struct {
+	Title       *string    `json:"title,omitempty" validate:"omitempty,min=1,max=200"`
+	Description *string    `json:"description,omitempty" validate:"omitempty,max=2000"`
+	DueDate     *time.Time `json:"due_date,omitempty"`
+}
+
+// MilestoneListResponse holds milestones and pagination metadata
+type MilestoneListResponse struct {
+	Milestones []Milestone `json:"milestones"`
+	Pagination
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NotificationEvent names something users are notified about. Names are used as keys of
+// stored preferences, so they must not contain dots.
+type NotificationEvent string
+
+const (
+	EventAccountCreated      NotificationEvent = "account_created"       // Welcome email with the verification link
+	EventAdminAccountCreated NotificationEvent = "admin_account_created" // Temporary password of a new admin
+	EventPasswordReset       NotificationEvent = "password_reset"        // Password reset link
+	EventUploadQuarantined   NotificationEvent = "upload_quarantined"    // An upload was flagged by the virus scanner (admins)
+	EventWeeklyDigest        NotificationEvent = "weekly_digest"         // Weekly summary of the user's tasks
+	EventDailySummary        NotificationEvent = "daily_summary"         // Morning summary of the user's tasks for the day
+	EventProjectMemberAdded  NotificationEvent = "project_member_added"  // The user was added to a project
+	EventTasksGoingStale     NotificationEvent = "tasks_going_stale"     // The user's untouched tasks will soon be flagged or archived
+	EventSLABreached         NotificationEvent = "sla_breached"          // A task stayed in a status longer than an SLA rule allows (managers)
+)
+
+// NotificationChannel is a way of delivering notifications
+type NotificationChannel string
+
+const (
+	ChannelEmail   NotificationChannel = "email"
+	ChannelInApp   NotificationChannel = "in_app"  // Listed by GET /notifications
+	ChannelPush    NotificationChannel = "push"    // Sent to the user's devices through the push gateway
+	ChannelWebhook NotificationChannel = "webhook" // POSTed to the user's webhook URL
+)
+
+// Notification is an in-app notification
+type Notification struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Event     NotificationEvent  `bson:"event" json:"event"`
+	Title     string             `bson:"title" json:"title"`
+	Body      string             `bson:"body,omitempty" json:"body,omitempty"`
+	Link      string             `bson:"link,omitempty" json:"link,omitempty"`
+	Key       string             `bson:"key,omitempty" json:"-"` // Deduplicates notifications that may be sent twice
+	Read      bool               `bson:"read" json:"read"`
+	ReadAt    *time.Time         `bson:"read_at,omitempty" json:"read_at,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// NotificationListResponse holds in-app notifications and pagination metadata
+type NotificationListResponse struct {
+	Notifications []Notification `json:"notifications"`
+	Pagination
+	UnreadCount int64 `json:"unread_count"`
+}
+
+// MarkAllReadResponse reports how many notifications were marked as read
+type MarkAllReadResponse struct {
+	Updated int64 `json:"updated"`
+}
+
+// NotificationPreferences are the channels a user chose for each event, and where to
+// deliver webhook and push notifications
+type NotificationPreferences struct {
+	UserID        primitive.ObjectID                          `bson:"_id" json:"-"`
+	Channels      map[NotificationEvent][]NotificationChannel `bson:"channels" json:"channels"` // Events not listed use their default channels
+	WebhookURL    string                                      `bson:"webhook_url,omitempty" json:"webhook_url,omitempty"`
+	WebhookSecret string                                      `bson:"webhook_secret,omitempty" json:"webhook_secret,omitempty"` // Signs webhook requests
+	PushTokens    []string                                    `bson:"push_tokens" json:"push_tokens"`
+	UpdatedAt     time.Time                                   `bson:"updated_at" json:"updated_at"`
+}
+
+// NotificationEventInfo describes an event and the channels it can be delivered on
+type NotificationEventInfo struct {
+	Event       NotificationEvent     `json:"event"`
+	Description string                `json:"description"`
+	Channels    []NotificationChannel `json:"channels"`          // Channels the event can be delivered on
+	Defaults    []NotificationChannel `json:"default_channels"`  // Used until the user chooses
+	Required    []NotificationChannel `json:"required_channels"` // Always used, e.g. for password reset emails
+}
+
+// NotificationPreferencesResponse holds the channels in effect for every event
+type NotificationPreferencesResponse struct {
+	NotificationPreferences
+	Events []NotificationEventInfo `json:"events"`
+}
+
+// UpdateNotificationPreferencesRequest changes the fields that are set. Channels replaces the
+// choice for the events it lists; an empty list turns an event's optional channels off.
+// Setting a webhook URL generates a new signing secret; an empty one removes the webhook.
+type UpdateNotificationPreferencesRequest struct {
+	Channels   map[NotificationEvent][]NotificationChannel `json:"channels"`
+	WebhookURL *string                                     `json:"webhook_url" validate:"omitempty,max=2048"`
+	PushTokens *[]string                                   `json:"push_tokens" validate:"omitempty,max=10,dive,required,max=4096"`
+}
@@ -0,0 +1,33 @@
+package models
+
+// Pagination is the paging metadata embedded in list responses
+type Pagination struct {
+	TotalCount int64      `json:"total_count"`
+	Page       int64      `json:"page"`
+	Limit      int64      `json:"limit"`
+	TotalPages int64      `json:"total_pages"`
+	Links      *PageLinks `json:"links,omitempty"` // Added by the HTTP API, which also sends them in a Link header
+}
+
+// PageLinks are the URLs of the pages around one page of a list response. Prev and Next are
+// empty on the first and last pages.
+type PageLinks struct {
+	First string `json:"first"`
+	Prev  string `json:"prev,omitempty"`
+	Next  string `json:"next,omitempty"`
+	Last  string `json:"last"`
+}
+
+// NewPagination returns the metadata of page page of limit records out of totalCount
+func NewPagination(totalCount, page, limit int64) Pagination {
+	var totalPages int64
+	if limit > 0 {
+		totalPages = (totalCount + limit - 1) / limit
+	}
+	return Pagination{TotalCount: totalCount, Page: page, Limit: limit, TotalPages: totalPages}
+}
+
+// PageInfo returns the paging metadata of a list response embedding Pagination
+func (p *Pagination) PageInfo() *Pagination {
+	return p
+}
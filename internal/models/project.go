@@ -0,0 +1,107 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ProjectRole is a member's role in a project. Each role can do everything the roles
+// before it can.
+type ProjectRole string
+
+const (
+	ProjectRoleViewer  ProjectRole = "viewer"  // Sees the project and its tasks
+	ProjectRoleEditor  ProjectRole = "editor"  // Also files tasks under the project and updates them
+	ProjectRoleManager ProjectRole = "manager" // Also changes the project and its members
+)
+
+// projectRoleRanks orders the project roles
+var projectRoleRanks = map[ProjectRole]int{ProjectRoleViewer: 1, ProjectRoleEditor: 2, ProjectRoleManager: 3}
+
+// Includes reports whether r grants everything role does
+func (r ProjectRole) Includes(role ProjectRole) bool {
+	return projectRoleRanks[role] > 0 && projectRoleRanks[r] >= projectRoleRanks[role]
+}
+
+// ProjectMember is a user the owner or a manager added to a project
+type ProjectMember struct {
+	UserID  primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Role    ProjectRole        `bson:"role" json:"role"`
+	AddedBy primitive.ObjectID `bson:"added_by" json:"added_by"`
+	AddedAt time.Time          `bson:"added_at" json:"added_at"`
+}
+
+// Project groups related tasks
+type Project struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name        string             `bson:"name" json:"name"`
+	Description string             `bson:"description" json:"description"`
+	OwnerID     primitive.ObjectID `bson:"owner_id" json:"owner_id"` // User who created the project; a manager without being listed in Members
+	Members     []ProjectMember    `bson:"members" json:"members"`
+	Archived    bool               `bson:"archived" json:"archived"` // Archived projects accept no new tasks
+	ArchivedAt  *time.Time         `bson:"archived_at,omitempty" json:"archived_at,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// RoleOf returns the role userID has in the project, or "" when they aren't a member
+func (p *Project) RoleOf(userID primitive.ObjectID) ProjectRole {
+	if p.OwnerID == userID {
+		return ProjectRoleManager
+	}
+	for _, member := range p.Members {
+		if member.UserID == userID {
+			return member.Role
+		}
+	}
+	return ""
+}
+
+// CreateProjectRequest is for creating a new project
+type CreateProjectRequest struct {
+	Name        string `json:"name" validate:"required,max=100"`
+	Description string `json:"description" validate:"max=2000"`
+}
+
+// UpdateProjectRequest is for updating an existing project
+type UpdateProjectRequest struct {
+	Name        *string `json:"name,omitempty" validate:"omitempty,min=1,max=100"`
+	Description *string `json:"description,omitempty" validate:"omitempty,max=2000"`
+}
+
+// AddProjectMemberRequest adds an existing user, given by ID or email, to a project
+type AddProjectMemberRequest struct {
+	UserID string      `json:"user_id" validate:"required_without=Email"`
+	Email  string      `json:"email" validate:"omitempty,email"`
+	Role   ProjectRole `json:"role" validate:"required,oneof=viewer editor manager"`
+}
+
+// UpdateProjectMemberRequest changes a member's role
+type UpdateProjectMemberRequest struct {
+	Role ProjectRole `json:"role" validate:"required,oneof=viewer editor manager"`
+}
+
+// ProjectListResponse holds projects and pagination metadata
+type ProjectListResponse struct {
+	Projects []Project `json:"projects"`
+	Pagination
+}
+
+// BurndownDay is the number of tasks open at the end of one calendar day
+type BurndownDay struct {
+	Date      string `json:"date"` // YYYY-MM-DD
+	Remaining int64  `json:"remaining"`
+	Created   int64  `json:"created"`   // Tasks created that day
+	Completed int64  `json:"completed"` // Tasks completed that day
+}
+
+// BurndownResponse holds the open tasks of a project, or of one of its sprints, per day
+type BurndownResponse struct {
+	ProjectID primitive.ObjectID  `json:"project_id"`
+	SprintID  *primitive.ObjectID `json:"sprint_id,omitempty"`
+	StartDate string              `json:"start_date"` // YYYY-MM-DD
+	EndDate   string              `json:"end_date"`   // YYYY-MM-DD
+	TimeZone  string              `json:"time_zone"`
+	Days      []BurndownDay       `json:"days"` // Every day from StartDate to EndDate up to today, oldest first
+}
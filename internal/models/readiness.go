@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// ReadinessReport is the outcome of the dependency checks, returned by GET /readyz. Failures
+// are only detailed in the server's log.
+type ReadinessReport struct {
+	Ready     bool               `json:"ready"`
+	CheckedAt time.Time          `json:"checked_at"`
+	Checks    []DependencyStatus `json:"checks"`
+}
+
+// DependencyStatus is the outcome of checking one dependency
+type DependencyStatus struct {
+	Name       string `json:"name"`
+	OK         bool   `json:"ok"`
+	Error      string `json:"-"` // May name hosts and accounts, so it is only logged
+	DurationMS int64  `json:"duration_ms"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReportFrequency is how often a scheduled report is sent
+type ReportFrequency string
+
+const (
+	ReportDaily   ReportFrequency = "daily"
+	ReportWeekly  ReportFrequency = "weekly"
+	ReportMonthly ReportFrequency = "monthly"
+)
+
+// ReportSchedule emails the dashboard metrics of the day, week or month just ended to a list
+// of recipients. Runs happen at Hour:00 UTC; weekly reports go out on Weekday and monthly ones
+// on DayOfMonth.
+type ReportSchedule struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Frequency  ReportFrequency    `bson:"frequency" json:"frequency"`
+	Hour       int                `bson:"hour" json:"hour"`
+	Weekday    string             `bson:"weekday,omitempty" json:"weekday,omitempty"`           // Weekly reports only, e.g. "monday"
+	DayOfMonth int                `bson:"day_of_month,omitempty" json:"day_of_month,omitempty"` // Monthly reports only
+	Recipients []string           `bson:"recipients" json:"recipients"`
+	Enabled    bool               `bson:"enabled" json:"enabled"`
+	NextRunAt  *time.Time         `bson:"next_run_at,omitempty" json:"next_run_at,omitempty"` // Unset while disabled
+	LastRunAt  *time.Time         `bson:"last_run_at,omitempty" json:"last_run_at,omitempty"`
+	CreatedBy  primitive.ObjectID `bson:"created_by" json:"created_by"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// ReportScheduleRequest creates or replaces a report schedule. Hour defaults to 8, Weekday
+// to "monday" and DayOfMonth to 1; Enabled defaults to true.
+type ReportScheduleRequest struct {
+	Frequency  string   `json:"frequency" validate:"required,oneof=daily weekly monthly"`
+	Hour       *int     `json:"hour,omitempty" validate:"omitempty,min=0,max=23"`
+	Weekday    string   `json:"weekday,omitempty" validate:"omitempty,oneof=sunday monday tuesday wednesday thursday friday saturday"`
+	DayOfMonth int      `json:"day_of_month,omitempty" validate:"omitempty,min=1,max=28"`
+	Recipients []string `json:"recipients" validate:"required,min=1,max=50,dive,required,email"`
+	Enabled    *bool    `json:"enabled,omitempty"`
+}
+
+// ReportScheduleListResponse holds every report schedule
+type ReportScheduleListResponse struct {
+	Schedules []ReportSchedule `json:"schedules"`
+}
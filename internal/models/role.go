@@ -22,14 +22,34 @@ var DefaultRoles = []Role{
 			{Action: "task:create"}, {Action: "task:read_all"}, {Action: "task:update_all"}, {Action: "task:delete_all"},
 			{Action: "user:read_all"}, {Action: "user:update_role"}, {Action: "user:update_profile"}, {Action: "user:verify_email"},
 			{Action: "user:create_admin"}, // Permission for an Admin to add another Admin
-			{Action: "dashboard:read_metrics"}, // Access to dashboard metrics
+			{Action: "user:delete"},       // Delete users (optionally reassigning their tasks)
+			{Action: "user:merge"},        // Merge duplicate accounts
+			{Action: "dashboard:read_metrics"},     // Access to dashboard metrics
+			{Action: "dashboard:read_own"},         // Statistics about their own tasks
+			{Action: "dashboard:read_leaderboard"}, // Rank users by completed tasks
+			{Action: "audit:read"},                 // Read the audit log of mutating requests
+			{Action: "upload:delete_all"},          // Delete any user's uploads
+			{Action: "email_template:manage"},      // Customise transactional email templates
+			{Action: "email_delivery:read"},        // Search the email delivery log
+			{Action: "report:manage"},              // Schedule dashboard report emails
+			{Action: "sla:manage"},                 // Set how long tasks may stay in a status before managers are told
+			{Action: "announcement:manage"},        // Publish banner announcements to every user
+			{Action: "data:export"},                // Download a full export of the data
+			{Action: "service_account:manage"},     // Create service accounts and issue their API keys
+			{Action: "ip_block:manage"},            // Inspect and lift bans of addresses failing to authenticate
+			{Action: "config:reload"},              // Reload the settings that apply without a restart
+			{Action: "log_level:manage"},           // Change the log level of a running server
+			{Action: "project:create"}, {Action: "project:read_own"}, {Action: "project:update_own"}, {Action: "project:delete_own"},
+			{Action: "project:read_all"}, {Action: "project:update_all"}, {Action: "project:delete_all"}, // Any user's projects
 		},
 	},
 	{
 		Name: "Manager",
 		Permissions: []Permission{
 			{Action: "task:create"}, {Action: "task:read_all"}, {Action: "task:update_all"}, {Action: "task:delete_all"},
-			{Action: "user:update_profile"},
+			{Action: "user:update_profile"}, {Action: "dashboard:read_own"}, {Action: "dashboard:read_leaderboard"},
+			{Action: "project:create"}, {Action: "project:read_own"}, {Action: "project:update_own"}, {Action: "project:delete_own"},
+			{Action: "project:read_all"}, {Action: "project:update_all"}, {Action: "project:delete_all"},
 		},
 	},
 	{
@@ -37,6 +57,8 @@ var DefaultRoles = []Role{
 		Permissions: []Permission{
 			{Action: "task:create"}, {Action: "task:read_own"}, {Action: "task:update_own"}, {Action: "task:delete_own"},
 			{Action: "user:update_profile"}, // Users can update their own profile
+			{Action: "dashboard:read_own"},         // Users can see statistics about their own tasks
+			{Action: "project:create"}, {Action: "project:read_own"}, {Action: "project:update_own"}, {Action: "project:delete_own"},
 		},
 	},
 }
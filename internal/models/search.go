@@ -0,0 +1,25 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// SearchResponse groups the results of GET /search by type. A group is omitted when it wasn't
+// requested or the caller isn't allowed to search that type; each group is paginated on its own.
+type SearchResponse struct {
+	Query    string               `json:"query"`
+	Tasks    *TaskListResponse    `json:"tasks,omitempty"`
+	Comments *CommentListResponse `json:"comments,omitempty"` // On the tasks the caller can see
+	Users    *UserListResponse    `json:"users,omitempty"`    // Only for callers with 'user:read_all'
+}
+
+// TaskSuggestion is a task whose title starts with what the user is typing
+type TaskSuggestion struct {
+	ID     primitive.ObjectID `bson:"_id" json:"id"`
+	Title  string             `bson:"title" json:"title"`
+	Status TaskStatus         `bson:"status" json:"status"`
+}
+
+// TaskSuggestResponse holds the suggestions for a typed prefix, in title order
+type TaskSuggestResponse struct {
+	Query       string           `json:"query"`
+	Suggestions []TaskSuggestion `json:"suggestions"`
+}
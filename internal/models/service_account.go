@@ -0,0 +1,87 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// APIKeyPrefix starts every service account key, so the authentication middleware can tell
+// keys from user tokens and leaked keys are easy to search for
+const APIKeyPrefix = "tfk_"
+
+// APIKey is a credential of a service account. Only a hash of the key is stored; the key
+// itself is shown once, when it's created.
+type APIKey struct {
+	ID                 primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ServiceAccountID   primitive.ObjectID `bson:"service_account_id" json:"service_account_id"`
+	Name               string             `bson:"name" json:"name"`
+	Prefix             string             `bson:"prefix" json:"prefix"` // The first characters of the key, to tell keys apart
+	Hash               string             `bson:"hash" json:"-"`        // Hex SHA-256 of the key
+	ExpiresAt          *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	LastUsedAt         *time.Time         `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`                   // Updated at most once a minute
+	RateLimitPerMinute int                `bson:"rate_limit_per_minute,omitempty" json:"rate_limit_per_minute,omitempty"` // 0 applies the server's default limit
+	CreatedBy          primitive.ObjectID `bson:"created_by" json:"created_by"`
+	CreatedAt          time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// CreateServiceAccountRequest creates a service account with the given role
+type CreateServiceAccountRequest struct {
+	Name     string `json:"name" validate:"required,min=2,max=50"`
+	RoleName string `json:"role_name" validate:"required"`
+}
+
+// CreateAPIKeyRequest creates a key for a service account; keys never expire unless
+// ExpiresInDays is set
+type CreateAPIKeyRequest struct {
+	Name               string `json:"name" validate:"required,max=100"`
+	ExpiresInDays      int    `json:"expires_in_days,omitempty" validate:"omitempty,min=1,max=3650"`
+	RateLimitPerMinute int    `json:"rate_limit_per_minute,omitempty" validate:"omitempty,min=1,max=100000"`
+}
+
+// UpdateAPIKeyRequest renames a key or changes its rate limit; a limit of 0 restores the
+// server's default
+type UpdateAPIKeyRequest struct {
+	Name               *string `json:"name,omitempty" validate:"omitempty,min=1,max=100"`
+	RateLimitPerMinute *int    `json:"rate_limit_per_minute,omitempty" validate:"omitempty,min=0,max=100000"`
+}
+
+// CreateAPIKeyResponse returns a new key; Key can't be retrieved again
+type CreateAPIKeyResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// APIKeyListResponse holds the keys of a service account
+type APIKeyListResponse struct {
+	Keys []APIKey `json:"keys"`
+}
+
+// APIKeyUsage counts the requests made with a key during one hour. Minutes holds the count of
+// each minute ("00" to "59"), which the rate limit is enforced on.
+type APIKeyUsage struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty"`
+	KeyID    primitive.ObjectID `bson:"key_id"`
+	Hour     time.Time          `bson:"hour"`
+	Requests int64              `bson:"requests"` // Including rejected requests
+	Rejected int64              `bson:"rejected"` // Refused for exceeding the rate limit
+	Minutes  map[string]int64   `bson:"minutes"`
+}
+
+// APIKeyUsageDay is the number of requests made with a key on one UTC day
+type APIKeyUsageDay struct {
+	Date     string `json:"date"` // YYYY-MM-DD
+	Requests int64  `json:"requests"`
+	Rejected int64  `json:"rejected"`
+}
+
+// APIKeyUsageResponse reports the requests made with a key over the past days, today included
+type APIKeyUsageResponse struct {
+	KeyID              primitive.ObjectID `json:"key_id"`
+	Name               string             `json:"name"`
+	RateLimitPerMinute int                `json:"rate_limit_per_minute"` // The limit in force; 0 means unlimited
+	LastUsedAt         *time.Time         `json:"last_used_at,omitempty"`
+	Requests           int64              `json:"requests"`
+	Rejected           int64              `json:"rejected"`
+	Days               []APIKeyUsageDay   `json:"days"` // Oldest first, including days without requests
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RefreshToken is a stored refresh token of a cookie session. Only a hash of the token is
+// stored. Tokens are single use: a refresh replaces the token with a new one of the same
+// family, and presenting a replaced token again revokes the whole family, since only a copy
+// stolen before the refresh can still be sent.
+type RefreshToken struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	Hash      string             `bson:"hash"`      // Hex SHA-256 of the token
+	FamilyID  primitive.ObjectID `bson:"family_id"` // Shared by the tokens descending from one login
+	UserID    primitive.ObjectID `bson:"user_id"`
+	ExpiresAt time.Time          `bson:"expires_at"`
+	UsedAt    *time.Time         `bson:"used_at,omitempty"` // When it was exchanged for the next token
+	CreatedAt time.Time          `bson:"created_at"`
+}
+
+// Session holds the tokens of a cookie session, which the handlers set as cookies
+type Session struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+// RefreshSessionResponse is returned when a cookie session is refreshed
+type RefreshSessionResponse struct {
+	Message          string    `json:"message"`
+	SessionExpiresAt time.Time `json:"session_expires_at"` // When the new access cookie expires
+}
+
+// AuthTokenPurpose says what an AuthToken may be used for
+type AuthTokenPurpose string
+
+const (
+	AuthTokenPasswordReset     AuthTokenPurpose = "password_reset"
+	AuthTokenEmailVerification AuthTokenPurpose = "email_verification"
+)
+
+// AuthToken is a stored single-use token emailed to a user, such as a password reset link.
+// Only a hash of the token is stored.
+type AuthToken struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	Hash      string             `bson:"hash"` // Hex SHA-256 of the token
+	Purpose   AuthTokenPurpose   `bson:"purpose"`
+	UserID    primitive.ObjectID `bson:"user_id"`
+	ExpiresAt time.Time          `bson:"expires_at"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
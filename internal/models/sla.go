@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SLARule says how long tasks may stay in a status: a task still in Status MaxHours after
+// entering it breaches the rule. Rules may be narrowed down to one priority and one project.
+type SLARule struct {
+	ID        primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Name      string              `bson:"name" json:"name"`
+	Status    TaskStatus          `bson:"status" json:"status"`                             // "todo" or "in_progress"
+	MaxHours  int                 `bson:"max_hours" json:"max_hours"`                       // Hours tasks may stay in Status
+	Priority  TaskPriority        `bson:"priority,omitempty" json:"priority,omitempty"`     // Only tasks of this priority; any when empty
+	ProjectID *primitive.ObjectID `bson:"project_id,omitempty" json:"project_id,omitempty"` // Only tasks of this project; any when unset
+	Enabled   bool                `bson:"enabled" json:"enabled"`
+	CreatedBy primitive.ObjectID  `bson:"created_by" json:"created_by"`
+	CreatedAt time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// SLARuleRequest creates or replaces an SLA rule; Enabled defaults to true
+type SLARuleRequest struct {
+	Name      string `json:"name" validate:"required,max=100"`
+	Status    string `json:"status" validate:"required,oneof=todo in_progress"`
+	MaxHours  int    `json:"max_hours" validate:"required,min=1,max=8760"`
+	Priority  string `json:"priority,omitempty" validate:"omitempty,oneof=low medium high urgent"`
+	ProjectID string `json:"project_id,omitempty"`
+	Enabled   *bool  `json:"enabled,omitempty"`
+}
+
+// SLARuleListResponse holds every SLA rule
+type SLARuleListResponse struct {
+	Rules []SLARule `json:"rules"`
+}
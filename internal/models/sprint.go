@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Sprint is a time box in a project that some of the project's tasks are planned into
+type Sprint struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ProjectID primitive.ObjectID `bson:"project_id" json:"project_id"`
+	Name      string             `bson:"name" json:"name"`
+	Goal      string             `bson:"goal" json:"goal"`
+	StartDate time.Time          `bson:"start_date" json:"start_date"`
+	EndDate   time.Time          `bson:"end_date" json:"end_date"`
+	Closed    bool               `bson:"closed" json:"closed"` // Closed sprints accept no new tasks
+	ClosedAt  *time.Time         `bson:"closed_at,omitempty" json:"closed_at,omitempty"`
+	// RolledOverTo is the sprint the unfinished tasks were moved to on closing; none means
+	// they went back to the project's backlog
+	RolledOverTo    *primitive.ObjectID `bson:"rolled_over_to,omitempty" json:"rolled_over_to,omitempty"`
+	RolledOverCount int64               `bson:"rolled_over_count" json:"rolled_over_count"` // Unfinished tasks moved out on closing
+	CreatedBy       primitive.ObjectID  `bson:"created_by" json:"created_by"`
+	CreatedAt       time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// CreateSprintRequest is for creating a new sprint in a project
+type CreateSprintRequest struct {
+	Name      string    `json:"name" validate:"required,max=100"`
+	Goal      string    `json:"goal" validate:"max=500"`
+	StartDate time.Time `json:"start_date" validate:"required"`
+	EndDate   time.Time `json:"end_date" validate:"required,gtfield=StartDate"`
+}
+
+// UpdateSprintRequest is for updating an existing sprint
+type UpdateSprintRequest struct {
+	Name      *string    `json:"name,omitempty" validate:"omitempty,min=1,max=100"`
+	Goal      *string    `json:"goal,omitempty" validate:"omitempty,max=500"`
+	StartDate *time.Time `json:"start_date,omitempty"`
+	EndDate   *time.Time `json:"end_date,omitempty"`
+}
+
+// SprintTaskRequest adds a task of the sprint's project to a sprint
+type SprintTaskRequest struct {
+	TaskID string `json:"task_id" validate:"required"`
+}
+
+// CloseSprintRequest is for closing a sprint
+type CloseSprintRequest struct {
+	// NextSprintID is the open sprint of the project unfinished tasks move to. By default they
+	// move to the next open sprint, or back to the backlog when there is none.
+	NextSprintID string `json:"next_sprint_id,omitempty"`
+}
+
+// SprintListResponse holds sprints and pagination metadata
+type SprintListResponse struct {
+	Sprints []Sprint `json:"sprints"`
+	Pagination
+}
+
+// SprintSummaryResponse holds the statistics of a sprint's tasks
+type SprintSummaryResponse struct {
+	SprintID          primitive.ObjectID `json:"sprint_id"`
+	Closed            bool               `json:"closed"`
+	TotalTasks        int64              `json:"total_tasks"` // Tasks in the sprint, including those rolled over when it closed
+	CompletedTasks    int64              `json:"completed_tasks"`
+	RemainingTasks    int64              `json:"remaining_tasks"` // Unfinished tasks still in the sprint
+	RolledOverCount   int64              `json:"rolled_over_count"`
+	OverdueCount      int64              `json:"overdue_count"` // Unfinished tasks past their due date
+	TasksByStatus     []TaskStatusCount  `json:"tasks_by_status"`
+	CompletionPercent float64            `json:"completion_percent"` // Share of TotalTasks done, 0 to 100
+	DaysTotal         int                `json:"days_total"`
+	DaysElapsed       int                `json:"days_elapsed"`
+	DaysRemaining     int                `json:"days_remaining"` // 0 once the sprint has ended or closed
+}
@@ -15,35 +15,110 @@ const (
 	StatusDone       TaskStatus = "done"
 )
 
+// TaskPriority represents how urgent a task is; tasks may have none
+type TaskPriority string
+
+const (
+	PriorityLow    TaskPriority = "low"
+	PriorityMedium TaskPriority = "medium"
+	PriorityHigh   TaskPriority = "high"
+	PriorityUrgent TaskPriority = "urgent"
+)
+
 // Task represents a single task item
 type Task struct {
-	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	Title       string             `bson:"title" json:"title" validate:"required,min=5"`
-	Description string             `bson:"description" json:"description"`
-	Status      TaskStatus         `bson:"status" json:"status" validate:"required,oneof=todo in_progress done"`
-	UserID      primitive.ObjectID `bson:"user_id" json:"user_id"` // Owner of the task
-	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty"`
+	Title       string              `bson:"title" json:"title" validate:"required,min=5"`
+	Description string              `bson:"description" json:"description"`
+	Status      TaskStatus          `bson:"status" json:"status" validate:"required,oneof=todo in_progress done"`
+	UserID      primitive.ObjectID  `bson:"user_id" json:"user_id"`                               // Owner of the task
+	AssignedAt  *time.Time          `bson:"assigned_at,omitempty" json:"assigned_at,omitempty"`   // When the task was handed over to its owner by another user, if it was
+	ProjectID   *primitive.ObjectID `bson:"project_id,omitempty" json:"project_id,omitempty"`     // Project the task is filed under, if any
+	MilestoneID *primitive.ObjectID `bson:"milestone_id,omitempty" json:"milestone_id,omitempty"` // Milestone of the project the task counts towards, if any
+	SprintID    *primitive.ObjectID `bson:"sprint_id,omitempty" json:"sprint_id,omitempty"`       // Sprint of the project the task is planned into, if any
+	Archived    bool                `bson:"archived,omitempty" json:"archived,omitempty"`         // Set while its project is archived, or it is archived as stale, hiding it from listings
+	DueDate     *time.Time          `bson:"due_date,omitempty" json:"due_date,omitempty"`
+	DueTimeZone string              `bson:"due_time_zone,omitempty" json:"due_time_zone,omitempty"` // IANA time zone the due date was set in
+	Priority    TaskPriority        `bson:"priority,omitempty" json:"priority,omitempty"`
+	Tags        []string            `bson:"tags,omitempty" json:"tags,omitempty"`                 // Lowercase, without duplicates
+	Color       string              `bson:"color,omitempty" json:"color,omitempty"`               // Lowercase hex color ("#ff8800") or palette name ("teal"), for boards
+	CompletedAt *time.Time          `bson:"completed_at,omitempty" json:"completed_at,omitempty"` // Set while the task is done
+	// StatusChangedAt is when the task entered its current status; tasks saved before it was
+	// recorded don't have one
+	StatusChangedAt *time.Time `bson:"status_changed_at,omitempty" json:"status_changed_at,omitempty"`
+	// StaleWarnedAt is when the owner was told the untouched task is about to go stale, and
+	// StaleAt when the stale task job flagged or archived it. Updating the task clears both.
+	StaleWarnedAt *time.Time `bson:"stale_warned_at,omitempty" json:"stale_warned_at,omitempty"`
+	StaleAt       *time.Time `bson:"stale_at,omitempty" json:"stale_at,omitempty"`
+	// SLABreachedAt is when the SLA check found the task had stayed in its status longer than
+	// the rule SLARuleID allows. Both are cleared when the status changes.
+	SLABreachedAt *time.Time          `bson:"sla_breached_at,omitempty" json:"sla_breached_at,omitempty"`
+	SLARuleID     *primitive.ObjectID `bson:"sla_rule_id,omitempty" json:"sla_rule_id,omitempty"`
+	MergedInto    *primitive.ObjectID `bson:"merged_into,omitempty" json:"merged_into,omitempty"` // Task this duplicate was merged into and closed for
+	CreatedAt     time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time           `bson:"updated_at" json:"updated_at"`
+	// Pinned and PinnedAt are set for the caller: whether they pinned the task, and when. Pins
+	// are per user and stored as TaskPins.
+	Pinned   bool       `bson:"-" json:"pinned,omitempty"`
+	PinnedAt *time.Time `bson:"-" json:"pinned_at,omitempty"`
+	// HasUnreadUpdates is set in listings: whether the task was updated, or commented on by
+	// someone else, since the caller last viewed it (or since it was created, if they never did)
+	HasUnreadUpdates *bool `bson:"-" json:"has_unread_updates,omitempty"`
 }
 
 // CreateTaskRequest is for creating a new task
 type CreateTaskRequest struct {
-	Title       string `json:"title" validate:"required,min=5"`
-	Description string `json:"description"`
-	Status      string `json:"status" validate:"omitempty,oneof=todo in_progress done"`
+	Title       string     `json:"title" validate:"required,min=5"`
+	Description string     `json:"description"`
+	Status      string     `json:"status" validate:"omitempty,oneof=todo in_progress done"`
+	DueDate     *time.Time `json:"due_date,omitempty"`
+	DueTimeZone string     `json:"due_time_zone,omitempty" validate:"omitempty,timezone"` // Defaults to the caller's time zone
+	Priority    string     `json:"priority,omitempty" validate:"omitempty,oneof=low medium high urgent"`
+	Tags        []string   `json:"tags,omitempty" validate:"max=20,dive,required,max=50"`
+	Color       string     `json:"color,omitempty" validate:"omitempty,hexcolor|oneof=red orange yellow green teal blue purple pink gray"` // Hex color or palette name
+	ProjectID   string     `json:"project_id,omitempty"`
+	MilestoneID string     `json:"milestone_id,omitempty"` // Must be a milestone of the task's project
+}
+
+// QuickAddTaskRequest is for creating a task from a line of shorthand such as
+// "Pay rent tomorrow 5pm #finance !high"
+type QuickAddTaskRequest struct {
+	Text        string `json:"text" validate:"required,max=500"`
+	ProjectID   string `json:"project_id,omitempty"`
+	MilestoneID string `json:"milestone_id,omitempty"` // Must be a milestone of the task's project
 }
 
 // UpdateTaskRequest is for updating an existing task
 type UpdateTaskRequest struct {
-	Title       *string `json:"title,omitempty" validate:"omitempty,min=5"`
-	Description *string `json:"description,omitempty"`
-	Status      *string `json:"status,omitempty" validate:"omitempty,oneof=todo in_progress done"`
+	Title       *string    `json:"title,omitempty" validate:"omitempty,min=5"`
+	Description *string    `json:"description,omitempty"`
+	Status      *string    `json:"status,omitempty" validate:"omitempty,oneof=todo in_progress done"`
+	DueDate     *time.Time `json:"due_date,omitempty"`
+	DueTimeZone string     `json:"due_time_zone,omitempty" validate:"omitempty,timezone"`                // Defaults to the caller's time zone when due_date is set
+	Priority    *string    `json:"priority,omitempty" validate:"omitempty,oneof=low medium high urgent"` // An empty string clears it
+	Tags        []string   `json:"tags,omitempty" validate:"omitempty,max=20,dive,required,max=50"`      // Replaces the tags; [] removes them
+	ProjectID   *string    `json:"project_id,omitempty"`                                                 // An empty string takes the task out of its project
+	MilestoneID *string    `json:"milestone_id,omitempty"`                                               // An empty string takes the task off its milestone
+	// A hex color or palette name; an empty string clears it
+	Color *string `json:"color,omitempty" validate:"omitempty,eq=|hexcolor|oneof=red orange yellow green teal blue purple pink gray"`
+}
+
+// MergeTasksRequest names the duplicate task to merge into the task in the URL
+type MergeTasksRequest struct {
+	SourceID string `json:"source_id" validate:"required"`
+}
+
+// MergeTasksResponse reports what a merge moved to the task kept
+type MergeTasksResponse struct {
+	Task             *Task              `json:"task"`
+	SourceID         primitive.ObjectID `json:"source_id"`
+	CommentsMoved    int64              `json:"comments_moved"`
+	AttachmentsMoved int64              `json:"attachments_moved"`
 }
 
 // TaskListResponse holds tasks and pagination metadata
 type TaskListResponse struct {
-	Tasks      []Task `json:"tasks"`
-	TotalCount int64  `json:"total_count"`
-	Page       int64  `json:"page"`
-	Limit      int64  `json:"limit"`
+	Tasks []Task `json:"tasks"`
+	Pagination
+	CountEstimated bool `json:"count_estimated,omitempty"` // TotalCount is approximate (?count_mode=estimated)
 }
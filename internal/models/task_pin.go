@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TaskPin records that a user pinned a task, to keep it at hand and list it first
+type TaskPin struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	UserID   primitive.ObjectID `bson:"user_id" json:"user_id"`
+	TaskID   primitive.ObjectID `bson:"task_id" json:"task_id"`
+	PinnedAt time.Time          `bson:"pinned_at" json:"pinned_at"`
+}
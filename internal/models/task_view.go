@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TaskView records when a user last viewed a task, to tell which tasks changed since
+type TaskView struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	UserID primitive.ObjectID `bson:"user_id" json:"user_id"`
+	TaskID primitive.ObjectID `bson:"task_id" json:"task_id"`
+	SeenAt time.Time          `bson:"seen_at" json:"seen_at"`
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Upload records a stored file. Variants maps each thumbnail variant name to its URL;
+// variants the original is already smaller than point at the original.
+type Upload struct {
+	ID           primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	PublicID     string              `bson:"public_id" json:"public_id"` // Storage key, used to delete the upload
+	URL          string              `bson:"url" json:"url"`
+	Variants     map[string]string   `bson:"variants,omitempty" json:"variants,omitempty"`
+	Size         int64               `bson:"size" json:"size"` // Bytes
+	ContentType  string              `bson:"content_type,omitempty" json:"content_type,omitempty"`
+	UploaderID   primitive.ObjectID  `bson:"uploader_id" json:"uploader_id"`
+	ResourceType string              `bson:"resource_type,omitempty" json:"resource_type,omitempty"` // Linked resource: "user" or "task"
+	ResourceID   *primitive.ObjectID `bson:"resource_id,omitempty" json:"resource_id,omitempty"`
+	CreatedAt    time.Time           `bson:"created_at" json:"created_at"`
+}
+
+// UploadLink optionally links an upload to the resource it belongs to (e.g. the task it is attached to)
+type UploadLink struct {
+	ResourceType string `json:"resource_type,omitempty" validate:"omitempty,oneof=user task"`
+	ResourceID   string `json:"resource_id,omitempty" validate:"required_with=ResourceType,omitempty,len=24,hexadecimal"`
+}
+
+// LinkAttachmentRequest attaches an existing upload to a task
+type LinkAttachmentRequest struct {
+	PublicID string `json:"public_id" validate:"required"`
+}
+
+// UploadResponse is returned once a file has been stored
+type UploadResponse struct {
+	Message string `json:"message"`
+	Upload
+}
+
+// UploadListResponse holds uploads and pagination metadata
+type UploadListResponse struct {
+	Uploads []Upload `json:"uploads"`
+	Pagination
+}
+
+// UploadUsage reports how much of their storage quota a user has used.
+// QuotaBytes and RemainingBytes are omitted when storage is unlimited.
+type UploadUsage struct {
+	UsedBytes      int64  `json:"used_bytes"`
+	FileCount      int64  `json:"file_count"`
+	QuotaBytes     *int64 `json:"quota_bytes,omitempty"`
+	RemainingBytes *int64 `json:"remaining_bytes,omitempty"`
+}
+
+// DirectUploadSignature authorizes a single upload sent by the client straight to the
+// storage backend: the file is posted to UploadURL as multipart field "file" along with Fields
+type DirectUploadSignature struct {
+	UploadURL string            `json:"upload_url"`
+	Fields    map[string]string `json:"fields"`
+	PublicID  string            `json:"public_id"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// DirectUploadLimits restricts what a signed direct upload may store. The backend refuses other
+// files itself. Empty limits are not enforced.
+type DirectUploadLimits struct {
+	Formats []string // File extensions without the dot, such as "png"
+	MaxSize int64    // Bytes
+}
+
+// StoredAsset describes a file the storage backend holds
+type StoredAsset struct {
+	PublicID    string
+	URL         string
+	Size        int64 // Bytes
+	ContentType string
+	Width       int // Pixels, 0 when unknown or not an image
+	Height      int
+}
+
+// PendingUpload reserves quota for a signed direct upload until it is confirmed. An upload
+// still pending at ExpiresAt is deleted from the storage backend.
+type PendingUpload struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	PublicID   string             `bson:"public_id"`
+	UploaderID primitive.ObjectID `bson:"uploader_id"`
+	MaxSize    int64              `bson:"max_size"` // Bytes reserved, 0 when unlimited
+	ExpiresAt  time.Time          `bson:"expires_at"`
+}
+
+// ConfirmDirectUploadRequest reports a finished direct upload, copied from the storage backend's response
+type ConfirmDirectUploadRequest struct {
+	PublicID  string `json:"public_id" validate:"required"`
+	Version   int64  `json:"version" validate:"required"`
+	Signature string `json:"signature" validate:"required"`
+	UploadLink
+}
@@ -6,19 +6,33 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// User represents a user in the system
+// User represents a user in the system.
+// Fields tagged encrypted hold personal data and are encrypted at rest when a data key is
+// configured; the UserService decrypts them.
 type User struct {
-	ID                  primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	FirstName           string             `bson:"first_name" json:"first_name" validate:"required,min=2,max=50"`
-	LastName            string             `bson:"last_name" json:"last_name" validate:"required,min=2,max=50"`
-	Email               string             `bson:"email" json:"email" validate:"required,email"`
-	Password            string             `bson:"password" json:"-"` // Exclude from JSON output
-	RoleID              primitive.ObjectID `bson:"role_id" json:"role_id"`
-	ProfilePictureURL   string             `bson:"profile_picture_url,omitempty" json:"profile_picture_url,omitempty"`
-	IsEmailVerified     bool               `bson:"is_email_verified" json:"is_email_verified"`
-	NeedsPasswordChange bool               `bson:"needs_password_change" json:"needs_password_change"` // New field
-	CreatedAt           time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt           time.Time          `bson:"updated_at" json:"updated_at"`
+	ID                  primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty"`
+	FirstName           string              `bson:"first_name" json:"first_name" validate:"required,min=2,max=50"`
+	LastName            string              `bson:"last_name" json:"last_name" validate:"required,min=2,max=50"`
+	Email               string              `bson:"email" json:"email" validate:"required,email"`
+	Password            string              `bson:"password" json:"-"` // Exclude from JSON output
+	RoleID              primitive.ObjectID  `bson:"role_id" json:"role_id"`
+	ProfilePictureURL   string              `bson:"profile_picture_url,omitempty" json:"profile_picture_url,omitempty"`
+	Phone               string              `bson:"phone,omitempty" json:"phone,omitempty" encrypted:"true"`
+	Address             string              `bson:"address,omitempty" json:"address,omitempty" encrypted:"true"`
+	IsEmailVerified     bool                `bson:"is_email_verified" json:"is_email_verified"`
+	NeedsPasswordChange bool                `bson:"needs_password_change" json:"needs_password_change"` // New field
+	WeeklyDigest        bool                `bson:"weekly_digest" json:"weekly_digest"`                 // Opted in to the weekly summary email
+	DailySummary        bool                `bson:"daily_summary" json:"daily_summary"`                 // Opted in to the morning summary of the day's tasks
+	Locale              string              `bson:"locale,omitempty" json:"locale,omitempty"`           // Language of emails, e.g. "fr"; English when empty
+	TimeZone            string              `bson:"time_zone,omitempty" json:"time_zone,omitempty"`     // IANA time zone days are counted in, e.g. "Europe/Paris"; UTC when empty
+	IsServiceAccount    bool                `bson:"is_service_account" json:"is_service_account"`       // A machine principal that authenticates with API keys only
+	Disabled            bool                `bson:"disabled" json:"disabled"`                           // Disabled users can't log in or use their tokens
+	MergedInto          *primitive.ObjectID `bson:"merged_into,omitempty" json:"merged_into,omitempty"` // User this duplicate account was merged into
+	// DailySummaryHour is the hour of the user's day the daily summary is sent at; the
+	// configured default when unset
+	DailySummaryHour *int      `bson:"daily_summary_hour,omitempty" json:"daily_summary_hour,omitempty"`
+	CreatedAt        time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt        time.Time `bson:"updated_at" json:"updated_at"`
 }
 
 // UserLoginRequest is used for login requests (email and password only)
@@ -27,33 +41,54 @@ type UserLoginRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
-// UserRegisterRequest is used for registration requests (email and password only)
+// UserRegisterRequest is used for registration requests (email, password and optional locale)
 type UserRegisterRequest struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required,min=6"`
+	Locale   string `json:"locale,omitempty" validate:"omitempty,bcp47_language_tag"`
 }
 
 // UserResponse is used for user data returned to client
 type UserResponse struct {
-	ID                  string    `json:"id"`
-	FirstName           string    `json:"first_name"`
-	LastName            string    `json:"last_name"`
-	Email               string    `json:"email"`
-	RoleName            string    `json:"role_name"` // Populated from Role collection
-	ProfilePictureURL   string    `json:"profile_picture_url,omitempty"`
-	IsEmailVerified     bool      `json:"is_email_verified"`
-	NeedsPasswordChange bool      `json:"needs_password_change"` // New field
-	CreatedAt           time.Time `json:"created_at"`
-	UpdatedAt           time.Time `json:"updated_at"`
+	ID                  string              `json:"id"`
+	FirstName           string              `json:"first_name"`
+	LastName            string              `json:"last_name"`
+	Email               string              `json:"email"`
+	RoleName            string              `json:"role_name"` // Populated from Role collection
+	ProfilePictureURL   string              `json:"profile_picture_url,omitempty"`
+	Phone               string              `json:"phone,omitempty"`
+	Address             string              `json:"address,omitempty"`
+	IsEmailVerified     bool                `json:"is_email_verified"`
+	NeedsPasswordChange bool                `json:"needs_password_change"` // New field
+	WeeklyDigest        bool                `json:"weekly_digest"`
+	DailySummary        bool                `json:"daily_summary"`
+	DailySummaryHour    *int                `json:"daily_summary_hour,omitempty"`
+	Locale              string              `json:"locale,omitempty"`
+	TimeZone            string              `json:"time_zone,omitempty"`
+	IsServiceAccount    bool                `json:"is_service_account"`
+	Disabled            bool                `json:"disabled"`
+	MergedInto          *primitive.ObjectID `json:"merged_into,omitempty"`
+	CreatedAt           time.Time           `json:"created_at"`
+	UpdatedAt           time.Time           `json:"updated_at"`
+}
+
+// CSRFTokenResponse holds the token cookie-authenticated frontends send in the X-CSRF-Token
+// header of state-changing requests
+type CSRFTokenResponse struct {
+	CSRFToken string `json:"csrf_token"`
 }
 
 // LoginResponse is the response body for a successful login
+// With cookie authentication the token is set as a cookie instead, and the response says
+// when that session expires and holds the CSRF token.
 type LoginResponse struct {
-	Message             string `json:"message"`
-	Token               string `json:"token"`
-	UserID              string `json:"user_id"`
-	RoleName            string `json:"role_name"`
-	NeedsPasswordChange bool   `json:"needs_password_change"` // Added for frontend redirection
+	Message             string     `json:"message"`
+	Token               string     `json:"token,omitempty"`
+	UserID              string     `json:"user_id"`
+	RoleName            string     `json:"role_name"`
+	NeedsPasswordChange bool       `json:"needs_password_change"` // Added for frontend redirection
+	SessionExpiresAt    *time.Time `json:"session_expires_at,omitempty"`
+	CSRFToken           string     `json:"csrf_token,omitempty"`
 }
 
 // UpdateUserRoleRequest for changing user roles
@@ -66,6 +101,14 @@ type UpdateUserProfileRequest struct {
 	FirstName         *string `json:"first_name,omitempty" validate:"omitempty,min=2,max=50"`
 	LastName          *string `json:"last_name,omitempty" validate:"omitempty,min=2,max=50"`
 	ProfilePictureURL *string `json:"profile_picture_url,omitempty" validate:"omitempty,url"`
+	Phone             *string `json:"phone,omitempty" validate:"omitempty,max=30"`    // Empty string removes it
+	Address           *string `json:"address,omitempty" validate:"omitempty,max=500"` // Empty string removes it
+	WeeklyDigest      *bool   `json:"weekly_digest,omitempty"`
+	DailySummary      *bool   `json:"daily_summary,omitempty"`
+	Locale            *string `json:"locale,omitempty" validate:"omitempty,bcp47_language_tag"` // Empty string resets to English
+	TimeZone          *string `json:"time_zone,omitempty" validate:"omitempty,eq=|timezone"`    // IANA name such as "Europe/Paris"; empty string resets to UTC
+	// DailySummaryHour is the hour of the user's day the daily summary is sent at
+	DailySummaryHour *int `json:"daily_summary_hour,omitempty" validate:"omitempty,min=0,max=23"`
 }
 
 // ForgotPasswordRequest for initiating password reset
@@ -93,6 +136,11 @@ type AuthContext struct {
 	Permissions         []Permission
 	IsEmailVerified     bool
 	NeedsPasswordChange bool
+	IsServiceAccount    bool
+	TimeZone            string             // The user's preferred time zone; UTC when empty
+	APIKeyID            primitive.ObjectID // The service account key the request authenticated with; zero for user tokens
+	// ProjectRoles holds the user's role in each project they own or are a member of
+	ProjectRoles map[primitive.ObjectID]ProjectRole
 }
 
 // HasPermission checks if the AuthContext has a specific permission
@@ -105,10 +153,61 @@ func (ac *AuthContext) HasPermission(permission string) bool {
 	return false
 }
 
+// ProjectRole returns the user's role in a project, empty when they aren't a member
+func (ac *AuthContext) ProjectRole(projectID primitive.ObjectID) ProjectRole {
+	return ac.ProjectRoles[projectID]
+}
+
+// ProjectIDs returns the IDs of the projects the user owns or is a member of
+func (ac *AuthContext) ProjectIDs() []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, 0, len(ac.ProjectRoles))
+	for id := range ac.ProjectRoles {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Location returns the user's preferred time zone, UTC when they haven't chosen one
+func (ac *AuthContext) Location() *time.Location {
+	return LoadLocation(ac.TimeZone)
+}
+
+// Location returns the user's preferred time zone, UTC when they haven't chosen one
+func (u *User) Location() *time.Location {
+	return LoadLocation(u.TimeZone)
+}
+
+// LoadLocation returns the IANA time zone named name, or UTC when name is empty or unknown
+func LoadLocation(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// MergeUsersRequest names the duplicate account to merge into the user in the URL
+type MergeUsersRequest struct {
+	DuplicateID string `json:"duplicate_id" validate:"required"`
+}
+
+// MergeUsersResponse reports what a merge moved to the primary user
+type MergeUsersResponse struct {
+	Primary       *UserResponse      `json:"primary"`
+	DuplicateID   primitive.ObjectID `json:"duplicate_id"`
+	TasksMoved    int64              `json:"tasks_moved"`
+	CommentsMoved int64              `json:"comments_moved"`
+	UploadsMoved  int64              `json:"uploads_moved"`
+	ProjectsMoved int64              `json:"projects_moved"` // Projects owned or joined by the duplicate
+	RoleChanged   bool               `json:"role_changed"`   // The primary took the duplicate's role, which grants more
+}
+
 // UserListResponse holds a list of users and pagination metadata
 type UserListResponse struct {
-	Users      []UserResponse `json:"users"`
-	TotalCount int64          `json:"total_count"`
-	Page       int64          `json:"page"`
-	Limit      int64          `json:"limit"`
+	Users []UserResponse `json:"users"`
+	Pagination
+	CountEstimated bool `json:"count_estimated,omitempty"` // TotalCount is approximate (?count_mode=estimated)
 }
@@ -0,0 +1,345 @@
+package openapi
+
+import (
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Param describes a query parameter accepted by an operation
+type Param struct {
+	Name        string
+	Description string
+	Type        string // "string", "integer" or "boolean"
+	Required    bool
+}
+
+// Operation documents a single route. Request and Response are example values
+// (usually zero-valued models) whose types are reflected into JSON schemas.
+type Operation struct {
+	Summary        string
+	Tag            string
+	Public         bool   // No bearer token required
+	Permission     string // Permission enforced by the auth middleware, if any
+	Request        interface{}
+	Response       interface{}
+	ResponseStatus int // Defaults to 200
+	Query          []Param
+}
+
+// Route is a registered method + path template pair
+type Route struct {
+	Method string
+	Path   string
+}
+
+// Generator builds an OpenAPI 3 document from routes and operation metadata
+type Generator struct {
+	title      string
+	version    string
+	schemas    map[string]interface{}
+	typeNames  map[reflect.Type]string
+	errorModel interface{}
+}
+
+// NewGenerator creates a Generator. errorModel is the body returned with error responses.
+func NewGenerator(title, version string, errorModel interface{}) *Generator {
+	return &Generator{
+		title:      title,
+		version:    version,
+		schemas:    map[string]interface{}{},
+		typeNames:  map[reflect.Type]string{},
+		errorModel: errorModel,
+	}
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^}:]+)(?::[^}]*)?\}`)
+
+// Build produces the OpenAPI document. lookup returns the documented operation for a
+// route, if any; undocumented routes are still listed with a generic description.
+func (g *Generator) Build(routes []Route, lookup func(Route) (Operation, bool)) map[string]interface{} {
+	paths := map[string]map[string]interface{}{}
+
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path == routes[j].Path {
+			return routes[i].Method < routes[j].Method
+		}
+		return routes[i].Path < routes[j].Path
+	})
+
+	for _, route := range routes {
+		op, documented := lookup(route)
+		openAPIPath := pathParamPattern.ReplaceAllString(route.Path, "{$1}")
+		if paths[openAPIPath] == nil {
+			paths[openAPIPath] = map[string]interface{}{}
+		}
+		paths[openAPIPath][strings.ToLower(route.Method)] = g.operation(route, op, documented)
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   g.title,
+			"version": g.version,
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": g.schemas,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{
+					"type":         "http",
+					"scheme":       "bearer",
+					"bearerFormat": "JWT",
+					"description":  "A JWT from POST /auth/login, or a service account API key (tfk_...)",
+				},
+			},
+		},
+	}
+}
+
+// operation renders a single OpenAPI operation object
+func (g *Generator) operation(route Route, op Operation, documented bool) map[string]interface{} {
+	summary := op.Summary
+	if !documented {
+		summary = route.Method + " " + route.Path
+	}
+	result := map[string]interface{}{
+		"summary":     summary,
+		"operationId": operationID(route),
+	}
+	if op.Tag != "" {
+		result["tags"] = []string{op.Tag}
+	}
+	if op.Permission != "" {
+		result["description"] = "Requires the `" + op.Permission + "` permission."
+	}
+
+	var parameters []interface{}
+	for _, match := range pathParamPattern.FindAllStringSubmatch(route.Path, -1) {
+		parameters = append(parameters, map[string]interface{}{
+			"name":     match[1],
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]interface{}{"type": "string"},
+		})
+	}
+	for _, q := range op.Query {
+		typ := q.Type
+		if typ == "" {
+			typ = "string"
+		}
+		parameters = append(parameters, map[string]interface{}{
+			"name":        q.Name,
+			"in":          "query",
+			"required":    q.Required,
+			"description": q.Description,
+			"schema":      map[string]interface{}{"type": typ},
+		})
+	}
+	if len(parameters) > 0 {
+		result["parameters"] = parameters
+	}
+
+	if op.Request != nil {
+		result["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": g.SchemaFor(reflect.TypeOf(op.Request))},
+			},
+		}
+	}
+
+	status := op.ResponseStatus
+	if status == 0 {
+		status = 200
+	}
+	success := map[string]interface{}{"description": "Success"}
+	if op.Response != nil {
+		success["content"] = map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": g.SchemaFor(reflect.TypeOf(op.Response))},
+		}
+	}
+	responses := map[string]interface{}{strconv.Itoa(status): success}
+	if g.errorModel != nil && op.Request != nil {
+		responses["400"] = map[string]interface{}{
+			"description": "The request body doesn't match its schema; details.fields names each failing field and rule",
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": g.SchemaFor(reflect.TypeOf(g.errorModel))},
+			},
+		}
+	}
+	if g.errorModel != nil {
+		responses["default"] = map[string]interface{}{
+			"description": "Error",
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": g.SchemaFor(reflect.TypeOf(g.errorModel))},
+			},
+		}
+	}
+	result["responses"] = responses
+
+	if !documented || !op.Public {
+		result["security"] = []interface{}{map[string]interface{}{"bearerAuth": []string{}}}
+	}
+	return result
+}
+
+// operationID derives a stable identifier such as "get_api_v1_tasks_id"
+func operationID(route Route) string {
+	id := strings.ToLower(route.Method) + route.Path
+	id = pathParamPattern.ReplaceAllString(id, "$1")
+	return strings.Trim(strings.NewReplacer("/", "_", "-", "_").Replace(id), "_")
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	objectIDType = reflect.TypeOf(primitive.ObjectID{})
+)
+
+// SchemaFor returns a JSON schema for t; named structs are registered as components and referenced
+func (g *Generator) SchemaFor(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case objectIDType:
+		return map[string]interface{}{"type": "string", "pattern": "^[0-9a-fA-F]{24}$"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": g.SchemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.SchemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name, ok := g.typeNames[t]
+		if !ok {
+			name = t.Name()
+			g.typeNames[t] = name
+			g.schemas[name] = map[string]interface{}{} // Placeholder guards against recursive types
+			g.schemas[name] = g.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]interface{}{}
+}
+
+// structSchema reflects a struct's exported JSON fields, honouring validate tags
+func (g *Generator) structSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		// Like encoding/json, an untagged embedded struct contributes its fields directly
+		if embedded := field.Type; field.Anonymous && field.Tag.Get("json") == "" {
+			for embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				inner := g.structSchema(embedded)
+				for name, schema := range inner["properties"].(map[string]interface{}) {
+					if _, shadowed := properties[name]; !shadowed {
+						properties[name] = schema
+					}
+				}
+				if innerRequired, ok := inner["required"].([]string); ok {
+					required = append(required, innerRequired...)
+				}
+				continue
+			}
+		}
+
+		name := field.Name
+		if tag := field.Tag.Get("json"); tag != "" {
+			parts := strings.Split(tag, ",")
+			if parts[0] == "-" {
+				continue
+			}
+			if parts[0] != "" {
+				name = parts[0]
+			}
+		}
+
+		// Rules after "dive" apply to the elements of a list
+		tag, itemTag, _ := strings.Cut(field.Tag.Get("validate"), ",dive,")
+		schema := g.SchemaFor(field.Type)
+		if _, isRef := schema["$ref"]; !isRef {
+			applyValidateTag(schema, tag)
+			if items, ok := schema["items"].(map[string]interface{}); ok {
+				applyValidateTag(items, itemTag)
+			}
+		}
+		properties[name] = schema
+
+		for _, rule := range strings.Split(tag, ",") {
+			if rule == "required" {
+				required = append(required, name)
+			}
+		}
+	}
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// applyValidateTag maps go-playground/validator rules onto JSON schema keywords
+func applyValidateTag(schema map[string]interface{}, tag string) {
+	if tag == "" {
+		return
+	}
+	typ, _ := schema["type"].(string)
+	for _, rule := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(rule, "=")
+		switch key {
+		case "email":
+			schema["format"] = "email"
+		case "url":
+			schema["format"] = "uri"
+		case "oneof":
+			schema["enum"] = strings.Fields(value)
+		case "min", "max":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				continue
+			}
+			switch typ {
+			case "string":
+				schema[key+"Length"] = n
+			case "array":
+				schema[key+"Items"] = n
+			case "integer", "number":
+				schema[key+"imum"] = n
+			}
+		}
+	}
+}
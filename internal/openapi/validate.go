@@ -0,0 +1,197 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// RequestValidator checks request bodies against the schemas of the operations' Request
+// models, the same schemas the OpenAPI document publishes
+type RequestValidator struct {
+	generator *Generator
+	schemas   map[Route]map[string]interface{}
+	patterns  sync.Map // Compiled pattern keywords, by pattern
+}
+
+// NewRequestValidator reflects the request schemas of the documented routes. lookup returns
+// the documented operation for a route, as for Generator.Build.
+func NewRequestValidator(routes []Route, lookup func(Route) (Operation, bool)) *RequestValidator {
+	v := &RequestValidator{
+		generator: NewGenerator("", "", nil),
+		schemas:   map[Route]map[string]interface{}{},
+	}
+	for _, route := range routes {
+		if op, ok := lookup(route); ok && op.Request != nil {
+			v.schemas[route] = v.generator.SchemaFor(reflect.TypeOf(op.Request))
+		}
+	}
+	return v
+}
+
+// HasSchema reports whether the route's request body is validated
+func (v *RequestValidator) HasSchema(route Route) bool {
+	_, ok := v.schemas[route]
+	return ok
+}
+
+// Validate checks body, decoded with json.Decoder.UseNumber, against the route's request
+// schema. It returns the problems found keyed by field path (e.g. "members[0].user_id"), each
+// naming the rule broken (e.g. "required" or "minLength=5"); nil means the body is valid.
+func (v *RequestValidator) Validate(route Route, body interface{}) map[string]interface{} {
+	schema, ok := v.schemas[route]
+	if !ok {
+		return nil
+	}
+	problems := map[string]interface{}{}
+	v.check(schema, body, "", problems)
+	if len(problems) == 0 {
+		return nil
+	}
+	return problems
+}
+
+// check adds the ways value breaks schema to problems, under path
+func (v *RequestValidator) check(schema map[string]interface{}, value interface{}, path string, problems map[string]interface{}) {
+	if ref, ok := schema["$ref"].(string); ok {
+		schema, _ = v.generator.schemas[strings.TrimPrefix(ref, "#/components/schemas/")].(map[string]interface{})
+	}
+	field := path
+	if field == "" {
+		field = "body"
+	}
+
+	typ, _ := schema["type"].(string)
+	if typ != "" && !hasType(value, typ) {
+		problems[field] = "type=" + typ
+		return
+	}
+
+	switch value := value.(type) {
+	case string:
+		// Validate tags usually allow empty strings (omitempty) or reject them (required), which
+		// the struct validation run by handlers already checks
+		if value == "" {
+			return
+		}
+		length := utf8.RuneCountInString(value)
+		if n, ok := schema["minLength"].(int); ok && length < n {
+			problems[field] = "minLength=" + strconv.Itoa(n)
+		}
+		if n, ok := schema["maxLength"].(int); ok && length > n {
+			problems[field] = "maxLength=" + strconv.Itoa(n)
+		}
+		if enum, ok := schema["enum"].([]string); ok && !contains(enum, value) {
+			problems[field] = "enum=" + strings.Join(enum, " ")
+		}
+		if pattern, ok := schema["pattern"].(string); ok && !v.compile(pattern).MatchString(value) {
+			problems[field] = "pattern=" + pattern
+		}
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339, value); err != nil {
+				problems[field] = "format=date-time"
+			}
+		}
+
+	case json.Number:
+		n, _ := value.Float64()
+		if min, ok := schema["minimum"].(int); ok && n < float64(min) {
+			problems[field] = "minimum=" + strconv.Itoa(min)
+		}
+		if max, ok := schema["maximum"].(int); ok && n > float64(max) {
+			problems[field] = "maximum=" + strconv.Itoa(max)
+		}
+
+	case []interface{}:
+		if n, ok := schema["minItems"].(int); ok && len(value) < n {
+			problems[field] = "minItems=" + strconv.Itoa(n)
+		}
+		if n, ok := schema["maxItems"].(int); ok && len(value) > n {
+			problems[field] = "maxItems=" + strconv.Itoa(n)
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range value {
+				v.check(items, item, fmt.Sprintf("%s[%d]", path, i), problems)
+			}
+		}
+
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		required, _ := schema["required"].([]string)
+		for _, name := range required {
+			if value[name] == nil {
+				problems[join(path, name)] = "required"
+			}
+		}
+		for name, member := range value {
+			// null leaves a Go field at its zero value, and members without a schema are ignored
+			// when decoding, so neither is checked
+			if propertySchema, ok := properties[name].(map[string]interface{}); ok && member != nil {
+				v.check(propertySchema, member, join(path, name), problems)
+			}
+		}
+		if additional, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+			for name, member := range value {
+				if member != nil {
+					v.check(additional, member, join(path, name), problems)
+				}
+			}
+		}
+	}
+}
+
+// compile returns the compiled form of a pattern keyword; patterns come from the schemas
+// reflected from the models, so they are known to compile
+func (v *RequestValidator) compile(pattern string) *regexp.Regexp {
+	if re, ok := v.patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp)
+	}
+	re := regexp.MustCompile(pattern)
+	v.patterns.Store(pattern, re)
+	return re
+}
+
+// hasType reports whether a decoded JSON value is of a JSON schema type
+func hasType(value interface{}, typ string) bool {
+	switch value := value.(type) {
+	case string:
+		return typ == "string"
+	case bool:
+		return typ == "boolean"
+	case json.Number:
+		if typ == "integer" {
+			_, err := strconv.ParseInt(string(value), 10, 64)
+			return err == nil
+		}
+		return typ == "number"
+	case []interface{}:
+		return typ == "array"
+	case map[string]interface{}:
+		return typ == "object"
+	}
+	return false
+}
+
+// join appends a member name to a field path
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// contains reports whether values holds value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
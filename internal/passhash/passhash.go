@@ -0,0 +1,129 @@
+// Package passhash hashes passwords with bcrypt or argon2id.
+//
+// Verify accepts hashes made by either algorithm with any parameters, so the configured
+// Hasher can change without resetting passwords: hashes it reports as needing a rehash are
+// replaced the next time their user logs in.
+package passhash
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// argon2SaltSize and argon2KeySize are the lengths in bytes of argon2id salts and hashes
+	argon2SaltSize = 16
+	argon2KeySize  = 32
+	// argon2Prefix starts every argon2id hash, in the PHC string format
+	argon2Prefix = "$argon2id$"
+)
+
+// Hasher hashes passwords with one algorithm and set of parameters
+type Hasher interface {
+	// Hash returns the encoded hash of password, which names the algorithm and parameters
+	Hash(password string) (string, error)
+	// NeedsRehash reports whether hash was made with another algorithm or other parameters
+	NeedsRehash(hash string) bool
+}
+
+// New returns the Hasher for algorithm, "bcrypt" or "argon2id"
+func New(algorithm string, bcryptCost int, argon2Params Argon2id) (Hasher, error) {
+	switch algorithm {
+	case "bcrypt":
+		if bcryptCost < bcrypt.MinCost || bcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+		return Bcrypt{Cost: bcryptCost}, nil
+	case "argon2id":
+		if argon2Params.Iterations < 1 || argon2Params.Parallelism < 1 {
+			return nil, fmt.Errorf("argon2id iterations and parallelism must be at least 1")
+		}
+		if argon2Params.Memory < 8*uint32(argon2Params.Parallelism) {
+			return nil, fmt.Errorf("argon2id memory must be at least 8 KiB per thread")
+		}
+		return argon2Params, nil
+	default:
+		return nil, fmt.Errorf("unknown password hashing algorithm %q (expected bcrypt or argon2id)", algorithm)
+	}
+}
+
+// Verify reports whether password matches hash, whichever algorithm and parameters made it
+func Verify(password, hash string) bool {
+	if strings.HasPrefix(hash, argon2Prefix) {
+		params, salt, key, ok := parseArgon2id(hash)
+		if !ok {
+			return false
+		}
+		computed := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+		return subtle.ConstantTimeCompare(computed, key) == 1
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// Bcrypt hashes passwords with bcrypt
+type Bcrypt struct {
+	Cost int
+}
+
+// Hash returns the bcrypt hash of password
+func (b Bcrypt) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.Cost)
+	return string(hash), err
+}
+
+// NeedsRehash reports whether hash isn't a bcrypt hash of the hasher's cost
+func (b Bcrypt) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != b.Cost
+}
+
+// Argon2id hashes passwords with argon2id
+type Argon2id struct {
+	Memory      uint32 // In KiB
+	Iterations  uint32
+	Parallelism uint8
+}
+
+// Hash returns the argon2id hash of password with a random salt, encoded as
+// $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<hash>
+func (a Argon2id) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, a.Iterations, a.Memory, a.Parallelism, argon2KeySize)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2Prefix, argon2.Version, a.Memory, a.Iterations, a.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// NeedsRehash reports whether hash isn't an argon2id hash of the hasher's parameters
+func (a Argon2id) NeedsRehash(hash string) bool {
+	params, _, key, ok := parseArgon2id(hash)
+	return !ok || params != a || len(key) != argon2KeySize
+}
+
+// parseArgon2id decodes a hash Argon2id.Hash returned
+func parseArgon2id(hash string) (params Argon2id, salt, key []byte, ok bool) {
+	parts := strings.Split(strings.TrimPrefix(hash, argon2Prefix), "$")
+	if len(parts) != 4 || parts[0] != fmt.Sprintf("v=%d", argon2.Version) {
+		return Argon2id{}, nil, nil, false
+	}
+	if _, err := fmt.Sscanf(parts[1], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return Argon2id{}, nil, nil, false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Argon2id{}, nil, nil, false
+	}
+	key, err = base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil || len(key) == 0 || params.Iterations < 1 || params.Parallelism < 1 {
+		return Argon2id{}, nil, nil, false
+	}
+	return params, salt, key, true
+}
@@ -0,0 +1,317 @@
+// Package pdf writes simple text documents as PDF: headings, labelled fields and wrapped
+// paragraphs on A4 pages in the standard Helvetica fonts, which every reader has, so nothing
+// needs to be embedded
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Page geometry in points (1/72 inch)
+const (
+	pageWidth    = 595.28 // A4
+	pageHeight   = 841.89
+	margin       = 56.0
+	bottomMargin = 64.0 // Leaves room for the footer
+	footerY      = 36.0
+	labelWidth   = 120.0 // Width of the label column of fields
+)
+
+// font is one of the two standard fonts a document uses
+type font struct {
+	resource string // Name in the page resources
+	widths   *[95]int
+}
+
+var (
+	regular = font{resource: "F1", widths: &helveticaWidths}
+	bold    = font{resource: "F2", widths: &helveticaBoldWidths}
+)
+
+// Document is a PDF being written. Content is laid out top to bottom as it is added, starting
+// new pages as they fill up.
+type Document struct {
+	title  string
+	footer string
+	pages  []*bytes.Buffer // Content stream of each page
+	y      float64         // Baseline of the last line written on the current page
+}
+
+// New creates an empty document with the given title, shown by PDF readers in place of the
+// file name
+func New(title string) *Document {
+	return &Document{title: title}
+}
+
+// SetFooter sets the text printed at the bottom of every page, next to the page number
+func (d *Document) SetFooter(footer string) {
+	d.footer = footer
+}
+
+// Heading writes a large bold heading
+func (d *Document) Heading(text string) {
+	d.Space()
+	d.paragraph(bold, 16, margin, pageWidth-2*margin, text, false)
+	d.Space()
+}
+
+// Subheading writes a bold heading for a section
+func (d *Document) Subheading(text string) {
+	d.Space()
+	d.paragraph(bold, 12, margin, pageWidth-2*margin, text, false)
+	d.y -= 4
+}
+
+// Text writes a paragraph, wrapping it to the page width. Line breaks in text are kept.
+func (d *Document) Text(text string) {
+	d.paragraph(regular, 10, margin, pageWidth-2*margin, text, false)
+}
+
+// Note writes a small grey paragraph, for details such as who wrote something and when
+func (d *Document) Note(text string) {
+	d.paragraph(regular, 8, margin, pageWidth-2*margin, text, true)
+}
+
+// Field writes a bold label with its value wrapped beside it
+func (d *Document) Field(label, value string) {
+	d.ensureRoom(14)
+	top := d.y
+	d.paragraph(bold, 10, margin, labelWidth, label, false)
+	labelEnd := d.y
+	page := len(d.pages)
+	d.y = top
+	d.paragraph(regular, 10, margin+labelWidth, pageWidth-2*margin-labelWidth, value, false)
+	if len(d.pages) == page && labelEnd < d.y {
+		d.y = labelEnd // The label took more lines than the value
+	}
+}
+
+// Space leaves a blank line, unless at the top of a page
+func (d *Document) Space() {
+	if len(d.pages) > 0 && d.y < pageHeight-margin {
+		d.y -= 8
+	}
+}
+
+// paragraph writes text in f at size, wrapped to width starting at x
+func (d *Document) paragraph(f font, size, x, width float64, text string, grey bool) {
+	leading := size * 1.4
+	for _, line := range wrap(f, size, width, encode(text)) {
+		d.ensureRoom(leading)
+		d.y -= leading
+		page := d.pages[len(d.pages)-1]
+		if grey {
+			page.WriteString("0.4 g\n")
+		}
+		fmt.Fprintf(page, "BT /%s %g Tf %.2f %.2f Td (%s) Tj ET\n", f.resource, size, x, d.y, escape(line))
+		if grey {
+			page.WriteString("0 g\n")
+		}
+	}
+}
+
+// ensureRoom starts a new page when a line of height doesn't fit on the current one
+func (d *Document) ensureRoom(height float64) {
+	if len(d.pages) == 0 || d.y-height < bottomMargin {
+		d.pages = append(d.pages, &bytes.Buffer{})
+		d.y = pageHeight - margin
+	}
+}
+
+// WriteTo writes the finished document to w
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	if len(d.pages) == 0 {
+		d.ensureRoom(0)
+	}
+
+	var buf bytes.Buffer
+	var offsets []int // Byte offset of each object, object n at offsets[n-1]
+	object := func(format string, args ...interface{}) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n", len(offsets))
+		fmt.Fprintf(&buf, format, args...)
+		buf.WriteString("\nendobj\n")
+	}
+
+	// Objects 1 to 5 are fixed; each page then takes a page object and its content stream
+	const firstPage = 6
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	object("<< /Title (%s) /Producer (TaskFlow) /CreationDate (D:%s) >>",
+		escape(encode(d.title)), time.Now().UTC().Format("20060102150405Z"))
+
+	for i, content := range d.pages {
+		var stream bytes.Buffer
+		zw := zlib.NewWriter(&stream)
+		if _, err := zw.Write(content.Bytes()); err != nil {
+			return 0, err
+		}
+		if _, err := io.WriteString(zw, d.footerOps(i+1, len(d.pages))); err != nil {
+			return 0, err
+		}
+		if err := zw.Close(); err != nil {
+			return 0, err
+		}
+
+		object("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %g %g] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, firstPage+2*i+1)
+		object("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", stream.Len(), stream.Bytes())
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return buf.WriteTo(w)
+}
+
+// footerOps draws the footer and page number of page n of count
+func (d *Document) footerOps(n, count int) string {
+	var b strings.Builder
+	b.WriteString("0.4 g\n")
+	if d.footer != "" {
+		footer := wrap(regular, 8, pageWidth-2*margin-80, encode(d.footer))[0]
+		fmt.Fprintf(&b, "BT /F1 8 Tf %.2f %.2f Td (%s) Tj ET\n", margin, footerY, escape(footer))
+	}
+	number := encode(fmt.Sprintf("Page %d of %d", n, count))
+	fmt.Fprintf(&b, "BT /F1 8 Tf %.2f %.2f Td (%s) Tj ET\n", pageWidth-margin-textWidth(regular, 8, number), footerY, escape(number))
+	b.WriteString("0 g\n")
+	return b.String()
+}
+
+// wrap breaks WinAnsi-encoded text into lines no wider than width, at spaces where it can and
+// inside words longer than a line. Each line break in text starts a new line; an empty text
+// is one empty line.
+func wrap(f font, size, width float64, text []byte) [][]byte {
+	var lines [][]byte
+	for _, paragraph := range bytes.Split(text, []byte("\n")) {
+		var line []byte
+		for _, word := range bytes.Fields(paragraph) {
+			candidate := word
+			if len(line) > 0 {
+				candidate = append(append(append([]byte{}, line...), ' '), word...)
+			}
+			if textWidth(f, size, candidate) <= width {
+				line = candidate
+				continue
+			}
+			if len(line) > 0 {
+				lines = append(lines, line)
+			}
+			// A word too long for a line of its own is split wherever it runs out of room
+			for len(word) > 1 && textWidth(f, size, word) > width {
+				n := 1
+				for n < len(word) && textWidth(f, size, word[:n+1]) <= width {
+					n++
+				}
+				lines = append(lines, word[:n])
+				word = word[n:]
+			}
+			line = word
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// textWidth measures WinAnsi-encoded text set in f at size
+func textWidth(f font, size float64, text []byte) float64 {
+	var units int
+	for _, c := range text {
+		if c >= 32 && c <= 126 {
+			units += f.widths[c-32]
+		} else {
+			units += 556 // Close enough for the accented letters and symbols above ASCII
+		}
+	}
+	return float64(units) * size / 1000
+}
+
+// winAnsiSymbols maps the characters WinAnsiEncoding places in 0x80-0x9F, where Latin-1 has
+// control codes
+var winAnsiSymbols = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87, 'ˆ': 0x88,
+	'‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E, '‘': 0x91, '’': 0x92, '“': 0x93,
+	'”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B,
+	'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// encode converts text to WinAnsiEncoding, the character set of the standard fonts. Tabs
+// become spaces, other control characters are dropped and characters the fonts don't have
+// become '?'.
+func encode(text string) []byte {
+	out := make([]byte, 0, len(text))
+	for _, r := range strings.ReplaceAll(text, "\r\n", "\n") {
+		switch {
+		case r == '\n':
+			out = append(out, '\n')
+		case r == '\t':
+			out = append(out, ' ')
+		case r < 32 || r == 127 || (r >= 0x80 && r < 0xA0):
+			// Control characters
+		case r < 256:
+			out = append(out, byte(r))
+		default:
+			if c, ok := winAnsiSymbols[r]; ok {
+				out = append(out, c)
+			} else {
+				out = append(out, '?')
+			}
+		}
+	}
+	return out
+}
+
+// escape makes encoded text safe inside a PDF literal string
+func escape(text []byte) string {
+	var b strings.Builder
+	for _, c := range text {
+		switch c {
+		case '\\', '(', ')':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n':
+			b.WriteByte(' ')
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// helveticaWidths are the advance widths of the printable ASCII characters (32 to 126) in
+// Helvetica, in thousandths of the font size, from the font's AFM metrics
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278, // space to /
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556, // 0 to ?
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778, // @ to O
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556, // P to _
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556, // ` to o
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584, // p to ~
+}
+
+// helveticaBoldWidths are the same for Helvetica-Bold
+var helveticaBoldWidths = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278, // space to /
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611, // 0 to ?
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778, // @ to O
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556, // P to _
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611, // ` to o
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584, // p to ~
+}
@@ -0,0 +1,338 @@
+// Package query parses list-endpoint query strings (pagination, whitelisted filters,
+// sorting and ranges) into MongoDB-style filters and find options, so every list
+// endpoint accepts the same parameter conventions. Non-Mongo repositories translate
+// the filter documents themselves.
+package query
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/OsGift/taskflow-api/internal/apperror"
+)
+
+const (
+	defaultLimit int64 = 10
+	maxLimit     int64 = 100
+)
+
+// Kind determines how a filter parameter is parsed and matched
+type Kind int
+
+const (
+	Exact     Kind = iota // Equality on the raw string
+	Enum                  // Equality, restricted to Values (matched case-insensitively)
+	ObjectID              // Equality on a hex ObjectID
+	Contains              // Case-insensitive substring match
+	Int                   // Equality on an integer
+	Bool                  // Equality on a boolean
+	TimeRange             // <param>_from / <param>_to bounds (RFC 3339 or YYYY-MM-DD)
+	IntRange              // <param>_min / <param>_max bounds
+)
+
+// Filter whitelists one query parameter
+type Filter struct {
+	Param  string // Query parameter name (prefix for range kinds)
+	Field  string // Document field; defaults to Param
+	Kind   Kind
+	Values []string // Allowed values for Enum, in their stored form
+}
+
+// Spec describes what a list endpoint accepts
+type Spec struct {
+	Filters     []Filter
+	Sorts       []string // Fields allowed in ?sort=
+	DefaultSort string   // e.g. "-created_at"
+	// Fields are the response fields ?fields= may select; without any, ?fields= is ignored
+	Fields []string
+	// FieldSources names the document fields a response field is built from, for those that
+	// aren't stored under their own name (e.g. a role name built from role_id)
+	FieldSources map[string][]string
+}
+
+// Query is a parsed list request
+type Query struct {
+	Filter primitive.M
+	Sort   bson.D
+	Page   int64
+	Limit  int64
+	// Offset skips that many more documents before the page, for listings stitched together
+	// from several queries
+	Offset int64
+	// EstimateCount asks for an approximate total count, from ?count_mode=estimated, for
+	// listings too large to count exactly on every page
+	EstimateCount bool
+	// Fields are the response fields selected with ?fields=, which always include the ID;
+	// empty selects all
+	Fields []string
+	// Projection limits the document fields read to those Fields are built from; nil reads all
+	Projection primitive.M
+}
+
+// DefaultSort orders results newest first; used when a Query has no explicit sort
+var DefaultSort = bson.D{{Key: "created_at", Value: -1}}
+
+// New creates a Query for callers that build filters themselves (e.g. the gRPC API)
+func New(filter primitive.M, page, limit int64) *Query {
+	if filter == nil {
+		filter = primitive.M{}
+	}
+	q := &Query{Filter: filter, Page: page, Limit: limit}
+	q.normalizePaging()
+	return q
+}
+
+// Parse validates values against the spec. Unknown parameters are ignored so endpoints
+// can read extra parameters themselves; malformed or non-whitelisted values are rejected
+// with an invalid_argument error.
+func (s Spec) Parse(values url.Values) (*Query, error) {
+	q := &Query{Filter: primitive.M{}}
+
+	// Pagination falls back to defaults rather than failing, as the API always has
+	q.Page, _ = strconv.ParseInt(values.Get("page"), 10, 64)
+	q.Limit, _ = strconv.ParseInt(values.Get("limit"), 10, 64)
+	q.normalizePaging()
+
+	switch values.Get("count_mode") {
+	case "", "exact":
+	case "estimated":
+		q.EstimateCount = true
+	default:
+		return nil, invalid("count_mode must be exact or estimated")
+	}
+
+	for _, f := range s.Filters {
+		if err := f.apply(values, q.Filter); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.parseFields(values.Get("fields"), q); err != nil {
+		return nil, err
+	}
+
+	sortParam := values.Get("sort")
+	if sortParam == "" {
+		sortParam = s.DefaultSort
+	}
+	sort, err := s.parseSort(sortParam)
+	if err != nil {
+		return nil, err
+	}
+	q.Sort = sort
+	return q, nil
+}
+
+// Skip returns the number of documents before the requested page
+func (q *Query) Skip() int64 {
+	return (q.Page-1)*q.Limit + q.Offset
+}
+
+// SortFields returns the effective sort order. _id is appended as a tiebreaker so
+// pages are stable when sort values repeat.
+func (q *Query) SortFields() bson.D {
+	sort := q.Sort
+	if len(sort) == 0 {
+		sort = DefaultSort
+	}
+	sort = append(bson.D{}, sort...)
+	if sort[len(sort)-1].Key != "_id" {
+		sort = append(sort, bson.E{Key: "_id", Value: sort[len(sort)-1].Value})
+	}
+	return sort
+}
+
+// FindOptions returns skip/limit/sort options for the query, and its projection if any
+func (q *Query) FindOptions() *options.FindOptions {
+	return q.FindAllOptions().SetSkip(q.Skip()).SetLimit(q.Limit)
+}
+
+// FindAllOptions returns the sort and projection options for every match of the query,
+// ignoring its paging
+func (q *Query) FindAllOptions() *options.FindOptions {
+	opts := options.Find().SetSort(q.SortFields())
+	if q.Projection != nil {
+		opts.SetProjection(q.Projection)
+	}
+	return opts
+}
+
+// normalizePaging applies the default and maximum page size
+func (q *Query) normalizePaging() {
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.Limit < 1 || q.Limit > maxLimit {
+		q.Limit = defaultLimit
+	}
+}
+
+// parseSort turns "-created_at,title" into a sort document, rejecting fields not in Sorts
+func (s Spec) parseSort(param string) (bson.D, error) {
+	var sort bson.D
+	for _, part := range strings.Split(param, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		direction := 1
+		if strings.HasPrefix(part, "-") {
+			direction = -1
+			part = part[1:]
+		}
+		if !contains(s.Sorts, part) {
+			return nil, invalid("cannot sort by %q; allowed: %s", part, strings.Join(s.Sorts, ", "))
+		}
+		sort = append(sort, bson.E{Key: part, Value: direction})
+	}
+	return sort, nil
+}
+
+// parseFields turns "title,status" into the query's Fields and Projection, rejecting fields
+// not in Fields
+func (s Spec) parseFields(param string, q *Query) error {
+	if param == "" || len(s.Fields) == 0 {
+		return nil
+	}
+	projection := primitive.M{"_id": 1}
+	for _, part := range strings.Split(param, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if part == "id" {
+			q.Fields = append(q.Fields, part)
+			continue
+		}
+		if !contains(s.Fields, part) {
+			return invalid("cannot select field %q; allowed: %s", part, strings.Join(s.Fields, ", "))
+		}
+		q.Fields = append(q.Fields, part)
+		sources, ok := s.FieldSources[part]
+		if !ok {
+			sources = []string{part}
+		}
+		for _, source := range sources {
+			projection[source] = 1
+		}
+	}
+	if len(q.Fields) > 0 {
+		q.Projection = projection
+	}
+	return nil
+}
+
+// apply adds the filter's condition to filter if its parameter(s) are present
+func (f Filter) apply(values url.Values, filter primitive.M) error {
+	field := f.Field
+	if field == "" {
+		field = f.Param
+	}
+
+	switch f.Kind {
+	case TimeRange:
+		bounds := primitive.M{}
+		for suffix, operator := range map[string]string{"_from": "$gte", "_to": "$lte"} {
+			raw := values.Get(f.Param + suffix)
+			if raw == "" {
+				continue
+			}
+			t, err := parseTime(raw)
+			if err != nil {
+				return invalid("%s%s must be an RFC 3339 time or YYYY-MM-DD date", f.Param, suffix)
+			}
+			bounds[operator] = t
+		}
+		if len(bounds) > 0 {
+			filter[field] = bounds
+		}
+		return nil
+
+	case IntRange:
+		bounds := primitive.M{}
+		for suffix, operator := range map[string]string{"_min": "$gte", "_max": "$lte"} {
+			raw := values.Get(f.Param + suffix)
+			if raw == "" {
+				continue
+			}
+			n, err := strconv.Atoi(raw)
+			if err != nil {
+				return invalid("%s%s must be an integer", f.Param, suffix)
+			}
+			bounds[operator] = n
+		}
+		if len(bounds) > 0 {
+			filter[field] = bounds
+		}
+		return nil
+	}
+
+	raw := strings.TrimSpace(values.Get(f.Param))
+	if raw == "" {
+		return nil
+	}
+
+	switch f.Kind {
+	case Exact:
+		filter[field] = raw
+	case Enum:
+		for _, allowed := range f.Values {
+			if strings.EqualFold(raw, allowed) {
+				filter[field] = allowed
+				return nil
+			}
+		}
+		return invalid("invalid %s filter; must be one of: %s", f.Param, strings.Join(f.Values, ", "))
+	case ObjectID:
+		id, err := primitive.ObjectIDFromHex(raw)
+		if err != nil {
+			return invalid("invalid %s filter format", f.Param)
+		}
+		filter[field] = id
+	case Contains:
+		filter[field] = primitive.Regex{Pattern: regexp.QuoteMeta(raw), Options: "i"}
+	case Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return invalid("%s must be an integer", f.Param)
+		}
+		filter[field] = n
+	case Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return invalid("%s must be true or false", f.Param)
+		}
+		filter[field] = b
+	}
+	return nil
+}
+
+// parseTime accepts RFC 3339 timestamps or plain dates (interpreted as UTC midnight)
+func parseTime(raw string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", raw)
+}
+
+// invalid builds an invalid_argument error
+func invalid(format string, args ...interface{}) error {
+	return apperror.New(apperror.CodeInvalidArgument, fmt.Sprintf(format, args...))
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
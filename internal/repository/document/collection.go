@@ -0,0 +1,516 @@
+package document
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Query selects documents for a Storage
+type Query struct {
+	Filter bson.M
+	Sort   []SortKey
+	Skip   int64
+	Limit  int64 // 0 for no limit
+	// ForUpdate locks the documents loaded until the Storage.Atomic call it is made in returns
+	ForUpdate bool
+}
+
+// Storage keeps the documents of one collection for a Collection
+type Storage interface {
+	// Load returns the documents matching q. It may return more, or ignore q's order and
+	// paging, as long as it reports so by returning false; the Collection then filters,
+	// sorts and pages them itself.
+	Load(ctx context.Context, q Query) (docs []bson.M, exact bool, err error)
+	// Count counts the documents matching filter, or returns false when it can't without the
+	// Collection filtering them
+	Count(ctx context.Context, filter bson.M) (count int64, exact bool, err error)
+	// Insert adds doc, failing with DuplicateKeyError when its _id or the value of a unique
+	// index is taken
+	Insert(ctx context.Context, doc bson.M) error
+	// Replace overwrites the document with the _id of doc, failing like Insert
+	Replace(ctx context.Context, doc bson.M) error
+	// Delete removes the documents with the given _ids
+	Delete(ctx context.Context, ids []interface{}) error
+	// DeleteMatching removes the documents matching filter, or returns false without deleting
+	// any when it can't without the Collection filtering them
+	DeleteMatching(ctx context.Context, filter bson.M) (deleted int64, exact bool, err error)
+	// Atomic runs fn so that the documents it loads for update can't change until it returns,
+	// and its writes are undone when it fails. fn must use the context it is given.
+	Atomic(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// DuplicateKeyError returns the error MongoDB gives for a write that would duplicate the
+// value of a unique index, so mongo.IsDuplicateKeyError recognizes it
+func DuplicateKeyError(index string) error {
+	return mongo.WriteException{WriteErrors: mongo.WriteErrors{{
+		Code:    11000,
+		Message: "E11000 duplicate key error index: " + index,
+	}}}
+}
+
+// Collection emulates a *mongo.Collection over a Storage, evaluating queries and updates
+// with this package. It implements repository.Collection.
+type Collection struct {
+	storage Storage
+}
+
+// NewCollection returns the Collection of the documents in storage
+func NewCollection(storage Storage) *Collection {
+	return &Collection{storage: storage}
+}
+
+// find returns the documents matching q, filtering, sorting and paging them when storage
+// couldn't
+func (c *Collection) find(ctx context.Context, q Query) ([]bson.M, error) {
+	docs, exact, err := c.storage.Load(ctx, q)
+	if err != nil || exact {
+		return docs, err
+	}
+
+	matching := docs[:0]
+	for _, doc := range docs {
+		matched, err := Match(doc, q.Filter)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			matching = append(matching, doc)
+		}
+	}
+	Sort(matching, q.Sort)
+	if q.Skip > 0 {
+		matching = matching[min(q.Skip, int64(len(matching))):]
+	}
+	if q.Limit > 0 && int64(len(matching)) > q.Limit {
+		matching = matching[:q.Limit]
+	}
+	return matching, nil
+}
+
+// findFirst returns the first document matching filter in sortOrder, or nil
+func (c *Collection) findFirst(ctx context.Context, filter bson.M, sortOrder interface{}, skip int64, forUpdate bool) (bson.M, error) {
+	keys, err := ParseSort(sortOrder)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := c.find(ctx, Query{Filter: filter, Sort: keys, Skip: skip, Limit: 1, ForUpdate: forUpdate})
+	if err != nil || len(docs) == 0 {
+		return nil, err
+	}
+	return docs[0], nil
+}
+
+// singleResult returns doc, projected, as the result of a single-document operation
+func singleResult(doc bson.M, projection interface{}, err error) *mongo.SingleResult {
+	if err != nil {
+		return mongo.NewSingleResultFromDocument(bson.D{}, err, nil)
+	}
+	if doc == nil {
+		return mongo.NewSingleResultFromDocument(bson.D{}, mongo.ErrNoDocuments, nil)
+	}
+	fields, err := Normalize(projection)
+	if err == nil {
+		doc, err = Project(doc, fields)
+	}
+	if err != nil {
+		return mongo.NewSingleResultFromDocument(bson.D{}, err, nil)
+	}
+	return mongo.NewSingleResultFromDocument(doc, nil, nil)
+}
+
+// InsertOne implements repository.Collection
+func (c *Collection) InsertOne(ctx context.Context, document interface{}, opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error) {
+	doc, err := Normalize(document)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := doc["_id"]; !ok {
+		doc["_id"] = primitive.NewObjectID()
+	}
+	if err := c.storage.Insert(ctx, doc); err != nil {
+		return nil, err
+	}
+	return &mongo.InsertOneResult{InsertedID: doc["_id"]}, nil
+}
+
+// FindOne implements repository.Collection
+func (c *Collection) FindOne(ctx context.Context, filter interface{}, opts ...*options.FindOneOptions) *mongo.SingleResult {
+	o := options.MergeFindOneOptions(opts...)
+	query, err := Normalize(filter)
+	if err != nil {
+		return singleResult(nil, nil, err)
+	}
+	var skip int64
+	if o.Skip != nil {
+		skip = *o.Skip
+	}
+	doc, err := c.findFirst(ctx, query, o.Sort, skip, false)
+	return singleResult(doc, o.Projection, err)
+}
+
+// Find implements repository.Collection
+func (c *Collection) Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*mongo.Cursor, error) {
+	o := options.MergeFindOptions(opts...)
+	q := Query{}
+	var err error
+	if q.Filter, err = Normalize(filter); err != nil {
+		return nil, err
+	}
+	if q.Sort, err = ParseSort(o.Sort); err != nil {
+		return nil, err
+	}
+	if o.Skip != nil {
+		q.Skip = *o.Skip
+	}
+	if o.Limit != nil {
+		q.Limit = max(*o.Limit, -*o.Limit)
+	}
+	docs, err := c.find(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	projection, err := Normalize(o.Projection)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]interface{}, len(docs))
+	for i, doc := range docs {
+		if results[i], err = Project(doc, projection); err != nil {
+			return nil, err
+		}
+	}
+	return mongo.NewCursorFromDocuments(results, nil, nil)
+}
+
+// CountDocuments implements repository.Collection
+func (c *Collection) CountDocuments(ctx context.Context, filter interface{}, opts ...*options.CountOptions) (int64, error) {
+	o := options.MergeCountOptions(opts...)
+	query, err := Normalize(filter)
+	if err != nil {
+		return 0, err
+	}
+	if o.Skip == nil && o.Limit == nil {
+		count, exact, err := c.storage.Count(ctx, query)
+		if err != nil || exact {
+			return count, err
+		}
+	}
+	q := Query{Filter: query}
+	if o.Skip != nil {
+		q.Skip = *o.Skip
+	}
+	if o.Limit != nil {
+		q.Limit = *o.Limit
+	}
+	docs, err := c.find(ctx, q)
+	return int64(len(docs)), err
+}
+
+// Distinct implements repository.Collection
+func (c *Collection) Distinct(ctx context.Context, fieldName string, filter interface{}, opts ...*options.DistinctOptions) ([]interface{}, error) {
+	query, err := Normalize(filter)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := c.find(ctx, Query{Filter: query})
+	if err != nil {
+		return nil, err
+	}
+	return Values(docs, fieldName), nil
+}
+
+// UpdateOne implements repository.Collection
+func (c *Collection) UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	return c.update(ctx, filter, update, upsert(options.MergeUpdateOptions(opts...).Upsert), false)
+}
+
+// UpdateByID implements repository.Collection
+func (c *Collection) UpdateByID(ctx context.Context, id interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	return c.UpdateOne(ctx, bson.D{{Key: "_id", Value: id}}, update, opts...)
+}
+
+// UpdateMany implements repository.Collection
+func (c *Collection) UpdateMany(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	return c.update(ctx, filter, update, upsert(options.MergeUpdateOptions(opts...).Upsert), true)
+}
+
+// upsert returns the value of an Upsert option
+func upsert(option *bool) bool {
+	return option != nil && *option
+}
+
+// update applies update to the first document matching filter, or all of them with many,
+// inserting one when none does and upserting is set
+func (c *Collection) update(ctx context.Context, filter, update interface{}, upserting, many bool) (*mongo.UpdateResult, error) {
+	query, err := Normalize(filter)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := Normalize(update)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &mongo.UpdateResult{}
+	err = c.storage.Atomic(ctx, func(ctx context.Context) error {
+		q := Query{Filter: query, ForUpdate: true}
+		if !many {
+			q.Limit = 1
+		}
+		docs, err := c.find(ctx, q)
+		if err != nil {
+			return err
+		}
+		if len(docs) == 0 && upserting {
+			doc, err := c.upsert(ctx, query, changes)
+			if err != nil {
+				return err
+			}
+			result.UpsertedCount, result.UpsertedID = 1, doc["_id"]
+			return nil
+		}
+		for _, doc := range docs {
+			modified, err := c.modify(ctx, doc, changes, query)
+			if err != nil {
+				return err
+			}
+			result.MatchedCount++
+			if modified != nil {
+				result.ModifiedCount++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// modify applies the update operators of changes to doc and stores it, returning the
+// updated document, or nil when the update left it unchanged
+func (c *Collection) modify(ctx context.Context, doc, changes, filter bson.M) (bson.M, error) {
+	updated := Clone(doc)
+	if err := Apply(updated, changes, filter, false); err != nil {
+		return nil, err
+	}
+	if !Equal(updated["_id"], doc["_id"]) {
+		return nil, fmt.Errorf("the _id field cannot be changed")
+	}
+	if Equal(updated, doc) {
+		return nil, nil
+	}
+	return updated, c.storage.Replace(ctx, updated)
+}
+
+// upsert inserts the document an update with upsert set creates when nothing matches filter
+func (c *Collection) upsert(ctx context.Context, filter, changes bson.M) (bson.M, error) {
+	doc, err := Seed(filter)
+	if err != nil {
+		return nil, err
+	}
+	if IsUpdate(changes) {
+		err = Apply(doc, changes, filter, true)
+	} else {
+		err = replaceFields(doc, changes)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := doc["_id"]; !ok {
+		doc["_id"] = primitive.NewObjectID()
+	}
+	return doc, c.storage.Insert(ctx, doc)
+}
+
+// replaceFields replaces the fields of doc but _id with those of replacement
+func replaceFields(doc, replacement bson.M) error {
+	if IsOperatorDocument(replacement) {
+		return fmt.Errorf("replacement document cannot contain update operators")
+	}
+	if id, ok := replacement["_id"]; ok && doc["_id"] != nil && !Equal(id, doc["_id"]) {
+		return fmt.Errorf("the _id field cannot be changed")
+	}
+	for field := range doc {
+		if field != "_id" {
+			delete(doc, field)
+		}
+	}
+	for field, value := range replacement {
+		doc[field] = cloneValue(value)
+	}
+	return nil
+}
+
+// ReplaceOne implements repository.Collection
+func (c *Collection) ReplaceOne(ctx context.Context, filter interface{}, replacement interface{}, opts ...*options.ReplaceOptions) (*mongo.UpdateResult, error) {
+	query, err := Normalize(filter)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := Normalize(replacement)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &mongo.UpdateResult{}
+	err = c.storage.Atomic(ctx, func(ctx context.Context) error {
+		doc, err := c.findFirst(ctx, query, nil, 0, true)
+		if err != nil {
+			return err
+		}
+		if doc == nil {
+			if !upsert(options.MergeReplaceOptions(opts...).Upsert) {
+				return nil
+			}
+			inserted, err := c.upsert(ctx, query, fields)
+			if err != nil {
+				return err
+			}
+			result.UpsertedCount, result.UpsertedID = 1, inserted["_id"]
+			return nil
+		}
+
+		replaced := Clone(doc)
+		if err := replaceFields(replaced, fields); err != nil {
+			return err
+		}
+		result.MatchedCount = 1
+		if Equal(replaced, doc) {
+			return nil
+		}
+		result.ModifiedCount = 1
+		return c.storage.Replace(ctx, replaced)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// FindOneAndUpdate implements repository.Collection
+func (c *Collection) FindOneAndUpdate(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) *mongo.SingleResult {
+	o := options.MergeFindOneAndUpdateOptions(opts...)
+	query, err := Normalize(filter)
+	if err != nil {
+		return singleResult(nil, nil, err)
+	}
+	changes, err := Normalize(update)
+	if err != nil {
+		return singleResult(nil, nil, err)
+	}
+	returnAfter := o.ReturnDocument != nil && *o.ReturnDocument == options.After
+
+	var result bson.M
+	err = c.storage.Atomic(ctx, func(ctx context.Context) error {
+		doc, err := c.findFirst(ctx, query, o.Sort, 0, true)
+		if err != nil {
+			return err
+		}
+		if doc == nil {
+			if !upsert(o.Upsert) {
+				return nil
+			}
+			inserted, err := c.upsert(ctx, query, changes)
+			if err == nil && returnAfter {
+				result = inserted
+			}
+			return err
+		}
+
+		modified, err := c.modify(ctx, doc, changes, query)
+		switch {
+		case err != nil:
+			return err
+		case !returnAfter:
+			result = doc
+		case modified != nil:
+			result = modified
+		default:
+			result = doc
+		}
+		return nil
+	})
+	return singleResult(result, o.Projection, err)
+}
+
+// FindOneAndDelete implements repository.Collection
+func (c *Collection) FindOneAndDelete(ctx context.Context, filter interface{}, opts ...*options.FindOneAndDeleteOptions) *mongo.SingleResult {
+	o := options.MergeFindOneAndDeleteOptions(opts...)
+	query, err := Normalize(filter)
+	if err != nil {
+		return singleResult(nil, nil, err)
+	}
+
+	var deleted bson.M
+	err = c.storage.Atomic(ctx, func(ctx context.Context) error {
+		doc, err := c.findFirst(ctx, query, o.Sort, 0, true)
+		if err != nil || doc == nil {
+			return err
+		}
+		deleted = doc
+		return c.storage.Delete(ctx, []interface{}{doc["_id"]})
+	})
+	return singleResult(deleted, o.Projection, err)
+}
+
+// DeleteOne implements repository.Collection
+func (c *Collection) DeleteOne(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+	query, err := Normalize(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &mongo.DeleteResult{}
+	err = c.storage.Atomic(ctx, func(ctx context.Context) error {
+		doc, err := c.findFirst(ctx, query, nil, 0, true)
+		if err != nil || doc == nil {
+			return err
+		}
+		result.DeletedCount = 1
+		return c.storage.Delete(ctx, []interface{}{doc["_id"]})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DeleteMany implements repository.Collection
+func (c *Collection) DeleteMany(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+	query, err := Normalize(filter)
+	if err != nil {
+		return nil, err
+	}
+	deleted, exact, err := c.storage.DeleteMatching(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if exact {
+		return &mongo.DeleteResult{DeletedCount: deleted}, nil
+	}
+
+	result := &mongo.DeleteResult{}
+	err = c.storage.Atomic(ctx, func(ctx context.Context) error {
+		docs, err := c.find(ctx, Query{Filter: query, ForUpdate: true})
+		if err != nil || len(docs) == 0 {
+			return err
+		}
+		ids := make([]interface{}, len(docs))
+		for i, doc := range docs {
+			ids[i] = doc["_id"]
+		}
+		result.DeletedCount = int64(len(ids))
+		return c.storage.Delete(ctx, ids)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,314 @@
+// Package document evaluates MongoDB filters, updates, sorts and projections on documents
+// held in memory, for the backends that emulate MongoDB collections (pgstore and memstore).
+// It covers the operators the services use; any other operator is reported as an error
+// rather than ignored.
+//
+// Documents are normalized by Normalize: embedded documents are bson.M, arrays bson.A and
+// other values keep the Go type the driver decodes their BSON type into
+// (primitive.ObjectID, primitive.DateTime, int32, int64, float64, string...).
+package document
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Normalize converts v, anything the driver encodes as a document (a struct, bson.M, bson.D...),
+// into a normalized document with the field names and types MongoDB would store. A nil v
+// gives an empty document.
+func Normalize(v interface{}) (bson.M, error) {
+	if v == nil {
+		return bson.M{}, nil
+	}
+	if raw, ok := v.(bson.Raw); ok {
+		return FromRaw(raw)
+	}
+	raw, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return FromRaw(raw)
+}
+
+// FromRaw decodes a BSON document into a normalized document
+func FromRaw(raw bson.Raw) (bson.M, error) {
+	elements, err := raw.Elements()
+	if err != nil {
+		return nil, err
+	}
+	doc := make(bson.M, len(elements))
+	for _, element := range elements {
+		value, err := fromRawValue(element.Value())
+		if err != nil {
+			return nil, err
+		}
+		doc[element.Key()] = value
+	}
+	return doc, nil
+}
+
+// fromRawValue decodes a BSON value into its normalized form
+func fromRawValue(raw bson.RawValue) (interface{}, error) {
+	switch raw.Type {
+	case bsontype.EmbeddedDocument:
+		return FromRaw(raw.Document())
+	case bsontype.Array:
+		values, err := raw.Array().Values()
+		if err != nil {
+			return nil, err
+		}
+		array := make(bson.A, len(values))
+		for i, value := range values {
+			if array[i], err = fromRawValue(value); err != nil {
+				return nil, err
+			}
+		}
+		return array, nil
+	}
+	var value interface{}
+	if err := raw.Unmarshal(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Clone returns a deep copy of the normalized document doc
+func Clone(doc bson.M) bson.M {
+	return cloneValue(doc).(bson.M)
+}
+
+// cloneValue returns a deep copy of a normalized value
+func cloneValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case bson.M:
+		clone := make(bson.M, len(t))
+		for key, value := range t {
+			clone[key] = cloneValue(value)
+		}
+		return clone
+	case bson.A:
+		clone := make(bson.A, len(t))
+		for i, value := range t {
+			clone[i] = cloneValue(value)
+		}
+		return clone
+	}
+	return v
+}
+
+// typeOrder ranks normalized values by type in MongoDB's comparison order: null, numbers,
+// strings, documents, arrays, binary data, ObjectIDs, booleans, dates, timestamps, regular
+// expressions
+func typeOrder(v interface{}) int {
+	switch v.(type) {
+	case primitive.MinKey:
+		return 0
+	case nil, primitive.Null, primitive.Undefined:
+		return 1
+	case int32, int64, float64, primitive.Decimal128:
+		return 2
+	case string, primitive.Symbol:
+		return 3
+	case bson.M:
+		return 4
+	case bson.A:
+		return 5
+	case primitive.Binary:
+		return 6
+	case primitive.ObjectID:
+		return 7
+	case bool:
+		return 8
+	case primitive.DateTime:
+		return 9
+	case primitive.Timestamp:
+		return 10
+	case primitive.Regex:
+		return 11
+	case primitive.MaxKey:
+		return 13
+	}
+	return 12
+}
+
+// Compare orders two normalized values the way MongoDB sorts them, returning a negative
+// number when a sorts first, 0 when they are equal and a positive one otherwise
+func Compare(a, b interface{}) int {
+	if ta, tb := typeOrder(a), typeOrder(b); ta != tb {
+		return ta - tb
+	}
+	switch a := a.(type) {
+	case int32, int64, float64, primitive.Decimal128:
+		return compareNumbers(a, b)
+	case string:
+		return strings.Compare(a, stringValue(b))
+	case primitive.Symbol:
+		return strings.Compare(string(a), stringValue(b))
+	case bson.M:
+		return compareDocuments(a, b.(bson.M))
+	case bson.A:
+		b := b.(bson.A)
+		for i := 0; i < len(a) && i < len(b); i++ {
+			if c := Compare(a[i], b[i]); c != 0 {
+				return c
+			}
+		}
+		return len(a) - len(b)
+	case primitive.Binary:
+		b := b.(primitive.Binary)
+		if len(a.Data) != len(b.Data) {
+			return len(a.Data) - len(b.Data)
+		}
+		if a.Subtype != b.Subtype {
+			return int(a.Subtype) - int(b.Subtype)
+		}
+		return bytes.Compare(a.Data, b.Data)
+	case primitive.ObjectID:
+		b := b.(primitive.ObjectID)
+		return bytes.Compare(a[:], b[:])
+	case bool:
+		switch b := b.(bool); {
+		case a == b:
+			return 0
+		case a:
+			return 1
+		}
+		return -1
+	case primitive.DateTime:
+		return compareInts(int64(a), int64(b.(primitive.DateTime)))
+	case primitive.Timestamp:
+		return primitive.CompareTimestamp(a, b.(primitive.Timestamp))
+	case primitive.Regex:
+		b := b.(primitive.Regex)
+		if c := strings.Compare(a.Pattern, b.Pattern); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Options, b.Options)
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// Equal reports whether two normalized values are equal in MongoDB's eyes, where numbers of
+// different types are equal when their values are
+func Equal(a, b interface{}) bool {
+	return Compare(a, b) == 0
+}
+
+// stringValue returns the text of a string or symbol
+func stringValue(v interface{}) string {
+	if symbol, ok := v.(primitive.Symbol); ok {
+		return string(symbol)
+	}
+	return v.(string)
+}
+
+// compareDocuments orders documents by their fields in key order, then by their number of fields
+func compareDocuments(a, b bson.M) int {
+	keysA, keysB := sortedKeys(a), sortedKeys(b)
+	for i := 0; i < len(keysA) && i < len(keysB); i++ {
+		if c := strings.Compare(keysA[i], keysB[i]); c != 0 {
+			return c
+		}
+		if c := Compare(a[keysA[i]], b[keysB[i]]); c != 0 {
+			return c
+		}
+	}
+	return len(keysA) - len(keysB)
+}
+
+// sortedKeys returns the keys of doc in ascending order
+func sortedKeys(doc bson.M) []string {
+	keys := make([]string, 0, len(doc))
+	for key := range doc {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// compareNumbers orders two numbers of any numeric type, exactly for integers
+func compareNumbers(a, b interface{}) int {
+	intA, aIsInt := integer(a)
+	intB, bIsInt := integer(b)
+	if aIsInt && bIsInt {
+		return compareInts(intA, intB)
+	}
+	floatA, floatB := Float(a), Float(b)
+	switch {
+	case floatA < floatB:
+		return -1
+	case floatA > floatB:
+		return 1
+	case floatA == floatB:
+		return 0
+	case math.IsNaN(floatA) && math.IsNaN(floatB):
+		return 0
+	case math.IsNaN(floatA): // NaN sorts before every other number
+		return -1
+	}
+	return 1
+}
+
+// compareInts orders two int64s
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// integer returns the value of an int32 or int64
+func integer(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	}
+	return 0, false
+}
+
+// Float returns the value of a normalized number as a float64, or NaN for other values
+func Float(v interface{}) float64 {
+	switch n := v.(type) {
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	case primitive.Decimal128:
+		f, err := parseDecimal(n)
+		if err == nil {
+			return f
+		}
+	}
+	return math.NaN()
+}
+
+// parseDecimal converts a Decimal128 to the nearest float64
+func parseDecimal(d primitive.Decimal128) (float64, error) {
+	var f float64
+	_, err := fmt.Sscan(d.String(), &f)
+	return f, err
+}
+
+// IsNumber reports whether v is a normalized number
+func IsNumber(v interface{}) bool {
+	return typeOrder(v) == 2
+}
+
+// splitPath splits a dotted field path into its components
+func splitPath(path string) []string {
+	return strings.Split(path, ".")
+}
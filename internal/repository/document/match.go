@@ -0,0 +1,353 @@
+package document
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Match reports whether the normalized document doc matches the normalized filter
+func Match(doc, filter bson.M) (bool, error) {
+	for key, condition := range filter {
+		var matched bool
+		var err error
+		switch key {
+		case "$and", "$or", "$nor":
+			matched, err = matchLogical(doc, key, condition)
+		case "$comment":
+			matched = true
+		default:
+			if strings.HasPrefix(key, "$") {
+				return false, fmt.Errorf("unsupported query operator %s", key)
+			}
+			matched, err = matchField(doc, key, condition)
+		}
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// matchLogical evaluates $and, $or or $nor over the filters in clauses
+func matchLogical(doc bson.M, operator string, clauses interface{}) (bool, error) {
+	filters, ok := clauses.(bson.A)
+	if !ok || len(filters) == 0 {
+		return false, fmt.Errorf("%s needs a non-empty array", operator)
+	}
+	for _, clause := range filters {
+		filter, ok := clause.(bson.M)
+		if !ok {
+			return false, fmt.Errorf("%s needs an array of documents", operator)
+		}
+		matched, err := Match(doc, filter)
+		if err != nil {
+			return false, err
+		}
+		switch {
+		case operator == "$and" && !matched:
+			return false, nil
+		case operator == "$or" && matched:
+			return true, nil
+		case operator == "$nor" && matched:
+			return false, nil
+		}
+	}
+	return operator != "$or", nil
+}
+
+// IsOperatorDocument reports whether condition holds query operators ({"$gt": 1}) rather than
+// being a value to compare with
+func IsOperatorDocument(condition interface{}) bool {
+	doc, ok := condition.(bson.M)
+	if !ok || len(doc) == 0 {
+		return false
+	}
+	for key := range doc {
+		if !strings.HasPrefix(key, "$") {
+			return false
+		}
+	}
+	return true
+}
+
+// matchField reports whether the field at path of doc satisfies condition
+func matchField(doc bson.M, path string, condition interface{}) (bool, error) {
+	var values []interface{}
+	lookup(doc, splitPath(path), &values)
+	if !IsOperatorDocument(condition) {
+		return matchEquality(values, condition)
+	}
+	return matchOperators(values, condition.(bson.M))
+}
+
+// lookup appends the values found at path in v to values. Arrays met on the way are
+// descended into: "a.b" finds the "b" field of every document in the array "a", and a
+// numeric component selects an element.
+func lookup(v interface{}, path []string, values *[]interface{}) {
+	if len(path) == 0 {
+		*values = append(*values, v)
+		return
+	}
+	switch t := v.(type) {
+	case bson.M:
+		if child, ok := t[path[0]]; ok {
+			lookup(child, path[1:], values)
+		}
+	case bson.A:
+		if i, err := strconv.Atoi(path[0]); err == nil && i >= 0 {
+			if i < len(t) {
+				lookup(t[i], path[1:], values)
+			}
+			return
+		}
+		for _, element := range t {
+			if _, ok := element.(bson.M); ok {
+				lookup(element, path, values)
+			}
+		}
+	}
+}
+
+// candidates returns the values a comparison is tried against: each value found, and the
+// elements of those that are arrays
+func candidates(values []interface{}) []interface{} {
+	var all []interface{}
+	for _, value := range values {
+		all = append(all, value)
+		if array, ok := value.(bson.A); ok {
+			all = append(all, array...)
+		}
+	}
+	return all
+}
+
+// matchEquality reports whether any value equals want, or matches it when it is a regular
+// expression. A null want also matches a missing field.
+func matchEquality(values []interface{}, want interface{}) (bool, error) {
+	if regex, ok := want.(primitive.Regex); ok {
+		return matchRegex(values, regex.Pattern, regex.Options)
+	}
+	if typeOrder(want) == 1 && len(values) == 0 {
+		return true, nil
+	}
+	for _, value := range candidates(values) {
+		if typeOrder(value) == typeOrder(want) && Equal(value, want) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// matchOperators reports whether values satisfy every operator of conditions
+func matchOperators(values []interface{}, conditions bson.M) (bool, error) {
+	if pattern, ok := conditions["$regex"]; ok {
+		options, _ := conditions["$options"].(string)
+		var matched bool
+		var err error
+		switch p := pattern.(type) {
+		case string:
+			matched, err = matchRegex(values, p, options)
+		case primitive.Regex:
+			matched, err = matchRegex(values, p.Pattern, p.Options+options)
+		default:
+			err = fmt.Errorf("$regex needs a string")
+		}
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+
+	for operator, operand := range conditions {
+		var matched bool
+		var err error
+		switch operator {
+		case "$regex", "$options":
+			continue
+		case "$eq":
+			matched, err = matchEquality(values, operand)
+		case "$ne":
+			matched, err = matchEquality(values, operand)
+			matched = !matched
+		case "$gt", "$gte", "$lt", "$lte":
+			matched = matchComparison(values, operator, operand)
+		case "$in", "$nin":
+			matched, err = matchIn(values, operator, operand)
+			if operator == "$nin" {
+				matched = !matched
+			}
+		case "$exists":
+			matched = (len(values) > 0) == truthy(operand)
+		case "$not":
+			if IsOperatorDocument(operand) {
+				matched, err = matchOperators(values, operand.(bson.M))
+			} else {
+				matched, err = matchEquality(values, operand)
+			}
+			matched = !matched
+		case "$size":
+			matched = matchSize(values, operand)
+		case "$all":
+			matched, err = matchAll(values, operand)
+		case "$elemMatch":
+			matched, err = matchElement(values, operand)
+		default:
+			return false, fmt.Errorf("unsupported query operator %s", operator)
+		}
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// matchComparison reports whether any value of the same type as operand compares with it as
+// operator requires
+func matchComparison(values []interface{}, operator string, operand interface{}) bool {
+	for _, value := range candidates(values) {
+		if typeOrder(value) != typeOrder(operand) {
+			continue
+		}
+		c := Compare(value, operand)
+		switch {
+		case operator == "$gt" && c > 0, operator == "$gte" && c >= 0,
+			operator == "$lt" && c < 0, operator == "$lte" && c <= 0:
+			return true
+		}
+	}
+	return false
+}
+
+// matchIn reports whether values equal any element of the array operand
+func matchIn(values []interface{}, operator string, operand interface{}) (bool, error) {
+	options, ok := operand.(bson.A)
+	if !ok {
+		return false, fmt.Errorf("%s needs an array", operator)
+	}
+	for _, option := range options {
+		matched, err := matchEquality(values, option)
+		if err != nil || matched {
+			return matched, err
+		}
+	}
+	return false, nil
+}
+
+// matchSize reports whether any value is an array of operand elements
+func matchSize(values []interface{}, operand interface{}) bool {
+	size, ok := integer(operand)
+	if !ok {
+		if f := Float(operand); f == float64(int64(f)) {
+			size, ok = int64(f), true
+		}
+	}
+	for _, value := range values {
+		if array, isArray := value.(bson.A); ok && isArray && int64(len(array)) == size {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAll reports whether values contain every element of the array operand
+func matchAll(values []interface{}, operand interface{}) (bool, error) {
+	wanted, ok := operand.(bson.A)
+	if !ok {
+		return false, fmt.Errorf("$all needs an array")
+	}
+	for _, want := range wanted {
+		matched, err := matchEquality(values, want)
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+	return len(wanted) > 0, nil
+}
+
+// matchElement reports whether an element of an array value matches operand: a filter for
+// document elements, or operators for the element itself
+func matchElement(values []interface{}, operand interface{}) (bool, error) {
+	condition, ok := operand.(bson.M)
+	if !ok {
+		return false, fmt.Errorf("$elemMatch needs a document")
+	}
+	for _, value := range values {
+		array, ok := value.(bson.A)
+		if !ok {
+			continue
+		}
+		for _, element := range array {
+			matched, err := matchArrayElement(element, condition)
+			if err != nil || matched {
+				return matched, err
+			}
+		}
+	}
+	return false, nil
+}
+
+// matchArrayElement reports whether one array element satisfies condition, as in
+// $elemMatch and $pull
+func matchArrayElement(element interface{}, condition bson.M) (bool, error) {
+	if IsOperatorDocument(condition) {
+		if _, logical := condition["$and"]; !logical {
+			if _, logical := condition["$or"]; !logical {
+				return matchOperators([]interface{}{element}, condition)
+			}
+		}
+	}
+	doc, ok := element.(bson.M)
+	if !ok {
+		return false, nil
+	}
+	return Match(doc, condition)
+}
+
+// matchRegex reports whether any string value matches pattern
+func matchRegex(values []interface{}, pattern, options string) (bool, error) {
+	re, err := compileRegex(pattern, options)
+	if err != nil {
+		return false, err
+	}
+	for _, value := range candidates(values) {
+		if s, ok := value.(string); ok && re.MatchString(s) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// compileRegex compiles a MongoDB regular expression; only the i, m and s options are supported
+func compileRegex(pattern, options string) (*regexp.Regexp, error) {
+	flags := ""
+	for _, option := range options {
+		switch option {
+		case 'i', 'm', 's':
+			flags += string(option)
+		default:
+			return nil, fmt.Errorf("unsupported regular expression option %q", option)
+		}
+	}
+	if flags != "" {
+		pattern = "(?" + flags + ")" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+// truthy reports whether an operand such as $exists's counts as true
+func truthy(v interface{}) bool {
+	switch t := v.(type) {
+	case bool:
+		return t
+	case nil:
+		return false
+	}
+	if IsNumber(v) {
+		return Float(v) != 0
+	}
+	return true
+}
@@ -0,0 +1,156 @@
+package document
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// SortKey is one field of a sort order
+type SortKey struct {
+	Path       string
+	Descending bool
+}
+
+// ParseSort reads a sort order as given to the driver's options (bson.D, or bson.M for a
+// single field). A nil sort gives no keys.
+func ParseSort(sortOrder interface{}) ([]SortKey, error) {
+	if sortOrder == nil {
+		return nil, nil
+	}
+	raw, err := bson.Marshal(sortOrder)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sort: %w", err)
+	}
+	elements, err := bson.Raw(raw).Elements()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]SortKey, 0, len(elements))
+	for _, element := range elements {
+		direction, err := fromRawValue(element.Value())
+		if err != nil {
+			return nil, err
+		}
+		switch Float(direction) {
+		case 1:
+			keys = append(keys, SortKey{Path: element.Key()})
+		case -1:
+			keys = append(keys, SortKey{Path: element.Key(), Descending: true})
+		default:
+			return nil, fmt.Errorf("unsupported sort direction for %s", element.Key())
+		}
+	}
+	return keys, nil
+}
+
+// Sort orders docs by keys, stably
+func Sort(docs []bson.M, keys []SortKey) {
+	if len(keys) == 0 {
+		return
+	}
+	sort.SliceStable(docs, func(i, j int) bool {
+		for _, key := range keys {
+			c := Compare(sortValue(docs[i], key), sortValue(docs[j], key))
+			if key.Descending {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+}
+
+// sortValue returns the value doc sorts by for key: null when the field is missing and, for
+// arrays, their smallest element in ascending order and their largest in descending order
+func sortValue(doc bson.M, key SortKey) interface{} {
+	var values []interface{}
+	lookup(doc, splitPath(key.Path), &values)
+	var best interface{}
+	for i, value := range flatten(values) {
+		c := Compare(value, best)
+		if i == 0 || (key.Descending && c > 0) || (!key.Descending && c < 0) {
+			best = value
+		}
+	}
+	return best
+}
+
+// flatten replaces the arrays among values with their elements
+func flatten(values []interface{}) []interface{} {
+	var flat []interface{}
+	for _, value := range values {
+		if array, ok := value.(bson.A); ok && len(array) > 0 {
+			flat = append(flat, array...)
+		} else {
+			flat = append(flat, value)
+		}
+	}
+	return flat
+}
+
+// Values returns the distinct values of the field at path among docs, with arrays replaced by
+// their elements, as the distinct command does
+func Values(docs []bson.M, path string) []interface{} {
+	var distinct []interface{}
+	for _, doc := range docs {
+		var values []interface{}
+		lookup(doc, splitPath(path), &values)
+		for _, value := range flatten(values) {
+			if !containsValue(distinct, value) {
+				distinct = append(distinct, value)
+			}
+		}
+	}
+	return distinct
+}
+
+// Project returns the fields of doc the normalized projection selects: either the fields
+// set to 1 (and _id unless it is set to 0) or every field but those set to 0
+func Project(doc, projection bson.M) (bson.M, error) {
+	if len(projection) == 0 {
+		return doc, nil
+	}
+	inclusive := false
+	for field, value := range projection {
+		if field != "_id" && truthy(value) {
+			inclusive = true
+		}
+	}
+
+	if !inclusive {
+		projected := Clone(doc)
+		for field, value := range projection {
+			if !truthy(value) {
+				unsetPath(projected, splitPath(field))
+			}
+		}
+		return projected, nil
+	}
+
+	projected := bson.M{}
+	if value, ok := projection["_id"]; !ok || truthy(value) {
+		if id, ok := doc["_id"]; ok {
+			projected["_id"] = id
+		}
+	}
+	for field, value := range projection {
+		if field == "_id" || !truthy(value) {
+			continue
+		}
+		if strings.HasPrefix(field, "$") || IsOperatorDocument(value) {
+			return nil, fmt.Errorf("unsupported projection of %s", field)
+		}
+		path := splitPath(field)
+		if value, found := getPath(doc, path); found {
+			if err := setPath(projected, path, cloneValue(value)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return projected, nil
+}
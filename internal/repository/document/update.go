@@ -0,0 +1,361 @@
+package document
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// IsUpdate reports whether update is made of update operators ({"$set": ...}) rather than
+// being a replacement document
+func IsUpdate(update bson.M) bool {
+	return IsOperatorDocument(update)
+}
+
+// Apply applies the update operators of update to doc in place. filter is the filter that
+// selected doc, which resolves the positional operator ("members.$.role"); inserting reports
+// whether doc is being created by an upsert, which applies $setOnInsert.
+func Apply(doc, update, filter bson.M, inserting bool) error {
+	if !IsUpdate(update) {
+		return fmt.Errorf("update document must contain update operators")
+	}
+	for operator, operand := range update {
+		fields, ok := operand.(bson.M)
+		if !ok {
+			return fmt.Errorf("%s needs a document", operator)
+		}
+		for path, value := range fields {
+			if strings.Contains(path, ".$") {
+				resolved, err := resolvePositional(doc, path, filter)
+				if err != nil {
+					return err
+				}
+				path = resolved
+			}
+			if err := applyOperator(doc, operator, splitPath(path), value, inserting); err != nil {
+				return fmt.Errorf("%s %s: %w", operator, path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// applyOperator applies one update operator to the field at path
+func applyOperator(doc bson.M, operator string, path []string, value interface{}, inserting bool) error {
+	switch operator {
+	case "$set":
+		return setPath(doc, path, cloneValue(value))
+	case "$setOnInsert":
+		if inserting {
+			return setPath(doc, path, cloneValue(value))
+		}
+		return nil
+	case "$unset":
+		unsetPath(doc, path)
+		return nil
+	case "$inc":
+		current, found := getPath(doc, path)
+		if !found {
+			return setPath(doc, path, value)
+		}
+		sum, err := add(current, value)
+		if err != nil {
+			return err
+		}
+		return setPath(doc, path, sum)
+	case "$max", "$min":
+		current, found := getPath(doc, path)
+		if c := Compare(value, current); !found || (operator == "$max" && c > 0) || (operator == "$min" && c < 0) {
+			return setPath(doc, path, cloneValue(value))
+		}
+		return nil
+	case "$push", "$addToSet":
+		return pushPath(doc, path, value, operator == "$addToSet")
+	case "$pull":
+		return pullPath(doc, path, value)
+	}
+	return fmt.Errorf("unsupported update operator")
+}
+
+// getPath returns the value at path of doc, without descending into arrays except by index
+func getPath(doc bson.M, path []string) (interface{}, bool) {
+	var current interface{} = doc
+	for _, key := range path {
+		switch t := current.(type) {
+		case bson.M:
+			value, ok := t[key]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case bson.A:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(t) {
+				return nil, false
+			}
+			current = t[i]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// setPath sets the value at path of doc, creating the missing embedded documents on the way
+func setPath(doc bson.M, path []string, value interface{}) error {
+	var current interface{} = doc
+	for i, key := range path {
+		last := i == len(path)-1
+		switch t := current.(type) {
+		case bson.M:
+			if last {
+				t[key] = value
+				return nil
+			}
+			next, ok := t[key]
+			if !ok || next == nil {
+				next = bson.M{}
+				t[key] = next
+			}
+			current = next
+		case bson.A:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 {
+				return fmt.Errorf("cannot create field %q in an array", key)
+			}
+			if index >= len(t) {
+				return fmt.Errorf("array index %d out of range", index)
+			}
+			if last {
+				t[index] = value
+				return nil
+			}
+			if t[index] == nil {
+				t[index] = bson.M{}
+			}
+			current = t[index]
+		default:
+			return fmt.Errorf("cannot create field %q in a %T", key, current)
+		}
+	}
+	return nil
+}
+
+// unsetPath removes the field at path of doc, if it exists; array elements are set to null
+func unsetPath(doc bson.M, path []string) {
+	parent, found := getPath(doc, path[:len(path)-1])
+	if !found {
+		return
+	}
+	key := path[len(path)-1]
+	switch t := parent.(type) {
+	case bson.M:
+		delete(t, key)
+	case bson.A:
+		if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < len(t) {
+			t[i] = nil
+		}
+	}
+}
+
+// pushPath appends value, or the elements of {"$each": [...]}, to the array at path. With
+// unique, values already in the array are left out.
+func pushPath(doc bson.M, path []string, value interface{}, unique bool) error {
+	values := bson.A{value}
+	if modifiers, ok := value.(bson.M); ok && IsOperatorDocument(modifiers) {
+		each, ok := modifiers["$each"].(bson.A)
+		if !ok || len(modifiers) != 1 {
+			return fmt.Errorf("only the $each modifier is supported")
+		}
+		values = each
+	}
+
+	current, found := getPath(doc, path)
+	array, isArray := current.(bson.A)
+	if found && !isArray {
+		return fmt.Errorf("the field is not an array")
+	}
+	array = append(bson.A{}, array...)
+	for _, v := range values {
+		if unique && containsValue(array, v) {
+			continue
+		}
+		array = append(array, cloneValue(v))
+	}
+	return setPath(doc, path, array)
+}
+
+// containsValue reports whether array has an element equal to v
+func containsValue(array bson.A, v interface{}) bool {
+	for _, element := range array {
+		if Equal(element, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// pullPath removes the elements of the array at path matching condition: a value to
+// compare with, query operators for the elements, or a filter for document elements
+func pullPath(doc bson.M, path []string, condition interface{}) error {
+	current, found := getPath(doc, path)
+	if !found {
+		return nil
+	}
+	array, ok := current.(bson.A)
+	if !ok {
+		return fmt.Errorf("the field is not an array")
+	}
+	kept := bson.A{}
+	for _, element := range array {
+		var matched bool
+		var err error
+		if filter, ok := condition.(bson.M); ok {
+			matched, err = matchArrayElement(element, filter)
+		} else {
+			matched, err = matchEquality([]interface{}{element}, condition)
+		}
+		if err != nil {
+			return err
+		}
+		if !matched {
+			kept = append(kept, element)
+		}
+	}
+	return setPath(doc, path, kept)
+}
+
+// add returns a + b, keeping integers as integers unless the sum overflows them
+func add(a, b interface{}) (interface{}, error) {
+	if !IsNumber(a) || !IsNumber(b) {
+		return nil, fmt.Errorf("cannot increment a non-numeric value")
+	}
+	intA, aIsInt := integer(a)
+	intB, bIsInt := integer(b)
+	if !aIsInt || !bIsInt {
+		return Float(a) + Float(b), nil
+	}
+	sum := intA + intB
+	if (sum > intA) != (intB > 0) {
+		return Float(a) + Float(b), nil // Overflowed int64
+	}
+	_, a32 := a.(int32)
+	_, b32 := b.(int32)
+	if a32 && b32 && sum >= math.MinInt32 && sum <= math.MaxInt32 {
+		return int32(sum), nil
+	}
+	return sum, nil
+}
+
+// resolvePositional replaces the "$" in path with the index of the first element of the
+// array before it that matches the conditions filter sets on that array
+func resolvePositional(doc bson.M, path string, filter bson.M) (string, error) {
+	i := strings.Index(path, ".$")
+	if i < 0 || (len(path) > i+2 && path[i+2] != '.') {
+		return "", fmt.Errorf("unsupported positional operator in %s", path)
+	}
+	arrayPath := path[:i]
+	current, _ := getPath(doc, splitPath(arrayPath))
+	array, ok := current.(bson.A)
+	if !ok {
+		return "", fmt.Errorf("the positional operator did not find the match needed from the query")
+	}
+
+	conditions := arrayConditions(filter, arrayPath)
+	for index, element := range array {
+		matched := len(conditions) > 0
+		for _, condition := range conditions {
+			ok, err := condition(element)
+			if err != nil {
+				return "", err
+			}
+			matched = matched && ok
+		}
+		if matched {
+			return arrayPath + "." + strconv.Itoa(index) + path[i+2:], nil
+		}
+	}
+	return "", fmt.Errorf("the positional operator did not find the match needed from the query")
+}
+
+// arrayConditions returns the conditions filter sets on the elements of the array at
+// arrayPath, from its top level and $and clauses
+func arrayConditions(filter bson.M, arrayPath string) []func(element interface{}) (bool, error) {
+	var conditions []func(interface{}) (bool, error)
+	for key, condition := range filter {
+		switch {
+		case key == "$and":
+			clauses, _ := condition.(bson.A)
+			for _, clause := range clauses {
+				if clause, ok := clause.(bson.M); ok {
+					conditions = append(conditions, arrayConditions(clause, arrayPath)...)
+				}
+			}
+		case key == arrayPath:
+			condition := condition
+			conditions = append(conditions, func(element interface{}) (bool, error) {
+				if operators, ok := condition.(bson.M); ok && IsOperatorDocument(operators) {
+					if elemMatch, ok := operators["$elemMatch"].(bson.M); ok {
+						return matchArrayElement(element, elemMatch)
+					}
+					return matchOperators([]interface{}{element}, operators)
+				}
+				return matchEquality([]interface{}{element}, condition)
+			})
+		case strings.HasPrefix(key, arrayPath+"."):
+			subFilter := bson.M{key[len(arrayPath)+1:]: condition}
+			conditions = append(conditions, func(element interface{}) (bool, error) {
+				doc, ok := element.(bson.M)
+				if !ok {
+					return false, nil
+				}
+				return Match(doc, subFilter)
+			})
+		}
+	}
+	return conditions
+}
+
+// Seed returns the document an upsert creates before applying its update: the fields filter
+// requires to equal a value, from its top level and $and clauses
+func Seed(filter bson.M) (bson.M, error) {
+	doc := bson.M{}
+	if err := seed(doc, filter); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// seed sets the equality fields of filter on doc
+func seed(doc, filter bson.M) error {
+	for key, condition := range filter {
+		if key == "$and" {
+			clauses, _ := condition.(bson.A)
+			for _, clause := range clauses {
+				if clause, ok := clause.(bson.M); ok {
+					if err := seed(doc, clause); err != nil {
+						return err
+					}
+				}
+			}
+			continue
+		}
+		if strings.HasPrefix(key, "$") {
+			continue
+		}
+		if operators, ok := condition.(bson.M); ok && IsOperatorDocument(operators) {
+			value, ok := operators["$eq"]
+			if !ok {
+				continue
+			}
+			condition = value
+		}
+		if err := setPath(doc, splitPath(key), cloneValue(condition)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
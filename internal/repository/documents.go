@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Collection stores the documents of one kind (projects, comments, sessions, jobs...) for the
+// services that keep their data as documents rather than behind a dedicated repository. Its
+// methods have the signatures of *mongo.Collection, which implements it; other backends
+// emulate the filters, update operators and options the services use (see the document
+// package).
+type Collection interface {
+	InsertOne(ctx context.Context, document interface{}, opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error)
+	FindOne(ctx context.Context, filter interface{}, opts ...*options.FindOneOptions) *mongo.SingleResult
+	Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*mongo.Cursor, error)
+	CountDocuments(ctx context.Context, filter interface{}, opts ...*options.CountOptions) (int64, error)
+	Distinct(ctx context.Context, fieldName string, filter interface{}, opts ...*options.DistinctOptions) ([]interface{}, error)
+	UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+	UpdateByID(ctx context.Context, id interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+	UpdateMany(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+	ReplaceOne(ctx context.Context, filter interface{}, replacement interface{}, opts ...*options.ReplaceOptions) (*mongo.UpdateResult, error)
+	FindOneAndUpdate(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) *mongo.SingleResult
+	FindOneAndDelete(ctx context.Context, filter interface{}, opts ...*options.FindOneAndDeleteOptions) *mongo.SingleResult
+	DeleteOne(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error)
+	DeleteMany(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error)
+}
+
+// Documents holds the document collections of one backend
+type Documents interface {
+	Collection(name string) Collection
+	// EnsureIndexes creates the indexes of collection that don't exist yet. Backends that
+	// aren't MongoDB enforce the unique ones, expire documents for the TTL ones and may ignore
+	// the others.
+	EnsureIndexes(ctx context.Context, collection string, indexes []mongo.IndexModel) error
+	// WithTransaction runs fn in a transaction, committing when it returns nil. fn must use the
+	// context it is given for every operation, of the collections and of the repositories of
+	// the same Store, so they join the transaction. When ctx is already inside one, fn joins
+	// it instead of starting another.
+	WithTransaction(ctx context.Context, fn func(txCtx context.Context) error) error
+	// Ping checks that the backend is reachable
+	Ping(ctx context.Context) error
+}
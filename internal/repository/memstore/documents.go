@@ -0,0 +1,287 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/repository"
+	"github.com/OsGift/taskflow-api/internal/repository/document"
+)
+
+// entry is a stored document; seq keeps the insertion order, which is MongoDB's natural order
+type entry struct {
+	seq int64
+	doc bson.M
+}
+
+// uniqueIndex is a unique index of a document collection
+type uniqueIndex struct {
+	name    string
+	keys    []string
+	partial bson.M // Nil when the index covers every document
+}
+
+// txKey is the context key marking the transaction a context is in
+type txKey struct{}
+
+// snapshot is a copy of every table, restored when a transaction fails
+type snapshot struct {
+	users       map[primitive.ObjectID]models.User
+	roles       map[primitive.ObjectID]models.Role
+	tasks       map[primitive.ObjectID]models.Task
+	collections map[string]map[string]entry
+}
+
+// documentStore keeps the document collections in memory. Transactions take turns with each
+// other and with document writes, and undo every change, to the users, roles and tasks as
+// well, when they fail. TTL indexes aren't enforced.
+type documentStore struct {
+	*data
+}
+
+// Collection implements repository.Documents
+func (d *documentStore) Collection(name string) repository.Collection {
+	return document.NewCollection(&collectionStorage{data: d.data, collection: name})
+}
+
+// EnsureIndexes implements repository.Documents; only the unique indexes matter here
+func (d *documentStore) EnsureIndexes(ctx context.Context, collection string, indexes []mongo.IndexModel) error {
+	for _, index := range indexes {
+		o := index.Options
+		if o == nil || o.Unique == nil || !*o.Unique {
+			continue
+		}
+		keys, err := document.ParseSort(index.Keys)
+		if err != nil {
+			return err
+		}
+		unique := uniqueIndex{name: fmt.Sprint(index.Keys)}
+		if o.Name != nil {
+			unique.name = *o.Name
+		}
+		for _, key := range keys {
+			unique.keys = append(unique.keys, key.Path)
+		}
+		if o.PartialFilterExpression != nil {
+			if unique.partial, err = document.Normalize(o.PartialFilterExpression); err != nil {
+				return err
+			}
+		}
+		d.mu.Lock()
+		d.uniques[collection] = append(d.uniques[collection], unique)
+		d.mu.Unlock()
+	}
+	return nil
+}
+
+// WithTransaction implements repository.Documents
+func (d *documentStore) WithTransaction(ctx context.Context, fn func(txCtx context.Context) error) error {
+	return d.transaction(ctx, fn)
+}
+
+// Ping implements repository.Documents
+func (d *documentStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+// transaction runs fn holding the write lock, or within the transaction ctx is already in,
+// and restores the tables as they were before it when it fails
+func (d *data) transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(txKey{}) != d {
+		d.writes.Lock()
+		defer d.writes.Unlock()
+		ctx = context.WithValue(ctx, txKey{}, d)
+	}
+
+	before := d.snapshot()
+	if err := fn(ctx); err != nil {
+		d.restore(before)
+		return err
+	}
+	return nil
+}
+
+// snapshot copies every table; stored documents are never modified in place, so the
+// collections are copied shallowly
+func (d *data) snapshot() snapshot {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	s := snapshot{
+		users:       maps.Clone(d.users),
+		roles:       maps.Clone(d.roles),
+		tasks:       maps.Clone(d.tasks),
+		collections: make(map[string]map[string]entry, len(d.collections)),
+	}
+	for name, collection := range d.collections {
+		s.collections[name] = maps.Clone(collection)
+	}
+	return s
+}
+
+// restore puts back the tables of a snapshot
+func (d *data) restore(s snapshot) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.users, d.roles, d.tasks, d.collections = s.users, s.roles, s.tasks, s.collections
+}
+
+// collectionStorage is the document.Storage of one collection. It leaves filtering to the
+// document.Collection, which gets every document from Load.
+type collectionStorage struct {
+	*data
+	collection string
+}
+
+// key returns the key of a document's _id in its collection
+func key(id interface{}) string {
+	return fmt.Sprintf("%T:%v", id, id)
+}
+
+// Load implements document.Storage
+func (s *collectionStorage) Load(ctx context.Context, q document.Query) ([]bson.M, bool, error) {
+	s.mu.RLock()
+	entries := make([]entry, 0, len(s.collections[s.collection]))
+	for _, e := range s.collections[s.collection] {
+		entries = append(entries, e)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].seq < entries[j].seq })
+	docs := make([]bson.M, len(entries))
+	for i, e := range entries {
+		docs[i] = document.Clone(e.doc)
+	}
+	return docs, false, nil
+}
+
+// Count implements document.Storage
+func (s *collectionStorage) Count(ctx context.Context, filter bson.M) (int64, bool, error) {
+	return 0, false, nil
+}
+
+// Insert implements document.Storage
+func (s *collectionStorage) Insert(ctx context.Context, doc bson.M) error {
+	return s.transaction(ctx, func(ctx context.Context) error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if _, ok := s.collections[s.collection][key(doc["_id"])]; ok {
+			return document.DuplicateKeyError(s.collection + "._id_")
+		}
+		return s.put(doc)
+	})
+}
+
+// Replace implements document.Storage
+func (s *collectionStorage) Replace(ctx context.Context, doc bson.M) error {
+	return s.transaction(ctx, func(ctx context.Context) error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if _, ok := s.collections[s.collection][key(doc["_id"])]; !ok {
+			return nil
+		}
+		return s.put(doc)
+	})
+}
+
+// put stores doc, keeping the position of the document it replaces, unless it would break a
+// unique index. The caller holds mu.
+func (s *collectionStorage) put(doc bson.M) error {
+	id := key(doc["_id"])
+	for _, unique := range s.uniques[s.collection] {
+		conflict, err := s.conflicts(unique, id, doc)
+		if err != nil {
+			return err
+		}
+		if conflict {
+			return document.DuplicateKeyError(s.collection + "." + unique.name)
+		}
+	}
+
+	collection := s.collections[s.collection]
+	if collection == nil {
+		collection = map[string]entry{}
+		s.collections[s.collection] = collection
+	}
+	e, ok := collection[id]
+	if !ok {
+		s.seq++
+		e.seq = s.seq
+	}
+	e.doc = document.Clone(doc)
+	collection[id] = e
+	return nil
+}
+
+// conflicts reports whether another document than the one with key id has the values doc has
+// for the keys of a unique index. The caller holds mu.
+func (s *collectionStorage) conflicts(unique uniqueIndex, id string, doc bson.M) (bool, error) {
+	covered := func(doc bson.M) (bool, error) {
+		if unique.partial == nil {
+			return true, nil
+		}
+		return document.Match(doc, unique.partial)
+	}
+	if ok, err := covered(doc); !ok || err != nil {
+		return false, err
+	}
+	for otherID, other := range s.collections[s.collection] {
+		if otherID == id {
+			continue
+		}
+		ok, err := covered(other.doc)
+		if err != nil {
+			return false, err
+		}
+		same := ok
+		for _, path := range unique.keys {
+			same = same && document.Equal(indexValue(doc, path), indexValue(other.doc, path))
+		}
+		if same {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// indexValue returns the value a document has for an index key, null when it's missing
+func indexValue(doc bson.M, path string) interface{} {
+	values := document.Values([]bson.M{doc}, path)
+	if len(values) == 0 {
+		return nil
+	}
+	return values[0]
+}
+
+// Delete implements document.Storage
+func (s *collectionStorage) Delete(ctx context.Context, ids []interface{}) error {
+	return s.transaction(ctx, func(ctx context.Context) error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		for _, id := range ids {
+			delete(s.collections[s.collection], key(id))
+		}
+		return nil
+	})
+}
+
+// DeleteMatching implements document.Storage
+func (s *collectionStorage) DeleteMatching(ctx context.Context, filter bson.M) (int64, bool, error) {
+	return 0, false, nil
+}
+
+// Atomic implements document.Storage
+func (s *collectionStorage) Atomic(ctx context.Context, fn func(ctx context.Context) error) error {
+	return s.transaction(ctx, fn)
+}
@@ -0,0 +1,380 @@
+package memstore
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+// fieldIndexes caches, per struct type, the field index for each bson name
+var fieldIndexes sync.Map // reflect.Type -> map[string]int
+
+// field returns the struct field of record (a struct value) stored under a bson name
+func field(record reflect.Value, name string) (reflect.Value, bool) {
+	t := record.Type()
+	cached, ok := fieldIndexes.Load(t)
+	if !ok {
+		indexes := map[string]int{}
+		for i := 0; i < t.NumField(); i++ {
+			tag := strings.Split(t.Field(i).Tag.Get("bson"), ",")[0]
+			if tag != "" && tag != "-" {
+				indexes[tag] = i
+			}
+		}
+		cached, _ = fieldIndexes.LoadOrStore(t, indexes)
+	}
+	i, ok := cached.(map[string]int)[name]
+	if !ok {
+		return reflect.Value{}, false
+	}
+	return record.Field(i), true
+}
+
+// set applies update fields to the struct pointed to by record
+func set(record interface{}, fields repository.Fields) error {
+	v := reflect.ValueOf(record).Elem()
+	for name, value := range fields {
+		target, ok := field(v, name)
+		if !ok {
+			return fmt.Errorf("%s has no field %q", v.Type().Name(), name)
+		}
+		if value == nil {
+			target.Set(reflect.Zero(target.Type()))
+			continue
+		}
+		source := reflect.ValueOf(value)
+		if target.Kind() == reflect.Pointer && source.Kind() != reflect.Pointer && source.Type().ConvertibleTo(target.Type().Elem()) {
+			// Optional fields such as due_date are pointers, but updates set plain values
+			ptr := reflect.New(target.Type().Elem())
+			ptr.Elem().Set(source.Convert(target.Type().Elem()))
+			source = ptr
+		}
+		if !source.Type().ConvertibleTo(target.Type()) {
+			return fmt.Errorf("cannot set %s.%s to a %T", v.Type().Name(), name, value)
+		}
+		target.Set(source.Convert(target.Type()))
+	}
+	return nil
+}
+
+// matches reports whether record (a struct value) satisfies a filter document.
+// Supported: equality, nil, regexes, $eq/$ne/$gt/$gte/$lt/$lte/$in, $or and $and.
+func matches(record reflect.Value, filter primitive.M) (bool, error) {
+	for key, condition := range filter {
+		if key == "$or" || key == "$and" {
+			evaluate := matchesAny
+			if key == "$and" {
+				evaluate = matchesAll
+			}
+			ok, err := evaluate(record, condition)
+			if err != nil || !ok {
+				return false, err
+			}
+			continue
+		}
+
+		f, ok := field(record, key)
+		if !ok {
+			return false, fmt.Errorf("%s has no field %q", record.Type().Name(), key)
+		}
+		value := f.Interface()
+
+		switch c := condition.(type) {
+		case nil:
+			if !f.IsZero() {
+				return false, nil
+			}
+		case primitive.Regex:
+			pattern := c.Pattern
+			if strings.Contains(c.Options, "i") {
+				pattern = "(?i)" + pattern
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return false, err
+			}
+			s, isString := normalize(value).(string)
+			if !isString || !re.MatchString(s) {
+				return false, nil
+			}
+		case primitive.M:
+			ok, err := matchesOperators(value, c)
+			if err != nil || !ok {
+				return false, err
+			}
+		default:
+			if f.Kind() == reflect.Slice {
+				// Like MongoDB, equality on an array matches arrays containing the value
+				if !contains(f, c) {
+					return false, nil
+				}
+				continue
+			}
+			if cmp, comparable := compare(value, c); !comparable || cmp != 0 {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// contains reports whether one of the elements of list equals value
+func contains(list reflect.Value, value interface{}) bool {
+	for i := 0; i < list.Len(); i++ {
+		if cmp, comparable := compare(list.Index(i).Interface(), value); comparable && cmp == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// documents reads the list of filter documents given to a $or or $and operator
+func documents(operator string, condition interface{}) ([]primitive.M, error) {
+	var branches []primitive.M
+	switch c := condition.(type) {
+	case []primitive.M:
+		branches = c
+	case []interface{}:
+		for _, item := range c {
+			branch, ok := item.(primitive.M)
+			if !ok {
+				return nil, fmt.Errorf("%s expects a list of documents", operator)
+			}
+			branches = append(branches, branch)
+		}
+	default:
+		return nil, fmt.Errorf("%s expects a list of documents", operator)
+	}
+	return branches, nil
+}
+
+// matchesAny evaluates a $or list of filter documents
+func matchesAny(record reflect.Value, condition interface{}) (bool, error) {
+	branches, err := documents("$or", condition)
+	if err != nil {
+		return false, err
+	}
+	for _, branch := range branches {
+		ok, err := matches(record, branch)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// matchesAll evaluates a $and list of filter documents
+func matchesAll(record reflect.Value, condition interface{}) (bool, error) {
+	branches, err := documents("$and", condition)
+	if err != nil {
+		return false, err
+	}
+	for _, branch := range branches {
+		ok, err := matches(record, branch)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// matchesOperators evaluates an operator document such as {"$gte": from, "$lte": to}
+func matchesOperators(value interface{}, operators primitive.M) (bool, error) {
+	for name, operand := range operators {
+		if name == "$in" {
+			list := reflect.ValueOf(operand)
+			if list.Kind() != reflect.Slice {
+				return false, fmt.Errorf("$in expects a list")
+			}
+			found := false
+			for i := 0; i < list.Len() && !found; i++ {
+				cmp, comparable := compare(value, list.Index(i).Interface())
+				found = comparable && cmp == 0
+			}
+			if !found {
+				return false, nil
+			}
+			continue
+		}
+
+		cmp, comparable := compare(value, operand)
+		var ok bool
+		switch name {
+		case "$eq":
+			ok = comparable && cmp == 0
+		case "$ne":
+			ok = !comparable || cmp != 0
+		case "$gt":
+			ok = comparable && cmp > 0
+		case "$gte":
+			ok = comparable && cmp >= 0
+		case "$lt":
+			ok = comparable && cmp < 0
+		case "$lte":
+			ok = comparable && cmp <= 0
+		default:
+			return false, fmt.Errorf("unsupported filter operator %s", name)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// normalize reduces values to string, int64, float64, bool or time.Time so differently
+// typed but equal values (e.g. TaskStatus and string, int and int64) compare equal
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case primitive.ObjectID:
+		return v.Hex()
+	case *primitive.ObjectID:
+		if v == nil {
+			return nil
+		}
+		return v.Hex()
+	case time.Time:
+		return v
+	case *time.Time:
+		if v == nil {
+			return nil
+		}
+		return *v
+	case primitive.DateTime:
+		return v.Time()
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.Bool:
+		return rv.Bool()
+	}
+	return value
+}
+
+// compare orders a relative to b; comparable is false when their types don't match
+func compare(a, b interface{}) (cmp int, comparable bool) {
+	a, b = normalize(a), normalize(b)
+	switch x := a.(type) {
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), true
+		}
+	case int64:
+		switch y := b.(type) {
+		case int64:
+			return compareOrdered(x, y), true
+		case float64:
+			return compareOrdered(float64(x), y), true
+		}
+	case float64:
+		switch y := b.(type) {
+		case float64:
+			return compareOrdered(x, y), true
+		case int64:
+			return compareOrdered(x, float64(y)), true
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			if x == y {
+				return 0, true
+			}
+			if !x {
+				return -1, true
+			}
+			return 1, true
+		}
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			return x.Compare(y), true
+		}
+	}
+	return 0, false
+}
+
+func compareOrdered[T int64 | float64](x, y T) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// filterPage returns the records matching q's filter, sorted and paged
+func filterPage[T any](records []T, q *query.Query) ([]T, error) {
+	matched, err := filterSorted(records, q)
+	if err != nil {
+		return nil, err
+	}
+
+	start := q.Skip()
+	if start > int64(len(matched)) {
+		start = int64(len(matched))
+	}
+	end := start + q.Limit
+	if end > int64(len(matched)) {
+		end = int64(len(matched))
+	}
+	return matched[start:end], nil
+}
+
+// filterSorted returns the records matching q's filter in q's sort order
+func filterSorted[T any](records []T, q *query.Query) ([]T, error) {
+	matched, err := filterAll(records, q.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	order := q.SortFields()
+	sort.SliceStable(matched, func(i, j int) bool {
+		a, b := reflect.ValueOf(matched[i]), reflect.ValueOf(matched[j])
+		for _, key := range order {
+			fa, _ := field(a, key.Key)
+			fb, _ := field(b, key.Key)
+			cmp, _ := compare(fa.Interface(), fb.Interface())
+			if cmp == 0 {
+				continue
+			}
+			if direction, ok := key.Value.(int); ok && direction < 0 {
+				return cmp > 0
+			}
+			return cmp < 0
+		}
+		return false
+	})
+	return matched, nil
+}
+
+// filterAll returns the records matching filter
+func filterAll[T any](records []T, filter primitive.M) ([]T, error) {
+	var matched []T
+	for _, record := range records {
+		ok, err := matches(reflect.ValueOf(record), filter)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, record)
+		}
+	}
+	return matched, nil
+}
@@ -0,0 +1,79 @@
+// Package memstore implements the repositories in memory, so services and handlers can
+// be exercised without a database. Filters support the same MongoDB-style subset as
+// pgstore; records are addressed by their bson field names. The document collections are
+// evaluated by the document package, as in pgstore.
+package memstore
+
+import (
+	"sort"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+// data holds every record. A single lock keeps multi-record operations (role changes,
+// user deletion) atomic, as the database implementations do with transactions.
+type data struct {
+	mu    sync.RWMutex
+	users map[primitive.ObjectID]models.User
+	roles map[primitive.ObjectID]models.Role
+	tasks map[primitive.ObjectID]models.Task
+
+	collections map[string]map[string]entry // Documents by collection and _id key
+	uniques     map[string][]uniqueIndex    // Unique indexes by collection
+	seq         int64                       // Last insertion sequence number
+	writes      sync.Mutex                  // Held by transactions and document writes
+}
+
+// New returns empty in-memory repositories; seed roles with repository.SeedDefaultRoles
+func New() *repository.Store {
+	d := &data{
+		users: map[primitive.ObjectID]models.User{},
+		roles: map[primitive.ObjectID]models.Role{},
+		tasks: map[primitive.ObjectID]models.Task{},
+
+		collections: map[string]map[string]entry{},
+		uniques:     map[string][]uniqueIndex{},
+	}
+	return &repository.Store{
+		Users:     &userRepository{data: d},
+		Roles:     &roleRepository{data: d},
+		Tasks:     &taskRepository{data: d},
+		Documents: &documentStore{data: d},
+	}
+}
+
+// values copies a table's records into a slice
+func values[T any](table map[primitive.ObjectID]T) []T {
+	records := make([]T, 0, len(table))
+	for _, record := range table {
+		records = append(records, record)
+	}
+	return records
+}
+
+// each calls fn with a snapshot of a table's records in ID order, stopping at the first error.
+// The lock is released before fn runs so fn can use the repositories.
+func each[T any](d *data, table map[primitive.ObjectID]T, fn func(*T) error) error {
+	d.mu.RLock()
+	ids := make([]primitive.ObjectID, 0, len(table))
+	for id := range table {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].Hex() < ids[j].Hex() })
+	records := make([]T, len(ids))
+	for i, id := range ids {
+		records[i] = table[id]
+	}
+	d.mu.RUnlock()
+
+	for i := range records {
+		if err := fn(&records[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
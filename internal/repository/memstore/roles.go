@@ -0,0 +1,79 @@
+package memstore
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+// roleRepository stores roles in memory
+type roleRepository struct {
+	*data
+}
+
+// FindByID retrieves a role by ID
+func (r *roleRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Role, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	role, ok := r.roles[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &role, nil
+}
+
+// FindByIDs retrieves the roles with the given IDs
+func (r *roleRepository) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Role, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	roles := []models.Role{}
+	for _, id := range ids {
+		if role, ok := r.roles[id]; ok {
+			roles = append(roles, role)
+		}
+	}
+	return roles, nil
+}
+
+// FindByName retrieves a role by name
+func (r *roleRepository) FindByName(ctx context.Context, name string) (*models.Role, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, role := range r.roles {
+		if role.Name == name {
+			return &role, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+// Sync inserts the role or refreshes the permissions of the existing role with its name
+func (r *roleRepository) Sync(ctx context.Context, role models.Role) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, existing := range r.roles {
+		if existing.Name == role.Name {
+			existing.Permissions = append([]models.Permission(nil), role.Permissions...)
+			r.roles[id] = existing
+			return false, nil
+		}
+	}
+	if role.ID.IsZero() {
+		role.ID = primitive.NewObjectID()
+	}
+	role.Permissions = append([]models.Permission(nil), role.Permissions...)
+	r.roles[role.ID] = role
+	return true, nil
+}
+
+// Each calls fn with every role in ID order
+func (r *roleRepository) Each(ctx context.Context, fn func(*models.Role) error) error {
+	return each(r.data, r.roles, fn)
+}
@@ -0,0 +1,292 @@
+package memstore
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+// taskRepository stores tasks in memory
+type taskRepository struct {
+	*data
+}
+
+// Create inserts a new task
+func (r *taskRepository) Create(ctx context.Context, task *models.Task) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.tasks[task.ID]; exists {
+		return repository.ErrDuplicate
+	}
+	r.tasks[task.ID] = *task
+	return nil
+}
+
+// FindByID retrieves a task by ID
+func (r *taskRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	task, ok := r.tasks[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &task, nil
+}
+
+// List returns one page of tasks matching the query
+func (r *taskRepository) List(ctx context.Context, q *query.Query) ([]models.Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return filterPage(values(r.tasks), q)
+}
+
+// Each calls fn with every task in ID order
+func (r *taskRepository) Each(ctx context.Context, fn func(*models.Task) error) error {
+	return each(r.data, r.tasks, fn)
+}
+
+// EachMatching calls fn with a snapshot of the tasks matching q's filter, in q's sort order
+func (r *taskRepository) EachMatching(ctx context.Context, q *query.Query, fn func(*models.Task) error) error {
+	r.mu.RLock()
+	matched, err := filterSorted(values(r.tasks), q)
+	r.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	for i := range matched {
+		if err := fn(&matched[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Count counts the tasks matching filter
+func (r *taskRepository) Count(ctx context.Context, filter primitive.M) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	matched, err := filterAll(values(r.tasks), filter)
+	return int64(len(matched)), err
+}
+
+// EstimateCount counts the tasks matching filter exactly, since doing so is cheap in memory
+func (r *taskRepository) EstimateCount(ctx context.Context, filter primitive.M, limit int64) (int64, error) {
+	return r.Count(ctx, filter)
+}
+
+// CountByStatus counts the tasks matching filter per status
+func (r *taskRepository) CountByStatus(ctx context.Context, filter primitive.M) ([]models.TaskStatusCount, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	matched, err := filterAll(values(r.tasks), filter)
+	if err != nil {
+		return nil, err
+	}
+	byStatus := map[models.TaskStatus]int64{}
+	for _, task := range matched {
+		byStatus[task.Status]++
+	}
+
+	counts := make([]models.TaskStatusCount, 0, len(byStatus))
+	for status, count := range byStatus {
+		counts = append(counts, models.TaskStatusCount{Status: status, Count: count})
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i].Status < counts[j].Status })
+	return counts, nil
+}
+
+// CountByUser counts the tasks matching filter per owner
+func (r *taskRepository) CountByUser(ctx context.Context, filter primitive.M) ([]models.UserTaskCount, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	matched, err := filterAll(values(r.tasks), filter)
+	if err != nil {
+		return nil, err
+	}
+	byUser := map[primitive.ObjectID]int64{}
+	for _, task := range matched {
+		byUser[task.UserID]++
+	}
+
+	counts := make([]models.UserTaskCount, 0, len(byUser))
+	for userID, count := range byUser {
+		counts = append(counts, models.UserTaskCount{UserID: userID, Count: count})
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i].UserID.Hex() < counts[j].UserID.Hex() })
+	return counts, nil
+}
+
+// SuggestTitles returns up to limit matching tasks whose title starts with prefix, ignoring case
+func (r *taskRepository) SuggestTitles(ctx context.Context, filter primitive.M, prefix string, limit int64) ([]models.TaskSuggestion, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	matched, err := filterAll(values(r.tasks), filter)
+	if err != nil {
+		return nil, err
+	}
+	prefix = strings.ToLower(prefix)
+	var suggestions []models.TaskSuggestion
+	for _, task := range matched {
+		if strings.HasPrefix(strings.ToLower(task.Title), prefix) {
+			suggestions = append(suggestions, models.TaskSuggestion{ID: task.ID, Title: task.Title, Status: task.Status})
+		}
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		return strings.ToLower(suggestions[i].Title) < strings.ToLower(suggestions[j].Title)
+	})
+	if int64(len(suggestions)) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions, nil
+}
+
+// CompletionLeaderboard ranks users by the tasks they completed from from to to
+func (r *taskRepository) CompletionLeaderboard(ctx context.Context, from, to time.Time, skip, limit int64) ([]models.LeaderboardEntry, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := map[primitive.ObjectID]int64{}
+	for _, task := range r.tasks {
+		if task.Status == models.StatusDone && task.CompletedAt != nil &&
+			!task.CompletedAt.Before(from) && !task.CompletedAt.After(to) {
+			counts[task.UserID]++
+		}
+	}
+
+	entries := make([]models.LeaderboardEntry, 0, len(counts))
+	for userID, count := range counts {
+		user := r.users[userID]
+		entries = append(entries, models.LeaderboardEntry{
+			UserID:         userID,
+			FirstName:      user.FirstName,
+			LastName:       user.LastName,
+			CompletedCount: count,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].CompletedCount != entries[j].CompletedCount {
+			return entries[i].CompletedCount > entries[j].CompletedCount
+		}
+		return entries[i].UserID.Hex() < entries[j].UserID.Hex()
+	})
+	for i := range entries {
+		entries[i].Rank = int64(i) + 1
+		if i > 0 && entries[i].CompletedCount == entries[i-1].CompletedCount {
+			entries[i].Rank = entries[i-1].Rank
+		}
+	}
+
+	total := int64(len(entries))
+	if skip >= total {
+		return []models.LeaderboardEntry{}, total, nil
+	}
+	return entries[skip:min(skip+limit, total)], total, nil
+}
+
+// ActivityByDay counts the tasks matching filter created and completed per day in loc since from
+func (r *taskRepository) ActivityByDay(ctx context.Context, filter primitive.M, from time.Time, loc *time.Location) ([]models.ActivityDay, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	matched, err := filterAll(values(r.tasks), filter)
+	if err != nil {
+		return nil, err
+	}
+	byDay := map[string]*models.ActivityDay{}
+	count := func(at time.Time, completed bool) {
+		if at.Before(from) {
+			return
+		}
+		date := at.In(loc).Format("2006-01-02")
+		day := byDay[date]
+		if day == nil {
+			day = &models.ActivityDay{Date: date}
+			byDay[date] = day
+		}
+		if completed {
+			day.Completed++
+		} else {
+			day.Created++
+		}
+		day.Count++
+	}
+	for _, task := range matched {
+		count(task.CreatedAt, false)
+		if task.CompletedAt != nil {
+			count(*task.CompletedAt, true)
+		}
+	}
+
+	activity := make([]models.ActivityDay, 0, len(byDay))
+	for _, day := range byDay {
+		activity = append(activity, *day)
+	}
+	sort.Slice(activity, func(i, j int) bool { return activity[i].Date < activity[j].Date })
+	return activity, nil
+}
+
+// Update sets fields on a task
+func (r *taskRepository) Update(ctx context.Context, id primitive.ObjectID, fields repository.Fields) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	task, ok := r.tasks[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if err := set(&task, fields); err != nil {
+		return err
+	}
+	r.tasks[id] = task
+	return nil
+}
+
+// UpdateMany sets fields on every task matching filter
+func (r *taskRepository) UpdateMany(ctx context.Context, filter primitive.M, fields repository.Fields) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var matched int64
+	for id, task := range r.tasks {
+		ok, err := matches(reflect.ValueOf(task), filter)
+		if err != nil {
+			return matched, err
+		}
+		if !ok {
+			continue
+		}
+		if err := set(&task, fields); err != nil {
+			return matched, err
+		}
+		r.tasks[id] = task
+		matched++
+	}
+	return matched, nil
+}
+
+// Delete removes a task
+func (r *taskRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.tasks[id]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(r.tasks, id)
+	return nil
+}
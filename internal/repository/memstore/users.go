@@ -0,0 +1,301 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+// userRepository stores users in memory
+type userRepository struct {
+	*data
+}
+
+// Create inserts a new user; emails are unique as in the databases
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.users {
+		if existing.ID == user.ID || existing.Email == user.Email {
+			return repository.ErrDuplicate
+		}
+	}
+	r.users[user.ID] = *user
+	return nil
+}
+
+// FindByID retrieves a user by ID
+func (r *userRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &user, nil
+}
+
+// FindByEmail retrieves a user by email address
+func (r *userRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+// List returns one page of users matching the query
+func (r *userRepository) List(ctx context.Context, q *query.Query) ([]models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return filterPage(values(r.users), q)
+}
+
+// Each calls fn with every user in ID order
+func (r *userRepository) Each(ctx context.Context, fn func(*models.User) error) error {
+	return each(r.data, r.users, fn)
+}
+
+// EachMatching calls fn with a snapshot of the users matching q's filter, in q's sort order
+func (r *userRepository) EachMatching(ctx context.Context, q *query.Query, fn func(*models.User) error) error {
+	r.mu.RLock()
+	matched, err := filterSorted(values(r.users), q)
+	r.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	for i := range matched {
+		if err := fn(&matched[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Count counts the users matching filter
+func (r *userRepository) Count(ctx context.Context, filter primitive.M) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	matched, err := filterAll(values(r.users), filter)
+	return int64(len(matched)), err
+}
+
+// EstimateCount counts the users matching filter exactly, since doing so is cheap in memory
+func (r *userRepository) EstimateCount(ctx context.Context, filter primitive.M, limit int64) (int64, error) {
+	return r.Count(ctx, filter)
+}
+
+// Update sets fields on a user
+func (r *userRepository) Update(ctx context.Context, id primitive.ObjectID, fields repository.Fields) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if err := set(&user, fields); err != nil {
+		return err
+	}
+	r.users[id] = user
+	return nil
+}
+
+// ReplacePassword sets the password hash if it is still oldHash
+func (r *userRepository) ReplacePassword(ctx context.Context, id primitive.ObjectID, oldHash, newHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || user.Password != oldHash {
+		return repository.ErrNotFound
+	}
+	user.Password = newHash
+	user.UpdatedAt = time.Now()
+	r.users[id] = user
+	return nil
+}
+
+// UpdateRole assigns a role by name
+func (r *userRepository) UpdateRole(ctx context.Context, id primitive.ObjectID, roleName string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var role *models.Role
+	for _, candidate := range r.roles {
+		if candidate.Name == roleName {
+			role = &candidate
+			break
+		}
+	}
+	if role == nil {
+		return repository.ErrRoleNotFound
+	}
+
+	user, ok := r.users[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	user.RoleID = role.ID
+	user.UpdatedAt = time.Now()
+	r.users[id] = user
+	return nil
+}
+
+// Delete removes a user and deletes or reassigns their tasks
+func (r *userRepository) Delete(ctx context.Context, id primitive.ObjectID, reassignTo *primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[id]; !ok {
+		return repository.ErrNotFound
+	}
+	if reassignTo != nil {
+		if _, ok := r.users[*reassignTo]; !ok {
+			return repository.ErrReassignTargetNotFound
+		}
+	}
+
+	for taskID, task := range r.tasks {
+		if task.UserID != id {
+			continue
+		}
+		if reassignTo == nil {
+			delete(r.tasks, taskID)
+			continue
+		}
+		now := time.Now()
+		task.UserID = *reassignTo
+		task.AssignedAt = &now
+		task.UpdatedAt = now
+		r.tasks[taskID] = task
+	}
+	delete(r.users, id)
+	return nil
+}
+
+// Merge hands a duplicate user's tasks over to the primary user and disables the duplicate
+func (r *userRepository) Merge(ctx context.Context, duplicateID, primaryID primitive.ObjectID, roleID *primitive.ObjectID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	primary, ok := r.users[primaryID]
+	if !ok {
+		return 0, repository.ErrNotFound
+	}
+	duplicate, ok := r.users[duplicateID]
+	if !ok {
+		return 0, repository.ErrNotFound
+	}
+
+	now := time.Now()
+	if roleID != nil {
+		primary.RoleID = *roleID
+	}
+	primary.UpdatedAt = now
+	r.users[primaryID] = primary
+	duplicate.Disabled = true
+	duplicate.MergedInto = &primaryID
+	duplicate.UpdatedAt = now
+	r.users[duplicateID] = duplicate
+
+	var moved int64
+	for taskID, task := range r.tasks {
+		if task.UserID != duplicateID {
+			continue
+		}
+		task.UserID = primaryID
+		task.UpdatedAt = now
+		r.tasks[taskID] = task
+		moved++
+	}
+	return moved, nil
+}
+
+// DashboardCounts counts users by role and tasks by status
+func (r *userRepository) DashboardCounts(ctx context.Context, now time.Time, from, to *time.Time) (*models.DashboardCounts, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ranged := from != nil && to != nil
+	inRange := func(t time.Time) bool {
+		return ranged && !t.Before(*from) && !t.After(*to)
+	}
+
+	counts := &models.DashboardCounts{
+		UsersByRole:    map[string]int64{},
+		TasksByStatus:  []models.TaskStatusCount{},
+		OpenTasksByAge: []models.TaskAgeCount{},
+	}
+	for _, role := range r.roles {
+		counts.UsersByRole[role.Name] = 0
+	}
+	for _, user := range r.users {
+		counts.TotalUsers++
+		if role, ok := r.roles[user.RoleID]; ok {
+			counts.UsersByRole[role.Name]++
+		}
+		if inRange(user.CreatedAt) {
+			counts.NewUsers++
+		}
+	}
+
+	byStatus := map[models.TaskStatus]int64{}
+	byAge := map[models.TaskStatus]*models.TaskAgeCount{}
+	for _, task := range r.tasks {
+		counts.TotalTasks++
+		if inRange(task.CreatedAt) {
+			counts.NewTasks++
+		}
+		if !ranged || inRange(task.CreatedAt) {
+			byStatus[task.Status]++
+		}
+		if task.Status == models.StatusDone {
+			continue
+		}
+
+		if task.DueDate != nil && task.DueDate.Before(now) {
+			counts.OverdueTasks++
+		}
+		age := byAge[task.Status]
+		if age == nil {
+			age = &models.TaskAgeCount{Status: task.Status}
+			byAge[task.Status] = age
+		}
+		since := task.CreatedAt
+		if task.StatusChangedAt != nil {
+			since = *task.StatusChangedAt
+		}
+		switch elapsed := now.Sub(since); {
+		case elapsed < models.TaskAgeRecent:
+			age.Recent++
+		case elapsed < models.TaskAgeStale:
+			age.Aging++
+		default:
+			age.Stale++
+		}
+	}
+	for status, count := range byStatus {
+		counts.TasksByStatus = append(counts.TasksByStatus, models.TaskStatusCount{Status: status, Count: count})
+	}
+	sort.Slice(counts.TasksByStatus, func(i, j int) bool { return counts.TasksByStatus[i].Status < counts.TasksByStatus[j].Status })
+	for _, age := range byAge {
+		counts.OpenTasksByAge = append(counts.OpenTasksByAge, *age)
+	}
+	sort.Slice(counts.OpenTasksByAge, func(i, j int) bool { return counts.OpenTasksByAge[i].Status < counts.OpenTasksByAge[j].Status })
+	return counts, nil
+}
@@ -0,0 +1,38 @@
+package mongostore
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+
+	"github.com/OsGift/taskflow-api/internal/database"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+// documents stores the document collections in db, where *mongo.Collection already is a
+// repository.Collection
+type documents struct {
+	db *mongo.Database
+}
+
+// Collection implements repository.Documents
+func (d *documents) Collection(name string) repository.Collection {
+	return d.db.Collection(name)
+}
+
+// EnsureIndexes implements repository.Documents
+func (d *documents) EnsureIndexes(ctx context.Context, collection string, indexes []mongo.IndexModel) error {
+	_, err := d.db.Collection(collection).Indexes().CreateMany(ctx, indexes)
+	return err
+}
+
+// WithTransaction implements repository.Documents
+func (d *documents) WithTransaction(ctx context.Context, fn func(txCtx context.Context) error) error {
+	return database.WithTransaction(ctx, d.db, fn)
+}
+
+// Ping implements repository.Documents
+func (d *documents) Ping(ctx context.Context) error {
+	return d.db.Client().Ping(ctx, readpref.Primary())
+}
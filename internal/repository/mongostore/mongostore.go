@@ -0,0 +1,106 @@
+// Package mongostore implements the repositories on MongoDB
+package mongostore
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/OsGift/taskflow-api/internal/database"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+// New returns the MongoDB-backed repositories for db. Reads that fail with a transient error
+// are retried through retry, which may be nil; writes are only retried by the driver.
+func New(db *mongo.Database, retry *database.Retrier) *repository.Store {
+	users := db.Collection("users")
+	tasks := db.Collection("tasks")
+	roles := db.Collection("roles")
+	return &repository.Store{
+		Users:     &userRepository{db: db, users: users, roles: roles, tasks: tasks, retry: retry},
+		Roles:     &roleRepository{roles: roles, retry: retry},
+		Tasks:     &taskRepository{tasks: tasks, retry: retry},
+		Documents: &documents{db: db},
+	}
+}
+
+// notFound maps mongo.ErrNoDocuments to repository.ErrNotFound
+func notFound(err error) error {
+	if err == mongo.ErrNoDocuments {
+		return repository.ErrNotFound
+	}
+	return err
+}
+
+// retried runs a read through retry and returns its result
+func retried[T any](ctx context.Context, retry *database.Retrier, read func() (T, error)) (T, error) {
+	var result T
+	err := retry.Do(ctx, func() (err error) {
+		result, err = read()
+		return err
+	})
+	return result, err
+}
+
+// findOne decodes the document matching filter into result, mapping a miss to
+// repository.ErrNotFound
+func findOne(ctx context.Context, retry *database.Retrier, collection *mongo.Collection, filter interface{}, result interface{}) error {
+	return notFound(retry.Do(ctx, func() error {
+		return collection.FindOne(ctx, filter).Decode(result)
+	}))
+}
+
+// findAll decodes every document a find returns into results
+func findAll[T any](ctx context.Context, retry *database.Retrier, collection *mongo.Collection, filter interface{}, opts *options.FindOptions, results *[]T) error {
+	return retry.Do(ctx, func() error {
+		cursor, err := collection.Find(ctx, filter, opts)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+		return cursor.All(ctx, results)
+	})
+}
+
+// aggregateAll decodes every document an aggregation returns into results
+func aggregateAll[T any](ctx context.Context, retry *database.Retrier, collection *mongo.Collection, pipeline mongo.Pipeline, results *[]T) error {
+	return retry.Do(ctx, func() error {
+		cursor, err := collection.Aggregate(ctx, pipeline)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+		return cursor.All(ctx, results)
+	})
+}
+
+// each decodes every document of collection in _id order and calls fn with it, stopping at
+// the first error
+func each[T any](ctx context.Context, retry *database.Retrier, collection *mongo.Collection, fn func(*T) error) error {
+	return eachFound(ctx, retry, collection, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}), fn)
+}
+
+// eachFound calls fn with every document a find returns, decoding them as the cursor iterates.
+// Only the find is retried: fn may already have seen documents once iterating fails.
+func eachFound[T any](ctx context.Context, retry *database.Retrier, collection *mongo.Collection, filter interface{}, opts *options.FindOptions, fn func(*T) error) error {
+	cursor, err := retried(ctx, retry, func() (*mongo.Cursor, error) {
+		return collection.Find(ctx, filter, opts)
+	})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var record T
+		if err := cursor.Decode(&record); err != nil {
+			return err
+		}
+		if err := fn(&record); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
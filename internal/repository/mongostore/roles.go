@@ -0,0 +1,70 @@
+package mongostore
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/OsGift/taskflow-api/internal/database"
+	"github.com/OsGift/taskflow-api/internal/models"
+)
+
+// roleRepository stores roles in the "roles" collection
+type roleRepository struct {
+	roles *mongo.Collection
+	retry *database.Retrier
+}
+
+// FindByID retrieves a role by ID
+func (r *roleRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Role, error) {
+	var role models.Role
+	if err := findOne(ctx, r.retry, r.roles, bson.M{"_id": id}, &role); err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// FindByIDs retrieves the roles with the given IDs
+func (r *roleRepository) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Role, error) {
+	roles := []models.Role{}
+	if err := findAll(ctx, r.retry, r.roles, bson.M{"_id": bson.M{"$in": ids}}, nil, &roles); err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// FindByName retrieves a role by name
+func (r *roleRepository) FindByName(ctx context.Context, name string) (*models.Role, error) {
+	var role models.Role
+	if err := findOne(ctx, r.retry, r.roles, bson.M{"name": name}, &role); err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// Sync inserts the role or refreshes the permissions of the existing role with its name
+func (r *roleRepository) Sync(ctx context.Context, role models.Role) (bool, error) {
+	filter := bson.M{"name": role.Name}
+	var existing models.Role
+	err := r.roles.FindOne(ctx, filter).Decode(&existing)
+	if err == mongo.ErrNoDocuments {
+		if role.ID.IsZero() {
+			role.ID = primitive.NewObjectID()
+		}
+		_, err = r.roles.InsertOne(ctx, role)
+		return err == nil, err
+	}
+	if err != nil {
+		return false, err
+	}
+
+	_, err = r.roles.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"permissions": role.Permissions}})
+	return false, err
+}
+
+// Each calls fn with every role in ID order
+func (r *roleRepository) Each(ctx context.Context, fn func(*models.Role) error) error {
+	return each(ctx, r.retry, r.roles, fn)
+}
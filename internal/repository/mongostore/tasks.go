@@ -0,0 +1,292 @@
+package mongostore
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/OsGift/taskflow-api/internal/database"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+// taskRepository stores tasks in the "tasks" collection
+type taskRepository struct {
+	tasks *mongo.Collection
+	retry *database.Retrier
+}
+
+// Create inserts a new task
+func (r *taskRepository) Create(ctx context.Context, task *models.Task) error {
+	_, err := r.tasks.InsertOne(ctx, task)
+	return err
+}
+
+// FindByID retrieves a task by ID
+func (r *taskRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Task, error) {
+	var task models.Task
+	if err := findOne(ctx, r.retry, r.tasks, bson.M{"_id": id}, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// List returns one page of tasks matching the query
+func (r *taskRepository) List(ctx context.Context, q *query.Query) ([]models.Task, error) {
+	var tasks []models.Task
+	if err := findAll(ctx, r.retry, r.tasks, q.Filter, q.FindOptions(), &tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// Each calls fn with every task in ID order
+func (r *taskRepository) Each(ctx context.Context, fn func(*models.Task) error) error {
+	return each(ctx, r.retry, r.tasks, fn)
+}
+
+// EachMatching calls fn with every task matching q's filter, in q's sort order
+func (r *taskRepository) EachMatching(ctx context.Context, q *query.Query, fn func(*models.Task) error) error {
+	return eachFound(ctx, r.retry, r.tasks, q.Filter, q.FindAllOptions(), fn)
+}
+
+// Count counts the tasks matching filter
+func (r *taskRepository) Count(ctx context.Context, filter primitive.M) (int64, error) {
+	return retried(ctx, r.retry, func() (int64, error) { return r.tasks.CountDocuments(ctx, filter) })
+}
+
+// EstimateCount reads the collection's metadata count when filter is empty, and otherwise
+// counts up to limit matching tasks
+func (r *taskRepository) EstimateCount(ctx context.Context, filter primitive.M, limit int64) (int64, error) {
+	if len(filter) == 0 {
+		return retried(ctx, r.retry, func() (int64, error) { return r.tasks.EstimatedDocumentCount(ctx) })
+	}
+	return retried(ctx, r.retry, func() (int64, error) { return r.tasks.CountDocuments(ctx, filter, options.Count().SetLimit(limit)) })
+}
+
+// CountByStatus counts the tasks matching filter per status
+func (r *taskRepository) CountByStatus(ctx context.Context, filter primitive.M) ([]models.TaskStatusCount, error) {
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$status"},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+		bson.D{{Key: "$project", Value: bson.D{
+			{Key: "status", Value: "$_id"},
+			{Key: "count", Value: 1},
+			{Key: "_id", Value: 0},
+		}}},
+	}
+	if len(filter) > 0 {
+		pipeline = append(mongo.Pipeline{bson.D{{Key: "$match", Value: filter}}}, pipeline...)
+	}
+
+	var counts []models.TaskStatusCount
+	if err := aggregateAll(ctx, r.retry, r.tasks, pipeline, &counts); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// CountByUser counts the tasks matching filter per owner
+func (r *taskRepository) CountByUser(ctx context.Context, filter primitive.M) ([]models.UserTaskCount, error) {
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$user_id"},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+		bson.D{{Key: "$project", Value: bson.D{
+			{Key: "user_id", Value: "$_id"},
+			{Key: "count", Value: 1},
+			{Key: "_id", Value: 0},
+		}}},
+	}
+	if len(filter) > 0 {
+		pipeline = append(mongo.Pipeline{bson.D{{Key: "$match", Value: filter}}}, pipeline...)
+	}
+
+	var counts []models.UserTaskCount
+	if err := aggregateAll(ctx, r.retry, r.tasks, pipeline, &counts); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// titleCollation compares titles ignoring case. The title indexes that serve suggestions are
+// built with it, which they must be for the range below to use them.
+var titleCollation = &options.Collation{Locale: "en", Strength: 2}
+
+// SuggestTitles scans the title range starting with prefix: U+FFFF sorts after every other
+// character in the collation, so it closes the range
+func (r *taskRepository) SuggestTitles(ctx context.Context, filter primitive.M, prefix string, limit int64) ([]models.TaskSuggestion, error) {
+	match := bson.M{"title": bson.M{"$gte": prefix, "$lt": prefix + "\uffff"}}
+	for key, value := range filter {
+		match[key] = value
+	}
+
+	opts := options.Find().
+		SetCollation(titleCollation).
+		SetSort(bson.D{{Key: "title", Value: 1}}).
+		SetLimit(limit).
+		SetProjection(bson.D{{Key: "title", Value: 1}, {Key: "status", Value: 1}})
+
+	var suggestions []models.TaskSuggestion
+	if err := findAll(ctx, r.retry, r.tasks, match, opts, &suggestions); err != nil {
+		return nil, err
+	}
+	return suggestions, nil
+}
+
+// CompletionLeaderboard ranks users by completed tasks in a single aggregation: completions
+// are grouped per user, ranked with $rank and paged, with the total counted in the same $facet
+func (r *taskRepository) CompletionLeaderboard(ctx context.Context, from, to time.Time, skip, limit int64) ([]models.LeaderboardEntry, int64, error) {
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{
+			"status":       models.StatusDone,
+			"completed_at": bson.M{"$gte": from, "$lte": to},
+		}}},
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$user_id"},
+			{Key: "completed_count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+		bson.D{{Key: "$setWindowFields", Value: bson.D{
+			{Key: "sortBy", Value: bson.D{{Key: "completed_count", Value: -1}}},
+			{Key: "output", Value: bson.D{{Key: "rank", Value: bson.D{{Key: "$rank", Value: bson.D{}}}}}},
+		}}},
+		bson.D{{Key: "$facet", Value: bson.D{
+			{Key: "total", Value: bson.A{bson.D{{Key: "$count", Value: "count"}}}},
+			{Key: "entries", Value: bson.A{
+				bson.D{{Key: "$sort", Value: bson.D{{Key: "rank", Value: 1}, {Key: "_id", Value: 1}}}},
+				bson.D{{Key: "$skip", Value: skip}},
+				bson.D{{Key: "$limit", Value: limit}},
+				bson.D{{Key: "$lookup", Value: bson.D{
+					{Key: "from", Value: "users"},
+					{Key: "localField", Value: "_id"},
+					{Key: "foreignField", Value: "_id"},
+					{Key: "as", Value: "user"},
+				}}},
+				bson.D{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$user"}, {Key: "preserveNullAndEmptyArrays", Value: true}}}},
+				bson.D{{Key: "$project", Value: bson.D{
+					{Key: "_id", Value: 0},
+					{Key: "rank", Value: 1},
+					{Key: "user_id", Value: "$_id"},
+					{Key: "first_name", Value: "$user.first_name"},
+					{Key: "last_name", Value: "$user.last_name"},
+					{Key: "completed_count", Value: 1},
+				}}},
+			}},
+		}}},
+	}
+
+	var results []struct {
+		Total []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+		Entries []models.LeaderboardEntry `bson:"entries"`
+	}
+	if err := aggregateAll(ctx, r.retry, r.tasks, pipeline, &results); err != nil {
+		return nil, 0, err
+	}
+	if len(results) == 0 || len(results[0].Total) == 0 {
+		return []models.LeaderboardEntry{}, 0, nil
+	}
+	return results[0].Entries, results[0].Total[0].Count, nil
+}
+
+// ActivityByDay turns each task into its creation and completion events, keeps those since
+// from and counts them per $dateToString day in loc
+func (r *taskRepository) ActivityByDay(ctx context.Context, filter primitive.M, from time.Time, loc *time.Location) ([]models.ActivityDay, error) {
+	event := func(kind, field string) bson.D {
+		return bson.D{{Key: "kind", Value: kind}, {Key: "at", Value: field}}
+	}
+	isKind := func(kind string) bson.D {
+		return bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{
+			bson.D{{Key: "$eq", Value: bson.A{"$events.kind", kind}}}, 1, 0,
+		}}}}}
+	}
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{"$and": bson.A{filter, bson.M{"$or": bson.A{
+			bson.M{"created_at": bson.M{"$gte": from}},
+			bson.M{"completed_at": bson.M{"$gte": from}},
+		}}}}}},
+		bson.D{{Key: "$project", Value: bson.D{
+			{Key: "_id", Value: 0},
+			{Key: "events", Value: bson.D{{Key: "$filter", Value: bson.D{
+				{Key: "input", Value: bson.A{event("created", "$created_at"), event("completed", "$completed_at")}},
+				// A missing completed_at compares below any date
+				{Key: "cond", Value: bson.D{{Key: "$gte", Value: bson.A{"$$this.at", from}}}},
+			}}}},
+		}}},
+		bson.D{{Key: "$unwind", Value: "$events"}},
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{{Key: "$dateToString", Value: bson.D{
+				{Key: "format", Value: "%Y-%m-%d"},
+				{Key: "date", Value: "$events.at"},
+				{Key: "timezone", Value: loc.String()},
+			}}}},
+			{Key: "created", Value: isKind("created")},
+			{Key: "completed", Value: isKind("completed")},
+		}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+		bson.D{{Key: "$project", Value: bson.D{
+			{Key: "_id", Value: 0},
+			{Key: "date", Value: "$_id"},
+			{Key: "created", Value: 1},
+			{Key: "completed", Value: 1},
+			{Key: "count", Value: bson.D{{Key: "$add", Value: bson.A{"$created", "$completed"}}}},
+		}}},
+	}
+
+	var days []struct {
+		Date      string `bson:"date"`
+		Created   int64  `bson:"created"`
+		Completed int64  `bson:"completed"`
+		Count     int64  `bson:"count"`
+	}
+	if err := aggregateAll(ctx, r.retry, r.tasks, pipeline, &days); err != nil {
+		return nil, err
+	}
+	activity := make([]models.ActivityDay, 0, len(days))
+	for _, day := range days {
+		activity = append(activity, models.ActivityDay(day))
+	}
+	return activity, nil
+}
+
+// Update sets fields on a task
+func (r *taskRepository) Update(ctx context.Context, id primitive.ObjectID, fields repository.Fields) error {
+	result, err := r.tasks.UpdateByID(ctx, id, bson.M{"$set": bson.M(fields)})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// UpdateMany sets fields on every task matching filter
+func (r *taskRepository) UpdateMany(ctx context.Context, filter primitive.M, fields repository.Fields) (int64, error) {
+	result, err := r.tasks.UpdateMany(ctx, filter, bson.M{"$set": bson.M(fields)})
+	if err != nil {
+		return 0, err
+	}
+	return result.MatchedCount, nil
+}
+
+// Delete removes a task
+func (r *taskRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.tasks.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
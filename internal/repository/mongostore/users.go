@@ -0,0 +1,374 @@
+package mongostore
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/OsGift/taskflow-api/internal/database"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+// userRepository stores users in the "users" collection
+type userRepository struct {
+	db    *mongo.Database
+	users *mongo.Collection
+	roles *mongo.Collection
+	tasks *mongo.Collection
+	retry *database.Retrier
+}
+
+// Create inserts a new user
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	_, err := r.users.InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		return repository.ErrDuplicate
+	}
+	return err
+}
+
+// FindByID retrieves a user by ID
+func (r *userRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	var user models.User
+	if err := findOne(ctx, r.retry, r.users, bson.M{"_id": id}, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// FindByEmail retrieves a user by email address
+func (r *userRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	if err := findOne(ctx, r.retry, r.users, bson.M{"email": email}, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// List returns one page of users matching the query
+func (r *userRepository) List(ctx context.Context, q *query.Query) ([]models.User, error) {
+	var users []models.User
+	if err := findAll(ctx, r.retry, r.users, q.Filter, q.FindOptions(), &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// Each calls fn with every user in ID order
+func (r *userRepository) Each(ctx context.Context, fn func(*models.User) error) error {
+	return each(ctx, r.retry, r.users, fn)
+}
+
+// EachMatching calls fn with every user matching q's filter, in q's sort order
+func (r *userRepository) EachMatching(ctx context.Context, q *query.Query, fn func(*models.User) error) error {
+	return eachFound(ctx, r.retry, r.users, q.Filter, q.FindAllOptions(), fn)
+}
+
+// Count counts the users matching filter
+func (r *userRepository) Count(ctx context.Context, filter primitive.M) (int64, error) {
+	return retried(ctx, r.retry, func() (int64, error) { return r.users.CountDocuments(ctx, filter) })
+}
+
+// EstimateCount reads the collection's metadata count when filter is empty, and otherwise
+// counts up to limit matching users
+func (r *userRepository) EstimateCount(ctx context.Context, filter primitive.M, limit int64) (int64, error) {
+	if len(filter) == 0 {
+		return retried(ctx, r.retry, func() (int64, error) { return r.users.EstimatedDocumentCount(ctx) })
+	}
+	return retried(ctx, r.retry, func() (int64, error) { return r.users.CountDocuments(ctx, filter, options.Count().SetLimit(limit)) })
+}
+
+// Update sets fields on a user
+func (r *userRepository) Update(ctx context.Context, id primitive.ObjectID, fields repository.Fields) error {
+	result, err := r.users.UpdateByID(ctx, id, bson.M{"$set": bson.M(fields)})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// ReplacePassword sets the password hash if it is still oldHash
+func (r *userRepository) ReplacePassword(ctx context.Context, id primitive.ObjectID, oldHash, newHash string) error {
+	result, err := r.users.UpdateOne(ctx, bson.M{"_id": id, "password": oldHash},
+		bson.M{"$set": bson.M{"password": newHash, "updated_at": time.Now()}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// UpdateRole assigns a role by name. The role lookup and the user update run in one
+// transaction so a role removed concurrently can't be assigned.
+func (r *userRepository) UpdateRole(ctx context.Context, id primitive.ObjectID, roleName string) error {
+	return database.WithTransaction(ctx, r.db, func(txCtx context.Context) error {
+		var role models.Role
+		if err := r.roles.FindOne(txCtx, bson.M{"name": roleName}).Decode(&role); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return repository.ErrRoleNotFound
+			}
+			return err
+		}
+
+		result, err := r.users.UpdateByID(txCtx, id, bson.M{"$set": bson.M{
+			"role_id":    role.ID,
+			"updated_at": time.Now(),
+		}})
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return repository.ErrNotFound
+		}
+		return nil
+	})
+}
+
+// Delete removes a user and deletes or reassigns their tasks in a single transaction
+func (r *userRepository) Delete(ctx context.Context, id primitive.ObjectID, reassignTo *primitive.ObjectID) error {
+	return database.WithTransaction(ctx, r.db, func(txCtx context.Context) error {
+		if reassignTo != nil {
+			count, err := r.users.CountDocuments(txCtx, bson.M{"_id": *reassignTo})
+			if err != nil {
+				return err
+			}
+			if count == 0 {
+				return repository.ErrReassignTargetNotFound
+			}
+			now := time.Now()
+			_, err = r.tasks.UpdateMany(txCtx, bson.M{"user_id": id}, bson.M{"$set": bson.M{
+				"user_id":     *reassignTo,
+				"assigned_at": now,
+				"updated_at":  now,
+			}})
+			if err != nil {
+				return err
+			}
+		} else {
+			if _, err := r.tasks.DeleteMany(txCtx, bson.M{"user_id": id}); err != nil {
+				return err
+			}
+		}
+
+		result, err := r.users.DeleteOne(txCtx, bson.M{"_id": id})
+		if err != nil {
+			return err
+		}
+		if result.DeletedCount == 0 {
+			return repository.ErrNotFound
+		}
+		return nil
+	})
+}
+
+// Merge hands a duplicate user's tasks over to the primary user and disables the duplicate
+// in a single transaction
+func (r *userRepository) Merge(ctx context.Context, duplicateID, primaryID primitive.ObjectID, roleID *primitive.ObjectID) (int64, error) {
+	var moved int64
+	err := database.WithTransaction(ctx, r.db, func(txCtx context.Context) error {
+		now := time.Now()
+		primary := bson.M{"updated_at": now}
+		if roleID != nil {
+			primary["role_id"] = *roleID
+		}
+		result, err := r.users.UpdateByID(txCtx, primaryID, bson.M{"$set": primary})
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return repository.ErrNotFound
+		}
+
+		result, err = r.users.UpdateByID(txCtx, duplicateID, bson.M{"$set": bson.M{
+			"disabled":    true,
+			"merged_into": primaryID,
+			"updated_at":  now,
+		}})
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return repository.ErrNotFound
+		}
+
+		result, err = r.tasks.UpdateMany(txCtx, bson.M{"user_id": duplicateID}, bson.M{"$set": bson.M{
+			"user_id":    primaryID,
+			"updated_at": now,
+		}})
+		if err != nil {
+			return err
+		}
+		moved = result.ModifiedCount
+		return nil
+	})
+	return moved, err
+}
+
+// DashboardCounts computes every dashboard count in one aggregation: roles and tasks are
+// appended to the users with $unionWith and a $facet groups users by role (the role documents
+// make sure roles without users show up) and tasks by status, flagging the records created in
+// the range as it goes. Tasks saved before status_changed_at was
+// recorded are aged from their creation.
+func (r *userRepository) DashboardCounts(ctx context.Context, now time.Time, from, to *time.Time) (*models.DashboardCounts, error) {
+	inRange := interface{}(false)
+	if from != nil && to != nil {
+		inRange = bson.D{{Key: "$and", Value: bson.A{
+			bson.D{{Key: "$gte", Value: bson.A{"$created_at", *from}}},
+			bson.D{{Key: "$lte", Value: bson.A{"$created_at", *to}}},
+		}}}
+	}
+	sum := func(condition interface{}) bson.D {
+		return bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{condition, 1, 0}}}}}
+	}
+	recent, stale := now.Add(-models.TaskAgeRecent), now.Add(-models.TaskAgeStale)
+
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$project", Value: bson.D{
+			{Key: "_id", Value: 0},
+			{Key: "kind", Value: bson.D{{Key: "$literal", Value: "user"}}},
+			{Key: "role_id", Value: 1},
+			{Key: "in_range", Value: inRange},
+		}}},
+		bson.D{{Key: "$unionWith", Value: bson.D{
+			{Key: "coll", Value: r.roles.Name()},
+			{Key: "pipeline", Value: bson.A{
+				bson.D{{Key: "$project", Value: bson.D{
+					{Key: "_id", Value: 0},
+					{Key: "kind", Value: bson.D{{Key: "$literal", Value: "role"}}},
+					{Key: "role_id", Value: "$_id"},
+					{Key: "in_range", Value: false},
+				}}},
+			}},
+		}}},
+		bson.D{{Key: "$unionWith", Value: bson.D{
+			{Key: "coll", Value: r.tasks.Name()},
+			{Key: "pipeline", Value: bson.A{
+				bson.D{{Key: "$project", Value: bson.D{
+					{Key: "_id", Value: 0},
+					{Key: "kind", Value: bson.D{{Key: "$literal", Value: "task"}}},
+					{Key: "status", Value: 1},
+					{Key: "in_range", Value: inRange},
+					// A missing due date compares below null, so it is never overdue
+					{Key: "overdue", Value: bson.D{{Key: "$and", Value: bson.A{
+						bson.D{{Key: "$gt", Value: bson.A{"$due_date", nil}}},
+						bson.D{{Key: "$lt", Value: bson.A{"$due_date", now}}},
+					}}}},
+					{Key: "since", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$status_changed_at", "$created_at"}}}},
+				}}},
+			}},
+		}}},
+		bson.D{{Key: "$facet", Value: bson.D{
+			{Key: "users", Value: bson.A{
+				bson.D{{Key: "$match", Value: bson.M{"kind": bson.M{"$in": bson.A{"user", "role"}}}}},
+				bson.D{{Key: "$group", Value: bson.D{
+					{Key: "_id", Value: "$role_id"},
+					{Key: "count", Value: sum(bson.D{{Key: "$eq", Value: bson.A{"$kind", "user"}}})},
+					{Key: "new", Value: sum("$in_range")},
+				}}},
+				bson.D{{Key: "$lookup", Value: bson.D{
+					{Key: "from", Value: r.roles.Name()},
+					{Key: "localField", Value: "_id"},
+					{Key: "foreignField", Value: "_id"},
+					{Key: "as", Value: "role"},
+				}}},
+				bson.D{{Key: "$project", Value: bson.D{
+					{Key: "_id", Value: 0},
+					{Key: "role", Value: bson.D{{Key: "$ifNull", Value: bson.A{bson.D{{Key: "$first", Value: "$role.name"}}, ""}}}},
+					{Key: "count", Value: 1},
+					{Key: "new", Value: 1},
+				}}},
+			}},
+			{Key: "tasks", Value: bson.A{
+				bson.D{{Key: "$match", Value: bson.M{"kind": "task"}}},
+				bson.D{{Key: "$group", Value: bson.D{
+					{Key: "_id", Value: "$status"},
+					{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+					{Key: "new", Value: sum("$in_range")},
+					{Key: "overdue", Value: sum("$overdue")},
+					{Key: "recent", Value: sum(bson.D{{Key: "$gt", Value: bson.A{"$since", recent}}})},
+					{Key: "aging", Value: sum(bson.D{{Key: "$and", Value: bson.A{
+						bson.D{{Key: "$lte", Value: bson.A{"$since", recent}}},
+						bson.D{{Key: "$gt", Value: bson.A{"$since", stale}}},
+					}}})},
+					{Key: "stale", Value: sum(bson.D{{Key: "$lte", Value: bson.A{"$since", stale}}})},
+				}}},
+				bson.D{{Key: "$project", Value: bson.D{
+					{Key: "_id", Value: 0},
+					{Key: "status", Value: "$_id"},
+					{Key: "count", Value: 1},
+					{Key: "new", Value: 1},
+					{Key: "overdue", Value: 1},
+					{Key: "recent", Value: 1},
+					{Key: "aging", Value: 1},
+					{Key: "stale", Value: 1},
+				}}},
+			}},
+		}}},
+	}
+
+	var result []struct {
+		Users []struct {
+			Role  string `bson:"role"`
+			Count int64  `bson:"count"`
+			New   int64  `bson:"new"`
+		} `bson:"users"`
+		Tasks []struct {
+			Status  models.TaskStatus `bson:"status"`
+			Count   int64             `bson:"count"`
+			New     int64             `bson:"new"`
+			Overdue int64             `bson:"overdue"`
+			Recent  int64             `bson:"recent"`
+			Aging   int64             `bson:"aging"`
+			Stale   int64             `bson:"stale"`
+		} `bson:"tasks"`
+	}
+	if err := aggregateAll(ctx, r.retry, r.users, pipeline, &result); err != nil {
+		return nil, err
+	}
+
+	counts := &models.DashboardCounts{
+		UsersByRole:    map[string]int64{},
+		TasksByStatus:  []models.TaskStatusCount{},
+		OpenTasksByAge: []models.TaskAgeCount{},
+	}
+	if len(result) == 0 {
+		return counts, nil
+	}
+	ranged := from != nil && to != nil
+	for _, users := range result[0].Users {
+		counts.TotalUsers += users.Count
+		counts.NewUsers += users.New
+		if users.Role != "" { // Users left with a deleted role only count towards the total
+			counts.UsersByRole[users.Role] += users.Count
+		}
+	}
+	for _, tasks := range result[0].Tasks {
+		counts.TotalTasks += tasks.Count
+		counts.NewTasks += tasks.New
+		count := tasks.Count
+		if ranged {
+			count = tasks.New
+		}
+		if count > 0 {
+			counts.TasksByStatus = append(counts.TasksByStatus, models.TaskStatusCount{Status: tasks.Status, Count: count})
+		}
+		if tasks.Status != models.StatusDone {
+			counts.OverdueTasks += tasks.Overdue
+			counts.OpenTasksByAge = append(counts.OpenTasksByAge, models.TaskAgeCount{
+				Status: tasks.Status, Recent: tasks.Recent, Aging: tasks.Aging, Stale: tasks.Stale,
+			})
+		}
+	}
+	return counts, nil
+}
@@ -0,0 +1,410 @@
+package pgstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/OsGift/taskflow-api/internal/database"
+	"github.com/OsGift/taskflow-api/internal/logging"
+	"github.com/OsGift/taskflow-api/internal/repository"
+	"github.com/OsGift/taskflow-api/internal/repository/document"
+)
+
+// tableCollections are the collections kept in tables of their own rather than as documents
+var tableCollections = map[string]bool{"users": true, "roles": true, "tasks": true}
+
+// documentStore keeps the document collections in the documents table, one row per document
+type documentStore struct {
+	db conn
+}
+
+// Collection implements repository.Documents
+func (d *documentStore) Collection(name string) repository.Collection {
+	return document.NewCollection(&collectionStorage{db: d.db, collection: name})
+}
+
+// WithTransaction implements repository.Documents
+func (d *documentStore) WithTransaction(ctx context.Context, fn func(txCtx context.Context) error) error {
+	if d.db.tx(ctx) != nil {
+		return fn(ctx)
+	}
+	tx, err := d.db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Ping implements repository.Documents
+func (d *documentStore) Ping(ctx context.Context) error {
+	return d.db.db.PingContext(ctx)
+}
+
+// EnsureIndexes implements repository.Documents. Unique indexes become unique expression
+// indexes over the query form, partial ones included, and the TTL ones are recorded for
+// expire; other indexes become plain expression indexes, which serve the sorts and range
+// queries the GIN index on the query form doesn't.
+func (d *documentStore) EnsureIndexes(ctx context.Context, collection string, indexes []mongo.IndexModel) error {
+	for _, index := range indexes {
+		o := index.Options
+		if o == nil {
+			o = &options.IndexOptions{}
+		}
+		keys, err := document.ParseSort(index.Keys)
+		if err != nil {
+			continue // Text and other special indexes have no equivalent here
+		}
+		if o.ExpireAfterSeconds != nil {
+			if len(keys) != 1 || strings.Contains(keys[0].Path, ".") {
+				return fmt.Errorf("TTL index on %s must have a single top-level field", collection)
+			}
+			_, err := d.db.ExecContext(ctx, `INSERT INTO document_ttls (collection, field, expire_after_seconds)
+				VALUES ($1, $2, $3) ON CONFLICT (collection, field) DO UPDATE SET expire_after_seconds = EXCLUDED.expire_after_seconds`,
+				collection, keys[0].Path, *o.ExpireAfterSeconds)
+			if err != nil {
+				return err
+			}
+		}
+
+		name := indexName(keys, o.Name)
+		statement, err := indexStatement(collection, name, keys, o)
+		if err != nil {
+			return err
+		}
+		if _, err := d.db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to create index %s.%s: %w", collection, name, err)
+		}
+	}
+	return nil
+}
+
+// indexName returns the name of an index, by default the one MongoDB gives it
+func indexName(keys []document.SortKey, name *string) string {
+	if name != nil {
+		return *name
+	}
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		direction := "1"
+		if key.Descending {
+			direction = "-1"
+		}
+		parts = append(parts, key.Path+"_"+direction)
+	}
+	return strings.Join(parts, "_")
+}
+
+// unsafeIndexChars matches what can't appear in the name of a PostgreSQL index
+var unsafeIndexChars = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// indexStatement returns the CREATE INDEX statement of the index name on collection
+func indexStatement(collection, name string, keys []document.SortKey, o *options.IndexOptions) (string, error) {
+	columns := make([]string, 0, len(keys))
+	for _, key := range keys {
+		path, ok := fieldPath(key.Path)
+		if !ok {
+			return "", fmt.Errorf("unsupported index key %s.%s", collection, key.Path)
+		}
+		column := "(doc #> " + pq.QuoteLiteral("{"+strings.Join(path, ",")+"}") + ")"
+		if key.Descending {
+			column += " DESC"
+		}
+		columns = append(columns, column)
+	}
+
+	where := "collection = " + pq.QuoteLiteral(collection)
+	if o.PartialFilterExpression != nil {
+		filter, err := document.Normalize(o.PartialFilterExpression)
+		if err != nil {
+			return "", err
+		}
+		predicate, exact := jsonPathFilter(filter)
+		if !exact || predicate == "" {
+			return "", fmt.Errorf("unsupported partial filter on index %s.%s", collection, name)
+		}
+		where += " AND doc @? " + pq.QuoteLiteral(predicate) + "::jsonpath"
+	}
+
+	indexName := unsafeIndexChars.ReplaceAllString(strings.ToLower("documents_"+collection+"_"+name), "_")
+	if len(indexName) > 63 {
+		indexName = indexName[:63]
+	}
+	unique := ""
+	if o.Unique != nil && *o.Unique {
+		unique = "UNIQUE "
+	}
+	return `CREATE ` + unique + `INDEX IF NOT EXISTS ` + pq.QuoteIdentifier(indexName) +
+		` ON documents (` + strings.Join(columns, ", ") + `) WHERE ` + where, nil
+}
+
+// ensureDocumentIndexes creates the indexes the document collections have in MongoDB
+func ensureDocumentIndexes(ctx context.Context, db conn) error {
+	d := &documentStore{db: db}
+	for collection, indexes := range database.Indexes() {
+		if tableCollections[collection] {
+			continue
+		}
+		if err := d.EnsureIndexes(ctx, collection, indexes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expire deletes the documents past the expiry of their collection's TTL indexes every
+// interval until ctx is done, like MongoDB's TTL monitor. Every process runs it: deleting
+// twice is harmless.
+func (d *documentStore) expire(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		_, err := d.db.ExecContext(ctx, `DELETE FROM documents d USING document_ttls t
+			WHERE d.collection = t.collection AND CASE WHEN jsonb_typeof(d.doc -> t.field) = 'number'
+				THEN (d.doc ->> t.field)::numeric < $1 - t.expire_after_seconds * 1000 ELSE FALSE END`,
+			time.Now().UnixMilli())
+		if err != nil {
+			logging.Warnf("Failed to delete expired documents: %v", err)
+		}
+	}
+}
+
+// collectionStorage is the document.Storage of one collection in the documents table
+type collectionStorage struct {
+	db         conn
+	collection string
+}
+
+// documentID returns the key of a document's _id in the id column
+func documentID(id interface{}) (string, error) {
+	switch t := id.(type) {
+	case primitive.ObjectID:
+		return t.Hex(), nil
+	case string:
+		return t, nil
+	case nil:
+		return "", errors.New("document has no _id")
+	}
+	encoded, err := json.Marshal(queryForm(id))
+	return string(encoded), err
+}
+
+// where returns the WHERE clause selecting the documents of the collection that match
+// filter, and whether it selects exactly those
+func (s *collectionStorage) where(filter bson.M, a *args) (string, bool) {
+	where := ` WHERE collection = ` + a.add(s.collection)
+	// A filter on _id alone is a primary key lookup
+	if len(filter) == 1 {
+		if id, ok := filter["_id"]; ok && !document.IsOperatorDocument(id) {
+			if key, err := documentID(id); err == nil && (isObjectID(id) || isString(id)) {
+				return where + ` AND id = ` + a.add(key), true
+			}
+		}
+	}
+	predicate, exact := jsonPathFilter(filter)
+	if predicate != "" {
+		where += ` AND doc @? ` + a.add(predicate) + `::jsonpath`
+	}
+	return where, exact
+}
+
+// isObjectID reports whether v is an ObjectID
+func isObjectID(v interface{}) bool {
+	_, ok := v.(primitive.ObjectID)
+	return ok
+}
+
+// isString reports whether v is a string
+func isString(v interface{}) bool {
+	_, ok := v.(string)
+	return ok
+}
+
+// orderBy returns the ORDER BY clause of keys over the query form, or false when a key isn't
+// a simple field. Missing fields sort first in ascending order, like nulls in MongoDB.
+func orderBy(keys []document.SortKey) (string, bool) {
+	if len(keys) == 0 {
+		return ` ORDER BY id`, true
+	}
+	terms := make([]string, 0, len(keys)+1)
+	for _, key := range keys {
+		path, ok := fieldPath(key.Path)
+		if !ok {
+			return "", false
+		}
+		term := "doc #> " + pq.QuoteLiteral("{"+strings.Join(path, ",")+"}")
+		if key.Descending {
+			term += " DESC NULLS LAST"
+		} else {
+			term += " NULLS FIRST"
+		}
+		terms = append(terms, term)
+	}
+	return ` ORDER BY ` + strings.Join(terms, ", ") + `, id`, true
+}
+
+// Load implements document.Storage
+func (s *collectionStorage) Load(ctx context.Context, q document.Query) ([]bson.M, bool, error) {
+	var a args
+	where, exact := s.where(q.Filter, &a)
+	order, sortable := orderBy(q.Sort)
+	statement := `SELECT data FROM documents` + where
+	if exact && sortable {
+		statement += order
+		if q.Limit > 0 {
+			statement += ` LIMIT ` + a.add(q.Limit)
+		}
+		if q.Skip > 0 {
+			statement += ` OFFSET ` + a.add(q.Skip)
+		}
+	} else {
+		exact = false
+		statement += ` ORDER BY id`
+	}
+	if q.ForUpdate {
+		statement += ` FOR UPDATE`
+	}
+
+	rows, err := s.db.QueryContext(ctx, statement, a...)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	var docs []bson.M
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, false, err
+		}
+		doc, err := document.FromRaw(data)
+		if err != nil {
+			return nil, false, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, exact, rows.Err()
+}
+
+// Count implements document.Storage
+func (s *collectionStorage) Count(ctx context.Context, filter bson.M) (int64, bool, error) {
+	var a args
+	where, exact := s.where(filter, &a)
+	if !exact {
+		return 0, false, nil
+	}
+	var count int64
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM documents`+where, a...).Scan(&count)
+	return count, true, err
+}
+
+// encode returns the id, BSON and query form columns of doc
+func encode(doc bson.M) (string, []byte, []byte, error) {
+	id, err := documentID(doc["_id"])
+	if err != nil {
+		return "", nil, nil, err
+	}
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	form, err := json.Marshal(queryForm(doc))
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return id, data, form, nil
+}
+
+// write runs a statement writing one document, in a savepoint when in a transaction so a
+// duplicate key doesn't abort it, and maps unique violations to MongoDB's duplicate key error
+func (s *collectionStorage) write(ctx context.Context, statement string, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	err := withTx(ctx, s.db, func(tx *sql.Tx) (err error) {
+		result, err = tx.ExecContext(ctx, statement, args...)
+		return err
+	})
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" { // unique_violation
+		return nil, document.DuplicateKeyError(s.collection + "." + pqErr.Constraint)
+	}
+	return result, err
+}
+
+// Insert implements document.Storage
+func (s *collectionStorage) Insert(ctx context.Context, doc bson.M) error {
+	id, data, form, err := encode(doc)
+	if err != nil {
+		return err
+	}
+	_, err = s.write(ctx, `INSERT INTO documents (collection, id, data, doc) VALUES ($1, $2, $3, $4)`,
+		s.collection, id, data, string(form))
+	return err
+}
+
+// Replace implements document.Storage
+func (s *collectionStorage) Replace(ctx context.Context, doc bson.M) error {
+	id, data, form, err := encode(doc)
+	if err != nil {
+		return err
+	}
+	_, err = s.write(ctx, `UPDATE documents SET data = $1, doc = $2 WHERE collection = $3 AND id = $4`,
+		data, string(form), s.collection, id)
+	return err
+}
+
+// Delete implements document.Storage
+func (s *collectionStorage) Delete(ctx context.Context, ids []interface{}) error {
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		var err error
+		if keys[i], err = documentID(id); err != nil {
+			return err
+		}
+	}
+	_, err := s.db.ExecContext(ctx, `DELETE FROM documents WHERE collection = $1 AND id = ANY($2)`,
+		s.collection, pq.Array(keys))
+	return err
+}
+
+// DeleteMatching implements document.Storage
+func (s *collectionStorage) DeleteMatching(ctx context.Context, filter bson.M) (int64, bool, error) {
+	var a args
+	where, exact := s.where(filter, &a)
+	if !exact {
+		return 0, false, nil
+	}
+	result, err := s.db.ExecContext(ctx, `DELETE FROM documents`+where, a...)
+	if err != nil {
+		return 0, false, err
+	}
+	deleted, err := result.RowsAffected()
+	return deleted, true, err
+}
+
+// Atomic implements document.Storage: fn runs in a transaction, or a savepoint of the one
+// ctx is in
+func (s *collectionStorage) Atomic(ctx context.Context, fn func(ctx context.Context) error) error {
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
@@ -0,0 +1,294 @@
+package pgstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+// table maps document field names onto the columns of one table
+type table struct {
+	name    string
+	columns map[string]string
+	// lists are the JSONB array columns; like in MongoDB, equality on them matches arrays
+	// containing the value
+	lists map[string]bool
+}
+
+var (
+	usersTable = table{name: "users", columns: map[string]string{
+		"_id": "id", "first_name": "first_name", "last_name": "last_name", "email": "email",
+		"password": "password", "role_id": "role_id", "profile_picture_url": "profile_picture_url",
+		"is_email_verified": "is_email_verified", "needs_password_change": "needs_password_change",
+		"weekly_digest": "weekly_digest", "locale": "locale", "is_service_account": "is_service_account",
+		"created_at": "created_at", "updated_at": "updated_at", "disabled": "disabled", "merged_into": "merged_into",
+		"phone": "phone", "address": "address", "time_zone": "time_zone",
+		"daily_summary": "daily_summary", "daily_summary_hour": "daily_summary_hour",
+	}}
+	tasksTable = table{name: "tasks", columns: map[string]string{
+		"_id": "id", "title": "title", "description": "description", "status": "status",
+		"user_id": "user_id", "due_date": "due_date", "completed_at": "completed_at",
+		"status_changed_at": "status_changed_at", "created_at": "created_at", "updated_at": "updated_at",
+		"project_id": "project_id", "archived": "archived", "milestone_id": "milestone_id", "sprint_id": "sprint_id",
+		"priority": "priority", "tags": "tags", "due_time_zone": "due_time_zone",
+		"stale_warned_at": "stale_warned_at", "stale_at": "stale_at", "assigned_at": "assigned_at",
+		"sla_breached_at": "sla_breached_at", "sla_rule_id": "sla_rule_id", "merged_into": "merged_into",
+		"color": "color",
+	}, lists: map[string]bool{"tags": true}}
+)
+
+// comparisonOperators maps the supported MongoDB comparison operators onto SQL
+var comparisonOperators = map[string]string{
+	"$eq": "=", "$ne": "<>", "$gt": ">", "$gte": ">=", "$lt": "<", "$lte": "<=",
+}
+
+// column returns the column for a document field, rejecting fields the table doesn't have
+func (t table) column(field string) (string, error) {
+	column, ok := t.columns[field]
+	if !ok {
+		return "", fmt.Errorf("%s has no column for field %q", t.name, field)
+	}
+	return column, nil
+}
+
+// args collects positional query arguments
+type args []interface{}
+
+// add appends value and returns its placeholder
+func (a *args) add(value interface{}) string {
+	*a = append(*a, sqlValue(value))
+	return fmt.Sprintf("$%d", len(*a))
+}
+
+// where translates a filter document into a WHERE clause ("" when filter is empty).
+// Supported: equality, nil (IS NULL), regexes, $eq/$ne/$gt/$gte/$lt/$lte/$in/$nin, $or and $and.
+func (t table) where(filter primitive.M, a *args) (string, error) {
+	conditions, err := t.conditions(filter, a)
+	if err != nil || len(conditions) == 0 {
+		return "", err
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), nil
+}
+
+// conditions translates each entry of filter, in key order so statements are deterministic
+func (t table) conditions(filter primitive.M, a *args) ([]string, error) {
+	keys := make([]string, 0, len(filter))
+	for key := range filter {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var conditions []string
+	for _, key := range keys {
+		value := filter[key]
+		if key == "$or" {
+			condition, err := t.or(value, a)
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, condition)
+			continue
+		}
+		if key == "$and" {
+			branches, err := documents("$and", value)
+			if err != nil {
+				return nil, err
+			}
+			for _, branch := range branches {
+				branchConditions, err := t.conditions(branch, a)
+				if err != nil {
+					return nil, err
+				}
+				conditions = append(conditions, branchConditions...)
+			}
+			continue
+		}
+
+		column, err := t.column(key)
+		if err != nil {
+			return nil, err
+		}
+		switch v := value.(type) {
+		case nil:
+			conditions = append(conditions, column+" IS NULL")
+		case primitive.Regex:
+			operator := "~"
+			if strings.Contains(v.Options, "i") {
+				operator = "~*"
+			}
+			conditions = append(conditions, column+" "+operator+" "+a.add(v.Pattern))
+		case primitive.M:
+			operatorConditions, err := t.operators(column, v, a)
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, operatorConditions...)
+		default:
+			if t.lists[column] {
+				conditions = append(conditions, column+" @> jsonb_build_array("+a.add(v)+"::text)")
+				continue
+			}
+			conditions = append(conditions, column+" = "+a.add(v))
+		}
+	}
+	return conditions, nil
+}
+
+// operators translates an operator document such as {"$gte": from, "$lte": to}
+func (t table) operators(column string, operators primitive.M, a *args) ([]string, error) {
+	names := make([]string, 0, len(operators))
+	for name := range operators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var conditions []string
+	for _, name := range names {
+		value := operators[name]
+		if name == "$in" || name == "$nin" {
+			list := reflect.ValueOf(value)
+			if list.Kind() != reflect.Slice {
+				return nil, fmt.Errorf("%s on %s expects a list", name, column)
+			}
+			if list.Len() == 0 {
+				if name == "$in" {
+					conditions = append(conditions, "FALSE")
+				}
+				continue
+			}
+			placeholders := make([]string, list.Len())
+			for i := range placeholders {
+				placeholders[i] = a.add(list.Index(i).Interface())
+			}
+			operator := " IN ("
+			if name == "$nin" {
+				operator = " NOT IN ("
+			}
+			conditions = append(conditions, column+operator+strings.Join(placeholders, ", ")+")")
+			continue
+		}
+
+		operator, ok := comparisonOperators[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported filter operator %s on %s", name, column)
+		}
+		conditions = append(conditions, column+" "+operator+" "+a.add(value))
+	}
+	return conditions, nil
+}
+
+// documents reads the list of filter documents given to a $or or $and operator
+func documents(operator string, value interface{}) ([]primitive.M, error) {
+	var branches []primitive.M
+	switch v := value.(type) {
+	case []primitive.M:
+		branches = v
+	case []interface{}:
+		for _, item := range v {
+			branch, ok := item.(primitive.M)
+			if !ok {
+				return nil, fmt.Errorf("%s expects a list of documents", operator)
+			}
+			branches = append(branches, branch)
+		}
+	default:
+		return nil, fmt.Errorf("%s expects a list of documents", operator)
+	}
+	return branches, nil
+}
+
+// or translates a $or list of filter documents
+func (t table) or(value interface{}, a *args) (string, error) {
+	branches, err := documents("$or", value)
+	if err != nil {
+		return "", err
+	}
+
+	parts := make([]string, 0, len(branches))
+	for _, branch := range branches {
+		conditions, err := t.conditions(branch, a)
+		if err != nil {
+			return "", err
+		}
+		if len(conditions) == 0 {
+			return "TRUE", nil
+		}
+		parts = append(parts, "("+strings.Join(conditions, " AND ")+")")
+	}
+	if len(parts) == 0 {
+		return "FALSE", nil
+	}
+	return "(" + strings.Join(parts, " OR ") + ")", nil
+}
+
+// orderBy translates the query's sort and paging into ORDER BY/LIMIT/OFFSET
+func (t table) orderBy(q *query.Query, a *args) (string, error) {
+	order, err := t.sortOrder(q)
+	if err != nil {
+		return "", err
+	}
+	return order + " LIMIT " + a.add(q.Limit) + " OFFSET " + a.add(q.Skip()), nil
+}
+
+// sortOrder translates the query's sort into ORDER BY
+func (t table) sortOrder(q *query.Query) (string, error) {
+	var terms []string
+	for _, field := range q.SortFields() {
+		column, err := t.column(field.Key)
+		if err != nil {
+			return "", err
+		}
+		direction := "ASC"
+		if n, ok := field.Value.(int); ok && n < 0 {
+			direction = "DESC"
+		}
+		terms = append(terms, column+" "+direction)
+	}
+	return " ORDER BY " + strings.Join(terms, ", "), nil
+}
+
+// set translates update fields into a SET clause
+func (t table) set(fields repository.Fields, a *args) (string, error) {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	assignments := make([]string, 0, len(keys))
+	for _, key := range keys {
+		column, err := t.column(key)
+		if err != nil {
+			return "", err
+		}
+		assignments = append(assignments, column+" = "+a.add(fields[key]))
+	}
+	return " SET " + strings.Join(assignments, ", "), nil
+}
+
+// sqlValue converts values database/sql can't encode itself
+func sqlValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case primitive.ObjectID:
+		return v.Hex()
+	case *primitive.ObjectID:
+		if v == nil {
+			return nil
+		}
+		return v.Hex()
+	case []string:
+		if v == nil {
+			v = []string{}
+		}
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	}
+	return value
+}
@@ -0,0 +1,279 @@
+package pgstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/OsGift/taskflow-api/internal/repository/document"
+)
+
+// The documents table keeps each document twice: as BSON, which is what reads return, and in
+// a JSONB query form that SQL can filter and sort on. The query form stores ObjectIDs as their
+// hex string and dates as milliseconds since the epoch, so both compare like in MongoDB;
+// values without a JSON equivalent are replaced by a {"$bson": type} placeholder.
+
+// queryForm converts a normalized value to its JSONB query form
+func queryForm(v interface{}) interface{} {
+	switch t := v.(type) {
+	case bson.M:
+		form := make(map[string]interface{}, len(t))
+		for key, value := range t {
+			form[key] = queryForm(value)
+		}
+		return form
+	case bson.A:
+		form := make([]interface{}, len(t))
+		for i, value := range t {
+			form[i] = queryForm(value)
+		}
+		return form
+	case nil, bool, int32, int64:
+		return t
+	case float64:
+		if math.IsNaN(t) || math.IsInf(t, 0) {
+			return map[string]interface{}{"$bson": "double"}
+		}
+		return t
+	case string:
+		return strings.ReplaceAll(t, "\x00", "") // JSONB can't hold NUL characters
+	case primitive.ObjectID:
+		return t.Hex()
+	case primitive.DateTime:
+		return int64(t)
+	}
+	return map[string]interface{}{"$bson": fmt.Sprintf("%T", v)}
+}
+
+// simpleField matches the field names that can be written into SQL and JSON paths as is
+var simpleField = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// fieldPath splits a dotted field into its components, or returns false when a component
+// isn't a simple field name (array indexes among them)
+func fieldPath(field string) ([]string, bool) {
+	path := strings.Split(field, ".")
+	for _, component := range path {
+		if !simpleField.MatchString(component) {
+			return nil, false
+		}
+	}
+	return path, true
+}
+
+// jsonPathLiteral writes a normalized scalar as a JSON path literal in its query form, or
+// returns false for values SQL can't compare the way MongoDB does
+func jsonPathLiteral(v interface{}) (string, bool) {
+	switch t := v.(type) {
+	case nil:
+		return "null", true
+	case bool:
+		return strconv.FormatBool(t), true
+	case int32:
+		return strconv.FormatInt(int64(t), 10), true
+	case int64:
+		return strconv.FormatInt(t, 10), true
+	case float64:
+		if math.IsNaN(t) || math.IsInf(t, 0) {
+			return "", false
+		}
+		return strconv.FormatFloat(t, 'f', -1, 64), true
+	case primitive.DateTime:
+		return strconv.FormatInt(int64(t), 10), true
+	case primitive.ObjectID:
+		return `"` + t.Hex() + `"`, true
+	case string:
+		if strings.ContainsRune(t, 0) {
+			return "", false
+		}
+		encoded, err := json.Marshal(t)
+		if err != nil {
+			return "", false
+		}
+		return string(encoded), true
+	}
+	return "", false
+}
+
+// jsonPathFilter translates a normalized MongoDB filter into a JSON path predicate on the
+// query form, for the @? operator. Conditions it can't translate are left out, in which case
+// it returns false and the predicate selects more documents than the filter; an empty
+// predicate selects every document.
+func jsonPathFilter(filter bson.M) (string, bool) {
+	predicate, exact := conjunction(filter)
+	if predicate == "" {
+		return "", exact
+	}
+	return "$ ? (" + predicate + ")", exact
+}
+
+// conjunction translates the conditions of filter, joined with &&
+func conjunction(filter bson.M) (string, bool) {
+	exact := true
+	var predicates []string
+	for _, key := range sortedKeys(filter) {
+		predicate, ok := condition(key, filter[key])
+		if !ok {
+			exact = false
+		}
+		if predicate != "" {
+			predicates = append(predicates, predicate)
+		}
+	}
+	return strings.Join(predicates, " && "), exact
+}
+
+// sortedKeys returns the keys of m in order, so equal filters give equal statements
+func sortedKeys(m bson.M) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// condition translates one top-level condition of a filter. It returns an empty predicate
+// when nothing of it could be translated.
+func condition(key string, value interface{}) (string, bool) {
+	switch key {
+	case "$and":
+		clauses, _ := value.(bson.A)
+		exact := len(clauses) > 0
+		var predicates []string
+		for _, clause := range clauses {
+			filter, ok := clause.(bson.M)
+			if !ok {
+				return "", false
+			}
+			predicate, ok := conjunction(filter)
+			exact = exact && ok
+			if predicate != "" {
+				predicates = append(predicates, "("+predicate+")")
+			}
+		}
+		return strings.Join(predicates, " && "), exact
+	case "$or", "$nor":
+		// Leaving a clause out would select fewer documents, so it's all or nothing
+		clauses, _ := value.(bson.A)
+		if len(clauses) == 0 {
+			return "", false
+		}
+		predicates := make([]string, 0, len(clauses))
+		for _, clause := range clauses {
+			filter, ok := clause.(bson.M)
+			if !ok {
+				return "", false
+			}
+			predicate, exact := conjunction(filter)
+			if !exact || predicate == "" {
+				return "", false
+			}
+			predicates = append(predicates, "("+predicate+")")
+		}
+		if key == "$nor" {
+			return negate(strings.Join(predicates, " || ")), true
+		}
+		return "(" + strings.Join(predicates, " || ") + ")", true
+	}
+
+	path, ok := fieldPath(key)
+	if !ok {
+		return "", false
+	}
+	accessor := "@"
+	for _, component := range path {
+		accessor += `."` + component + `"`
+	}
+	if !document.IsOperatorDocument(value) {
+		return equality(accessor, value)
+	}
+
+	exact := true
+	var predicates []string
+	operators := value.(bson.M)
+	for _, operator := range sortedKeys(operators) {
+		predicate, ok := operatorCondition(accessor, operator, operators[operator])
+		if !ok {
+			exact = false
+			continue
+		}
+		predicates = append(predicates, predicate)
+	}
+	return strings.Join(predicates, " && "), exact
+}
+
+// equality translates a comparison with value; null also matches a missing field
+func equality(accessor string, value interface{}) (string, bool) {
+	literal, ok := jsonPathLiteral(value)
+	if !ok {
+		return "", false
+	}
+	if value == nil {
+		return "(!exists(" + accessor + ") || " + accessor + " == null)", true
+	}
+	return accessor + " == " + literal, true
+}
+
+// negate negates a predicate the way MongoDB's $ne and $nin do, matching documents whose field
+// has another type too, where the JSON path comparison is unknown
+func negate(predicate string) string {
+	return "(!(" + predicate + ") || (" + predicate + ") is unknown)"
+}
+
+// comparisonSymbols maps MongoDB's range operators onto JSON path comparisons
+var comparisonSymbols = map[string]string{"$gt": ">", "$gte": ">=", "$lt": "<", "$lte": "<="}
+
+// operatorCondition translates one query operator applied to the field at accessor
+func operatorCondition(accessor, operator string, operand interface{}) (string, bool) {
+	switch operator {
+	case "$eq":
+		return equality(accessor, operand)
+	case "$ne":
+		predicate, ok := equality(accessor, operand)
+		if !ok {
+			return "", false
+		}
+		return negate(predicate), true
+	case "$gt", "$gte", "$lt", "$lte":
+		literal, ok := jsonPathLiteral(operand)
+		if !ok || operand == nil {
+			return "", false
+		}
+		return accessor + " " + comparisonSymbols[operator] + " " + literal, true
+	case "$in", "$nin":
+		options, _ := operand.(bson.A)
+		if len(options) == 0 {
+			return "", false
+		}
+		predicates := make([]string, 0, len(options))
+		for _, option := range options {
+			predicate, ok := equality(accessor, option)
+			if !ok {
+				return "", false
+			}
+			predicates = append(predicates, predicate)
+		}
+		predicate := "(" + strings.Join(predicates, " || ") + ")"
+		if operator == "$nin" {
+			return negate(predicate), true
+		}
+		return predicate, true
+	case "$exists":
+		exists, ok := operand.(bool)
+		if !ok {
+			return "", false
+		}
+		if exists {
+			return "exists(" + accessor + ")", true
+		}
+		return "!exists(" + accessor + ")", true
+	}
+	return "", false
+}
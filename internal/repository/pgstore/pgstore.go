@@ -0,0 +1,353 @@
+// Package pgstore implements the repositories on PostgreSQL, for deployments that can't run
+// MongoDB: users, roles and tasks in their own tables, every other collection in the
+// documents table. IDs keep the ObjectID format (stored as 24-character hex strings) so API
+// responses look the same on either backend.
+package pgstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+// schema creates the tables and indexes on first start. Statements must stay idempotent;
+// add columns with ALTER TABLE ... ADD COLUMN IF NOT EXISTS.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS roles (
+		id          CHAR(24) PRIMARY KEY,
+		name        TEXT NOT NULL UNIQUE,
+		permissions JSONB NOT NULL DEFAULT '[]'
+	)`,
+	`CREATE TABLE IF NOT EXISTS users (
+		id                    CHAR(24) PRIMARY KEY,
+		first_name            TEXT NOT NULL,
+		last_name             TEXT NOT NULL,
+		email                 TEXT NOT NULL UNIQUE,
+		password              TEXT NOT NULL,
+		role_id               CHAR(24) NOT NULL REFERENCES roles (id),
+		profile_picture_url   TEXT NOT NULL DEFAULT '',
+		is_email_verified     BOOLEAN NOT NULL DEFAULT FALSE,
+		needs_password_change BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at            TIMESTAMPTZ NOT NULL,
+		updated_at            TIMESTAMPTZ NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS users_role_id ON users (role_id)`,
+	`CREATE INDEX IF NOT EXISTS users_created_at_desc ON users (created_at DESC)`,
+	`CREATE TABLE IF NOT EXISTS tasks (
+		id          CHAR(24) PRIMARY KEY,
+		title       TEXT NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		status      TEXT NOT NULL,
+		user_id     CHAR(24) NOT NULL REFERENCES users (id),
+		created_at  TIMESTAMPTZ NOT NULL,
+		updated_at  TIMESTAMPTZ NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS tasks_user_id_created_at ON tasks (user_id, created_at DESC)`,
+	`CREATE INDEX IF NOT EXISTS tasks_user_id_status ON tasks (user_id, status)`,
+	`CREATE INDEX IF NOT EXISTS tasks_status ON tasks (status)`,
+	`CREATE INDEX IF NOT EXISTS tasks_created_at_desc ON tasks (created_at DESC)`,
+	`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS due_date TIMESTAMPTZ`,
+	`CREATE INDEX IF NOT EXISTS tasks_user_id_due_date ON tasks (user_id, due_date)`,
+	`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS completed_at TIMESTAMPTZ`,
+	`UPDATE tasks SET completed_at = updated_at WHERE status = 'done' AND completed_at IS NULL`,
+	`CREATE INDEX IF NOT EXISTS tasks_user_id_completed_at ON tasks (user_id, completed_at DESC)`,
+	`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS status_changed_at TIMESTAMPTZ`,
+	`UPDATE tasks SET status_changed_at = COALESCE(completed_at, created_at) WHERE status_changed_at IS NULL`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS weekly_digest BOOLEAN NOT NULL DEFAULT FALSE`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS locale TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS is_service_account BOOLEAN NOT NULL DEFAULT FALSE`,
+	`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS project_id CHAR(24)`,
+	`CREATE INDEX IF NOT EXISTS tasks_project_id_created_at ON tasks (project_id, created_at DESC)`,
+	`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS archived BOOLEAN NOT NULL DEFAULT FALSE`,
+	`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS milestone_id CHAR(24)`,
+	`CREATE INDEX IF NOT EXISTS tasks_milestone_id ON tasks (milestone_id)`,
+	`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS sprint_id CHAR(24)`,
+	`CREATE INDEX IF NOT EXISTS tasks_sprint_id ON tasks (sprint_id)`,
+	`CREATE INDEX IF NOT EXISTS tasks_user_id_lower_title ON tasks (user_id, lower(title) text_pattern_ops)`,
+	`CREATE INDEX IF NOT EXISTS tasks_lower_title ON tasks (lower(title) text_pattern_ops)`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS disabled BOOLEAN NOT NULL DEFAULT FALSE`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS merged_into CHAR(24)`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS phone TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS address TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS priority TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS tags JSONB NOT NULL DEFAULT '[]'`,
+	`CREATE INDEX IF NOT EXISTS tasks_tags ON tasks USING GIN (tags)`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS time_zone TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS due_time_zone TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS stale_warned_at TIMESTAMPTZ`,
+	`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS stale_at TIMESTAMPTZ`,
+	`CREATE INDEX IF NOT EXISTS tasks_status_updated_at ON tasks (status, updated_at)`,
+	`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS assigned_at TIMESTAMPTZ`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS daily_summary BOOLEAN NOT NULL DEFAULT FALSE`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS daily_summary_hour INTEGER`,
+	`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS sla_breached_at TIMESTAMPTZ`,
+	`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS sla_rule_id CHAR(24)`,
+	`CREATE INDEX IF NOT EXISTS tasks_status_status_changed_at ON tasks (status, status_changed_at)`,
+	`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS merged_into CHAR(24)`,
+	`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS color TEXT NOT NULL DEFAULT ''`,
+	// Every other collection is stored as documents, in BSON and in a JSONB form for queries
+	// (see jsonpath.go); ensureDocumentIndexes adds their indexes
+	`CREATE TABLE IF NOT EXISTS documents (
+		collection TEXT NOT NULL,
+		id         TEXT NOT NULL,
+		data       BYTEA NOT NULL,
+		doc        JSONB NOT NULL,
+		PRIMARY KEY (collection, id)
+	)`,
+	`CREATE INDEX IF NOT EXISTS documents_doc ON documents USING GIN (doc jsonb_path_ops)`,
+	`CREATE TABLE IF NOT EXISTS document_ttls (
+		collection           TEXT NOT NULL,
+		field                TEXT NOT NULL,
+		expire_after_seconds BIGINT NOT NULL,
+		PRIMARY KEY (collection, field)
+	)`,
+}
+
+// Open connects to PostgreSQL and creates the schema if it doesn't exist yet
+func Open(url string) (*sql.DB, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	for _, statement := range schema {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to apply PostgreSQL schema: %w", err)
+		}
+	}
+	if err := ensureDocumentIndexes(ctx, conn{db: db}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to apply PostgreSQL schema: %w", err)
+	}
+
+	log.Println("Successfully connected to PostgreSQL")
+	return db, nil
+}
+
+// New returns the PostgreSQL-backed repositories for db. It starts expiring the documents
+// of the collections with TTL indexes, as MongoDB would, for as long as the process runs.
+func New(db *sql.DB) *repository.Store {
+	c := conn{db: db}
+	docs := &documentStore{db: c}
+	go docs.expire(context.Background(), time.Minute)
+	return &repository.Store{
+		Users:     &userRepository{db: c},
+		Roles:     &roleRepository{db: c},
+		Tasks:     &taskRepository{db: c},
+		Documents: docs,
+	}
+}
+
+// txKey is the context key of the transaction started by documentStore.WithTransaction
+type txKey struct{}
+
+// conn runs statements on db, or on the transaction of the context they are given, so every
+// repository joins the transactions started by documentStore.WithTransaction
+type conn struct {
+	db *sql.DB
+}
+
+// tx returns the transaction ctx is in, or nil
+func (c conn) tx(ctx context.Context) *sql.Tx {
+	tx, _ := ctx.Value(txKey{}).(*sql.Tx)
+	return tx
+}
+
+// ExecContext runs a statement that returns no rows
+func (c conn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if tx := c.tx(ctx); tx != nil {
+		return tx.ExecContext(ctx, query, args...)
+	}
+	return c.db.ExecContext(ctx, query, args...)
+}
+
+// QueryContext runs a query that returns rows
+func (c conn) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if tx := c.tx(ctx); tx != nil {
+		return tx.QueryContext(ctx, query, args...)
+	}
+	return c.db.QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext runs a query that returns at most one row
+func (c conn) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if tx := c.tx(ctx); tx != nil {
+		return tx.QueryRowContext(ctx, query, args...)
+	}
+	return c.db.QueryRowContext(ctx, query, args...)
+}
+
+// scanner is implemented by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// idColumn scans a hex id column into an ObjectID
+type idColumn struct {
+	id *primitive.ObjectID
+}
+
+// Scan implements sql.Scanner
+func (c idColumn) Scan(src interface{}) error {
+	var hex string
+	switch v := src.(type) {
+	case string:
+		hex = v
+	case []byte:
+		hex = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into an ObjectID", src)
+	}
+	id, err := primitive.ObjectIDFromHex(strings.TrimSpace(hex))
+	if err != nil {
+		return err
+	}
+	*c.id = id
+	return nil
+}
+
+// nullIDColumn scans a nullable hex id column into an *ObjectID, leaving it nil for NULL
+type nullIDColumn struct {
+	id **primitive.ObjectID
+}
+
+// Scan implements sql.Scanner
+func (c nullIDColumn) Scan(src interface{}) error {
+	if src == nil {
+		*c.id = nil
+		return nil
+	}
+	var id primitive.ObjectID
+	if err := (idColumn{&id}).Scan(src); err != nil {
+		return err
+	}
+	*c.id = &id
+	return nil
+}
+
+// eachRow runs a query and calls fn with each row read by scan, stopping at the first error.
+// Inside a transaction, which can't run fn's statements while rows are left to read, every
+// row is read before fn is called.
+func eachRow[T any](ctx context.Context, db conn, query string, scan func(scanner) (*T, error), fn func(*T) error, queryArgs ...interface{}) error {
+	rows, err := db.QueryContext(ctx, query, queryArgs...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var buffered []*T
+	for rows.Next() {
+		record, err := scan(rows)
+		if err != nil {
+			return err
+		}
+		if db.tx(ctx) != nil {
+			buffered = append(buffered, record)
+			continue
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+	for _, record := range buffered {
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// estimateCount approximates the number of rows of t matching filter. An empty filter reads
+// the row estimate kept by ANALYZE, unless the table was never analyzed; otherwise counting
+// stops at limit rows.
+func estimateCount(ctx context.Context, db conn, t table, filter primitive.M, limit int64) (int64, error) {
+	var count int64
+	if len(filter) == 0 {
+		err := db.QueryRowContext(ctx, `SELECT reltuples::bigint FROM pg_class WHERE oid = $1::regclass`, t.name).Scan(&count)
+		if err != nil || count >= 0 {
+			return count, err
+		}
+	}
+
+	var a args
+	where, err := t.where(filter, &a)
+	if err != nil {
+		return 0, err
+	}
+	err = db.QueryRowContext(ctx, `SELECT COUNT(*) FROM (SELECT 1 FROM `+t.name+where+` LIMIT `+a.add(limit)+`) capped`, a...).Scan(&count)
+	return count, err
+}
+
+// withTx runs fn in a transaction, committing on success and rolling back otherwise. Inside
+// the transaction of ctx, fn runs in a savepoint of it instead: a failure then only undoes
+// fn's statements, and leaves the transaction usable.
+func withTx(ctx context.Context, db conn, fn func(tx *sql.Tx) error) error {
+	if tx := db.tx(ctx); tx != nil {
+		if _, err := tx.ExecContext(ctx, `SAVEPOINT pgstore`); err != nil {
+			return err
+		}
+		if err := fn(tx); err != nil {
+			tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT pgstore`)
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT pgstore`)
+		return err
+	}
+
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// translateError maps driver errors onto the repository errors
+func translateError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.ErrNotFound
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" { // unique_violation
+		return repository.ErrDuplicate
+	}
+	return err
+}
+
+// affectedOne returns ErrNotFound when an update or delete matched no rows
+func affectedOne(result sql.Result, err error) error {
+	if err != nil {
+		return translateError(err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package pgstore
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+)
+
+// roleRepository stores roles in the "roles" table; permissions are kept as JSONB
+type roleRepository struct {
+	db conn
+}
+
+// scanRole reads one (id, name, permissions) row
+func scanRole(row scanner) (*models.Role, error) {
+	var role models.Role
+	var permissions []byte
+	if err := row.Scan(idColumn{&role.ID}, &role.Name, &permissions); err != nil {
+		return nil, translateError(err)
+	}
+	if err := json.Unmarshal(permissions, &role.Permissions); err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// FindByID retrieves a role by ID
+func (r *roleRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Role, error) {
+	return scanRole(r.db.QueryRowContext(ctx, `SELECT id, name, permissions FROM roles WHERE id = $1`, id.Hex()))
+}
+
+// FindByIDs retrieves the roles with the given IDs
+func (r *roleRepository) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Role, error) {
+	roles := []models.Role{}
+	if len(ids) == 0 {
+		return roles, nil
+	}
+	var a args
+	placeholders := make([]string, len(ids))
+	for i, id := range ids {
+		placeholders[i] = a.add(id)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT id, name, permissions FROM roles WHERE id IN (`+strings.Join(placeholders, ", ")+`)`, a...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, *role)
+	}
+	return roles, rows.Err()
+}
+
+// FindByName retrieves a role by name
+func (r *roleRepository) FindByName(ctx context.Context, name string) (*models.Role, error) {
+	return scanRole(r.db.QueryRowContext(ctx, `SELECT id, name, permissions FROM roles WHERE name = $1`, name))
+}
+
+// Sync inserts the role or refreshes the permissions of the existing role with its name
+func (r *roleRepository) Sync(ctx context.Context, role models.Role) (bool, error) {
+	if role.ID.IsZero() {
+		role.ID = primitive.NewObjectID()
+	}
+	permissions, err := json.Marshal(role.Permissions)
+	if err != nil {
+		return false, err
+	}
+
+	// xmax is 0 only for freshly inserted rows
+	var created bool
+	err = r.db.QueryRowContext(ctx, `INSERT INTO roles (id, name, permissions) VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO UPDATE SET permissions = EXCLUDED.permissions
+		RETURNING xmax = 0`, role.ID.Hex(), role.Name, permissions).Scan(&created)
+	return created, err
+}
+
+// Each calls fn with every role in ID order
+func (r *roleRepository) Each(ctx context.Context, fn func(*models.Role) error) error {
+	return eachRow(ctx, r.db, `SELECT id, name, permissions FROM roles ORDER BY id`, scanRole, fn)
+}
@@ -0,0 +1,326 @@
+package pgstore
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+const taskColumns = `id, title, description, status, user_id, due_date, completed_at, status_changed_at,
+	created_at, updated_at, project_id, archived, milestone_id, sprint_id, priority, tags, due_time_zone,
+	stale_warned_at, stale_at, assigned_at, sla_breached_at, sla_rule_id, merged_into, color`
+
+// taskRepository stores tasks in the "tasks" table
+type taskRepository struct {
+	db conn
+}
+
+// scanTask reads one row selected with taskColumns
+func scanTask(row scanner) (*models.Task, error) {
+	var task models.Task
+	var tags []byte
+	err := row.Scan(idColumn{&task.ID}, &task.Title, &task.Description, &task.Status,
+		idColumn{&task.UserID}, &task.DueDate, &task.CompletedAt, &task.StatusChangedAt, &task.CreatedAt, &task.UpdatedAt,
+		nullIDColumn{&task.ProjectID}, &task.Archived, nullIDColumn{&task.MilestoneID}, nullIDColumn{&task.SprintID},
+		&task.Priority, &tags, &task.DueTimeZone, &task.StaleWarnedAt, &task.StaleAt, &task.AssignedAt,
+		&task.SLABreachedAt, nullIDColumn{&task.SLARuleID}, nullIDColumn{&task.MergedInto}, &task.Color)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	if err := json.Unmarshal(tags, &task.Tags); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// Create inserts a new task
+func (r *taskRepository) Create(ctx context.Context, task *models.Task) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO tasks (`+taskColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24)`,
+		task.ID.Hex(), task.Title, task.Description, task.Status, task.UserID.Hex(), task.DueDate, task.CompletedAt,
+		task.StatusChangedAt, task.CreatedAt, task.UpdatedAt, sqlValue(task.ProjectID), task.Archived, sqlValue(task.MilestoneID), sqlValue(task.SprintID),
+		task.Priority, sqlValue(task.Tags), task.DueTimeZone, task.StaleWarnedAt, task.StaleAt, task.AssignedAt,
+		task.SLABreachedAt, sqlValue(task.SLARuleID), sqlValue(task.MergedInto), task.Color)
+	return translateError(err)
+}
+
+// FindByID retrieves a task by ID
+func (r *taskRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Task, error) {
+	return scanTask(r.db.QueryRowContext(ctx, `SELECT `+taskColumns+` FROM tasks WHERE id = $1`, id.Hex()))
+}
+
+// List returns one page of tasks matching the query
+func (r *taskRepository) List(ctx context.Context, q *query.Query) ([]models.Task, error) {
+	var a args
+	where, err := tasksTable.where(q.Filter, &a)
+	if err != nil {
+		return nil, err
+	}
+	orderBy, err := tasksTable.orderBy(q, &a)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+taskColumns+` FROM tasks`+where+orderBy, a...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []models.Task
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, *task)
+	}
+	return tasks, rows.Err()
+}
+
+// Each calls fn with every task in ID order
+func (r *taskRepository) Each(ctx context.Context, fn func(*models.Task) error) error {
+	return eachRow(ctx, r.db, `SELECT `+taskColumns+` FROM tasks ORDER BY id`, scanTask, fn)
+}
+
+// EachMatching calls fn with every task matching q's filter, in q's sort order
+func (r *taskRepository) EachMatching(ctx context.Context, q *query.Query, fn func(*models.Task) error) error {
+	var a args
+	where, err := tasksTable.where(q.Filter, &a)
+	if err != nil {
+		return err
+	}
+	orderBy, err := tasksTable.sortOrder(q)
+	if err != nil {
+		return err
+	}
+	return eachRow(ctx, r.db, `SELECT `+taskColumns+` FROM tasks`+where+orderBy, scanTask, fn, a...)
+}
+
+// Count counts the tasks matching filter
+func (r *taskRepository) Count(ctx context.Context, filter primitive.M) (int64, error) {
+	var a args
+	where, err := tasksTable.where(filter, &a)
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	err = r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM tasks`+where, a...).Scan(&count)
+	return count, err
+}
+
+// EstimateCount reads the planner's row estimate when filter is empty, and otherwise counts
+// up to limit matching tasks
+func (r *taskRepository) EstimateCount(ctx context.Context, filter primitive.M, limit int64) (int64, error) {
+	return estimateCount(ctx, r.db, tasksTable, filter, limit)
+}
+
+// CountByStatus counts the tasks matching filter per status
+func (r *taskRepository) CountByStatus(ctx context.Context, filter primitive.M) ([]models.TaskStatusCount, error) {
+	var a args
+	where, err := tasksTable.where(filter, &a)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT status, COUNT(*) FROM tasks`+where+` GROUP BY status`, a...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []models.TaskStatusCount
+	for rows.Next() {
+		var count models.TaskStatusCount
+		if err := rows.Scan(&count.Status, &count.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+	return counts, rows.Err()
+}
+
+// CountByUser counts the tasks matching filter per owner
+func (r *taskRepository) CountByUser(ctx context.Context, filter primitive.M) ([]models.UserTaskCount, error) {
+	var a args
+	where, err := tasksTable.where(filter, &a)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT user_id, COUNT(*) FROM tasks`+where+` GROUP BY user_id`, a...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []models.UserTaskCount
+	for rows.Next() {
+		var count models.UserTaskCount
+		if err := rows.Scan(idColumn{&count.UserID}, &count.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+	return counts, rows.Err()
+}
+
+// likeEscaper escapes the LIKE wildcards in a literal prefix
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SuggestTitles matches the prefix against lower(title), which the tasks_*_lower_title
+// indexes cover with text_pattern_ops so that LIKE 'prefix%' is a range scan
+func (r *taskRepository) SuggestTitles(ctx context.Context, filter primitive.M, prefix string, limit int64) ([]models.TaskSuggestion, error) {
+	var a args
+	conditions, err := tasksTable.conditions(filter, &a)
+	if err != nil {
+		return nil, err
+	}
+	conditions = append(conditions, "lower(title) LIKE "+a.add(likeEscaper.Replace(strings.ToLower(prefix))+"%"))
+
+	rows, err := r.db.QueryContext(ctx, `SELECT id, title, status FROM tasks WHERE `+strings.Join(conditions, " AND ")+
+		` ORDER BY lower(title) LIMIT `+a.add(limit), a...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var suggestions []models.TaskSuggestion
+	for rows.Next() {
+		var suggestion models.TaskSuggestion
+		if err := rows.Scan(idColumn{&suggestion.ID}, &suggestion.Title, &suggestion.Status); err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions, rows.Err()
+}
+
+// CompletionLeaderboard ranks users by completed tasks with RANK(); the window count gives
+// the number of ranked users alongside the page
+func (r *taskRepository) CompletionLeaderboard(ctx context.Context, from, to time.Time, skip, limit int64) ([]models.LeaderboardEntry, int64, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		WITH counts AS (
+			SELECT user_id, COUNT(*) AS completed_count FROM tasks
+			WHERE status = $1 AND completed_at >= $2 AND completed_at <= $3
+			GROUP BY user_id
+		), ranked AS (
+			SELECT user_id, completed_count,
+				RANK() OVER (ORDER BY completed_count DESC) AS rank,
+				COUNT(*) OVER () AS total
+			FROM counts
+		)
+		SELECT ranked.rank, ranked.user_id, COALESCE(users.first_name, ''), COALESCE(users.last_name, ''),
+			ranked.completed_count, ranked.total
+		FROM ranked LEFT JOIN users ON users.id = ranked.user_id
+		ORDER BY ranked.rank, ranked.user_id
+		LIMIT $4 OFFSET $5`,
+		models.StatusDone, from, to, limit, skip)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := []models.LeaderboardEntry{}
+	var total int64
+	for rows.Next() {
+		var entry models.LeaderboardEntry
+		if err := rows.Scan(&entry.Rank, idColumn{&entry.UserID}, &entry.FirstName, &entry.LastName,
+			&entry.CompletedCount, &total); err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	// A page past the end has no rows to carry the total
+	if len(entries) == 0 && skip > 0 {
+		err = r.db.QueryRowContext(ctx, `SELECT COUNT(DISTINCT user_id) FROM tasks
+			WHERE status = $1 AND completed_at >= $2 AND completed_at <= $3`, models.StatusDone, from, to).Scan(&total)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	return entries, total, nil
+}
+
+// ActivityByDay unions the creation and completion events of the matching tasks and counts
+// them per day in loc
+func (r *taskRepository) ActivityByDay(ctx context.Context, filter primitive.M, from time.Time, loc *time.Location) ([]models.ActivityDay, error) {
+	var a args
+	conditions, err := tasksTable.conditions(filter, &a)
+	if err != nil {
+		return nil, err
+	}
+	since := a.add(from)
+	where := func(column string) string {
+		return " WHERE " + strings.Join(append([]string{column + " >= " + since}, conditions...), " AND ")
+	}
+	day := func(column string) string {
+		return "to_char(" + column + " AT TIME ZONE " + a.add(loc.String()) + ", 'YYYY-MM-DD')"
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT day, SUM(created), SUM(completed) FROM (
+			SELECT `+day("created_at")+` AS day, 1 AS created, 0 AS completed FROM tasks`+where("created_at")+`
+			UNION ALL
+			SELECT `+day("completed_at")+`, 0, 1 FROM tasks`+where("completed_at")+`
+		) AS events
+		GROUP BY day ORDER BY day`, a...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	activity := []models.ActivityDay{}
+	for rows.Next() {
+		var day models.ActivityDay
+		if err := rows.Scan(&day.Date, &day.Created, &day.Completed); err != nil {
+			return nil, err
+		}
+		day.Count = day.Created + day.Completed
+		activity = append(activity, day)
+	}
+	return activity, rows.Err()
+}
+
+// Update sets fields on a task
+func (r *taskRepository) Update(ctx context.Context, id primitive.ObjectID, fields repository.Fields) error {
+	var a args
+	set, err := tasksTable.set(fields, &a)
+	if err != nil {
+		return err
+	}
+	return affectedOne(r.db.ExecContext(ctx, `UPDATE tasks`+set+` WHERE id = `+a.add(id), a...))
+}
+
+// UpdateMany sets fields on every task matching filter
+func (r *taskRepository) UpdateMany(ctx context.Context, filter primitive.M, fields repository.Fields) (int64, error) {
+	var a args
+	set, err := tasksTable.set(fields, &a)
+	if err != nil {
+		return 0, err
+	}
+	where, err := tasksTable.where(filter, &a)
+	if err != nil {
+		return 0, err
+	}
+	result, err := r.db.ExecContext(ctx, `UPDATE tasks`+set+where, a...)
+	if err != nil {
+		return 0, translateError(err)
+	}
+	return result.RowsAffected()
+}
+
+// Delete removes a task
+func (r *taskRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return affectedOne(r.db.ExecContext(ctx, `DELETE FROM tasks WHERE id = $1`, id.Hex()))
+}
@@ -0,0 +1,271 @@
+package pgstore
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+const userColumns = `id, first_name, last_name, email, password, role_id, profile_picture_url,
+	is_email_verified, needs_password_change, weekly_digest, locale, is_service_account, created_at, updated_at,
+	disabled, merged_into, phone, address, time_zone, daily_summary, daily_summary_hour`
+
+// userRepository stores users in the "users" table
+type userRepository struct {
+	db conn
+}
+
+// scanUser reads one row selected with userColumns
+func scanUser(row scanner) (*models.User, error) {
+	var user models.User
+	err := row.Scan(idColumn{&user.ID}, &user.FirstName, &user.LastName, &user.Email, &user.Password,
+		idColumn{&user.RoleID}, &user.ProfilePictureURL, &user.IsEmailVerified, &user.NeedsPasswordChange,
+		&user.WeeklyDigest, &user.Locale, &user.IsServiceAccount, &user.CreatedAt, &user.UpdatedAt,
+		&user.Disabled, nullIDColumn{&user.MergedInto}, &user.Phone, &user.Address, &user.TimeZone,
+		&user.DailySummary, &user.DailySummaryHour)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &user, nil
+}
+
+// Create inserts a new user
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO users (`+userColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)`,
+		user.ID.Hex(), user.FirstName, user.LastName, user.Email, user.Password, user.RoleID.Hex(),
+		user.ProfilePictureURL, user.IsEmailVerified, user.NeedsPasswordChange, user.WeeklyDigest, user.Locale,
+		user.IsServiceAccount, user.CreatedAt, user.UpdatedAt, user.Disabled, sqlValue(user.MergedInto),
+		user.Phone, user.Address, user.TimeZone, user.DailySummary, user.DailySummaryHour)
+	return translateError(err)
+}
+
+// FindByID retrieves a user by ID
+func (r *userRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	return scanUser(r.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`, id.Hex()))
+}
+
+// FindByEmail retrieves a user by email address
+func (r *userRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return scanUser(r.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE email = $1`, email))
+}
+
+// List returns one page of users matching the query
+func (r *userRepository) List(ctx context.Context, q *query.Query) ([]models.User, error) {
+	var a args
+	where, err := usersTable.where(q.Filter, &a)
+	if err != nil {
+		return nil, err
+	}
+	orderBy, err := usersTable.orderBy(q, &a)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+userColumns+` FROM users`+where+orderBy, a...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	return users, rows.Err()
+}
+
+// Each calls fn with every user in ID order
+func (r *userRepository) Each(ctx context.Context, fn func(*models.User) error) error {
+	return eachRow(ctx, r.db, `SELECT `+userColumns+` FROM users ORDER BY id`, scanUser, fn)
+}
+
+// EachMatching calls fn with every user matching q's filter, in q's sort order
+func (r *userRepository) EachMatching(ctx context.Context, q *query.Query, fn func(*models.User) error) error {
+	var a args
+	where, err := usersTable.where(q.Filter, &a)
+	if err != nil {
+		return err
+	}
+	orderBy, err := usersTable.sortOrder(q)
+	if err != nil {
+		return err
+	}
+	return eachRow(ctx, r.db, `SELECT `+userColumns+` FROM users`+where+orderBy, scanUser, fn, a...)
+}
+
+// Count counts the users matching filter
+func (r *userRepository) Count(ctx context.Context, filter primitive.M) (int64, error) {
+	var a args
+	where, err := usersTable.where(filter, &a)
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	err = r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users`+where, a...).Scan(&count)
+	return count, err
+}
+
+// EstimateCount reads the planner's row estimate when filter is empty, and otherwise counts
+// up to limit matching users
+func (r *userRepository) EstimateCount(ctx context.Context, filter primitive.M, limit int64) (int64, error) {
+	return estimateCount(ctx, r.db, usersTable, filter, limit)
+}
+
+// Update sets fields on a user
+func (r *userRepository) Update(ctx context.Context, id primitive.ObjectID, fields repository.Fields) error {
+	var a args
+	set, err := usersTable.set(fields, &a)
+	if err != nil {
+		return err
+	}
+	return affectedOne(r.db.ExecContext(ctx, `UPDATE users`+set+` WHERE id = `+a.add(id), a...))
+}
+
+// ReplacePassword sets the password hash if it is still oldHash
+func (r *userRepository) ReplacePassword(ctx context.Context, id primitive.ObjectID, oldHash, newHash string) error {
+	return affectedOne(r.db.ExecContext(ctx, `UPDATE users SET password = $1, updated_at = $2 WHERE id = $3 AND password = $4`,
+		newHash, time.Now(), id.Hex(), oldHash))
+}
+
+// UpdateRole assigns a role by name. The role row is locked until the user is updated
+// so a role removed concurrently can't be assigned.
+func (r *userRepository) UpdateRole(ctx context.Context, id primitive.ObjectID, roleName string) error {
+	return withTx(ctx, r.db, func(tx *sql.Tx) error {
+		var roleID string
+		err := tx.QueryRowContext(ctx, `SELECT id FROM roles WHERE name = $1 FOR SHARE`, roleName).Scan(&roleID)
+		if err == sql.ErrNoRows {
+			return repository.ErrRoleNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return affectedOne(tx.ExecContext(ctx, `UPDATE users SET role_id = $1, updated_at = $2 WHERE id = $3`,
+			roleID, time.Now(), id.Hex()))
+	})
+}
+
+// Delete removes a user and deletes or reassigns their tasks in a single transaction
+func (r *userRepository) Delete(ctx context.Context, id primitive.ObjectID, reassignTo *primitive.ObjectID) error {
+	return withTx(ctx, r.db, func(tx *sql.Tx) error {
+		if reassignTo != nil {
+			var exists bool
+			err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 FOR SHARE)`, reassignTo.Hex()).Scan(&exists)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				return repository.ErrReassignTargetNotFound
+			}
+			_, err = tx.ExecContext(ctx, `UPDATE tasks SET user_id = $1, assigned_at = $2, updated_at = $2 WHERE user_id = $3`,
+				reassignTo.Hex(), time.Now(), id.Hex())
+			if err != nil {
+				return err
+			}
+		} else {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM tasks WHERE user_id = $1`, id.Hex()); err != nil {
+				return err
+			}
+		}
+		return affectedOne(tx.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id.Hex()))
+	})
+}
+
+// Merge hands a duplicate user's tasks over to the primary user and disables the duplicate
+// in a single transaction
+func (r *userRepository) Merge(ctx context.Context, duplicateID, primaryID primitive.ObjectID, roleID *primitive.ObjectID) (int64, error) {
+	var moved int64
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		now := time.Now()
+		err := affectedOne(tx.ExecContext(ctx, `UPDATE users SET role_id = COALESCE($1, role_id), updated_at = $2 WHERE id = $3`,
+			sqlValue(roleID), now, primaryID.Hex()))
+		if err != nil {
+			return err
+		}
+		err = affectedOne(tx.ExecContext(ctx, `UPDATE users SET disabled = TRUE, merged_into = $1, updated_at = $2 WHERE id = $3`,
+			primaryID.Hex(), now, duplicateID.Hex()))
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx, `UPDATE tasks SET user_id = $1, updated_at = $2 WHERE user_id = $3`,
+			primaryID.Hex(), now, duplicateID.Hex())
+		if err != nil {
+			return err
+		}
+		moved, err = result.RowsAffected()
+		return err
+	})
+	return moved, err
+}
+
+// DashboardCounts groups users by role (starting from roles, so that roles without users are
+// listed) and tasks by status in one query, counting the rows
+// created in the range, the overdue ones and the age buckets with FILTER
+func (r *userRepository) DashboardCounts(ctx context.Context, now time.Time, from, to *time.Time) (*models.DashboardCounts, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT 'user', roles.name, COUNT(users.id),
+			COUNT(*) FILTER (WHERE users.created_at >= $1::timestamptz AND users.created_at <= $2::timestamptz),
+			0, 0, 0, 0
+		FROM roles LEFT JOIN users ON users.role_id = roles.id
+		GROUP BY roles.name
+		UNION ALL
+		SELECT 'task', status, COUNT(*),
+			COUNT(*) FILTER (WHERE created_at >= $1::timestamptz AND created_at <= $2::timestamptz),
+			COUNT(*) FILTER (WHERE due_date < $3),
+			COUNT(*) FILTER (WHERE COALESCE(status_changed_at, created_at) > $4),
+			COUNT(*) FILTER (WHERE COALESCE(status_changed_at, created_at) <= $4 AND COALESCE(status_changed_at, created_at) > $5),
+			COUNT(*) FILTER (WHERE COALESCE(status_changed_at, created_at) <= $5)
+		FROM tasks
+		GROUP BY status`, from, to, now, now.Add(-models.TaskAgeRecent), now.Add(-models.TaskAgeStale))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := &models.DashboardCounts{
+		UsersByRole:    map[string]int64{},
+		TasksByStatus:  []models.TaskStatusCount{},
+		OpenTasksByAge: []models.TaskAgeCount{},
+	}
+	ranged := from != nil && to != nil
+	for rows.Next() {
+		var kind, key string
+		var count, created, overdue int64
+		var age models.TaskAgeCount
+		if err := rows.Scan(&kind, &key, &count, &created, &overdue, &age.Recent, &age.Aging, &age.Stale); err != nil {
+			return nil, err
+		}
+		if kind == "user" {
+			counts.TotalUsers += count
+			counts.NewUsers += created
+			counts.UsersByRole[key] += count
+			continue
+		}
+		status := models.TaskStatus(key)
+		counts.TotalTasks += count
+		counts.NewTasks += created
+		if ranged {
+			count = created
+		}
+		if count > 0 {
+			counts.TasksByStatus = append(counts.TasksByStatus, models.TaskStatusCount{Status: status, Count: count})
+		}
+		if status != models.StatusDone {
+			age.Status = status
+			counts.OverdueTasks += overdue
+			counts.OpenTasksByAge = append(counts.OpenTasksByAge, age)
+		}
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,136 @@
+// Package repository defines the storage interfaces behind the services, so the persistence
+// backend (MongoDB or PostgreSQL) can be chosen per deployment: repositories for users, roles
+// and tasks, and document collections for everything else. Filters are MongoDB-style
+// documents as produced by the query package; non-Mongo implementations translate the subset
+// the services use.
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+)
+
+// Errors returned by every implementation; services translate them into their own errors
+var (
+	ErrNotFound               = errors.New("record not found")
+	ErrDuplicate              = errors.New("record already exists")
+	ErrRoleNotFound           = errors.New("role not found")
+	ErrReassignTargetNotFound = errors.New("reassignment target user not found")
+)
+
+// Fields holds the values to set in an update, keyed by document field name (e.g. "updated_at")
+type Fields map[string]interface{}
+
+// UserRepository stores users
+type UserRepository interface {
+	Create(ctx context.Context, user *models.User) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error)
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	List(ctx context.Context, q *query.Query) ([]models.User, error)
+	Count(ctx context.Context, filter primitive.M) (int64, error)
+	// EstimateCount cheaply approximates Count: stores may read collection statistics when
+	// filter is empty, and otherwise stop counting at limit
+	EstimateCount(ctx context.Context, filter primitive.M, limit int64) (int64, error)
+	Update(ctx context.Context, id primitive.ObjectID, fields Fields) error
+	// ReplacePassword sets a user's password hash to newHash only while it is still oldHash, so
+	// rehashing a password can't undo a concurrent password change. It returns ErrNotFound when
+	// the user doesn't exist or the hash changed.
+	ReplacePassword(ctx context.Context, id primitive.ObjectID, oldHash, newHash string) error
+	// UpdateRole looks up roleName and assigns it atomically, returning ErrRoleNotFound if it doesn't exist
+	UpdateRole(ctx context.Context, id primitive.ObjectID, roleName string) error
+	// Delete removes a user together with their tasks, or hands the tasks over to reassignTo when it
+	// is non-nil. It is atomic: a failure leaves both users and tasks untouched. Deleted tasks are
+	// removed directly, without the cleanup TaskService does, so callers delete them through
+	// TaskService first and leave only those created in the meantime to Delete.
+	Delete(ctx context.Context, id primitive.ObjectID, reassignTo *primitive.ObjectID) error
+	// Merge folds the user duplicateID into primaryID: the duplicate's tasks are handed over, the
+	// duplicate is disabled and marked as merged and, when roleID is non-nil, the primary gets that
+	// role. It returns how many tasks moved, ErrNotFound when either user doesn't exist, and is
+	// atomic like Delete.
+	Merge(ctx context.Context, duplicateID, primaryID primitive.ObjectID, roleID *primitive.ObjectID) (int64, error)
+	// DashboardCounts counts users and tasks for the metrics dashboard in a single round trip.
+	// from and to bound the "new" and per-status counts (inclusive); when they are nil there are
+	// no new records and the per-status counts cover every task. The overdue and age counts
+	// cover every open task as of now.
+	DashboardCounts(ctx context.Context, now time.Time, from, to *time.Time) (*models.DashboardCounts, error)
+	// Each calls fn with every user in ID order, reading them as it goes rather than all at
+	// once, and stops at the first error fn returns
+	Each(ctx context.Context, fn func(*models.User) error) error
+	// EachMatching calls fn with every user matching q's filter, in q's sort order and ignoring
+	// its paging, reading them as it goes; it stops at the first error fn returns
+	EachMatching(ctx context.Context, q *query.Query, fn func(*models.User) error) error
+}
+
+// RoleRepository stores roles
+type RoleRepository interface {
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Role, error)
+	// FindByIDs retrieves the roles with the given IDs in one query; unknown IDs are skipped
+	FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Role, error)
+	FindByName(ctx context.Context, name string) (*models.Role, error)
+	// Sync inserts role if no role with its name exists, otherwise overwrites its permissions
+	Sync(ctx context.Context, role models.Role) (created bool, err error)
+	// Each calls fn with every role in ID order and stops at the first error fn returns
+	Each(ctx context.Context, fn func(*models.Role) error) error
+}
+
+// TaskRepository stores tasks
+type TaskRepository interface {
+	Create(ctx context.Context, task *models.Task) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Task, error)
+	List(ctx context.Context, q *query.Query) ([]models.Task, error)
+	Count(ctx context.Context, filter primitive.M) (int64, error)
+	// EstimateCount cheaply approximates Count, like UserRepository.EstimateCount
+	EstimateCount(ctx context.Context, filter primitive.M, limit int64) (int64, error)
+	CountByStatus(ctx context.Context, filter primitive.M) ([]models.TaskStatusCount, error)
+	// CountByUser counts the tasks matching filter per owner; owners without any are left out
+	CountByUser(ctx context.Context, filter primitive.M) ([]models.UserTaskCount, error)
+	// SuggestTitles returns up to limit tasks matching filter whose title starts with prefix,
+	// ignoring case, in title order. Stores serve it from an index on the title.
+	SuggestTitles(ctx context.Context, filter primitive.M, prefix string, limit int64) ([]models.TaskSuggestion, error)
+	// CompletionLeaderboard ranks users by the tasks they completed from from to to, most first,
+	// with ties sharing a rank. It returns one page of entries and the number of ranked users.
+	CompletionLeaderboard(ctx context.Context, from, to time.Time, skip, limit int64) ([]models.LeaderboardEntry, int64, error)
+	// ActivityByDay counts the tasks matching filter that were created, and completed, on each
+	// calendar day in loc since from. Only days with activity are returned, oldest first.
+	ActivityByDay(ctx context.Context, filter primitive.M, from time.Time, loc *time.Location) ([]models.ActivityDay, error)
+	Update(ctx context.Context, id primitive.ObjectID, fields Fields) error
+	// UpdateMany sets fields on every task matching filter and returns how many matched
+	UpdateMany(ctx context.Context, filter primitive.M, fields Fields) (int64, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	// Each calls fn with every task in ID order, reading them as it goes rather than all at
+	// once, and stops at the first error fn returns
+	Each(ctx context.Context, fn func(*models.Task) error) error
+	// EachMatching calls fn with every task matching q's filter, like UserRepository.EachMatching
+	EachMatching(ctx context.Context, q *query.Query, fn func(*models.Task) error) error
+}
+
+// Store bundles the repositories of one backend
+type Store struct {
+	Users     UserRepository
+	Roles     RoleRepository
+	Tasks     TaskRepository
+	Documents Documents
+}
+
+// SeedDefaultRoles ensures the default roles exist and carry their current permissions
+func SeedDefaultRoles(ctx context.Context, roles RoleRepository) error {
+	for _, defaultRole := range models.DefaultRoles {
+		created, err := roles.Sync(ctx, defaultRole)
+		if err != nil {
+			return err
+		}
+		if created {
+			log.Printf("Seeded default role: %s", defaultRole.Name)
+		} else {
+			log.Printf("Updated existing default role: %s", defaultRole.Name)
+		}
+	}
+	return nil
+}
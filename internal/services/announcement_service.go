@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/OsGift/taskflow-api/internal/cache"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+// maxUpcomingAnnouncements caps how many announcements that haven't ended are read for
+// GET /announcements/active
+const maxUpcomingAnnouncements = 100
+
+// announcementRank orders severities from the most to the least prominent
+var announcementRank = map[models.AnnouncementSeverity]int{
+	models.AnnouncementCritical: 0,
+	models.AnnouncementWarning:  1,
+	models.AnnouncementInfo:     2,
+}
+
+// AnnouncementService stores the banner announcements administrators publish and serves
+// the ones to show now. Every frontend polls for those, so the announcements that haven't
+// ended are cached and filtered by time on each read; writes invalidate the cache.
+type AnnouncementService struct {
+	announcementCollection repository.Collection
+	cache                  cache.Cache // May be nil
+}
+
+// NewAnnouncementService creates a new AnnouncementService
+func NewAnnouncementService(db repository.Documents, c cache.Cache) *AnnouncementService {
+	return &AnnouncementService{
+		announcementCollection: db.Collection("announcements"),
+		cache:                  c,
+	}
+}
+
+// CreateAnnouncement publishes an announcement
+func (s *AnnouncementService) CreateAnnouncement(ctx context.Context, req *models.CreateAnnouncementRequest, createdBy primitive.ObjectID) (*models.Announcement, error) {
+	now := time.Now()
+	announcement := &models.Announcement{
+		ID:        primitive.NewObjectID(),
+		Message:   req.Message,
+		Severity:  models.AnnouncementInfo,
+		StartsAt:  now,
+		EndsAt:    req.EndsAt,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if req.Severity != "" {
+		announcement.Severity = models.AnnouncementSeverity(req.Severity)
+	}
+	if req.StartsAt != nil {
+		announcement.StartsAt = *req.StartsAt
+	}
+	if announcement.EndsAt != nil && !announcement.EndsAt.After(announcement.StartsAt) {
+		return nil, ErrInvalidAnnouncementTime
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if _, err := s.announcementCollection.InsertOne(ctx, announcement); err != nil {
+		return nil, err
+	}
+	cache.InvalidatePrefixes(ctx, s.cache, cachePrefixAnnouncements)
+	return announcement, nil
+}
+
+// ListAnnouncements retrieves the announcements matching the query, past and future included
+func (s *AnnouncementService) ListAnnouncements(ctx context.Context, q *query.Query) (*models.AnnouncementListResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cursor, err := s.announcementCollection.Find(ctx, q.Filter, q.FindOptions())
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	announcements := []models.Announcement{}
+	if err = cursor.All(ctx, &announcements); err != nil {
+		return nil, err
+	}
+
+	totalCount, err := s.announcementCollection.CountDocuments(ctx, q.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AnnouncementListResponse{
+		Announcements: announcements,
+		Pagination:    models.NewPagination(totalCount, q.Page, q.Limit),
+	}, nil
+}
+
+// GetAnnouncement retrieves an announcement by its ID
+func (s *AnnouncementService) GetAnnouncement(ctx context.Context, idHex string) (*models.Announcement, error) {
+	id, err := primitive.ObjectIDFromHex(idHex)
+	if err != nil {
+		return nil, ErrInvalidAnnouncementID
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var announcement models.Announcement
+	err = s.announcementCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&announcement)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrAnnouncementNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &announcement, nil
+}
+
+// UpdateAnnouncement changes the message, severity or times of an announcement
+func (s *AnnouncementService) UpdateAnnouncement(ctx context.Context, idHex string, req *models.UpdateAnnouncementRequest) (*models.Announcement, error) {
+	current, err := s.GetAnnouncement(ctx, idHex)
+	if err != nil {
+		return nil, err
+	}
+
+	set := bson.M{"updated_at": time.Now()}
+	update := bson.M{"$set": set}
+	if req.Message != nil {
+		set["message"] = *req.Message
+	}
+	if req.Severity != nil {
+		set["severity"] = *req.Severity
+	}
+	start, end := current.StartsAt, current.EndsAt
+	if req.StartsAt != nil {
+		start = *req.StartsAt
+		set["starts_at"] = start
+	}
+	switch {
+	case req.NoEnd:
+		end = nil
+		update["$unset"] = bson.M{"ends_at": ""}
+	case req.EndsAt != nil:
+		end = req.EndsAt
+		set["ends_at"] = *end
+	}
+	if end != nil && !end.After(start) {
+		return nil, ErrInvalidAnnouncementTime
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var announcement models.Announcement
+	err = s.announcementCollection.FindOneAndUpdate(ctx, bson.M{"_id": current.ID}, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&announcement)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrAnnouncementNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	cache.InvalidatePrefixes(ctx, s.cache, cachePrefixAnnouncements)
+	return &announcement, nil
+}
+
+// DeleteAnnouncement deletes an announcement, taking it down if it is showing
+func (s *AnnouncementService) DeleteAnnouncement(ctx context.Context, idHex string) error {
+	id, err := primitive.ObjectIDFromHex(idHex)
+	if err != nil {
+		return ErrInvalidAnnouncementID
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := s.announcementCollection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrAnnouncementNotFound
+	}
+	cache.InvalidatePrefixes(ctx, s.cache, cachePrefixAnnouncements)
+	return nil
+}
+
+// ActiveAnnouncements returns the announcements to show now, the most severe and then the
+// most recently started first
+func (s *AnnouncementService) ActiveAnnouncements(ctx context.Context) (*models.ActiveAnnouncementsResponse, error) {
+	upcoming, err := s.upcoming(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	active := []models.ActiveAnnouncement{}
+	for _, announcement := range upcoming {
+		if !announcement.StartsAt.After(now) && (announcement.EndsAt == nil || announcement.EndsAt.After(now)) {
+			active = append(active, announcement)
+		}
+	}
+	sort.SliceStable(active, func(i, j int) bool {
+		if ri, rj := announcementRank[active[i].Severity], announcementRank[active[j].Severity]; ri != rj {
+			return ri < rj
+		}
+		return active[i].StartsAt.After(active[j].StartsAt)
+	})
+	return &models.ActiveAnnouncementsResponse{Announcements: active}, nil
+}
+
+// upcoming returns the announcements that haven't ended, showing or not yet started, from
+// the cache when it has them
+func (s *AnnouncementService) upcoming(ctx context.Context) ([]models.ActiveAnnouncement, error) {
+	cacheKey := cachePrefixAnnouncements + "upcoming"
+	var cached []models.ActiveAnnouncement
+	if cache.GetJSON(ctx, s.cache, cacheKey, &cached) {
+		return cached, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"$or": []bson.M{
+		{"ends_at": bson.M{"$exists": false}},
+		{"ends_at": bson.M{"$gt": time.Now()}},
+	}}
+	cursor, err := s.announcementCollection.Find(ctx, filter,
+		options.Find().SetSort(bson.D{{Key: "starts_at", Value: 1}}).SetLimit(maxUpcomingAnnouncements))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	upcoming := []models.ActiveAnnouncement{}
+	if err = cursor.All(ctx, &upcoming); err != nil {
+		return nil, err
+	}
+	cache.SetJSON(ctx, s.cache, cacheKey, upcoming, announcementCacheTTL)
+	return upcoming, nil
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+// AuditService stores and queries the audit trail of mutating requests
+type AuditService struct {
+	auditCollection repository.Collection
+}
+
+// NewAuditService creates a new AuditService
+func NewAuditService(db repository.Documents) *AuditService {
+	return &AuditService{
+		auditCollection: db.Collection("audit_logs"),
+	}
+}
+
+// Record inserts an audit entry
+func (s *AuditService) Record(ctx context.Context, entry *models.AuditLog) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	_, err := s.auditCollection.InsertOne(ctx, entry)
+	return err
+}
+
+// ListAuditLogs retrieves audit entries matching the query
+func (s *AuditService) ListAuditLogs(ctx context.Context, q *query.Query) (*models.AuditLogListResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cursor, err := s.auditCollection.Find(ctx, q.Filter, q.FindOptions())
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	logs := []models.AuditLog{}
+	if err = cursor.All(ctx, &logs); err != nil {
+		return nil, err
+	}
+
+	totalCount, err := s.auditCollection.CountDocuments(ctx, q.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AuditLogListResponse{
+		Logs:       logs,
+		Pagination: models.NewPagination(totalCount, q.Page, q.Limit),
+	}, nil
+}
+
+// TargetLogs retrieves the successful requests that changed the resource with ID targetID,
+// oldest first, up to limit of the most recent ones
+func (s *AuditService) TargetLogs(ctx context.Context, targetID string, limit int64) ([]models.AuditLog, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cursor, err := s.auditCollection.Find(ctx, bson.M{"target_id": targetID, "status": bson.M{"$lt": 400}},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	logs := []models.AuditLog{}
+	if err = cursor.All(ctx, &logs); err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(logs)-1; i < j; i, j = i+1, j-1 {
+		logs[i], logs[j] = logs[j], logs[i]
+	}
+	return logs, nil
+}
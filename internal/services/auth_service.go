@@ -1,48 +1,55 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"sync" // For in-memory reset tokens
 	"time"
 	// For HTML email templates
 	"github.com/golang-jwt/jwt/v5"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/passhash"
 	"github.com/OsGift/taskflow-api/internal/utils"
 )
 
-// In-memory store for password reset tokens.
-// In a real application, this should be persisted (e.g., MongoDB, Redis)
-// and more robustly handled (e.g., single-use tokens, rate limiting).
-var (
-	passwordResetTokens = make(map[string]primitive.ObjectID) // token -> user ID
-	tokenMutex          sync.Mutex
+// How long emailed tokens can be used, matching the expiry of the tokens themselves
+const (
+	passwordResetTokenTTL     = time.Hour
+	emailVerificationTokenTTL = 24 * time.Hour
 )
 
 // AuthService provides methods for user authentication and JWT operations
 type AuthService struct {
 	userService         *UserService
 	jwtSecret           []byte
-	passwordResetSecret []byte // New secret for password reset tokens
+	passwordResetSecret []byte               // New secret for password reset tokens
+	notifications       *NotificationService // Account emails are routed through notification preferences
+	hasher              passhash.Hasher      // Hashes new passwords; hashes made otherwise are replaced on login
+	authTokens          *AuthTokenService    // Makes emailed reset and verification tokens single use
+	sessions            *SessionService      // Sessions are ended when the password changes
 }
 
 // NewAuthService creates a new AuthService
-func NewAuthService(us *UserService, jwtSecret, passwordResetSecret []byte) *AuthService {
+func NewAuthService(us *UserService, jwtSecret, passwordResetSecret []byte, ns *NotificationService, hasher passhash.Hasher, ats *AuthTokenService, ss *SessionService) *AuthService {
 	return &AuthService{
 		userService:         us,
 		jwtSecret:           jwtSecret,
 		passwordResetSecret: passwordResetSecret,
+		notifications:       ns,
+		hasher:              hasher,
+		authTokens:          ats,
+		sessions:            ss,
 	}
 }
 
 // RegisterUser handles user registration. Can also register admins.
-func (s *AuthService) RegisterUser(req models.UserRegisterRequest, isAdminCreation bool, tempPassword string) (*models.UserResponse, error) {
+func (s *AuthService) RegisterUser(ctx context.Context, req models.UserRegisterRequest, isAdminCreation bool, tempPassword string) (*models.UserResponse, error) {
 	// Check if user with this email already exists
-	existingUser, _ := s.userService.GetUserByEmail(req.Email)
+	existingUser, _ := s.userService.GetUserByEmail(ctx, req.Email)
 	if existingUser != nil {
-		return nil, errors.New("email already registered")
+		return nil, ErrEmailAlreadyRegistered
 	}
 
 	var hashedPassword string
@@ -51,22 +58,22 @@ func (s *AuthService) RegisterUser(req models.UserRegisterRequest, isAdminCreati
 	var err error
 
 	if isAdminCreation {
-		hashedPassword, err = utils.HashPassword(tempPassword)
+		hashedPassword, err = s.hasher.Hash(tempPassword)
 		if err != nil {
 			return nil, errors.New("failed to hash temporary password")
 		}
 		needsPasswordChange = true
-		role, err = s.userService.GetRoleByName("Admin")
+		role, err = s.userService.GetRoleByName(ctx, "Admin")
 		if err != nil {
 			return nil, errors.New("admin role not found")
 		}
 	} else {
-		hashedPassword, err = utils.HashPassword(req.Password)
+		hashedPassword, err = s.hasher.Hash(req.Password)
 		if err != nil {
 			return nil, errors.New("failed to hash password")
 		}
 		needsPasswordChange = false
-		role, err = s.userService.GetRoleByName("User")
+		role, err = s.userService.GetRoleByName(ctx, "User")
 		if err != nil {
 			return nil, errors.New("default user role not found")
 		}
@@ -85,11 +92,12 @@ func (s *AuthService) RegisterUser(req models.UserRegisterRequest, isAdminCreati
 		ProfilePictureURL:   "https://placehold.co/150x150/cccccc/ffffff?text=Avatar", // Default avatar
 		IsEmailVerified:     false,                                                    // Not verified initially
 		NeedsPasswordChange: needsPasswordChange,                                      // Set based on admin creation
+		Locale:              utils.NormalizeLocale(req.Locale),
 		CreatedAt:           time.Now(),
 		UpdatedAt:           time.Now(),
 	}
 
-	userResponse, err := s.userService.CreateUser(newUser)
+	userResponse, err := s.userService.CreateUser(ctx, newUser)
 	if err != nil {
 		return nil, err
 	}
@@ -107,9 +115,21 @@ func (s *AuthService) RegisterUser(req models.UserRegisterRequest, isAdminCreati
 			LoginLink:         "http://localhost:3000/login", // Frontend login URL
 			Year:              time.Now().Year(),
 		}
-		go utils.SendEmail("admin_temp_password", "Your TaskFlow Admin Account Details", req.Email, emailData)
+		_, err := s.notifications.Notify(ctx, &Notice{
+			Event:    models.EventAdminAccountCreated,
+			User:     newUser,
+			Template: "admin_temp_password",
+			Subject:  "Your TaskFlow Admin Account Details",
+			Data:     emailData,
+		})
+		if err != nil {
+			fmt.Printf("Warning: Failed to queue admin credentials email for %s: %v\n", req.Email, err)
+		}
 	} else {
 		verificationToken, err := utils.GenerateVerificationToken(userResponse.ID, s.jwtSecret) // Pass hex string
+		if err == nil {
+			err = s.authTokens.Store(ctx, models.AuthTokenEmailVerification, newUser.ID, verificationToken, time.Now().Add(emailVerificationTokenTTL))
+		}
 		if err != nil {
 			fmt.Printf("Warning: Failed to generate verification token for %s: %v\n", req.Email, err)
 			// Proceed without sending verification email if token generation fails
@@ -123,7 +143,16 @@ func (s *AuthService) RegisterUser(req models.UserRegisterRequest, isAdminCreati
 				VerificationLink: fmt.Sprintf("http://localhost:3000/verify-email?token=%s", verificationToken), // Frontend verify URL
 				Year:             time.Now().Year(),
 			}
-			go utils.SendEmail("welcome", "Welcome to TaskFlow! Please verify your email.", req.Email, emailData)
+			_, err := s.notifications.Notify(ctx, &Notice{
+				Event:    models.EventAccountCreated,
+				User:     newUser,
+				Template: "welcome",
+				Subject:  "Welcome to TaskFlow! Please verify your email.",
+				Data:     emailData,
+			})
+			if err != nil {
+				fmt.Printf("Warning: Failed to queue welcome email for %s: %v\n", req.Email, err)
+			}
 		}
 	}
 
@@ -131,18 +160,24 @@ func (s *AuthService) RegisterUser(req models.UserRegisterRequest, isAdminCreati
 }
 
 // LoginUser handles user login and JWT generation
-func (s *AuthService) LoginUser(req models.UserLoginRequest) (*models.LoginResponse, error) {
-	user, err := s.userService.GetUserByEmail(req.Email)
-	if err != nil {
-		return nil, errors.New("invalid credentials")
+func (s *AuthService) LoginUser(ctx context.Context, req models.UserLoginRequest) (*models.LoginResponse, error) {
+	user, err := s.userService.GetUserByEmail(ctx, req.Email)
+	if err != nil || user.IsServiceAccount { // Service accounts authenticate with API keys only
+		return nil, ErrInvalidCredentials
 	}
 
-	if !utils.CheckPasswordHash(req.Password, user.Password) {
-		return nil, errors.New("invalid credentials")
+	if !passhash.Verify(req.Password, user.Password) {
+		return nil, ErrInvalidCredentials
+	}
+	if user.Disabled {
+		return nil, ErrAccountDisabled
+	}
+	if s.hasher.NeedsRehash(user.Password) {
+		s.rehashPassword(ctx, user, req.Password)
 	}
 
 	// Get user's role name
-	role, err := s.userService.GetRoleByID(user.RoleID.Hex())
+	role, err := s.userService.GetRoleByID(ctx, user.RoleID.Hex())
 	if err != nil {
 		return nil, errors.New("user role not found") // Should not happen if roles are seeded
 	}
@@ -162,6 +197,19 @@ func (s *AuthService) LoginUser(req models.UserLoginRequest) (*models.LoginRespo
 	}, nil
 }
 
+// rehashPassword replaces the password hash of a user who just logged in, made with another
+// algorithm or parameters, by one made with the current hasher. A failure doesn't fail the
+// login; the rehash is tried again on the next one.
+func (s *AuthService) rehashPassword(ctx context.Context, user *models.User, password string) {
+	hashedPassword, err := s.hasher.Hash(password)
+	if err == nil {
+		err = s.userService.ReplacePasswordHash(ctx, user.ID, user.Password, hashedPassword)
+	}
+	if err != nil {
+		fmt.Printf("Warning: Failed to rehash the password of user %s: %v\n", user.ID.Hex(), err)
+	}
+}
+
 // ValidateToken validates a JWT token string (used by middleware)
 func (s *AuthService) ValidateToken(tokenString string) (jwt.MapClaims, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
@@ -178,27 +226,29 @@ func (s *AuthService) ValidateToken(tokenString string) (jwt.MapClaims, error) {
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
 		return claims, nil
 	}
-	return nil, errors.New("invalid token")
+	return nil, ErrInvalidToken
 }
 
 // ForgotPassword generates a password reset token and "sends" it to the user's email
-func (s *AuthService) ForgotPassword(email string) error {
-	user, err := s.userService.GetUserByEmail(email)
+func (s *AuthService) ForgotPassword(ctx context.Context, email string) error {
+	user, err := s.userService.GetUserByEmail(ctx, email)
 	if err != nil {
 		// For security, don't reveal if email exists or not
 		fmt.Printf("Attempted password reset for non-existent email: %s\n", email)
 		return nil // Return nil to prevent leaking user existence
 	}
+	if user.IsServiceAccount || user.Disabled {
+		return nil // Service accounts have no password to reset, and disabled users can't log in
+	}
 
 	resetToken, err := utils.GeneratePasswordResetToken(user.ID, s.passwordResetSecret)
 	if err != nil {
 		return errors.New("failed to generate reset token")
 	}
 
-	// Store token in-memory with user ID. In production, this would be a DB/Redis entry
-	tokenMutex.Lock()
-	passwordResetTokens[resetToken] = user.ID
-	tokenMutex.Unlock()
+	if err := s.authTokens.Store(ctx, models.AuthTokenPasswordReset, user.ID, resetToken, time.Now().Add(passwordResetTokenTTL)); err != nil {
+		return errors.New("failed to store reset token")
+	}
 
 	// Simulate sending email with reset link
 	emailData := struct {
@@ -208,95 +258,98 @@ func (s *AuthService) ForgotPassword(email string) error {
 		ResetLink: fmt.Sprintf("http://localhost:3000/reset-password?token=%s", resetToken), // Frontend reset password URL
 		Year:      time.Now().Year(),
 	}
-	go utils.SendEmail("forgot_password", "Password Reset Request for TaskFlow", email, emailData)
-
-	// Remove token after some time (e.g., 1 hour)
-	go func(token string) {
-		time.Sleep(1 * time.Hour)
-		tokenMutex.Lock()
-		delete(passwordResetTokens, token)
-		tokenMutex.Unlock()
-		fmt.Printf("Password reset token %s expired and removed.\n", token)
-	}(resetToken)
+	_, err = s.notifications.Notify(ctx, &Notice{
+		Event:    models.EventPasswordReset,
+		User:     user,
+		Template: "forgot_password",
+		Subject:  "Password Reset Request for TaskFlow",
+		Data:     emailData,
+	})
+	if err != nil {
+		return errors.New("failed to queue password reset email")
+	}
 
 	return nil
 }
 
-// ResetPassword validates the token and updates the user's password
-func (s *AuthService) ResetPassword(tokenString, newPassword string) error {
-	tokenMutex.Lock()
-	userID, exists := passwordResetTokens[tokenString]
-	tokenMutex.Unlock()
-
-	if !exists {
-		return errors.New("invalid or expired password reset token")
+// ResetPassword validates the token and updates the user's password, ending the user's
+// sessions so whoever took over the account is logged out
+func (s *AuthService) ResetPassword(ctx context.Context, tokenString, newPassword string) error {
+	userID, err := utils.ValidatePasswordResetToken(tokenString, s.passwordResetSecret)
+	if err != nil {
+		return ErrInvalidResetToken
 	}
 
-	// Remove the token after use (important for security)
-	tokenMutex.Lock()
-	delete(passwordResetTokens, tokenString)
-	tokenMutex.Unlock()
+	// Use the token up, so a leaked reset link can't be replayed
+	valid, err := s.authTokens.Consume(ctx, models.AuthTokenPasswordReset, userID, tokenString)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return ErrInvalidResetToken
+	}
 
-	hashedPassword, err := utils.HashPassword(newPassword)
+	hashedPassword, err := s.hasher.Hash(newPassword)
 	if err != nil {
 		return errors.New("failed to hash new password")
 	}
 
-	err = s.userService.UpdateUserPassword(userID, hashedPassword)
+	err = s.userService.UpdateUserPassword(ctx, userID, hashedPassword)
 	if err != nil {
 		return errors.New("failed to update password in database")
 	}
 
-	return nil
+	return s.sessions.RevokeAllForUser(ctx, userID)
+}
+
+// VerifyEmail marks a user's email as verified with the token emailed to them at registration
+func (s *AuthService) VerifyEmail(ctx context.Context, userID primitive.ObjectID, token string) error {
+	valid, err := s.authTokens.Consume(ctx, models.AuthTokenEmailVerification, userID, token)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return ErrInvalidVerificationToken
+	}
+	return s.userService.VerifyUserEmail(ctx, userID)
 }
 
-// ChangeTemporaryPassword allows a logged-in user with needs_password_change to set a new password
-func (s *AuthService) ChangeTemporaryPassword(userID primitive.ObjectID, oldPassword, newPassword string) error {
-	user, err := s.userService.GetUserByID(userID.Hex())
+// ChangeTemporaryPassword allows a logged-in user with needs_password_change to set a new
+// password. The user's sessions are ended, including the current one.
+func (s *AuthService) ChangeTemporaryPassword(ctx context.Context, userID primitive.ObjectID, oldPassword, newPassword string) error {
+	user, err := s.userService.GetUserByID(ctx, userID.Hex())
 	if err != nil {
-		return errors.New("user not found")
+		return ErrUserNotFound
 	}
 
 	if !user.NeedsPasswordChange {
-		return errors.New("password change not required for this account")
+		return ErrPasswordChangeNotRequired
 	}
 
 	// Verify old password (even if temporary)
-	if !utils.CheckPasswordHash(oldPassword, user.Password) {
-		return errors.New("invalid old password")
+	if !passhash.Verify(oldPassword, user.Password) {
+		return ErrInvalidOldPassword
 	}
 
-	hashedNewPassword, err := utils.HashPassword(newPassword)
+	hashedNewPassword, err := s.hasher.Hash(newPassword)
 	if err != nil {
 		return errors.New("failed to hash new password")
 	}
 
-	err = s.userService.UpdateUserPasswordAndNeedsChange(userID, hashedNewPassword, false)
+	err = s.userService.UpdateUserPasswordAndNeedsChange(ctx, userID, hashedNewPassword, false)
 	if err != nil {
 		return errors.New("failed to update password")
 	}
-	return nil
+	return s.sessions.RevokeAllForUser(ctx, userID)
 }
 
 // AuthenticatedUserContext fetches the full AuthContext for a given user ID and role ID.
-// This is used by the middleware to prepare the context.
-func (s *AuthService) AuthenticatedUserContext(userID primitive.ObjectID, roleID primitive.ObjectID) (*models.AuthContext, error) {
-	user, err := s.userService.GetUserByID(userID.Hex())
-	if err != nil {
-		return nil, fmt.Errorf("user not found: %w", err)
-	}
-
-	role, err := s.userService.GetRoleByID(roleID.Hex())
+// This is used by the middleware to prepare the context. Results are cached by the
+// UserService so most requests don't hit the database.
+func (s *AuthService) AuthenticatedUserContext(ctx context.Context, userID primitive.ObjectID, roleID primitive.ObjectID) (*models.AuthContext, error) {
+	authContext, err := s.userService.GetAuthContext(ctx, userID, roleID)
 	if err != nil {
-		return nil, fmt.Errorf("user role not found: %w", err)
+		return nil, fmt.Errorf("failed to resolve user context: %w", err)
 	}
-
-	return &models.AuthContext{
-		UserID:              user.ID,
-		RoleID:              role.ID,
-		RoleName:            role.Name,
-		Permissions:         role.Permissions,
-		IsEmailVerified:     user.IsEmailVerified,
-		NeedsPasswordChange: user.NeedsPasswordChange,
-	}, nil
+	return authContext, nil
 }
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+// AuthTokenService stores the single-use tokens emailed to users, such as password reset
+// links, in the "auth_tokens" collection, where a TTL index removes them once expired.
+// Tokens are kept in the database rather than in memory so they survive restarts and work
+// on every server.
+type AuthTokenService struct {
+	tokenCollection repository.Collection
+}
+
+// NewAuthTokenService creates a new AuthTokenService
+func NewAuthTokenService(db repository.Documents) *AuthTokenService {
+	return &AuthTokenService{
+		tokenCollection: db.Collection("auth_tokens"),
+	}
+}
+
+// Store records token as usable once by userID for purpose until expiresAt
+func (s *AuthTokenService) Store(ctx context.Context, purpose models.AuthTokenPurpose, userID primitive.ObjectID, token string, expiresAt time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := s.tokenCollection.InsertOne(ctx, models.AuthToken{
+		ID:        primitive.NewObjectID(),
+		Hash:      hashToken(token),
+		Purpose:   purpose,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	})
+	return err
+}
+
+// Consume uses up token, reporting whether it was a stored, unexpired token of userID for
+// purpose. Other tokens of the user for the same purpose are revoked with it, since they
+// would repeat what was just done.
+func (s *AuthTokenService) Consume(ctx context.Context, purpose models.AuthTokenPurpose, userID primitive.ObjectID, token string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// The TTL monitor runs about once a minute, so expired tokens may still be there
+	err := s.tokenCollection.FindOneAndDelete(ctx, bson.M{
+		"hash":       hashToken(token),
+		"purpose":    purpose,
+		"user_id":    userID,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}).Err()
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	_, err = s.tokenCollection.DeleteMany(ctx, bson.M{"purpose": purpose, "user_id": userID})
+	return true, err
+}
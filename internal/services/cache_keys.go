@@ -0,0 +1,37 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// Cache key prefixes shared by services, so that writes can invalidate what reads cached
+const (
+	cachePrefixRole          = "role:"
+	cachePrefixTaskCount     = "count:tasks:"
+	cachePrefixUserCount     = "count:users:"
+	cachePrefixDashboard     = "dashboard:"
+	cachePrefixAnnouncements = "announcements:"
+)
+
+// Cache lifetimes; explicit invalidation on writes keeps entries fresh in the meantime
+const (
+	roleCacheTTL         = 5 * time.Minute
+	localRoleCacheTTL    = time.Minute // Kept short, since other servers' invalidations don't reach it
+	countCacheTTL        = 30 * time.Second
+	dashboardCacheTTL    = time.Minute
+	announcementCacheTTL = time.Minute
+)
+
+// queryCacheKey derives a stable cache key for a Mongo filter.
+// encoding/json sorts map keys, so equal filters always hash the same.
+func queryCacheKey(prefix string, query interface{}) string {
+	data, err := json.Marshal(query)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return prefix + hex.EncodeToString(sum[:16])
+}
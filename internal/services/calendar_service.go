@@ -0,0 +1,497 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/oauth2"
+
+	"github.com/OsGift/taskflow-api/internal/gcal"
+	"github.com/OsGift/taskflow-api/internal/jobs"
+	"github.com/OsGift/taskflow-api/internal/logging"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+const (
+	// calendarStateTTL is how long a user has to finish the Google consent screen
+	calendarStateTTL = 10 * time.Minute
+	// calendarEventLength is the duration of the event mirroring a task's due date
+	calendarEventLength = 30 * time.Minute
+	// calendarBackfillWindow is how far back due dates are mirrored when a calendar is connected
+	calendarBackfillWindow = 30 * 24 * time.Hour
+	// calendarRevokedError is shown once Google stops accepting the stored refresh token
+	calendarRevokedError = "Google Calendar access was revoked; connect the calendar again to resume syncing"
+)
+
+// CalendarService mirrors tasks with due dates into their owners' Google Calendars and applies
+// date changes made in the calendar back to the tasks. Pushes run as jobs queued on every task
+// write; each connected calendar is pulled periodically, every pull queuing the next one.
+type CalendarService struct {
+	connectionCollection repository.Collection
+	eventCollection      repository.Collection
+	tasks                repository.TaskRepository
+	taskService          *TaskService
+	jobQueue             *jobs.Queue
+	oauth                *oauth2.Config // nil when the integration isn't configured
+	stateSecret          []byte
+	pullInterval         time.Duration
+}
+
+// NewCalendarService creates a new CalendarService. oauth may be nil to disable the
+// integration; stateSecret signs the state parameter of the consent flow.
+func NewCalendarService(db repository.Documents, store *repository.Store, ts *TaskService, jq *jobs.Queue, oauth *oauth2.Config, stateSecret []byte, pullInterval time.Duration) *CalendarService {
+	return &CalendarService{
+		connectionCollection: db.Collection("calendar_connections"),
+		eventCollection:      db.Collection("calendar_events"),
+		tasks:                store.Tasks,
+		taskService:          ts,
+		jobQueue:             jq,
+		oauth:                oauth,
+		stateSecret:          stateSecret,
+		pullInterval:         pullInterval,
+	}
+}
+
+// ConnectURL returns the Google consent page a user visits to connect their calendar
+func (s *CalendarService) ConnectURL(userID primitive.ObjectID) (string, error) {
+	if s.oauth == nil {
+		return "", ErrCalendarNotConfigured
+	}
+	return gcal.AuthCodeURL(s.oauth, s.signState(userID, time.Now().Add(calendarStateTTL))), nil
+}
+
+// CompleteConnection finishes the consent flow Google redirected back from: it stores the
+// user's tokens, mirrors their recent and upcoming tasks and starts pulling calendar changes
+func (s *CalendarService) CompleteConnection(ctx context.Context, state, code string) error {
+	if s.oauth == nil {
+		return ErrCalendarNotConfigured
+	}
+	userID, err := s.verifyState(state)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	token, err := s.oauth.Exchange(ctx, code)
+	var retrieveErr *oauth2.RetrieveError
+	if errors.As(err, &retrieveErr) {
+		return ErrCalendarCodeRejected
+	}
+	if err != nil {
+		return fmt.Errorf("failed to exchange the Google authorization code: %w", err)
+	}
+	if token.RefreshToken == "" {
+		return ErrCalendarNoRefresh
+	}
+
+	now := time.Now()
+	nextPull := now.Truncate(time.Second)
+	_, err = s.connectionCollection.UpdateOne(ctx, bson.M{"user_id": userID}, bson.M{
+		"$set": bson.M{
+			"calendar_id":   "primary",
+			"access_token":  token.AccessToken,
+			"refresh_token": token.RefreshToken,
+			"token_expiry":  token.Expiry,
+			"next_pull_at":  nextPull,
+			"updated_at":    now,
+		},
+		"$unset":       bson.M{"sync_token": "", "last_error": ""},
+		"$setOnInsert": bson.M{"created_at": now},
+	}, options.Update().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+	// Events mirrored through an earlier connection may be in another account's calendar
+	if _, err := s.eventCollection.DeleteMany(ctx, bson.M{"user_id": userID}); err != nil {
+		return err
+	}
+
+	if err := s.queueBackfill(ctx, userID, now); err != nil {
+		return err
+	}
+	return jobs.ScheduleCalendarPull(ctx, s.jobQueue, userID, nextPull)
+}
+
+// queueBackfill queues a push for each of the user's tasks due since calendarBackfillWindow
+func (s *CalendarService) queueBackfill(ctx context.Context, userID primitive.ObjectID, now time.Time) error {
+	for page := int64(1); ; page++ {
+		q := query.New(bson.M{"user_id": userID, "due_date": bson.M{"$gte": now.Add(-calendarBackfillWindow)}}, page, 100)
+		tasks, err := s.tasks.List(ctx, q)
+		if err != nil {
+			return err
+		}
+		for _, task := range tasks {
+			if err := s.jobQueue.Enqueue(ctx, jobs.TypeCalendarPushTask, jobs.CalendarPushTaskPayload{TaskID: task.ID, UserID: userID}); err != nil {
+				return err
+			}
+		}
+		if len(tasks) < 100 {
+			return nil
+		}
+	}
+}
+
+// GetConnection returns the user's calendar connection
+func (s *CalendarService) GetConnection(ctx context.Context, userID primitive.ObjectID) (*models.CalendarConnection, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var conn models.CalendarConnection
+	if err := s.connectionCollection.FindOne(ctx, bson.M{"user_id": userID}).Decode(&conn); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrCalendarNotConnected
+		}
+		return nil, err
+	}
+	return &conn, nil
+}
+
+// Disconnect stops syncing the user's calendar and revokes the server's access to it.
+// Events already in the calendar are left there.
+func (s *CalendarService) Disconnect(ctx context.Context, userID primitive.ObjectID) error {
+	conn, err := s.GetConnection(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if _, err := s.connectionCollection.DeleteOne(ctx, bson.M{"_id": conn.ID}); err != nil {
+		return err
+	}
+	if _, err := s.eventCollection.DeleteMany(ctx, bson.M{"user_id": userID}); err != nil {
+		return err
+	}
+	if err := gcal.Revoke(ctx, conn.RefreshToken); err != nil {
+		logging.Warnf("Failed to revoke the Google Calendar token of user %s: %v", userID.Hex(), err)
+	}
+	return nil
+}
+
+// TaskSaved queues a push of the task when its owner has connected a calendar
+func (s *CalendarService) TaskSaved(ctx context.Context, task *models.Task) {
+	if s.oauth == nil {
+		return
+	}
+	count, err := s.connectionCollection.CountDocuments(ctx, bson.M{"user_id": task.UserID})
+	if err == nil && count > 0 {
+		err = s.jobQueue.Enqueue(ctx, jobs.TypeCalendarPushTask, jobs.CalendarPushTaskPayload{TaskID: task.ID, UserID: task.UserID})
+	}
+	if err != nil {
+		logging.Warnf("Failed to queue the calendar sync of task %s: %v", task.ID.Hex(), err)
+	}
+}
+
+// TaskDeleted queues a push removing the event of a deleted task, if it was mirrored
+func (s *CalendarService) TaskDeleted(ctx context.Context, id primitive.ObjectID) {
+	if s.oauth == nil {
+		return
+	}
+	var link models.CalendarEvent
+	err := s.eventCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&link)
+	if err == nil {
+		err = s.jobQueue.Enqueue(ctx, jobs.TypeCalendarPushTask, jobs.CalendarPushTaskPayload{TaskID: id, UserID: link.UserID})
+	}
+	if err != nil && err != mongo.ErrNoDocuments {
+		logging.Warnf("Failed to queue the calendar sync of deleted task %s: %v", id.Hex(), err)
+	}
+}
+
+// PushTask is the job handler mirroring one task into its owner's calendar: it creates or
+// updates the task's event, or deletes it once the task is gone or has changed hands
+func (s *CalendarService) PushTask(ctx context.Context, payload []byte) error {
+	var p jobs.CalendarPushTaskPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return fmt.Errorf("invalid calendar push payload: %w", err)
+	}
+	if s.oauth == nil {
+		return nil // The integration was switched off since the job was queued
+	}
+
+	conn, err := s.GetConnection(ctx, p.UserID)
+	if err == ErrCalendarNotConnected {
+		_, err = s.eventCollection.DeleteOne(ctx, bson.M{"_id": p.TaskID})
+		return err
+	}
+	if err != nil || conn.LastError != "" {
+		return err
+	}
+
+	var link *models.CalendarEvent
+	var existing models.CalendarEvent
+	if err := s.eventCollection.FindOne(ctx, bson.M{"_id": p.TaskID}).Decode(&existing); err == nil {
+		link = &existing
+	} else if err != mongo.ErrNoDocuments {
+		return err
+	}
+
+	task, err := s.tasks.FindByID(ctx, p.TaskID)
+	if err != nil && err != repository.ErrNotFound {
+		return err
+	}
+	client, tokens := s.client(ctx, conn)
+
+	if task == nil || task.UserID != p.UserID || task.DueDate == nil {
+		if link == nil {
+			return nil
+		}
+		if err := client.DeleteEvent(ctx, conn.CalendarID, link.EventID); err != nil {
+			return s.syncFailed(ctx, conn, err)
+		}
+		_, err := s.eventCollection.DeleteOne(ctx, bson.M{"_id": p.TaskID})
+		return err
+	}
+
+	// Google keeps times to the second
+	due := task.DueDate.Truncate(time.Second)
+	done := task.Status == models.StatusDone
+	if link != nil && link.DueDate.Equal(due) && link.Title == task.Title && link.Done == done {
+		return nil
+	}
+
+	event := calendarEvent(task, due)
+	eventID := task.ID.Hex() // Object IDs are valid event IDs, so a retried insert can't duplicate
+	if link != nil {
+		eventID = link.EventID
+		_, err = client.PatchEvent(ctx, conn.CalendarID, eventID, event)
+	}
+	if link == nil || err == gcal.ErrNotFound {
+		event.ID = eventID
+		_, err = client.InsertEvent(ctx, conn.CalendarID, event)
+		if err == gcal.ErrConflict {
+			// Inserted by an earlier attempt, or deleted in the calendar; patching restores it
+			event.ID = ""
+			_, err = client.PatchEvent(ctx, conn.CalendarID, eventID, event)
+		}
+	}
+	if err != nil {
+		return s.syncFailed(ctx, conn, err)
+	}
+	s.saveToken(ctx, conn, tokens)
+
+	_, err = s.eventCollection.UpdateOne(ctx, bson.M{"_id": task.ID}, bson.M{"$set": bson.M{
+		"user_id":   p.UserID,
+		"event_id":  eventID,
+		"due_date":  due,
+		"title":     task.Title,
+		"done":      done,
+		"synced_at": time.Now(),
+	}}, options.Update().SetUpsert(true))
+	return err
+}
+
+// calendarEvent builds the event mirroring task, due at due, in the time zone its due date was
+// set in
+func calendarEvent(task *models.Task, due time.Time) *gcal.Event {
+	loc := models.LoadLocation(task.DueTimeZone)
+	summary := task.Title
+	if task.Status == models.StatusDone {
+		summary = "✓ " + summary
+	}
+	return &gcal.Event{
+		Status:      "confirmed",
+		Summary:     summary,
+		Description: task.Description,
+		Start:       &gcal.EventTime{DateTime: due.In(loc).Format(time.RFC3339), TimeZone: loc.String()},
+		End:         &gcal.EventTime{DateTime: due.Add(calendarEventLength).In(loc).Format(time.RFC3339), TimeZone: loc.String()},
+		ExtendedProperties: &gcal.ExtendedProperties{
+			Private: map[string]string{gcal.TaskIDProperty: task.ID.Hex()},
+		},
+	}
+}
+
+// PullChanges is the job handler applying the changes made in a user's calendar since the
+// previous pull: moving a task's event changes its due date. It queues the next pull first,
+// so a failing pull doesn't stop the sync.
+func (s *CalendarService) PullChanges(ctx context.Context, payload []byte) error {
+	var p jobs.CalendarPullPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return fmt.Errorf("invalid calendar pull payload: %w", err)
+	}
+	if s.oauth == nil {
+		return nil // The integration was switched off; pulls resume when the user reconnects
+	}
+
+	conn, err := s.GetConnection(ctx, p.UserID)
+	if err == ErrCalendarNotConnected {
+		return nil // Disconnected since the pull was queued
+	}
+	if err != nil {
+		return err
+	}
+	// Reconnecting starts a new chain of pulls; this one belongs to an older chain
+	if conn.LastError != "" || !conn.NextPullAt.Equal(p.RunAt) {
+		return nil
+	}
+
+	next := p.RunAt.Add(s.pullInterval)
+	if now := time.Now().Truncate(time.Second); next.Before(now) {
+		next = now.Add(s.pullInterval)
+	}
+	if _, err := s.connectionCollection.UpdateOne(ctx, bson.M{"_id": conn.ID}, bson.M{"$set": bson.M{"next_pull_at": next}}); err != nil {
+		return err
+	}
+	if err := jobs.ScheduleCalendarPull(ctx, s.jobQueue, p.UserID, next); err != nil {
+		return err
+	}
+
+	client, tokens := s.client(ctx, conn)
+	syncToken, pageToken := conn.SyncToken, ""
+	for {
+		list, err := client.ListEvents(ctx, conn.CalendarID, syncToken, pageToken)
+		if err == gcal.ErrSyncTokenExpired {
+			syncToken, pageToken = "", ""
+			continue
+		}
+		if err != nil {
+			return s.syncFailed(ctx, conn, err)
+		}
+		for i := range list.Items {
+			if err := s.applyEvent(ctx, conn, &list.Items[i]); err != nil {
+				return err
+			}
+		}
+		if list.NextPageToken == "" {
+			syncToken = list.NextSyncToken
+			break
+		}
+		pageToken = list.NextPageToken
+	}
+	s.saveToken(ctx, conn, tokens)
+
+	_, err = s.connectionCollection.UpdateOne(ctx, bson.M{"_id": conn.ID}, bson.M{"$set": bson.M{
+		"sync_token":     syncToken,
+		"last_synced_at": time.Now(),
+	}})
+	return err
+}
+
+// applyEvent applies a changed calendar event to the task it mirrors, if any
+func (s *CalendarService) applyEvent(ctx context.Context, conn *models.CalendarConnection, event *gcal.Event) error {
+	taskID, err := primitive.ObjectIDFromHex(event.TaskID())
+	if err != nil {
+		return nil // Not one of ours
+	}
+	var link models.CalendarEvent
+	if err := s.eventCollection.FindOne(ctx, bson.M{"_id": taskID, "user_id": conn.UserID}).Decode(&link); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		return err
+	}
+
+	if event.Status == "cancelled" {
+		// Deleted in the calendar: the task keeps its due date but is no longer mirrored
+		_, err := s.eventCollection.DeleteOne(ctx, bson.M{"_id": taskID})
+		return err
+	}
+	if event.Start == nil {
+		return nil
+	}
+	start, err := event.Start.Time()
+	if err != nil || start.Equal(link.DueDate) {
+		return nil
+	}
+
+	// Record the new date first, so the push queued by the task update finds nothing to do
+	if _, err := s.eventCollection.UpdateOne(ctx, bson.M{"_id": taskID}, bson.M{"$set": bson.M{"due_date": start, "synced_at": time.Now()}}); err != nil {
+		return err
+	}
+	_, err = s.taskService.UpdateTask(ctx, taskID.Hex(), &models.UpdateTaskRequest{DueDate: &start})
+	if err == ErrTaskNotModified {
+		_, err = s.eventCollection.DeleteOne(ctx, bson.M{"_id": taskID})
+	}
+	return err
+}
+
+// client returns a Calendar client acting as the connection's user, and the token source
+// refreshing its access token
+func (s *CalendarService) client(ctx context.Context, conn *models.CalendarConnection) (*gcal.Client, oauth2.TokenSource) {
+	tokens := s.oauth.TokenSource(ctx, &oauth2.Token{
+		AccessToken:  conn.AccessToken,
+		RefreshToken: conn.RefreshToken,
+		Expiry:       conn.TokenExpiry,
+	})
+	return gcal.NewClient(oauth2.NewClient(ctx, tokens)), tokens
+}
+
+// saveToken stores the access token after it was refreshed, so the next job doesn't refresh it again
+func (s *CalendarService) saveToken(ctx context.Context, conn *models.CalendarConnection, tokens oauth2.TokenSource) {
+	token, err := tokens.Token()
+	if err != nil || token.AccessToken == conn.AccessToken {
+		return
+	}
+	_, err = s.connectionCollection.UpdateOne(ctx, bson.M{"_id": conn.ID}, bson.M{"$set": bson.M{
+		"access_token": token.AccessToken,
+		"token_expiry": token.Expiry,
+		"updated_at":   time.Now(),
+	}})
+	if err != nil {
+		logging.Warnf("Failed to store the refreshed Google token of user %s: %v", conn.UserID.Hex(), err)
+	}
+}
+
+// syncFailed handles a failed Calendar API call. When Google no longer accepts the refresh
+// token, syncing stops until the user reconnects; other errors are returned so the job retries.
+func (s *CalendarService) syncFailed(ctx context.Context, conn *models.CalendarConnection, err error) error {
+	var retrieveErr *oauth2.RetrieveError
+	if !errors.As(err, &retrieveErr) || retrieveErr.ErrorCode != "invalid_grant" {
+		return err
+	}
+	_, updateErr := s.connectionCollection.UpdateOne(ctx, bson.M{"_id": conn.ID}, bson.M{"$set": bson.M{
+		"last_error": calendarRevokedError,
+		"updated_at": time.Now(),
+	}})
+	return updateErr
+}
+
+// signState encodes the user starting the consent flow and when the request expires
+func (s *CalendarService) signState(userID primitive.ObjectID, expires time.Time) string {
+	payload := userID.Hex() + "." + strconv.FormatInt(expires.Unix(), 10)
+	return payload + "." + s.stateSignature(payload)
+}
+
+// verifyState returns the user a state parameter was signed for
+func (s *CalendarService) verifyState(state string) (primitive.ObjectID, error) {
+	parts := strings.Split(state, ".")
+	if len(parts) != 3 {
+		return primitive.NilObjectID, ErrInvalidCalendarState
+	}
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(s.stateSignature(payload))) {
+		return primitive.NilObjectID, ErrInvalidCalendarState
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return primitive.NilObjectID, ErrInvalidCalendarState
+	}
+	userID, err := primitive.ObjectIDFromHex(parts[0])
+	if err != nil {
+		return primitive.NilObjectID, ErrInvalidCalendarState
+	}
+	return userID, nil
+}
+
+// stateSignature signs a state payload, keyed so it can't be confused with other signatures
+func (s *CalendarService) stateSignature(payload string) string {
+	mac := hmac.New(sha256.New, s.stateSecret)
+	mac.Write([]byte("calendar-state:" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
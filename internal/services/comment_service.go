@@ -0,0 +1,261 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/OsGift/taskflow-api/internal/logging"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+// CommentService stores the comments on tasks. Edited comments keep their earlier versions
+// in the comment_versions collection.
+type CommentService struct {
+	commentCollection repository.Collection
+	versionCollection repository.Collection
+	editWindow        time.Duration // How long after posting authors may edit; 0 means forever
+}
+
+// NewCommentService creates a new CommentService
+func NewCommentService(db repository.Documents, editWindow time.Duration) *CommentService {
+	return &CommentService{
+		commentCollection: db.Collection("comments"),
+		versionCollection: db.Collection("comment_versions"),
+		editWindow:        editWindow,
+	}
+}
+
+// CreateComment adds a comment to a task
+func (s *CommentService) CreateComment(ctx context.Context, taskID, authorID primitive.ObjectID, body string) (*models.Comment, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	comment := &models.Comment{
+		ID:        primitive.NewObjectID(),
+		TaskID:    taskID,
+		AuthorID:  authorID,
+		Body:      body,
+		Version:   1,
+		CreatedAt: time.Now(),
+	}
+	if _, err := s.commentCollection.InsertOne(ctx, comment); err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// ListComments retrieves the comments on a task matching the query
+func (s *CommentService) ListComments(ctx context.Context, taskID primitive.ObjectID, q *query.Query) (*models.CommentListResponse, error) {
+	q.Filter["task_id"] = taskID
+	return s.SearchComments(ctx, q)
+}
+
+// SearchComments retrieves the comments on any task matching the query
+func (s *CommentService) SearchComments(ctx context.Context, q *query.Query) (*models.CommentListResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cursor, err := s.commentCollection.Find(ctx, q.Filter, q.FindOptions())
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	comments := []models.Comment{}
+	if err = cursor.All(ctx, &comments); err != nil {
+		return nil, err
+	}
+
+	totalCount, err := s.commentCollection.CountDocuments(ctx, q.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CommentListResponse{
+		Comments:   comments,
+		Pagination: models.NewPagination(totalCount, q.Page, q.Limit),
+	}, nil
+}
+
+// TaskComments retrieves all of a task's comments, oldest first
+func (s *CommentService) TaskComments(ctx context.Context, taskID primitive.ObjectID) ([]models.Comment, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cursor, err := s.commentCollection.Find(ctx, bson.M{"task_id": taskID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	comments := []models.Comment{}
+	if err = cursor.All(ctx, &comments); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// Each calls fn with every comment, in ID order, reading them from the database as it goes
+func (s *CommentService) Each(ctx context.Context, fn func(*models.Comment) error) error {
+	cursor, err := s.commentCollection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var comment models.Comment
+		if err := cursor.Decode(&comment); err != nil {
+			return err
+		}
+		if err := fn(&comment); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// GetComment retrieves one of a task's comments
+func (s *CommentService) GetComment(ctx context.Context, taskID primitive.ObjectID, commentIDHex string) (*models.Comment, error) {
+	commentID, err := primitive.ObjectIDFromHex(commentIDHex)
+	if err != nil {
+		return nil, ErrInvalidCommentID
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var comment models.Comment
+	err = s.commentCollection.FindOne(ctx, bson.M{"_id": commentID, "task_id": taskID}).Decode(&comment)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrCommentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// UpdateComment replaces the body of a comment, keeping the previous one as a version.
+// Only the author may edit, and only within the edit window when one is configured.
+func (s *CommentService) UpdateComment(ctx context.Context, taskID primitive.ObjectID, commentIDHex string, authorID primitive.ObjectID, body string) (*models.Comment, error) {
+	comment, err := s.GetComment(ctx, taskID, commentIDHex)
+	if err != nil {
+		return nil, err
+	}
+	if comment.AuthorID != authorID {
+		return nil, ErrNotCommentAuthor
+	}
+	now := time.Now()
+	if s.editWindow > 0 && now.Sub(comment.CreatedAt) > s.editWindow {
+		return nil, ErrCommentEditWindowClosed.WithDetails(map[string]interface{}{"edit_window_minutes": int(s.editWindow.Minutes())})
+	}
+	if body == comment.Body {
+		return comment, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// The current version is saved before it is replaced. Concurrent edits save the same
+	// version, so the upsert is harmless; only the first of them then updates the comment.
+	written := comment.CreatedAt
+	if comment.EditedAt != nil {
+		written = *comment.EditedAt
+	}
+	_, err = s.versionCollection.ReplaceOne(ctx,
+		bson.M{"comment_id": comment.ID, "version": comment.Version},
+		models.CommentVersion{
+			CommentID:  comment.ID,
+			Version:    comment.Version,
+			Body:       comment.Body,
+			CreatedAt:  written,
+			ReplacedAt: now,
+		},
+		options.Replace().SetUpsert(true))
+	if err != nil && !mongo.IsDuplicateKeyError(err) { // A concurrent edit inserted it first
+		return nil, err
+	}
+
+	result, err := s.commentCollection.UpdateOne(ctx,
+		bson.M{"_id": comment.ID, "version": comment.Version},
+		bson.M{"$set": bson.M{"body": body, "version": comment.Version + 1, "edited_at": now}})
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, ErrCommentEditConflict
+	}
+
+	comment.Body = body
+	comment.Version++
+	comment.EditedAt = &now
+	return comment, nil
+}
+
+// History returns a comment and its earlier versions, oldest first
+func (s *CommentService) History(ctx context.Context, taskID primitive.ObjectID, commentIDHex string) (*models.CommentHistoryResponse, error) {
+	comment, err := s.GetComment(ctx, taskID, commentIDHex)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cursor, err := s.versionCollection.Find(ctx, bson.M{"comment_id": comment.ID}, options.Find().SetSort(bson.D{{Key: "version", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	versions := []models.CommentVersion{}
+	if err = cursor.All(ctx, &versions); err != nil {
+		return nil, err
+	}
+	return &models.CommentHistoryResponse{Comment: *comment, Versions: versions}, nil
+}
+
+// DeleteComment deletes a comment and its earlier versions
+func (s *CommentService) DeleteComment(ctx context.Context, commentID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := s.commentCollection.DeleteOne(ctx, bson.M{"_id": commentID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrCommentNotFound
+	}
+	_, err = s.versionCollection.DeleteMany(ctx, bson.M{"comment_id": commentID})
+	return err
+}
+
+// TaskSaved does nothing; it makes CommentService a TaskObserver
+func (s *CommentService) TaskSaved(ctx context.Context, task *models.Task) {}
+
+// TaskDeleted deletes the comments of a deleted task
+func (s *CommentService) TaskDeleted(ctx context.Context, taskID primitive.ObjectID) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"task_id": taskID}
+	commentIDs, err := s.commentCollection.Distinct(ctx, "_id", filter)
+	if err == nil && len(commentIDs) > 0 {
+		if _, err = s.versionCollection.DeleteMany(ctx, bson.M{"comment_id": bson.M{"$in": commentIDs}}); err == nil {
+			_, err = s.commentCollection.DeleteMany(ctx, filter)
+		}
+	}
+	if err != nil {
+		logging.Warnf("Failed to delete the comments of deleted task %s: %v", taskID.Hex(), err)
+	}
+}
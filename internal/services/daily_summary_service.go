@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/OsGift/taskflow-api/internal/jobs"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+const (
+	// dailySummaryTemplate is the email template of the daily summary
+	dailySummaryTemplate = "daily_summary"
+	dailySummarySubject  = "Your TaskFlow day"
+
+	// maxSummaryListed caps the tasks listed in each section of a summary
+	maxSummaryListed = 10
+)
+
+// DailySummaryService sends opted-in users a morning summary of the tasks due that day, those
+// in progress and those new to them, at the hour of their day they chose
+type DailySummaryService struct {
+	users         repository.UserRepository
+	tasks         repository.TaskRepository
+	jobQueue      *jobs.Queue
+	notifications *NotificationService
+	enabled       atomic.Bool
+	defaultHour   int
+}
+
+// NewDailySummaryService creates a DailySummaryService sending summaries at defaultHour:00 of
+// the users' days, unless they chose another hour. When enabled is false, runs that were
+// already queued do nothing and aren't rescheduled.
+func NewDailySummaryService(store *repository.Store, jq *jobs.Queue, ns *NotificationService, enabled bool, defaultHour int) *DailySummaryService {
+	s := &DailySummaryService{
+		users:         store.Users,
+		tasks:         store.Tasks,
+		jobQueue:      jq,
+		notifications: ns,
+		defaultHour:   defaultHour,
+	}
+	s.enabled.Store(enabled)
+	return s
+}
+
+// SetEnabled turns summaries on or off, queuing the next run when turning them on
+func (s *DailySummaryService) SetEnabled(ctx context.Context, enabled bool) error {
+	if s.enabled.Swap(enabled) || !enabled {
+		return nil
+	}
+	return s.Schedule(ctx)
+}
+
+// Schedule makes sure the next summary run is queued
+func (s *DailySummaryService) Schedule(ctx context.Context) error {
+	return jobs.ScheduleNext(ctx, s.jobQueue, jobs.TypeDailySummary, s.NextRun)
+}
+
+// NextRun is the jobs.NextRunFunc of TypeDailySummary: runs are hourly while summaries are
+// enabled, as users choose the hour of their own day they get the summary at
+func (s *DailySummaryService) NextRun(ctx context.Context, run jobs.RunPayload) (time.Time, bool, error) {
+	return jobs.NextHour(run.RunAt), s.enabled.Load(), nil
+}
+
+// SendDailySummaries handles a TypeDailySummary run: it sends a summary to every opted-in user
+// for whom the run falls on their summary hour. Summaries are keyed by user and day, so a
+// retried run doesn't notify anyone twice.
+func (s *DailySummaryService) SendDailySummaries(ctx context.Context, run jobs.RunPayload) error {
+	var sent int
+	for page := int64(1); ; page++ {
+		q := query.New(bson.M{"daily_summary": true}, page, 100)
+		q.Sort = bson.D{{Key: "_id", Value: 1}}
+		users, err := s.users.List(ctx, q)
+		if err != nil {
+			return err
+		}
+
+		for _, user := range users {
+			hour := s.defaultHour
+			if user.DailySummaryHour != nil {
+				hour = *user.DailySummaryHour
+			}
+			now := run.RunAt.In(user.Location())
+			if now.Hour() != hour {
+				continue
+			}
+
+			queued, err := s.sendSummary(ctx, &user, now)
+			if err != nil {
+				return fmt.Errorf("failed to send daily summary to user %s: %w", user.ID.Hex(), err)
+			}
+			if queued {
+				sent++
+			}
+		}
+		if int64(len(users)) < q.Limit {
+			break
+		}
+	}
+
+	log.Printf("Daily summary: sent %d summaries", sent)
+	return nil
+}
+
+// sendSummary sends the summary of one user for the day of now on the channels they chose,
+// unless they have nothing to report or it was already sent that day. New tasks are those
+// created for the user, or handed over to them, in the last day.
+func (s *DailySummaryService) sendSummary(ctx context.Context, user *models.User, now time.Time) (bool, error) {
+	open := bson.M{"$in": []string{string(models.StatusTodo), string(models.StatusInProgress)}}
+	since := now.AddDate(0, 0, -1)
+
+	dueToday, dueCount, err := s.listTasks(ctx, bson.M{
+		"user_id":  user.ID,
+		"status":   open,
+		"archived": bson.M{"$ne": true},
+		"due_date": bson.M{"$gte": startOfDay(now, 0), "$lt": startOfDay(now, 1)},
+	}, bson.D{{Key: "due_date", Value: 1}}, now.Location())
+	if err != nil {
+		return false, err
+	}
+	inProgress, inProgressCount, err := s.listTasks(ctx, bson.M{
+		"user_id":  user.ID,
+		"status":   models.StatusInProgress,
+		"archived": bson.M{"$ne": true},
+	}, bson.D{{Key: "updated_at", Value: -1}}, now.Location())
+	if err != nil {
+		return false, err
+	}
+	newTasks, newCount, err := s.listTasks(ctx, bson.M{
+		"user_id":  user.ID,
+		"status":   open,
+		"archived": bson.M{"$ne": true},
+		"$or":      []bson.M{{"created_at": bson.M{"$gte": since}}, {"assigned_at": bson.M{"$gte": since}}},
+	}, bson.D{{Key: "created_at", Value: -1}}, now.Location())
+	if err != nil {
+		return false, err
+	}
+	if dueCount == 0 && inProgressCount == 0 && newCount == 0 {
+		return false, nil
+	}
+
+	emailData := map[string]interface{}{
+		"FirstName":       user.FirstName,
+		"Date":            now.Format("Monday, January 2"),
+		"DueToday":        dueToday,
+		"DueTodayCount":   dueCount,
+		"InProgress":      inProgress,
+		"InProgressCount": inProgressCount,
+		"New":             newTasks,
+		"NewCount":        newCount,
+		"DashboardLink":   "http://localhost:3000/dashboard", // Frontend dashboard URL
+		"Year":            now.Year(),
+	}
+	return s.notifications.Notify(ctx, &Notice{
+		Event:    models.EventDailySummary,
+		User:     user,
+		Title:    "Your day",
+		Body:     fmt.Sprintf("%d tasks due today, %d in progress, %d new.", dueCount, inProgressCount, newCount),
+		Link:     "http://localhost:3000/dashboard",
+		Template: dailySummaryTemplate,
+		Subject:  dailySummarySubject,
+		Data:     emailData,
+		Key:      fmt.Sprintf("%s:%s:%s", jobs.TypeDailySummary, now.Format("2006-01-02"), user.ID.Hex()),
+	})
+}
+
+// listTasks returns the first tasks matching filter in sort order for a summary, with due
+// times in loc, and how many match in all
+func (s *DailySummaryService) listTasks(ctx context.Context, filter bson.M, sort bson.D, loc *time.Location) ([]map[string]interface{}, int64, error) {
+	count, err := s.tasks.Count(ctx, filter)
+	if err != nil || count == 0 {
+		return nil, 0, err
+	}
+	q := query.New(filter, 1, maxSummaryListed)
+	q.Sort = sort
+	tasks, err := s.tasks.List(ctx, q)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	listed := make([]map[string]interface{}, 0, len(tasks))
+	for _, task := range tasks {
+		item := map[string]interface{}{"Title": task.Title}
+		if task.DueDate != nil {
+			item["DueDate"] = task.DueDate.In(loc).Format("Mon, Jan 2 15:04 MST")
+		}
+		listed = append(listed, item)
+	}
+	return listed, count, nil
+}
@@ -2,158 +2,472 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"sort"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/sync/singleflight"
 
+	"github.com/OsGift/taskflow-api/internal/cache"
 	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/repository"
 )
 
-// DashboardService provides methods for fetching application-wide metrics
+// DashboardService provides methods for fetching application-wide and per-user metrics
 type DashboardService struct {
-	usersCollection *mongo.Collection
-	tasksCollection *mongo.Collection
-	rolesCollection *mongo.Collection
+	users repository.UserRepository
+	tasks repository.TaskRepository
+	cache cache.Cache // Shared cache for computed metrics; may be nil
+
+	// metrics coalesces concurrent cache misses for the same period so that a burst of
+	// dashboard loads runs the underlying counts once
+	metrics singleflight.Group
 }
 
 // NewDashboardService creates a new DashboardService
-func NewDashboardService(db *mongo.Database) *DashboardService {
+func NewDashboardService(store *repository.Store, c cache.Cache) *DashboardService {
 	return &DashboardService{
-		usersCollection: db.Collection("users"),
-		tasksCollection: db.Collection("tasks"),
-		rolesCollection: db.Collection("roles"),
+		users: store.Users,
+		tasks: store.Tasks,
+		cache: c,
 	}
 }
 
-// GetDashboardMetrics fetches various metrics based on the specified time period or custom range
+// GetDashboardMetrics fetches various metrics based on the specified time period or custom
+// range. The current day, week or month is the one in loc.
 func (s *DashboardService) GetDashboardMetrics(
+	ctx context.Context,
 	period models.DashboardPeriod,
 	startDate, endDate *time.Time,
+	loc *time.Location,
 ) (*models.DashboardMetricsResponse, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
 	defer cancel()
 
+	// Metrics are cached per period/range; task and user writes invalidate them
+	cacheKey := cachePrefixDashboard + string(period) + ":" + loc.String()
+	if startDate != nil && endDate != nil {
+		cacheKey += ":" + startDate.Format(time.RFC3339) + ":" + endDate.Format(time.RFC3339)
+	}
+	var cached models.DashboardMetricsResponse
+	if cache.GetJSON(ctx, s.cache, cacheKey, &cached) {
+		return &cached, nil
+	}
+
+	v, err, _ := s.metrics.Do(cacheKey, func() (interface{}, error) {
+		metrics, err := s.computeMetrics(ctx, period, startDate, endDate, loc)
+		if err != nil {
+			return nil, err
+		}
+		cache.SetJSON(ctx, s.cache, cacheKey, metrics, dashboardCacheTTL)
+		return metrics, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Callers sharing a result get their own copy, as they would from the cache
+	metrics := *v.(*models.DashboardMetricsResponse)
+	return &metrics, nil
+}
+
+// computeMetrics runs the counts behind GetDashboardMetrics
+func (s *DashboardService) computeMetrics(
+	ctx context.Context,
+	period models.DashboardPeriod,
+	startDate, endDate *time.Time,
+	loc *time.Location,
+) (*models.DashboardMetricsResponse, error) {
 	metrics := &models.DashboardMetricsResponse{
 		Period: period,
 	}
 
-	// 1. Get total counts (always relevant)
-	totalUsers, err := s.usersCollection.CountDocuments(ctx, bson.M{})
+	// "New" counts and the status breakdown are limited to the period, if any
+	var from, to *time.Time
+	if start, end, ok := periodRange(period, startDate, endDate, loc); ok {
+		from, to = &start, &end
+		metrics.StartDate = &start
+		metrics.EndDate = &end
+	}
+
+	// Every count comes from a single aggregation rather than one query each
+	counts, err := s.users.DashboardCounts(ctx, time.Now(), from, to)
 	if err != nil {
 		return nil, err
 	}
-	metrics.TotalUsers = totalUsers
+	metrics.TotalUsers = counts.TotalUsers
+	metrics.TotalTasks = counts.TotalTasks
+	metrics.NewUsers = counts.NewUsers
+	metrics.NewTasks = counts.NewTasks
+	metrics.TasksByStatus = counts.TasksByStatus
+	metrics.OverdueCount = counts.OverdueTasks
+	metrics.OpenTasksByAge = counts.OpenTasksByAge
+	metrics.UsersByRole = counts.UsersByRole
 
-	totalTasks, err := s.tasksCollection.CountDocuments(ctx, bson.M{})
+	return metrics, nil
+}
+
+// periodRange returns the time range a dashboard period covers: startDate to endDate for a
+// custom period, otherwise the current day, week (from Monday) or month in loc up to now. ok
+// is false for a custom period without dates.
+func periodRange(period models.DashboardPeriod, startDate, endDate *time.Time, loc *time.Location) (start, end time.Time, ok bool) {
+	if period == models.PeriodCustom {
+		if startDate == nil || endDate == nil {
+			return time.Time{}, time.Time{}, false
+		}
+		return *startDate, *endDate, true
+	}
+
+	// Calculate dynamic start/end dates based on period
+	now := time.Now().In(loc)
+	switch period {
+	case models.PeriodDaily:
+		start = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	case models.PeriodWeekly:
+		weekday := int(now.Weekday())
+		if weekday == 0 { // Sunday
+			weekday = 7
+		}
+		start = time.Date(now.Year(), now.Month(), now.Day()-(weekday-1), 0, 0, 0, 0, now.Location())
+	case models.PeriodMonthly:
+		start = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	}
+	return start, now, true
+}
+
+// GetLeaderboard ranks users by the number of tasks they completed during the period.
+// Pages are cached like the dashboard metrics.
+func (s *DashboardService) GetLeaderboard(
+	ctx context.Context,
+	period models.DashboardPeriod,
+	startDate, endDate *time.Time,
+	loc *time.Location,
+	page, limit int64,
+) (*models.LeaderboardResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	start, end, ok := periodRange(period, startDate, endDate, loc)
+	if !ok {
+		return nil, ErrInvalidDashboardPeriod
+	}
+	q := query.New(nil, page, limit)
+
+	cacheKey := fmt.Sprintf("%sleaderboard:%s:%s:%d:%d", cachePrefixDashboard, period, loc, q.Page, q.Limit)
+	if period == models.PeriodCustom {
+		cacheKey += ":" + start.Format(time.RFC3339) + ":" + end.Format(time.RFC3339)
+	}
+	var cached models.LeaderboardResponse
+	if cache.GetJSON(ctx, s.cache, cacheKey, &cached) {
+		return &cached, nil
+	}
+
+	entries, total, err := s.tasks.CompletionLeaderboard(ctx, start, end, q.Skip(), q.Limit)
 	if err != nil {
 		return nil, err
 	}
-	metrics.TotalTasks = totalTasks
+	leaderboard := &models.LeaderboardResponse{
+		Entries:    entries,
+		Pagination: models.NewPagination(total, q.Page, q.Limit),
+		StartDate:  start,
+		EndDate:    end,
+		Period:     period,
+	}
+
+	cache.SetJSON(ctx, s.cache, cacheKey, leaderboard, dashboardCacheTTL)
+	return leaderboard, nil
+}
+
+// activityHeatmapDays is how many days, up to today, GetActivityHeatmap covers
+const activityHeatmapDays = 365
+
+// GetActivityHeatmap counts the tasks created and completed on each of the last
+// activityHeatmapDays calendar days in loc, for userID's tasks or the whole workspace when
+// userID is nil. Days without activity are included with zero counts.
+func (s *DashboardService) GetActivityHeatmap(ctx context.Context, userID *primitive.ObjectID, loc *time.Location) (*models.ActivityHeatmapResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	now := time.Now().In(loc)
+	end := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	start := end.AddDate(0, 0, 1-activityHeatmapDays)
 
-	// 2. Get counts by role
-	adminRole, _ := s.rolesCollection.FindOne(ctx, bson.M{"name": "Admin"}).DecodeBytes()
-	if adminRole != nil {
-		metrics.AdminsCount, _ = s.usersCollection.CountDocuments(ctx, bson.M{"role_id": adminRole.Lookup("_id").ObjectID()})
+	filter := bson.M{}
+	scope := "all"
+	if userID != nil {
+		filter["user_id"] = *userID
+		scope = userID.Hex()
 	}
-	managerRole, _ := s.rolesCollection.FindOne(ctx, bson.M{"name": "Manager"}).DecodeBytes()
-	if managerRole != nil {
-		metrics.ManagersCount, _ = s.usersCollection.CountDocuments(ctx, bson.M{"role_id": managerRole.Lookup("_id").ObjectID()})
+	cacheKey := fmt.Sprintf("%sactivity:%s:%s:%s", cachePrefixDashboard, scope, loc, end.Format("2006-01-02"))
+	var cached models.ActivityHeatmapResponse
+	if cache.GetJSON(ctx, s.cache, cacheKey, &cached) {
+		return &cached, nil
 	}
-	userRole, _ := s.rolesCollection.FindOne(ctx, bson.M{"name": "User"}).DecodeBytes()
-	if userRole != nil {
-		metrics.RegularUsersCount, _ = s.usersCollection.CountDocuments(ctx, bson.M{"role_id": userRole.Lookup("_id").ObjectID()})
+
+	active, err := s.tasks.ActivityByDay(ctx, filter, start, loc)
+	if err != nil {
+		return nil, err
+	}
+	byDate := make(map[string]models.ActivityDay, len(active))
+	for _, day := range active {
+		byDate[day.Date] = day
 	}
 
-	// 3. Define date range for "new" counts and filtering
-	var periodFilter bson.M
-	if period == models.PeriodCustom && startDate != nil && endDate != nil {
-		periodFilter = bson.M{
-			"created_at": bson.M{
-				"$gte": *startDate,
-				"$lte": *endDate,
-			},
-		}
-		metrics.StartDate = startDate
-		metrics.EndDate = endDate
-	} else if period != models.PeriodCustom {
-		// Calculate dynamic start/end dates based on period
-		now := time.Now()
-		var start time.Time
-		switch period {
-		case models.PeriodDaily:
-			start = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-			endDate = &now // End date is now
-		case models.PeriodWeekly:
-			weekday := time.Duration(now.Weekday())
-			if weekday == 0 { // Sunday
-				weekday = 7
-			}
-			start = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).Add(-((weekday - 1) * 24 * time.Hour))
-			endDate = &now
-		case models.PeriodMonthly:
-			start = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
-			endDate = &now
-		}
-		periodFilter = bson.M{
-			"created_at": bson.M{
-				"$gte": start,
-				"$lte": *endDate,
-			},
+	heatmap := &models.ActivityHeatmapResponse{
+		UserID:    userID,
+		StartDate: start.Format("2006-01-02"),
+		EndDate:   end.Format("2006-01-02"),
+		TimeZone:  loc.String(),
+		Days:      make([]models.ActivityDay, 0, activityHeatmapDays),
+	}
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		activity, ok := byDate[date]
+		if !ok {
+			activity = models.ActivityDay{Date: date}
 		}
-		metrics.StartDate = &start
-		metrics.EndDate = endDate
+		heatmap.Total += activity.Count
+		heatmap.Days = append(heatmap.Days, activity)
 	}
 
-	// 4. Get new users/tasks within the specified period
-	if periodFilter != nil {
-		newUsers, err := s.usersCollection.CountDocuments(ctx, periodFilter)
-		if err != nil {
-			return nil, err
-		}
-		metrics.NewUsers = newUsers
+	cache.SetJSON(ctx, s.cache, cacheKey, heatmap, dashboardCacheTTL)
+	return heatmap, nil
+}
 
-		newTasks, err := s.tasksCollection.CountDocuments(ctx, periodFilter)
-		if err != nil {
-			return nil, err
+// GetProjectMetrics returns statistics about the tasks of a project: how they are spread over
+// statuses and members, and how many were created and completed during the period. Days
+// are calendar days in loc.
+func (s *DashboardService) GetProjectMetrics(
+	ctx context.Context,
+	project *models.Project,
+	period models.DashboardPeriod,
+	startDate, endDate *time.Time,
+	loc *time.Location,
+) (*models.ProjectMetricsResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	start, end, ok := periodRange(period, startDate, endDate, loc)
+	if !ok {
+		return nil, ErrInvalidDashboardPeriod
+	}
+
+	cacheKey := fmt.Sprintf("%sproject:%s:%s:%s", cachePrefixDashboard, project.ID.Hex(), period, loc)
+	if period == models.PeriodCustom {
+		cacheKey += ":" + start.Format(time.RFC3339) + ":" + end.Format(time.RFC3339)
+	}
+	var cached models.ProjectMetricsResponse
+	if cache.GetJSON(ctx, s.cache, cacheKey, &cached) {
+		return &cached, nil
+	}
+
+	now := time.Now()
+	inProject := bson.M{"project_id": project.ID}
+	open := bson.M{"$in": []string{string(models.StatusTodo), string(models.StatusInProgress)}}
+	openFilter := bson.M{"project_id": project.ID, "status": open}
+	overdueFilter := bson.M{"project_id": project.ID, "status": open, "due_date": bson.M{"$lt": now}}
+	completedFilter := bson.M{"project_id": project.ID, "status": models.StatusDone, "completed_at": bson.M{"$gte": start, "$lte": end}}
+	metrics := &models.ProjectMetricsResponse{
+		ProjectID: project.ID,
+		StartDate: start,
+		EndDate:   end,
+		Period:    period,
+		TimeZone:  loc.String(),
+	}
+
+	var err error
+	if metrics.TotalTasks, err = s.tasks.Count(ctx, inProject); err != nil {
+		return nil, err
+	}
+	if metrics.TasksByStatus, err = s.tasks.CountByStatus(ctx, inProject); err != nil {
+		return nil, err
+	}
+	if metrics.OverdueCount, err = s.tasks.Count(ctx, overdueFilter); err != nil {
+		return nil, err
+	}
+	if metrics.MemberWorkload, err = s.memberWorkload(ctx, project, openFilter, overdueFilter, completedFilter); err != nil {
+		return nil, err
+	}
+
+	throughput := &metrics.Throughput
+	if throughput.Created, err = s.tasks.Count(ctx, bson.M{"project_id": project.ID, "created_at": bson.M{"$gte": start, "$lte": end}}); err != nil {
+		return nil, err
+	}
+	if throughput.Completed, err = s.tasks.Count(ctx, completedFilter); err != nil {
+		return nil, err
+	}
+	days, err := s.tasks.ActivityByDay(ctx, inProject, start, loc)
+	if err != nil {
+		return nil, err
+	}
+	throughput.Days = make([]models.ActivityDay, 0, len(days))
+	lastDay := end.In(loc).Format("2006-01-02")
+	for _, day := range days {
+		if day.Date <= lastDay { // ActivityByDay has no upper bound
+			throughput.Days = append(throughput.Days, day)
 		}
-		metrics.NewTasks = newTasks
-	} else {
-		// If no periodFilter (e.g., initial load without specific date filters), new users/tasks count is 0
-		metrics.NewUsers = 0
-		metrics.NewTasks = 0
 	}
 
-	// 5. Get tasks by status
-	// Use aggregation pipeline for tasks by status
-	pipeline := mongo.Pipeline{
-		bson.D{{Key: "$group", Value: bson.D{
-			{Key: "_id", Value: "$status"},
-			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
-		}}},
-		bson.D{{Key: "$project", Value: bson.D{
-			{Key: "status", Value: "$_id"},
-			{Key: "count", Value: 1},
-			{Key: "_id", Value: 0},
-		}}},
+	cache.SetJSON(ctx, s.cache, cacheKey, metrics, dashboardCacheTTL)
+	return metrics, nil
+}
+
+// memberWorkload counts the open, overdue and completed tasks of a project's owner and
+// members, most open tasks first. Tasks of former members aren't anyone's workload.
+func (s *DashboardService) memberWorkload(ctx context.Context, project *models.Project, openFilter, overdueFilter, completedFilter bson.M) ([]models.MemberWorkload, error) {
+	open, err := s.countByUser(ctx, openFilter)
+	if err != nil {
+		return nil, err
+	}
+	overdue, err := s.countByUser(ctx, overdueFilter)
+	if err != nil {
+		return nil, err
+	}
+	completed, err := s.countByUser(ctx, completedFilter)
+	if err != nil {
+		return nil, err
 	}
 
-	// Add period filter to aggregation if applicable
-	if periodFilter != nil {
-		pipeline = append(mongo.Pipeline{bson.D{{Key: "$match", Value: periodFilter}}}, pipeline...)
+	workload := []models.MemberWorkload{{UserID: project.OwnerID, Role: models.ProjectRoleManager}}
+	for _, member := range project.Members {
+		workload = append(workload, models.MemberWorkload{UserID: member.UserID, Role: member.Role})
 	}
+	for i := range workload {
+		member := &workload[i]
+		member.OpenTasks = open[member.UserID]
+		member.OverdueCount = overdue[member.UserID]
+		member.CompletedCount = completed[member.UserID]
+	}
+	sort.SliceStable(workload, func(i, j int) bool { return workload[i].OpenTasks > workload[j].OpenTasks })
+	return workload, nil
+}
 
-	cursor, err := s.tasksCollection.Aggregate(ctx, pipeline)
+// countByUser counts the tasks matching filter per owner
+func (s *DashboardService) countByUser(ctx context.Context, filter bson.M) (map[primitive.ObjectID]int64, error) {
+	counts, err := s.tasks.CountByUser(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(ctx)
+	byUser := make(map[primitive.ObjectID]int64, len(counts))
+	for _, count := range counts {
+		byUser[count.UserID] = count.Count
+	}
+	return byUser, nil
+}
+
+// recentActivityLimit caps the tasks listed in MyDashboardResponse.RecentActivity
+const recentActivityLimit = 10
+
+// GetMyDashboard returns statistics about the tasks of userID. Days (for tasks due today or
+// soon, and the completion streak) are calendar days in loc.
+func (s *DashboardService) GetMyDashboard(ctx context.Context, userID primitive.ObjectID, loc *time.Location) (*models.MyDashboardResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	now := time.Now().In(loc)
+	own := bson.M{"user_id": userID}
+	open := bson.M{"$in": []string{string(models.StatusTodo), string(models.StatusInProgress)}}
+	metrics := &models.MyDashboardResponse{TimeZone: loc.String()}
 
-	var taskStatusCounts []models.TaskStatusCount
-	if err = cursor.All(ctx, &taskStatusCounts); err != nil {
+	var err error
+	if metrics.TotalTasks, err = s.tasks.Count(ctx, own); err != nil {
+		return nil, err
+	}
+	if metrics.TasksByStatus, err = s.tasks.CountByStatus(ctx, own); err != nil {
+		return nil, err
+	}
+	if metrics.OverdueCount, err = s.tasks.Count(ctx, bson.M{"user_id": userID, "status": open, "due_date": bson.M{"$lt": now}}); err != nil {
+		return nil, err
+	}
+	metrics.DueTodayCount, err = s.tasks.Count(ctx, bson.M{
+		"user_id":  userID,
+		"status":   open,
+		"due_date": bson.M{"$gte": startOfDay(now, 0), "$lt": startOfDay(now, 1)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	metrics.DueSoonCount, err = s.tasks.Count(ctx, bson.M{
+		"user_id":  userID,
+		"status":   open,
+		"due_date": bson.M{"$gte": now, "$lt": startOfDay(now, 8)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if metrics.CompletionStreak, err = s.completionStreak(ctx, userID, now); err != nil {
 		return nil, err
 	}
-	metrics.TasksByStatus = taskStatusCounts
 
+	q := query.New(own, 1, recentActivityLimit)
+	q.Sort = bson.D{{Key: "updated_at", Value: -1}, {Key: "_id", Value: -1}}
+	recent, err := s.tasks.List(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	metrics.RecentActivity = make([]models.TaskActivity, 0, len(recent))
+	for _, task := range recent {
+		metrics.RecentActivity = append(metrics.RecentActivity, taskActivity(&task))
+	}
 	return metrics, nil
 }
+
+// startOfDay returns midnight days days after the day of t, in t's time zone
+func startOfDay(t time.Time, days int) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day()+days, 0, 0, 0, 0, t.Location())
+}
+
+// completionStreak counts the consecutive days up to now on which userID completed at least
+// one task. A streak without a completion today yet still counts until the day is over.
+func (s *DashboardService) completionStreak(ctx context.Context, userID primitive.ObjectID, now time.Time) (int, error) {
+	today := startOfDay(now, 0)
+
+	var streak int
+	var last time.Time // Last day counted
+	filter := bson.M{"user_id": userID, "status": models.StatusDone, "completed_at": bson.M{"$lte": now}}
+	for page := int64(1); ; page++ {
+		q := query.New(filter, page, 100)
+		q.Sort = bson.D{{Key: "completed_at", Value: -1}, {Key: "_id", Value: -1}}
+		tasks, err := s.tasks.List(ctx, q)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, task := range tasks {
+			day := startOfDay(task.CompletedAt.In(now.Location()), 0)
+			switch {
+			case streak > 0 && day.Equal(last):
+				continue
+			case streak == 0 && (day.Equal(today) || day.Equal(today.AddDate(0, 0, -1))),
+				streak > 0 && day.Equal(last.AddDate(0, 0, -1)):
+				streak++
+				last = day
+			default:
+				return streak, nil
+			}
+		}
+		if int64(len(tasks)) < q.Limit {
+			return streak, nil
+		}
+	}
+}
+
+// taskActivity describes the latest change to task
+func taskActivity(task *models.Task) models.TaskActivity {
+	action := "updated"
+	switch {
+	case task.CompletedAt != nil && task.CompletedAt.Equal(task.UpdatedAt):
+		action = "completed"
+	case task.CreatedAt.Equal(task.UpdatedAt):
+		action = "created"
+	}
+	return models.TaskActivity{
+		TaskID: task.ID.Hex(),
+		Title:  task.Title,
+		Status: task.Status,
+		Action: action,
+		At:     task.UpdatedAt,
+	}
+}
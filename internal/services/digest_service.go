@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/OsGift/taskflow-api/internal/jobs"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+const (
+	// weeklyDigestTemplate is the email template of the weekly digest
+	weeklyDigestTemplate = "weekly_digest"
+	weeklyDigestSubject  = "Your TaskFlow weekly digest"
+
+	// maxDigestUpcoming caps the upcoming deadlines listed in a digest
+	maxDigestUpcoming = 5
+)
+
+// DigestService sends opted-in users a weekly summary of their tasks
+type DigestService struct {
+	users         repository.UserRepository
+	tasks         repository.TaskRepository
+	jobQueue      *jobs.Queue
+	notifications *NotificationService
+	enabled       atomic.Bool
+	weekday       time.Weekday
+	hour          int
+}
+
+// NewDigestService creates a DigestService sending digests on weekday at hour:00 UTC.
+// When enabled is false, runs that were already queued do nothing and aren't rescheduled.
+func NewDigestService(store *repository.Store, jq *jobs.Queue, ns *NotificationService, enabled bool, weekday time.Weekday, hour int) *DigestService {
+	s := &DigestService{
+		users:         store.Users,
+		tasks:         store.Tasks,
+		jobQueue:      jq,
+		notifications: ns,
+		weekday:       weekday,
+		hour:          hour,
+	}
+	s.enabled.Store(enabled)
+	return s
+}
+
+// SetEnabled turns digests on or off, queuing the next run when turning them on
+func (s *DigestService) SetEnabled(ctx context.Context, enabled bool) error {
+	if s.enabled.Swap(enabled) || !enabled {
+		return nil
+	}
+	return s.Schedule(ctx)
+}
+
+// Schedule makes sure the next digest run is queued
+func (s *DigestService) Schedule(ctx context.Context) error {
+	return jobs.ScheduleNext(ctx, s.jobQueue, jobs.TypeWeeklyDigest, s.NextRun)
+}
+
+// NextRun is the jobs.NextRunFunc of TypeWeeklyDigest: runs are weekly while digests are enabled
+func (s *DigestService) NextRun(ctx context.Context, run jobs.RunPayload) (time.Time, bool, error) {
+	return jobs.NextWeekly(run.RunAt, s.weekday, s.hour), s.enabled.Load(), nil
+}
+
+// SendWeeklyDigests handles a TypeWeeklyDigest run: it sends a digest to every opted-in user
+// with something to report. Digests are keyed by run and user, so a retried run doesn't notify
+// anyone twice.
+func (s *DigestService) SendWeeklyDigests(ctx context.Context, run jobs.RunPayload) error {
+	var sent int
+	for page := int64(1); ; page++ {
+		q := query.New(bson.M{"weekly_digest": true}, page, 100)
+		q.Sort = bson.D{{Key: "_id", Value: 1}}
+		users, err := s.users.List(ctx, q)
+		if err != nil {
+			return err
+		}
+
+		for _, user := range users {
+			queued, err := s.sendDigest(ctx, &user, run.RunAt)
+			if err != nil {
+				return fmt.Errorf("failed to send weekly digest to user %s: %w", user.ID.Hex(), err)
+			}
+			if queued {
+				sent++
+			}
+		}
+		if int64(len(users)) < q.Limit {
+			break
+		}
+	}
+
+	log.Printf("Weekly digest: sent %d digests", sent)
+	return nil
+}
+
+// sendDigest sends the digest of one user on the channels they chose, unless they have no
+// tasks to report on or it was already sent for this run. Days, and the times tasks are due,
+// are those of the user's time zone.
+func (s *DigestService) sendDigest(ctx context.Context, user *models.User, runAt time.Time) (bool, error) {
+	now := time.Now().In(user.Location())
+	open := bson.M{"$in": []string{string(models.StatusTodo), string(models.StatusInProgress)}}
+
+	completed, err := s.tasks.Count(ctx, bson.M{
+		"user_id":      user.ID,
+		"status":       models.StatusDone,
+		"completed_at": bson.M{"$gte": runAt.AddDate(0, 0, -7)},
+	})
+	if err != nil {
+		return false, err
+	}
+	openCount, err := s.tasks.Count(ctx, bson.M{"user_id": user.ID, "status": open})
+	if err != nil {
+		return false, err
+	}
+	if completed == 0 && openCount == 0 {
+		return false, nil
+	}
+	overdue, err := s.tasks.Count(ctx, bson.M{"user_id": user.ID, "status": open, "due_date": bson.M{"$lt": now}})
+	if err != nil {
+		return false, err
+	}
+
+	q := query.New(bson.M{
+		"user_id":  user.ID,
+		"status":   open,
+		"due_date": bson.M{"$gte": now, "$lt": startOfDay(now, 8)},
+	}, 1, maxDigestUpcoming)
+	q.Sort = bson.D{{Key: "due_date", Value: 1}}
+	upcomingTasks, err := s.tasks.List(ctx, q)
+	if err != nil {
+		return false, err
+	}
+	upcoming := make([]map[string]interface{}, 0, len(upcomingTasks))
+	for _, task := range upcomingTasks {
+		upcoming = append(upcoming, map[string]interface{}{
+			"Title":   task.Title,
+			"DueDate": task.DueDate.In(now.Location()).Format("Mon, Jan 2 15:04 MST"),
+		})
+	}
+
+	emailData := map[string]interface{}{
+		"FirstName":      user.FirstName,
+		"CompletedCount": completed,
+		"OpenCount":      openCount,
+		"OverdueCount":   overdue,
+		"Upcoming":       upcoming,
+		"DashboardLink":  "http://localhost:3000/dashboard", // Frontend dashboard URL
+		"Year":           now.Year(),
+	}
+	return s.notifications.Notify(ctx, &Notice{
+		Event:    models.EventWeeklyDigest,
+		User:     user,
+		Title:    "Your weekly digest",
+		Body:     fmt.Sprintf("%d tasks completed this week, %d open, %d overdue.", completed, openCount, overdue),
+		Link:     "http://localhost:3000/dashboard",
+		Template: weeklyDigestTemplate,
+		Subject:  weeklyDigestSubject,
+		Data:     emailData,
+		Key:      digestKey(runAt, user.ID),
+	})
+}
+
+// digestKey identifies the digest of one user for one run
+func digestKey(runAt time.Time, userID primitive.ObjectID) string {
+	return fmt.Sprintf("%s:%s:%s", jobs.TypeWeeklyDigest, runAt.Format(time.RFC3339), userID.Hex())
+}
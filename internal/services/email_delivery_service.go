@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+// EmailDeliveryService stores and queries the log of email send attempts
+type EmailDeliveryService struct {
+	deliveryCollection repository.Collection
+}
+
+// NewEmailDeliveryService creates a new EmailDeliveryService
+func NewEmailDeliveryService(db repository.Documents) *EmailDeliveryService {
+	return &EmailDeliveryService{
+		deliveryCollection: db.Collection("email_deliveries"),
+	}
+}
+
+// RecordDelivery inserts a delivery attempt. It makes the service a jobs.DeliveryRecorder.
+func (s *EmailDeliveryService) RecordDelivery(ctx context.Context, delivery *models.EmailDelivery) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if delivery.CreatedAt.IsZero() {
+		delivery.CreatedAt = time.Now()
+	}
+	_, err := s.deliveryCollection.InsertOne(ctx, delivery)
+	return err
+}
+
+// ListDeliveries retrieves delivery attempts matching the query
+func (s *EmailDeliveryService) ListDeliveries(ctx context.Context, q *query.Query) (*models.EmailDeliveryListResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cursor, err := s.deliveryCollection.Find(ctx, q.Filter, q.FindOptions())
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	deliveries := []models.EmailDelivery{}
+	if err = cursor.All(ctx, &deliveries); err != nil {
+		return nil, err
+	}
+
+	totalCount, err := s.deliveryCollection.CountDocuments(ctx, q.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.EmailDeliveryListResponse{
+		Deliveries: deliveries,
+		Pagination: models.NewPagination(totalCount, q.Page, q.Limit),
+	}, nil
+}
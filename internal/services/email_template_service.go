@@ -0,0 +1,495 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"sort"
+	texttemplate "text/template"
+	"text/template/parse"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/OsGift/taskflow-api/internal/logging"
+	"github.com/OsGift/taskflow-api/internal/mailer"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/repository"
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+// emailTemplateSpec describes a template the application sends: the subject it is sent with
+// and sample values for every variable it is given, used for validation and previews
+type emailTemplateSpec struct {
+	subject string
+	sample  map[string]interface{}
+}
+
+// emailTemplateCatalog lists every email the application sends, keyed by template name.
+// Keep it in step with the data passed to EnqueueEmail.
+var emailTemplateCatalog = map[string]emailTemplateSpec{
+	"welcome": {
+		subject: "Welcome to TaskFlow! Please verify your email.",
+		sample: map[string]interface{}{
+			"FirstName":        "Ada",
+			"VerificationLink": "https://app.example.com/verify-email?token=sample-token",
+			"Year":             time.Now().Year(),
+		},
+	},
+	"admin_temp_password": {
+		subject: "Your TaskFlow Admin Account Details",
+		sample: map[string]interface{}{
+			"FirstName":         "Ada",
+			"TemporaryPassword": "Temp-Passw0rd",
+			"LoginLink":         "https://app.example.com/login",
+			"Year":              time.Now().Year(),
+		},
+	},
+	"forgot_password": {
+		subject: "Password Reset Request for TaskFlow",
+		sample: map[string]interface{}{
+			"ResetLink": "https://app.example.com/reset-password?token=sample-token",
+			"Year":      time.Now().Year(),
+		},
+	},
+	"upload_quarantined": {
+		subject: "TaskFlow: Upload Quarantined",
+		sample: map[string]interface{}{
+			"FirstName":      "Ada",
+			"Filename":       "invoice.pdf",
+			"Threat":         "Eicar-Signature",
+			"UploaderEmail":  "user@example.com",
+			"UploaderID":     "64b7f0c2e4b0a1a2b3c4d5e6",
+			"QuarantinePath": "quarantine/64b7f0c2e4b0a1a2b3c4d5e6_invoice.pdf",
+			"Year":           time.Now().Year(),
+		},
+	},
+	"weekly_digest": {
+		subject: weeklyDigestSubject,
+		sample: map[string]interface{}{
+			"FirstName":      "Ada",
+			"CompletedCount": 7,
+			"OpenCount":      4,
+			"OverdueCount":   1,
+			"Upcoming": []map[string]interface{}{
+				{"Title": "Prepare quarterly report", "DueDate": "Wed, Oct 21 17:00 UTC"},
+				{"Title": "Review pull requests", "DueDate": "Fri, Oct 23 12:00 UTC"},
+			},
+			"DashboardLink": "http://localhost:3000/dashboard",
+			"Year":          time.Now().Year(),
+		},
+	},
+	"daily_summary": {
+		subject: dailySummarySubject,
+		sample: map[string]interface{}{
+			"FirstName":     "Ada",
+			"Date":          "Friday, October 16",
+			"DueTodayCount": 1,
+			"DueToday": []map[string]interface{}{
+				{"Title": "Send the invoice", "DueDate": "Fri, Oct 16 17:00 UTC"},
+			},
+			"InProgressCount": 2,
+			"InProgress": []map[string]interface{}{
+				{"Title": "Prepare quarterly report", "DueDate": "Wed, Oct 21 17:00 UTC"},
+				{"Title": "Review pull requests"},
+			},
+			"NewCount": 1,
+			"New": []map[string]interface{}{
+				{"Title": "Book the team offsite"},
+			},
+			"DashboardLink": "http://localhost:3000/dashboard",
+			"Year":          time.Now().Year(),
+		},
+	},
+	"dashboard_report": {
+		subject: dashboardReportSubject,
+		sample: map[string]interface{}{
+			"Frequency":    "weekly",
+			"StartDate":    "Mon, Oct 12 08:00 UTC",
+			"EndDate":      "Mon, Oct 19 08:00 UTC",
+			"TotalUsers":   42,
+			"NewUsers":     3,
+			"TotalTasks":   318,
+			"NewTasks":     27,
+			"OverdueCount": 5,
+			"StaleCount":   2,
+			"TasksByStatus": []map[string]interface{}{
+				{"Status": "todo", "Count": 12},
+				{"Status": "in_progress", "Count": 9},
+				{"Status": "done", "Count": 6},
+			},
+			"UsersByRole": []map[string]interface{}{
+				{"Role": "Admin", "Count": 2},
+				{"Role": "Manager", "Count": 5},
+				{"Role": "User", "Count": 35},
+			},
+			"DashboardLink": "http://localhost:3000/dashboard",
+			"Year":          time.Now().Year(),
+		},
+	},
+	"stale_tasks": {
+		subject: staleTasksSubject,
+		sample: map[string]interface{}{
+			"FirstName": "Ada",
+			"Count":     2,
+			"Days":      23,
+			"Action":    "archived",
+			"StaleOn":   "Mon, Oct 26",
+			"Tasks": []map[string]interface{}{
+				{"Title": "Draft onboarding checklist", "UpdatedAt": "Tue, Sep 22 2026"},
+				{"Title": "Clean up old feature flags", "UpdatedAt": "Wed, Sep 23 2026"},
+			},
+			"MoreCount": 0,
+			"TasksLink": "http://localhost:3000/tasks",
+			"Year":      time.Now().Year(),
+		},
+	},
+	"sla_breached": {
+		subject: slaBreachedSubject,
+		sample: map[string]interface{}{
+			"FirstName":  "Ada",
+			"TaskTitle":  "Fix checkout timeout",
+			"OwnerEmail": "grace@example.com",
+			"RuleName":   "High priority triage",
+			"Status":     "todo",
+			"MaxHours":   24,
+			"EnteredAt":  "Wed, Oct 14 09:30 UTC",
+			"TaskLink":   "http://localhost:3000/tasks/64b7f0c2e4b0a1a2b3c4d5e6",
+			"Year":       time.Now().Year(),
+		},
+	},
+	"job_failed": {
+		subject: "TaskFlow: background job failed",
+		sample: map[string]interface{}{
+			"JobID":     "64b7f0c2e4b0a1a2b3c4d5e6",
+			"JobType":   "email:send",
+			"Attempts":  5,
+			"Error":     "connection refused",
+			"Email":     "forgot_password",
+			"Recipient": "user@example.com",
+			"Year":      time.Now().Year(),
+		},
+	},
+}
+
+// EmailTemplateService stores customised email templates, which replace the built-in ones
+type EmailTemplateService struct {
+	templateCollection repository.Collection
+}
+
+// NewEmailTemplateService creates a new EmailTemplateService
+func NewEmailTemplateService(db repository.Documents) *EmailTemplateService {
+	return &EmailTemplateService{
+		templateCollection: db.Collection("email_templates"),
+	}
+}
+
+// ListTemplates returns every email template, customised or built-in, ordered by name
+func (s *EmailTemplateService) ListTemplates(ctx context.Context) (*models.EmailTemplateListResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := s.templateCollection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var stored []models.EmailTemplate
+	if err = cursor.All(ctx, &stored); err != nil {
+		return nil, err
+	}
+	customized := make(map[string]models.EmailTemplate, len(stored))
+	for _, t := range stored {
+		customized[t.Name] = t
+	}
+
+	names := make([]string, 0, len(emailTemplateCatalog))
+	for name := range emailTemplateCatalog {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	templates := make([]models.EmailTemplate, 0, len(names))
+	for _, name := range names {
+		if t, ok := customized[name]; ok {
+			templates = append(templates, *withVariables(&t, true))
+		} else {
+			templates = append(templates, *builtinTemplate(name))
+		}
+	}
+	return &models.EmailTemplateListResponse{Templates: templates}, nil
+}
+
+// GetTemplate returns the template currently used for name
+func (s *EmailTemplateService) GetTemplate(ctx context.Context, name string) (*models.EmailTemplate, error) {
+	if _, ok := emailTemplateCatalog[name]; !ok {
+		return nil, ErrEmailTemplateNotFound
+	}
+
+	t, err := s.findTemplate(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return builtinTemplate(name), nil
+	}
+	return withVariables(t, true), nil
+}
+
+// UpdateTemplate validates and stores a customised version of template name
+func (s *EmailTemplateService) UpdateTemplate(ctx context.Context, name string, req *models.UpdateEmailTemplateRequest, actorID primitive.ObjectID) (*models.EmailTemplate, error) {
+	spec, ok := emailTemplateCatalog[name]
+	if !ok {
+		return nil, ErrEmailTemplateNotFound
+	}
+	if err := validateEmailTemplate(name, spec, req.Subject, req.Body); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"subject":    req.Subject,
+			"body":       req.Body,
+			"updated_by": actorID,
+			"updated_at": now,
+		},
+		"$setOnInsert": bson.M{"name": name, "created_at": now},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var t models.EmailTemplate
+	if err := s.templateCollection.FindOneAndUpdate(ctx, bson.M{"name": name}, update, opts).Decode(&t); err != nil {
+		return nil, err
+	}
+	return withVariables(&t, true), nil
+}
+
+// ResetTemplate deletes the customised version of template name, restoring the built-in one
+func (s *EmailTemplateService) ResetTemplate(ctx context.Context, name string) error {
+	if _, ok := emailTemplateCatalog[name]; !ok {
+		return ErrEmailTemplateNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := s.templateCollection.DeleteOne(ctx, bson.M{"name": name})
+	return err
+}
+
+// PreviewTemplate renders template name with sample data. Unsaved changes in req are
+// validated and rendered instead of the stored template; otherwise the template is rendered
+// as it would be sent to a user with req.Locale.
+func (s *EmailTemplateService) PreviewTemplate(ctx context.Context, name string, req *models.PreviewEmailTemplateRequest) (*models.EmailTemplatePreview, error) {
+	spec, ok := emailTemplateCatalog[name]
+	if !ok {
+		return nil, ErrEmailTemplateNotFound
+	}
+
+	data := spec.sampleData(req.Data)
+
+	var subject, html string
+	var err error
+	if req.Body != "" {
+		if err := validateEmailTemplate(name, spec, req.Subject, req.Body); err != nil {
+			return nil, err
+		}
+		if req.Subject == "" {
+			req.Subject = spec.subject
+		}
+		subject, html, err = utils.RenderEmailTemplate(name, req.Subject, req.Body, data)
+	} else {
+		subject, html, err = utils.RenderEmail(ctx, name, req.Locale, spec.subject, data)
+	}
+	if err != nil {
+		return nil, ErrInvalidEmailTemplate.WithDetails(map[string]interface{}{"error": err.Error()})
+	}
+	return &models.EmailTemplatePreview{Subject: subject, HTML: html, Text: mailer.PlainText(html)}, nil
+}
+
+// SendTestEmail sends template name, rendered with sample data as a user with req.Locale would
+// receive it, to req.To. It bypasses the job queue, so provider errors are reported to the caller.
+func (s *EmailTemplateService) SendTestEmail(ctx context.Context, name string, req *models.SendTestEmailRequest) (*models.TestEmailResult, error) {
+	spec, ok := emailTemplateCatalog[name]
+	if !ok {
+		return nil, ErrEmailTemplateNotFound
+	}
+
+	subject, html, err := utils.RenderEmail(ctx, name, req.Locale, spec.subject, spec.sampleData(req.Data))
+	if err != nil {
+		return nil, err
+	}
+	subject = "[Test] " + subject
+
+	if err := utils.DeliverEmail(ctx, req.To, subject, html); err != nil {
+		logging.Warnf("Test email %q to %s failed: %v", name, req.To, err)
+		return nil, ErrTestEmailFailed.WithDetails(map[string]interface{}{"error": err.Error()})
+	}
+	return &models.TestEmailResult{Template: name, To: req.To, Subject: subject}, nil
+}
+
+// EmailTemplate returns the customised subject and body of name, if any. It makes the
+// service a utils.TemplateSource, so sent emails use the customised templates.
+func (s *EmailTemplateService) EmailTemplate(ctx context.Context, name string) (string, string, bool, error) {
+	t, err := s.findTemplate(ctx, name)
+	if err != nil || t == nil {
+		return "", "", false, err
+	}
+	return t.Subject, t.Body, true, nil
+}
+
+// findTemplate returns the stored template name, or nil when it hasn't been customised
+func (s *EmailTemplateService) findTemplate(ctx context.Context, name string) (*models.EmailTemplate, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var t models.EmailTemplate
+	err := s.templateCollection.FindOne(ctx, bson.M{"name": name}).Decode(&t)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// builtinTemplate describes the built-in template name
+func builtinTemplate(name string) *models.EmailTemplate {
+	body, _ := utils.BuiltinEmailTemplate(name)
+	return withVariables(&models.EmailTemplate{
+		Name:    name,
+		Subject: emailTemplateCatalog[name].subject,
+		Body:    body,
+	}, false)
+}
+
+// withVariables fills in the fields of t that aren't stored
+func withVariables(t *models.EmailTemplate, customized bool) *models.EmailTemplate {
+	t.Variables = emailTemplateCatalog[t.Name].variables()
+	t.Translations = utils.TranslatedEmailLocales(t.Name)
+	t.Customized = customized
+	return t
+}
+
+// sampleData returns the template's sample data with overrides applied
+func (spec emailTemplateSpec) sampleData(overrides map[string]interface{}) map[string]interface{} {
+	data := make(map[string]interface{}, len(spec.sample)+len(overrides))
+	for key, value := range spec.sample {
+		data[key] = value
+	}
+	for key, value := range overrides {
+		data[key] = value
+	}
+	return data
+}
+
+// variables returns the names of the template's variables, sorted
+func (spec emailTemplateSpec) variables() []string {
+	names := make([]string, 0, len(spec.sample))
+	for name := range spec.sample {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateEmailTemplate checks that subject and body parse, only use the template's
+// variables and render with its sample data
+func validateEmailTemplate(name string, spec emailTemplateSpec, subject, body string) error {
+	invalid := func(err error) error {
+		return ErrInvalidEmailTemplate.WithDetails(map[string]interface{}{"error": err.Error()})
+	}
+
+	subjectTemplate, err := texttemplate.New(name + "_subject").Parse(subject)
+	if err != nil {
+		return invalid(err)
+	}
+	bodyTemplate, err := template.New(name).Parse(body)
+	if err != nil {
+		return invalid(err)
+	}
+
+	used := map[string]bool{}
+	for _, t := range subjectTemplate.Templates() {
+		if t.Tree != nil {
+			collectTemplateFields(t.Tree.Root, used)
+		}
+	}
+	for _, t := range bodyTemplate.Templates() {
+		if t.Tree != nil {
+			collectTemplateFields(t.Tree.Root, used)
+		}
+	}
+	var unknown []string
+	for field := range used {
+		if _, ok := spec.sample[field]; !ok {
+			unknown = append(unknown, field)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return ErrInvalidEmailTemplate.WithDetails(map[string]interface{}{
+			"unknown_variables": unknown,
+			"allowed_variables": spec.variables(),
+		})
+	}
+
+	if _, _, err := utils.RenderEmailTemplate(name, subject, body, spec.sample); err != nil {
+		return invalid(err)
+	}
+	return nil
+}
+
+// collectTemplateFields records the data fields referenced by node, e.g. "FirstName" for
+// {{.FirstName}}. The bodies of range and with blocks are skipped, since dot is rebound there.
+func collectTemplateFields(node parse.Node, fields map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectTemplateFields(child, fields)
+		}
+	case *parse.ActionNode:
+		collectTemplateFields(n.Pipe, fields)
+	case *parse.IfNode:
+		collectTemplateFields(n.Pipe, fields)
+		collectTemplateFields(n.List, fields)
+		collectTemplateFields(n.ElseList, fields)
+	case *parse.RangeNode:
+		collectTemplateFields(n.Pipe, fields)
+		collectTemplateFields(n.ElseList, fields)
+	case *parse.WithNode:
+		collectTemplateFields(n.Pipe, fields)
+		collectTemplateFields(n.ElseList, fields)
+	case *parse.TemplateNode:
+		collectTemplateFields(n.Pipe, fields)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			for _, arg := range cmd.Args {
+				collectTemplateFields(arg, fields)
+			}
+		}
+	case *parse.ChainNode:
+		collectTemplateFields(n.Node, fields)
+	case *parse.FieldNode:
+		fields[n.Ident[0]] = true
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			fields[n.Ident[1]] = true
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package services
+
+import "github.com/OsGift/taskflow-api/internal/apperror"
+
+// Errors returned by the services. Handlers and the gRPC server translate them by code,
+// so messages can be reworded without breaking clients.
+var (
+	ErrInvalidTaskID   = apperror.New(apperror.CodeInvalidArgument, "invalid task ID format")
+	ErrTaskNotFound    = apperror.New(apperror.CodeNotFound, "task not found")
+	ErrTaskNotModified = apperror.New(apperror.CodeNotFound, "task not found or no changes made")
+
+	ErrInvalidSourceTaskID = apperror.New(apperror.CodeInvalidArgument, "invalid source_id task ID format")
+	ErrSourceTaskNotFound  = apperror.New(apperror.CodeInvalidArgument, "source_id task not found")
+	ErrMergeTaskIntoSelf   = apperror.New(apperror.CodeInvalidArgument, "cannot merge a task into itself")
+	ErrTaskAlreadyMerged   = apperror.New(apperror.CodeFailedPrecondition, "the task was already merged into another task")
+	ErrTooManyPins         = apperror.New(apperror.CodeFailedPrecondition, "too many pinned tasks; unpin some first")
+
+	ErrInvalidUserID          = apperror.New(apperror.CodeInvalidArgument, "invalid user ID format")
+	ErrUserNotFound           = apperror.New(apperror.CodeNotFound, "user not found")
+	ErrInvalidRoleID          = apperror.New(apperror.CodeInvalidArgument, "invalid role ID format")
+	ErrRoleNotFound           = apperror.New(apperror.CodeNotFound, "role not found")
+	ErrNewRoleNotFound        = apperror.New(apperror.CodeInvalidArgument, "new role not found")
+	ErrRoleNotChanged         = apperror.New(apperror.CodeInvalidArgument, "user not found or role not changed")
+	ErrProfileNotChanged      = apperror.New(apperror.CodeNotFound, "user not found or no changes made to profile")
+	ErrEmailAlreadyVerified   = apperror.New(apperror.CodeFailedPrecondition, "user not found or email already verified")
+	ErrInvalidReassignUserID  = apperror.New(apperror.CodeInvalidArgument, "invalid reassign_to user ID format")
+	ErrReassignUserNotFound   = apperror.New(apperror.CodeInvalidArgument, "reassign_to user not found")
+	ErrReassignToDeletedUser  = apperror.New(apperror.CodeInvalidArgument, "cannot reassign tasks to the user being deleted")
+	ErrEmailAlreadyRegistered = apperror.New(apperror.CodeAlreadyExists, "email already registered")
+	ErrReservedPersonalData   = apperror.New(apperror.CodeInvalidArgument, `phone and address can't start with "enc:"`)
+	ErrAccountDisabled        = apperror.New(apperror.CodePermissionDenied, "this account has been disabled")
+	ErrInvalidDuplicateUserID = apperror.New(apperror.CodeInvalidArgument, "invalid duplicate_id user ID format")
+	ErrDuplicateUserNotFound  = apperror.New(apperror.CodeInvalidArgument, "duplicate_id user not found")
+	ErrMergeIntoSelf          = apperror.New(apperror.CodeInvalidArgument, "cannot merge a user into itself")
+	ErrMergeServiceAccount    = apperror.New(apperror.CodeInvalidArgument, "service accounts can't be merged")
+	ErrMergeDisabledUser      = apperror.New(apperror.CodeFailedPrecondition, "both users must be enabled; the duplicate may already have been merged into another user")
+
+	ErrInvalidCredentials           = apperror.New(apperror.CodeUnauthenticated, "invalid credentials")
+	ErrInvalidToken                 = apperror.New(apperror.CodeUnauthenticated, "invalid token")
+	ErrTokenAccountInactive         = apperror.New(apperror.CodeUnauthenticated, "the account this token was issued for no longer exists or has been disabled")
+	ErrInvalidRefreshToken          = apperror.New(apperror.CodeUnauthenticated, "invalid or expired refresh token; log in again")
+	ErrInvalidResetToken            = apperror.New(apperror.CodeInvalidArgument, "invalid or expired password reset token")
+	ErrInvalidVerificationToken     = apperror.New(apperror.CodeInvalidArgument, "invalid or expired email verification token")
+	ErrPasswordChangeNotRequired    = apperror.New(apperror.CodeFailedPrecondition, "password change not required for this account")
+	ErrInvalidOldPassword           = apperror.New(apperror.CodeInvalidArgument, "invalid old password")
+	ErrIdempotencyRecordDisappeared = apperror.New(apperror.CodeConflict, "idempotency record disappeared, retry the request")
+
+	ErrInvalidUploadID         = apperror.New(apperror.CodeInvalidArgument, "invalid upload ID")
+	ErrUploadNotFound          = apperror.New(apperror.CodeNotFound, "upload not found")
+	ErrInvalidUploadLink       = apperror.New(apperror.CodeInvalidArgument, "invalid resource_id format")
+	ErrStorageQuotaExceeded    = apperror.New(apperror.CodePayloadTooLarge, "storage quota exceeded")
+	ErrUploadInfected          = apperror.New(apperror.CodeUnprocessableEntity, "file was rejected by the virus scanner")
+	ErrVirusScanUnavailable    = apperror.New(apperror.CodeUnavailable, "virus scanning is unavailable, try again later")
+	ErrDirectUploadUnsupported = apperror.New(apperror.CodeFailedPrecondition, "direct uploads are not supported by the configured storage backend")
+	ErrUploadNotOwned          = apperror.New(apperror.CodePermissionDenied, "upload does not belong to the current user")
+	ErrInvalidUploadSignature  = apperror.New(apperror.CodeInvalidArgument, "invalid upload signature")
+	ErrDirectUploadExpired     = apperror.New(apperror.CodeFailedPrecondition, "direct upload has expired, request a new signature")
+
+	ErrInvalidDashboardPeriod = apperror.New(apperror.CodeInvalidArgument, "start_date and end_date are required for custom period")
+
+	ErrEmailTemplateNotFound = apperror.New(apperror.CodeNotFound, "email template not found")
+	ErrInvalidEmailTemplate  = apperror.New(apperror.CodeInvalidArgument, "invalid email template")
+	ErrTestEmailFailed       = apperror.New(apperror.CodeUnavailable, "the email provider did not accept the test email")
+
+	ErrInvalidReportScheduleID = apperror.New(apperror.CodeInvalidArgument, "invalid report schedule ID format")
+	ErrReportScheduleNotFound  = apperror.New(apperror.CodeNotFound, "report schedule not found")
+
+	ErrInvalidSLARuleID = apperror.New(apperror.CodeInvalidArgument, "invalid SLA rule ID format")
+	ErrSLARuleNotFound  = apperror.New(apperror.CodeNotFound, "SLA rule not found")
+
+	ErrInvalidAnnouncementID   = apperror.New(apperror.CodeInvalidArgument, "invalid announcement ID format")
+	ErrAnnouncementNotFound    = apperror.New(apperror.CodeNotFound, "announcement not found")
+	ErrInvalidAnnouncementTime = apperror.New(apperror.CodeInvalidArgument, "ends_at must be after starts_at")
+
+	ErrCalendarNotConfigured = apperror.New(apperror.CodeFailedPrecondition, "Google Calendar sync is not configured on this server")
+	ErrCalendarNotConnected  = apperror.New(apperror.CodeNotFound, "no Google Calendar is connected")
+	ErrInvalidCalendarState  = apperror.New(apperror.CodeInvalidArgument, "invalid or expired calendar connection request")
+	ErrCalendarNoRefresh     = apperror.New(apperror.CodeFailedPrecondition, "Google did not grant offline access; try connecting again")
+	ErrCalendarCodeRejected  = apperror.New(apperror.CodeInvalidArgument, "Google rejected the authorization code; try connecting again")
+
+	ErrServiceAccountNotFound = apperror.New(apperror.CodeNotFound, "service account not found")
+	ErrInvalidAPIKeyID        = apperror.New(apperror.CodeInvalidArgument, "invalid API key ID format")
+	ErrAPIKeyNotFound         = apperror.New(apperror.CodeNotFound, "API key not found")
+	ErrInvalidAPIKey          = apperror.New(apperror.CodeUnauthenticated, "invalid, expired or revoked API key")
+	ErrAPIKeyRateLimited      = apperror.New(apperror.CodeRateLimited, "API key rate limit exceeded, retry after the time in the Retry-After header")
+	ErrNotAPIKeyRequest       = apperror.New(apperror.CodeFailedPrecondition, "this endpoint reports on the API key used to call it; call it with an API key")
+	ErrInvalidUsageDays       = apperror.New(apperror.CodeInvalidArgument, "days must be between 1 and 90")
+
+	ErrIPBanned        = apperror.New(apperror.CodeRateLimited, "too many failed authentication attempts from this address, retry after the time in the Retry-After header")
+	ErrIPBlockNotFound = apperror.New(apperror.CodeNotFound, "no failed authentication attempts are tracked for this address")
+
+	ErrInvalidSearchQuery  = apperror.New(apperror.CodeInvalidArgument, "q must be between 2 and 100 characters")
+	ErrInvalidSearchType   = apperror.New(apperror.CodeInvalidArgument, "types must be a comma-separated list of tasks and users")
+	ErrInvalidSuggestQuery = apperror.New(apperror.CodeInvalidArgument, "q must be between 1 and 100 characters")
+
+	ErrInvalidNotificationID         = apperror.New(apperror.CodeInvalidArgument, "invalid notification ID format")
+	ErrNotificationNotFound          = apperror.New(apperror.CodeNotFound, "notification not found")
+	ErrUnknownNotificationEvent      = apperror.New(apperror.CodeInvalidArgument, "unknown notification event")
+	ErrNotificationChannelNotAllowed = apperror.New(apperror.CodeInvalidArgument, "the event can't be delivered on this channel")
+	ErrInvalidWebhookURL             = apperror.New(apperror.CodeInvalidArgument, "webhook_url must be an http or https URL")
+
+	ErrInvalidCommentID        = apperror.New(apperror.CodeInvalidArgument, "invalid comment ID format")
+	ErrCommentNotFound         = apperror.New(apperror.CodeNotFound, "comment not found")
+	ErrNotCommentAuthor        = apperror.New(apperror.CodePermissionDenied, "only the author of a comment can edit it")
+	ErrCommentEditWindowClosed = apperror.New(apperror.CodeFailedPrecondition, "the comment can no longer be edited")
+	ErrCommentEditConflict     = apperror.New(apperror.CodeConflict, "the comment was edited at the same time; reload it and try again")
+
+	ErrInvalidProjectID      = apperror.New(apperror.CodeInvalidArgument, "invalid project ID format")
+	ErrProjectNotFound       = apperror.New(apperror.CodeNotFound, "project not found")
+	ErrProjectArchived       = apperror.New(apperror.CodeFailedPrecondition, "the project is archived and accepts no new tasks")
+	ErrProjectMemberExists   = apperror.New(apperror.CodeAlreadyExists, "the user is already a member of the project")
+	ErrProjectMemberNotFound = apperror.New(apperror.CodeNotFound, "the user is not a member of the project")
+	ErrProjectOwnerMember    = apperror.New(apperror.CodeInvalidArgument, "the project owner is always a manager and can't be added, changed or removed as a member")
+	ErrUserOwnsProjects      = apperror.New(apperror.CodeFailedPrecondition, "the user owns projects; give a user to reassign their tasks and projects to")
+	ErrInvalidBurndownRange  = apperror.New(apperror.CodeInvalidArgument, "give a sprint_id, or from and to dates at most 366 days apart")
+
+	ErrInvalidMilestoneID       = apperror.New(apperror.CodeInvalidArgument, "invalid milestone ID format")
+	ErrMilestoneNotFound        = apperror.New(apperror.CodeNotFound, "milestone not found")
+	ErrMilestoneProjectMismatch = apperror.New(apperror.CodeInvalidArgument, "a task can only be attached to a milestone of its own project")
+
+	ErrInvalidSprintID       = apperror.New(apperror.CodeInvalidArgument, "invalid sprint ID format")
+	ErrSprintNotFound        = apperror.New(apperror.CodeNotFound, "sprint not found")
+	ErrSprintClosed          = apperror.New(apperror.CodeFailedPrecondition, "the sprint is closed")
+	ErrInvalidSprintDates    = apperror.New(apperror.CodeInvalidArgument, "end_date must be after start_date")
+	ErrSprintProjectMismatch = apperror.New(apperror.CodeInvalidArgument, "a task can only be added to a sprint of its own project")
+	ErrTaskNotInSprint       = apperror.New(apperror.CodeNotFound, "the task is not in the sprint")
+	ErrInvalidNextSprint     = apperror.New(apperror.CodeInvalidArgument, "unfinished tasks can only roll over to another open sprint of the same project")
+
+	ErrUnknownImportSource = apperror.New(apperror.CodeNotFound, "unknown import source")
+	ErrImportTooLarge      = apperror.New(apperror.CodePayloadTooLarge, "the export is too large to import at once")
+)
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+// exportFlushEvery is how many records are written between flushes of a streamed export
+const exportFlushEvery = 500
+
+// taskBackupBatch is how many tasks a task backup reads at a time
+const taskBackupBatch = 100
+
+// ExportService writes complete copies of the data for backups and migrations, users'
+// backups of their own tasks and printable copies of single tasks
+type ExportService struct {
+	store          *repository.Store
+	userService    *UserService
+	commentService *CommentService
+	uploadService  *UploadService
+	auditService   *AuditService
+}
+
+// NewExportService creates a new ExportService
+func NewExportService(store *repository.Store, users *UserService, cs *CommentService, us *UploadService, as *AuditService) *ExportService {
+	return &ExportService{
+		store:          store,
+		userService:    users,
+		commentService: cs,
+		uploadService:  us,
+		auditService:   as,
+	}
+}
+
+// Export writes every role, user, task and comment to w as newline-delimited JSON records,
+// reading them from the database as it goes. flush, when not nil, is called every few hundred
+// records so the data reaches the client instead of piling up in buffers. Password hashes are
+// not exported, and personal data encrypted at rest is exported decrypted.
+func (s *ExportService) Export(ctx context.Context, w io.Writer, flush func()) error {
+	encoder := json.NewEncoder(w)
+	var written int
+	write := func(recordType string, data interface{}) error {
+		if err := encoder.Encode(models.ExportRecord{Type: recordType, Data: data}); err != nil {
+			return err
+		}
+		written++
+		if flush != nil && written%exportFlushEvery == 0 {
+			flush()
+		}
+		return nil
+	}
+
+	if err := write(models.ExportRecordHeader, models.ExportHeader{Version: models.ExportFormatVersion, ExportedAt: time.Now().UTC()}); err != nil {
+		return err
+	}
+
+	var summary models.ExportSummary
+	// Roles come first so an import can resolve the users' role IDs, users before their tasks and
+	// tasks before their comments
+	err := s.store.Roles.Each(ctx, func(role *models.Role) error {
+		summary.Roles++
+		return write(models.ExportRecordRole, role)
+	})
+	if err != nil {
+		return err
+	}
+	err = s.store.Users.Each(ctx, func(user *models.User) error {
+		summary.Users++
+		s.userService.decryptUser(user)
+		return write(models.ExportRecordUser, user)
+	})
+	if err != nil {
+		return err
+	}
+	err = s.store.Tasks.Each(ctx, func(task *models.Task) error {
+		summary.Tasks++
+		return write(models.ExportRecordTask, task)
+	})
+	if err != nil {
+		return err
+	}
+	err = s.commentService.Each(ctx, func(comment *models.Comment) error {
+		summary.Comments++
+		return write(models.ExportRecordComment, comment)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := write(models.ExportRecordEnd, summary); err != nil {
+		return err
+	}
+	if flush != nil {
+		flush()
+	}
+	return nil
+}
+
+// ExportUserTasks writes every task of a user, with its comments and attachments, to w as a
+// models.TaskBackup JSON document. Tasks are read and written a batch at a time and flush,
+// when not nil, is called after each batch; a backup that fails part-way is left unterminated.
+func (s *ExportService) ExportUserTasks(ctx context.Context, userID primitive.ObjectID, w io.Writer, flush func()) error {
+	exportedAt, err := json.Marshal(time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	// The document is written piece by piece, in the field order of models.TaskBackup
+	if _, err := fmt.Fprintf(w, `{"version":%d,"exported_at":%s,"user_id":"%s","tasks":[`,
+		models.TaskBackupFormatVersion, exportedAt, userID.Hex()); err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	var count int64
+	err = s.EachTaskBackupEntry(ctx, userID, func(entry *models.TaskBackupEntry) error {
+		if count > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+		count++
+		if flush != nil && count%taskBackupBatch == 0 {
+			flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, `],"task_count":%d}`+"\n", count)
+	return err
+}
+
+// EachTaskBackupEntry calls fn with every task of a user, in ID order, along with its comments
+// and attachments. Tasks are read a batch at a time, so backups of any size can be streamed.
+func (s *ExportService) EachTaskBackupEntry(ctx context.Context, userID primitive.ObjectID, fn func(*models.TaskBackupEntry) error) error {
+	for page := int64(1); ; page++ {
+		q := query.New(bson.M{"user_id": userID}, page, taskBackupBatch)
+		q.Sort = bson.D{{Key: "_id", Value: 1}}
+		tasks, err := s.store.Tasks.List(ctx, q)
+		if err != nil {
+			return err
+		}
+
+		for _, task := range tasks {
+			entry := models.TaskBackupEntry{Task: task}
+			if entry.Comments, err = s.commentService.TaskComments(ctx, task.ID); err != nil {
+				return err
+			}
+			if entry.Attachments, err = s.uploadService.TaskAttachments(ctx, task.ID); err != nil {
+				return err
+			}
+			if err := fn(&entry); err != nil {
+				return err
+			}
+		}
+		if int64(len(tasks)) < q.Limit {
+			return nil
+		}
+	}
+}
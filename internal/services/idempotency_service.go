@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+// IdempotencyService persists Idempotency-Key outcomes so duplicate requests can be replayed
+type IdempotencyService struct {
+	db             repository.Documents
+	keysCollection repository.Collection
+	ttl            time.Duration
+}
+
+// NewIdempotencyService creates a new IdempotencyService; records expire after ttl
+func NewIdempotencyService(db repository.Documents, ttl time.Duration) *IdempotencyService {
+	return &IdempotencyService{
+		db:             db,
+		keysCollection: db.Collection("idempotency_keys"),
+		ttl:            ttl,
+	}
+}
+
+// EnsureIndexes creates the unique key index and the TTL index used to expire old records
+func (s *IdempotencyService) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return s.db.EnsureIndexes(ctx, "idempotency_keys", []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "key", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(s.ttl.Seconds())),
+		},
+	})
+}
+
+// Begin reserves a key for a new request. If the key was already used, the existing
+// record is returned with existing set to true and nothing is written.
+func (s *IdempotencyService) Begin(ctx context.Context, key, requestHash string) (record *models.IdempotencyRecord, existing bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	record = &models.IdempotencyRecord{
+		Key:         key,
+		RequestHash: requestHash,
+		Status:      models.IdempotencyInProgress,
+		CreatedAt:   time.Now(),
+	}
+	_, err = s.keysCollection.InsertOne(ctx, record)
+	if err == nil {
+		return record, false, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return nil, false, err
+	}
+
+	var stored models.IdempotencyRecord
+	if err := s.keysCollection.FindOne(ctx, bson.M{"key": key}).Decode(&stored); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, false, ErrIdempotencyRecordDisappeared
+		}
+		return nil, false, err
+	}
+	return &stored, true, nil
+}
+
+// Complete stores the response produced for a reserved key
+func (s *IdempotencyService) Complete(ctx context.Context, key string, status int, contentType string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := s.keysCollection.UpdateOne(ctx, bson.M{"key": key}, bson.M{"$set": bson.M{
+		"status":          models.IdempotencyCompleted,
+		"response_status": status,
+		"content_type":    contentType,
+		"response_body":   body,
+	}})
+	return err
+}
+
+// Release removes a reserved key so the client can retry (used when the request failed server-side)
+func (s *IdempotencyService) Release(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := s.keysCollection.DeleteOne(ctx, bson.M{"key": key})
+	return err
+}
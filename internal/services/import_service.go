@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/OsGift/taskflow-api/internal/apperror"
+	"github.com/OsGift/taskflow-api/internal/importer"
+	"github.com/OsGift/taskflow-api/internal/models"
+)
+
+// importSources are the converters for each supported export format, by source name
+var importSources = map[string]func(io.Reader, time.Time) (*models.ImportPreview, error){
+	"trello":  importer.Trello,
+	"todoist": importer.Todoist,
+}
+
+// ImportService brings tasks over from other task managers' exports
+type ImportService struct {
+	taskService *TaskService
+}
+
+// NewImportService creates a new ImportService
+func NewImportService(ts *TaskService) *ImportService {
+	return &ImportService{
+		taskService: ts,
+	}
+}
+
+// Import converts the export read from r and, unless dryRun is set, creates its tasks for the
+// user. The preview lists every task and every skipped item either way.
+func (s *ImportService) Import(ctx context.Context, userID primitive.ObjectID, source string, r io.Reader, dryRun bool) (*models.ImportPreview, error) {
+	convert, ok := importSources[source]
+	if !ok {
+		return nil, ErrUnknownImportSource
+	}
+
+	preview, err := convert(r, time.Now().UTC())
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr), errors.Is(err, importer.ErrTooManyTasks):
+		return nil, ErrImportTooLarge.WithDetails(map[string]interface{}{"max_tasks": importer.MaxTasks})
+	case err != nil:
+		return nil, apperror.Wrap(apperror.CodeInvalidArgument, err.Error(), err)
+	}
+	preview.DryRun = dryRun
+	if dryRun || len(preview.Tasks) == 0 {
+		return preview, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	tasks := make([]*models.Task, len(preview.Tasks))
+	for i, imported := range preview.Tasks {
+		tasks[i] = &models.Task{
+			Title:       imported.Title,
+			Description: imported.Description,
+			Status:      imported.Status,
+			UserID:      userID,
+			DueDate:     imported.DueDate,
+			CompletedAt: imported.CompletedAt,
+			CreatedAt:   imported.CreatedAt,
+		}
+	}
+	if err := s.taskService.ImportTasks(ctx, tasks); err != nil {
+		return nil, err
+	}
+	preview.Imported = len(tasks)
+	return preview, nil
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/OsGift/taskflow-api/internal/cache"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+const (
+	// ipBanCheckTTL is how long each server caches whether an address is banned, so bans and
+	// cleared bans take up to this long to apply on the other servers
+	ipBanCheckTTL = 10 * time.Second
+	// maxIPBan caps how long repeated bans grow
+	maxIPBan = 24 * time.Hour
+	// ipReputationTTL is how long an address's failures and bans are remembered after its last
+	// failure or ban ends
+	ipReputationTTL = 7 * 24 * time.Hour
+)
+
+// IPBlockService counts failed authentication attempts per IP address and bans addresses
+// that make too many. Counts are kept in the "ip_blocks" collection, shared by every server,
+// where a TTL index forgets addresses that stopped failing.
+type IPBlockService struct {
+	blockCollection repository.Collection
+	bannedUntil     *cache.TTLCache[string, time.Time] // Zero for addresses that aren't banned
+
+	mu          sync.RWMutex // Guards the policy, which can be changed by reloading the configuration
+	maxFailures int          // Failures within window that ban an address; 0 disables blocking
+	window      time.Duration
+	banDuration time.Duration // Length of a first ban, doubled by every later one
+}
+
+// NewIPBlockService creates a new IPBlockService; an address failing maxFailures times within
+// window is banned for banDuration, and 0 maxFailures disables blocking
+func NewIPBlockService(db repository.Documents, maxFailures int, window, banDuration time.Duration) *IPBlockService {
+	return &IPBlockService{
+		blockCollection: db.Collection("ip_blocks"),
+		maxFailures:     maxFailures,
+		window:          window,
+		banDuration:     banDuration,
+		bannedUntil:     cache.NewTTLCache[string, time.Time](ipBanCheckTTL),
+	}
+}
+
+// SetPolicy changes how many failures within window ban an address and for how long, as
+// passed to NewIPBlockService. Bans already in force keep their length.
+func (s *IPBlockService) SetPolicy(maxFailures int, window, banDuration time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxFailures, s.window, s.banDuration = maxFailures, window, banDuration
+}
+
+// policy returns the current ban policy
+func (s *IPBlockService) policy() (maxFailures int, window, banDuration time.Duration) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.maxFailures, s.window, s.banDuration
+}
+
+// Enabled reports whether failed attempts are counted and addresses banned
+func (s *IPBlockService) Enabled() bool {
+	maxFailures, _, _ := s.policy()
+	return maxFailures > 0
+}
+
+// BannedUntil returns when the ban of ip ends, or the zero time when it isn't banned
+func (s *IPBlockService) BannedUntil(ctx context.Context, ip string) (time.Time, error) {
+	now := time.Now()
+	if until, ok := s.bannedUntil.Get(ip); ok {
+		if until.After(now) {
+			return until, nil
+		}
+		return time.Time{}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var block models.IPBlock
+	err := s.blockCollection.FindOne(ctx, bson.M{"_id": ip, "banned_until": bson.M{"$gt": now}},
+		options.FindOne().SetProjection(bson.M{"banned_until": 1})).Decode(&block)
+	if err == mongo.ErrNoDocuments {
+		s.bannedUntil.Set(ip, time.Time{})
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	s.bannedUntil.Set(ip, *block.BannedUntil)
+	return *block.BannedUntil, nil
+}
+
+// RecordFailure counts a failed authentication attempt from ip, banning it once it reaches
+// the limit. Each ban lasts twice as long as the address's previous one, up to a day.
+func (s *IPBlockService) RecordFailure(ctx context.Context, ip string) error {
+	maxFailures, window, banDuration := s.policy()
+	if maxFailures <= 0 || ip == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	block, err := s.countFailure(ctx, ip, now, window)
+	if err != nil {
+		return err
+	}
+	if block.Failures < maxFailures || (block.BannedUntil != nil && block.BannedUntil.After(now)) {
+		return nil
+	}
+
+	// The failures filter lets only one of several concurrent failures start the ban
+	until := now.Add(banLength(banDuration, block.Bans))
+	result, err := s.blockCollection.UpdateOne(ctx,
+		bson.M{"_id": ip, "failures": bson.M{"$gte": maxFailures}},
+		bson.M{
+			"$set": bson.M{"banned_until": until, "failures": 0, "window_start": now, "expires_at": until.Add(ipReputationTTL)},
+			"$inc": bson.M{"bans": 1},
+		})
+	if err != nil {
+		return err
+	}
+	if result.ModifiedCount > 0 {
+		s.bannedUntil.Set(ip, until)
+	}
+	return nil
+}
+
+// countFailure adds a failure at now to the count of ip, restarting the count when the window
+// of the previous failures has passed, and returns the updated record
+func (s *IPBlockService) countFailure(ctx context.Context, ip string, now time.Time, window time.Duration) (*models.IPBlock, error) {
+	cutoff := now.Add(-window)
+	after := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	for {
+		var block models.IPBlock
+		err := s.blockCollection.FindOneAndUpdate(ctx,
+			bson.M{"_id": ip, "window_start": bson.M{"$gte": cutoff}},
+			bson.M{
+				"$inc": bson.M{"failures": 1},
+				"$set": bson.M{"last_failure_at": now},
+				"$max": bson.M{"expires_at": now.Add(ipReputationTTL)},
+			}, after).Decode(&block)
+		if err != mongo.ErrNoDocuments {
+			return &block, err
+		}
+
+		// No count in the window: start one, creating the record for a new address. An upsert
+		// racing with another one fails on the _id and retries the count above.
+		err = s.blockCollection.FindOneAndUpdate(ctx,
+			bson.M{"_id": ip, "$or": bson.A{
+				bson.M{"window_start": bson.M{"$lt": cutoff}},
+				bson.M{"window_start": nil},
+			}},
+			bson.M{
+				"$set":         bson.M{"failures": 1, "window_start": now, "last_failure_at": now},
+				"$setOnInsert": bson.M{"bans": 0},
+				"$max":         bson.M{"expires_at": now.Add(ipReputationTTL)},
+			}, options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(&block)
+		if err == nil || !mongo.IsDuplicateKeyError(err) {
+			return &block, err
+		}
+	}
+}
+
+// banLength returns how long an address that was banned bans times before is banned for,
+// when a first ban lasts first
+func banLength(first time.Duration, bans int) time.Duration {
+	length := first
+	for i := 0; i < bans && length < maxIPBan; i++ {
+		length *= 2
+	}
+	return min(length, maxIPBan)
+}
+
+// ListBlocks retrieves the tracked addresses matching the query
+func (s *IPBlockService) ListBlocks(ctx context.Context, q *query.Query) (*models.IPBlockListResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cursor, err := s.blockCollection.Find(ctx, q.Filter, q.FindOptions())
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	blocks := []models.IPBlock{}
+	if err = cursor.All(ctx, &blocks); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i := range blocks {
+		blocks[i].Banned = blocks[i].BannedUntil != nil && blocks[i].BannedUntil.After(now)
+	}
+
+	totalCount, err := s.blockCollection.CountDocuments(ctx, q.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.IPBlockListResponse{
+		Blocks:     blocks,
+		Pagination: models.NewPagination(totalCount, q.Page, q.Limit),
+	}, nil
+}
+
+// ClearBlock lifts the ban of ip, if any, and forgets its failures and earlier bans
+func (s *IPBlockService) ClearBlock(ctx context.Context, ip string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := s.blockCollection.DeleteOne(ctx, bson.M{"_id": ip})
+	if err != nil {
+		return err
+	}
+	s.bannedUntil.Delete(ip)
+	if result.DeletedCount == 0 {
+		return ErrIPBlockNotFound
+	}
+	return nil
+}
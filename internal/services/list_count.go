@@ -0,0 +1,43 @@
+package services
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/OsGift/taskflow-api/internal/cache"
+)
+
+// estimatedCountLimit is where estimated counts of filtered listings stop counting
+const estimatedCountLimit = 10000
+
+// counter counts the records of a repository matching a filter, exactly or estimated
+type counter interface {
+	Count(ctx context.Context, filter primitive.M) (int64, error)
+	EstimateCount(ctx context.Context, filter primitive.M, limit int64) (int64, error)
+}
+
+// listCount returns the total count of a listing's records, cached under prefix until the
+// records are written. With estimate, the count comes from collection statistics or stops
+// at estimatedCountLimit; the second result reports whether it may be inexact.
+func listCount(ctx context.Context, c cache.Cache, prefix string, records counter, filter primitive.M, estimate bool) (int64, bool, error) {
+	if estimate {
+		prefix += "estimated:"
+	}
+	countKey := queryCacheKey(prefix, filter)
+	var count int64
+	if !cache.GetJSON(ctx, c, countKey, &count) {
+		var err error
+		if estimate {
+			count, err = records.EstimateCount(ctx, filter, estimatedCountLimit)
+		} else {
+			count, err = records.Count(ctx, filter)
+		}
+		if err != nil {
+			return 0, false, err
+		}
+		cache.SetJSON(ctx, c, countKey, count, countCacheTTL)
+	}
+	// A capped count below the cap counted every record
+	return count, estimate && (len(filter) == 0 || count >= estimatedCountLimit), nil
+}
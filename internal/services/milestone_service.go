@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+// MilestoneService stores the milestones of projects and computes their progress from the
+// tasks attached to them
+type MilestoneService struct {
+	milestoneCollection repository.Collection
+	tasks               repository.TaskRepository
+	taskService         *TaskService
+}
+
+// NewMilestoneService creates a new MilestoneService
+func NewMilestoneService(db repository.Documents, store *repository.Store, ts *TaskService) *MilestoneService {
+	return &MilestoneService{
+		milestoneCollection: db.Collection("milestones"),
+		tasks:               store.Tasks,
+		taskService:         ts,
+	}
+}
+
+// CreateMilestone creates a milestone in a project
+func (s *MilestoneService) CreateMilestone(ctx context.Context, projectID, createdBy primitive.ObjectID, req *models.CreateMilestoneRequest) (*models.Milestone, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	milestone := &models.Milestone{
+		ID:          primitive.NewObjectID(),
+		ProjectID:   projectID,
+		Title:       req.Title,
+		Description: req.Description,
+		DueDate:     req.DueDate,
+		CreatedBy:   createdBy,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if _, err := s.milestoneCollection.InsertOne(ctx, milestone); err != nil {
+		return nil, err
+	}
+	milestone.Progress = &models.MilestoneProgress{}
+	return milestone, nil
+}
+
+// ListMilestones retrieves the milestones of a project matching the query, with their progress
+func (s *MilestoneService) ListMilestones(ctx context.Context, projectID primitive.ObjectID, q *query.Query) (*models.MilestoneListResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	q.Filter["project_id"] = projectID
+	cursor, err := s.milestoneCollection.Find(ctx, q.Filter, q.FindOptions())
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	milestones := []models.Milestone{}
+	if err = cursor.All(ctx, &milestones); err != nil {
+		return nil, err
+	}
+	for i := range milestones {
+		if milestones[i].Progress, err = s.progress(ctx, milestones[i].ID); err != nil {
+			return nil, err
+		}
+	}
+
+	totalCount, err := s.milestoneCollection.CountDocuments(ctx, q.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.MilestoneListResponse{
+		Milestones: milestones,
+		Pagination: models.NewPagination(totalCount, q.Page, q.Limit),
+	}, nil
+}
+
+// GetMilestone retrieves a milestone by its ID, with its progress
+func (s *MilestoneService) GetMilestone(ctx context.Context, idHex string) (*models.Milestone, error) {
+	id, err := primitive.ObjectIDFromHex(idHex)
+	if err != nil {
+		return nil, ErrInvalidMilestoneID
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var milestone models.Milestone
+	err = s.milestoneCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&milestone)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrMilestoneNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if milestone.Progress, err = s.progress(ctx, milestone.ID); err != nil {
+		return nil, err
+	}
+	return &milestone, nil
+}
+
+// UpdateMilestone changes the title, description or due date of a milestone
+func (s *MilestoneService) UpdateMilestone(ctx context.Context, id primitive.ObjectID, req *models.UpdateMilestoneRequest) (*models.Milestone, error) {
+	fields := bson.M{"updated_at": time.Now()}
+	if req.Title != nil {
+		fields["title"] = *req.Title
+	}
+	if req.Description != nil {
+		fields["description"] = *req.Description
+	}
+	if req.DueDate != nil {
+		fields["due_date"] = *req.DueDate
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var milestone models.Milestone
+	err := s.milestoneCollection.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$set": fields},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&milestone)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrMilestoneNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if milestone.Progress, err = s.progress(ctx, milestone.ID); err != nil {
+		return nil, err
+	}
+	return &milestone, nil
+}
+
+// DeleteMilestone deletes a milestone. Its tasks are kept and taken off the milestone.
+func (s *MilestoneService) DeleteMilestone(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	result, err := s.milestoneCollection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrMilestoneNotFound
+	}
+	_, err = s.taskService.DetachMilestone(ctx, id)
+	return err
+}
+
+// progress counts the tasks attached to a milestone and those of them that are done
+func (s *MilestoneService) progress(ctx context.Context, milestoneID primitive.ObjectID) (*models.MilestoneProgress, error) {
+	counts, err := s.tasks.CountByStatus(ctx, bson.M{"milestone_id": milestoneID})
+	if err != nil {
+		return nil, err
+	}
+	progress := &models.MilestoneProgress{}
+	for _, count := range counts {
+		progress.TotalTasks += count.Count
+		if count.Status == models.StatusDone {
+			progress.CompletedTasks += count.Count
+		}
+	}
+	if progress.TotalTasks > 0 {
+		progress.Percent = float64(progress.CompletedTasks) * 100 / float64(progress.TotalTasks)
+	}
+	return progress, nil
+}
@@ -0,0 +1,465 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/OsGift/taskflow-api/internal/jobs"
+	"github.com/OsGift/taskflow-api/internal/logging"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+// notificationEvents lists every event users can be notified about, with the channels each
+// can use. Events carrying secrets (links, passwords) are limited to email.
+var notificationEvents = []models.NotificationEventInfo{
+	{
+		Event:       models.EventAccountCreated,
+		Description: "Welcome email with the link to verify your address",
+		Channels:    []models.NotificationChannel{models.ChannelEmail},
+		Defaults:    []models.NotificationChannel{models.ChannelEmail},
+		Required:    []models.NotificationChannel{models.ChannelEmail},
+	},
+	{
+		Event:       models.EventAdminAccountCreated,
+		Description: "Temporary password of a new administrator account",
+		Channels:    []models.NotificationChannel{models.ChannelEmail},
+		Defaults:    []models.NotificationChannel{models.ChannelEmail},
+		Required:    []models.NotificationChannel{models.ChannelEmail},
+	},
+	{
+		Event:       models.EventPasswordReset,
+		Description: "Password reset link",
+		Channels:    []models.NotificationChannel{models.ChannelEmail},
+		Defaults:    []models.NotificationChannel{models.ChannelEmail},
+		Required:    []models.NotificationChannel{models.ChannelEmail},
+	},
+	{
+		Event:       models.EventUploadQuarantined,
+		Description: "An upload was flagged by the virus scanner (administrators)",
+		Channels:    []models.NotificationChannel{models.ChannelEmail, models.ChannelInApp, models.ChannelPush, models.ChannelWebhook},
+		Defaults:    []models.NotificationChannel{models.ChannelEmail, models.ChannelInApp},
+		Required:    []models.NotificationChannel{},
+	},
+	{
+		Event:       models.EventWeeklyDigest,
+		Description: "Weekly summary of your tasks, when enabled in your profile",
+		Channels:    []models.NotificationChannel{models.ChannelEmail, models.ChannelPush, models.ChannelWebhook},
+		Defaults:    []models.NotificationChannel{models.ChannelEmail},
+		Required:    []models.NotificationChannel{},
+	},
+	{
+		Event:       models.EventDailySummary,
+		Description: "Morning summary of your tasks due today, in progress and new, when enabled in your profile",
+		Channels:    []models.NotificationChannel{models.ChannelEmail, models.ChannelInApp, models.ChannelPush, models.ChannelWebhook},
+		Defaults:    []models.NotificationChannel{models.ChannelEmail, models.ChannelInApp},
+		Required:    []models.NotificationChannel{},
+	},
+	{
+		Event:       models.EventProjectMemberAdded,
+		Description: "Someone added you to a project",
+		Channels:    []models.NotificationChannel{models.ChannelInApp, models.ChannelPush, models.ChannelWebhook},
+		Defaults:    []models.NotificationChannel{models.ChannelInApp},
+		Required:    []models.NotificationChannel{},
+	},
+	{
+		Event:       models.EventTasksGoingStale,
+		Description: "Tasks you haven't updated in a while will soon be flagged or archived as stale",
+		Channels:    []models.NotificationChannel{models.ChannelEmail, models.ChannelInApp, models.ChannelPush, models.ChannelWebhook},
+		Defaults:    []models.NotificationChannel{models.ChannelEmail, models.ChannelInApp},
+		Required:    []models.NotificationChannel{},
+	},
+	{
+		Event:       models.EventSLABreached,
+		Description: "A task stayed in a status longer than an SLA rule allows (managers)",
+		Channels:    []models.NotificationChannel{models.ChannelEmail, models.ChannelInApp, models.ChannelPush, models.ChannelWebhook},
+		Defaults:    []models.NotificationChannel{models.ChannelEmail, models.ChannelInApp},
+		Required:    []models.NotificationChannel{},
+	},
+}
+
+// Notice is an event to notify one user about. Email renders Template with Data; the other
+// channels show Title, Body and Link, which must not carry secrets.
+type Notice struct {
+	Event    models.NotificationEvent
+	User     *models.User
+	Title    string
+	Body     string
+	Link     string
+	Template string
+	Subject  string // English subject, translated to the user's locale when the email is sent
+	Data     interface{}
+	Key      string // When set, each channel delivers the notice at most once per key
+}
+
+// NotificationService fans events out to users on the channels they chose. Emails, push
+// notifications and webhooks are queued for the job worker; in-app notifications are stored
+// in the notifications collection.
+type NotificationService struct {
+	notificationCollection repository.Collection
+	preferenceCollection   repository.Collection
+	jobQueue               *jobs.Queue
+	pushEnabled            bool // Whether a push gateway is configured
+}
+
+// NewNotificationService creates a new NotificationService; the push channel is only offered
+// when pushEnabled is true
+func NewNotificationService(db repository.Documents, jq *jobs.Queue, pushEnabled bool) *NotificationService {
+	return &NotificationService{
+		notificationCollection: db.Collection("notifications"),
+		preferenceCollection:   db.Collection("notification_preferences"),
+		jobQueue:               jq,
+		pushEnabled:            pushEnabled,
+	}
+}
+
+// Notify delivers a notice on every channel the user chose for its event, and on the
+// event's required channels. A failing channel doesn't stop the others; their errors are
+// returned together. It reports whether the notice was delivered anywhere, which it isn't
+// when every channel was turned off or had already delivered the notice under its key.
+func (s *NotificationService) Notify(ctx context.Context, notice *Notice) (bool, error) {
+	info := s.eventInfo(notice.Event)
+	if info == nil {
+		return false, fmt.Errorf("unknown notification event %q", notice.Event)
+	}
+
+	prefs, err := s.preferences(ctx, notice.User.ID)
+	if err != nil {
+		// Required channels (e.g. password reset emails) must work without preferences
+		logging.Warnf("Failed to load notification preferences of user %s, using defaults: %v", notice.User.ID.Hex(), err)
+		prefs = &models.NotificationPreferences{UserID: notice.User.ID}
+	}
+
+	var delivered bool
+	var errs []error
+	for _, channel := range channelsFor(info, prefs) {
+		sent, err := s.deliver(ctx, channel, notice, prefs)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", channel, err))
+			continue
+		}
+		delivered = delivered || sent
+	}
+	return delivered, errors.Join(errs...)
+}
+
+// deliver sends a notice on one channel, reporting false when there was nowhere to send it
+// or it was already sent under its key
+func (s *NotificationService) deliver(ctx context.Context, channel models.NotificationChannel, notice *Notice, prefs *models.NotificationPreferences) (bool, error) {
+	user := notice.User
+	switch channel {
+	case models.ChannelEmail:
+		if user.IsServiceAccount { // Their addresses can't receive email
+			return false, nil
+		}
+		if notice.Key == "" {
+			return true, s.jobQueue.EnqueueEmail(ctx, notice.Template, notice.Subject, user.Email, user.Locale, notice.Data)
+		}
+		return s.jobQueue.EnqueueUniqueEmail(ctx, notice.Key+":email", notice.Template, notice.Subject, user.Email, user.Locale, notice.Data)
+
+	case models.ChannelInApp:
+		return s.store(ctx, notice)
+
+	case models.ChannelPush:
+		if len(prefs.PushTokens) == 0 {
+			return false, nil
+		}
+		return s.enqueue(ctx, notice.Key, channel, jobs.TypeNotificationPush, jobs.PushPayload{
+			Tokens: prefs.PushTokens,
+			Title:  notice.Title,
+			Body:   notice.Body,
+			Data:   map[string]string{"event": string(notice.Event), "link": notice.Link},
+		})
+
+	case models.ChannelWebhook:
+		if prefs.WebhookURL == "" {
+			return false, nil
+		}
+		body, err := json.Marshal(map[string]interface{}{
+			"event":      notice.Event,
+			"user_id":    user.ID.Hex(),
+			"title":      notice.Title,
+			"body":       notice.Body,
+			"link":       notice.Link,
+			"created_at": time.Now().UTC(),
+		})
+		if err != nil {
+			return false, err
+		}
+		return s.enqueue(ctx, notice.Key, channel, jobs.TypeNotificationWebhook, jobs.WebhookPayload{
+			URL:       prefs.WebhookURL,
+			Body:      body,
+			Signature: signWebhook(prefs.WebhookSecret, body),
+		})
+	}
+	return false, fmt.Errorf("unknown channel")
+}
+
+// store saves an in-app notification. Notices with a key are only stored once.
+func (s *NotificationService) store(ctx context.Context, notice *Notice) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	notification := models.Notification{
+		ID:        primitive.NewObjectID(),
+		UserID:    notice.User.ID,
+		Event:     notice.Event,
+		Title:     notice.Title,
+		Body:      notice.Body,
+		Link:      notice.Link,
+		Key:       notice.Key,
+		CreatedAt: time.Now(),
+	}
+	if notice.Key == "" {
+		_, err := s.notificationCollection.InsertOne(ctx, notification)
+		return err == nil, err
+	}
+	result, err := s.notificationCollection.UpdateOne(ctx,
+		bson.M{"key": notice.Key},
+		bson.M{"$setOnInsert": notification},
+		options.Update().SetUpsert(true))
+	if err != nil {
+		return false, err
+	}
+	return result.UpsertedCount > 0, nil
+}
+
+// enqueue queues a delivery job, only once per key and channel when key is set
+func (s *NotificationService) enqueue(ctx context.Context, key string, channel models.NotificationChannel, jobType string, payload interface{}) (bool, error) {
+	if key == "" {
+		return true, s.jobQueue.Enqueue(ctx, jobType, payload)
+	}
+	return s.jobQueue.EnqueueUnique(ctx, key+":"+string(channel), jobType, payload, time.Now())
+}
+
+// signWebhook signs a webhook body with the user's secret, so receivers can check it came
+// from this server
+func signWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// channelsFor returns the channels a notice is delivered on: the user's choice (or the
+// event's defaults) restricted to the event's channels, plus its required ones
+func channelsFor(info *models.NotificationEventInfo, prefs *models.NotificationPreferences) []models.NotificationChannel {
+	chosen, ok := prefs.Channels[info.Event]
+	if !ok {
+		chosen = info.Defaults
+	}
+	var channels []models.NotificationChannel
+	for _, channel := range info.Channels {
+		if slices.Contains(chosen, channel) || slices.Contains(info.Required, channel) {
+			channels = append(channels, channel)
+		}
+	}
+	return channels
+}
+
+// eventInfo describes an event, with the push channel removed when there is no push gateway
+func (s *NotificationService) eventInfo(event models.NotificationEvent) *models.NotificationEventInfo {
+	for _, info := range notificationEvents {
+		if info.Event != event {
+			continue
+		}
+		if !s.pushEnabled {
+			isPush := func(channel models.NotificationChannel) bool { return channel == models.ChannelPush }
+			info.Channels = slices.DeleteFunc(slices.Clone(info.Channels), isPush)
+			info.Defaults = slices.DeleteFunc(slices.Clone(info.Defaults), isPush)
+		}
+		return &info
+	}
+	return nil
+}
+
+// preferences loads a user's notification preferences; users who never saved any get empty ones
+func (s *NotificationService) preferences(ctx context.Context, userID primitive.ObjectID) (*models.NotificationPreferences, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var prefs models.NotificationPreferences
+	err := s.preferenceCollection.FindOne(ctx, bson.M{"_id": userID}).Decode(&prefs)
+	if err == mongo.ErrNoDocuments {
+		return &models.NotificationPreferences{UserID: userID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &prefs, nil
+}
+
+// GetPreferences returns the channels in effect for every event, and the webhook and push settings
+func (s *NotificationService) GetPreferences(ctx context.Context, userID primitive.ObjectID) (*models.NotificationPreferencesResponse, error) {
+	prefs, err := s.preferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.preferencesResponse(prefs), nil
+}
+
+// UpdatePreferences changes a user's notification preferences
+func (s *NotificationService) UpdatePreferences(ctx context.Context, userID primitive.ObjectID, req *models.UpdateNotificationPreferencesRequest) (*models.NotificationPreferencesResponse, error) {
+	prefs, err := s.preferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if prefs.Channels == nil {
+		prefs.Channels = map[models.NotificationEvent][]models.NotificationChannel{}
+	}
+	for event, channels := range req.Channels {
+		info := s.eventInfo(event)
+		if info == nil {
+			return nil, ErrUnknownNotificationEvent.WithDetails(map[string]interface{}{"event": event})
+		}
+		for _, channel := range channels {
+			if !slices.Contains(info.Channels, channel) {
+				return nil, ErrNotificationChannelNotAllowed.WithDetails(map[string]interface{}{
+					"event": event, "channel": channel, "allowed": info.Channels,
+				})
+			}
+		}
+		slices.Sort(channels)
+		prefs.Channels[event] = slices.Compact(channels)
+	}
+
+	if req.WebhookURL != nil && *req.WebhookURL != prefs.WebhookURL {
+		prefs.WebhookURL, prefs.WebhookSecret = "", ""
+		if *req.WebhookURL != "" {
+			u, err := url.Parse(*req.WebhookURL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return nil, ErrInvalidWebhookURL
+			}
+			secret := make([]byte, 32)
+			if _, err := rand.Read(secret); err != nil {
+				return nil, err
+			}
+			prefs.WebhookURL, prefs.WebhookSecret = u.String(), hex.EncodeToString(secret)
+		}
+	}
+	if req.PushTokens != nil {
+		prefs.PushTokens = slices.Compact(slices.Sorted(slices.Values(*req.PushTokens)))
+	}
+	prefs.UpdatedAt = time.Now()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	_, err = s.preferenceCollection.ReplaceOne(ctx, bson.M{"_id": userID}, prefs, options.Replace().SetUpsert(true))
+	if err != nil {
+		return nil, err
+	}
+	return s.preferencesResponse(prefs), nil
+}
+
+// preferencesResponse fills in the channels in effect for every event
+func (s *NotificationService) preferencesResponse(prefs *models.NotificationPreferences) *models.NotificationPreferencesResponse {
+	response := &models.NotificationPreferencesResponse{
+		NotificationPreferences: *prefs,
+		Events:                  make([]models.NotificationEventInfo, 0, len(notificationEvents)),
+	}
+	response.Channels = map[models.NotificationEvent][]models.NotificationChannel{}
+	if response.PushTokens == nil {
+		response.PushTokens = []string{}
+	}
+	for _, event := range notificationEvents {
+		info := s.eventInfo(event.Event)
+		response.Events = append(response.Events, *info)
+		channels := channelsFor(info, prefs)
+		if channels == nil {
+			channels = []models.NotificationChannel{}
+		}
+		response.Channels[event.Event] = channels
+	}
+	return response
+}
+
+// ListNotifications retrieves a user's in-app notifications matching the query
+func (s *NotificationService) ListNotifications(ctx context.Context, userID primitive.ObjectID, q *query.Query) (*models.NotificationListResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	q.Filter["user_id"] = userID
+	cursor, err := s.notificationCollection.Find(ctx, q.Filter, q.FindOptions())
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	notifications := []models.Notification{}
+	if err = cursor.All(ctx, &notifications); err != nil {
+		return nil, err
+	}
+
+	totalCount, err := s.notificationCollection.CountDocuments(ctx, q.Filter)
+	if err != nil {
+		return nil, err
+	}
+	unreadCount, err := s.notificationCollection.CountDocuments(ctx, bson.M{"user_id": userID, "read": false})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.NotificationListResponse{
+		Notifications: notifications,
+		Pagination:    models.NewPagination(totalCount, q.Page, q.Limit),
+		UnreadCount:   unreadCount,
+	}, nil
+}
+
+// MarkRead marks one of a user's in-app notifications as read
+func (s *NotificationService) MarkRead(ctx context.Context, userID primitive.ObjectID, notificationIDHex string) (*models.Notification, error) {
+	notificationID, err := primitive.ObjectIDFromHex(notificationIDHex)
+	if err != nil {
+		return nil, ErrInvalidNotificationID
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// Only unread notifications are updated, so read_at keeps the first time it was read
+	var notification models.Notification
+	err = s.notificationCollection.FindOneAndUpdate(ctx,
+		bson.M{"_id": notificationID, "user_id": userID, "read": false},
+		bson.M{"$set": bson.M{"read": true, "read_at": time.Now()}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&notification)
+	if err == mongo.ErrNoDocuments {
+		err = s.notificationCollection.FindOne(ctx, bson.M{"_id": notificationID, "user_id": userID}).Decode(&notification)
+	}
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotificationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &notification, nil
+}
+
+// MarkAllRead marks all of a user's in-app notifications as read and returns how many were unread
+func (s *NotificationService) MarkAllRead(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	result, err := s.notificationCollection.UpdateMany(ctx,
+		bson.M{"user_id": userID, "read": false},
+		bson.M{"$set": bson.M{"read": true, "read_at": time.Now()}})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
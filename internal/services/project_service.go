@@ -0,0 +1,516 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/OsGift/taskflow-api/internal/logging"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+// maxBurndownDays caps the days of a burn-down
+const maxBurndownDays = 366
+
+// ProjectService stores projects, which group tasks, and their members
+type ProjectService struct {
+	projectCollection   repository.Collection
+	milestoneCollection repository.Collection
+	sprintCollection    repository.Collection
+	tasks               repository.TaskRepository
+	taskService         *TaskService
+	userService         *UserService
+	notifications       *NotificationService
+}
+
+// NewProjectService creates a new ProjectService
+func NewProjectService(db repository.Documents, ts *TaskService, us *UserService, ns *NotificationService) *ProjectService {
+	return &ProjectService{
+		projectCollection:   db.Collection("projects"),
+		milestoneCollection: db.Collection("milestones"),
+		sprintCollection:    db.Collection("sprints"),
+		tasks:               ts.tasks,
+		taskService:         ts,
+		userService:         us,
+		notifications:       ns,
+	}
+}
+
+// memberFilter matches the projects userID owns or is a member of
+func memberFilter(userID primitive.ObjectID) bson.M {
+	return bson.M{"$or": []bson.M{{"owner_id": userID}, {"members.user_id": userID}}}
+}
+
+// CreateProject creates a project owned by ownerID
+func (s *ProjectService) CreateProject(ctx context.Context, ownerID primitive.ObjectID, req *models.CreateProjectRequest) (*models.Project, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	project := &models.Project{
+		ID:          primitive.NewObjectID(),
+		Name:        req.Name,
+		Description: req.Description,
+		OwnerID:     ownerID,
+		Members:     []models.ProjectMember{},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if _, err := s.projectCollection.InsertOne(ctx, project); err != nil {
+		return nil, err
+	}
+	s.userService.InvalidateAuthContext(ownerID)
+	return project, nil
+}
+
+// ListProjects retrieves the projects matching the query
+func (s *ProjectService) ListProjects(ctx context.Context, q *query.Query) (*models.ProjectListResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cursor, err := s.projectCollection.Find(ctx, q.Filter, q.FindOptions())
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	projects := []models.Project{}
+	if err = cursor.All(ctx, &projects); err != nil {
+		return nil, err
+	}
+
+	totalCount, err := s.projectCollection.CountDocuments(ctx, q.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ProjectListResponse{
+		Projects:   projects,
+		Pagination: models.NewPagination(totalCount, q.Page, q.Limit),
+	}, nil
+}
+
+// ProjectRolesOf returns userID's role in each project they own or are a member of. It
+// fills AuthContext.ProjectRoles, so every change to a project's owner or members must
+// invalidate the AuthContext of the users concerned.
+func (s *ProjectService) ProjectRolesOf(ctx context.Context, userID primitive.ObjectID) (map[primitive.ObjectID]models.ProjectRole, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := s.projectCollection.Find(ctx, memberFilter(userID),
+		options.Find().SetProjection(bson.M{"owner_id": 1, "members": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var projects []models.Project
+	if err = cursor.All(ctx, &projects); err != nil {
+		return nil, err
+	}
+	roles := make(map[primitive.ObjectID]models.ProjectRole, len(projects))
+	for _, project := range projects {
+		roles[project.ID] = project.RoleOf(userID)
+	}
+	return roles, nil
+}
+
+// invalidateMembers drops the cached AuthContexts of a project's owner and members after
+// their roles in it changed
+func (s *ProjectService) invalidateMembers(project *models.Project) {
+	s.userService.InvalidateAuthContext(project.OwnerID)
+	for _, member := range project.Members {
+		s.userService.InvalidateAuthContext(member.UserID)
+	}
+}
+
+// GetProject retrieves a project by its ID
+func (s *ProjectService) GetProject(ctx context.Context, idHex string) (*models.Project, error) {
+	id, err := primitive.ObjectIDFromHex(idHex)
+	if err != nil {
+		return nil, ErrInvalidProjectID
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var project models.Project
+	err = s.projectCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&project)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrProjectNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// Burndown counts the tasks of a project that were open at the end of each calendar day in
+// loc, from when they were created and completed. With a sprint ID only the sprint's tasks
+// count, over the sprint's dates unless from and to are given; otherwise from and to are
+// required. Tasks rolled over to another sprint count for that one. Days after today are left
+// out.
+func (s *ProjectService) Burndown(ctx context.Context, project *models.Project, sprintIDHex string, from, to *time.Time, loc *time.Location) (*models.BurndownResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	burndown := &models.BurndownResponse{ProjectID: project.ID, TimeZone: loc.String()}
+	filter := bson.M{"project_id": project.ID}
+	if sprintIDHex != "" {
+		sprintID, err := primitive.ObjectIDFromHex(sprintIDHex)
+		if err != nil {
+			return nil, ErrInvalidSprintID
+		}
+		var sprint models.Sprint
+		err = s.sprintCollection.FindOne(ctx, bson.M{"_id": sprintID, "project_id": project.ID}).Decode(&sprint)
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrSprintNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		if from == nil {
+			from = &sprint.StartDate
+		}
+		if to == nil {
+			to = &sprint.EndDate
+		}
+		burndown.SprintID = &sprint.ID
+		filter = bson.M{"sprint_id": sprint.ID}
+	}
+	if from == nil || to == nil {
+		return nil, ErrInvalidBurndownRange
+	}
+	start, end := startOfDay(from.In(loc), 0), startOfDay(to.In(loc), 0)
+	if end.Before(start) || end.After(start.AddDate(0, 0, maxBurndownDays-1)) {
+		return nil, ErrInvalidBurndownRange
+	}
+	burndown.StartDate = start.Format("2006-01-02")
+	burndown.EndDate = end.Format("2006-01-02")
+
+	// Tasks still open when the range starts, then each day's created and completed tasks
+	openFilter := bson.M{
+		"created_at": bson.M{"$lt": start},
+		"$or":        []bson.M{{"completed_at": nil}, {"completed_at": bson.M{"$gte": start}}},
+	}
+	for key, value := range filter {
+		openFilter[key] = value
+	}
+	remaining, err := s.tasks.Count(ctx, openFilter)
+	if err != nil {
+		return nil, err
+	}
+	active, err := s.tasks.ActivityByDay(ctx, filter, start, loc)
+	if err != nil {
+		return nil, err
+	}
+	byDate := make(map[string]models.ActivityDay, len(active))
+	for _, day := range active {
+		byDate[day.Date] = day
+	}
+
+	today := startOfDay(time.Now().In(loc), 0)
+	burndown.Days = []models.BurndownDay{}
+	for day := start; !day.After(end) && !day.After(today); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		activity := byDate[date]
+		remaining += activity.Created - activity.Completed
+		burndown.Days = append(burndown.Days, models.BurndownDay{
+			Date:      date,
+			Remaining: remaining,
+			Created:   activity.Created,
+			Completed: activity.Completed,
+		})
+	}
+	return burndown, nil
+}
+
+// UpdateProject changes the name or description of a project
+func (s *ProjectService) UpdateProject(ctx context.Context, id primitive.ObjectID, req *models.UpdateProjectRequest) (*models.Project, error) {
+	fields := bson.M{"updated_at": time.Now()}
+	if req.Name != nil {
+		fields["name"] = *req.Name
+	}
+	if req.Description != nil {
+		fields["description"] = *req.Description
+	}
+	return s.update(ctx, id, fields)
+}
+
+// ArchiveProject archives a project: no new tasks can be filed under it and its tasks are
+// hidden from listings, but kept. Archiving an archived project changes nothing.
+func (s *ProjectService) ArchiveProject(ctx context.Context, id primitive.ObjectID) (*models.Project, error) {
+	now := time.Now()
+	return s.setArchived(ctx, id, true, bson.M{
+		"$set": bson.M{"archived": true, "archived_at": now, "updated_at": now},
+	})
+}
+
+// RestoreProject undoes ArchiveProject. Restoring an active project changes nothing.
+func (s *ProjectService) RestoreProject(ctx context.Context, id primitive.ObjectID) (*models.Project, error) {
+	return s.setArchived(ctx, id, false, bson.M{
+		"$set":   bson.M{"archived": false, "updated_at": time.Now()},
+		"$unset": bson.M{"archived_at": ""},
+	})
+}
+
+// setArchived applies update to a project unless it is already archived or restored, then
+// hides or shows its tasks. The tasks are updated either way, so a retry repairs a failure.
+func (s *ProjectService) setArchived(ctx context.Context, id primitive.ObjectID, archived bool, update bson.M) (*models.Project, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var project models.Project
+	err := s.projectCollection.FindOneAndUpdate(ctx, bson.M{"_id": id, "archived": !archived}, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&project)
+	if err == mongo.ErrNoDocuments {
+		current, err := s.GetProject(ctx, id.Hex())
+		if err != nil {
+			return nil, err
+		}
+		project = *current
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := s.taskService.SetProjectArchived(ctx, id, project.Archived); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// DeleteProject deletes a project with its milestones and sprints. Its tasks are kept and
+// taken out of the project.
+func (s *ProjectService) DeleteProject(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var project models.Project
+	err := s.projectCollection.FindOneAndDelete(ctx, bson.M{"_id": id}).Decode(&project)
+	if err == mongo.ErrNoDocuments {
+		return ErrProjectNotFound
+	}
+	if err != nil {
+		return err
+	}
+	s.invalidateMembers(&project)
+	if _, err = s.milestoneCollection.DeleteMany(ctx, bson.M{"project_id": id}); err != nil {
+		return err
+	}
+	if _, err = s.sprintCollection.DeleteMany(ctx, bson.M{"project_id": id}); err != nil {
+		return err
+	}
+	_, err = s.taskService.DetachProject(ctx, id)
+	return err
+}
+
+// AddMember adds the user given by ID or email to a project and notifies them
+func (s *ProjectService) AddMember(ctx context.Context, project *models.Project, req *models.AddProjectMemberRequest, addedBy primitive.ObjectID) (*models.Project, error) {
+	var user *models.User
+	var err error
+	if req.UserID != "" {
+		user, err = s.userService.GetUserByID(ctx, req.UserID)
+	} else {
+		user, err = s.userService.GetUserByEmail(ctx, req.Email)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.ID == project.OwnerID {
+		return nil, ErrProjectOwnerMember
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	member := models.ProjectMember{UserID: user.ID, Role: req.Role, AddedBy: addedBy, AddedAt: time.Now()}
+	var updated models.Project
+	err = s.projectCollection.FindOneAndUpdate(ctx,
+		bson.M{"_id": project.ID, "members.user_id": bson.M{"$ne": user.ID}},
+		bson.M{"$push": bson.M{"members": member}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		if _, err := s.GetProject(ctx, project.ID.Hex()); err != nil {
+			return nil, err
+		}
+		return nil, ErrProjectMemberExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.userService.InvalidateAuthContext(user.ID)
+
+	_, err = s.notifications.Notify(ctx, &Notice{
+		Event: models.EventProjectMemberAdded,
+		User:  user,
+		Title: "Added to a project",
+		Body:  fmt.Sprintf("You were added to the project %q as %s.", updated.Name, req.Role),
+	})
+	if err != nil {
+		logging.Warnf("Failed to notify user %s about being added to project %s: %v", user.ID.Hex(), project.ID.Hex(), err)
+	}
+	return &updated, nil
+}
+
+// UpdateMemberRole changes the role of a project member
+func (s *ProjectService) UpdateMemberRole(ctx context.Context, projectID, userID primitive.ObjectID, role models.ProjectRole) (*models.Project, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var project models.Project
+	err := s.projectCollection.FindOneAndUpdate(ctx,
+		bson.M{"_id": projectID, "members.user_id": userID},
+		bson.M{"$set": bson.M{"members.$.role": role}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&project)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrProjectMemberNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.userService.InvalidateAuthContext(userID)
+	return &project, nil
+}
+
+// RemoveMember removes a member from a project. The tasks they filed under it stay there.
+func (s *ProjectService) RemoveMember(ctx context.Context, projectID, userID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := s.projectCollection.UpdateOne(ctx,
+		bson.M{"_id": projectID, "members.user_id": userID},
+		bson.M{"$pull": bson.M{"members": bson.M{"user_id": userID}}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrProjectMemberNotFound
+	}
+	s.userService.InvalidateAuthContext(userID)
+	return nil
+}
+
+// CleanUpUserData takes a user being deleted out of the projects they are a member of, and
+// hands the projects they own over to reassignTo. Without reassignTo, owning a project stops
+// the deletion, since deleting the projects would take other members' work with them.
+func (s *ProjectService) CleanUpUserData(ctx context.Context, userID primitive.ObjectID, reassignTo *primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if reassignTo == nil {
+		owned, err := s.projectCollection.CountDocuments(ctx, bson.M{"owner_id": userID})
+		if err != nil {
+			return err
+		}
+		if owned > 0 {
+			return ErrUserOwnsProjects
+		}
+	} else {
+		// The new owner is a manager through owning, so drop any membership they had
+		_, err := s.projectCollection.UpdateMany(ctx, bson.M{"owner_id": userID}, bson.M{
+			"$set":  bson.M{"owner_id": *reassignTo, "updated_at": time.Now()},
+			"$pull": bson.M{"members": bson.M{"user_id": *reassignTo}},
+		})
+		if err != nil {
+			return err
+		}
+		s.userService.InvalidateAuthContext(*reassignTo)
+	}
+
+	_, err := s.projectCollection.UpdateMany(ctx, bson.M{"members.user_id": userID},
+		bson.M{"$pull": bson.M{"members": bson.M{"user_id": userID}}})
+	return err
+}
+
+// MergeUser hands the projects of the user duplicateID, merged into primaryID, over to the
+// primary user: the projects the duplicate owns and its memberships. Where both users were in
+// a project, the primary keeps the role that grants more, and owning beats any membership. It
+// returns how many projects changed, and only ever changes a project once, so merges can be
+// resumed.
+func (s *ProjectService) MergeUser(ctx context.Context, duplicateID, primaryID primitive.ObjectID) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	// The new owner is a manager through owning, so drop any membership they had
+	owned, err := s.projectCollection.UpdateMany(ctx, bson.M{"owner_id": duplicateID}, bson.M{
+		"$set":  bson.M{"owner_id": primaryID, "updated_at": now},
+		"$pull": bson.M{"members": bson.M{"user_id": primaryID}},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	cursor, err := s.projectCollection.Find(ctx, bson.M{"members.user_id": duplicateID})
+	if err != nil {
+		return 0, err
+	}
+	var projects []models.Project
+	if err := cursor.All(ctx, &projects); err != nil {
+		return 0, err
+	}
+	for _, project := range projects {
+		_, err := s.projectCollection.UpdateOne(ctx, bson.M{"_id": project.ID}, bson.M{"$set": bson.M{
+			"members":    mergedMembers(&project, duplicateID, primaryID),
+			"updated_at": now,
+		}})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	s.userService.InvalidateAuthContext(primaryID)
+	s.userService.InvalidateAuthContext(duplicateID)
+	return owned.ModifiedCount + int64(len(projects)), nil
+}
+
+// mergedMembers returns the members of a project once the duplicate's membership is handed
+// over to the primary user, who ends up with the greater of both their roles
+func mergedMembers(project *models.Project, duplicateID, primaryID primitive.ObjectID) []models.ProjectMember {
+	var duplicate *models.ProjectMember
+	members := make([]models.ProjectMember, 0, len(project.Members))
+	for _, member := range project.Members {
+		if member.UserID == duplicateID {
+			duplicate = &member
+			continue
+		}
+		members = append(members, member)
+	}
+	if duplicate == nil || project.OwnerID == primaryID {
+		return members
+	}
+	for i, member := range members {
+		if member.UserID == primaryID {
+			if !member.Role.Includes(duplicate.Role) {
+				members[i].Role = duplicate.Role
+			}
+			return members
+		}
+	}
+	duplicate.UserID = primaryID
+	return append(members, *duplicate)
+}
+
+// update sets fields on a project and returns the updated project
+func (s *ProjectService) update(ctx context.Context, id primitive.ObjectID, fields bson.M) (*models.Project, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var project models.Project
+	err := s.projectCollection.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$set": fields},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&project)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrProjectNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+)
+
+// quickTimePattern matches the times of quick-add text: "5pm", "5:30pm", "17:00"
+var quickTimePattern = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)?$`)
+
+// quickDueWords introduce a date or time in quick-add text, as in "on friday" or "at 5pm",
+// and are dropped from the title along with it
+var quickDueWords = map[string]bool{"on": true, "by": true, "due": true, "at": true}
+
+// ParseQuickAdd turns a line of shorthand such as "Pay rent tomorrow 5pm #finance !high" into
+// the task it describes:
+//   - #word adds the tag "word"
+//   - !low, !medium, !high or !urgent sets the priority
+//   - today, tomorrow, a weekday ("friday", "next friday"), "next week" (Monday), "in 3 days",
+//     "in 2 weeks" or a YYYY-MM-DD date sets the due date
+//   - 5pm, 5:30pm, 17:00 or noon sets the due time; a time alone is due today, or tomorrow
+//     once it has passed, and a date alone at the end of the day
+//
+// Everything else, in order, is the title. Only the first date, time and priority are read;
+// later ones are left in the title. Dates are read in now's time zone.
+func ParseQuickAdd(text string, now time.Time) *models.CreateTaskRequest {
+	req := &models.CreateTaskRequest{}
+	words := strings.Fields(text)
+
+	var title []string
+	var day *time.Time
+	hour, minute := -1, -1
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		lower := strings.ToLower(strings.TrimRight(word, ",.;"))
+
+		if tag, ok := strings.CutPrefix(lower, "#"); ok && tag != "" {
+			if !slices.Contains(req.Tags, tag) {
+				req.Tags = append(req.Tags, tag)
+			}
+			continue
+		}
+		if priority, ok := strings.CutPrefix(lower, "!"); ok && req.Priority == "" && isTaskPriority(priority) {
+			req.Priority = priority
+			continue
+		}
+
+		// A leading "on", "by", "due" or "at" goes along with the date or time after it
+		start := i
+		if quickDueWords[lower] && i+1 < len(words) {
+			i++
+		}
+		if day == nil {
+			if d, n := parseQuickDate(words[i:], now); n > 0 {
+				day = &d
+				i += n - 1
+				continue
+			}
+		}
+		if hour < 0 {
+			if h, m, n := parseQuickTime(words[i:]); n > 0 {
+				hour, minute = h, m
+				i += n - 1
+				continue
+			}
+		}
+		i = start
+		title = append(title, word)
+	}
+	req.Title = strings.Join(title, " ")
+
+	switch {
+	case day != nil && hour >= 0:
+		due := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, now.Location())
+		req.DueDate = &due
+	case day != nil:
+		due := time.Date(day.Year(), day.Month(), day.Day(), 23, 59, 59, 0, now.Location())
+		req.DueDate = &due
+	case hour >= 0:
+		due := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+		if !due.After(now) {
+			due = due.AddDate(0, 0, 1)
+		}
+		req.DueDate = &due
+	}
+	return req
+}
+
+// parseQuickDate reads a date from the start of words, returning the day and how many words
+// it took (0 when words don't start with a date)
+func parseQuickDate(words []string, now time.Time) (time.Time, int) {
+	word := func(i int) string {
+		if i >= len(words) {
+			return ""
+		}
+		return strings.ToLower(strings.TrimRight(words[i], ",.;"))
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch first := word(0); first {
+	case "today", "tonight":
+		return today, 1
+	case "tomorrow", "tmrw":
+		return today.AddDate(0, 0, 1), 1
+	case "next":
+		if word(1) == "week" {
+			return nextWeekday(today, time.Monday, false), 2
+		}
+		if weekday, ok := parseWeekday(word(1)); ok {
+			return nextWeekday(today, weekday, false), 2
+		}
+	case "in":
+		n, err := strconv.Atoi(word(1))
+		if err != nil || n < 1 || n > 365 {
+			break
+		}
+		switch word(2) {
+		case "day", "days":
+			return today.AddDate(0, 0, n), 3
+		case "week", "weeks":
+			return today.AddDate(0, 0, 7*n), 3
+		}
+	default:
+		if weekday, ok := parseWeekday(first); ok {
+			return nextWeekday(today, weekday, true), 1
+		}
+		if d, err := time.ParseInLocation("2006-01-02", first, now.Location()); err == nil {
+			return d, 1
+		}
+	}
+	return time.Time{}, 0
+}
+
+// parseQuickTime reads a time of day from the start of words, returning it and how many words
+// it took (0 when words don't start with a time)
+func parseQuickTime(words []string) (hour, minute, n int) {
+	if len(words) == 0 {
+		return 0, 0, 0
+	}
+	word := strings.ToLower(strings.TrimRight(words[0], ",.;"))
+	n = 1
+	if word == "noon" {
+		return 12, 0, 1
+	}
+	// "5 pm" is written as two words
+	if len(words) > 1 {
+		if suffix := strings.ToLower(strings.TrimRight(words[1], ",.;")); suffix == "am" || suffix == "pm" {
+			word += suffix
+			n = 2
+		}
+	}
+
+	match := quickTimePattern.FindStringSubmatch(word)
+	if match == nil || (match[2] == "" && match[3] == "") { // A bare number isn't a time
+		return 0, 0, 0
+	}
+	hour, _ = strconv.Atoi(match[1])
+	if match[2] != "" {
+		minute, _ = strconv.Atoi(match[2])
+	}
+	switch {
+	case minute > 59:
+		return 0, 0, 0
+	case match[3] != "":
+		if hour < 1 || hour > 12 {
+			return 0, 0, 0
+		}
+		hour %= 12
+		if match[3] == "pm" {
+			hour += 12
+		}
+	case hour > 23:
+		return 0, 0, 0
+	}
+	return hour, minute, n
+}
+
+// parseWeekday reads a weekday name. Abbreviations aren't read, as "sat" and "sun" are words
+// of their own.
+func parseWeekday(word string) (time.Weekday, bool) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if word == strings.ToLower(d.String()) {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// nextWeekday returns the first day after today, or from today when includeToday is set,
+// that falls on weekday
+func nextWeekday(today time.Time, weekday time.Weekday, includeToday bool) time.Time {
+	days := (int(weekday) - int(today.Weekday()) + 7) % 7
+	if days == 0 && !includeToday {
+		days = 7
+	}
+	return today.AddDate(0, 0, days)
+}
+
+// isTaskPriority reports whether s names a task priority
+func isTaskPriority(s string) bool {
+	switch models.TaskPriority(s) {
+	case models.PriorityLow, models.PriorityMedium, models.PriorityHigh, models.PriorityUrgent:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/OsGift/taskflow-api/internal/logging"
+	"github.com/OsGift/taskflow-api/internal/models"
+)
+
+// dependencyCheckTimeout bounds each dependency check
+const dependencyCheckTimeout = 10 * time.Second
+
+// DependencyChecker is implemented by the mailers and storage backends that can verify their
+// connection and credentials without side effects (SMTP, Cloudinary)
+type DependencyChecker interface {
+	Check(ctx context.Context) error
+}
+
+// DependencyCheck names one check of an external dependency
+type DependencyCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// ReadinessService checks the external dependencies the API needs, such as MongoDB and the
+// email provider, so a broken one is reported when the server starts and by GET /readyz,
+// rather than when a user first needs it
+type ReadinessService struct {
+	checks   []DependencyCheck
+	cacheTTL time.Duration
+
+	mu      sync.Mutex
+	report  *models.ReadinessReport
+	failing map[string]bool // Checks failing at the last run, to log only changes
+}
+
+// NewReadinessService creates a ReadinessService running checks. Reports are reused for
+// cacheTTL, so frequent probes don't run into the providers' rate limits.
+func NewReadinessService(checks []DependencyCheck, cacheTTL time.Duration) *ReadinessService {
+	return &ReadinessService{
+		checks:   checks,
+		cacheTTL: cacheTTL,
+		failing:  map[string]bool{},
+	}
+}
+
+// Report returns the latest report, running the checks again once it is older than the
+// cache TTL
+func (s *ReadinessService) Report(ctx context.Context) *models.ReadinessReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.report != nil && time.Since(s.report.CheckedAt) < s.cacheTTL {
+		return s.report
+	}
+	return s.run(ctx)
+}
+
+// Check runs every check now. Checks that start failing are logged with their error, and
+// those that recover are logged too.
+func (s *ReadinessService) Check(ctx context.Context) *models.ReadinessReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.run(ctx)
+}
+
+// run runs the checks concurrently and records the report; s.mu must be held
+func (s *ReadinessService) run(ctx context.Context) *models.ReadinessReport {
+	report := &models.ReadinessReport{
+		Ready:     true,
+		CheckedAt: time.Now(),
+		Checks:    make([]models.DependencyStatus, len(s.checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i, check := range s.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, dependencyCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check.Check(checkCtx)
+			status := models.DependencyStatus{Name: check.Name, OK: err == nil, DurationMS: time.Since(start).Milliseconds()}
+			if err != nil {
+				status.Error = err.Error()
+			}
+			report.Checks[i] = status
+		}()
+	}
+	wg.Wait()
+
+	for _, status := range report.Checks {
+		switch {
+		case !status.OK:
+			report.Ready = false
+			if !s.failing[status.Name] {
+				logging.Warnf("Dependency check %s failed: %s", status.Name, status.Error)
+			}
+		case s.failing[status.Name]:
+			log.Printf("Dependency check %s passes again", status.Name)
+		}
+		s.failing[status.Name] = !status.OK
+	}
+
+	s.report = report
+	return report
+}
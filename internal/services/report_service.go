@@ -0,0 +1,334 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/OsGift/taskflow-api/internal/jobs"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+const (
+	// dashboardReportTemplate is the email template of scheduled dashboard reports
+	dashboardReportTemplate = "dashboard_report"
+	dashboardReportSubject  = "Your TaskFlow dashboard report"
+
+	// Defaults for the optional fields of a ReportScheduleRequest
+	defaultReportHour       = 8
+	defaultReportWeekday    = "monday"
+	defaultReportDayOfMonth = 1
+)
+
+// ReportService stores the dashboard report schedules configured by administrators and
+// emails the reports when they are due. Every enabled schedule has its next run queued as a
+// job; each run queues the one after it.
+type ReportService struct {
+	scheduleCollection repository.Collection
+	dashboard          *DashboardService
+	jobQueue           *jobs.Queue
+}
+
+// NewReportService creates a new ReportService
+func NewReportService(db repository.Documents, ds *DashboardService, jq *jobs.Queue) *ReportService {
+	return &ReportService{
+		scheduleCollection: db.Collection("report_schedules"),
+		dashboard:          ds,
+		jobQueue:           jq,
+	}
+}
+
+// ListSchedules returns every report schedule, oldest first
+func (s *ReportService) ListSchedules(ctx context.Context) (*models.ReportScheduleListResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := s.scheduleCollection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	schedules := []models.ReportSchedule{}
+	if err := cursor.All(ctx, &schedules); err != nil {
+		return nil, err
+	}
+	return &models.ReportScheduleListResponse{Schedules: schedules}, nil
+}
+
+// GetSchedule retrieves a report schedule by its ID
+func (s *ReportService) GetSchedule(ctx context.Context, id string) (*models.ReportSchedule, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrInvalidReportScheduleID
+	}
+
+	var schedule models.ReportSchedule
+	if err := s.scheduleCollection.FindOne(ctx, bson.M{"_id": objID}).Decode(&schedule); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrReportScheduleNotFound
+		}
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// CreateSchedule stores a new report schedule and queues its first run
+func (s *ReportService) CreateSchedule(ctx context.Context, req *models.ReportScheduleRequest, createdBy primitive.ObjectID) (*models.ReportSchedule, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	schedule := &models.ReportSchedule{
+		ID:        primitive.NewObjectID(),
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	applyReportScheduleRequest(schedule, req)
+
+	// The run is queued first: should the insert fail, it finds no schedule and does nothing
+	if err := s.queueNextRun(ctx, schedule, now); err != nil {
+		return nil, err
+	}
+	if _, err := s.scheduleCollection.InsertOne(ctx, schedule); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// UpdateSchedule replaces the settings of a report schedule. A change of timing takes effect
+// from the next run: runs queued for the previous timing are skipped.
+func (s *ReportService) UpdateSchedule(ctx context.Context, id string, req *models.ReportScheduleRequest) (*models.ReportSchedule, error) {
+	schedule, err := s.GetSchedule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	applyReportScheduleRequest(schedule, req)
+	schedule.UpdatedAt = now
+	if err := s.queueNextRun(ctx, schedule, now); err != nil {
+		return nil, err
+	}
+
+	result, err := s.scheduleCollection.ReplaceOne(ctx, bson.M{"_id": schedule.ID}, schedule)
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, ErrReportScheduleNotFound
+	}
+	return schedule, nil
+}
+
+// DeleteSchedule deletes a report schedule; its queued run finds it gone and does nothing
+func (s *ReportService) DeleteSchedule(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrInvalidReportScheduleID
+	}
+
+	result, err := s.scheduleCollection.DeleteOne(ctx, bson.M{"_id": objID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrReportScheduleNotFound
+	}
+	return nil
+}
+
+// NextRun is the jobs.NextRunFunc of TypeScheduledReport: each schedule runs at its own timing
+// while enabled. Runs of deleted or disabled schedules, and runs left over from a change of
+// timing, have no next run and do nothing.
+func (s *ReportService) NextRun(ctx context.Context, run jobs.RunPayload) (time.Time, bool, error) {
+	schedule, err := s.dueSchedule(ctx, run)
+	if schedule == nil {
+		return time.Time{}, false, err
+	}
+	return nextReportRun(schedule, run.RunAt), true, nil
+}
+
+// SendScheduledReport handles a TypeScheduledReport run: it emails the report to every
+// recipient. Emails are keyed by run and recipient, so a retried run doesn't email anyone twice.
+func (s *ReportService) SendScheduledReport(ctx context.Context, run jobs.RunPayload) error {
+	schedule, err := s.dueSchedule(ctx, run)
+	if schedule == nil {
+		return err
+	}
+	_, err = s.scheduleCollection.UpdateOne(ctx, bson.M{"_id": schedule.ID}, bson.M{"$set": bson.M{
+		"next_run_at": nextReportRun(schedule, run.RunAt),
+		"last_run_at": run.RunAt,
+	}})
+	if err != nil {
+		return err
+	}
+
+	from, to := reportRange(schedule.Frequency, run.RunAt)
+	metrics, err := s.dashboard.GetDashboardMetrics(ctx, models.PeriodCustom, &from, &to, time.UTC)
+	if err != nil {
+		return err
+	}
+	emailData := reportEmailData(schedule.Frequency, metrics)
+
+	var sent int
+	for _, recipient := range schedule.Recipients {
+		key := fmt.Sprintf("%s:%s:%s:%s", jobs.TypeScheduledReport, schedule.ID.Hex(), run.RunAt.Format(time.RFC3339), recipient)
+		queued, err := s.jobQueue.EnqueueUniqueEmail(ctx, key, dashboardReportTemplate, dashboardReportSubject, recipient, "", emailData)
+		if err != nil {
+			return fmt.Errorf("failed to send report to %s: %w", recipient, err)
+		}
+		if queued {
+			sent++
+		}
+	}
+
+	log.Printf("Report schedule %s: queued %d emails", schedule.ID.Hex(), sent)
+	return nil
+}
+
+// queueNextRun sets schedule.NextRunAt to its first run after t and queues that run, or
+// clears it when the schedule is disabled
+func (s *ReportService) queueNextRun(ctx context.Context, schedule *models.ReportSchedule, t time.Time) error {
+	if !schedule.Enabled {
+		schedule.NextRunAt = nil
+		return nil
+	}
+	next := nextReportRun(schedule, t)
+	schedule.NextRunAt = &next
+	return jobs.ScheduleRun(ctx, s.jobQueue, jobs.TypeScheduledReport, jobs.RunPayload{ScheduleID: &schedule.ID, RunAt: next})
+}
+
+// dueSchedule returns the schedule of run, or nil when it was deleted or disabled, or its
+// timing changed since run was queued
+func (s *ReportService) dueSchedule(ctx context.Context, run jobs.RunPayload) (*models.ReportSchedule, error) {
+	if run.ScheduleID == nil {
+		return nil, nil
+	}
+	var schedule models.ReportSchedule
+	if err := s.scheduleCollection.FindOne(ctx, bson.M{"_id": run.ScheduleID}).Decode(&schedule); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !schedule.Enabled || !nextReportRun(&schedule, run.RunAt.Add(-time.Second)).Equal(run.RunAt) {
+		return nil, nil
+	}
+	return &schedule, nil
+}
+
+// applyReportScheduleRequest copies req onto schedule, filling in the defaults. Weekday and
+// DayOfMonth are only kept for the frequency that uses them.
+func applyReportScheduleRequest(schedule *models.ReportSchedule, req *models.ReportScheduleRequest) {
+	schedule.Frequency = models.ReportFrequency(req.Frequency)
+	schedule.Hour = defaultReportHour
+	if req.Hour != nil {
+		schedule.Hour = *req.Hour
+	}
+	schedule.Weekday = ""
+	if schedule.Frequency == models.ReportWeekly {
+		schedule.Weekday = defaultReportWeekday
+		if req.Weekday != "" {
+			schedule.Weekday = req.Weekday
+		}
+	}
+	schedule.DayOfMonth = 0
+	if schedule.Frequency == models.ReportMonthly {
+		schedule.DayOfMonth = defaultReportDayOfMonth
+		if req.DayOfMonth != 0 {
+			schedule.DayOfMonth = req.DayOfMonth
+		}
+	}
+	schedule.Recipients = req.Recipients
+	schedule.Enabled = req.Enabled == nil || *req.Enabled
+}
+
+// nextReportRun returns the first run of schedule strictly after t
+func nextReportRun(schedule *models.ReportSchedule, t time.Time) time.Time {
+	switch schedule.Frequency {
+	case models.ReportWeekly:
+		weekday := time.Monday
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			if strings.EqualFold(schedule.Weekday, day.String()) {
+				weekday = day
+			}
+		}
+		return jobs.NextWeekly(t, weekday, schedule.Hour)
+	case models.ReportMonthly:
+		return jobs.NextMonthly(t, schedule.DayOfMonth, schedule.Hour)
+	default:
+		return jobs.NextDaily(t, schedule.Hour)
+	}
+}
+
+// reportRange returns the period a report run at runAt covers: the day, week or month up to it
+func reportRange(frequency models.ReportFrequency, runAt time.Time) (from, to time.Time) {
+	switch frequency {
+	case models.ReportWeekly:
+		return runAt.AddDate(0, 0, -7), runAt
+	case models.ReportMonthly:
+		return runAt.AddDate(0, -1, 0), runAt
+	default:
+		return runAt.AddDate(0, 0, -1), runAt
+	}
+}
+
+// reportEmailData turns dashboard metrics into the variables of the dashboard report template
+func reportEmailData(frequency models.ReportFrequency, metrics *models.DashboardMetricsResponse) map[string]interface{} {
+	statuses := make([]map[string]interface{}, 0, len(metrics.TasksByStatus))
+	for _, status := range metrics.TasksByStatus {
+		statuses = append(statuses, map[string]interface{}{"Status": string(status.Status), "Count": status.Count})
+	}
+
+	roleNames := make([]string, 0, len(metrics.UsersByRole))
+	for name := range metrics.UsersByRole {
+		roleNames = append(roleNames, name)
+	}
+	sort.Strings(roleNames)
+	roles := make([]map[string]interface{}, 0, len(roleNames))
+	for _, name := range roleNames {
+		roles = append(roles, map[string]interface{}{"Role": name, "Count": metrics.UsersByRole[name]})
+	}
+
+	var stale int64
+	for _, age := range metrics.OpenTasksByAge {
+		stale += age.Stale
+	}
+
+	const dateFormat = "Mon, Jan 2 15:04 MST"
+	return map[string]interface{}{
+		"Frequency":     string(frequency),
+		"StartDate":     metrics.StartDate.UTC().Format(dateFormat),
+		"EndDate":       metrics.EndDate.UTC().Format(dateFormat),
+		"TotalUsers":    metrics.TotalUsers,
+		"NewUsers":      metrics.NewUsers,
+		"TotalTasks":    metrics.TotalTasks,
+		"NewTasks":      metrics.NewTasks,
+		"OverdueCount":  metrics.OverdueCount,
+		"StaleCount":    stale,
+		"TasksByStatus": statuses,
+		"UsersByRole":   roles,
+		"DashboardLink": "http://localhost:3000/dashboard", // Frontend dashboard URL
+		"Year":          time.Now().Year(),
+	}
+}
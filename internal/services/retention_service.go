@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/OsGift/taskflow-api/internal/jobs"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+// RetentionPolicy says for how many days records are kept before the cleanup job deletes
+// them; 0 keeps them forever
+type RetentionPolicy struct {
+	AuditLogDays         int // Audit log entries
+	EmailDeliveryDays    int // Records of email send attempts
+	ReadNotificationDays int // Notifications their user has read
+	CompletedJobDays     int // Background jobs that succeeded; dead jobs are kept for inspection
+}
+
+// retentionRule deletes the records of a collection whose time field is older than a number of days
+type retentionRule struct {
+	collection string
+	field      string
+	filter     bson.M // Further restricts which records are deleted; may be nil
+	days       int
+}
+
+// RetentionService runs the daily cleanup job that enforces the retention policy
+type RetentionService struct {
+	db       repository.Documents
+	jobQueue *jobs.Queue
+	rules    []retentionRule
+	enabled  atomic.Bool
+	dryRun   atomic.Bool // Only log how many records would be deleted
+	hour     int
+}
+
+// NewRetentionService creates a RetentionService cleaning up daily at hour:00 UTC. With dryRun,
+// runs only log what they would delete. When enabled is false, runs that were already queued
+// do nothing and aren't rescheduled.
+func NewRetentionService(db repository.Documents, jq *jobs.Queue, policy RetentionPolicy, enabled, dryRun bool, hour int) *RetentionService {
+	s := &RetentionService{
+		db:       db,
+		jobQueue: jq,
+		rules: []retentionRule{
+			{collection: "audit_logs", field: "created_at", days: policy.AuditLogDays},
+			{collection: "email_deliveries", field: "created_at", days: policy.EmailDeliveryDays},
+			{collection: "notifications", field: "created_at", filter: bson.M{"read": true}, days: policy.ReadNotificationDays},
+			{collection: "jobs", field: "completed_at", filter: bson.M{"status": models.JobCompleted}, days: policy.CompletedJobDays},
+		},
+		hour: hour,
+	}
+	s.enabled.Store(enabled)
+	s.dryRun.Store(dryRun)
+	return s
+}
+
+// SetEnabled turns the cleanup on or off, queuing the next run when turning it on, and
+// switches dry runs on or off
+func (s *RetentionService) SetEnabled(ctx context.Context, enabled, dryRun bool) error {
+	s.dryRun.Store(dryRun)
+	if s.enabled.Swap(enabled) || !enabled {
+		return nil
+	}
+	return s.Schedule(ctx)
+}
+
+// Schedule makes sure the next cleanup run is queued
+func (s *RetentionService) Schedule(ctx context.Context) error {
+	return jobs.ScheduleNext(ctx, s.jobQueue, jobs.TypeRetentionCleanup, s.NextRun)
+}
+
+// NextRun is the jobs.NextRunFunc of TypeRetentionCleanup: runs are daily while enabled
+func (s *RetentionService) NextRun(ctx context.Context, run jobs.RunPayload) (time.Time, bool, error) {
+	return jobs.NextDaily(run.RunAt, s.hour), s.enabled.Load(), nil
+}
+
+// RunCleanup handles a TypeRetentionCleanup run: it deletes the records each rule no longer
+// keeps. Deleting is idempotent, so a retried run is harmless.
+func (s *RetentionService) RunCleanup(ctx context.Context, run jobs.RunPayload) error {
+	for _, rule := range s.rules {
+		if rule.days <= 0 {
+			continue
+		}
+		count, err := s.apply(ctx, rule, time.Now().AddDate(0, 0, -rule.days))
+		if err != nil {
+			return fmt.Errorf("failed to clean up %s: %w", rule.collection, err)
+		}
+		if s.dryRun.Load() {
+			log.Printf("Retention cleanup (dry run): would delete %d %s older than %d days", count, rule.collection, rule.days)
+		} else {
+			log.Printf("Retention cleanup: deleted %d %s older than %d days", count, rule.collection, rule.days)
+		}
+	}
+	return nil
+}
+
+// apply deletes the records of rule older than cutoff, or only counts them in a dry run
+func (s *RetentionService) apply(ctx context.Context, rule retentionRule, cutoff time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	filter := bson.M{rule.field: bson.M{"$lt": cutoff}}
+	for key, value := range rule.filter {
+		filter[key] = value
+	}
+
+	collection := s.db.Collection(rule.collection)
+	if s.dryRun.Load() {
+		return collection.CountDocuments(ctx, filter)
+	}
+	result, err := collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+)
+
+// Search result types, as named in the types parameter of GET /search
+const (
+	SearchTypeTasks    = "tasks"
+	SearchTypeComments = "comments"
+	SearchTypeUsers    = "users"
+)
+
+// SearchTypes are the result types of GET /search, in response order
+var SearchTypes = []string{SearchTypeTasks, SearchTypeComments, SearchTypeUsers}
+
+const (
+	minSearchQueryLength = 2
+	maxSearchQueryLength = 100
+
+	defaultSuggestLimit = 10
+	maxSuggestLimit     = 20
+	// suggestTimeout bounds a suggestion query; typing has moved on long before it expires
+	suggestTimeout = time.Second
+)
+
+// SearchRequest is a search across types; Pages holds the page of each type to return
+type SearchRequest struct {
+	Query string
+	Types []string // Empty searches every type the caller may search
+	Pages map[string]*query.Query
+}
+
+// SearchService searches tasks, comments and users at once, returning only what the caller may see
+type SearchService struct {
+	taskService    *TaskService
+	commentService *CommentService
+	userService    *UserService
+}
+
+// NewSearchService creates a new SearchService
+func NewSearchService(ts *TaskService, cs *CommentService, us *UserService) *SearchService {
+	return &SearchService{
+		taskService:    ts,
+		commentService: cs,
+		userService:    us,
+	}
+}
+
+// Search matches the query, case-insensitively and literally, against task titles and
+// descriptions, comment bodies and user names and emails. Callers without 'task:read_all' only
+// find the tasks they can list, and the comments on them; users are only searched for callers
+// with 'user:read_all'.
+func (s *SearchService) Search(ctx context.Context, authContext *models.AuthContext, req SearchRequest) (*models.SearchResponse, error) {
+	term := strings.TrimSpace(req.Query)
+	if n := utf8.RuneCountInString(term); n < minSearchQueryLength || n > maxSearchQueryLength {
+		return nil, ErrInvalidSearchQuery
+	}
+
+	types := map[string]bool{}
+	for _, t := range req.Types {
+		if !slices.Contains(SearchTypes, t) {
+			return nil, ErrInvalidSearchType
+		}
+		types[t] = true
+	}
+	wanted := func(t string) bool { return len(types) == 0 || types[t] }
+	page := func(t string) *query.Query {
+		if q, ok := req.Pages[t]; ok {
+			return q
+		}
+		return query.New(nil, 1, 0)
+	}
+	pattern := primitive.Regex{Pattern: regexp.QuoteMeta(term), Options: "i"}
+
+	visible := VisibleTasksFilter(authContext)
+	response := &models.SearchResponse{Query: term}
+	if wanted(SearchTypeTasks) {
+		q := page(SearchTypeTasks)
+		q.Filter = bson.M{"$or": []bson.M{{"title": pattern}, {"description": pattern}}, "archived": bson.M{"$ne": true}}
+		if visible != nil {
+			q.Filter = bson.M{"$and": []bson.M{q.Filter, visible}}
+		}
+		tasks, err := s.taskService.ListTasks(ctx, q, "")
+		if err != nil {
+			return nil, err
+		}
+		if tasks.Tasks == nil {
+			tasks.Tasks = []models.Task{}
+		}
+		response.Tasks = tasks
+	}
+	if wanted(SearchTypeComments) {
+		q := page(SearchTypeComments)
+		q.Filter = bson.M{"body": pattern}
+		if visible != nil {
+			taskIDs, err := s.visibleTaskIDs(ctx, visible)
+			if err != nil {
+				return nil, err
+			}
+			q.Filter["task_id"] = bson.M{"$in": taskIDs}
+		}
+		comments, err := s.commentService.SearchComments(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		response.Comments = comments
+	}
+	if wanted(SearchTypeUsers) && authContext.HasPermission("user:read_all") {
+		q := page(SearchTypeUsers)
+		q.Filter = bson.M{"$or": []bson.M{{"first_name": pattern}, {"last_name": pattern}, {"email": pattern}}}
+		users, err := s.userService.ListUsers(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		response.Users = users
+	}
+	return response, nil
+}
+
+// Suggest returns the tasks whose title starts with prefix, ignoring case, to complete what the
+// caller is typing. Like Search, it only looks at the tasks the caller can list without
+// 'task:read_all' and leaves out those of archived projects. limit defaults to 10 and is capped at 20.
+func (s *SearchService) Suggest(ctx context.Context, authContext *models.AuthContext, prefix string, limit int64) (*models.TaskSuggestResponse, error) {
+	prefix = strings.TrimLeft(prefix, " \t")
+	if n := utf8.RuneCountInString(prefix); n < 1 || n > maxSearchQueryLength {
+		return nil, ErrInvalidSuggestQuery
+	}
+	if limit <= 0 {
+		limit = defaultSuggestLimit
+	}
+	limit = min(limit, maxSuggestLimit)
+
+	ctx, cancel := context.WithTimeout(ctx, suggestTimeout)
+	defer cancel()
+
+	filter := bson.M{"archived": bson.M{"$ne": true}}
+	for field, condition := range VisibleTasksFilter(authContext) {
+		filter[field] = condition
+	}
+	suggestions, err := s.taskService.SuggestTitles(ctx, filter, prefix, limit)
+	if err != nil {
+		return nil, err
+	}
+	if suggestions == nil {
+		suggestions = []models.TaskSuggestion{}
+	}
+	return &models.TaskSuggestResponse{Query: prefix, Suggestions: suggestions}, nil
+}
+
+// visibleTaskIDs returns the IDs of the unarchived tasks matching visible, the tasks a caller
+// without 'task:read_all' can list, so their comments can be searched
+func (s *SearchService) visibleTaskIDs(ctx context.Context, visible bson.M) ([]primitive.ObjectID, error) {
+	q := query.New(bson.M{"$and": []bson.M{visible, {"archived": bson.M{"$ne": true}}}}, 1, 0)
+	q.Projection = bson.M{"_id": 1}
+	ids := []primitive.ObjectID{}
+	err := s.taskService.EachTask(ctx, q, "", func(task *models.Task) error {
+		ids = append(ids, task.ID)
+		return nil
+	})
+	return ids, err
+}
@@ -0,0 +1,435 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/OsGift/taskflow-api/internal/logging"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+const (
+	// serviceAccountEmailDomain gives service accounts the unique email every user needs. The
+	// .invalid top-level domain is reserved, so nothing can ever be delivered to it.
+	serviceAccountEmailDomain = "service-accounts.invalid"
+	// apiKeyPrefixLength is how much of a key is kept in clear to tell keys apart
+	apiKeyPrefixLength = 12
+	// apiKeyUsageInterval is how often the last use of a key is recorded
+	apiKeyUsageInterval = time.Minute
+	// maxAPIKeyUsageDays is how far back key usage is kept and can be reported
+	maxAPIKeyUsageDays = 90
+)
+
+// RateLimit is the state of a key's rate limit after a request, reported in response headers
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time // When the current window ends
+}
+
+// ServiceAccountService manages service accounts, the non-human users that CI systems and bots
+// call the API as. A service account is a user with a role like any other, but it has no
+// password or verified email flow and authenticates with API keys instead.
+//
+// Every request made with a key is counted per minute and per hour; a key making more requests
+// in a minute than its rate limit allows is refused until the next minute.
+type ServiceAccountService struct {
+	keyCollection    repository.Collection
+	usageCollection  repository.Collection
+	userService      *UserService
+	defaultRateLimit atomic.Int64 // Requests per minute for keys without their own limit; 0 means unlimited
+}
+
+// NewServiceAccountService creates a new ServiceAccountService
+func NewServiceAccountService(db repository.Documents, us *UserService, defaultRateLimit int) *ServiceAccountService {
+	s := &ServiceAccountService{
+		keyCollection:   db.Collection("api_keys"),
+		usageCollection: db.Collection("api_key_usage"),
+		userService:     us,
+	}
+	s.defaultRateLimit.Store(int64(defaultRateLimit))
+	return s
+}
+
+// SetDefaultRateLimit changes the requests per minute allowed for keys without their own
+// limit; 0 means unlimited
+func (s *ServiceAccountService) SetDefaultRateLimit(limit int) {
+	s.defaultRateLimit.Store(int64(limit))
+}
+
+// CreateServiceAccount creates a service account with the named role
+func (s *ServiceAccountService) CreateServiceAccount(ctx context.Context, req models.CreateServiceAccountRequest) (*models.UserResponse, error) {
+	role, err := s.userService.GetRoleByName(ctx, req.RoleName)
+	if err != nil {
+		return nil, err
+	}
+	return s.userService.CreateUser(ctx, &models.User{
+		FirstName:        req.Name,
+		LastName:         "Service account",
+		Email:            "sa-" + primitive.NewObjectID().Hex() + "@" + serviceAccountEmailDomain,
+		RoleID:           role.ID,
+		IsEmailVerified:  true,
+		IsServiceAccount: true,
+	})
+}
+
+// ListServiceAccounts returns one page of service accounts matching the query
+func (s *ServiceAccountService) ListServiceAccounts(ctx context.Context, q *query.Query) (*models.UserListResponse, error) {
+	q.Filter["is_service_account"] = true
+	return s.userService.ListUsers(ctx, q)
+}
+
+// GetServiceAccount retrieves a service account by ID
+func (s *ServiceAccountService) GetServiceAccount(ctx context.Context, id string) (*models.User, error) {
+	user, err := s.userService.GetUserByID(ctx, id)
+	if err == ErrUserNotFound || (err == nil && !user.IsServiceAccount) {
+		return nil, ErrServiceAccountNotFound
+	}
+	return user, err
+}
+
+// DeleteServiceAccount revokes a service account's keys and deletes it like any user,
+// deleting its tasks or handing them over to reassignToID
+func (s *ServiceAccountService) DeleteServiceAccount(ctx context.Context, id, reassignToID string) error {
+	account, err := s.GetServiceAccount(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.userService.DeleteUser(ctx, id, reassignToID); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	_, err = s.keyCollection.DeleteMany(ctx, bson.M{"service_account_id": account.ID})
+	return err
+}
+
+// CreateKey issues a new API key for a service account. The key is only returned here.
+func (s *ServiceAccountService) CreateKey(ctx context.Context, accountID string, req models.CreateAPIKeyRequest, createdBy primitive.ObjectID) (*models.CreateAPIKeyResponse, error) {
+	account, err := s.GetServiceAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	key := models.APIKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	now := time.Now()
+	apiKey := models.APIKey{
+		ID:                 primitive.NewObjectID(),
+		ServiceAccountID:   account.ID,
+		Name:               req.Name,
+		Prefix:             key[:apiKeyPrefixLength],
+		Hash:               hashAPIKey(key),
+		CreatedBy:          createdBy,
+		CreatedAt:          now,
+		RateLimitPerMinute: req.RateLimitPerMinute,
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := now.AddDate(0, 0, req.ExpiresInDays)
+		apiKey.ExpiresAt = &expiresAt
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if _, err := s.keyCollection.InsertOne(ctx, apiKey); err != nil {
+		return nil, err
+	}
+	return &models.CreateAPIKeyResponse{APIKey: apiKey, Key: key}, nil
+}
+
+// ListKeys returns the keys of a service account, newest first
+func (s *ServiceAccountService) ListKeys(ctx context.Context, accountID string) (*models.APIKeyListResponse, error) {
+	account, err := s.GetServiceAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := s.keyCollection.Find(ctx, bson.M{"service_account_id": account.ID}, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	keys := []models.APIKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+	return &models.APIKeyListResponse{Keys: keys}, nil
+}
+
+// UpdateKey renames a key of a service account or changes its rate limit
+func (s *ServiceAccountService) UpdateKey(ctx context.Context, accountID, keyID string, req models.UpdateAPIKeyRequest) (*models.APIKey, error) {
+	key, err := s.findKey(ctx, accountID, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	set, unset := bson.M{}, bson.M{}
+	if req.Name != nil {
+		set["name"] = *req.Name
+	}
+	if req.RateLimitPerMinute != nil {
+		if *req.RateLimitPerMinute > 0 {
+			set["rate_limit_per_minute"] = *req.RateLimitPerMinute
+		} else {
+			unset["rate_limit_per_minute"] = ""
+		}
+	}
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	if len(update) == 0 {
+		return key, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var updated models.APIKey
+	err = s.keyCollection.FindOneAndUpdate(ctx, bson.M{"_id": key.ID}, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrAPIKeyNotFound // Revoked meanwhile
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// findKey retrieves a key of a service account
+func (s *ServiceAccountService) findKey(ctx context.Context, accountID, keyID string) (*models.APIKey, error) {
+	account, err := s.GetServiceAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	keyObjID, err := primitive.ObjectIDFromHex(keyID)
+	if err != nil {
+		return nil, ErrInvalidAPIKeyID
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var key models.APIKey
+	if err := s.keyCollection.FindOne(ctx, bson.M{"_id": keyObjID, "service_account_id": account.ID}).Decode(&key); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+// RevokeKey deletes a key of a service account; requests using it fail from then on
+func (s *ServiceAccountService) RevokeKey(ctx context.Context, accountID, keyID string) error {
+	account, err := s.GetServiceAccount(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	keyObjID, err := primitive.ObjectIDFromHex(keyID)
+	if err != nil {
+		return ErrInvalidAPIKeyID
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := s.keyCollection.DeleteOne(ctx, bson.M{"_id": keyObjID, "service_account_id": account.ID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// Authenticate resolves an API key to the AuthContext of its service account and counts the
+// request against the key's rate limit. The returned RateLimit is nil when the key is unlimited
+// or the request couldn't be counted; ErrAPIKeyRateLimited is returned with it when the limit
+// is exceeded.
+func (s *ServiceAccountService) Authenticate(ctx context.Context, key string) (*models.AuthContext, *RateLimit, error) {
+	if !strings.HasPrefix(key, models.APIKeyPrefix) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var apiKey models.APIKey
+	if err := s.keyCollection.FindOne(lookupCtx, bson.M{"hash": hashAPIKey(key)}).Decode(&apiKey); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil, ErrInvalidAPIKey
+		}
+		return nil, nil, err
+	}
+	now := time.Now()
+	if apiKey.ExpiresAt != nil && now.After(*apiKey.ExpiresAt) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	authContext, err := s.userService.GetAuthContext(ctx, apiKey.ServiceAccountID, primitive.NilObjectID)
+	if err == ErrUserNotFound {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	authContext.APIKeyID = apiKey.ID
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyUsageInterval {
+		if _, err := s.keyCollection.UpdateOne(lookupCtx, bson.M{"_id": apiKey.ID}, bson.M{"$set": bson.M{"last_used_at": now}}); err != nil {
+			logging.Warnf("Failed to record the use of API key %s: %v", apiKey.ID.Hex(), err)
+		}
+	}
+
+	rateLimit, err := s.countRequest(lookupCtx, &apiKey, now)
+	if err != nil {
+		return nil, rateLimit, err
+	}
+	return authContext, rateLimit, nil
+}
+
+// countRequest records a request made with key and checks it against the key's rate limit.
+// Counting failures are logged rather than returned, so a database hiccup doesn't lock every
+// integration out.
+func (s *ServiceAccountService) countRequest(ctx context.Context, key *models.APIKey, now time.Time) (*RateLimit, error) {
+	now = now.UTC()
+	minute := fmt.Sprintf("%02d", now.Minute())
+	filter := bson.M{"key_id": key.ID, "hour": now.Truncate(time.Hour)}
+	update := bson.M{"$inc": bson.M{"requests": 1, "minutes." + minute: 1}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var usage models.APIKeyUsage
+	err := s.usageCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&usage)
+	if mongo.IsDuplicateKeyError(err) {
+		// Another request created the hour's document first; it exists now
+		err = s.usageCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&usage)
+	}
+	if err != nil {
+		logging.Warnf("Failed to count a request of API key %s: %v", key.ID.Hex(), err)
+		return nil, nil
+	}
+
+	limit := s.rateLimitOf(key)
+	if limit == 0 {
+		return nil, nil
+	}
+	count := usage.Minutes[minute]
+	rateLimit := &RateLimit{
+		Limit:     limit,
+		Remaining: max(limit-int(count), 0),
+		Reset:     now.Truncate(time.Minute).Add(time.Minute),
+	}
+	if count <= int64(limit) {
+		return rateLimit, nil
+	}
+	if _, err := s.usageCollection.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"rejected": 1}}); err != nil {
+		logging.Warnf("Failed to count a rejected request of API key %s: %v", key.ID.Hex(), err)
+	}
+	return rateLimit, ErrAPIKeyRateLimited
+}
+
+// rateLimitOf returns the requests per minute allowed for key; 0 means unlimited
+func (s *ServiceAccountService) rateLimitOf(key *models.APIKey) int {
+	if key.RateLimitPerMinute > 0 {
+		return key.RateLimitPerMinute
+	}
+	return int(s.defaultRateLimit.Load())
+}
+
+// KeyUsage reports the requests made with a key of a service account over the past days
+func (s *ServiceAccountService) KeyUsage(ctx context.Context, accountID, keyID string, days int) (*models.APIKeyUsageResponse, error) {
+	key, err := s.findKey(ctx, accountID, keyID)
+	if err != nil {
+		return nil, err
+	}
+	return s.usage(ctx, key, days)
+}
+
+// CurrentKeyUsage reports the requests made over the past days with the key the caller
+// authenticated with, so integrations can monitor their own consumption
+func (s *ServiceAccountService) CurrentKeyUsage(ctx context.Context, authContext *models.AuthContext, days int) (*models.APIKeyUsageResponse, error) {
+	if authContext.APIKeyID.IsZero() {
+		return nil, ErrNotAPIKeyRequest
+	}
+	return s.KeyUsage(ctx, authContext.UserID.Hex(), authContext.APIKeyID.Hex(), days)
+}
+
+// usage adds up the hourly usage of key by UTC day
+func (s *ServiceAccountService) usage(ctx context.Context, key *models.APIKey, days int) (*models.APIKeyUsageResponse, error) {
+	if days < 1 || days > maxAPIKeyUsageDays {
+		return nil, ErrInvalidUsageDays
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	from := today.AddDate(0, 0, 1-days)
+	cursor, err := s.usageCollection.Find(ctx, bson.M{"key_id": key.ID, "hour": bson.M{"$gte": from}},
+		options.Find().SetProjection(bson.M{"minutes": 0}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	response := &models.APIKeyUsageResponse{
+		KeyID:              key.ID,
+		Name:               key.Name,
+		RateLimitPerMinute: s.rateLimitOf(key),
+		LastUsedAt:         key.LastUsedAt,
+		Days:               make([]models.APIKeyUsageDay, days),
+	}
+	for i := range response.Days {
+		response.Days[i].Date = from.AddDate(0, 0, i).Format("2006-01-02")
+	}
+	for cursor.Next(ctx) {
+		var usage models.APIKeyUsage
+		if err := cursor.Decode(&usage); err != nil {
+			return nil, err
+		}
+		i := int(usage.Hour.UTC().Sub(from) / (24 * time.Hour))
+		if i < 0 || i >= days {
+			continue
+		}
+		response.Days[i].Requests += usage.Requests
+		response.Days[i].Rejected += usage.Rejected
+		response.Requests += usage.Requests
+		response.Rejected += usage.Rejected
+	}
+	return response, cursor.Err()
+}
+
+// hashAPIKey returns the hash an API key is stored and looked up by. Keys are long and random,
+// so a fast hash is enough.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
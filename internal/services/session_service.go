@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/repository"
+	"github.com/OsGift/taskflow-api/internal/utils"
+)
+
+// refreshReuseGrace is how long after a refresh its token may be sent again without revoking
+// the session, so that tabs refreshing at the same time don't log each other out
+const refreshReuseGrace = 10 * time.Second
+
+// SessionService issues the short-lived access tokens and rotating refresh tokens of cookie
+// sessions. Refresh tokens are stored in the "refresh_tokens" collection, where a TTL index
+// removes them once expired.
+type SessionService struct {
+	refreshCollection repository.Collection
+	userService       *UserService
+	jwtSecret         []byte
+	accessTTL         time.Duration
+	refreshTTL        time.Duration
+}
+
+// NewSessionService creates a new SessionService; access tokens expire after accessTTL and
+// refresh tokens after refreshTTL without being used
+func NewSessionService(db repository.Documents, us *UserService, jwtSecret []byte, accessTTL, refreshTTL time.Duration) *SessionService {
+	return &SessionService{
+		refreshCollection: db.Collection("refresh_tokens"),
+		userService:       us,
+		jwtSecret:         jwtSecret,
+		accessTTL:         accessTTL,
+		refreshTTL:        refreshTTL,
+	}
+}
+
+// StartSession starts a session for a user who just logged in
+func (s *SessionService) StartSession(ctx context.Context, userID string) (*models.Session, error) {
+	user, err := s.userService.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.issue(ctx, user, primitive.NewObjectID())
+}
+
+// RefreshSession exchanges a refresh token for a new access token and a new refresh token.
+// A token that was already exchanged revokes the session, unless it was exchanged moments
+// ago by a concurrent request.
+func (s *SessionService) RefreshSession(ctx context.Context, refreshToken string) (*models.Session, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	hash := hashToken(refreshToken)
+	var stored models.RefreshToken
+	err := s.refreshCollection.FindOneAndUpdate(ctx,
+		bson.M{"hash": hash, "used_at": bson.M{"$exists": false}, "expires_at": bson.M{"$gt": now}},
+		bson.M{"$set": bson.M{"used_at": now}},
+	).Decode(&stored)
+	if err == mongo.ErrNoDocuments {
+		var used models.RefreshToken
+		err = s.refreshCollection.FindOne(ctx, bson.M{"hash": hash}).Decode(&used)
+		if err == nil && used.UsedAt != nil && now.Sub(*used.UsedAt) > refreshReuseGrace {
+			if _, err := s.refreshCollection.DeleteMany(ctx, bson.M{"family_id": used.FamilyID}); err != nil {
+				return nil, err
+			}
+		} else if err != nil && err != mongo.ErrNoDocuments {
+			return nil, err
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userService.GetUserByID(ctx, stored.UserID.Hex())
+	if err == ErrUserNotFound {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.Disabled {
+		return nil, ErrAccountDisabled
+	}
+	return s.issue(ctx, user, stored.FamilyID)
+}
+
+// RevokeSession ends the session a refresh token belongs to. Unknown tokens are ignored.
+func (s *SessionService) RevokeSession(ctx context.Context, refreshToken string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var stored models.RefreshToken
+	err := s.refreshCollection.FindOne(ctx, bson.M{"hash": hashToken(refreshToken)},
+		options.FindOne().SetProjection(bson.M{"family_id": 1})).Decode(&stored)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = s.refreshCollection.DeleteMany(ctx, bson.M{"family_id": stored.FamilyID})
+	return err
+}
+
+// RevokeAllForUser ends every session of a user, e.g. once their password changed or their
+// account was disabled. Access tokens already issued stay valid until they expire.
+func (s *SessionService) RevokeAllForUser(ctx context.Context, userID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := s.refreshCollection.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
+
+// issue creates an access token for user and a refresh token in the given family
+func (s *SessionService) issue(ctx context.Context, user *models.User, familyID primitive.ObjectID) (*models.Session, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	accessToken, err := utils.GenerateTokenWithTTL(user.ID, user.Email, user.RoleID, s.jwtSecret, s.accessTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(secret)
+	stored := models.RefreshToken{
+		ID:        primitive.NewObjectID(),
+		Hash:      hashToken(refreshToken),
+		FamilyID:  familyID,
+		UserID:    user.ID,
+		ExpiresAt: now.Add(s.refreshTTL),
+		CreatedAt: now,
+	}
+	if _, err := s.refreshCollection.InsertOne(ctx, stored); err != nil {
+		return nil, err
+	}
+
+	return &models.Session{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  now.Add(s.accessTTL),
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: stored.ExpiresAt,
+	}, nil
+}
+
+// hashToken returns the hex SHA-256 refresh tokens and emailed tokens are stored as
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
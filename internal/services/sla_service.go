@@ -0,0 +1,394 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/OsGift/taskflow-api/internal/jobs"
+	"github.com/OsGift/taskflow-api/internal/logging"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+const (
+	// slaBreachedTemplate is the email template escalating an SLA breach to managers
+	slaBreachedTemplate = "sla_breached"
+	slaBreachedSubject  = "TaskFlow: SLA breached"
+)
+
+// SLAService stores the SLA rules configured by administrators and runs the job that checks
+// tasks against them. A task breaching a rule is flagged, and every manager is told: the users
+// with the Manager role and, for project tasks, the project's managers.
+type SLAService struct {
+	ruleCollection    repository.Collection
+	projectCollection repository.Collection
+	tasks             repository.TaskRepository
+	users             repository.UserRepository
+	roles             repository.RoleRepository
+	taskService       *TaskService
+	jobQueue          *jobs.Queue
+	notifications     *NotificationService
+	enabled           atomic.Bool
+	interval          time.Duration
+}
+
+// NewSLAService creates an SLAService checking tasks every interval. When enabled is false,
+// checks that were already queued do nothing and aren't rescheduled.
+func NewSLAService(db repository.Documents, store *repository.Store, ts *TaskService, jq *jobs.Queue, ns *NotificationService, enabled bool, interval time.Duration) *SLAService {
+	s := &SLAService{
+		ruleCollection:    db.Collection("sla_rules"),
+		projectCollection: db.Collection("projects"),
+		tasks:             store.Tasks,
+		users:             store.Users,
+		roles:             store.Roles,
+		taskService:       ts,
+		jobQueue:          jq,
+		notifications:     ns,
+		interval:          interval,
+	}
+	s.enabled.Store(enabled)
+	return s
+}
+
+// SetEnabled turns the checks on or off, queuing the next one when turning them on
+func (s *SLAService) SetEnabled(ctx context.Context, enabled bool) error {
+	if s.enabled.Swap(enabled) || !enabled {
+		return nil
+	}
+	return s.Schedule(ctx)
+}
+
+// Schedule makes sure the next SLA check is queued
+func (s *SLAService) Schedule(ctx context.Context) error {
+	return jobs.ScheduleNext(ctx, s.jobQueue, jobs.TypeSLACheck, s.NextRun)
+}
+
+// NextRun is the jobs.NextRunFunc of TypeSLACheck: checks run every interval on the clock
+// while enabled
+func (s *SLAService) NextRun(ctx context.Context, run jobs.RunPayload) (time.Time, bool, error) {
+	return jobs.NextInterval(run.RunAt, s.interval), s.enabled.Load(), nil
+}
+
+// ListRules returns every SLA rule, oldest first
+func (s *SLAService) ListRules(ctx context.Context) (*models.SLARuleListResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := s.ruleCollection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	rules := []models.SLARule{}
+	if err := cursor.All(ctx, &rules); err != nil {
+		return nil, err
+	}
+	return &models.SLARuleListResponse{Rules: rules}, nil
+}
+
+// GetRule retrieves an SLA rule by its ID
+func (s *SLAService) GetRule(ctx context.Context, id string) (*models.SLARule, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrInvalidSLARuleID
+	}
+
+	var rule models.SLARule
+	if err := s.ruleCollection.FindOne(ctx, bson.M{"_id": objID}).Decode(&rule); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrSLARuleNotFound
+		}
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// CreateRule stores a new SLA rule, which applies from the next check
+func (s *SLAService) CreateRule(ctx context.Context, req *models.SLARuleRequest, createdBy primitive.ObjectID) (*models.SLARule, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	rule := &models.SLARule{
+		ID:        primitive.NewObjectID(),
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := applySLARuleRequest(rule, req); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.ruleCollection.InsertOne(ctx, rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// UpdateRule replaces the settings of an SLA rule. Tasks already flagged for breaching it stay
+// flagged until they change status.
+func (s *SLAService) UpdateRule(ctx context.Context, id string, req *models.SLARuleRequest) (*models.SLARule, error) {
+	rule, err := s.GetRule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := applySLARuleRequest(rule, req); err != nil {
+		return nil, err
+	}
+	rule.UpdatedAt = time.Now()
+
+	result, err := s.ruleCollection.ReplaceOne(ctx, bson.M{"_id": rule.ID}, rule)
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, ErrSLARuleNotFound
+	}
+	return rule, nil
+}
+
+// DeleteRule deletes an SLA rule; tasks flagged for breaching it stay flagged until they
+// change status
+func (s *SLAService) DeleteRule(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrInvalidSLARuleID
+	}
+
+	result, err := s.ruleCollection.DeleteOne(ctx, bson.M{"_id": objID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrSLARuleNotFound
+	}
+	return nil
+}
+
+// RunSLACheck handles a TypeSLACheck run: it flags the tasks breaching an enabled rule and
+// escalates them to the managers. Rules are checked tightest first, and a flagged task isn't
+// checked again until it changes status, so a task breaching several rules is escalated once. Notifications are keyed by task and status change, so a
+// retried check doesn't notify anyone twice.
+func (s *SLAService) RunSLACheck(ctx context.Context, run jobs.RunPayload) error {
+	cursor, err := s.ruleCollection.Find(ctx, bson.M{"enabled": true}, options.Find().SetSort(bson.D{{Key: "max_hours", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return err
+	}
+	var rules []models.SLARule
+	if err := cursor.All(ctx, &rules); err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+
+	managers, err := s.managers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to look up managers: %w", err)
+	}
+	projects := map[primitive.ObjectID]*models.Project{}
+
+	var breached int64
+	now := time.Now()
+	for _, rule := range rules {
+		count, err := s.checkRule(ctx, &rule, managers, projects, now)
+		if err != nil {
+			return fmt.Errorf("failed to check SLA rule %s: %w", rule.ID.Hex(), err)
+		}
+		breached += count
+	}
+
+	log.Printf("SLA check: %d tasks breached %d rules", breached, len(rules))
+	return nil
+}
+
+// checkRule escalates the unflagged tasks that have been in the rule's status for longer than
+// it allows, then flags them. It returns how many tasks were flagged.
+func (s *SLAService) checkRule(ctx context.Context, rule *models.SLARule, managers []models.User, projects map[primitive.ObjectID]*models.Project, now time.Time) (int64, error) {
+	cutoff := now.Add(-time.Duration(rule.MaxHours) * time.Hour)
+	filter := bson.M{
+		"status":          string(rule.Status),
+		"archived":        bson.M{"$ne": true},
+		"sla_breached_at": nil,
+		// Tasks saved before status changes were recorded count from their creation
+		"$or": []bson.M{
+			{"status_changed_at": bson.M{"$lt": cutoff}},
+			{"status_changed_at": nil, "created_at": bson.M{"$lt": cutoff}},
+		},
+	}
+	if rule.Priority != "" {
+		filter["priority"] = string(rule.Priority)
+	}
+	if rule.ProjectID != nil {
+		filter["project_id"] = *rule.ProjectID
+	}
+	q := query.New(filter, 1, 0)
+	q.Sort = bson.D{{Key: "_id", Value: 1}}
+
+	var ids []primitive.ObjectID
+	err := s.tasks.EachMatching(ctx, q, func(task *models.Task) error {
+		recipients, err := s.recipients(ctx, task, managers, projects)
+		if err != nil {
+			return err
+		}
+		for _, user := range recipients {
+			if err := s.escalate(ctx, rule, task, &user, now); err != nil {
+				logging.Warnf("Failed to notify %s of the SLA breach of task %s: %v", user.Email, task.ID.Hex(), err)
+			}
+		}
+		ids = append(ids, task.ID)
+		return nil
+	})
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	return s.taskService.MarkSLABreached(ctx, bson.M{"_id": bson.M{"$in": ids}, "sla_breached_at": nil}, rule.ID)
+}
+
+// managers returns the users with the Manager role. Breaches are escalated to their project's
+// managers as well, so a missing Manager role isn't an error.
+func (s *SLAService) managers(ctx context.Context) ([]models.User, error) {
+	role, err := s.roles.FindByName(ctx, "Manager")
+	if err == repository.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.users.List(ctx, &query.Query{Filter: primitive.M{"role_id": role.ID}, Page: 1, Limit: 100})
+}
+
+// recipients returns who to escalate a breach of task to: managers, and for a project task the
+// project's owner and managers. Disabled users are left out. Projects are looked up once per
+// check through projects.
+func (s *SLAService) recipients(ctx context.Context, task *models.Task, managers []models.User, projects map[primitive.ObjectID]*models.Project) ([]models.User, error) {
+	seen := map[primitive.ObjectID]bool{}
+	var recipients []models.User
+	add := func(user *models.User) {
+		if !seen[user.ID] && !user.Disabled {
+			seen[user.ID] = true
+			recipients = append(recipients, *user)
+		}
+	}
+	for _, manager := range managers {
+		add(&manager)
+	}
+	if task.ProjectID == nil {
+		return recipients, nil
+	}
+
+	project, ok := projects[*task.ProjectID]
+	if !ok {
+		project = &models.Project{}
+		err := s.projectCollection.FindOne(ctx, bson.M{"_id": *task.ProjectID}).Decode(project)
+		if err == mongo.ErrNoDocuments {
+			project = nil
+		} else if err != nil {
+			return nil, err
+		}
+		projects[*task.ProjectID] = project
+	}
+	if project == nil {
+		return recipients, nil
+	}
+
+	projectManagers := []primitive.ObjectID{project.OwnerID}
+	for _, member := range project.Members {
+		if member.Role == models.ProjectRoleManager {
+			projectManagers = append(projectManagers, member.UserID)
+		}
+	}
+	for _, id := range projectManagers {
+		if seen[id] {
+			continue
+		}
+		user, err := s.users.FindByID(ctx, id)
+		if err == repository.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		add(user)
+	}
+	return recipients, nil
+}
+
+// escalate tells one manager that task breached rule
+func (s *SLAService) escalate(ctx context.Context, rule *models.SLARule, task *models.Task, user *models.User, now time.Time) error {
+	enteredAt := task.CreatedAt
+	if task.StatusChangedAt != nil {
+		enteredAt = *task.StatusChangedAt
+	}
+	status := strings.ReplaceAll(string(rule.Status), "_", " ")
+	taskLink := fmt.Sprintf("http://localhost:3000/tasks/%s", task.ID.Hex()) // Frontend task URL
+
+	ownerEmail := "unknown user"
+	if owner, err := s.users.FindByID(ctx, task.UserID); err == nil {
+		ownerEmail = owner.Email
+	}
+
+	emailData := map[string]interface{}{
+		"FirstName":  user.FirstName,
+		"TaskTitle":  task.Title,
+		"OwnerEmail": ownerEmail,
+		"RuleName":   rule.Name,
+		"Status":     status,
+		"MaxHours":   rule.MaxHours,
+		"EnteredAt":  enteredAt.In(user.Location()).Format("Mon, Jan 2 15:04 MST"),
+		"TaskLink":   taskLink,
+		"Year":       now.Year(),
+	}
+	_, err := s.notifications.Notify(ctx, &Notice{
+		Event:    models.EventSLABreached,
+		User:     user,
+		Title:    "SLA breached",
+		Body:     fmt.Sprintf("%q has been %s for more than %d hours, breaching %q.", task.Title, status, rule.MaxHours, rule.Name),
+		Link:     taskLink,
+		Template: slaBreachedTemplate,
+		Subject:  slaBreachedSubject,
+		Data:     emailData,
+		Key:      fmt.Sprintf("%s:%s:%s:%s", models.EventSLABreached, task.ID.Hex(), enteredAt.UTC().Format(time.RFC3339), user.ID.Hex()),
+	})
+	return err
+}
+
+// applySLARuleRequest copies req onto rule
+func applySLARuleRequest(rule *models.SLARule, req *models.SLARuleRequest) error {
+	rule.ProjectID = nil
+	if req.ProjectID != "" {
+		projectID, err := primitive.ObjectIDFromHex(req.ProjectID)
+		if err != nil {
+			return ErrInvalidProjectID
+		}
+		rule.ProjectID = &projectID
+	}
+	rule.Name = req.Name
+	rule.Status = models.TaskStatus(req.Status)
+	rule.MaxHours = req.MaxHours
+	rule.Priority = models.TaskPriority(req.Priority)
+	rule.Enabled = req.Enabled == nil || *req.Enabled
+	return nil
+}
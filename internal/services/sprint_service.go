@@ -0,0 +1,308 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+// SprintService stores the sprints of projects. Tasks are planned into a sprint; closing it
+// rolls the unfinished ones over to the next sprint.
+type SprintService struct {
+	sprintCollection repository.Collection
+	tasks            repository.TaskRepository
+	taskService      *TaskService
+}
+
+// NewSprintService creates a new SprintService
+func NewSprintService(db repository.Documents, store *repository.Store, ts *TaskService) *SprintService {
+	return &SprintService{
+		sprintCollection: db.Collection("sprints"),
+		tasks:            store.Tasks,
+		taskService:      ts,
+	}
+}
+
+// CreateSprint creates a sprint in a project
+func (s *SprintService) CreateSprint(ctx context.Context, projectID, createdBy primitive.ObjectID, req *models.CreateSprintRequest) (*models.Sprint, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	sprint := &models.Sprint{
+		ID:        primitive.NewObjectID(),
+		ProjectID: projectID,
+		Name:      req.Name,
+		Goal:      req.Goal,
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if _, err := s.sprintCollection.InsertOne(ctx, sprint); err != nil {
+		return nil, err
+	}
+	return sprint, nil
+}
+
+// ListSprints retrieves the sprints of a project matching the query
+func (s *SprintService) ListSprints(ctx context.Context, projectID primitive.ObjectID, q *query.Query) (*models.SprintListResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	q.Filter["project_id"] = projectID
+	cursor, err := s.sprintCollection.Find(ctx, q.Filter, q.FindOptions())
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	sprints := []models.Sprint{}
+	if err = cursor.All(ctx, &sprints); err != nil {
+		return nil, err
+	}
+
+	totalCount, err := s.sprintCollection.CountDocuments(ctx, q.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SprintListResponse{
+		Sprints:    sprints,
+		Pagination: models.NewPagination(totalCount, q.Page, q.Limit),
+	}, nil
+}
+
+// GetSprint retrieves a sprint by its ID
+func (s *SprintService) GetSprint(ctx context.Context, idHex string) (*models.Sprint, error) {
+	id, err := primitive.ObjectIDFromHex(idHex)
+	if err != nil {
+		return nil, ErrInvalidSprintID
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var sprint models.Sprint
+	err = s.sprintCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&sprint)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrSprintNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sprint, nil
+}
+
+// UpdateSprint changes the name, goal or dates of an open sprint
+func (s *SprintService) UpdateSprint(ctx context.Context, sprint *models.Sprint, req *models.UpdateSprintRequest) (*models.Sprint, error) {
+	if sprint.Closed {
+		return nil, ErrSprintClosed
+	}
+
+	fields := bson.M{"updated_at": time.Now()}
+	if req.Name != nil {
+		fields["name"] = *req.Name
+	}
+	if req.Goal != nil {
+		fields["goal"] = *req.Goal
+	}
+	start, end := sprint.StartDate, sprint.EndDate
+	if req.StartDate != nil {
+		start = *req.StartDate
+		fields["start_date"] = start
+	}
+	if req.EndDate != nil {
+		end = *req.EndDate
+		fields["end_date"] = end
+	}
+	if !end.After(start) {
+		return nil, ErrInvalidSprintDates
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var updated models.Sprint
+	err := s.sprintCollection.FindOneAndUpdate(ctx, bson.M{"_id": sprint.ID, "closed": false}, bson.M{"$set": fields},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrSprintClosed // Closed since it was read, or deleted
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// CloseSprint closes an open sprint and moves its unfinished tasks to nextSprintIDHex, or
+// when it is empty to the project's next open sprint by start date. Without one they go back
+// to the project's backlog.
+func (s *SprintService) CloseSprint(ctx context.Context, sprint *models.Sprint, nextSprintIDHex string) (*models.Sprint, error) {
+	if sprint.Closed {
+		return nil, ErrSprintClosed
+	}
+	next, err := s.nextSprint(ctx, sprint, nextSprintIDHex)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	// The sprint is closed first so that no tasks are added to it while they are moved out
+	now := time.Now()
+	set := bson.M{"closed": true, "closed_at": now, "updated_at": now}
+	var nextID *primitive.ObjectID
+	if next != nil {
+		nextID = &next.ID
+		set["rolled_over_to"] = next.ID
+	}
+	result, err := s.sprintCollection.UpdateOne(ctx, bson.M{"_id": sprint.ID, "closed": false}, bson.M{"$set": set})
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, ErrSprintClosed
+	}
+
+	count, err := s.taskService.RollOverSprint(ctx, sprint.ID, nextID)
+	if err != nil {
+		return nil, err
+	}
+
+	var closed models.Sprint
+	err = s.sprintCollection.FindOneAndUpdate(ctx, bson.M{"_id": sprint.ID}, bson.M{"$set": bson.M{"rolled_over_count": count}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&closed)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrSprintNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &closed, nil
+}
+
+// nextSprint returns the sprint the unfinished tasks of sprint roll over to: the one with ID
+// idHex, which must be another open sprint of the same project, or by default the open
+// sprint starting soonest after it. It returns nil when there is none.
+func (s *SprintService) nextSprint(ctx context.Context, sprint *models.Sprint, idHex string) (*models.Sprint, error) {
+	if idHex != "" {
+		next, err := s.GetSprint(ctx, idHex)
+		if err == ErrSprintNotFound {
+			return nil, ErrInvalidNextSprint
+		}
+		if err != nil {
+			return nil, err
+		}
+		if next.ID == sprint.ID || next.ProjectID != sprint.ProjectID || next.Closed {
+			return nil, ErrInvalidNextSprint
+		}
+		return next, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var next models.Sprint
+	err := s.sprintCollection.FindOne(ctx,
+		bson.M{"project_id": sprint.ProjectID, "closed": false, "_id": bson.M{"$ne": sprint.ID}, "start_date": bson.M{"$gte": sprint.StartDate}},
+		options.FindOne().SetSort(bson.D{{Key: "start_date", Value: 1}})).Decode(&next)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &next, nil
+}
+
+// DeleteSprint deletes a sprint. Its tasks are kept in the project's backlog.
+func (s *SprintService) DeleteSprint(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	result, err := s.sprintCollection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrSprintNotFound
+	}
+	_, err = s.taskService.DetachSprint(ctx, id)
+	return err
+}
+
+// Summary counts the tasks of a sprint by status and how far through its dates it is
+func (s *SprintService) Summary(ctx context.Context, sprint *models.Sprint) (*models.SprintSummaryResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	inSprint := bson.M{"sprint_id": sprint.ID}
+	byStatus, err := s.tasks.CountByStatus(ctx, inSprint)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	overdue, err := s.tasks.Count(ctx, bson.M{
+		"sprint_id": sprint.ID,
+		"status":    bson.M{"$ne": models.StatusDone},
+		"due_date":  bson.M{"$lt": now},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &models.SprintSummaryResponse{
+		SprintID:        sprint.ID,
+		Closed:          sprint.Closed,
+		RolledOverCount: sprint.RolledOverCount,
+		OverdueCount:    overdue,
+		TasksByStatus:   byStatus,
+	}
+	for _, count := range byStatus {
+		summary.TotalTasks += count.Count
+		if count.Status == models.StatusDone {
+			summary.CompletedTasks += count.Count
+		} else {
+			summary.RemainingTasks += count.Count
+		}
+	}
+	summary.TotalTasks += sprint.RolledOverCount
+	if summary.TotalTasks > 0 {
+		summary.CompletionPercent = float64(summary.CompletedTasks) * 100 / float64(summary.TotalTasks)
+	}
+
+	// Days are the 24-hour periods from the start date, counting a started one; elapsed time
+	// stops when the sprint ends or is closed
+	summary.DaysTotal = sprintDays(sprint.StartDate, sprint.EndDate)
+	ended := sprint.EndDate
+	if sprint.ClosedAt != nil && sprint.ClosedAt.Before(ended) {
+		ended = *sprint.ClosedAt
+	}
+	if now.Before(ended) {
+		ended = now
+	}
+	summary.DaysElapsed = min(sprintDays(sprint.StartDate, ended), summary.DaysTotal)
+	if !sprint.Closed {
+		summary.DaysRemaining = summary.DaysTotal - summary.DaysElapsed
+	}
+	return summary, nil
+}
+
+// sprintDays counts the started days from start to end, 0 when end isn't after start
+func sprintDays(start, end time.Time) int {
+	if !end.After(start) {
+		return 0
+	}
+	return int((end.Sub(start) + 24*time.Hour - 1) / (24 * time.Hour))
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/OsGift/taskflow-api/internal/jobs"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+const (
+	// staleTasksTemplate is the email template warning owners about their untouched tasks
+	staleTasksTemplate = "stale_tasks"
+	staleTasksSubject  = "Your TaskFlow tasks are going stale"
+
+	// maxStaleTasksListed caps the tasks listed in a warning
+	maxStaleTasksListed = 10
+)
+
+// StaleTaskPolicy says when open tasks left untouched go stale
+type StaleTaskPolicy struct {
+	Days        int  // Days without an update after which a task goes stale
+	WarningDays int  // Days before that its owner is warned; 0 sends no warning
+	Archive     bool // Archive stale tasks, hiding them from listings, rather than only flagging them
+}
+
+// StaleTaskService runs the daily job that warns the owners of open tasks left untouched, then
+// flags or archives the tasks once they go stale. Updating a task brings it back.
+type StaleTaskService struct {
+	tasks         repository.TaskRepository
+	users         repository.UserRepository
+	taskService   *TaskService
+	jobQueue      *jobs.Queue
+	notifications *NotificationService
+	policy        StaleTaskPolicy
+	enabled       atomic.Bool
+	hour          int
+}
+
+// NewStaleTaskService creates a StaleTaskService running daily at hour:00 UTC. When enabled is
+// false, runs that were already queued do nothing and aren't rescheduled.
+func NewStaleTaskService(store *repository.Store, ts *TaskService, jq *jobs.Queue, ns *NotificationService, policy StaleTaskPolicy, enabled bool, hour int) *StaleTaskService {
+	s := &StaleTaskService{
+		tasks:         store.Tasks,
+		users:         store.Users,
+		taskService:   ts,
+		jobQueue:      jq,
+		notifications: ns,
+		policy:        policy,
+		hour:          hour,
+	}
+	s.enabled.Store(enabled)
+	return s
+}
+
+// SetEnabled turns the job on or off, queuing the next run when turning it on
+func (s *StaleTaskService) SetEnabled(ctx context.Context, enabled bool) error {
+	if s.enabled.Swap(enabled) || !enabled {
+		return nil
+	}
+	return s.Schedule(ctx)
+}
+
+// Schedule makes sure the next stale task run is queued
+func (s *StaleTaskService) Schedule(ctx context.Context) error {
+	return jobs.ScheduleNext(ctx, s.jobQueue, jobs.TypeStaleTasks, s.NextRun)
+}
+
+// NextRun is the jobs.NextRunFunc of TypeStaleTasks: runs are daily while the job is enabled
+func (s *StaleTaskService) NextRun(ctx context.Context, run jobs.RunPayload) (time.Time, bool, error) {
+	return jobs.NextDaily(run.RunAt, s.hour), s.enabled.Load(), nil
+}
+
+// RunStaleTasks handles a TypeStaleTasks run: it warns the owners of the tasks about to go
+// stale, then flags or archives the stale ones. Tasks only go stale once their owner has had
+// the full warning period, and warnings are keyed by run and owner, so a retried run doesn't
+// notify anyone twice.
+func (s *StaleTaskService) RunStaleTasks(ctx context.Context, run jobs.RunPayload) error {
+	now := time.Now()
+	var warned int
+	if s.policy.WarningDays > 0 {
+		var err error
+		if warned, err = s.warnOwners(ctx, run.RunAt, now); err != nil {
+			return err
+		}
+	}
+
+	filter := s.openTasks(now.AddDate(0, 0, -s.policy.Days))
+	if s.policy.WarningDays > 0 {
+		filter["stale_warned_at"] = bson.M{"$lte": now.AddDate(0, 0, -s.policy.WarningDays)}
+	}
+	if !s.policy.Archive {
+		filter["stale_at"] = nil // Flagged tasks stay flagged until they are updated
+	}
+	count, err := s.taskService.MarkStale(ctx, filter, s.policy.Archive)
+	if err != nil {
+		return fmt.Errorf("failed to mark stale tasks: %w", err)
+	}
+
+	action := "flagged"
+	if s.policy.Archive {
+		action = "archived"
+	}
+	log.Printf("Stale tasks: warned %d owners, %s %d tasks untouched for %d days", warned, action, count, s.policy.Days)
+	return nil
+}
+
+// openTasks returns the filter of the open, unarchived tasks last updated before cutoff
+func (s *StaleTaskService) openTasks(cutoff time.Time) bson.M {
+	return bson.M{
+		"status":     bson.M{"$in": []string{string(models.StatusTodo), string(models.StatusInProgress)}},
+		"archived":   bson.M{"$ne": true},
+		"updated_at": bson.M{"$lt": cutoff},
+	}
+}
+
+// warnOwners notifies the owners of the tasks entering the warning period, once per owner,
+// and records the warning on the tasks. It returns how many owners were notified.
+func (s *StaleTaskService) warnOwners(ctx context.Context, runAt, now time.Time) (int, error) {
+	filter := s.openTasks(now.AddDate(0, 0, s.policy.WarningDays-s.policy.Days))
+	filter["stale_at"] = nil
+	filter["stale_warned_at"] = nil
+	q := query.New(filter, 1, 0)
+	q.Sort = bson.D{{Key: "user_id", Value: 1}, {Key: "_id", Value: 1}}
+
+	// Tasks come sorted by owner, so each owner's are gathered before moving on to the next
+	var warned int
+	var owner primitive.ObjectID
+	var owned []models.Task
+	flush := func() error {
+		if len(owned) == 0 {
+			return nil
+		}
+		sent, err := s.warnOwner(ctx, owner, owned, runAt, now)
+		if err != nil {
+			return fmt.Errorf("failed to warn user %s about stale tasks: %w", owner.Hex(), err)
+		}
+		if sent {
+			warned++
+		}
+		owned = owned[:0]
+		return nil
+	}
+	err := s.tasks.EachMatching(ctx, q, func(task *models.Task) error {
+		if task.UserID != owner {
+			if err := flush(); err != nil {
+				return err
+			}
+			owner = task.UserID
+		}
+		owned = append(owned, *task)
+		return nil
+	})
+	if err != nil {
+		return warned, err
+	}
+	return warned, flush()
+}
+
+// warnOwner tells one owner which of their tasks go stale after the warning period, then
+// records the warning on the tasks. Owners that were deleted or disabled aren't notified, but
+// their tasks still go stale.
+func (s *StaleTaskService) warnOwner(ctx context.Context, userID primitive.ObjectID, tasks []models.Task, runAt, now time.Time) (bool, error) {
+	ids := make([]primitive.ObjectID, 0, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+
+	var sent bool
+	user, err := s.users.FindByID(ctx, userID)
+	switch {
+	case err == repository.ErrNotFound:
+	case err != nil:
+		return false, err
+	case !user.Disabled:
+		staleOn := now.AddDate(0, 0, s.policy.WarningDays).In(user.Location())
+		listed := make([]map[string]interface{}, 0, min(len(tasks), maxStaleTasksListed))
+		for _, task := range tasks[:min(len(tasks), maxStaleTasksListed)] {
+			listed = append(listed, map[string]interface{}{
+				"Title":     task.Title,
+				"UpdatedAt": task.UpdatedAt.In(staleOn.Location()).Format("Mon, Jan 2 2006"),
+			})
+		}
+
+		action := "flagged as stale"
+		if s.policy.Archive {
+			action = "archived"
+		}
+		body := fmt.Sprintf("%d of your tasks haven't been updated in %d days and will be %s on %s unless updated.",
+			len(tasks), s.policy.Days-s.policy.WarningDays, action, staleOn.Format("Mon, Jan 2"))
+		emailData := map[string]interface{}{
+			"FirstName": user.FirstName,
+			"Count":     len(tasks),
+			"Days":      s.policy.Days - s.policy.WarningDays,
+			"Action":    action,
+			"StaleOn":   staleOn.Format("Mon, Jan 2"),
+			"Tasks":     listed,
+			"MoreCount": len(tasks) - len(listed),
+			"TasksLink": "http://localhost:3000/tasks", // Frontend task list URL
+			"Year":      now.Year(),
+		}
+		sent, err = s.notifications.Notify(ctx, &Notice{
+			Event:    models.EventTasksGoingStale,
+			User:     user,
+			Title:    "Tasks going stale",
+			Body:     body,
+			Link:     "http://localhost:3000/tasks",
+			Template: staleTasksTemplate,
+			Subject:  staleTasksSubject,
+			Data:     emailData,
+			Key:      fmt.Sprintf("%s:%s:%s", jobs.TypeStaleTasks, runAt.Format(time.RFC3339), userID.Hex()),
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+
+	_, err = s.tasks.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}}, repository.Fields{"stale_warned_at": now})
+	return sent, err
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+// TaskMergeService folds duplicate tasks, such as the same bug reported twice, into the task
+// that is kept
+type TaskMergeService struct {
+	db                repository.Documents
+	tasks             repository.TaskRepository
+	commentCollection repository.Collection
+	uploadCollection  repository.Collection
+	taskService       *TaskService
+}
+
+// NewTaskMergeService creates a new TaskMergeService
+func NewTaskMergeService(db repository.Documents, store *repository.Store, ts *TaskService) *TaskMergeService {
+	return &TaskMergeService{
+		db:                db,
+		tasks:             store.Tasks,
+		commentCollection: db.Collection("comments"),
+		uploadCollection:  db.Collection("uploads"),
+		taskService:       ts,
+	}
+}
+
+// MergeTasks merges the task sourceID into targetID: the source's comments and attachments
+// move over to the target, and the source is closed as done, pointing to the target through
+// merged_into. Everything happens in one transaction, so a failure leaves both tasks as they
+// were. Tasks that were already merged can't take part in another merge.
+func (s *TaskMergeService) MergeTasks(ctx context.Context, targetID, sourceID string) (*models.MergeTasksResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	targetObjID, err := primitive.ObjectIDFromHex(targetID)
+	if err != nil {
+		return nil, ErrInvalidTaskID
+	}
+	sourceObjID, err := primitive.ObjectIDFromHex(sourceID)
+	if err != nil {
+		return nil, ErrInvalidSourceTaskID
+	}
+	if targetObjID == sourceObjID {
+		return nil, ErrMergeTaskIntoSelf
+	}
+
+	target, err := s.tasks.FindByID(ctx, targetObjID)
+	if err == repository.ErrNotFound {
+		return nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	source, err := s.tasks.FindByID(ctx, sourceObjID)
+	if err == repository.ErrNotFound {
+		return nil, ErrSourceTaskNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if target.MergedInto != nil || source.MergedInto != nil {
+		return nil, ErrTaskAlreadyMerged
+	}
+
+	now := time.Now()
+	fields := repository.Fields{"merged_into": targetObjID, "updated_at": now, "stale_warned_at": nil, "stale_at": nil}
+	if source.Status != models.StatusDone {
+		fields["status"] = models.StatusDone
+		fields["status_changed_at"] = now
+		fields["completed_at"] = now
+		fields["sla_breached_at"] = nil
+		fields["sla_rule_id"] = nil
+	}
+
+	response := &models.MergeTasksResponse{SourceID: sourceObjID}
+	err = s.db.WithTransaction(ctx, func(txCtx context.Context) error {
+		comments, err := s.commentCollection.UpdateMany(txCtx, bson.M{"task_id": sourceObjID},
+			bson.M{"$set": bson.M{"task_id": targetObjID}})
+		if err != nil {
+			return err
+		}
+		attachments, err := s.uploadCollection.UpdateMany(txCtx, bson.M{"resource_type": "task", "resource_id": sourceObjID},
+			bson.M{"$set": bson.M{"resource_id": targetObjID}})
+		if err != nil {
+			return err
+		}
+		// Only a source that wasn't merged since it was read is closed
+		closed, err := s.tasks.UpdateMany(txCtx, bson.M{"_id": sourceObjID, "merged_into": nil}, fields)
+		if err != nil {
+			return err
+		}
+		if closed == 0 {
+			return ErrTaskAlreadyMerged
+		}
+
+		response.CommentsMoved = comments.ModifiedCount
+		response.AttachmentsMoved = attachments.ModifiedCount
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.taskService.invalidateCaches(ctx)
+	if source, err = s.tasks.FindByID(ctx, sourceObjID); err == nil {
+		s.taskService.taskSaved(ctx, source)
+	}
+
+	response.Task, err = s.taskService.GetTaskByID(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
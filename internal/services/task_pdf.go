@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/pdf"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+// taskPDFActivityLimit is how many of the latest changes a task PDF lists
+const taskPDFActivityLimit = 500
+
+// taskPDFTime is how times are printed in task PDFs
+const taskPDFTime = "2006-01-02 15:04 MST"
+
+// taskAuditActions describes the requests recorded in the audit trail of a task, by method and
+// route below the API version
+var taskAuditActions = map[string]string{
+	"POST /tasks":                              "Created the task",
+	"PUT /tasks/{id}":                          "Updated the task",
+	"DELETE /tasks/{id}":                       "Deleted the task",
+	"POST /tasks/{id}/attachments/link":        "Attached a file",
+	"POST /tasks/{id}/comments":                "Added a comment",
+	"PUT /tasks/{id}/comments/{comment_id}":    "Edited a comment",
+	"DELETE /tasks/{id}/comments/{comment_id}": "Deleted a comment",
+}
+
+// TaskPDF writes a printable PDF of a task to w: its details and description, its comments
+// and the changes made to it through the API. Times are printed in loc.
+func (s *ExportService) TaskPDF(ctx context.Context, task *models.Task, loc *time.Location, w io.Writer) error {
+	comments, err := s.commentService.TaskComments(ctx, task.ID)
+	if err != nil {
+		return err
+	}
+	activity, err := s.auditService.TargetLogs(ctx, task.ID.Hex(), taskPDFActivityLimit)
+	if err != nil {
+		return err
+	}
+
+	names := map[primitive.ObjectID]string{}
+	name := func(id primitive.ObjectID) (string, error) {
+		if n, ok := names[id]; ok {
+			return n, nil
+		}
+		user, err := s.store.Users.FindByID(ctx, id)
+		if err == repository.ErrNotFound {
+			names[id] = "Deleted user"
+			return names[id], nil
+		}
+		if err != nil {
+			return "", err
+		}
+		names[id] = strings.TrimSpace(user.FirstName + " " + user.LastName)
+		return names[id], nil
+	}
+	at := func(t time.Time) string { return t.In(loc).Format(taskPDFTime) }
+
+	doc := pdf.New(task.Title)
+	doc.SetFooter(fmt.Sprintf("Task %s, printed %s", task.ID.Hex(), at(time.Now())))
+	doc.Heading(task.Title)
+
+	owner, err := name(task.UserID)
+	if err != nil {
+		return err
+	}
+	doc.Field("Status", string(task.Status))
+	doc.Field("Owner", owner)
+	if task.DueDate != nil {
+		doc.Field("Due", at(*task.DueDate))
+	}
+	if task.CompletedAt != nil {
+		doc.Field("Completed", at(*task.CompletedAt))
+	}
+	if task.Priority != "" {
+		doc.Field("Priority", string(task.Priority))
+	}
+	if len(task.Tags) > 0 {
+		doc.Field("Tags", strings.Join(task.Tags, ", "))
+	}
+	if task.ProjectID != nil {
+		doc.Field("Project", task.ProjectID.Hex())
+	}
+	if task.MilestoneID != nil {
+		doc.Field("Milestone", task.MilestoneID.Hex())
+	}
+	if task.SprintID != nil {
+		doc.Field("Sprint", task.SprintID.Hex())
+	}
+	if task.Archived {
+		doc.Field("Archived", "yes")
+	}
+	doc.Field("Created", at(task.CreatedAt))
+	doc.Field("Updated", at(task.UpdatedAt))
+
+	doc.Subheading("Description")
+	if task.Description == "" {
+		doc.Note("No description.")
+	} else {
+		doc.Text(task.Description)
+	}
+
+	doc.Subheading(fmt.Sprintf("Comments (%d)", len(comments)))
+	if len(comments) == 0 {
+		doc.Note("No comments.")
+	}
+	for i, comment := range comments {
+		author, err := name(comment.AuthorID)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			doc.Space()
+		}
+		note := author + ", " + at(comment.CreatedAt)
+		if comment.EditedAt != nil {
+			note += " (edited " + at(*comment.EditedAt) + ")"
+		}
+		doc.Note(note)
+		doc.Text(comment.Body)
+	}
+
+	doc.Subheading("Activity")
+	if len(activity) == 0 {
+		doc.Note("No recorded changes.")
+	}
+	if len(activity) == taskPDFActivityLimit {
+		doc.Note(fmt.Sprintf("Only the latest %d changes are listed.", taskPDFActivityLimit))
+	}
+	for _, entry := range activity {
+		actor := "Unknown"
+		if entry.ActorID != nil {
+			if actor, err = name(*entry.ActorID); err != nil {
+				return err
+			}
+		}
+		doc.Field(at(entry.CreatedAt), actor+": "+describeTaskActivity(&entry))
+	}
+
+	_, err = doc.WriteTo(w)
+	return err
+}
+
+// describeTaskActivity says what an audited request did to a task
+func describeTaskActivity(entry *models.AuditLog) string {
+	route := entry.Route
+	if i := strings.Index(route, "/tasks"); i >= 0 {
+		route = route[i:] // Without the /api/<version> prefix
+	}
+	if description, ok := taskAuditActions[entry.Method+" "+route]; ok {
+		return description
+	}
+	return entry.Method + " " + route
+}
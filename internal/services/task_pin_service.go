@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/OsGift/taskflow-api/internal/logging"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+// maxPinsPerUser caps how many tasks a user can pin, as listings filter on the pinned IDs
+const maxPinsPerUser = 200
+
+// TaskPinService keeps the tasks each user pinned, to keep them at hand and list them first.
+// Pins are per user: anyone who can read a task may pin it for themselves.
+type TaskPinService struct {
+	pinCollection repository.Collection
+}
+
+// NewTaskPinService creates a new TaskPinService
+func NewTaskPinService(db repository.Documents) *TaskPinService {
+	return &TaskPinService{pinCollection: db.Collection("task_pins")}
+}
+
+// Pin pins taskID for userID. Pinning a task again keeps the time it was first pinned.
+func (s *TaskPinService) Pin(ctx context.Context, userID, taskID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	count, err := s.pinCollection.CountDocuments(ctx, bson.M{"user_id": userID, "task_id": bson.M{"$ne": taskID}})
+	if err != nil {
+		return err
+	}
+	if count >= maxPinsPerUser {
+		return ErrTooManyPins
+	}
+	_, err = s.pinCollection.UpdateOne(ctx, bson.M{"user_id": userID, "task_id": taskID},
+		bson.M{"$setOnInsert": bson.M{"pinned_at": time.Now()}}, options.Update().SetUpsert(true))
+	return err
+}
+
+// Unpin unpins taskID for userID; unpinning a task that isn't pinned does nothing
+func (s *TaskPinService) Unpin(ctx context.Context, userID, taskID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := s.pinCollection.DeleteOne(ctx, bson.M{"user_id": userID, "task_id": taskID})
+	return err
+}
+
+// PinnedTaskIDs returns the IDs of the tasks userID pinned
+func (s *TaskPinService) PinnedTaskIDs(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	pins, err := s.pins(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, 0, len(pins))
+	for _, pin := range pins {
+		ids = append(ids, pin.TaskID)
+	}
+	return ids, nil
+}
+
+// FlagPinned sets Pinned and PinnedAt on the tasks userID pinned
+func (s *TaskPinService) FlagPinned(ctx context.Context, userID primitive.ObjectID, tasks []models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	ids := make([]primitive.ObjectID, 0, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+
+	pins, err := s.pins(ctx, bson.M{"user_id": userID, "task_id": bson.M{"$in": ids}})
+	if err != nil {
+		return err
+	}
+	pinnedAt := make(map[primitive.ObjectID]time.Time, len(pins))
+	for _, pin := range pins {
+		pinnedAt[pin.TaskID] = pin.PinnedAt
+	}
+	for i := range tasks {
+		if at, ok := pinnedAt[tasks[i].ID]; ok {
+			tasks[i].Pinned, tasks[i].PinnedAt = true, &at
+		}
+	}
+	return nil
+}
+
+// pins reads the pins matching filter
+func (s *TaskPinService) pins(ctx context.Context, filter bson.M) ([]models.TaskPin, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := s.pinCollection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	var pins []models.TaskPin
+	if err := cursor.All(ctx, &pins); err != nil {
+		return nil, err
+	}
+	return pins, nil
+}
+
+// TaskSaved does nothing; it makes TaskPinService a TaskObserver
+func (s *TaskPinService) TaskSaved(ctx context.Context, task *models.Task) {}
+
+// TaskDeleted forgets who pinned a deleted task
+func (s *TaskPinService) TaskDeleted(ctx context.Context, taskID primitive.ObjectID) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	if _, err := s.pinCollection.DeleteMany(ctx, bson.M{"task_id": taskID}); err != nil {
+		logging.Warnf("Failed to delete the pins of deleted task %s: %v", taskID.Hex(), err)
+	}
+}
+
+// CleanUpUserData deletes the pins of a user being deleted; they mean nothing to anyone else
+func (s *TaskPinService) CleanUpUserData(ctx context.Context, userID primitive.ObjectID, reassignTo *primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	_, err := s.pinCollection.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
@@ -2,179 +2,554 @@ package services
 
 import (
 	"context"
-	"errors"
+	"slices"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"github.com/OsGift/taskflow-api/internal/cache"
 	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/repository"
 )
 
+// TaskObserver is told about the task writes made through a TaskService, e.g. to mirror tasks
+// into an external calendar. It is called after the write succeeded and must return quickly.
+type TaskObserver interface {
+	TaskSaved(ctx context.Context, task *models.Task)
+	TaskDeleted(ctx context.Context, id primitive.ObjectID)
+}
+
 // TaskService provides methods for task-related operations
 type TaskService struct {
-	tasksCollection *mongo.Collection
+	tasks     repository.TaskRepository
+	cache     cache.Cache // Shared cache for list counts; may be nil
+	observers []TaskObserver
 }
 
 // NewTaskService creates a new TaskService
-func NewTaskService(db *mongo.Database) *TaskService {
+func NewTaskService(store *repository.Store, c cache.Cache) *TaskService {
 	return &TaskService{
-		tasksCollection: db.Collection("tasks"),
+		tasks: store.Tasks,
+		cache: c,
 	}
 }
 
+// AddObserver registers an observer told about task writes
+func (s *TaskService) AddObserver(o TaskObserver) {
+	s.observers = append(s.observers, o)
+}
+
+// taskSaved tells the observers about a created or updated task
+func (s *TaskService) taskSaved(ctx context.Context, task *models.Task) {
+	for _, o := range s.observers {
+		o.TaskSaved(ctx, task)
+	}
+}
+
+// invalidateCaches drops cached data derived from tasks after a write
+func (s *TaskService) invalidateCaches(ctx context.Context) {
+	cache.InvalidatePrefixes(ctx, s.cache, cachePrefixTaskCount, cachePrefixDashboard)
+}
+
 // CreateTask creates a new task
-func (s *TaskService) CreateTask(task *models.Task) (*models.Task, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *TaskService) CreateTask(ctx context.Context, task *models.Task) (*models.Task, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	task.ID = primitive.NewObjectID()
+	task.Tags = normalizeTags(task.Tags)
+	task.Color = strings.ToLower(task.Color)
+	if task.DueDate == nil {
+		task.DueTimeZone = ""
+	}
 	task.CreatedAt = time.Now()
-	task.UpdatedAt = time.Now()
+	task.UpdatedAt = task.CreatedAt
+	task.StatusChangedAt = &task.CreatedAt
+	if task.Status == models.StatusDone {
+		task.CompletedAt = &task.CreatedAt
+	}
 
-	_, err := s.tasksCollection.InsertOne(ctx, task)
-	if err != nil {
+	if err := s.tasks.Create(ctx, task); err != nil {
 		return nil, err
 	}
+	s.invalidateCaches(ctx)
+	s.taskSaved(ctx, task)
 	return task, nil
 }
 
+// normalizeTags lowercases tags and drops the "#" they may be written with, empty tags and
+// duplicates
+func normalizeTags(tags []string) []string {
+	if tags == nil {
+		return nil
+	}
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+		if tag != "" && !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized
+}
+
+// ImportTasks creates tasks brought over from another task manager, keeping the creation and
+// completion times they had there. Tasks created before a failure are kept.
+func (s *TaskService) ImportTasks(ctx context.Context, tasks []*models.Task) error {
+	defer s.invalidateCaches(ctx)
+	for _, task := range tasks {
+		task.ID = primitive.NewObjectID()
+		task.UpdatedAt = task.CreatedAt
+		task.StatusChangedAt = &task.CreatedAt
+		if task.CompletedAt != nil {
+			task.UpdatedAt = *task.CompletedAt
+			task.StatusChangedAt = task.CompletedAt
+		}
+		if err := s.tasks.Create(ctx, task); err != nil {
+			return err
+		}
+	}
+	for _, task := range tasks {
+		s.taskSaved(ctx, task)
+	}
+	return nil
+}
+
 // GetTaskByID retrieves a task by its ID
-func (s *TaskService) GetTaskByID(id string) (*models.Task, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *TaskService) GetTaskByID(ctx context.Context, id string) (*models.Task, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return nil, errors.New("invalid task ID format")
+		return nil, ErrInvalidTaskID
 	}
 
-	var task models.Task
-	err = s.tasksCollection.FindOne(ctx, bson.M{"_id": objID}).Decode(&task)
+	task, err := s.tasks.FindByID(ctx, objID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, errors.New("task not found")
+		if err == repository.ErrNotFound {
+			return nil, ErrTaskNotFound
 		}
 		return nil, err
 	}
-	return &task, nil
+	return task, nil
 }
 
-// ListTasks retrieves a list of tasks with optional filtering, search, and pagination
-func (s *TaskService) ListTasks(
-	filter primitive.M,
-	searchQuery string,
-	page int64,
-	limit int64,
-) (*models.TaskListResponse, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// ListTasks retrieves a list of tasks with optional filtering, search, sorting and pagination
+func (s *TaskService) ListTasks(ctx context.Context, q *query.Query, searchQuery string) (*models.TaskListResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	// Build the query filter
-	query := bson.M{}
-	for k, v := range filter {
-		query[k] = v
+	filter := taskSearchFilter(q.Filter, searchQuery)
+	listQuery := *q
+	listQuery.Filter = filter
+	tasks, err := s.tasks.List(ctx, &listQuery)
+	if err != nil {
+		return nil, err
 	}
 
-	// Add search query if provided (case-insensitive regex on title and description)
-	if searchQuery != "" {
-		searchPattern := primitive.Regex{Pattern: searchQuery, Options: "i"} // "i" for case-insensitive
-		query["$or"] = []bson.M{
-			{"title": searchPattern},
-			{"description": searchPattern},
-		}
+	// Get total count for pagination metadata (cached until tasks are written)
+	totalCount, estimated, err := listCount(ctx, s.cache, cachePrefixTaskCount, s.tasks, filter, q.EstimateCount)
+	if err != nil {
+		return nil, err
 	}
 
-	// Calculate skip for pagination
-	skip := (page - 1) * limit
-	if skip < 0 {
-		skip = 0 // Ensure skip is not negative
-	}
+	return &models.TaskListResponse{
+		Tasks:          tasks,
+		Pagination:     models.NewPagination(totalCount, q.Page, q.Limit),
+		CountEstimated: estimated,
+	}, nil
+}
 
-	findOptions := options.Find()
-	findOptions.SetSkip(skip)
-	findOptions.SetLimit(limit)
-	findOptions.SetSort(bson.D{{"created_at", -1}}) // Sort by creation date descending
+// ListTasksInGroups is ListTasks listing the tasks that also match first before those that
+// also match rest, each group in the query's sort order; e.g. the caller's pinned tasks first.
+// Every task matching the query must match exactly one of the two.
+func (s *TaskService) ListTasksInGroups(ctx context.Context, q *query.Query, searchQuery string, first, rest bson.M) (*models.TaskListResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
 
-	cursor, err := s.tasksCollection.Find(ctx, query, findOptions)
+	filter := taskSearchFilter(q.Filter, searchQuery)
+	firstQuery, restQuery := *q, *q
+	firstQuery.Filter = bson.M{"$and": []bson.M{filter, first}}
+	restQuery.Filter = bson.M{"$and": []bson.M{filter, rest}}
+
+	firstCount, err := s.tasks.Count(ctx, firstQuery.Filter)
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(ctx)
-
-	var tasks []models.Task
-	if err = cursor.All(ctx, &tasks); err != nil {
-		return nil, err
+	tasks := []models.Task{}
+	if q.Skip() < firstCount {
+		if tasks, err = s.tasks.List(ctx, &firstQuery); err != nil {
+			return nil, err
+		}
+	}
+	if remaining := q.Limit - int64(len(tasks)); remaining > 0 {
+		// The page starts in, or runs on into, the second group
+		restQuery.Page, restQuery.Limit, restQuery.Offset = 1, remaining, max(q.Skip()-firstCount, 0)
+		more, err := s.tasks.List(ctx, &restQuery)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, more...)
 	}
 
-	// Get total count for pagination metadata
-	totalCount, err := s.tasksCollection.CountDocuments(ctx, query)
+	totalCount, estimated, err := listCount(ctx, s.cache, cachePrefixTaskCount, s.tasks, filter, q.EstimateCount)
 	if err != nil {
 		return nil, err
 	}
 
 	return &models.TaskListResponse{
-		Tasks:      tasks,
-		TotalCount: totalCount,
-		Page:       page,
-		Limit:      limit,
+		Tasks:          tasks,
+		Pagination:     models.NewPagination(totalCount, q.Page, q.Limit),
+		CountEstimated: estimated,
 	}, nil
 }
 
+// EachTask calls fn with every task matching the query and search, in the query's sort order
+// and ignoring its paging. Tasks are read as fn consumes them, so listings of any size can be
+// streamed.
+func (s *TaskService) EachTask(ctx context.Context, q *query.Query, searchQuery string, fn func(*models.Task) error) error {
+	listQuery := *q
+	listQuery.Filter = taskSearchFilter(q.Filter, searchQuery)
+	return s.tasks.EachMatching(ctx, &listQuery, fn)
+}
+
+// VisibleTasksFilter returns the condition matching the tasks a caller without 'task:read_all'
+// may see: their own and those of the projects they are members of. It is nil for callers
+// with 'task:read_all', who see every task.
+func VisibleTasksFilter(authContext *models.AuthContext) bson.M {
+	if authContext.HasPermission("task:read_all") {
+		return nil
+	}
+	if projectIDs := authContext.ProjectIDs(); len(projectIDs) > 0 {
+		return bson.M{"$or": []bson.M{{"user_id": authContext.UserID}, {"project_id": bson.M{"$in": projectIDs}}}}
+	}
+	return bson.M{"user_id": authContext.UserID}
+}
+
+// taskSearchFilter returns a copy of filter that also requires a case-insensitive match of
+// searchQuery, when given, in the title or description
+func taskSearchFilter(filter bson.M, searchQuery string) bson.M {
+	combined := bson.M{}
+	for k, v := range filter {
+		combined[k] = v
+	}
+	if searchQuery == "" {
+		return combined
+	}
+
+	searchPattern := primitive.Regex{Pattern: searchQuery, Options: "i"} // "i" for case-insensitive
+	search := []bson.M{
+		{"title": searchPattern},
+		{"description": searchPattern},
+	}
+	if visible, ok := combined["$or"]; ok { // Both must hold, e.g. the caller's visibility
+		delete(combined, "$or")
+		combined["$and"] = []bson.M{{"$or": visible}, {"$or": search}}
+	} else {
+		combined["$or"] = search
+	}
+	return combined
+}
+
+// SuggestTitles returns up to limit tasks matching filter whose title starts with prefix
+func (s *TaskService) SuggestTitles(ctx context.Context, filter bson.M, prefix string, limit int64) ([]models.TaskSuggestion, error) {
+	return s.tasks.SuggestTitles(ctx, filter, prefix, limit)
+}
+
 // UpdateTask updates an existing task
-func (s *TaskService) UpdateTask(id string, update *models.UpdateTaskRequest) (*models.Task, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *TaskService) UpdateTask(ctx context.Context, id string, update *models.UpdateTaskRequest) (*models.Task, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return nil, errors.New("invalid task ID format")
+		return nil, ErrInvalidTaskID
+	}
+
+	// The new status and project are compared with the current ones
+	current, err := s.tasks.FindByID(ctx, objID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return nil, ErrTaskNotModified
+		}
+		return nil, err
 	}
 
-	updateDoc := bson.M{"$set": bson.M{"updated_at": time.Now()}}
+	// Updating a task brings it back from going stale
+	now := time.Now()
+	fields := repository.Fields{"updated_at": now, "stale_warned_at": nil, "stale_at": nil}
+	if current.StaleAt != nil && current.Archived {
+		fields["archived"] = false
+	}
 	if update.Title != nil {
-		updateDoc["$set"].(bson.M)["title"] = *update.Title
+		fields["title"] = *update.Title
 	}
 	if update.Description != nil {
-		updateDoc["$set"].(bson.M)["description"] = *update.Description
+		fields["description"] = *update.Description
 	}
 	if update.Status != nil {
-		updateDoc["$set"].(bson.M)["status"] = models.TaskStatus(*update.Status)
+		status := models.TaskStatus(*update.Status)
+		fields["status"] = status
+
+		// status_changed_at records when the task entered its status, completed_at when it was
+		// last marked done
+		if status != current.Status {
+			fields["status_changed_at"] = now
+			fields["sla_breached_at"] = nil // SLAs are kept per stay in a status
+			fields["sla_rule_id"] = nil
+		}
+		if status == models.StatusDone && current.Status != models.StatusDone {
+			fields["completed_at"] = now
+		} else if status != models.StatusDone {
+			fields["completed_at"] = nil
+		}
+	}
+	if update.DueDate != nil {
+		fields["due_date"] = *update.DueDate
+	}
+	if update.DueTimeZone != "" {
+		fields["due_time_zone"] = update.DueTimeZone
+	}
+	if update.Priority != nil {
+		fields["priority"] = models.TaskPriority(*update.Priority)
+	}
+	if update.Tags != nil {
+		fields["tags"] = normalizeTags(update.Tags)
 	}
+	if update.Color != nil {
+		fields["color"] = strings.ToLower(*update.Color)
+	}
+	if update.ProjectID != nil {
+		moved := current.ProjectID != nil
+		if *update.ProjectID == "" {
+			fields["project_id"] = nil
+		} else {
+			projectID, err := primitive.ObjectIDFromHex(*update.ProjectID)
+			if err != nil {
+				return nil, ErrInvalidProjectID
+			}
+			fields["project_id"] = projectID
+			moved = current.ProjectID == nil || *current.ProjectID != projectID
+		}
+		fields["archived"] = false // Tasks can only be moved into active projects
 
-	res, err := s.tasksCollection.UpdateByID(ctx, objID, updateDoc)
-	if err != nil {
-		return nil, err
+		// Milestones and sprints belong to a project, so a task moved to another one leaves them
+		if moved {
+			fields["milestone_id"] = nil
+			fields["sprint_id"] = nil
+		}
 	}
-	if res.ModifiedCount == 0 {
-		return nil, errors.New("task not found or no changes made")
+	if update.MilestoneID != nil {
+		if *update.MilestoneID == "" {
+			fields["milestone_id"] = nil
+		} else {
+			milestoneID, err := primitive.ObjectIDFromHex(*update.MilestoneID)
+			if err != nil {
+				return nil, ErrInvalidMilestoneID
+			}
+			fields["milestone_id"] = milestoneID
+		}
+	}
+
+	if err := s.tasks.Update(ctx, objID, fields); err != nil {
+		if err == repository.ErrNotFound {
+			return nil, ErrTaskNotModified
+		}
+		return nil, err
 	}
+	s.invalidateCaches(ctx)
 
-	updatedTask, err := s.GetTaskByID(id)
+	updatedTask, err := s.GetTaskByID(ctx, id)
 	if err != nil {
 		return nil, err // Task should exist, this would be an unexpected error
 	}
+	s.taskSaved(ctx, updatedTask)
 	return updatedTask, nil
 }
 
+// DetachProject takes every task out of a deleted project, and off its milestones and
+// sprints, and returns how many there were
+func (s *TaskService) DetachProject(ctx context.Context, projectID primitive.ObjectID) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	count, err := s.tasks.UpdateMany(ctx, bson.M{"project_id": projectID},
+		repository.Fields{"project_id": nil, "milestone_id": nil, "sprint_id": nil, "archived": false, "stale_at": nil,
+			"updated_at": time.Now(), "stale_warned_at": nil})
+	if count > 0 {
+		s.invalidateCaches(ctx)
+	}
+	return count, err
+}
+
+// DetachMilestone takes every task off a deleted milestone and returns how many there were
+func (s *TaskService) DetachMilestone(ctx context.Context, milestoneID primitive.ObjectID) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	count, err := s.tasks.UpdateMany(ctx, bson.M{"milestone_id": milestoneID},
+		repository.Fields{"milestone_id": nil, "updated_at": time.Now(), "stale_warned_at": nil})
+	if count > 0 {
+		s.invalidateCaches(ctx)
+	}
+	return count, err
+}
+
+// SetSprint puts a task into a sprint, or takes it out of its sprint when sprintID is nil
+func (s *TaskService) SetSprint(ctx context.Context, taskID primitive.ObjectID, sprintID *primitive.ObjectID) (*models.Task, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	fields := repository.Fields{"sprint_id": nil, "updated_at": time.Now(), "stale_warned_at": nil}
+	if sprintID != nil {
+		fields["sprint_id"] = *sprintID
+	}
+	if err := s.tasks.Update(ctx, taskID, fields); err != nil {
+		if err == repository.ErrNotFound {
+			return nil, ErrTaskNotFound
+		}
+		return nil, err
+	}
+	s.invalidateCaches(ctx)
+
+	task, err := s.GetTaskByID(ctx, taskID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	s.taskSaved(ctx, task)
+	return task, nil
+}
+
+// RollOverSprint moves the unfinished tasks of a closed sprint to the sprint with ID to, or
+// back to the backlog when to is nil, and returns how many there were
+func (s *TaskService) RollOverSprint(ctx context.Context, from primitive.ObjectID, to *primitive.ObjectID) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	fields := repository.Fields{"sprint_id": nil, "updated_at": time.Now(), "stale_warned_at": nil}
+	if to != nil {
+		fields["sprint_id"] = *to
+	}
+	count, err := s.tasks.UpdateMany(ctx, bson.M{"sprint_id": from, "status": bson.M{"$ne": models.StatusDone}}, fields)
+	if count > 0 {
+		s.invalidateCaches(ctx)
+	}
+	return count, err
+}
+
+// DetachSprint takes every task out of a deleted sprint and returns how many there were
+func (s *TaskService) DetachSprint(ctx context.Context, sprintID primitive.ObjectID) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	count, err := s.tasks.UpdateMany(ctx, bson.M{"sprint_id": sprintID},
+		repository.Fields{"sprint_id": nil, "updated_at": time.Now(), "stale_warned_at": nil})
+	if count > 0 {
+		s.invalidateCaches(ctx)
+	}
+	return count, err
+}
+
+// SetProjectArchived hides the tasks of an archived project from listings, or shows them again
+// once it is restored. Archiving the project clears what the stale task job did to its tasks,
+// so they all come back with it.
+func (s *TaskService) SetProjectArchived(ctx context.Context, projectID primitive.ObjectID, archived bool) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	fields := repository.Fields{"archived": archived}
+	if archived {
+		fields["stale_warned_at"] = nil
+		fields["stale_at"] = nil
+	}
+	count, err := s.tasks.UpdateMany(ctx, bson.M{"project_id": projectID}, fields)
+	if count > 0 {
+		s.invalidateCaches(ctx)
+	}
+	return err
+}
+
+// MarkStale flags the tasks matching filter as stale, archiving them too when archive is set,
+// and returns how many there were. Their update time is left alone.
+func (s *TaskService) MarkStale(ctx context.Context, filter bson.M, archive bool) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	fields := repository.Fields{"stale_at": time.Now()}
+	if archive {
+		fields["archived"] = true
+	}
+	count, err := s.tasks.UpdateMany(ctx, filter, fields)
+	if count > 0 {
+		s.invalidateCaches(ctx)
+	}
+	return count, err
+}
+
+// MarkSLABreached flags the tasks matching filter as breaching the SLA rule ruleID and
+// returns how many there were. Their update time is left alone.
+func (s *TaskService) MarkSLABreached(ctx context.Context, filter bson.M, ruleID primitive.ObjectID) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	count, err := s.tasks.UpdateMany(ctx, filter, repository.Fields{"sla_breached_at": time.Now(), "sla_rule_id": ruleID})
+	if count > 0 {
+		s.invalidateCaches(ctx)
+	}
+	return count, err
+}
+
+// CleanUpUserData deletes the tasks of a user being deleted one by one, so the observers
+// clean up their comments, calendar events and views. Tasks being handed over to reassignTo
+// are left to the users repository.
+func (s *TaskService) CleanUpUserData(ctx context.Context, userID primitive.ObjectID, reassignTo *primitive.ObjectID) error {
+	if reassignTo != nil {
+		return nil
+	}
+	for {
+		// Always the first page, as the tasks on it are gone by the next round
+		tasks, err := s.tasks.List(ctx, query.New(bson.M{"user_id": userID}, 1, 100))
+		if err != nil {
+			return err
+		}
+		for _, task := range tasks {
+			if err := s.tasks.Delete(ctx, task.ID); err != nil && err != repository.ErrNotFound {
+				return err
+			}
+			for _, o := range s.observers {
+				o.TaskDeleted(ctx, task.ID)
+			}
+		}
+		if len(tasks) < 100 {
+			s.invalidateCaches(ctx)
+			return nil
+		}
+	}
+}
+
 // DeleteTask deletes a task by its ID
-func (s *TaskService) DeleteTask(id string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *TaskService) DeleteTask(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return errors.New("invalid task ID format")
+		return ErrInvalidTaskID
 	}
 
-	res, err := s.tasksCollection.DeleteOne(ctx, bson.M{"_id": objID})
-	if err != nil {
+	if err := s.tasks.Delete(ctx, objID); err != nil {
+		if err == repository.ErrNotFound {
+			return ErrTaskNotFound
+		}
 		return err
 	}
-	if res.DeletedCount == 0 {
-		return errors.New("task not found")
+	s.invalidateCaches(ctx)
+	for _, o := range s.observers {
+		o.TaskDeleted(ctx, objID)
 	}
 	return nil
 }
@@ -0,0 +1,159 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/services"
+)
+
+func TestTaskCRUD(t *testing.T) {
+	tasks := services.NewTaskService(newStore(t), nil)
+	ctx := context.Background()
+	owner := primitive.NewObjectID()
+
+	created, err := tasks.CreateTask(ctx, &models.Task{
+		Title:  "Write the report",
+		Status: models.StatusTodo,
+		UserID: owner,
+		Tags:   []string{"#Docs", "docs", " "},
+		Color:  "#FF8800",
+	})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if len(created.Tags) != 1 || created.Tags[0] != "docs" || created.Color != "#ff8800" {
+		t.Errorf("CreateTask stored tags %v and color %q, want [docs] and #ff8800", created.Tags, created.Color)
+	}
+	if created.StatusChangedAt == nil || created.CompletedAt != nil {
+		t.Errorf("CreateTask set status_changed_at %v and completed_at %v", created.StatusChangedAt, created.CompletedAt)
+	}
+
+	got, err := tasks.GetTaskByID(ctx, created.ID.Hex())
+	if err != nil {
+		t.Fatalf("GetTaskByID: %v", err)
+	}
+	if got.Title != "Write the report" || got.UserID != owner {
+		t.Errorf("GetTaskByID returned %q owned by %s", got.Title, got.UserID.Hex())
+	}
+
+	title, done := "Write the annual report", string(models.StatusDone)
+	updated, err := tasks.UpdateTask(ctx, created.ID.Hex(), &models.UpdateTaskRequest{Title: &title, Status: &done})
+	if err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+	if updated.Title != title || updated.Status != models.StatusDone || updated.CompletedAt == nil {
+		t.Errorf("UpdateTask returned %q in %s completed at %v", updated.Title, updated.Status, updated.CompletedAt)
+	}
+
+	todo := string(models.StatusTodo)
+	reopened, err := tasks.UpdateTask(ctx, created.ID.Hex(), &models.UpdateTaskRequest{Status: &todo})
+	if err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+	if reopened.CompletedAt != nil {
+		t.Errorf("reopening the task kept completed_at %v", reopened.CompletedAt)
+	}
+
+	if err := tasks.DeleteTask(ctx, created.ID.Hex()); err != nil {
+		t.Fatalf("DeleteTask: %v", err)
+	}
+	if _, err := tasks.GetTaskByID(ctx, created.ID.Hex()); !errors.Is(err, services.ErrTaskNotFound) {
+		t.Errorf("GetTaskByID after DeleteTask: got %v, want ErrTaskNotFound", err)
+	}
+	if err := tasks.DeleteTask(ctx, created.ID.Hex()); !errors.Is(err, services.ErrTaskNotFound) {
+		t.Errorf("DeleteTask twice: got %v, want ErrTaskNotFound", err)
+	}
+	if _, err := tasks.UpdateTask(ctx, created.ID.Hex(), &models.UpdateTaskRequest{Title: &title}); !errors.Is(err, services.ErrTaskNotModified) {
+		t.Errorf("UpdateTask of a deleted task: got %v, want ErrTaskNotModified", err)
+	}
+	if _, err := tasks.GetTaskByID(ctx, "nope"); !errors.Is(err, services.ErrInvalidTaskID) {
+		t.Errorf("GetTaskByID with an invalid ID: got %v, want ErrInvalidTaskID", err)
+	}
+}
+
+func TestListTasksFilters(t *testing.T) {
+	tasks := services.NewTaskService(newStore(t), nil)
+	ctx := context.Background()
+	ada, grace := primitive.NewObjectID(), primitive.NewObjectID()
+	for _, task := range []models.Task{
+		{Title: "Write the report", Description: "Quarterly numbers", Status: models.StatusTodo, UserID: ada},
+		{Title: "Review the budget", Status: models.StatusDone, UserID: ada},
+		{Title: "Plan the offsite", Description: "Book the venue", Status: models.StatusInProgress, UserID: ada, Archived: true},
+		{Title: "Fix the printer", Status: models.StatusTodo, UserID: grace},
+	} {
+		if _, err := tasks.CreateTask(ctx, &task); err != nil {
+			t.Fatalf("CreateTask: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter bson.M
+		search string
+		want   []string
+	}{
+		{"owner", bson.M{"user_id": ada}, "", []string{"Write the report", "Review the budget", "Plan the offsite"}},
+		{"status", bson.M{"status": models.StatusTodo}, "", []string{"Write the report", "Fix the printer"}},
+		{"unarchived", bson.M{"user_id": ada, "archived": bson.M{"$ne": true}}, "", []string{"Write the report", "Review the budget"}},
+		{"status in", bson.M{"status": bson.M{"$in": bson.A{models.StatusDone, models.StatusInProgress}}}, "", []string{"Review the budget", "Plan the offsite"}},
+		{"search in title", bson.M{}, "PRINTER", []string{"Fix the printer"}},
+		{"search in description", bson.M{"user_id": ada}, "venue", []string{"Plan the offsite"}},
+		{"search within visibility", bson.M{"$or": []bson.M{{"user_id": grace}}}, "report", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := query.New(tt.filter, 1, 10)
+			q.Sort = bson.D{{Key: "created_at", Value: 1}}
+			list, err := tasks.ListTasks(ctx, q, tt.search)
+			if err != nil {
+				t.Fatalf("ListTasks: %v", err)
+			}
+			var titles []string
+			for _, task := range list.Tasks {
+				titles = append(titles, task.Title)
+			}
+			if !slices.Equal(titles, tt.want) {
+				t.Errorf("ListTasks returned %v, want %v", titles, tt.want)
+			}
+			if list.Pagination.TotalCount != int64(len(tt.want)) {
+				t.Errorf("ListTasks counted %d tasks, want %d", list.Pagination.TotalCount, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestListTasksPages(t *testing.T) {
+	tasks := services.NewTaskService(newStore(t), nil)
+	ctx := context.Background()
+	owner := primitive.NewObjectID()
+	for _, title := range []string{"Task number 1", "Task number 2", "Task number 3", "Task number 4", "Task number 5"} {
+		if _, err := tasks.CreateTask(ctx, &models.Task{Title: title, Status: models.StatusTodo, UserID: owner}); err != nil {
+			t.Fatalf("CreateTask: %v", err)
+		}
+	}
+
+	q := query.New(bson.M{"user_id": owner}, 2, 2)
+	q.Sort = bson.D{{Key: "title", Value: -1}}
+	list, err := tasks.ListTasks(ctx, q, "")
+	if err != nil {
+		t.Fatalf("ListTasks: %v", err)
+	}
+	var titles []string
+	for _, task := range list.Tasks {
+		titles = append(titles, task.Title)
+	}
+	if want := []string{"Task number 3", "Task number 2"}; !slices.Equal(titles, want) {
+		t.Errorf("page 2 holds %v, want %v", titles, want)
+	}
+	if list.Pagination.TotalCount != 5 || list.Pagination.TotalPages != 3 {
+		t.Errorf("pagination is %+v, want 5 items on 3 pages", list.Pagination)
+	}
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/OsGift/taskflow-api/internal/logging"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+// TaskViewService records when users last viewed each task, so listings can flag the tasks
+// that changed since
+type TaskViewService struct {
+	viewCollection    repository.Collection
+	commentCollection repository.Collection
+}
+
+// NewTaskViewService creates a new TaskViewService
+func NewTaskViewService(db repository.Documents) *TaskViewService {
+	return &TaskViewService{
+		viewCollection:    db.Collection("task_views"),
+		commentCollection: db.Collection("comments"),
+	}
+}
+
+// MarkSeen records that userID has seen taskID as it is now
+func (s *TaskViewService) MarkSeen(ctx context.Context, userID, taskID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := s.viewCollection.UpdateOne(ctx, bson.M{"user_id": userID, "task_id": taskID},
+		bson.M{"$max": bson.M{"seen_at": time.Now()}}, options.Update().SetUpsert(true))
+	return err
+}
+
+// FlagUnread sets HasUnreadUpdates on tasks for userID. A task's last activity is its last
+// update or the last comment someone else posted or edited on it; tasks userID never viewed
+// count as seen when they were created.
+func (s *TaskViewService) FlagUnread(ctx context.Context, userID primitive.ObjectID, tasks []models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	ids := make([]primitive.ObjectID, 0, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+
+	cursor, err := s.viewCollection.Find(ctx, bson.M{"user_id": userID, "task_id": bson.M{"$in": ids}})
+	if err != nil {
+		return err
+	}
+	var views []models.TaskView
+	if err := cursor.All(ctx, &views); err != nil {
+		return err
+	}
+	seen := make(map[primitive.ObjectID]time.Time, len(views))
+	for _, view := range views {
+		seen[view.TaskID] = view.SeenAt
+	}
+
+	cursor, err = s.commentCollection.Find(ctx,
+		bson.M{"task_id": bson.M{"$in": ids}, "author_id": bson.M{"$ne": userID}},
+		options.Find().SetProjection(bson.M{"task_id": 1, "edited_at": 1, "created_at": 1}))
+	if err != nil {
+		return err
+	}
+	var comments []models.Comment
+	if err := cursor.All(ctx, &comments); err != nil {
+		return err
+	}
+	commented := make(map[primitive.ObjectID]time.Time, len(comments))
+	for _, comment := range comments {
+		last := comment.CreatedAt
+		if comment.EditedAt != nil {
+			last = *comment.EditedAt
+		}
+		if last.After(commented[comment.TaskID]) {
+			commented[comment.TaskID] = last
+		}
+	}
+
+	for i := range tasks {
+		task := &tasks[i]
+		activity := task.UpdatedAt
+		if last, ok := commented[task.ID]; ok && last.After(activity) {
+			activity = last
+		}
+		seenAt, ok := seen[task.ID]
+		if !ok {
+			seenAt = task.CreatedAt
+		}
+		unread := activity.After(seenAt)
+		task.HasUnreadUpdates = &unread
+	}
+	return nil
+}
+
+// TaskSaved does nothing; it makes TaskViewService a TaskObserver
+func (s *TaskViewService) TaskSaved(ctx context.Context, task *models.Task) {}
+
+// TaskDeleted forgets who viewed a deleted task
+func (s *TaskViewService) TaskDeleted(ctx context.Context, taskID primitive.ObjectID) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	if _, err := s.viewCollection.DeleteMany(ctx, bson.M{"task_id": taskID}); err != nil {
+		logging.Warnf("Failed to delete the views of deleted task %s: %v", taskID.Hex(), err)
+	}
+}
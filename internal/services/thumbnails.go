@@ -0,0 +1,73 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"golang.org/x/image/draw"
+)
+
+// thumbnailVariant is a downscaled copy generated for uploaded images
+type thumbnailVariant struct {
+	Name    string
+	MaxEdge int // Longest side in pixels
+}
+
+// thumbnailVariants are generated for every uploaded image, smallest first
+var thumbnailVariants = []thumbnailVariant{
+	{Name: "small", MaxEdge: 150},
+	{Name: "medium", MaxEdge: 600},
+}
+
+// thumbnail is an encoded variant ready to be stored; data is nil when the original is
+// already within the variant's size
+type thumbnail struct {
+	variant     thumbnailVariant
+	data        []byte
+	contentType string
+}
+
+// generateThumbnails decodes an image and encodes a downscaled copy for each variant the
+// image is larger than. Formats with transparency are kept as PNG, everything else is JPEG.
+// Nothing is returned for files that can't be decoded as images.
+func generateThumbnails(file io.Reader) ([]thumbnail, error) {
+	src, format, err := image.Decode(file)
+	if err != nil {
+		return nil, nil
+	}
+
+	var thumbnails []thumbnail
+	bounds := src.Bounds()
+	for _, variant := range thumbnailVariants {
+		width, height := bounds.Dx(), bounds.Dy()
+		if width <= variant.MaxEdge && height <= variant.MaxEdge {
+			thumbnails = append(thumbnails, thumbnail{variant: variant})
+			continue
+		}
+		if width >= height {
+			width, height = variant.MaxEdge, max(1, height*variant.MaxEdge/width)
+		} else {
+			width, height = max(1, width*variant.MaxEdge/height), variant.MaxEdge
+		}
+
+		dst := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Src, nil)
+
+		var buf bytes.Buffer
+		contentType := "image/jpeg"
+		if format == "png" || format == "gif" || format == "webp" {
+			contentType = "image/png"
+			err = png.Encode(&buf, dst)
+		} else {
+			err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85})
+		}
+		if err != nil {
+			return nil, err
+		}
+		thumbnails = append(thumbnails, thumbnail{variant: variant, data: buf.Bytes(), contentType: contentType})
+	}
+	return thumbnails, nil
+}
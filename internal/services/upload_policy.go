@@ -0,0 +1,145 @@
+package services
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif" // Register decoders so image.DecodeConfig can read dimensions
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	_ "golang.org/x/image/webp"
+
+	"github.com/OsGift/taskflow-api/internal/apperror"
+	"github.com/OsGift/taskflow-api/internal/models"
+)
+
+// UploadPolicy restricts what may be uploaded. Zero limits are not enforced.
+type UploadPolicy struct {
+	AllowedTypes []string // MIME types such as "image/png", or wildcards such as "image/*"
+	MaxSize      int64    // Bytes
+	MaxWidth     int      // Pixels, images only
+	MaxHeight    int      // Pixels, images only
+	Quota        int64    // Total bytes each user may have stored
+}
+
+// allows reports whether a detected content type matches AllowedTypes
+func (p UploadPolicy) allows(contentType string) bool {
+	if len(p.AllowedTypes) == 0 {
+		return true
+	}
+	for _, allowed := range p.AllowedTypes {
+		if allowed == contentType || (strings.HasSuffix(allowed, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(allowed, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// formats returns the file extensions of AllowedTypes, for backends that check uploads
+// themselves; nil when any type is allowed or an allowed type has no known extensions
+func (p UploadPolicy) formats() []string {
+	var formats []string
+	for _, allowed := range p.AllowedTypes {
+		if strings.HasSuffix(allowed, "/*") {
+			return nil
+		}
+		extensions, _ := mime.ExtensionsByType(allowed)
+		if len(extensions) == 0 {
+			return nil
+		}
+		for _, extension := range extensions {
+			if format := strings.TrimPrefix(extension, "."); !slices.Contains(formats, format) {
+				formats = append(formats, format)
+			}
+		}
+	}
+	return formats
+}
+
+// checkSize rejects empty files and files over MaxSize
+func (p UploadPolicy) checkSize(size int64) error {
+	if size == 0 {
+		return apperror.New(apperror.CodeInvalidArgument, "Uploaded file is empty.")
+	}
+	if p.MaxSize > 0 && size > p.MaxSize {
+		return apperror.New(apperror.CodePayloadTooLarge, fmt.Sprintf("File exceeds the maximum size of %d bytes", p.MaxSize)).
+			WithDetails(map[string]interface{}{"size": size, "max_size": p.MaxSize})
+	}
+	return nil
+}
+
+// checkType rejects content types outside AllowedTypes
+func (p UploadPolicy) checkType(contentType string) error {
+	if !p.allows(contentType) {
+		return apperror.New(apperror.CodeUnsupportedMediaType, fmt.Sprintf("Files of type %s are not allowed", contentType)).
+			WithDetails(map[string]interface{}{"content_type": contentType, "allowed_types": p.AllowedTypes})
+	}
+	return nil
+}
+
+// checkDimensions rejects images larger than MaxWidth or MaxHeight
+func (p UploadPolicy) checkDimensions(width, height int) error {
+	if (p.MaxWidth > 0 && width > p.MaxWidth) || (p.MaxHeight > 0 && height > p.MaxHeight) {
+		return apperror.New(apperror.CodeUnprocessableEntity, fmt.Sprintf("Image dimensions %dx%d exceed the allowed limits", width, height)).
+			WithDetails(map[string]interface{}{
+				"width": width, "height": height,
+				"max_width": p.MaxWidth, "max_height": p.MaxHeight,
+			})
+	}
+	return nil
+}
+
+// Validate checks an upload against the policy and returns its content type, sniffed from
+// the file itself rather than the client-supplied header. file is rewound afterwards.
+func (p UploadPolicy) Validate(file io.ReadSeeker, size int64) (string, error) {
+	if err := p.checkSize(size); err != nil {
+		return "", err
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	contentType, _, _ := strings.Cut(http.DetectContentType(head[:n]), ";")
+	if err := p.checkType(contentType); err != nil {
+		return "", err
+	}
+
+	if strings.HasPrefix(contentType, "image/") && (p.MaxWidth > 0 || p.MaxHeight > 0) {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return "", err
+		}
+		// Formats without a registered decoder can't be measured and are let through
+		if config, _, err := image.DecodeConfig(file); err == nil {
+			if err := p.checkDimensions(config.Width, config.Height); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return contentType, nil
+}
+
+// ValidateAsset checks a file the storage backend already holds, described by the backend's
+// own metadata, against the policy
+func (p UploadPolicy) ValidateAsset(asset *models.StoredAsset) error {
+	if err := p.checkSize(asset.Size); err != nil {
+		return err
+	}
+	if err := p.checkType(asset.ContentType); err != nil {
+		return err
+	}
+	if strings.HasPrefix(asset.ContentType, "image/") {
+		return p.checkDimensions(asset.Width, asset.Height)
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/OsGift/taskflow-api/internal/logging"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+)
+
+// Scanner checks files for malware (e.g. a ClamAV daemon)
+type Scanner interface {
+	// Scan returns the name of the threat found in file, or "" when it is clean
+	Scan(ctx context.Context, file io.Reader) (string, error)
+}
+
+// VirusScanning configures the optional scan of every upload before it is accepted; a nil
+// Scanner disables it. Flagged files are copied to QuarantineDir, outside of upload storage,
+// and admins are notified about them.
+type VirusScanning struct {
+	Scanner       Scanner
+	QuarantineDir string
+}
+
+// unsafeFilenameChars are replaced when naming quarantined files
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// scan checks an upload with the virus scanner, if one is configured, and rewinds file.
+// Flagged files are quarantined and reported to admins. Scanner failures reject the upload,
+// so nothing unscanned is ever accepted.
+func (s *UploadService) scan(ctx context.Context, userID primitive.ObjectID, filename string, file io.ReadSeeker) error {
+	if s.scanning.Scanner == nil {
+		return nil
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	threat, err := s.scanning.Scanner.Scan(ctx, file)
+	if err != nil {
+		logging.Warnf("Virus scan of upload %q failed: %v", filename, err)
+		return ErrVirusScanUnavailable
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if threat == "" {
+		return nil
+	}
+
+	quarantinePath, err := s.quarantine(userID, filename, file)
+	if err != nil {
+		logging.Warnf("Failed to quarantine infected upload %q: %v", filename, err)
+	}
+	logging.Warnf("Rejected upload %q from user %s: %s found (quarantined at %q)", filename, userID.Hex(), threat, quarantinePath)
+	s.notifyAdmins(context.WithoutCancel(ctx), userID, filename, threat, quarantinePath)
+	return ErrUploadInfected.WithDetails(map[string]interface{}{"threat": threat})
+}
+
+// quarantine copies a flagged file into the quarantine directory and returns its path
+func (s *UploadService) quarantine(userID primitive.ObjectID, filename string, file io.Reader) (string, error) {
+	if err := os.MkdirAll(s.scanning.QuarantineDir, 0o700); err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("%d_%s_%s", time.Now().UnixNano(), userID.Hex(), unsafeFilenameChars.ReplaceAllString(filepath.Base(filename), "_"))
+	quarantinePath := filepath.Join(s.scanning.QuarantineDir, name)
+
+	dst, err := os.OpenFile(quarantinePath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(dst, file); err != nil {
+		dst.Close()
+		return "", err
+	}
+	return quarantinePath, dst.Close()
+}
+
+// notifyAdmins emails every admin about a quarantined upload. Failures are only logged:
+// the upload has already been rejected.
+func (s *UploadService) notifyAdmins(ctx context.Context, userID primitive.ObjectID, filename, threat, quarantinePath string) {
+	adminRole, err := s.roles.FindByName(ctx, "Admin")
+	if err != nil {
+		logging.Warnf("Failed to look up admins to notify about quarantined upload: %v", err)
+		return
+	}
+	admins, err := s.users.List(ctx, &query.Query{Filter: primitive.M{"role_id": adminRole.ID}, Page: 1, Limit: 100})
+	if err != nil {
+		logging.Warnf("Failed to look up admins to notify about quarantined upload: %v", err)
+		return
+	}
+
+	uploaderEmail := "unknown user"
+	if uploader, err := s.users.FindByID(ctx, userID); err == nil {
+		uploaderEmail = uploader.Email
+	}
+
+	for _, admin := range admins {
+		emailData := struct {
+			FirstName      string
+			Filename       string
+			Threat         string
+			UploaderEmail  string
+			UploaderID     string
+			QuarantinePath string
+			Year           int
+		}{
+			FirstName:      admin.FirstName,
+			Filename:       filename,
+			Threat:         threat,
+			UploaderEmail:  uploaderEmail,
+			UploaderID:     userID.Hex(),
+			QuarantinePath: quarantinePath,
+			Year:           time.Now().Year(),
+		}
+		_, err := s.notifications.Notify(ctx, &Notice{
+			Event:    models.EventUploadQuarantined,
+			User:     &admin,
+			Title:    "Upload quarantined",
+			Body:     fmt.Sprintf("%s uploaded by %s was flagged as %s and quarantined.", filename, uploaderEmail, threat),
+			Template: "upload_quarantined",
+			Subject:  "TaskFlow: Upload Quarantined",
+			Data:     emailData,
+		})
+		if err != nil {
+			logging.Warnf("Failed to send quarantine notification to %s: %v", admin.Email, err)
+		}
+	}
+}
+
+// scanDirectUpload scans a file the client uploaded straight to the storage backend, which
+// the API server hasn't seen yet; flagged files are deleted from storage
+func (s *UploadService) scanDirectUpload(ctx context.Context, userID primitive.ObjectID, upload *models.Upload) error {
+	if s.scanning.Scanner == nil {
+		return nil
+	}
+	file, err := s.downloadForScan(ctx, upload.URL)
+	if err != nil {
+		logging.Warnf("Virus scan of upload %q failed: %v", upload.PublicID, err)
+		return ErrVirusScanUnavailable
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	scanErr := s.scan(ctx, userID, path.Base(upload.PublicID), file)
+	if errors.Is(scanErr, ErrUploadInfected) {
+		if err := s.storage.Delete(context.WithoutCancel(ctx), upload.PublicID); err != nil {
+			logging.Warnf("Failed to delete infected upload %q from storage: %v", upload.PublicID, err)
+		}
+	}
+	return scanErr
+}
+
+// downloadForScan fetches a file uploaded directly to the storage backend into a temporary
+// file so it can be scanned. The caller removes the file.
+func (s *UploadService) downloadForScan(ctx context.Context, url string) (*os.File, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download upload for scanning: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download upload for scanning: status %d", resp.StatusCode)
+	}
+
+	tmp, err := os.CreateTemp("", "taskflow-scan-*")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(tmp, resp.Body); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, fmt.Errorf("failed to download upload for scanning: %w", err)
+	}
+	return tmp, nil
+}
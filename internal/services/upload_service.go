@@ -1,51 +1,571 @@
 package services
 
 import (
+	"bytes"
 	"context"
-	"mime/multipart"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime/multipart"
+	"net/url"
+	"regexp"
+	"strings"
 	"time"
 
-	"github.com/cloudinary/cloudinary-go/v2"
-	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/OsGift/taskflow-api/internal/jobs"
+	"github.com/OsGift/taskflow-api/internal/logging"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/repository"
 )
 
-// UploadService handles file uploads to Cloudinary
+// uploadFolder groups uploaded files under a common prefix in every backend
+const uploadFolder = "taskflow-uploads"
+
+// StorageProvider is a backend uploaded files are stored in (Cloudinary, S3, ...)
+type StorageProvider interface {
+	// Upload stores file under key and returns the URL it can be fetched from
+	Upload(ctx context.Context, key string, file io.Reader, size int64, contentType string) (string, error)
+	// Delete removes the file stored under key; deleting a missing file is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// DirectUploader is implemented by storage backends clients can upload to directly,
+// bypassing the API server (Cloudinary)
+type DirectUploader interface {
+	// SignUpload authorizes a single client upload stored under key, which the backend
+	// refuses unless it is within limits
+	SignUpload(key string, limits models.DirectUploadLimits) (*models.DirectUploadSignature, error)
+	// VerifyUpload reports whether signature is the backend's proof of uploading key at version
+	VerifyUpload(key string, version int64, signature string) bool
+	// Asset looks up a stored file, returning its URL, size, content type and dimensions
+	Asset(ctx context.Context, key string) (*models.StoredAsset, error)
+	// ResizedURL returns a URL serving the image at key scaled down to fit within maxEdge pixels
+	ResizedURL(key string, maxEdge int) (string, error)
+}
+
+// directUploadGrace is how long after its signature expires a direct upload may still be
+// confirmed; unconfirmed uploads are deleted then
+const directUploadGrace = 30 * time.Minute
+
+// UploadService handles file uploads to the configured storage backend and keeps a record
+// of every upload in the uploads collection. Signed direct uploads hold a reservation in the
+// pending_uploads collection until they are confirmed or expire.
 type UploadService struct {
-	cld    *cloudinary.Cloudinary
-	ctx    context.Context
+	users             repository.UserRepository
+	roles             repository.RoleRepository
+	uploadCollection  repository.Collection
+	pendingCollection repository.Collection
+	notifications     *NotificationService
+	jobQueue          *jobs.Queue
+	storage           StorageProvider
+	policy            UploadPolicy
+	scanning          VirusScanning
 }
 
-// NewUploadService creates a new UploadService instance
-func NewUploadService(cloudName, apiKey, apiSecret string) *UploadService {
-	cld, err := cloudinary.NewFromParams(cloudName, apiKey, apiSecret)
-	if err != nil {
-		// In a real application, you'd log this fatal error or return it.
-		// For this example, we'll panic if Cloudinary credentials are bad.
-		panic(fmt.Sprintf("Failed to initialize Cloudinary: %v", err))
-	}
+// NewUploadService creates a new UploadService instance; uploads are checked against policy
+// and, when scanning has a Scanner, for malware
+func NewUploadService(store *repository.Store, db repository.Documents, ns *NotificationService, jq *jobs.Queue, storage StorageProvider, policy UploadPolicy, scanning VirusScanning) *UploadService {
 	return &UploadService{
-		cld: cld,
-		ctx: context.Background(), // Using a background context for the service,
+		users:             store.Users,
+		roles:             store.Roles,
+		uploadCollection:  db.Collection("uploads"),
+		pendingCollection: db.Collection("pending_uploads"),
+		notifications:     ns,
+		jobQueue:          jq,
+		storage:           storage,
+		policy:            policy,
+		scanning:          scanning,
 	}
 }
 
-// UploadFile uploads a file to Cloudinary and returns its URL
-func (s *UploadService) UploadFile(fileHeader *multipart.FileHeader) (string, error) {
+// MaxSize returns the largest accepted upload in bytes (0 when unlimited)
+func (s *UploadService) MaxSize() int64 {
+	return s.policy.MaxSize
+}
+
+// UploadFile validates a file against the upload policy and uploads it, along with
+// thumbnails when it is an image
+func (s *UploadService) UploadFile(ctx context.Context, userID primitive.ObjectID, link models.UploadLink, fileHeader *multipart.FileHeader) (*models.Upload, error) {
+	upload, err := newUpload(userID, link)
+	if err != nil {
+		return nil, err
+	}
+
 	file, err := fileHeader.Open()
 	if err != nil {
-		return "", fmt.Errorf("failed to open file: %w", err)
+		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
-	// Upload parameters, can be customized
-	uploadResult, err := s.cld.Upload.Upload(s.ctx, file, uploader.UploadParams{
-		Folder: "taskflow-uploads", // Optional: organize uploads in a specific folder
-		PublicID: fmt.Sprintf("%s_%d", fileHeader.Filename, time.Now().UnixNano()), // Unique public ID
-	})
+	contentType, err := s.policy.Validate(file, fileHeader.Size)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkQuota(ctx, userID, fileHeader.Size, ""); err != nil {
+		return nil, err
+	}
+	if err := s.scan(ctx, userID, fileHeader.Filename, file); err != nil {
+		return nil, err
+	}
+
+	key := fmt.Sprintf("%s%s_%d", userUploadPrefix(userID), fileHeader.Filename, time.Now().UnixNano()) // Unique key
+	url, err := s.storage.Upload(ctx, key, file, fileHeader.Size, contentType)
+	if err != nil {
+		return nil, err
+	}
+	upload.PublicID, upload.URL, upload.Size, upload.ContentType = key, url, fileHeader.Size, contentType
+	if strings.HasPrefix(contentType, "image/") {
+		if upload.Variants, err = s.uploadThumbnails(ctx, key, url, file); err != nil {
+			return nil, err
+		}
+	}
+	return s.record(ctx, upload)
+}
+
+// uploadThumbnails stores a downscaled copy of an uploaded image for each thumbnail variant
+// and returns the variant URLs; nothing is returned for images that can't be decoded
+func (s *UploadService) uploadThumbnails(ctx context.Context, key, url string, file io.ReadSeeker) (map[string]string, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	thumbnails, err := generateThumbnails(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate thumbnails: %w", err)
+	}
+	if len(thumbnails) == 0 {
+		return nil, nil
+	}
+
+	variants := make(map[string]string, len(thumbnails))
+	for _, thumb := range thumbnails {
+		if thumb.data == nil {
+			variants[thumb.variant.Name] = url
+			continue
+		}
+		variantURL, err := s.storage.Upload(ctx, key+"_"+thumb.variant.Name, bytes.NewReader(thumb.data), int64(len(thumb.data)), thumb.contentType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upload %s thumbnail: %w", thumb.variant.Name, err)
+		}
+		variants[thumb.variant.Name] = variantURL
+	}
+	return variants, nil
+}
+
+// newUpload starts the record of an upload by userID, linked to a resource when link is set
+func newUpload(userID primitive.ObjectID, link models.UploadLink) (*models.Upload, error) {
+	upload := &models.Upload{UploaderID: userID, ResourceType: link.ResourceType}
+	if link.ResourceID != "" {
+		resourceID, err := primitive.ObjectIDFromHex(link.ResourceID)
+		if err != nil {
+			return nil, ErrInvalidUploadLink
+		}
+		upload.ResourceID = &resourceID
+	}
+	return upload, nil
+}
+
+// record stores an upload's metadata. Recording the same public ID twice (e.g. a retried
+// direct upload confirmation) returns the existing record.
+func (s *UploadService) record(ctx context.Context, upload *models.Upload) (*models.Upload, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	upload.ID = primitive.NewObjectID()
+	upload.CreatedAt = time.Now()
+	if _, err := s.uploadCollection.InsertOne(ctx, upload); err != nil {
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("failed to record upload: %w", err)
+		}
+		var existing models.Upload
+		if err := s.uploadCollection.FindOne(ctx, bson.M{"public_id": upload.PublicID}).Decode(&existing); err != nil {
+			return nil, fmt.Errorf("failed to record upload: %w", err)
+		}
+		return &existing, nil
+	}
+	return upload, nil
+}
+
+// LinkUpload links an upload by userID to a resource, replacing any previous link
+func (s *UploadService) LinkUpload(ctx context.Context, userID primitive.ObjectID, publicID, resourceType string, resourceID primitive.ObjectID) (*models.Upload, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var upload models.Upload
+	if err := s.uploadCollection.FindOne(ctx, bson.M{"public_id": publicID}).Decode(&upload); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrUploadNotFound
+		}
+		return nil, err
+	}
+	if upload.UploaderID != userID {
+		return nil, ErrUploadNotOwned
+	}
+
+	upload.ResourceType, upload.ResourceID = resourceType, &resourceID
+	update := bson.M{"$set": bson.M{"resource_type": resourceType, "resource_id": resourceID}}
+	if _, err := s.uploadCollection.UpdateByID(ctx, upload.ID, update); err != nil {
+		return nil, fmt.Errorf("failed to link upload: %w", err)
+	}
+	return &upload, nil
+}
+
+// ListUploads retrieves uploads matching the query
+func (s *UploadService) ListUploads(ctx context.Context, q *query.Query) (*models.UploadListResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cursor, err := s.uploadCollection.Find(ctx, q.Filter, q.FindOptions())
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	uploads := []models.Upload{}
+	if err = cursor.All(ctx, &uploads); err != nil {
+		return nil, err
+	}
+
+	totalCount, err := s.uploadCollection.CountDocuments(ctx, q.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.UploadListResponse{
+		Uploads:    uploads,
+		Pagination: models.NewPagination(totalCount, q.Page, q.Limit),
+	}, nil
+}
+
+// TaskAttachments retrieves the uploads attached to a task, oldest first
+func (s *UploadService) TaskAttachments(ctx context.Context, taskID primitive.ObjectID) ([]models.Upload, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cursor, err := s.uploadCollection.Find(ctx, bson.M{"resource_type": "task", "resource_id": taskID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	uploads := []models.Upload{}
+	if err = cursor.All(ctx, &uploads); err != nil {
+		return nil, err
+	}
+	return uploads, nil
+}
+
+// Usage totals the size and number of a user's recorded uploads against their quota
+func (s *UploadService) Usage(ctx context.Context, userID primitive.ObjectID) (*models.UploadUsage, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := s.uploadCollection.Find(ctx, bson.M{"uploader_id": userID},
+		options.Find().SetProjection(bson.M{"size": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	usage := &models.UploadUsage{}
+	for cursor.Next(ctx) {
+		var upload struct {
+			Size int64 `bson:"size"`
+		}
+		if err = cursor.Decode(&upload); err != nil {
+			return nil, err
+		}
+		usage.UsedBytes += upload.Size
+		usage.FileCount++
+	}
+	if err = cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	if s.policy.Quota > 0 {
+		quota, remaining := s.policy.Quota, max(0, s.policy.Quota-usage.UsedBytes)
+		usage.QuotaBytes, usage.RemainingBytes = &quota, &remaining
+	}
+	return usage, nil
+}
+
+// quotaUsed returns the bytes counted against userID's storage quota: their recorded uploads
+// and the reservations of their pending direct uploads, except the one stored under pendingID
+func (s *UploadService) quotaUsed(ctx context.Context, userID primitive.ObjectID, pendingID string) (int64, error) {
+	usage, err := s.Usage(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check storage quota: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	cursor, err := s.pendingCollection.Find(ctx, bson.M{
+		"uploader_id": userID, "public_id": bson.M{"$ne": pendingID}, "expires_at": bson.M{"$gt": time.Now()},
+	}, options.Find().SetProjection(bson.M{"max_size": 1}))
+	if err != nil {
+		return 0, fmt.Errorf("failed to check storage quota: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	used := usage.UsedBytes
+	for cursor.Next(ctx) {
+		var pending models.PendingUpload
+		if err := cursor.Decode(&pending); err != nil {
+			return 0, fmt.Errorf("failed to check storage quota: %w", err)
+		}
+		used += pending.MaxSize
+	}
+	if err := cursor.Err(); err != nil {
+		return 0, fmt.Errorf("failed to check storage quota: %w", err)
+	}
+	return used, nil
+}
+
+// checkQuota rejects an upload of size bytes that would take userID over their storage quota.
+// pendingID names the pending direct upload being confirmed, whose reservation is size itself.
+func (s *UploadService) checkQuota(ctx context.Context, userID primitive.ObjectID, size int64, pendingID string) error {
+	if s.policy.Quota <= 0 {
+		return nil
+	}
+	used, err := s.quotaUsed(ctx, userID, pendingID)
+	if err != nil {
+		return err
+	}
+	if used+size > s.policy.Quota {
+		return ErrStorageQuotaExceeded.WithDetails(map[string]interface{}{
+			"size": size, "used_bytes": used, "quota_bytes": s.policy.Quota,
+		})
+	}
+	return nil
+}
+
+// userUploadPrefix is the key prefix of a user's uploads, so a direct upload can be checked
+// against the user it was signed for
+func userUploadPrefix(userID primitive.ObjectID) string {
+	return fmt.Sprintf("%s/%s/", uploadFolder, userID.Hex())
+}
+
+// SignDirectUpload authorizes userID to upload one file straight to the storage backend. The
+// backend refuses files of types or sizes the policy doesn't allow, and files larger than
+// what is left of the user's quota, which the upload reserves until it is confirmed or
+// expires. Uploads that are never confirmed are deleted once they expire.
+func (s *UploadService) SignDirectUpload(ctx context.Context, userID primitive.ObjectID) (*models.DirectUploadSignature, error) {
+	direct, ok := s.storage.(DirectUploader)
+	if !ok {
+		return nil, ErrDirectUploadUnsupported
+	}
+
+	limits := models.DirectUploadLimits{Formats: s.policy.formats(), MaxSize: s.policy.MaxSize}
+	if s.policy.Quota > 0 {
+		used, err := s.quotaUsed(ctx, userID, "")
+		if err != nil {
+			return nil, err
+		}
+		remaining := s.policy.Quota - used
+		if remaining <= 0 {
+			return nil, ErrStorageQuotaExceeded.WithDetails(map[string]interface{}{
+				"used_bytes": used, "quota_bytes": s.policy.Quota,
+			})
+		}
+		if limits.MaxSize <= 0 || remaining < limits.MaxSize {
+			limits.MaxSize = remaining
+		}
+	}
+
+	signature, err := direct.SignUpload(fmt.Sprintf("%s%d", userUploadPrefix(userID), time.Now().UnixNano()), limits)
+	if err != nil {
+		return nil, err
+	}
+	pending := models.PendingUpload{
+		ID:         primitive.NewObjectID(),
+		PublicID:   signature.PublicID,
+		UploaderID: userID,
+		MaxSize:    limits.MaxSize,
+		ExpiresAt:  signature.ExpiresAt.Add(directUploadGrace),
+	}
+	if _, err := s.pendingCollection.InsertOne(ctx, pending); err != nil {
+		return nil, fmt.Errorf("failed to reserve direct upload: %w", err)
+	}
+	payload := jobs.ExpireDirectUploadPayload{PublicID: pending.PublicID}
+	if err := s.jobQueue.EnqueueAt(ctx, jobs.TypeExpireDirectUpload, payload, pending.ExpiresAt); err != nil {
+		if _, deleteErr := s.pendingCollection.DeleteOne(context.WithoutCancel(ctx), bson.M{"public_id": pending.PublicID}); deleteErr != nil {
+			logging.Warnf("Failed to release direct upload reservation %q: %v", pending.PublicID, deleteErr)
+		}
+		return nil, fmt.Errorf("failed to schedule direct upload expiry: %w", err)
+	}
+	return signature, nil
+}
+
+// ConfirmDirectUpload checks that a direct upload belongs to userID and was really stored by
+// the backend, and records it along with thumbnail variants for images
+func (s *UploadService) ConfirmDirectUpload(ctx context.Context, userID primitive.ObjectID, req models.ConfirmDirectUploadRequest) (*models.Upload, error) {
+	direct, ok := s.storage.(DirectUploader)
+	if !ok {
+		return nil, ErrDirectUploadUnsupported
+	}
+	if !strings.HasPrefix(req.PublicID, userUploadPrefix(userID)) {
+		return nil, ErrUploadNotOwned
+	}
+	if !direct.VerifyUpload(req.PublicID, req.Version, req.Signature) {
+		return nil, ErrInvalidUploadSignature
+	}
+
+	// Confirmations may be retried; the upload is only checked and recorded once
+	var existing models.Upload
+	err := s.uploadCollection.FindOne(ctx, bson.M{"public_id": req.PublicID}).Decode(&existing)
+	if err == nil {
+		return &existing, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+
+	// Uploads past their reservation have been, or are about to be, deleted
+	err = s.pendingCollection.FindOne(ctx, bson.M{"public_id": req.PublicID, "expires_at": bson.M{"$gt": time.Now()}}).Err()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrDirectUploadExpired
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	upload, err := newUpload(userID, req.UploadLink)
 	if err != nil {
-		return "", fmt.Errorf("failed to upload file to Cloudinary: %w", err)
+		return nil, err
+	}
+	asset, err := direct.Asset(ctx, req.PublicID)
+	if err != nil {
+		return nil, err
+	}
+	upload.PublicID, upload.URL, upload.Size, upload.ContentType = asset.PublicID, asset.URL, asset.Size, asset.ContentType
+	// The backend enforced the signed limits, but not the content type sniffing or the image
+	// dimensions, so the file is checked again by what the backend reports about it
+	if err := s.policy.ValidateAsset(asset); err != nil {
+		s.discardDirectUpload(ctx, upload.PublicID)
+		return nil, err
+	}
+	if err := s.checkQuota(ctx, userID, upload.Size, upload.PublicID); err != nil {
+		s.discardDirectUpload(ctx, upload.PublicID)
+		return nil, err
+	}
+	if err := s.scanDirectUpload(ctx, userID, upload); err != nil {
+		return nil, err
 	}
 
-	return uploadResult.SecureURL, nil
+	if strings.HasPrefix(upload.ContentType, "image/") {
+		// The backend resizes on delivery, so variants are just transformation URLs
+		upload.Variants = make(map[string]string, len(thumbnailVariants))
+		for _, variant := range thumbnailVariants {
+			variantURL, err := direct.ResizedURL(req.PublicID, variant.MaxEdge)
+			if err != nil {
+				return nil, fmt.Errorf("failed to build %s variant URL: %w", variant.Name, err)
+			}
+			upload.Variants[variant.Name] = variantURL
+		}
+	}
+	recorded, err := s.record(ctx, upload)
+	if err != nil {
+		return nil, err
+	}
+	// The recorded upload counts against the quota from now on
+	if _, err := s.pendingCollection.DeleteOne(ctx, bson.M{"public_id": upload.PublicID}); err != nil {
+		logging.Warnf("Failed to release direct upload reservation %q: %v", upload.PublicID, err)
+	}
+	return recorded, nil
+}
+
+// discardDirectUpload deletes a direct upload that was refused from the storage backend and
+// releases its reservation
+func (s *UploadService) discardDirectUpload(ctx context.Context, publicID string) {
+	ctx = context.WithoutCancel(ctx)
+	if err := s.storage.Delete(ctx, publicID); err != nil {
+		logging.Warnf("Failed to delete refused upload %q from storage: %v", publicID, err)
+		return // The expiry job retries
+	}
+	if _, err := s.pendingCollection.DeleteOne(ctx, bson.M{"public_id": publicID}); err != nil {
+		logging.Warnf("Failed to release direct upload reservation %q: %v", publicID, err)
+	}
+}
+
+// ExpireDirectUpload is the TypeExpireDirectUpload job handler: it deletes a direct upload that
+// was never confirmed from the storage backend and releases its reservation
+func (s *UploadService) ExpireDirectUpload(ctx context.Context, payload []byte) error {
+	var p jobs.ExpireDirectUploadPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return fmt.Errorf("invalid direct upload expiry payload: %w", err)
+	}
+
+	err := s.uploadCollection.FindOne(ctx, bson.M{"public_id": p.PublicID}).Err()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// Deleting a file that was never uploaded is not an error
+		if err := s.storage.Delete(ctx, p.PublicID); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	_, err = s.pendingCollection.DeleteOne(ctx, bson.M{"public_id": p.PublicID})
+	return err
+}
+
+// DeleteUpload removes an upload and its thumbnails from storage, along with its record and
+// any profile pictures pointing at it. Only the uploader recorded for the file may delete it
+// unless canDeleteAny is set; files without a record can only be deleted that way. The key
+// isn't proof of ownership, as merged accounts hand their uploads over under the old keys.
+func (s *UploadService) DeleteUpload(ctx context.Context, userID primitive.ObjectID, canDeleteAny bool, key string) error {
+	if !strings.HasPrefix(key, uploadFolder+"/") || strings.Contains(key, "..") {
+		return ErrInvalidUploadID
+	}
+	if !canDeleteAny {
+		var upload models.Upload
+		err := s.uploadCollection.FindOne(ctx, bson.M{"public_id": key}).Decode(&upload)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrUploadNotOwned
+		}
+		if err != nil {
+			return err
+		}
+		if upload.UploaderID != userID {
+			return ErrUploadNotOwned
+		}
+	}
+
+	if err := s.storage.Delete(ctx, key); err != nil {
+		return err
+	}
+	for _, variant := range thumbnailVariants {
+		if err := s.storage.Delete(ctx, key+"_"+variant.Name); err != nil {
+			return err
+		}
+	}
+
+	if _, err := s.uploadCollection.DeleteOne(ctx, bson.M{"public_id": key}); err != nil {
+		return fmt.Errorf("failed to delete upload record: %w", err)
+	}
+
+	// Backends link to keys either verbatim (Cloudinary) or path-escaped (S3, local disk)
+	escaped := strings.Split(key, "/")
+	for i, segment := range escaped {
+		escaped[i] = url.PathEscape(segment)
+	}
+	users, err := s.users.List(ctx, &query.Query{Filter: primitive.M{"$or": []primitive.M{
+		{"profile_picture_url": primitive.Regex{Pattern: regexp.QuoteMeta(key)}},
+		{"profile_picture_url": primitive.Regex{Pattern: regexp.QuoteMeta(strings.Join(escaped, "/"))}},
+	}}, Page: 1, Limit: 100})
+	if err != nil {
+		return fmt.Errorf("failed to find references to upload: %w", err)
+	}
+	for _, user := range users {
+		if err := s.users.Update(ctx, user.ID, repository.Fields{"profile_picture_url": "", "updated_at": time.Now()}); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("failed to clear profile picture: %w", err)
+		}
+	}
+	return nil
 }
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/OsGift/taskflow-api/internal/cache"
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/repository"
+)
+
+// UserMergeService folds duplicate accounts, such as one created by signing up twice with
+// different emails, into the account that is kept
+type UserMergeService struct {
+	db                repository.Documents
+	users             repository.UserRepository
+	commentCollection repository.Collection
+	uploadCollection  repository.Collection
+	userService       *UserService
+	projectService    *ProjectService
+	sessions          *SessionService
+	cache             cache.Cache // May be nil
+}
+
+// NewUserMergeService creates a new UserMergeService
+func NewUserMergeService(db repository.Documents, store *repository.Store, us *UserService, ps *ProjectService, ss *SessionService, c cache.Cache) *UserMergeService {
+	return &UserMergeService{
+		db:                db,
+		users:             store.Users,
+		commentCollection: db.Collection("comments"),
+		uploadCollection:  db.Collection("uploads"),
+		userService:       us,
+		projectService:    ps,
+		sessions:          ss,
+		cache:             c,
+	}
+}
+
+// MergeUsers merges the user duplicateID into primaryID: the duplicate's tasks, comments,
+// uploads, projects and project memberships are handed over to the primary user, who also
+// takes the duplicate's role when it grants more, and the duplicate is disabled and logged
+// out everywhere. Everything happens in one transaction, with the users and their tasks
+// updated last. Deployments without transactions (standalone MongoDB servers) can be left
+// with a merge done part-way, so every step is idempotent: merging the duplicate into the
+// same primary user again resumes an interrupted merge.
+func (s *UserMergeService) MergeUsers(ctx context.Context, primaryID, duplicateID string) (*models.MergeUsersResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	primaryObjID, err := primitive.ObjectIDFromHex(primaryID)
+	if err != nil {
+		return nil, ErrInvalidUserID
+	}
+	duplicateObjID, err := primitive.ObjectIDFromHex(duplicateID)
+	if err != nil {
+		return nil, ErrInvalidDuplicateUserID
+	}
+	if primaryObjID == duplicateObjID {
+		return nil, ErrMergeIntoSelf
+	}
+
+	primary, err := s.users.FindByID(ctx, primaryObjID)
+	if err == repository.ErrNotFound {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	duplicate, err := s.users.FindByID(ctx, duplicateObjID)
+	if err == repository.ErrNotFound {
+		return nil, ErrDuplicateUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if primary.IsServiceAccount || duplicate.IsServiceAccount {
+		return nil, ErrMergeServiceAccount
+	}
+	resuming := duplicate.MergedInto != nil && *duplicate.MergedInto == primaryObjID
+	if primary.Disabled || (duplicate.Disabled && !resuming) {
+		return nil, ErrMergeDisabledUser
+	}
+
+	roleID, err := s.mergedRole(ctx, primary.RoleID, duplicate.RoleID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.MergeUsersResponse{DuplicateID: duplicateObjID, RoleChanged: roleID != nil}
+	err = s.db.WithTransaction(ctx, func(txCtx context.Context) error {
+		comments, err := s.commentCollection.UpdateMany(txCtx, bson.M{"author_id": duplicateObjID},
+			bson.M{"$set": bson.M{"author_id": primaryObjID}})
+		if err != nil {
+			return err
+		}
+		uploads, err := s.uploadCollection.UpdateMany(txCtx, bson.M{"uploader_id": duplicateObjID},
+			bson.M{"$set": bson.M{"uploader_id": primaryObjID}})
+		if err != nil {
+			return err
+		}
+		projects, err := s.projectService.MergeUser(txCtx, duplicateObjID, primaryObjID)
+		if err != nil {
+			return err
+		}
+		if err := s.sessions.RevokeAllForUser(txCtx, duplicateObjID); err != nil {
+			return err
+		}
+		tasks, err := s.users.Merge(txCtx, duplicateObjID, primaryObjID, roleID)
+		if err == repository.ErrNotFound {
+			return ErrUserNotFound // Deleted since it was read
+		}
+		if err != nil {
+			return err
+		}
+
+		response.TasksMoved = tasks
+		response.CommentsMoved = comments.ModifiedCount
+		response.UploadsMoved = uploads.ModifiedCount
+		response.ProjectsMoved = projects
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.userService.InvalidateAuthContext(primaryObjID)
+	s.userService.InvalidateAuthContext(duplicateObjID)
+	cache.InvalidatePrefixes(ctx, s.cache, cachePrefixUserCount, cachePrefixTaskCount, cachePrefixDashboard)
+
+	response.Primary, err = s.userService.GetUserResponseByID(ctx, primaryID)
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// mergedRole returns the role the primary user ends up with when it changes: the duplicate's
+// role when it grants every permission the primary's does and more. Otherwise, including when
+// neither role covers the other, the primary keeps its role and nil is returned.
+func (s *UserMergeService) mergedRole(ctx context.Context, primaryRoleID, duplicateRoleID primitive.ObjectID) (*primitive.ObjectID, error) {
+	if primaryRoleID == duplicateRoleID {
+		return nil, nil
+	}
+	primaryRole, err := s.userService.GetRoleByID(ctx, primaryRoleID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	duplicateRole, err := s.userService.GetRoleByID(ctx, duplicateRoleID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	granted := make(map[string]bool, len(primaryRole.Permissions))
+	for _, p := range primaryRole.Permissions {
+		granted[p.Action] = false
+	}
+	more := false
+	for _, p := range duplicateRole.Permissions {
+		if _, ok := granted[p.Action]; ok {
+			granted[p.Action] = true
+		} else {
+			more = true
+		}
+	}
+	for _, covered := range granted {
+		if !covered {
+			return nil, nil
+		}
+	}
+	if !more {
+		return nil, nil // Same permissions under another name
+	}
+	return &duplicateRoleID, nil
+}
@@ -0,0 +1,98 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/services"
+)
+
+func TestMergeUsersHandsOverProjects(t *testing.T) {
+	f := newUserFixture(t)
+	ctx := context.Background()
+	projects := services.NewProjectService(f.store.Documents, f.tasks, f.users, nil)
+	sessions := services.NewSessionService(f.store.Documents, f.users, []byte("secret"), time.Minute, time.Hour)
+	merge := services.NewUserMergeService(f.store.Documents, f.store, f.users, projects, sessions, nil)
+
+	primaryUser, duplicateUser := f.createUser(t, "ada@example.com"), f.createUser(t, "ada.l@example.com")
+	primary, _ := primitive.ObjectIDFromHex(primaryUser.ID)
+	duplicate, _ := primitive.ObjectIDFromHex(duplicateUser.ID)
+	other := primitive.NewObjectID()
+	member := func(userID primitive.ObjectID, role models.ProjectRole) models.ProjectMember {
+		return models.ProjectMember{UserID: userID, Role: role, AddedBy: other}
+	}
+
+	newProject := func(name string, ownerID primitive.ObjectID, members ...models.ProjectMember) primitive.ObjectID {
+		t.Helper()
+		project := models.Project{ID: primitive.NewObjectID(), Name: name, OwnerID: ownerID, Members: members}
+		if _, err := f.store.Documents.Collection("projects").InsertOne(ctx, project); err != nil {
+			t.Fatalf("inserting project %s: %v", name, err)
+		}
+		return project.ID
+	}
+	owned := newProject("owned by the duplicate", duplicate, member(primary, models.ProjectRoleViewer), member(other, models.ProjectRoleEditor))
+	joined := newProject("joined by the duplicate", other, member(duplicate, models.ProjectRoleEditor))
+	both := newProject("joined by both", other, member(primary, models.ProjectRoleViewer), member(duplicate, models.ProjectRoleManager))
+	higher := newProject("primary has the higher role", other, member(primary, models.ProjectRoleManager), member(duplicate, models.ProjectRoleViewer))
+	ownedByPrimary := newProject("owned by the primary", primary, member(duplicate, models.ProjectRoleEditor))
+
+	response, err := merge.MergeUsers(ctx, primaryUser.ID, duplicateUser.ID)
+	if err != nil {
+		t.Fatalf("MergeUsers: %v", err)
+	}
+	if response.ProjectsMoved != 5 {
+		t.Errorf("MergeUsers moved %d projects, want 5", response.ProjectsMoved)
+	}
+
+	tests := []struct {
+		name    string
+		id      primitive.ObjectID
+		owner   primitive.ObjectID
+		members map[primitive.ObjectID]models.ProjectRole
+	}{
+		{"owned by the duplicate", owned, primary, map[primitive.ObjectID]models.ProjectRole{other: models.ProjectRoleEditor}},
+		{"joined by the duplicate", joined, other, map[primitive.ObjectID]models.ProjectRole{primary: models.ProjectRoleEditor}},
+		{"joined by both", both, other, map[primitive.ObjectID]models.ProjectRole{primary: models.ProjectRoleManager}},
+		{"primary has the higher role", higher, other, map[primitive.ObjectID]models.ProjectRole{primary: models.ProjectRoleManager}},
+		{"owned by the primary", ownedByPrimary, primary, map[primitive.ObjectID]models.ProjectRole{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project, err := projects.GetProject(ctx, tt.id.Hex())
+			if err != nil {
+				t.Fatalf("GetProject: %v", err)
+			}
+			if project.OwnerID != tt.owner {
+				t.Errorf("owner is %s, want %s", project.OwnerID.Hex(), tt.owner.Hex())
+			}
+			members := map[primitive.ObjectID]models.ProjectRole{}
+			for _, m := range project.Members {
+				if _, ok := members[m.UserID]; ok {
+					t.Errorf("%s is a member twice", m.UserID.Hex())
+				}
+				members[m.UserID] = m.Role
+			}
+			if len(members) != len(tt.members) {
+				t.Errorf("members are %v, want %v", members, tt.members)
+			}
+			for userID, role := range tt.members {
+				if members[userID] != role {
+					t.Errorf("members are %v, want %v", members, tt.members)
+				}
+			}
+		})
+	}
+
+	// Merging again resumes the merge, with nothing left to move
+	response, err = merge.MergeUsers(ctx, primaryUser.ID, duplicateUser.ID)
+	if err != nil {
+		t.Fatalf("MergeUsers again: %v", err)
+	}
+	if response.ProjectsMoved != 0 {
+		t.Errorf("merging again moved %d projects, want 0", response.ProjectsMoved)
+	}
+}
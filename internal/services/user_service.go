@@ -3,33 +3,80 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"github.com/OsGift/taskflow-api/internal/cache"
+	"github.com/OsGift/taskflow-api/internal/fieldcrypt"
+	"github.com/OsGift/taskflow-api/internal/logging"
 	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/repository"
+	"github.com/OsGift/taskflow-api/internal/utils"
 )
 
 // UserService provides methods for user and role related operations
 type UserService struct {
-	usersCollection *mongo.Collection
-	rolesCollection *mongo.Collection
+	users            repository.UserRepository
+	roles            repository.RoleRepository
+	db               repository.Documents                                    // Runs user deletions in a transaction
+	authContextCache *cache.TTLCache[primitive.ObjectID, models.AuthContext] // nil when caching is disabled
+	cache            cache.Cache                                             // Shared cache for roles and list counts; may be nil
+	localRoles       *cache.TTLCache[string, models.Role]                    // In-process role cache in front of cache, by role cache key
+	keys             *fieldcrypt.Keyring                                     // Encrypts personal data; nil stores it in plaintext
+	projectRoles     ProjectRoleSource                                       // Fills AuthContext.ProjectRoles; may be nil
+	dataCleaners     []UserDataCleaner                                       // Run in order before a user is deleted
 }
 
-// NewUserService creates a new UserService
-func NewUserService(db *mongo.Database) *UserService {
-	return &UserService{
-		usersCollection: db.Collection("users"),
-		rolesCollection: db.Collection("roles"),
-	}
+// UserDataCleaner deletes, or hands over to reassignTo when it is non-nil, what a user owns
+// outside the users repository before the user is deleted. It runs in the transaction that
+// deletes the user, and must do all its writes with the context it is given to join it; an
+// error rolls the whole deletion back.
+type UserDataCleaner interface {
+	CleanUpUserData(ctx context.Context, userID primitive.ObjectID, reassignTo *primitive.ObjectID) error
+}
+
+// ProjectRoleSource looks up the roles users hold in projects, for their AuthContext
+type ProjectRoleSource interface {
+	ProjectRolesOf(ctx context.Context, userID primitive.ObjectID) (map[primitive.ObjectID]models.ProjectRole, error)
+}
+
+// NewUserService creates a new UserService.
+// authContextTTL controls how long resolved AuthContexts are cached; zero disables the cache.
+// c is the shared cache used for role lookups and list counts (nil disables it).
+// keys encrypts the personal data fields of users at rest (nil stores them in plaintext).
+func NewUserService(store *repository.Store, authContextTTL time.Duration, c cache.Cache, keys *fieldcrypt.Keyring) *UserService {
+	s := &UserService{
+		users:      store.Users,
+		roles:      store.Roles,
+		db:         store.Documents,
+		cache:      c,
+		localRoles: cache.NewTTLCache[string, models.Role](localRoleCacheTTL),
+		keys:       keys,
+	}
+	if authContextTTL > 0 {
+		s.authContextCache = cache.NewTTLCache[primitive.ObjectID, models.AuthContext](authContextTTL)
+	}
+	return s
+}
+
+// SetProjectRoleSource sets where the project roles carried by AuthContexts come from. The
+// source must invalidate the AuthContext of users whose project roles it changes.
+func (s *UserService) SetProjectRoleSource(source ProjectRoleSource) {
+	s.projectRoles = source
+}
+
+// AddUserDataCleaner registers a cleaner to run when a user is deleted. Cleaners run in the
+// order they were added, so one that may refuse the deletion must come first.
+func (s *UserService) AddUserDataCleaner(c UserDataCleaner) {
+	s.dataCleaners = append(s.dataCleaners, c)
 }
 
 // CreateUser creates a new user in the database
-func (s *UserService) CreateUser(user *models.User) (*models.UserResponse, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *UserService) CreateUser(ctx context.Context, user *models.User) (*models.UserResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	user.ID = primitive.NewObjectID()
@@ -47,12 +94,19 @@ func (s *UserService) CreateUser(user *models.User) (*models.UserResponse, error
 	} // Default avatar
 	// IsEmailVerified and NeedsPasswordChange are set by the caller (AuthService)
 
-	_, err := s.usersCollection.InsertOne(ctx, user)
-	if err != nil {
+	stored := *user
+	if err := s.keys.EncryptFields(&stored); err != nil {
+		return nil, encryptionError(err)
+	}
+	if err := s.users.Create(ctx, &stored); err != nil {
+		if err == repository.ErrDuplicate {
+			return nil, ErrEmailAlreadyRegistered
+		}
 		return nil, err
 	}
+	cache.InvalidatePrefixes(ctx, s.cache, cachePrefixUserCount, cachePrefixDashboard)
 
-	role, err := s.GetRoleByID(user.RoleID.Hex())
+	role, err := s.GetRoleByID(ctx, user.RoleID.Hex())
 	if err != nil {
 		return nil, errors.New("failed to retrieve role for new user")
 	}
@@ -64,223 +118,438 @@ func (s *UserService) CreateUser(user *models.User) (*models.UserResponse, error
 		Email:               user.Email,
 		RoleName:            role.Name,
 		ProfilePictureURL:   user.ProfilePictureURL,
+		Phone:               user.Phone,
+		Address:             user.Address,
 		IsEmailVerified:     user.IsEmailVerified,
 		NeedsPasswordChange: user.NeedsPasswordChange,
+		WeeklyDigest:        user.WeeklyDigest,
+		DailySummary:        user.DailySummary,
+		DailySummaryHour:    user.DailySummaryHour,
+		Locale:              user.Locale,
+		TimeZone:            user.TimeZone,
+		IsServiceAccount:    user.IsServiceAccount,
+		Disabled:            user.Disabled,
+		MergedInto:          user.MergedInto,
 		CreatedAt:           user.CreatedAt,
 		UpdatedAt:           user.UpdatedAt,
 	}, nil
 }
 
 // GetUserByID retrieves a user by their ID
-func (s *UserService) GetUserByID(id string) (*models.User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *UserService) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return nil, errors.New("invalid user ID format")
+		return nil, ErrInvalidUserID
 	}
 
-	var user models.User
-	err = s.usersCollection.FindOne(ctx, bson.M{"_id": objID}).Decode(&user)
+	user, err := s.users.FindByID(ctx, objID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, errors.New("user not found")
+		if err == repository.ErrNotFound {
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
-	return &user, nil
+	s.decryptUser(user)
+	return user, nil
 }
 
 // GetUserByEmail retrieves a user by their email address
-func (s *UserService) GetUserByEmail(email string) (*models.User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	var user models.User
-	err := s.usersCollection.FindOne(ctx, bson.M{"email": email}).Decode(&user)
+	user, err := s.users.FindByEmail(ctx, email)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, errors.New("user not found")
+		if err == repository.ErrNotFound {
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
-	return &user, nil
+	s.decryptUser(user)
+	return user, nil
+}
+
+// decryptUser decrypts the personal data of a user read from the store. Fields that fail to
+// decrypt, e.g. under a key missing from the keyring, are logged and left empty, so one bad
+// value doesn't lock the user out or break the listings they appear in.
+func (s *UserService) decryptUser(user *models.User) {
+	if err := s.keys.DecryptFields(user); err != nil {
+		logging.Warnf("Failed to decrypt the personal data of user %s: %v", user.ID.Hex(), err)
+	}
+}
+
+// encryptionError turns the refusal to store plaintext that looks encrypted into a client error
+func encryptionError(err error) error {
+	if errors.Is(err, fieldcrypt.ErrReservedPrefix) {
+		return ErrReservedPersonalData
+	}
+	return err
 }
 
 // GetRoleByName retrieves a role by its name
-func (s *UserService) GetRoleByName(name string) (*models.Role, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *UserService) GetRoleByName(ctx context.Context, name string) (*models.Role, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	var role models.Role
-	err := s.rolesCollection.FindOne(ctx, bson.M{"name": name}).Decode(&role)
+	cacheKey := cachePrefixRole + "name:" + name
+	if cached, ok := s.cachedRole(ctx, cacheKey); ok {
+		return cached, nil
+	}
+
+	role, err := s.roles.FindByName(ctx, name)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, errors.New("role not found")
+		if err == repository.ErrNotFound {
+			return nil, ErrRoleNotFound
 		}
 		return nil, err
 	}
-	return &role, nil
+	s.cacheRole(ctx, cacheKey, role)
+	return role, nil
 }
 
 // GetRoleByID retrieves a role by its ID
-func (s *UserService) GetRoleByID(id string) (*models.Role, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *UserService) GetRoleByID(ctx context.Context, id string) (*models.Role, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return nil, errors.New("invalid role ID format")
+		return nil, ErrInvalidRoleID
 	}
 
-	var role models.Role
-	err = s.rolesCollection.FindOne(ctx, bson.M{"_id": objID}).Decode(&role)
+	cacheKey := cachePrefixRole + "id:" + objID.Hex()
+	if cached, ok := s.cachedRole(ctx, cacheKey); ok {
+		return cached, nil
+	}
+
+	role, err := s.roles.FindByID(ctx, objID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, errors.New("role not found")
+		if err == repository.ErrNotFound {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+	s.cacheRole(ctx, cacheKey, role)
+	return role, nil
+}
+
+// cachedRole returns the role cached under key, looking in this server's cache before the
+// shared one
+func (s *UserService) cachedRole(ctx context.Context, key string) (*models.Role, bool) {
+	if role, ok := s.localRoles.Get(key); ok {
+		return &role, true
+	}
+	var role models.Role
+	if !cache.GetJSON(ctx, s.cache, key, &role) {
+		return nil, false
+	}
+	s.localRoles.Set(key, role)
+	return &role, true
+}
+
+// cacheRole stores role under key in both this server's cache and the shared one
+func (s *UserService) cacheRole(ctx context.Context, key string, role *models.Role) {
+	s.localRoles.Set(key, *role)
+	cache.SetJSON(ctx, s.cache, key, role, roleCacheTTL)
+}
+
+// getRolesByIDs retrieves the roles with the given IDs, keyed by ID, reading the ones that
+// aren't cached with a single query. Roles that don't exist are left out of the map.
+func (s *UserService) getRolesByIDs(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]*models.Role, error) {
+	roles := make(map[primitive.ObjectID]*models.Role, len(ids))
+	missing := make(map[primitive.ObjectID]bool)
+	for _, id := range ids {
+		if _, ok := roles[id]; ok || missing[id] {
+			continue
+		}
+		if cached, ok := s.cachedRole(ctx, cachePrefixRole+"id:"+id.Hex()); ok {
+			roles[id] = cached
+		} else {
+			missing[id] = true
 		}
+	}
+	if len(missing) == 0 {
+		return roles, nil
+	}
+
+	missingIDs := make([]primitive.ObjectID, 0, len(missing))
+	for id := range missing {
+		missingIDs = append(missingIDs, id)
+	}
+	found, err := s.roles.FindByIDs(ctx, missingIDs)
+	if err != nil {
 		return nil, err
 	}
-	return &role, nil
+	for i := range found {
+		role := &found[i]
+		roles[role.ID] = role
+		s.cacheRole(ctx, cachePrefixRole+"id:"+role.ID.Hex(), role)
+	}
+	return roles, nil
 }
 
 // UpdateUserPassword updates a user's password
-func (s *UserService) UpdateUserPassword(userID primitive.ObjectID, hashedPassword string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *UserService) UpdateUserPassword(ctx context.Context, userID primitive.ObjectID, hashedPassword string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	update := bson.M{"$set": bson.M{
+	err := s.users.Update(ctx, userID, repository.Fields{
 		"password":   hashedPassword,
 		"updated_at": time.Now(),
-	}}
-	result, err := s.usersCollection.UpdateByID(ctx, userID, update)
-	if err != nil {
-		return err
-	}
-	if result.ModifiedCount == 0 {
+	})
+	if err == repository.ErrNotFound {
 		return errors.New("user not found or password not changed")
 	}
-	return nil
+	return err
+}
+
+// ReplacePasswordHash replaces a user's password hash with newHash, which must hash the same
+// password, unless the password changed since oldHash was read
+func (s *UserService) ReplacePasswordHash(ctx context.Context, userID primitive.ObjectID, oldHash, newHash string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err := s.users.ReplacePassword(ctx, userID, oldHash, newHash)
+	if err == repository.ErrNotFound {
+		return nil // Deleted or given a new password meanwhile, which needs no rehash
+	}
+	return err
 }
 
 // UpdateUserPasswordAndNeedsChange updates a user's password and sets needs_password_change flag
-func (s *UserService) UpdateUserPasswordAndNeedsChange(userID primitive.ObjectID, hashedPassword string, needsChange bool) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *UserService) UpdateUserPasswordAndNeedsChange(ctx context.Context, userID primitive.ObjectID, hashedPassword string, needsChange bool) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	update := bson.M{"$set": bson.M{
+	err := s.users.Update(ctx, userID, repository.Fields{
 		"password":              hashedPassword,
 		"needs_password_change": needsChange,
 		"updated_at":            time.Now(),
-	}}
-	result, err := s.usersCollection.UpdateByID(ctx, userID, update)
+	})
+	if err == repository.ErrNotFound {
+		return errors.New("user not found or password/needs_password_change not updated")
+	}
 	if err != nil {
 		return err
 	}
-	if result.ModifiedCount == 0 {
-		return errors.New("user not found or password/needs_password_change not updated")
-	}
+	s.InvalidateAuthContext(userID)
 	return nil
 }
 
-// UpdateUserRole updates a user's role
-func (s *UserService) UpdateUserRole(userID string, newRoleName string) (*models.UserResponse, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// UpdateUserRole updates a user's role.
+// The role lookup and the user update happen atomically so a role removed
+// concurrently can't be assigned.
+func (s *UserService) UpdateUserRole(ctx context.Context, userID string, newRoleName string) (*models.UserResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	objID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
-		return nil, errors.New("invalid user ID format")
+		return nil, ErrInvalidUserID
 	}
 
-	newRole, err := s.GetRoleByName(newRoleName)
-	if err != nil {
-		return nil, errors.New("new role not found")
+	switch err := s.users.UpdateRole(ctx, objID, newRoleName); err {
+	case nil:
+	case repository.ErrRoleNotFound:
+		return nil, ErrNewRoleNotFound
+	case repository.ErrNotFound:
+		return nil, ErrRoleNotChanged
+	default:
+		return nil, err
 	}
+	s.InvalidateAuthContext(objID)
+	cache.InvalidatePrefixes(ctx, s.cache, cachePrefixUserCount, cachePrefixDashboard)
 
-	update := bson.M{
-		"$set": bson.M{
-			"role_id":    newRole.ID,
-			"updated_at": time.Now(),
-		},
-	}
+	return s.GetUserResponseByID(ctx, userID) // Use the helper to build response
+}
 
-	result, err := s.usersCollection.UpdateByID(ctx, objID, update)
+// DeleteUser deletes a user together with their tasks, or hands the tasks over to
+// reassignToID when it is non-empty. The registered UserDataCleaners run first, e.g. to
+// delete the tasks one by one so what hangs off them goes too; the repository then deletes
+// or reassigns any tasks left and the user. All of it runs in one transaction, so a failure
+// part-way leaves the user and everything they own as it was.
+func (s *UserService) DeleteUser(ctx context.Context, userID, reassignToID string) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
-		return nil, err
+		return ErrInvalidUserID
+	}
+	var reassignTo *primitive.ObjectID
+	if reassignToID != "" {
+		reassignObjID, err := primitive.ObjectIDFromHex(reassignToID)
+		if err != nil {
+			return ErrInvalidReassignUserID
+		}
+		if reassignObjID == objID {
+			return ErrReassignToDeletedUser
+		}
+		reassignTo = &reassignObjID
+		if _, err := s.users.FindByID(ctx, reassignObjID); err == repository.ErrNotFound {
+			return ErrReassignUserNotFound
+		} else if err != nil {
+			return err
+		}
 	}
-	if result.ModifiedCount == 0 {
-		return nil, errors.New("user not found or role not changed")
+	if _, err := s.users.FindByID(ctx, objID); err == repository.ErrNotFound {
+		return ErrUserNotFound
+	} else if err != nil {
+		return err
 	}
 
-	updatedUser, err := s.GetUserByID(userID)
+	err = s.db.WithTransaction(ctx, func(txCtx context.Context) error {
+		for _, cleaner := range s.dataCleaners {
+			if err := cleaner.CleanUpUserData(txCtx, objID, reassignTo); err != nil {
+				return err
+			}
+		}
+
+		switch err := s.users.Delete(txCtx, objID, reassignTo); err {
+		case nil:
+			return nil
+		case repository.ErrNotFound:
+			return ErrUserNotFound
+		case repository.ErrReassignTargetNotFound:
+			return ErrReassignUserNotFound
+		default:
+			return err
+		}
+	})
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return s.GetUserResponseByID(updatedUser.ID.Hex()) // Use the helper to build response
+	s.InvalidateAuthContext(objID)
+	cache.InvalidatePrefixes(ctx, s.cache, cachePrefixUserCount, cachePrefixTaskCount, cachePrefixDashboard)
+	return nil
 }
 
 // UpdateUserProfile updates a user's profile details (first_name, last_name, profile_picture_url)
-func (s *UserService) UpdateUserProfile(userID string, req *models.UpdateUserProfileRequest) (*models.UserResponse, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *UserService) UpdateUserProfile(ctx context.Context, userID string, req *models.UpdateUserProfileRequest) (*models.UserResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	objID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
-		return nil, errors.New("invalid user ID format")
+		return nil, ErrInvalidUserID
 	}
 
-	updateDoc := bson.M{"$set": bson.M{"updated_at": time.Now()}}
+	fields := repository.Fields{"updated_at": time.Now()}
 	if req.FirstName != nil {
-		updateDoc["$set"].(bson.M)["first_name"] = *req.FirstName
+		fields["first_name"] = *req.FirstName
 	}
 	if req.LastName != nil {
-		updateDoc["$set"].(bson.M)["last_name"] = *req.LastName
+		fields["last_name"] = *req.LastName
 	}
 	if req.ProfilePictureURL != nil {
-		updateDoc["$set"].(bson.M)["profile_picture_url"] = *req.ProfilePictureURL
+		fields["profile_picture_url"] = *req.ProfilePictureURL
+	}
+	if req.Phone != nil {
+		if fields["phone"], err = s.keys.Encrypt("phone", *req.Phone); err != nil {
+			return nil, encryptionError(err)
+		}
+	}
+	if req.Address != nil {
+		if fields["address"], err = s.keys.Encrypt("address", *req.Address); err != nil {
+			return nil, encryptionError(err)
+		}
+	}
+	if req.WeeklyDigest != nil {
+		fields["weekly_digest"] = *req.WeeklyDigest
+	}
+	if req.DailySummary != nil {
+		fields["daily_summary"] = *req.DailySummary
+	}
+	if req.DailySummaryHour != nil {
+		fields["daily_summary_hour"] = *req.DailySummaryHour
+	}
+	if req.Locale != nil {
+		fields["locale"] = utils.NormalizeLocale(*req.Locale)
+	}
+	if req.TimeZone != nil {
+		fields["time_zone"] = *req.TimeZone
 	}
 
-	res, err := s.usersCollection.UpdateByID(ctx, objID, updateDoc)
-	if err != nil {
+	if err := s.users.Update(ctx, objID, fields); err != nil {
+		if err == repository.ErrNotFound {
+			return nil, ErrProfileNotChanged
+		}
 		return nil, err
 	}
-	if res.ModifiedCount == 0 {
-		return nil, errors.New("user not found or no changes made to profile")
+	if req.TimeZone != nil { // Cached auth contexts hold the time zone
+		s.InvalidateAuthContext(objID)
 	}
+	// The cached leaderboard embeds user names
+	cache.InvalidatePrefixes(ctx, s.cache, cachePrefixDashboard)
 
-	return s.GetUserResponseByID(userID) // Use the helper to build response
+	return s.GetUserResponseByID(ctx, userID) // Use the helper to build response
+}
+
+// RotateDataKey re-encrypts with the current data key the personal data of the users still
+// stored under a retired key, or in plaintext. It returns how many users it updated; once it
+// succeeds the retired keys are no longer needed.
+func (s *UserService) RotateDataKey(ctx context.Context) (int, error) {
+	rotated := 0
+	err := s.users.Each(ctx, func(user *models.User) error {
+		fields := repository.Fields{}
+		for name, value := range fieldcrypt.Fields(user) {
+			if s.keys.IsCurrent(value) {
+				continue
+			}
+			plaintext, err := s.keys.Decrypt(name, value)
+			if err != nil {
+				return fmt.Errorf("user %s: %s: %w", user.ID.Hex(), name, err)
+			}
+			if fields[name], err = s.keys.Encrypt(name, plaintext); err != nil {
+				return err
+			}
+		}
+		if len(fields) == 0 {
+			return nil
+		}
+
+		updateCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		if err := s.users.Update(updateCtx, user.ID, fields); err != nil && err != repository.ErrNotFound {
+			return err
+		}
+		rotated++
+		return nil
+	})
+	return rotated, err
 }
 
 // VerifyUserEmail sets a user's email_verified status to true
-func (s *UserService) VerifyUserEmail(userID primitive.ObjectID) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *UserService) VerifyUserEmail(ctx context.Context, userID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	update := bson.M{"$set": bson.M{
+	err := s.users.Update(ctx, userID, repository.Fields{
 		"is_email_verified": true,
 		"updated_at":        time.Now(),
-	}}
-	result, err := s.usersCollection.UpdateByID(ctx, userID, update)
+	})
+	if err == repository.ErrNotFound {
+		return ErrEmailAlreadyVerified
+	}
 	if err != nil {
 		return err
 	}
-	if result.ModifiedCount == 0 {
-		return errors.New("user not found or email already verified")
-	}
+	s.InvalidateAuthContext(userID)
 	return nil
 }
 
 // GetUserResponseByID populates UserResponse with role name (used in handlers)
-func (s *UserService) GetUserResponseByID(id string) (*models.UserResponse, error) {
-	user, err := s.GetUserByID(id)
+func (s *UserService) GetUserResponseByID(ctx context.Context, id string) (*models.UserResponse, error) {
+	user, err := s.GetUserByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	role, err := s.GetRoleByID(user.RoleID.Hex())
+	role, err := s.GetRoleByID(ctx, user.RoleID.Hex())
 	if err != nil {
 		// If role not found, might imply corrupted data; handle gracefully
 		return &models.UserResponse{
@@ -290,8 +559,18 @@ func (s *UserService) GetUserResponseByID(id string) (*models.UserResponse, erro
 			Email:               user.Email,
 			RoleName:            "Unknown", // Default to unknown role
 			ProfilePictureURL:   user.ProfilePictureURL,
+			Phone:               user.Phone,
+			Address:             user.Address,
 			IsEmailVerified:     user.IsEmailVerified,
 			NeedsPasswordChange: user.NeedsPasswordChange,
+			WeeklyDigest:        user.WeeklyDigest,
+			DailySummary:        user.DailySummary,
+			DailySummaryHour:    user.DailySummaryHour,
+			Locale:              user.Locale,
+			TimeZone:            user.TimeZone,
+			IsServiceAccount:    user.IsServiceAccount,
+			Disabled:            user.Disabled,
+			MergedInto:          user.MergedInto,
 			CreatedAt:           user.CreatedAt,
 			UpdatedAt:           user.UpdatedAt,
 		}, nil
@@ -304,95 +583,178 @@ func (s *UserService) GetUserResponseByID(id string) (*models.UserResponse, erro
 		Email:               user.Email,
 		RoleName:            role.Name,
 		ProfilePictureURL:   user.ProfilePictureURL,
+		Phone:               user.Phone,
+		Address:             user.Address,
 		IsEmailVerified:     user.IsEmailVerified,
 		NeedsPasswordChange: user.NeedsPasswordChange,
+		WeeklyDigest:        user.WeeklyDigest,
+		DailySummary:        user.DailySummary,
+		DailySummaryHour:    user.DailySummaryHour,
+		Locale:              user.Locale,
+		TimeZone:            user.TimeZone,
+		IsServiceAccount:    user.IsServiceAccount,
+		Disabled:            user.Disabled,
+		MergedInto:          user.MergedInto,
 		CreatedAt:           user.CreatedAt,
 		UpdatedAt:           user.UpdatedAt,
 	}, nil
 }
 
-// ListUsers retrieves a list of users with optional filtering and pagination
-func (s *UserService) ListUsers(
-	filter primitive.M,
-	page int64,
-	limit int64,
-) (*models.UserListResponse, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// ListUsers retrieves a list of users with optional filtering, sorting and pagination
+func (s *UserService) ListUsers(ctx context.Context, q *query.Query) (*models.UserListResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	// Calculate skip for pagination
-	skip := (page - 1) * limit
-	if skip < 0 {
-		skip = 0 // Ensure skip is not negative
-	}
-
-	findOptions := options.Find()
-	findOptions.SetSkip(skip)
-	findOptions.SetLimit(limit)
-	findOptions.SetSort(bson.D{{"created_at", -1}}) // Sort by creation date descending
-
-	cursor, err := s.usersCollection.Find(ctx, filter, findOptions)
+	filter := q.Filter
+	users, err := s.users.List(ctx, q)
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(ctx)
 
-	var users []models.User
-	if err = cursor.All(ctx, &users); err != nil {
+	// Look up the roles of the whole page at once rather than one query per user
+	roleIDs := make([]primitive.ObjectID, len(users))
+	for i, user := range users {
+		roleIDs[i] = user.RoleID
+	}
+	roles, err := s.getRolesByIDs(ctx, roleIDs)
+	if err != nil {
 		return nil, err
 	}
 
 	userResponses := make([]models.UserResponse, len(users))
 	for i, user := range users {
-		role, roleErr := s.GetRoleByID(user.RoleID.Hex())
+		s.decryptUser(&user)
 		roleName := "Unknown"
-		if roleErr == nil {
+		if role, ok := roles[user.RoleID]; ok {
 			roleName = role.Name
 		}
-		userResponses[i] = models.UserResponse{
-			ID:                  user.ID.Hex(),
-			FirstName:           user.FirstName,
-			LastName:            user.LastName,
-			Email:               user.Email,
-			RoleName:            roleName,
-			ProfilePictureURL:   user.ProfilePictureURL,
-			IsEmailVerified:     user.IsEmailVerified,
-			NeedsPasswordChange: user.NeedsPasswordChange,
-			CreatedAt:           user.CreatedAt,
-			UpdatedAt:           user.UpdatedAt,
-		}
+		userResponses[i] = newUserResponse(&user, roleName)
 	}
 
-	// Get total count for pagination metadata
-	totalCount, err := s.usersCollection.CountDocuments(ctx, filter)
+	// Get total count for pagination metadata (cached until users are written)
+	totalCount, estimated, err := listCount(ctx, s.cache, cachePrefixUserCount, s.users, filter, q.EstimateCount)
 	if err != nil {
 		return nil, err
 	}
 
 	return &models.UserListResponse{
-		Users:      userResponses,
-		TotalCount: totalCount,
-		Page:       page,
-		Limit:      limit,
+		Users:          userResponses,
+		Pagination:     models.NewPagination(totalCount, q.Page, q.Limit),
+		CountEstimated: estimated,
 	}, nil
 }
 
-func (s *UserService) GetAuthContext(userID, roleID primitive.ObjectID) (*models.AuthContext, error) {
-	user, err := s.GetUserByID(userID.Hex())
+// EachUser calls fn with every user matching the query, in the query's sort order and ignoring
+// its paging. Users are read as fn consumes them, so listings of any size can be streamed.
+func (s *UserService) EachUser(ctx context.Context, q *query.Query, fn func(*models.UserResponse) error) error {
+	return s.users.EachMatching(ctx, q, func(user *models.User) error {
+		s.decryptUser(user)
+		// Roles are few and cached, so looking each one up costs no query
+		roleName := "Unknown"
+		if role, err := s.GetRoleByID(ctx, user.RoleID.Hex()); err == nil {
+			roleName = role.Name
+		}
+		response := newUserResponse(user, roleName)
+		return fn(&response)
+	})
+}
+
+// newUserResponse returns the API representation of a decrypted user
+func newUserResponse(user *models.User, roleName string) models.UserResponse {
+	return models.UserResponse{
+		ID:                  user.ID.Hex(),
+		FirstName:           user.FirstName,
+		LastName:            user.LastName,
+		Email:               user.Email,
+		RoleName:            roleName,
+		ProfilePictureURL:   user.ProfilePictureURL,
+		Phone:               user.Phone,
+		Address:             user.Address,
+		IsEmailVerified:     user.IsEmailVerified,
+		NeedsPasswordChange: user.NeedsPasswordChange,
+		WeeklyDigest:        user.WeeklyDigest,
+		DailySummary:        user.DailySummary,
+		DailySummaryHour:    user.DailySummaryHour,
+		Locale:              user.Locale,
+		TimeZone:            user.TimeZone,
+		IsServiceAccount:    user.IsServiceAccount,
+		Disabled:            user.Disabled,
+		MergedInto:          user.MergedInto,
+		CreatedAt:           user.CreatedAt,
+		UpdatedAt:           user.UpdatedAt,
+	}
+}
+
+// GetAuthContext builds the AuthContext for a user, serving it from the cache when possible.
+// The user's current role is used rather than roleID (which comes from a possibly stale token),
+// so role changes take effect as soon as the cached entry is invalidated. The user's project
+// roles are cached with it. The cache is per process: other servers notice changes once
+// their own entries expire.
+func (s *UserService) GetAuthContext(ctx context.Context, userID, roleID primitive.ObjectID) (*models.AuthContext, error) {
+	var generation uint64
+	if s.authContextCache != nil {
+		if cached, ok := s.authContextCache.Get(userID); ok {
+			return &cached, nil
+		}
+		// Taken before reading, so a context invalidated while it is built isn't cached
+		generation = s.authContextCache.Generation()
+	}
+
+	user, err := s.GetUserByID(ctx, userID.Hex())
 	if err != nil {
 		return nil, err
 	}
+	if user.Disabled {
+		return nil, ErrAccountDisabled
+	}
 
-	role, err := s.GetRoleByID(user.RoleID.Hex())
+	role, err := s.GetRoleByID(ctx, user.RoleID.Hex())
 	if err != nil {
 		return nil, err
 	}
-	return &models.AuthContext{
+	authContext := models.AuthContext{
 		UserID:              user.ID,
 		RoleID:              role.ID,
 		RoleName:            role.Name,
 		Permissions:         role.Permissions,
 		IsEmailVerified:     user.IsEmailVerified,
 		NeedsPasswordChange: user.NeedsPasswordChange,
-	}, nil
+		IsServiceAccount:    user.IsServiceAccount,
+		TimeZone:            user.TimeZone,
+	}
+	if s.projectRoles != nil {
+		if authContext.ProjectRoles, err = s.projectRoles.ProjectRolesOf(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.authContextCache != nil {
+		s.authContextCache.SetIfGeneration(userID, authContext, generation)
+	}
+	return &authContext, nil
+}
+
+// InvalidateAuthContext drops the cached AuthContext for a user after their role or status changes
+func (s *UserService) InvalidateAuthContext(userID primitive.ObjectID) {
+	if s.authContextCache != nil {
+		s.authContextCache.Delete(userID)
+	}
+}
+
+// InvalidateRoleCache drops cached roles and auth contexts after role definitions change (e.g., seeding).
+// Other servers keep their in-process copies of the roles for up to a minute.
+func (s *UserService) InvalidateRoleCache(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	s.localRoles.Clear()
+	cache.InvalidatePrefixes(ctx, s.cache, cachePrefixRole)
+	s.InvalidateAllAuthContexts()
+}
+
+// InvalidateAllAuthContexts clears every cached AuthContext (e.g., after role permissions change)
+func (s *UserService) InvalidateAllAuthContexts() {
+	if s.authContextCache != nil {
+		s.authContextCache.Clear()
+	}
 }
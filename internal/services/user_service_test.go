@@ -0,0 +1,239 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+	"github.com/OsGift/taskflow-api/internal/query"
+	"github.com/OsGift/taskflow-api/internal/repository"
+	"github.com/OsGift/taskflow-api/internal/repository/memstore"
+	"github.com/OsGift/taskflow-api/internal/services"
+)
+
+// newStore returns in-memory repositories with the default roles
+func newStore(t *testing.T) *repository.Store {
+	t.Helper()
+	store := memstore.New()
+	if err := repository.SeedDefaultRoles(context.Background(), store.Roles); err != nil {
+		t.Fatalf("seeding roles: %v", err)
+	}
+	return store
+}
+
+// userFixture wires a UserService the way app.NewServices does, with the task and comment
+// services it cleans up after
+type userFixture struct {
+	store    *repository.Store
+	users    *services.UserService
+	tasks    *services.TaskService
+	comments *services.CommentService
+}
+
+func newUserFixture(t *testing.T) *userFixture {
+	t.Helper()
+	store := newStore(t)
+	f := &userFixture{
+		store:    store,
+		users:    services.NewUserService(store, time.Minute, nil, nil),
+		tasks:    services.NewTaskService(store, nil),
+		comments: services.NewCommentService(store.Documents, 0),
+	}
+	f.tasks.AddObserver(f.comments)
+	f.users.AddUserDataCleaner(f.tasks)
+	return f
+}
+
+// createUser creates a user with the User role
+func (f *userFixture) createUser(t *testing.T, email string) *models.UserResponse {
+	t.Helper()
+	ctx := context.Background()
+	role, err := f.users.GetRoleByName(ctx, "User")
+	if err != nil {
+		t.Fatalf("GetRoleByName: %v", err)
+	}
+	user, err := f.users.CreateUser(ctx, &models.User{Email: email, Password: "hash", RoleID: role.ID})
+	if err != nil {
+		t.Fatalf("CreateUser(%s): %v", email, err)
+	}
+	return user
+}
+
+// createTask creates a task owned by userID
+func (f *userFixture) createTask(t *testing.T, userID, title string) *models.Task {
+	t.Helper()
+	owner, _ := primitive.ObjectIDFromHex(userID)
+	task, err := f.tasks.CreateTask(context.Background(), &models.Task{Title: title, Status: models.StatusTodo, UserID: owner})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	return task
+}
+
+// tasksOf returns the tasks owned by userID
+func (f *userFixture) tasksOf(t *testing.T, userID string) []models.Task {
+	t.Helper()
+	owner, _ := primitive.ObjectIDFromHex(userID)
+	list, err := f.tasks.ListTasks(context.Background(), query.New(bson.M{"user_id": owner}, 1, 100), "")
+	if err != nil {
+		t.Fatalf("ListTasks: %v", err)
+	}
+	return list.Tasks
+}
+
+func TestCreateUser(t *testing.T) {
+	f := newUserFixture(t)
+	ctx := context.Background()
+
+	created := f.createUser(t, "ada@example.com")
+	if created.RoleName != "User" || created.FirstName != "New" || created.LastName != "User" {
+		t.Errorf("CreateUser returned role %q and name %q %q, want User and the default name",
+			created.RoleName, created.FirstName, created.LastName)
+	}
+
+	user, err := f.users.GetUserByEmail(ctx, "ada@example.com")
+	if err != nil {
+		t.Fatalf("GetUserByEmail: %v", err)
+	}
+	if user.ID.Hex() != created.ID {
+		t.Errorf("GetUserByEmail found %s, want %s", user.ID.Hex(), created.ID)
+	}
+
+	role, _ := f.users.GetRoleByName(ctx, "User")
+	_, err = f.users.CreateUser(ctx, &models.User{Email: "ada@example.com", Password: "hash", RoleID: role.ID})
+	if !errors.Is(err, services.ErrEmailAlreadyRegistered) {
+		t.Errorf("CreateUser with a taken email: got %v, want ErrEmailAlreadyRegistered", err)
+	}
+}
+
+func TestDeleteUserDeletesTheirTasksAndComments(t *testing.T) {
+	f := newUserFixture(t)
+	ctx := context.Background()
+	user := f.createUser(t, "ada@example.com")
+	other := f.createUser(t, "grace@example.com")
+	task := f.createTask(t, user.ID, "Write the report")
+	kept := f.createTask(t, other.ID, "Review the report")
+	authorID, _ := primitive.ObjectIDFromHex(other.ID)
+	if _, err := f.comments.CreateComment(ctx, task.ID, authorID, "Looks good"); err != nil {
+		t.Fatalf("CreateComment: %v", err)
+	}
+
+	if err := f.users.DeleteUser(ctx, user.ID, ""); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+
+	if _, err := f.users.GetUserByID(ctx, user.ID); !errors.Is(err, services.ErrUserNotFound) {
+		t.Errorf("GetUserByID after DeleteUser: got %v, want ErrUserNotFound", err)
+	}
+	if _, err := f.tasks.GetTaskByID(ctx, task.ID.Hex()); !errors.Is(err, services.ErrTaskNotFound) {
+		t.Errorf("GetTaskByID of the deleted user's task: got %v, want ErrTaskNotFound", err)
+	}
+	if _, err := f.tasks.GetTaskByID(ctx, kept.ID.Hex()); err != nil {
+		t.Errorf("GetTaskByID of another user's task: %v", err)
+	}
+	comments, err := f.comments.ListComments(ctx, task.ID, query.New(bson.M{}, 1, 10))
+	if err != nil {
+		t.Fatalf("ListComments: %v", err)
+	}
+	if len(comments.Comments) != 0 {
+		t.Errorf("the deleted task kept %d comments", len(comments.Comments))
+	}
+}
+
+func TestDeleteUserReassignsTheirTasks(t *testing.T) {
+	f := newUserFixture(t)
+	ctx := context.Background()
+	user := f.createUser(t, "ada@example.com")
+	heir := f.createUser(t, "grace@example.com")
+	task := f.createTask(t, user.ID, "Write the report")
+
+	if err := f.users.DeleteUser(ctx, user.ID, heir.ID); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+
+	tasks := f.tasksOf(t, heir.ID)
+	if len(tasks) != 1 || tasks[0].ID != task.ID {
+		t.Fatalf("the heir owns %v, want the deleted user's task %s", tasks, task.ID.Hex())
+	}
+	if len(f.tasksOf(t, user.ID)) != 0 {
+		t.Errorf("the deleted user still owns tasks")
+	}
+}
+
+// failingCleaner refuses every user deletion
+type failingCleaner struct{}
+
+func (failingCleaner) CleanUpUserData(ctx context.Context, userID primitive.ObjectID, reassignTo *primitive.ObjectID) error {
+	return errors.New("cleanup failed")
+}
+
+func TestDeleteUserRollsBackWhenACleanerFails(t *testing.T) {
+	f := newUserFixture(t)
+	ctx := context.Background()
+	user := f.createUser(t, "ada@example.com")
+	task := f.createTask(t, user.ID, "Write the report")
+	authorID, _ := primitive.ObjectIDFromHex(user.ID)
+	if _, err := f.comments.CreateComment(ctx, task.ID, authorID, "Started"); err != nil {
+		t.Fatalf("CreateComment: %v", err)
+	}
+	// Runs after the task cleaner has deleted the tasks and their comments
+	f.users.AddUserDataCleaner(failingCleaner{})
+
+	if err := f.users.DeleteUser(ctx, user.ID, ""); err == nil {
+		t.Fatal("DeleteUser succeeded despite the failing cleaner")
+	}
+
+	if _, err := f.users.GetUserByID(ctx, user.ID); err != nil {
+		t.Errorf("GetUserByID: %v", err)
+	}
+	if _, err := f.tasks.GetTaskByID(ctx, task.ID.Hex()); err != nil {
+		t.Errorf("GetTaskByID: %v", err)
+	}
+	comments, err := f.comments.ListComments(ctx, task.ID, query.New(bson.M{}, 1, 10))
+	if err != nil {
+		t.Fatalf("ListComments: %v", err)
+	}
+	if len(comments.Comments) != 1 {
+		t.Errorf("the task has %d comments, want the 1 it had", len(comments.Comments))
+	}
+}
+
+func TestDeleteUserRejectsInvalidRequests(t *testing.T) {
+	f := newUserFixture(t)
+	ctx := context.Background()
+	user := f.createUser(t, "ada@example.com")
+	f.createTask(t, user.ID, "Write the report")
+	missing := primitive.NewObjectID().Hex()
+
+	tests := []struct {
+		name               string
+		userID, reassignTo string
+		want               error
+	}{
+		{"invalid user ID", "nope", "", services.ErrInvalidUserID},
+		{"unknown user", missing, "", services.ErrUserNotFound},
+		{"invalid reassign ID", user.ID, "nope", services.ErrInvalidReassignUserID},
+		{"reassign to the user", user.ID, user.ID, services.ErrReassignToDeletedUser},
+		{"unknown reassign user", user.ID, missing, services.ErrReassignUserNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := f.users.DeleteUser(ctx, tt.userID, tt.reassignTo); !errors.Is(err, tt.want) {
+				t.Errorf("DeleteUser: got %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// None of the refused deletions touched anything
+	if _, err := f.users.GetUserByID(ctx, user.ID); err != nil {
+		t.Errorf("GetUserByID: %v", err)
+	}
+	if len(f.tasksOf(t, user.ID)) != 1 {
+		t.Errorf("the user's task is gone")
+	}
+}
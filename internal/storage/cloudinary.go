@@ -0,0 +1,151 @@
+// Package storage implements the backends UploadService can store files in
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudinary/cloudinary-go/v2"
+	"github.com/cloudinary/cloudinary-go/v2/api"
+	"github.com/cloudinary/cloudinary-go/v2/api/admin"
+	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
+
+	"github.com/OsGift/taskflow-api/internal/models"
+)
+
+// Cloudinary stores files as Cloudinary assets
+type Cloudinary struct {
+	cld *cloudinary.Cloudinary
+}
+
+// NewCloudinary creates a Cloudinary backend from account credentials
+func NewCloudinary(cloudName, apiKey, apiSecret string) (*Cloudinary, error) {
+	cld, err := cloudinary.NewFromParams(cloudName, apiKey, apiSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Cloudinary: %w", err)
+	}
+	return &Cloudinary{cld: cld}, nil
+}
+
+// Check verifies the account credentials with the Admin API's ping, which counts against the
+// account's Admin API rate limit
+func (c *Cloudinary) Check(ctx context.Context) error {
+	result, err := c.cld.Admin.Ping(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to reach Cloudinary: %w", err)
+	}
+	if result.Error.Message != "" {
+		return fmt.Errorf("Cloudinary rejected the cloud name, API key or secret: %s", result.Error.Message)
+	}
+	return nil
+}
+
+// Upload stores file under key (used as the asset's public ID) and returns its HTTPS URL
+func (c *Cloudinary) Upload(ctx context.Context, key string, file io.Reader, size int64, contentType string) (string, error) {
+	result, err := c.cld.Upload.Upload(ctx, file, uploader.UploadParams{PublicID: key})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload file to Cloudinary: %w", err)
+	}
+	return result.SecureURL, nil
+}
+
+// Delete removes the asset stored under key, along with its cached derived versions.
+// Missing assets are not an error.
+func (c *Cloudinary) Delete(ctx context.Context, key string) error {
+	invalidate := true
+	for _, resourceType := range []string{"image", "video", "raw"} {
+		result, err := c.cld.Upload.Destroy(ctx, uploader.DestroyParams{PublicID: key, ResourceType: resourceType, Invalidate: &invalidate})
+		if err != nil {
+			return fmt.Errorf("failed to delete Cloudinary asset: %w", err)
+		}
+		if result.Error.Message != "" {
+			return fmt.Errorf("failed to delete Cloudinary asset: %s", result.Error.Message)
+		}
+		if result.Result == "ok" {
+			return nil
+		}
+	}
+	return nil
+}
+
+// signedUploadTTL is how long Cloudinary accepts a signed upload; it rejects older timestamps itself
+const signedUploadTTL = time.Hour
+
+// SignUpload signs the parameters for a single client-side upload stored under key. The limits
+// are part of the signature, so Cloudinary refuses files breaking them.
+func (c *Cloudinary) SignUpload(key string, limits models.DirectUploadLimits) (*models.DirectUploadSignature, error) {
+	now := time.Now()
+	params := url.Values{}
+	params.Set("public_id", key)
+	params.Set("timestamp", strconv.FormatInt(now.Unix(), 10))
+	if len(limits.Formats) > 0 {
+		params.Set("allowed_formats", strings.Join(limits.Formats, ","))
+	}
+	if limits.MaxSize > 0 {
+		params.Set("max_file_size", strconv.FormatInt(limits.MaxSize, 10))
+	}
+
+	signature, err := api.SignParameters(params, c.cld.Config.Cloud.APISecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign Cloudinary upload: %w", err)
+	}
+	fields := map[string]string{"api_key": c.cld.Config.Cloud.APIKey, "signature": signature}
+	for name := range params {
+		fields[name] = params.Get(name)
+	}
+	return &models.DirectUploadSignature{
+		UploadURL: fmt.Sprintf("%s/v1_1/%s/auto/upload", strings.TrimSuffix(c.cld.Config.API.UploadPrefix, "/"), c.cld.Config.Cloud.CloudName),
+		Fields:    fields,
+		PublicID:  key,
+		ExpiresAt: now.Add(signedUploadTTL),
+	}, nil
+}
+
+// VerifyUpload reports whether signature is the one Cloudinary returned for uploading key at version
+func (c *Cloudinary) VerifyUpload(key string, version int64, signature string) bool {
+	return c.cld.Upload.VerifyApiResponseSignature(key, strconv.FormatInt(version, 10), signature)
+}
+
+// Asset looks up a stored asset, returning its HTTPS URL, size, content type and dimensions
+func (c *Cloudinary) Asset(ctx context.Context, key string) (*models.StoredAsset, error) {
+	for _, assetType := range []api.AssetType{api.Image, api.Video, api.File} {
+		result, err := c.cld.Admin.Asset(ctx, admin.AssetParams{AssetType: assetType, PublicID: key})
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up Cloudinary asset: %w", err)
+		}
+		if result.Error.Message != "" {
+			continue
+		}
+
+		contentType := mime.TypeByExtension("." + result.Format)
+		if contentType == "" && assetType == api.Image {
+			contentType = "image/" + result.Format
+		}
+		contentType, _, _ = strings.Cut(contentType, ";")
+		return &models.StoredAsset{
+			PublicID:    key,
+			URL:         result.SecureURL,
+			Size:        int64(result.Bytes),
+			ContentType: contentType,
+			Width:       result.Width,
+			Height:      result.Height,
+		}, nil
+	}
+	return nil, fmt.Errorf("Cloudinary asset %q not found", key)
+}
+
+// ResizedURL returns a URL delivering the image at key scaled down to fit within maxEdge pixels
+func (c *Cloudinary) ResizedURL(key string, maxEdge int) (string, error) {
+	image, err := c.cld.Image(key)
+	if err != nil {
+		return "", err
+	}
+	image.Transformation = fmt.Sprintf("c_limit,w_%d,h_%d", maxEdge, maxEdge)
+	return image.String()
+}
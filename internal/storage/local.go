@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrInvalidKey is returned for keys that could escape the storage root
+var ErrInvalidKey = errors.New("invalid file key")
+
+// Local stores files on the local filesystem under a root directory; they are served
+// back by the static file route mounted at PublicURL
+type Local struct {
+	root      string
+	publicURL string
+}
+
+// NewLocal creates a filesystem backend, creating root if needed
+func NewLocal(root, publicURL string) (*Local, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(absRoot, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+	return &Local{root: absRoot, publicURL: strings.TrimSuffix(publicURL, "/")}, nil
+}
+
+// Upload writes file to root/key and returns its public URL. The file is written under a
+// temporary name and renamed so readers never see a partial file.
+func (l *Local) Upload(ctx context.Context, key string, file io.Reader, size int64, contentType string) (string, error) {
+	path, err := ResolvePath(l.root, key)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create upload directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if _, err := io.Copy(tmp, file); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to store file: %w", err)
+	}
+	return l.publicURL + "/" + escapeKey(key), nil
+}
+
+// Delete removes the file stored under key. Missing files are not an error.
+func (l *Local) Delete(ctx context.Context, key string) error {
+	path, err := ResolvePath(l.root, key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return nil
+}
+
+// Root returns the absolute directory files are stored in
+func (l *Local) Root() string {
+	return l.root
+}
+
+// ResolvePath maps a slash-separated key onto a path inside root. Keys with empty, "." or
+// ".." segments, backslashes, NUL bytes or hidden (dot-prefixed) segments are rejected.
+func ResolvePath(root, key string) (string, error) {
+	if key == "" || strings.ContainsAny(key, "\\\x00") {
+		return "", ErrInvalidKey
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || strings.HasPrefix(segment, ".") {
+			return "", ErrInvalidKey
+		}
+	}
+
+	path := filepath.Join(root, filepath.FromSlash(key))
+	if !strings.HasPrefix(path, root+string(filepath.Separator)) {
+		return "", ErrInvalidKey
+	}
+	return path, nil
+}
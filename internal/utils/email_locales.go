@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// emailSubjectsFile is the translation catalog of a locale directory, mapping template
+// names to translated subjects
+const emailSubjectsFile = "subjects.json"
+
+// emailLocale holds the translations of one locale. Either may lack a template, in
+// which case the English one is used.
+type emailLocale struct {
+	templates *template.Template
+	subjects  map[string]string
+}
+
+// emailLocales holds the loaded translations, keyed by normalized locale (e.g. "fr", "pt-br")
+var emailLocales map[string]*emailLocale
+
+// NormalizeLocale lowercases a language tag and uses '-' as separator, e.g. "pt_BR" -> "pt-br"
+func NormalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// TranslatedEmailLocales returns the locales templateName is translated into, sorted
+func TranslatedEmailLocales(templateName string) []string {
+	locales := []string{}
+	for locale, l := range emailLocales {
+		if _, ok := l.subjects[templateName]; ok || l.templates.Lookup(templateName+".html") != nil {
+			locales = append(locales, locale)
+		}
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// loadEmailLocales adds the translations in the locale directories of fsys (e.g. "fr/welcome.html"
+// and "fr/subjects.json") to locales, replacing the ones already loaded
+func loadEmailLocales(fsys fs.FS, locales map[string]*emailLocale) (int, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return 0, err
+	}
+
+	var loaded int
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		locale := NormalizeLocale(entry.Name())
+		l, ok := locales[locale]
+		if !ok {
+			l = &emailLocale{templates: template.New(locale), subjects: map[string]string{}}
+		}
+
+		files, err := fs.Glob(fsys, path.Join(entry.Name(), "*.html"))
+		if err != nil {
+			return 0, err
+		}
+		for _, file := range files {
+			text, err := fs.ReadFile(fsys, file)
+			if err != nil {
+				return 0, fmt.Errorf("failed to read email template %s: %w", file, err)
+			}
+			if _, err := l.templates.New(path.Base(file)).Parse(string(text)); err != nil {
+				return 0, fmt.Errorf("failed to parse email template %s: %w", file, err)
+			}
+		}
+
+		catalog, err := fs.ReadFile(fsys, path.Join(entry.Name(), emailSubjectsFile))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return 0, fmt.Errorf("failed to read %s/%s: %w", entry.Name(), emailSubjectsFile, err)
+		}
+		if err == nil {
+			var subjects map[string]string
+			if err := json.Unmarshal(catalog, &subjects); err != nil {
+				return 0, fmt.Errorf("failed to parse %s/%s: %w", entry.Name(), emailSubjectsFile, err)
+			}
+			for name, subject := range subjects {
+				l.subjects[name] = subject
+			}
+		}
+
+		if len(files) == 0 && len(l.subjects) == 0 {
+			continue
+		}
+		locales[locale] = l
+		loaded++
+	}
+	return loaded, nil
+}
+
+// localeCandidates returns the locales to try for locale, most specific first: "pt-br"
+// yields "pt-br" then "pt". English is the default and has no candidates.
+func localeCandidates(locale string) []string {
+	locale = NormalizeLocale(locale)
+	if locale == "" {
+		return nil
+	}
+	candidates := []string{locale}
+	if base, _, found := strings.Cut(locale, "-"); found {
+		candidates = append(candidates, base)
+	}
+	return candidates
+}
+
+// translatedTemplate returns the translation of templateName for locale, or nil if there is none
+func translatedTemplate(locale, templateName string) *template.Template {
+	for _, candidate := range localeCandidates(locale) {
+		if l, ok := emailLocales[candidate]; ok {
+			if t := l.templates.Lookup(templateName + ".html"); t != nil {
+				return t
+			}
+		}
+	}
+	return nil
+}
+
+// translatedSubject returns the translated subject of templateName for locale, if there is one
+func translatedSubject(locale, templateName string) (string, bool) {
+	for _, candidate := range localeCandidates(locale) {
+		if l, ok := emailLocales[candidate]; ok {
+			if subject, ok := l.subjects[templateName]; ok {
+				return subject, true
+			}
+		}
+	}
+	return "", false
+}
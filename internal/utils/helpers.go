@@ -2,105 +2,239 @@ package utils
 
 import (
 	"bytes" // For building email body
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template" // For parsing HTML templates
+	"io/fs"
+	"log"
 	"math/rand"
 	"net/http"
-	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
 	"time"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/golang-jwt/jwt/v5"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"golang.org/x/crypto/bcrypt"
 	// For models.Permission
+
+	"github.com/OsGift/taskflow-api/internal/apperror"
+	"github.com/OsGift/taskflow-api/internal/logging"
+	"github.com/OsGift/taskflow-api/internal/mailer"
+	emailtemplates "github.com/OsGift/taskflow-api/templates"
 )
 
 // Global mailer configuration
 var (
-	smtpHost     string
-	smtpPort     string
-	smtpUsername string
-	smtpPassword string
-	auth         smtp.Auth
-	templates    *template.Template
+	mailSender      mailer.Mailer
+	mailFrom        string
+	templates       *template.Template
+	templateSources map[string]string // Built-in template text by name, after overrides
+	templateSource  TemplateSource
 )
 
-// InitMailer initializes the email sender with SMTP credentials and loads templates
-func InitMailer(host, port, username, password string) error {
-	smtpHost = host
-	smtpPort = port
-	smtpUsername = username
-	smtpPassword = password
-	auth = smtp.PlainAuth("", smtpUsername, smtpPassword, smtpHost)
-
-	// Load all HTML templates from the 'templates' directory
-	var err error
-	templates, err = template.ParseGlob("templates/*.html")
+// TemplateSource supplies customised email templates that take precedence over the built-in ones
+type TemplateSource interface {
+	// EmailTemplate returns the subject and HTML body stored for name. found is false when
+	// the template hasn't been customised; an empty subject keeps the caller's subject.
+	EmailTemplate(ctx context.Context, name string) (subject, body string, found bool, err error)
+}
+
+// InitMailer sets the provider emails are delivered through and loads templates.
+// from is the sender address used for every message. Templates are compiled into the
+// binary; *.html files in overrideDir (if set) replace the built-in templates of the same name,
+// and its locale directories (e.g. fr/welcome.html) the built-in translations.
+func InitMailer(m mailer.Mailer, from, overrideDir string) error {
+	mailSender = m
+	mailFrom = from
+
+	parsed, err := template.ParseFS(emailtemplates.FS, "*.html")
 	if err != nil {
 		return fmt.Errorf("failed to parse email templates: %w", err)
 	}
+	sources := map[string]string{}
+	builtin, _ := fs.Glob(emailtemplates.FS, "*.html")
+	for _, file := range builtin {
+		text, err := fs.ReadFile(emailtemplates.FS, file)
+		if err != nil {
+			return fmt.Errorf("failed to read email template %s: %w", file, err)
+		}
+		sources[strings.TrimSuffix(file, ".html")] = string(text)
+	}
+	locales := map[string]*emailLocale{}
+	if _, err := loadEmailLocales(emailtemplates.FS, locales); err != nil {
+		return err
+	}
+
+	if overrideDir != "" {
+		if info, err := os.Stat(overrideDir); err != nil || !info.IsDir() {
+			return fmt.Errorf("email template directory %s is not a readable directory", overrideDir)
+		}
+		overrides, err := filepath.Glob(filepath.Join(overrideDir, "*.html"))
+		if err != nil {
+			return fmt.Errorf("failed to list email template overrides: %w", err)
+		}
+		if len(overrides) > 0 {
+			if parsed, err = parsed.ParseFiles(overrides...); err != nil {
+				return fmt.Errorf("failed to parse email template overrides: %w", err)
+			}
+			for _, file := range overrides {
+				text, err := os.ReadFile(file)
+				if err != nil {
+					return fmt.Errorf("failed to read email template %s: %w", file, err)
+				}
+				sources[strings.TrimSuffix(filepath.Base(file), ".html")] = string(text)
+			}
+			log.Printf("Loaded %d email template override(s) from %s", len(overrides), overrideDir)
+		}
+		overridden, err := loadEmailLocales(os.DirFS(overrideDir), locales)
+		if err != nil {
+			return fmt.Errorf("failed to load email translations from %s: %w", overrideDir, err)
+		}
+		if overridden > 0 {
+			log.Printf("Loaded email translations for %d locale(s) from %s", overridden, overrideDir)
+		}
+	}
+
+	templates = parsed
+	templateSources = sources
+	emailLocales = locales
 	fmt.Println("Email templates loaded successfully.")
 	return nil
 }
 
-// SendEmail sends an HTML email using the specified template and data
-func SendEmail(templateName, subject, toEmail string, data interface{}) {
+// SetTemplateSource makes customised templates from src take precedence over the built-in ones
+func SetTemplateSource(src TemplateSource) {
+	templateSource = src
+}
+
+// BuiltinEmailTemplate returns the text of the built-in (or overridden on disk) template name
+func BuiltinEmailTemplate(name string) (string, bool) {
+	text, ok := templateSources[name]
+	return text, ok
+}
+
+// RenderEmail renders the subject and HTML body of templateName in locale with data. The
+// translation for locale (or its base language, e.g. "pt" for "pt-br") is used when there is
+// one; otherwise a customised template from the template source wins over the built-in English
+// one, and its subject (if any) replaces defaultSubject. Subject and body fall back separately.
+func RenderEmail(ctx context.Context, templateName, locale, defaultSubject string, data interface{}) (string, string, error) {
+	if subject, ok := translatedSubject(locale, templateName); ok {
+		defaultSubject = subject
+	}
+	if t := translatedTemplate(locale, templateName); t != nil {
+		var body bytes.Buffer
+		if err := t.Execute(&body, data); err != nil {
+			return "", "", fmt.Errorf("error executing template %s (%s): %w", templateName, NormalizeLocale(locale), err)
+		}
+		return defaultSubject, body.String(), nil
+	}
+
+	if templateSource != nil {
+		subject, body, found, err := templateSource.EmailTemplate(ctx, templateName)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to load email template %s: %w", templateName, err)
+		}
+		if found {
+			if _, translated := translatedSubject(locale, templateName); subject == "" || translated {
+				subject = defaultSubject
+			}
+			return RenderEmailTemplate(templateName, subject, body, data)
+		}
+	}
+
 	if templates == nil {
-		fmt.Println("Mailer not initialized. Skipping email sending.")
-		return
+		return "", "", fmt.Errorf("mailer not initialized")
 	}
 
 	var body bytes.Buffer
 	templatePath := fmt.Sprintf("%s.html", templateName)
 	t := templates.Lookup(templatePath)
 	if t == nil {
-		fmt.Printf("Error: Template %s not found.\n", templatePath)
-		return
+		return "", "", fmt.Errorf("template %s not found", templatePath)
 	}
 
 	err := t.Execute(&body, data)
 	if err != nil {
-		fmt.Printf("Error executing template %s: %v\n", templateName, err)
-		return
+		return "", "", fmt.Errorf("error executing template %s: %w", templateName, err)
 	}
+	return defaultSubject, body.String(), nil
+}
 
-	msg := []byte("To: " + toEmail + "\r\n" +
-		"From: " + smtpUsername + "\r\n" +
-		"Subject: " + subject + "\r\n" +
-		"MIME-version: 1.0;\r\n" +
-		"Content-Type: text/html; charset=\"UTF-8\";\r\n" +
-		"\r\n" +
-		body.String())
-
-	addr := fmt.Sprintf("%s:%s", smtpHost, smtpPort)
-	err = smtp.SendMail(addr, auth, smtpUsername, []string{toEmail}, msg)
+// RenderEmailTemplate renders template text: subject as plain text and body as HTML.
+// Line breaks are removed from the subject so data can't inject headers.
+func RenderEmailTemplate(name, subject, body string, data interface{}) (string, string, error) {
+	subjectTemplate, err := texttemplate.New(name + "_subject").Parse(subject)
+	if err != nil {
+		return "", "", fmt.Errorf("error parsing subject of template %s: %w", name, err)
+	}
+	bodyTemplate, err := template.New(name).Parse(body)
 	if err != nil {
-		fmt.Printf("Error sending email to %s: %v\n", toEmail, err)
-	} else {
-		fmt.Printf("Email '%s' sent to %s successfully.\n", subject, toEmail)
+		return "", "", fmt.Errorf("error parsing template %s: %w", name, err)
+	}
+
+	var renderedSubject, renderedBody bytes.Buffer
+	if err := subjectTemplate.Execute(&renderedSubject, data); err != nil {
+		return "", "", fmt.Errorf("error executing subject of template %s: %w", name, err)
+	}
+	if err := bodyTemplate.Execute(&renderedBody, data); err != nil {
+		return "", "", fmt.Errorf("error executing template %s: %w", name, err)
 	}
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(renderedSubject.String()), renderedBody.String(), nil
 }
 
-// HashPassword hashes a plain-text password using bcrypt
-func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(bytes), err
+// SendEmail sends an HTML email using the specified template and data, translated into
+// locale when possible, with a plain-text alternative derived from the HTML. Errors are
+// returned so callers (e.g., the job worker) can retry failed deliveries.
+func SendEmail(ctx context.Context, templateName, subject, toEmail, locale string, data interface{}) error {
+	if mailSender == nil {
+		return fmt.Errorf("mailer not initialized")
+	}
+
+	subject, html, err := RenderEmail(ctx, templateName, locale, subject, data)
+	if err != nil {
+		return err
+	}
+	return DeliverEmail(ctx, toEmail, subject, html)
 }
 
-// CheckPasswordHash compares a plain-text password with a hashed password
-func CheckPasswordHash(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
+// DeliverEmail sends an already rendered HTML email, with a plain-text alternative derived
+// from the HTML, through the configured provider
+func DeliverEmail(ctx context.Context, toEmail, subject, html string) error {
+	if mailSender == nil {
+		return fmt.Errorf("mailer not initialized")
+	}
+
+	err := mailSender.Send(ctx, mailer.Message{
+		From:    mailFrom,
+		To:      toEmail,
+		Subject: subject,
+		HTML:    html,
+		Text:    mailer.PlainText(html),
+	})
+	if err != nil {
+		return fmt.Errorf("error sending email to %s: %w", toEmail, err)
+	}
+	fmt.Printf("Email '%s' sent to %s successfully.\n", subject, toEmail)
+	return nil
 }
 
 // GenerateToken generates a new JWT token for the user
 func GenerateToken(userID primitive.ObjectID, email string, roleID primitive.ObjectID, secretKey []byte) (string, error) {
+	return GenerateTokenWithTTL(userID, email, roleID, secretKey, time.Hour*24) // Token expires in 24 hours
+}
+
+// GenerateTokenWithTTL generates a JWT token for the user that expires after ttl
+func GenerateTokenWithTTL(userID primitive.ObjectID, email string, roleID primitive.ObjectID, secretKey []byte, ttl time.Duration) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id": userID.Hex(),
 		"email":   email, // Using email in claims
 		"role_id": roleID.Hex(),
-		"exp":     time.Now().Add(time.Hour * 24).Unix(), // Token expires in 24 hours
+		"exp":     time.Now().Add(ttl).Unix(),
 		"iss":     "taskflow-api",
 		"aud":     "taskflow-clients",
 	}
@@ -177,9 +311,80 @@ func GenerateRandomString(length int) string {
 	return string(b)
 }
 
-// RespondWithError sends a JSON error response
+// ErrorResponse is the envelope returned for every API error
+type ErrorResponse struct {
+	Error   bool                   `json:"error"` // Always true; kept for clients that predate Code
+	Code    apperror.Code          `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// RespondWithError sends a JSON error response; the code is derived from the HTTP status
 func RespondWithError(w http.ResponseWriter, code int, message string) {
-	RespondWithJSON(w, code, map[string]interface{}{"error": true, "message": message})
+	RespondWithJSON(w, code, ErrorResponse{Error: true, Code: apperror.CodeForStatus(code), Message: message})
+}
+
+// RespondWithAppError sends err using its application error code and message.
+// Errors that aren't application errors (or are internal) are logged and answered
+// with a 500 and fallbackMessage, so internal details never reach the client.
+func RespondWithAppError(w http.ResponseWriter, err error, fallbackMessage string) {
+	appErr := apperror.From(err)
+	if appErr.Code == apperror.CodeInternal {
+		logging.Warnf("%s: %v", fallbackMessage, err)
+		recordError(w, err)
+		appErr = apperror.New(apperror.CodeInternal, fallbackMessage)
+	}
+	RespondWithJSON(w, apperror.HTTPStatus(appErr.Code), ErrorResponse{
+		Error:   true,
+		Code:    appErr.Code,
+		Message: appErr.Message,
+		Details: appErr.Details,
+	})
+}
+
+// errorRecorder is implemented by response writers that report the errors behind 500
+// responses, such as the error reporting middleware's
+type errorRecorder interface {
+	RecordError(err error)
+}
+
+// recordError hands err to the errorRecorder among w and the writers it wraps, if any
+func recordError(w http.ResponseWriter, err error) {
+	for {
+		if recorder, ok := w.(errorRecorder); ok {
+			recorder.RecordError(err)
+			return
+		}
+		wrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return
+		}
+		w = wrapper.Unwrap()
+	}
+}
+
+// RespondWithValidationError reports struct validation failures with one detail entry per field
+func RespondWithValidationError(w http.ResponseWriter, err error) {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	fields := make(map[string]interface{}, len(validationErrors))
+	for _, fieldErr := range validationErrors {
+		rule := fieldErr.Tag()
+		if fieldErr.Param() != "" {
+			rule += "=" + fieldErr.Param()
+		}
+		fields[fieldErr.Field()] = rule
+	}
+	RespondWithJSON(w, http.StatusBadRequest, ErrorResponse{
+		Error:   true,
+		Code:    apperror.CodeValidationFailed,
+		Message: err.Error(),
+		Details: map[string]interface{}{"fields": fields},
+	})
 }
 
 // RespondWithJSON sends a JSON response
@@ -190,5 +395,6 @@ func RespondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
 	w.Write(response)
 }
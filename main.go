@@ -3,76 +3,284 @@ package main
 import (
 	"context"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
+	_ "time/tzdata" // Users' time zones must load on hosts without a zoneinfo database
 
 	"github.com/gorilla/mux"
-	"github.com/rs/cors"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"golang.org/x/crypto/acme/autocert"
 
 	"github.com/OsGift/taskflow-api/api"
+	"github.com/OsGift/taskflow-api/internal/app"
 	"github.com/OsGift/taskflow-api/internal/config"
 	"github.com/OsGift/taskflow-api/internal/database"
+	"github.com/OsGift/taskflow-api/internal/grpcapi"
 	"github.com/OsGift/taskflow-api/internal/handlers"
+	"github.com/OsGift/taskflow-api/internal/jobs"
+	"github.com/OsGift/taskflow-api/internal/logging"
+	"github.com/OsGift/taskflow-api/internal/mailer"
 	"github.com/OsGift/taskflow-api/internal/middleware"
+	"github.com/OsGift/taskflow-api/internal/migrations"
+	"github.com/OsGift/taskflow-api/internal/repository"
+	"github.com/OsGift/taskflow-api/internal/repository/mongostore"
+	"github.com/OsGift/taskflow-api/internal/repository/pgstore"
 	"github.com/OsGift/taskflow-api/internal/services"
+	"github.com/OsGift/taskflow-api/internal/storage"
 	"github.com/OsGift/taskflow-api/internal/utils" // Import utils for mailer initialization
 )
 
 func main() {
 	// 1. Load configuration
-	cfg, err := config.LoadConfig(".env")
+	cfg, err := config.Load(os.Args[1:])
 	if err != nil {
-		log.Fatalf("Error loading config: %v", err)
+		logging.Fatalf("Error loading config: %v", err)
 	}
+	log.Printf("Configuration:\n%s", cfg.Summary())
+	logLevel, _ := logging.ParseLevel(cfg.LogLevel) // Validated by LoadConfig
+	logging.SetLevel(logLevel)
+	reloader := config.NewReloader(cfg, os.Args[1:])
 
 	// 2. Initialize Mailer
-	if err := utils.InitMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword); err != nil {
-		log.Fatalf("Error initializing mailer: %v", err)
-	}
-
-	// 3. Connect to MongoDB
-	client, err := database.ConnectMongoDB(cfg.MongoURI, cfg.DBName)
+	emailSender, err := mailer.New(context.Background(), cfg.Mailer())
 	if err != nil {
-		log.Fatalf("Error connecting to MongoDB: %v", err)
+		logging.Fatalf("Error initializing mailer: %v", err)
 	}
-	defer func() {
-		if err = client.Disconnect(context.Background()); err != nil {
-			log.Printf("Error disconnecting from MongoDB: %v", err)
+	if err := utils.InitMailer(emailSender, cfg.SenderAddress(), cfg.EmailTemplateDir); err != nil {
+		logging.Fatalf("Error initializing mailer: %v", err)
+	}
+
+	// 3. Connect to the database: PostgreSQL when STORAGE_DRIVER selects it, otherwise MongoDB,
+	// retrying transient read failures and failing fast while it is down
+	dbBreaker := database.NewBreaker(cfg.MongoBreakerThreshold, time.Duration(cfg.MongoBreakerCooldownSeconds)*time.Second)
+	var store *repository.Store
+	var client *mongo.Client // Nil with PostgreSQL
+	switch cfg.StorageDriver {
+	case "postgres":
+		pg, err := pgstore.Open(cfg.PostgresURL)
+		if err != nil {
+			logging.Fatalf("Error connecting to PostgreSQL: %v", err)
 		}
-	}()
+		defer pg.Close()
+		store = pgstore.New(pg)
+	default:
+		dbRetrier := database.NewRetrier(cfg.MongoRetryAttempts, time.Duration(cfg.MongoRetryBackoffMS)*time.Millisecond, dbBreaker)
+		mongoOptions, _ := cfg.MongoClientOptions() // Validated by LoadConfig
+		mongoOptions.Breaker = dbBreaker
+		client, err = database.ConnectMongoDB(cfg.MongoURI, cfg.DBName, mongoOptions)
+		if err != nil {
+			logging.Fatalf("Error connecting to MongoDB: %v", err)
+		}
+		defer func() {
+			if err = client.Disconnect(context.Background()); err != nil {
+				logging.Warnf("Error disconnecting from MongoDB: %v", err)
+			}
+		}()
+		store = mongostore.New(client.Database(cfg.DBName), dbRetrier)
+	}
 
-	// 4. Initialize services
-	userService := services.NewUserService(client.Database(cfg.DBName))
-	taskService := services.NewTaskService(client.Database(cfg.DBName))
-	authService := services.NewAuthService(userService, []byte(cfg.JWTSecret), []byte(cfg.PasswordResetSecret))
-	dashboardService := services.NewDashboardService(client.Database(cfg.DBName))
-	uploadService := services.NewUploadService(cfg.CloudinaryCloudName, cfg.CloudinaryAPIKey, cfg.CloudinaryAPISecret)
+	// 4. Initialize the shared cache and the services, wired as in cmd/taskflow-worker
+	sharedCache, closeCache, err := app.NewCache(cfg)
+	if err != nil {
+		logging.Fatalf("Error initializing cache: %v", err)
+	}
+	defer closeCache()
+	svc, err := app.NewServices(cfg, store, sharedCache)
+	if err != nil {
+		logging.Fatalf("Error initializing services: %v", err)
+	}
 
 	// 5. Initialize handlers
-	authHandler := handlers.NewAuthHandler(authService, userService)
-	userHandler := handlers.NewUserHandler(userService, authService)
-	taskHandler := handlers.NewTaskHandler(taskService)
-	dashboardHandler := handlers.NewDashboardHandler(dashboardService)
-	uploadHandler := handlers.NewUploadHandler(uploadService)
+	cookieSameSite, _ := cfg.CookieSameSiteMode() // Validated by LoadConfig
+	var cookieSessions *services.SessionService   // Nil disables cookie auth
+	if cfg.CookieAuthEnabled {
+		cookieSessions = svc.Sessions
+	}
+	authHandler := handlers.NewAuthHandler(svc.Auth, svc.Users, cookieSessions, cookieSameSite)
+	userHandler := handlers.NewUserHandler(svc.Users, svc.Auth, svc.UserMerge)
+	serviceAccountHandler := handlers.NewServiceAccountHandler(svc.ServiceAccount)
+	taskHandler := handlers.NewTaskHandler(svc.Tasks, svc.Uploads, svc.Projects, svc.Milestones, svc.TaskMerge, svc.TaskViews, svc.TaskPins)
+	projectHandler := handlers.NewProjectHandler(svc.Projects, svc.Dashboard)
+	milestoneHandler := handlers.NewMilestoneHandler(svc.Projects, svc.Milestones)
+	sprintHandler := handlers.NewSprintHandler(svc.Projects, svc.Sprints, svc.Tasks)
+	dashboardHandler := handlers.NewDashboardHandler(svc.Dashboard)
+	uploadHandler := handlers.NewUploadHandler(svc.Uploads)
+	inboundEmailHandler := handlers.NewInboundEmailHandler(svc.Tasks, svc.Users, cfg.InboundEmailSecret, cfg.InboundEmailMailgunSigningKey)
+	auditHandler := handlers.NewAuditHandler(svc.Audit)
+	ipBlockHandler := handlers.NewIPBlockHandler(svc.IPBlock)
+	configHandler := handlers.NewConfigHandler(reloader)
+	emailTemplateHandler := handlers.NewEmailTemplateHandler(svc.EmailTemplates)
+	emailDeliveryHandler := handlers.NewEmailDeliveryHandler(svc.EmailDelivery)
+	reportScheduleHandler := handlers.NewReportScheduleHandler(svc.Reports)
+	slaRuleHandler := handlers.NewSLARuleHandler(svc.SLA)
+	announcementHandler := handlers.NewAnnouncementHandler(svc.Announcements)
+	commentHandler := handlers.NewCommentHandler(svc.Tasks, svc.Comments)
+	searchHandler := handlers.NewSearchHandler(svc.Search)
+	notificationHandler := handlers.NewNotificationHandler(svc.Notifications)
+	exportHandler := handlers.NewExportHandler(svc.Export, svc.Tasks)
+	importHandler := handlers.NewImportHandler(svc.Import)
+	calendarHandler := handlers.NewCalendarHandler(svc.Calendar, cfg.GoogleCalendarReturnURL)
+	var fileHandler *handlers.FileHandler
+	if local, ok := svc.Storage.(*storage.Local); ok {
+		fileHandler = handlers.NewFileHandler(local.Root())
+	}
+	readinessService := services.NewReadinessService(dependencyChecks(client, cfg.DBName, store, emailSender, svc.Storage),
+		time.Duration(cfg.ReadinessCacheSeconds)*time.Second)
+	healthHandler := handlers.NewHealthHandler(readinessService)
 
 	// 6. Initialize middleware
-	authMiddleware := middleware.NewAuthMiddleware([]byte(cfg.JWTSecret), userService, authService)
+	authMiddleware := middleware.NewAuthMiddleware([]byte(cfg.JWTSecret), svc.Users, svc.Auth, svc.ServiceAccount, cfg.CookieAuthEnabled)
+	compressionMiddleware := middleware.NewCompressionMiddleware(cfg.CompressionMinSize)
+	idempotencyMiddleware := middleware.NewIdempotencyMiddleware(svc.Idempotency, cfg.TrustedProxyHops)
+	auditMiddleware := middleware.NewAuditMiddleware(svc.Audit)
+	ipBlockMiddleware := middleware.NewIPBlockMiddleware(svc.IPBlock, cfg.TrustedProxyHops)
+	csrfMiddleware := middleware.NewCSRFMiddleware(cfg.CookieAuthEnabled, cookieSameSite)
+	errorTracker, _ := cfg.ErrorTracker() // Validated by LoadConfig
+	if errorTracker != nil {
+		log.Printf("Reporting server errors to %s", errorTracker.Host())
+	}
 
 	// 7. Seed default roles if they don't exist
-	if err := database.SeedDefaultRoles(client.Database(cfg.DBName)); err != nil {
-		log.Fatalf("Error seeding default roles: %v", err)
+	seedCtx, cancelSeed := context.WithTimeout(context.Background(), 5*time.Second)
+	err = repository.SeedDefaultRoles(seedCtx, store.Roles)
+	cancelSeed()
+	if err != nil {
+		logging.Fatalf("Error seeding default roles: %v", err)
+	}
+	svc.Users.InvalidateRoleCache(context.Background())
+
+	// PostgreSQL stores are created in the current format, with their indexes, by pgstore.Open
+	if client != nil {
+		// Apply pending schema/data migrations before indexes are built on the migrated fields
+		if err := migrations.Run(client.Database(cfg.DBName)); err != nil {
+			logging.Fatalf("Error running database migrations: %v", err)
+		}
+
+		// Create any missing indexes so production queries don't collection-scan
+		if err := database.EnsureIndexes(client.Database(cfg.DBName)); err != nil {
+			logging.Fatalf("Error creating database indexes: %v", err)
+		}
+	}
+
+	// Report broken dependencies now rather than when a user first needs them
+	checkCtx, cancelCheck := context.WithTimeout(context.Background(), 30*time.Second)
+	readiness := readinessService.Check(checkCtx)
+	cancelCheck()
+	if !readiness.Ready && cfg.StartupChecksRequired {
+		logging.Fatalf("Dependency checks failed; set STARTUP_CHECKS_REQUIRED=false to start anyway")
 	}
 
 	// 8. Setup router
 	router := mux.NewRouter()
-	api.SetupRoutes(router, authMiddleware, authHandler, userHandler, taskHandler, dashboardHandler, uploadHandler)
+	v1Policy := middleware.DeprecationPolicy{Deprecated: cfg.APIV1Deprecated, Successor: "/api/v2"}
+	if cfg.APIV1SunsetDate != "" {
+		v1Policy.SunsetAt, _ = time.Parse("2006-01-02", cfg.APIV1SunsetDate) // Validated by LoadConfig
+	}
+	api.SetupRoutes(router,
+		api.Middlewares{Auth: authMiddleware, Idempotency: idempotencyMiddleware, CSRF: csrfMiddleware},
+		api.Handlers{
+			Auth:           authHandler,
+			User:           userHandler,
+			ServiceAccount: serviceAccountHandler,
+			Task:           taskHandler,
+			Project:        projectHandler,
+			Milestone:      milestoneHandler,
+			Sprint:         sprintHandler,
+			Dashboard:      dashboardHandler,
+			Upload:         uploadHandler,
+			InboundEmail:   inboundEmailHandler,
+			Audit:          auditHandler,
+			IPBlock:        ipBlockHandler,
+			Config:         configHandler,
+			EmailTemplate:  emailTemplateHandler,
+			EmailDelivery:  emailDeliveryHandler,
+			ReportSchedule: reportScheduleHandler,
+			SLARule:        slaRuleHandler,
+			Announcement:   announcementHandler,
+			Comment:        commentHandler,
+			Search:         searchHandler,
+			Notification:   notificationHandler,
+			Export:         exportHandler,
+			Import:         importHandler,
+			Calendar:       calendarHandler,
+			Files:          fileHandler,
+			Health:         healthHandler,
+		},
+		map[string]middleware.DeprecationPolicy{"v1": v1Policy},
+	)
+	router.Use(middleware.NewRequestLogMiddleware().Handler)
+	// Refuses requests while MongoDB is down, inside request logging so they are still logged
+	router.Use(middleware.NewDatabaseBreakerMiddleware(dbBreaker).Handler)
+	router.Use(ipBlockMiddleware.Handler) // First to do any work, so banned addresses cost as little as possible
+	router.Use(compressionMiddleware.Handler)
+	router.Use(auditMiddleware.Handler) // Inside compression so it sees the uncompressed response
+	if errorTracker != nil {
+		router.Use(middleware.NewErrorReportingMiddleware(errorTracker).Handler) // Inside audit so it sees the caller
+	}
+	router.Use(csrfMiddleware.Handler) // Inside audit so rejected requests are logged
+	router.Use(middleware.NewRequestValidationMiddleware(api.NewRequestValidator(router)).Handler)
 
-	// --- CORS: Allow All Origins ---
-	c := cors.AllowAll()
-	handlerWithCORS := c.Handler(router)
+	// --- CORS: Allow All Origins, or the configured ones with credentials (cookies) ---
+	corsMiddleware := middleware.NewCORSMiddleware(cfg.CORSOrigins())
+	handlerWithCORS := corsMiddleware.Handler(router)
 
-	// 9. Start HTTP server
-	log.Printf("Server starting on port %s", cfg.Port)
+	// Apply the reloadable settings on SIGHUP or POST /config/reload
+	reloader.OnReload(func(c *config.Config) {
+		// A level set with PUT /log-level stays until LOG_LEVEL itself changes
+		if c.LogLevel != logLevel.String() {
+			logLevel, _ = logging.ParseLevel(c.LogLevel)
+			logging.SetLevel(logLevel)
+		}
+		corsMiddleware.SetOrigins(c.CORSOrigins())
+		svc.ServiceAccount.SetDefaultRateLimit(c.APIKeyRateLimitPerMinute)
+		svc.IPBlock.SetPolicy(c.IPBanMaxFailures, time.Duration(c.IPBanWindowMinutes)*time.Minute, time.Duration(c.IPBanMinutes)*time.Minute)
+	})
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	go func() {
+		for range hangups {
+			if _, _, err := reloader.Reload(); err != nil {
+				log.Printf("Configuration not reloaded: %v", err)
+			}
+		}
+	}()
+
+	// 9. Start the background job worker (can also run separately via cmd/taskflow-worker)
+	workerCtx, stopWorker := context.WithCancel(context.Background())
+	defer stopWorker()
+	if cfg.JobWorkerEnabled {
+		worker := jobs.NewWorker(svc.Queue, cfg.JobWorkerConcurrency, time.Duration(cfg.JobPollIntervalSeconds)*time.Second)
+		svc.RegisterJobs(workerCtx, worker, cfg)
+		reloader.OnReload(func(c *config.Config) {
+			svc.ApplyJobSettings(workerCtx, c)
+		})
+		go worker.Run(workerCtx)
+	}
+
+	// 10. Start gRPC server for internal consumers
+	if cfg.GRPCAuthToken != "" {
+		grpcListener, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+		if err != nil {
+			logging.Fatalf("Could not listen on gRPC port %s: %v", cfg.GRPCPort, err)
+		}
+		grpcServer := grpcapi.NewServer(svc.Tasks, svc.Users, cfg.GRPCAuthToken)
+		defer grpcServer.GracefulStop()
+		go func() {
+			log.Printf("gRPC server starting on port %s", cfg.GRPCPort)
+			if err := grpcServer.Serve(grpcListener); err != nil {
+				logging.Warnf("gRPC server stopped: %v", err)
+			}
+		}()
+	} else {
+		log.Println("GRPC_AUTH_TOKEN not set, gRPC server disabled.")
+	}
+
+	// 11. Start HTTP server
 	srv := &http.Server{
 		Addr:         ":" + cfg.Port,
 		Handler:      handlerWithCORS,
@@ -81,7 +289,71 @@ func main() {
 		WriteTimeout: 10 * time.Second,
 	}
 
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("Could not listen on %s: %v\n", cfg.Port, err)
+	if err := listenAndServe(srv, cfg); err != nil && err != http.ErrServerClosed {
+		logging.Fatalf("Could not listen on %s: %v\n", cfg.Port, err)
+	}
+}
+
+// dependencyChecks lists the checks of the external dependencies run at startup and by
+// GET /readyz. Email providers and storage backends without a side-effect-free check, such
+// as the HTTP email APIs, are left out. client is nil when store is in PostgreSQL.
+func dependencyChecks(client *mongo.Client, dbName string, store *repository.Store, emailSender mailer.Mailer, storageProvider services.StorageProvider) []services.DependencyCheck {
+	checks := []services.DependencyCheck{
+		{Name: "postgres", Check: store.Documents.Ping},
 	}
+	if client != nil {
+		checks = []services.DependencyCheck{
+			{Name: "mongodb", Check: func(ctx context.Context) error {
+				return client.Ping(ctx, readpref.Primary())
+			}},
+			{Name: "mongodb_indexes", Check: func(ctx context.Context) error {
+				return database.CheckIndexes(ctx, client.Database(dbName))
+			}},
+		}
+	}
+	if checker, ok := emailSender.(services.DependencyChecker); ok {
+		checks = append(checks, services.DependencyCheck{Name: "email", Check: checker.Check})
+	}
+	if checker, ok := storageProvider.(services.DependencyChecker); ok {
+		checks = append(checks, services.DependencyCheck{Name: "upload_storage", Check: checker.Check})
+	}
+	return checks
+}
+
+// listenAndServe starts srv over plain HTTP, HTTPS with the configured certificate files,
+// or HTTPS with Let's Encrypt certificates obtained and renewed by autocert
+func listenAndServe(srv *http.Server, cfg *config.Config) error {
+	switch cfg.TLSMode() {
+	case "files":
+		log.Printf("Server starting on port %s (TLS)", cfg.Port)
+		return srv.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+
+	case "autocert":
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.AutocertHosts()...),
+			Cache:      autocert.DirCache(cfg.AutocertCacheDir),
+			Email:      cfg.AutocertEmail,
+		}
+		srv.TLSConfig = manager.TLSConfig()
+
+		// HTTP-01 challenges must be answered on port 80; other plain HTTP requests are redirected
+		go func() {
+			log.Printf("ACME challenge listener starting on port %s", cfg.ACMEHTTPPort)
+			challengeServer := &http.Server{
+				Addr:              ":" + cfg.ACMEHTTPPort,
+				Handler:           manager.HTTPHandler(nil),
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+			if err := challengeServer.ListenAndServe(); err != nil {
+				logging.Warnf("ACME challenge listener stopped: %v", err)
+			}
+		}()
+
+		log.Printf("Server starting on port %s (TLS via Let's Encrypt for %s)", cfg.Port, cfg.AutocertDomains)
+		return srv.ListenAndServeTLS("", "")
+	}
+
+	log.Printf("Server starting on port %s", cfg.Port)
+	return srv.ListenAndServe()
 }
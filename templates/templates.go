@@ -0,0 +1,11 @@
+// Package templates holds the HTML email templates compiled into the binary
+package templates
+
+import "embed"
+
+// FS contains every *.html email template, named by file name (e.g. "welcome.html").
+// Translations live in one directory per locale (e.g. "fr/welcome.html"), together with
+// a subjects.json catalog mapping template names to translated subjects.
+//
+//go:embed *.html */*.html */subjects.json
+var FS embed.FS